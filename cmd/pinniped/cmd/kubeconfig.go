@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cmd
@@ -30,6 +30,7 @@ import (
 	idpdiscoveryv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	conciergeclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/net/phttp"
 	"go.pinniped.dev/internal/plog"
@@ -68,6 +69,7 @@ type getKubeconfigOIDCParams struct {
 	upstreamIDPName   string
 	upstreamIDPType   string
 	upstreamIDPFlow   string
+	upstreamIDPHint   string
 }
 
 type getKubeconfigConciergeParams struct {
@@ -145,6 +147,7 @@ func kubeconfigCommand(deps kubeconfigDeps) *cobra.Command {
 	f.StringVar(&flags.oidc.upstreamIDPName, "upstream-identity-provider-name", "", "The name of the upstream identity provider used during login with a Supervisor")
 	f.StringVar(&flags.oidc.upstreamIDPType, "upstream-identity-provider-type", "", fmt.Sprintf("The type of the upstream identity provider used during login with a Supervisor (e.g. '%s', '%s', '%s')", idpdiscoveryv1alpha1.IDPTypeOIDC, idpdiscoveryv1alpha1.IDPTypeLDAP, idpdiscoveryv1alpha1.IDPTypeActiveDirectory))
 	f.StringVar(&flags.oidc.upstreamIDPFlow, "upstream-identity-provider-flow", "", fmt.Sprintf("The type of client flow to use with the upstream identity provider during login with a Supervisor (e.g. '%s', '%s')", idpdiscoveryv1alpha1.IDPFlowCLIPassword, idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode))
+	f.StringVar(&flags.oidc.upstreamIDPHint, "upstream-identity-provider-login-hint", "", "The username or email address used to choose the upstream identity provider by its routing rules during discovery with a Supervisor")
 	f.StringVar(&flags.kubeconfigPath, "kubeconfig", os.Getenv("KUBECONFIG"), "Path to kubeconfig file")
 	f.StringVar(&flags.kubeconfigContextOverride, "kubeconfig-context", "", "Kubeconfig context name (default: current active context)")
 	f.BoolVar(&flags.skipValidate, "skip-validation", false, "Skip final validation of the kubeconfig (default: false)")
@@ -826,7 +829,7 @@ func discoverSupervisorUpstreamIDP(ctx context.Context, pinnipedIDPsEndpoint str
 		return nil
 	}
 
-	selectedIDPName, selectedIDPType, discoveredIDPFlows, err := selectUpstreamIDPNameAndType(discoveredUpstreamIDPs, flags.oidc.upstreamIDPName, flags.oidc.upstreamIDPType)
+	selectedIDPName, selectedIDPType, discoveredIDPFlows, err := selectUpstreamIDPNameAndType(discoveredUpstreamIDPs, flags.oidc.upstreamIDPName, flags.oidc.upstreamIDPType, flags.oidc.upstreamIDPHint)
 	if err != nil {
 		return err
 	}
@@ -902,7 +905,7 @@ func discoverAllAvailableSupervisorUpstreamIDPs(ctx context.Context, pinnipedIDP
 	return body.PinnipedIDPs, nil
 }
 
-func selectUpstreamIDPNameAndType(pinnipedIDPs []idpdiscoveryv1alpha1.PinnipedIDP, specifiedIDPName, specifiedIDPType string) (string, idpdiscoveryv1alpha1.IDPType, []idpdiscoveryv1alpha1.IDPFlow, error) {
+func selectUpstreamIDPNameAndType(pinnipedIDPs []idpdiscoveryv1alpha1.PinnipedIDP, specifiedIDPName, specifiedIDPType, loginHint string) (string, idpdiscoveryv1alpha1.IDPType, []idpdiscoveryv1alpha1.IDPFlow, error) {
 	pinnipedIDPsString, _ := json.Marshal(pinnipedIDPs)
 	var discoveredFlows []idpdiscoveryv1alpha1.IDPFlow
 	switch {
@@ -916,6 +919,31 @@ func selectUpstreamIDPNameAndType(pinnipedIDPs []idpdiscoveryv1alpha1.PinnipedID
 		return "", "", nil, fmt.Errorf(
 			"no Supervisor upstream identity providers with name %q of type %q were found. "+
 				"Found these upstreams: %s", specifiedIDPName, specifiedIDPType, pinnipedIDPsString)
+	case specifiedIDPName == "" && loginHint != "":
+		// The user specified a login hint and maybe a type, so check if there is only one IDP whose routing rules match.
+		var matches []idpdiscoveryv1alpha1.PinnipedIDP
+		for _, idp := range pinnipedIDPs {
+			if specifiedIDPType != "" && !idp.Type.Equals(specifiedIDPType) {
+				continue
+			}
+			if idpRoutingMatches(idp.Routing, loginHint) {
+				matches = append(matches, idp)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return "", "", nil, fmt.Errorf(
+				"no Supervisor upstream identity providers have routing rules which match the login hint %q, "+
+					"so the --upstream-identity-provider-name/--upstream-identity-provider-type flags must be specified. "+
+					"Found these upstreams: %s", loginHint, pinnipedIDPsString)
+		case 1:
+			return matches[0].Name, matches[0].Type, matches[0].Flows, nil
+		default:
+			return "", "", nil, fmt.Errorf(
+				"multiple Supervisor upstream identity providers have routing rules which match the login hint %q, "+
+					"so the --upstream-identity-provider-name/--upstream-identity-provider-type flags must be specified. "+
+					"Found these upstreams: %s", loginHint, pinnipedIDPsString)
+		}
 	case specifiedIDPType != "":
 		// The user specified only a type, so check if there is only one of that type found.
 		discoveredName := ""
@@ -974,6 +1002,20 @@ func selectUpstreamIDPNameAndType(pinnipedIDPs []idpdiscoveryv1alpha1.PinnipedID
 	}
 }
 
+// idpRoutingMatches returns true when the login hint matches the routing rules of an IDP, as reported by IDP discovery.
+// Rules which cannot be parsed, e.g. because a newer Supervisor supports a regular expression syntax that this CLI
+// does not, never match.
+func idpRoutingMatches(routing *idpdiscoveryv1alpha1.IDPRouting, loginHint string) bool {
+	if routing == nil {
+		return false
+	}
+	rules, err := idprouting.New(routing.EmailDomains, routing.UsernameRegex)
+	if err != nil {
+		return false
+	}
+	return rules.Matches(loginHint)
+}
+
 func selectUpstreamIDPFlow(discoveredIDPFlows []idpdiscoveryv1alpha1.IDPFlow, selectedIDPName string, selectedIDPType idpdiscoveryv1alpha1.IDPType, specifiedFlow string, log plog.MinLogger) (idpdiscoveryv1alpha1.IDPFlow, error) {
 	switch {
 	case len(discoveredIDPFlows) == 0:
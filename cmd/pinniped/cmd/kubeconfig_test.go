@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cmd
@@ -123,38 +123,39 @@ func TestGetKubeconfig(t *testing.T) {
 				  kubeconfig [flags]
 
 				Flags:
-				      --concierge-api-group-suffix string        Concierge API group suffix (default "pinniped.dev")
-				      --concierge-authenticator-name string      Concierge authenticator name (default: autodiscover)
-				      --concierge-authenticator-type string      Concierge authenticator type (e.g., 'webhook', 'jwt') (default: autodiscover)
-				      --concierge-ca-bundle path                 Path to TLS certificate authority bundle (PEM format, optional, can be repeated) to use when connecting to the Concierge
-				      --concierge-credential-issuer string       Concierge CredentialIssuer object to use for autodiscovery (default: autodiscover)
-				      --concierge-endpoint string                API base for the Concierge endpoint
-				      --concierge-mode mode                      Concierge mode of operation (default TokenCredentialRequestAPI)
-				      --concierge-skip-wait                      Skip waiting for any pending Concierge strategies to become ready (default: false)
-				      --credential-cache string                  Path to cluster-specific credentials cache
-				      --generated-name-suffix string             Suffix to append to generated cluster, context, user kubeconfig entries (default "-pinniped")
-				  -h, --help                                     help for kubeconfig
-				      --install-hint string                      This text is shown to the user when the pinniped CLI is not installed. (default "The pinniped CLI does not appear to be installed.  See https://get.pinniped.dev/cli for more details")
-				      --kubeconfig string                        Path to kubeconfig file
-				      --kubeconfig-context string                Kubeconfig context name (default: current active context)
-				      --no-concierge                             Generate a configuration which does not use the Concierge, but sends the credential to the cluster directly
-				      --oidc-ca-bundle path                      Path to TLS certificate authority bundle (PEM format, optional, can be repeated)
-				      --oidc-client-id string                    OpenID Connect client ID (default: autodiscover) (default "pinniped-cli")
-				      --oidc-issuer string                       OpenID Connect issuer URL (default: autodiscover)
-				      --oidc-listen-port uint16                  TCP port for localhost listener (authorization code flow only)
-				      --oidc-request-audience string             Request a token with an alternate audience using RFC8693 token exchange
-				      --oidc-scopes strings                      OpenID Connect scopes to request during login (default [offline_access,openid,pinniped:request-audience,username,groups])
-				      --oidc-session-cache string                Path to OpenID Connect session cache file
-				      --oidc-skip-browser                        During OpenID Connect login, skip opening the browser (just print the URL)
-				  -o, --output string                            Output file path (default: stdout)
-				      --pinniped-cli-path string                 Full path or executable name for the Pinniped CLI binary to be embedded in the resulting kubeconfig output (e.g. 'pinniped') (default: full path of the binary used to execute this command)
-				      --skip-validation                          Skip final validation of the kubeconfig (default: false)
-				      --static-token string                      Instead of doing an OIDC-based login, specify a static token
-				      --static-token-env string                  Instead of doing an OIDC-based login, read a static token from the environment
-				      --timeout duration                         Timeout for autodiscovery and validation (default 10m0s)
-				      --upstream-identity-provider-flow string   The type of client flow to use with the upstream identity provider during login with a Supervisor (e.g. 'cli_password', 'browser_authcode')
-				      --upstream-identity-provider-name string   The name of the upstream identity provider used during login with a Supervisor
-				      --upstream-identity-provider-type string   The type of the upstream identity provider used during login with a Supervisor (e.g. 'oidc', 'ldap', 'activedirectory')
+				      --concierge-api-group-suffix string              Concierge API group suffix (default "pinniped.dev")
+				      --concierge-authenticator-name string            Concierge authenticator name (default: autodiscover)
+				      --concierge-authenticator-type string            Concierge authenticator type (e.g., 'webhook', 'jwt') (default: autodiscover)
+				      --concierge-ca-bundle path                       Path to TLS certificate authority bundle (PEM format, optional, can be repeated) to use when connecting to the Concierge
+				      --concierge-credential-issuer string             Concierge CredentialIssuer object to use for autodiscovery (default: autodiscover)
+				      --concierge-endpoint string                      API base for the Concierge endpoint
+				      --concierge-mode mode                            Concierge mode of operation (default TokenCredentialRequestAPI)
+				      --concierge-skip-wait                            Skip waiting for any pending Concierge strategies to become ready (default: false)
+				      --credential-cache string                        Path to cluster-specific credentials cache
+				      --generated-name-suffix string                   Suffix to append to generated cluster, context, user kubeconfig entries (default "-pinniped")
+				  -h, --help                                           help for kubeconfig
+				      --install-hint string                            This text is shown to the user when the pinniped CLI is not installed. (default "The pinniped CLI does not appear to be installed.  See https://get.pinniped.dev/cli for more details")
+				      --kubeconfig string                              Path to kubeconfig file
+				      --kubeconfig-context string                      Kubeconfig context name (default: current active context)
+				      --no-concierge                                   Generate a configuration which does not use the Concierge, but sends the credential to the cluster directly
+				      --oidc-ca-bundle path                            Path to TLS certificate authority bundle (PEM format, optional, can be repeated)
+				      --oidc-client-id string                          OpenID Connect client ID (default: autodiscover) (default "pinniped-cli")
+				      --oidc-issuer string                             OpenID Connect issuer URL (default: autodiscover)
+				      --oidc-listen-port uint16                        TCP port for localhost listener (authorization code flow only)
+				      --oidc-request-audience string                   Request a token with an alternate audience using RFC8693 token exchange
+				      --oidc-scopes strings                            OpenID Connect scopes to request during login (default [offline_access,openid,pinniped:request-audience,username,groups])
+				      --oidc-session-cache string                      Path to OpenID Connect session cache file
+				      --oidc-skip-browser                              During OpenID Connect login, skip opening the browser (just print the URL)
+				  -o, --output string                                  Output file path (default: stdout)
+				      --pinniped-cli-path string                       Full path or executable name for the Pinniped CLI binary to be embedded in the resulting kubeconfig output (e.g. 'pinniped') (default: full path of the binary used to execute this command)
+				      --skip-validation                                Skip final validation of the kubeconfig (default: false)
+				      --static-token string                            Instead of doing an OIDC-based login, specify a static token
+				      --static-token-env string                        Instead of doing an OIDC-based login, read a static token from the environment
+				      --timeout duration                               Timeout for autodiscovery and validation (default 10m0s)
+				      --upstream-identity-provider-flow string         The type of client flow to use with the upstream identity provider during login with a Supervisor (e.g. 'cli_password', 'browser_authcode')
+				      --upstream-identity-provider-login-hint string   The username or email address used to choose the upstream identity provider by its routing rules during discovery with a Supervisor
+				      --upstream-identity-provider-name string         The name of the upstream identity provider used during login with a Supervisor
+				      --upstream-identity-provider-type string         The type of the upstream identity provider used during login with a Supervisor (e.g. 'oidc', 'ldap', 'activedirectory')
 			`)
 			},
 		},
@@ -1165,6 +1166,60 @@ func TestGetKubeconfig(t *testing.T) {
 					` Found these upstreams: [{"name":"some-ldap-idp","type":"ldap"},{"name":"some-other-ldap-idp","type":"ldap"},{"name":"some-oidc-idp","type":"oidc"},{"name":"some-other-oidc-idp","type":"oidc"}]` + "\n")
 			},
 		},
+		{
+			name: "supervisor upstream IDP discovery fails to resolve ambiguity when a login hint is specified but does not match any IDP",
+			args: func(issuerCABundle string, issuerURL string) []string {
+				f := testutil.WriteStringToTempFile(t, "testca-*.pem", issuerCABundle)
+				return []string{
+					"--kubeconfig", "./testdata/kubeconfig.yaml",
+					"--skip-validation",
+					"--no-concierge",
+					"--oidc-issuer", issuerURL,
+					"--oidc-ca-bundle", f.Name(),
+					"--upstream-identity-provider-login-hint", "pinny@example.org",
+				}
+			},
+			oidcDiscoveryResponse: happyOIDCDiscoveryResponse,
+			idpsDiscoveryResponse: here.Docf(`{
+				"pinniped_identity_providers": [
+					{"name": "some-ldap-idp", "type": "ldap", "routing": {"email_domains": ["example.com"]}},
+					{"name": "some-oidc-idp", "type": "oidc"}
+				]
+			}`),
+			wantError: true,
+			wantStderr: func(issuerCABundle string, issuerURL string) testutil.RequireErrorStringFunc {
+				return testutil.WantExactErrorString(`Error: no Supervisor upstream identity providers have routing rules which match the login hint "pinny@example.org",` +
+					` so the --upstream-identity-provider-name/--upstream-identity-provider-type flags must be specified.` +
+					` Found these upstreams: [{"name":"some-ldap-idp","type":"ldap","routing":{"email_domains":["example.com"]}},{"name":"some-oidc-idp","type":"oidc"}]` + "\n")
+			},
+		},
+		{
+			name: "supervisor upstream IDP discovery fails to resolve ambiguity when a login hint is specified but matches multiple IDPs",
+			args: func(issuerCABundle string, issuerURL string) []string {
+				f := testutil.WriteStringToTempFile(t, "testca-*.pem", issuerCABundle)
+				return []string{
+					"--kubeconfig", "./testdata/kubeconfig.yaml",
+					"--skip-validation",
+					"--no-concierge",
+					"--oidc-issuer", issuerURL,
+					"--oidc-ca-bundle", f.Name(),
+					"--upstream-identity-provider-login-hint", "pinny@example.com",
+				}
+			},
+			oidcDiscoveryResponse: happyOIDCDiscoveryResponse,
+			idpsDiscoveryResponse: here.Docf(`{
+				"pinniped_identity_providers": [
+					{"name": "some-ldap-idp", "type": "ldap", "routing": {"email_domains": ["example.com"]}},
+					{"name": "some-oidc-idp", "type": "oidc", "routing": {"username_regex": ".*@example\\.com"}}
+				]
+			}`),
+			wantError: true,
+			wantStderr: func(issuerCABundle string, issuerURL string) testutil.RequireErrorStringFunc {
+				return testutil.WantExactErrorString(`Error: multiple Supervisor upstream identity providers have routing rules which match the login hint "pinny@example.com",` +
+					` so the --upstream-identity-provider-name/--upstream-identity-provider-type flags must be specified.` +
+					` Found these upstreams: [{"name":"some-ldap-idp","type":"ldap","routing":{"email_domains":["example.com"]}},{"name":"some-oidc-idp","type":"oidc","routing":{"username_regex":".*@example\\.com"}}]` + "\n")
+			},
+		},
 		{
 			name: "supervisor upstream IDP discovery fails to resolve ambiguity when name is specified but type is not",
 			args: func(issuerCABundle string, issuerURL string) []string {
@@ -2823,6 +2878,129 @@ func TestGetKubeconfig(t *testing.T) {
 					base64.StdEncoding.EncodeToString([]byte(issuerCABundle)))
 			},
 		},
+		{
+			name: "supervisor upstream IDP discovery resolves ambiguity when a login hint is specified but name and type are not",
+			args: func(issuerCABundle string, issuerURL string) []string {
+				f := testutil.WriteStringToTempFile(t, "testca-*.pem", issuerCABundle)
+				return []string{
+					"--kubeconfig", "./testdata/kubeconfig.yaml",
+					"--skip-validation",
+					"--no-concierge",
+					"--oidc-issuer", issuerURL,
+					"--oidc-ca-bundle", f.Name(),
+					"--upstream-identity-provider-login-hint", "pinny@Example.com",
+				}
+			},
+			oidcDiscoveryResponse: happyOIDCDiscoveryResponse,
+			idpsDiscoveryResponse: here.Docf(`{
+				"pinniped_identity_providers": [
+					{"name": "some-ldap-idp", "type": "ldap", "routing": {"email_domains": ["example.com"]}},
+					{"name": "some-oidc-idp", "type": "oidc", "routing": {"username_regex": "admin@.*"}},
+					{"name": "some-other-oidc-idp", "type": "oidc"}
+				]
+			}`),
+			wantStdout: func(issuerCABundle string, issuerURL string) string {
+				return here.Docf(`
+					apiVersion: v1
+					clusters:
+					- cluster:
+						certificate-authority-data: ZmFrZS1jZXJ0aWZpY2F0ZS1hdXRob3JpdHktZGF0YS12YWx1ZQ==
+						server: https://fake-server-url-value
+					  name: kind-cluster-pinniped
+					contexts:
+					- context:
+						cluster: kind-cluster-pinniped
+						user: kind-user-pinniped
+					  name: kind-context-pinniped
+					current-context: kind-context-pinniped
+					kind: Config
+					preferences: {}
+					users:
+					- name: kind-user-pinniped
+					  user:
+						exec:
+						  apiVersion: client.authentication.k8s.io/v1beta1
+						  args:
+						  - login
+						  - oidc
+						  - --issuer=%s
+						  - --client-id=pinniped-cli
+						  - --scopes=offline_access,openid,pinniped:request-audience,username,groups
+						  - --ca-bundle-data=%s
+						  - --upstream-identity-provider-name=some-ldap-idp
+						  - --upstream-identity-provider-type=ldap
+						  command: '.../path/to/pinniped'
+						  env: []
+						  installHint: The pinniped CLI does not appear to be installed.  See https://get.pinniped.dev/cli
+						    for more details
+						  provideClusterInfo: true
+					`,
+					issuerURL,
+					base64.StdEncoding.EncodeToString([]byte(issuerCABundle)))
+			},
+		},
+		{
+			name: "supervisor upstream IDP discovery resolves ambiguity when a login hint and type are specified but name is not",
+			args: func(issuerCABundle string, issuerURL string) []string {
+				f := testutil.WriteStringToTempFile(t, "testca-*.pem", issuerCABundle)
+				return []string{
+					"--kubeconfig", "./testdata/kubeconfig.yaml",
+					"--skip-validation",
+					"--no-concierge",
+					"--oidc-issuer", issuerURL,
+					"--oidc-ca-bundle", f.Name(),
+					"--upstream-identity-provider-type", "ldap",
+					"--upstream-identity-provider-login-hint", "pinny.admin",
+				}
+			},
+			oidcDiscoveryResponse: happyOIDCDiscoveryResponse,
+			idpsDiscoveryResponse: here.Docf(`{
+				"pinniped_identity_providers": [
+					{"name": "some-ldap-idp", "type": "ldap", "routing": {"username_regex": ".*\\.admin"}},
+					{"name": "some-oidc-idp", "type": "oidc", "routing": {"username_regex": ".*\\.admin"}},
+					{"name": "some-other-oidc-idp", "type": "oidc"}
+				]
+			}`),
+			wantStdout: func(issuerCABundle string, issuerURL string) string {
+				return here.Docf(`
+					apiVersion: v1
+					clusters:
+					- cluster:
+						certificate-authority-data: ZmFrZS1jZXJ0aWZpY2F0ZS1hdXRob3JpdHktZGF0YS12YWx1ZQ==
+						server: https://fake-server-url-value
+					  name: kind-cluster-pinniped
+					contexts:
+					- context:
+						cluster: kind-cluster-pinniped
+						user: kind-user-pinniped
+					  name: kind-context-pinniped
+					current-context: kind-context-pinniped
+					kind: Config
+					preferences: {}
+					users:
+					- name: kind-user-pinniped
+					  user:
+						exec:
+						  apiVersion: client.authentication.k8s.io/v1beta1
+						  args:
+						  - login
+						  - oidc
+						  - --issuer=%s
+						  - --client-id=pinniped-cli
+						  - --scopes=offline_access,openid,pinniped:request-audience,username,groups
+						  - --ca-bundle-data=%s
+						  - --upstream-identity-provider-name=some-ldap-idp
+						  - --upstream-identity-provider-type=ldap
+						  command: '.../path/to/pinniped'
+						  env: []
+						  installHint: The pinniped CLI does not appear to be installed.  See https://get.pinniped.dev/cli
+						    for more details
+						  provideClusterInfo: true
+					`,
+					issuerURL,
+					base64.StdEncoding.EncodeToString([]byte(issuerCABundle)))
+			},
+		},
 		{
 			name: "supervisor upstream IDP discovery resolves ambiguity when name is specified but type is not",
 			args: func(issuerCABundle string, issuerURL string) []string {
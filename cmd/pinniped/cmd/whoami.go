@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cmd
@@ -162,6 +162,28 @@ func writeWhoamiOutputText(output io.Writer, clusterInfo *clusterInfo, whoAmI *i
 		Username: %s
 		Groups: %s
 `, clusterInfo.name, clusterInfo.url, whoAmI.Status.KubernetesUserInfo.User.Username, prettyStrings(whoAmI.Status.KubernetesUserInfo.User.Groups)))
+
+	// only reported when the request went through the impersonation proxy
+	if info := whoAmI.Status.AuthenticationInfo; info != nil {
+		fmt.Fprint(output, here.Docf(`
+
+			Current authentication info:
+
+			Impersonation proxy: %t
+`, info.ImpersonationProxy))
+		if info.Authenticator != nil {
+			fmt.Fprintf(output, "Authenticator: %s/%s\n", info.Authenticator.Kind, info.Authenticator.Name)
+		}
+		if info.CredentialExpiration != nil {
+			fmt.Fprintf(output, "Credential expiration: %s\n", info.CredentialExpiration.UTC().Format(time.RFC3339))
+		}
+		if len(info.SupervisorIssuer) != 0 {
+			fmt.Fprintf(output, "Supervisor issuer: %s\n", info.SupervisorIssuer)
+		}
+		if len(info.UpstreamIdentityProviderName) != 0 {
+			fmt.Fprintf(output, "Upstream identity provider: %s\n", info.UpstreamIdentityProviderName)
+		}
+	}
 	return nil
 }
 
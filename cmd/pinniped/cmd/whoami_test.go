@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package cmd
@@ -6,12 +6,16 @@ package cmd
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubetesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/utils/ptr"
 
 	identityv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/identity/v1alpha1"
 	conciergeclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
@@ -25,6 +29,7 @@ func TestWhoami(t *testing.T) {
 		name                   string
 		args                   []string
 		groupsOverride         []string
+		authenticationInfo     *identityv1alpha1.AuthenticationInfo
 		gettingClientsetErr    error
 		callingAPIErr          error
 		wantError              bool
@@ -110,6 +115,60 @@ func TestWhoami(t *testing.T) {
 				Groups: 
 			`),
 		},
+		{
+			name: "text output with authentication info",
+			args: []string{"--kubeconfig", "testdata/kubeconfig.yaml"},
+			authenticationInfo: &identityv1alpha1.AuthenticationInfo{
+				ImpersonationProxy: true,
+				Authenticator: &corev1.TypedLocalObjectReference{
+					APIGroup: ptr.To("authentication.concierge.pinniped.dev"),
+					Kind:     "JWTAuthenticator",
+					Name:     "some-jwt-authenticator",
+				},
+				CredentialExpiration:         ptr.To(metav1.NewTime(time.Date(2026, 4, 13, 9, 57, 0, 0, time.UTC))),
+				SupervisorIssuer:             "https://supervisor.example.com",
+				UpstreamIdentityProviderName: "some-idp",
+			},
+			wantStdout: here.Doc(`
+				Current cluster info:
+
+				Name: kind-cluster
+				URL: https://fake-server-url-value
+
+				Current user info:
+
+				Username: some-username
+				Groups: some-group-0, some-group-1
+
+				Current authentication info:
+
+				Impersonation proxy: true
+				Authenticator: JWTAuthenticator/some-jwt-authenticator
+				Credential expiration: 2026-04-13T09:57:00Z
+				Supervisor issuer: https://supervisor.example.com
+				Upstream identity provider: some-idp
+			`),
+		},
+		{
+			name:               "text output with empty authentication info",
+			args:               []string{"--kubeconfig", "testdata/kubeconfig.yaml"},
+			authenticationInfo: &identityv1alpha1.AuthenticationInfo{ImpersonationProxy: true},
+			wantStdout: here.Doc(`
+				Current cluster info:
+
+				Name: kind-cluster
+				URL: https://fake-server-url-value
+
+				Current user info:
+
+				Username: some-username
+				Groups: some-group-0, some-group-1
+
+				Current authentication info:
+
+				Impersonation proxy: true
+			`),
+		},
 		{
 			name: "json output",
 			args: []string{"--kubeconfig", "testdata/kubeconfig.yaml", "-o", "json"},
@@ -303,6 +362,7 @@ func TestWhoami(t *testing.T) {
 									Groups:   groups,
 								},
 							},
+							AuthenticationInfo: test.authenticationInfo,
 						},
 					}, nil
 				})
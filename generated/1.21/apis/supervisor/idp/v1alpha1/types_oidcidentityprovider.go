@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -93,6 +93,18 @@ type OIDCAuthorizationConfig struct {
 	// +listMapKey=name
 	AdditionalAuthorizeParameters []Parameter `json:"additionalAuthorizeParameters,omitempty"`
 
+	// passthroughAuthorizeParameters are the names of parameters of the downstream authorization request which will be
+	// copied into the authorization request to your OIDC provider during an OIDC Authorization Code Flow, when the
+	// client which started the login sent them. For example, passing through "login_hint" allows your OIDC provider to
+	// prefill the username on its login page, so users do not need to type it again. The allowed names are
+	// "login_hint", "domain_hint", "prompt", "ui_locales", and "display". A parameter name cannot be included in both
+	// this setting and the additionalAuthorizeParameters setting. By default, no downstream parameters are copied.
+	// Note that the "prompt" parameter may affect whether your OIDC provider returns refresh tokens, which the Pinniped
+	// Supervisor requires. Invalid names are reported by the AdditionalAuthorizeParametersValid condition.
+	// +optional
+	// +listType=set
+	PassthroughAuthorizeParameters []string `json:"passthroughAuthorizeParameters,omitempty"`
+
 	// allowPasswordGrant, when true, will allow the use of OAuth 2.0's Resource Owner Password Credentials Grant
 	// (see https://datatracker.ietf.org/doc/html/rfc6749#section-4.3) to authenticate to the OIDC provider using a
 	// username and password without a web browser, in addition to the usual browser-based OIDC Authorization Code Flow.
@@ -149,6 +161,15 @@ type OIDCClaims struct {
 	// are not available, the "additionalClaims" claim will be excluded from the ID tokens generated by the Supervisor.
 	// +optional
 	AdditionalClaimMappings map[string]string `json:"additionalClaimMappings,omitempty"`
+
+	// AdditionalClaimsForTransformations provides the names of other ID token claims or userinfo endpoint response
+	// claims which should be made available to the FederationDomain's identity transformation expressions as the
+	// upstreamClaims variable, e.g. "email_verified" or "acr". Claims which are not present for a user will not be
+	// present in upstreamClaims. The values are read only during the user's initial login, and are reused during
+	// refreshes. When this list is empty, no upstream claims are made available to identity transformation expressions.
+	// +optional
+	// +listType=set
+	AdditionalClaimsForTransformations []string `json:"additionalClaimsForTransformations,omitempty"`
 }
 
 // OIDCClient contains information about an OIDC client (e.g., client ID and client
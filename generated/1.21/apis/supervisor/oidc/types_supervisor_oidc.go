@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidc
@@ -15,6 +15,16 @@ const (
 	// or an LDAPIdentityProvider.
 	AuthorizePasswordHeaderName = "Pinniped-Password" //nolint:gosec // this is not a credential
 
+	// AuthorizeOneTimePasswordHeaderName is the name of the HTTP header which can be used to transmit a one-time
+	// password to the authorize endpoint when using a password flow, when the FederationDomain requires a TOTP
+	// second factor for the identity provider.
+	AuthorizeOneTimePasswordHeaderName = "Pinniped-One-Time-Password" //nolint:gosec // this is not a credential
+
+	// AuthorizeErrorOneTimePasswordRequired is the value of the error param returned by the authorize endpoint
+	// when using a password flow, when the request must be sent again with a one-time password
+	// in the AuthorizeOneTimePasswordHeaderName header.
+	AuthorizeErrorOneTimePasswordRequired = "pinniped_one_time_password_required" //nolint:gosec // this is not a credential
+
 	// AuthorizeUpstreamIDPNameParamName is the name of the HTTP request parameter which can be used to help select
 	// which identity provider should be used for authentication by sending the name of the desired identity provider.
 	AuthorizeUpstreamIDPNameParamName = "pinniped_idp_name"
@@ -33,6 +43,10 @@ const (
 	// in Supervisor-issued ID tokens to identify with which external identity provider the user authenticated.
 	IDTokenSubClaimIDPNameQueryParam = "idpName"
 
+	// IDTokenSubClaimClientIDQueryParam is the name of the query param used in the values of the "sub" claim
+	// in Supervisor-issued ID tokens to identify the client when the tokens were issued by the client_credentials grant.
+	IDTokenSubClaimClientIDQueryParam = "clientID"
+
 	// IDTokenClaimAuthorizedParty is name of the authorized party claim defined by the OIDC spec.
 	IDTokenClaimAuthorizedParty = "azp"
 
@@ -57,6 +71,9 @@ const (
 	// GrantTypeTokenExchange is the name of a custom grant type for RFC8693 token exchanges.
 	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange" //nolint:gosec // this is not a credential
 
+	// GrantTypeClientCredentials is the name of the grant type for client credentials flows defined by RFC6749.
+	GrantTypeClientCredentials = "client_credentials"
+
 	// ScopeOpenID is name of the openid scope defined by the OIDC spec.
 	ScopeOpenID = "openid"
 
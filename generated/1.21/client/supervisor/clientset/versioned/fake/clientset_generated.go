@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by client-gen. DO NOT EDIT.
@@ -13,6 +13,8 @@ import (
 	fakeconfigv1alpha1 "go.pinniped.dev/generated/1.21/client/supervisor/clientset/versioned/typed/config/v1alpha1/fake"
 	idpv1alpha1 "go.pinniped.dev/generated/1.21/client/supervisor/clientset/versioned/typed/idp/v1alpha1"
 	fakeidpv1alpha1 "go.pinniped.dev/generated/1.21/client/supervisor/clientset/versioned/typed/idp/v1alpha1/fake"
+	transformationv1alpha1 "go.pinniped.dev/generated/1.21/client/supervisor/clientset/versioned/typed/transformation/v1alpha1"
+	faketransformationv1alpha1 "go.pinniped.dev/generated/1.21/client/supervisor/clientset/versioned/typed/transformation/v1alpha1/fake"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/discovery"
@@ -81,3 +83,8 @@ func (c *Clientset) ConfigV1alpha1() configv1alpha1.ConfigV1alpha1Interface {
 func (c *Clientset) IDPV1alpha1() idpv1alpha1.IDPV1alpha1Interface {
 	return &fakeidpv1alpha1.FakeIDPV1alpha1{Fake: &c.Fake}
 }
+
+// TransformationV1alpha1 retrieves the TransformationV1alpha1Client
+func (c *Clientset) TransformationV1alpha1() transformationv1alpha1.TransformationV1alpha1Interface {
+	return &faketransformationv1alpha1.FakeTransformationV1alpha1{Fake: &c.Fake}
+}
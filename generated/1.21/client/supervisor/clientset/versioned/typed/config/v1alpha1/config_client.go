@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by client-gen. DO NOT EDIT.
@@ -14,6 +14,7 @@ import (
 type ConfigV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	FederationDomainsGetter
+	IdentityTransformationLibrariesGetter
 	OIDCClientsGetter
 }
 
@@ -26,6 +27,10 @@ func (c *ConfigV1alpha1Client) FederationDomains(namespace string) FederationDom
 	return newFederationDomains(c, namespace)
 }
 
+func (c *ConfigV1alpha1Client) IdentityTransformationLibraries() IdentityTransformationLibraryInterface {
+	return newIdentityTransformationLibraries(c)
+}
+
 func (c *ConfigV1alpha1Client) OIDCClients(namespace string) OIDCClientInterface {
 	return newOIDCClients(c, namespace)
 }
@@ -1,7 +1,7 @@
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by deepcopy-gen. DO NOT EDIT.
@@ -12,6 +12,55 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerIssuerReference) DeepCopyInto(out *CertManagerIssuerReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerIssuerReference.
+func (in *CertManagerIssuerReference) DeepCopy() *CertManagerIssuerReference {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerIssuerReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertManagerSignerSpec) DeepCopyInto(out *CertManagerSignerSpec) {
+	*out = *in
+	out.IssuerRef = in.IssuerRef
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertManagerSignerSpec.
+func (in *CertManagerSignerSpec) DeepCopy() *CertManagerSignerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertManagerSignerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateSigningRequestSignerSpec) DeepCopyInto(out *CertificateSigningRequestSignerSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateSigningRequestSignerSpec.
+func (in *CertificateSigningRequestSignerSpec) DeepCopy() *CertificateSigningRequestSignerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateSigningRequestSignerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CredentialIssuer) DeepCopyInto(out *CredentialIssuer) {
 	*out = *in
@@ -123,6 +172,16 @@ func (in *CredentialIssuerSpec) DeepCopyInto(out *CredentialIssuerSpec) {
 		*out = new(ImpersonationProxySpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.ExternalSigner != nil {
+		in, out := &in.ExternalSigner, &out.ExternalSigner
+		*out = new(ExternalSignerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagedClientCA != nil {
+		in, out := &in.ManagedClientCA, &out.ManagedClientCA
+		*out = new(ManagedClientCASpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -186,6 +245,32 @@ func (in *CredentialIssuerStrategy) DeepCopy() *CredentialIssuerStrategy {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalSignerSpec) DeepCopyInto(out *ExternalSignerSpec) {
+	*out = *in
+	if in.CertificateSigningRequest != nil {
+		in, out := &in.CertificateSigningRequest, &out.CertificateSigningRequest
+		*out = new(CertificateSigningRequestSignerSpec)
+		**out = **in
+	}
+	if in.CertManager != nil {
+		in, out := &in.CertManager, &out.CertManager
+		*out = new(CertManagerSignerSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalSignerSpec.
+func (in *ExternalSignerSpec) DeepCopy() *ExternalSignerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalSignerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImpersonationProxyInfo) DeepCopyInto(out *ImpersonationProxyInfo) {
 	*out = *in
@@ -202,6 +287,91 @@ func (in *ImpersonationProxyInfo) DeepCopy() *ImpersonationProxyInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyPolicy) DeepCopyInto(out *ImpersonationProxyPolicy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ImpersonationProxyPolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyPolicy.
+func (in *ImpersonationProxyPolicy) DeepCopy() *ImpersonationProxyPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyPolicyRule) DeepCopyInto(out *ImpersonationProxyPolicyRule) {
+	*out = *in
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.APIGroups != nil {
+		in, out := &in.APIGroups, &out.APIGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(ImpersonationProxyRateLimit)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyPolicyRule.
+func (in *ImpersonationProxyPolicyRule) DeepCopy() *ImpersonationProxyPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyRateLimit) DeepCopyInto(out *ImpersonationProxyRateLimit) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyRateLimit.
+func (in *ImpersonationProxyRateLimit) DeepCopy() *ImpersonationProxyRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImpersonationProxyServiceSpec) DeepCopyInto(out *ImpersonationProxyServiceSpec) {
 	*out = *in
@@ -234,6 +404,11 @@ func (in *ImpersonationProxySpec) DeepCopyInto(out *ImpersonationProxySpec) {
 		*out = new(ImpersonationProxyTLSSpec)
 		**out = **in
 	}
+	if in.Policy != nil {
+		in, out := &in.Policy, &out.Policy
+		*out = new(ImpersonationProxyPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -263,6 +438,32 @@ func (in *ImpersonationProxyTLSSpec) DeepCopy() *ImpersonationProxyTLSSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedClientCASpec) DeepCopyInto(out *ManagedClientCASpec) {
+	*out = *in
+	if in.DurationSeconds != nil {
+		in, out := &in.DurationSeconds, &out.DurationSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.RotationOverlapSeconds != nil {
+		in, out := &in.RotationOverlapSeconds, &out.RotationOverlapSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedClientCASpec.
+func (in *ManagedClientCASpec) DeepCopy() *ManagedClientCASpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedClientCASpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TokenCredentialRequestAPIInfo) DeepCopyInto(out *TokenCredentialRequestAPIInfo) {
 	*out = *in
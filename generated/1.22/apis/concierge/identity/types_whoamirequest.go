@@ -1,9 +1,10 @@
-// Copyright 2021-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package identity
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -28,7 +29,36 @@ type WhoAmIRequestStatus struct {
 	// The current authenticated user, exactly as Kubernetes understands it.
 	KubernetesUserInfo KubernetesUserInfo
 
-	// We may add concierge specific information here in the future.
+	// AuthenticationInfo describes how the current user authenticated, as far as the Concierge can observe it.
+	// It is only set when the request was made through the impersonation proxy, because the Kubernetes API
+	// server does not tell the Concierge anything about the credential which was used to authenticate.
+	// +optional
+	AuthenticationInfo *AuthenticationInfo
+}
+
+// AuthenticationInfo describes how the current user authenticated.
+type AuthenticationInfo struct {
+	// ImpersonationProxy is true when the request was made through the Concierge impersonation proxy.
+	ImpersonationProxy bool
+
+	// Authenticator is the Concierge authenticator which authenticated the token that the user exchanged
+	// for their current credential using the TokenCredentialRequest API.
+	// +optional
+	Authenticator *corev1.TypedLocalObjectReference
+
+	// CredentialExpiration is the time at which the current credential expires.
+	// +optional
+	CredentialExpiration *metav1.Time
+
+	// SupervisorIssuer is the issuer of the Pinniped Supervisor FederationDomain which issued the token
+	// that the user exchanged for their current credential.
+	// +optional
+	SupervisorIssuer string
+
+	// UpstreamIdentityProviderName is the name of the identity provider, as configured on the Supervisor
+	// FederationDomain, with which the user logged in to get the token that they exchanged for their current credential.
+	// +optional
+	UpstreamIdentityProviderName string
 }
 
 // WhoAmIRequestList is a list of WhoAmIRequest objects.
@@ -1,7 +1,7 @@
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by conversion-gen. DO NOT EDIT.
@@ -12,6 +12,8 @@ import (
 	unsafe "unsafe"
 
 	identity "go.pinniped.dev/generated/1.23/apis/concierge/identity"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	conversion "k8s.io/apimachinery/pkg/conversion"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -23,6 +25,16 @@ func init() {
 // RegisterConversions adds conversion functions to the given scheme.
 // Public to allow building arbitrary schemes.
 func RegisterConversions(s *runtime.Scheme) error {
+	if err := s.AddGeneratedConversionFunc((*AuthenticationInfo)(nil), (*identity.AuthenticationInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v1alpha1_AuthenticationInfo_To_identity_AuthenticationInfo(a.(*AuthenticationInfo), b.(*identity.AuthenticationInfo), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddGeneratedConversionFunc((*identity.AuthenticationInfo)(nil), (*AuthenticationInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_identity_AuthenticationInfo_To_v1alpha1_AuthenticationInfo(a.(*identity.AuthenticationInfo), b.(*AuthenticationInfo), scope)
+	}); err != nil {
+		return err
+	}
 	if err := s.AddGeneratedConversionFunc((*KubernetesUserInfo)(nil), (*identity.KubernetesUserInfo)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1alpha1_KubernetesUserInfo_To_identity_KubernetesUserInfo(a.(*KubernetesUserInfo), b.(*identity.KubernetesUserInfo), scope)
 	}); err != nil {
@@ -86,6 +98,34 @@ func RegisterConversions(s *runtime.Scheme) error {
 	return nil
 }
 
+func autoConvert_v1alpha1_AuthenticationInfo_To_identity_AuthenticationInfo(in *AuthenticationInfo, out *identity.AuthenticationInfo, s conversion.Scope) error {
+	out.ImpersonationProxy = in.ImpersonationProxy
+	out.Authenticator = (*v1.TypedLocalObjectReference)(unsafe.Pointer(in.Authenticator))
+	out.CredentialExpiration = (*metav1.Time)(unsafe.Pointer(in.CredentialExpiration))
+	out.SupervisorIssuer = in.SupervisorIssuer
+	out.UpstreamIdentityProviderName = in.UpstreamIdentityProviderName
+	return nil
+}
+
+// Convert_v1alpha1_AuthenticationInfo_To_identity_AuthenticationInfo is an autogenerated conversion function.
+func Convert_v1alpha1_AuthenticationInfo_To_identity_AuthenticationInfo(in *AuthenticationInfo, out *identity.AuthenticationInfo, s conversion.Scope) error {
+	return autoConvert_v1alpha1_AuthenticationInfo_To_identity_AuthenticationInfo(in, out, s)
+}
+
+func autoConvert_identity_AuthenticationInfo_To_v1alpha1_AuthenticationInfo(in *identity.AuthenticationInfo, out *AuthenticationInfo, s conversion.Scope) error {
+	out.ImpersonationProxy = in.ImpersonationProxy
+	out.Authenticator = (*v1.TypedLocalObjectReference)(unsafe.Pointer(in.Authenticator))
+	out.CredentialExpiration = (*metav1.Time)(unsafe.Pointer(in.CredentialExpiration))
+	out.SupervisorIssuer = in.SupervisorIssuer
+	out.UpstreamIdentityProviderName = in.UpstreamIdentityProviderName
+	return nil
+}
+
+// Convert_identity_AuthenticationInfo_To_v1alpha1_AuthenticationInfo is an autogenerated conversion function.
+func Convert_identity_AuthenticationInfo_To_v1alpha1_AuthenticationInfo(in *identity.AuthenticationInfo, out *AuthenticationInfo, s conversion.Scope) error {
+	return autoConvert_identity_AuthenticationInfo_To_v1alpha1_AuthenticationInfo(in, out, s)
+}
+
 func autoConvert_v1alpha1_KubernetesUserInfo_To_identity_KubernetesUserInfo(in *KubernetesUserInfo, out *identity.KubernetesUserInfo, s conversion.Scope) error {
 	if err := Convert_v1alpha1_UserInfo_To_identity_UserInfo(&in.User, &out.User, s); err != nil {
 		return err
@@ -214,6 +254,7 @@ func autoConvert_v1alpha1_WhoAmIRequestStatus_To_identity_WhoAmIRequestStatus(in
 	if err := Convert_v1alpha1_KubernetesUserInfo_To_identity_KubernetesUserInfo(&in.KubernetesUserInfo, &out.KubernetesUserInfo, s); err != nil {
 		return err
 	}
+	out.AuthenticationInfo = (*identity.AuthenticationInfo)(unsafe.Pointer(in.AuthenticationInfo))
 	return nil
 }
 
@@ -226,6 +267,7 @@ func autoConvert_identity_WhoAmIRequestStatus_To_v1alpha1_WhoAmIRequestStatus(in
 	if err := Convert_identity_KubernetesUserInfo_To_v1alpha1_KubernetesUserInfo(&in.KubernetesUserInfo, &out.KubernetesUserInfo, s); err != nil {
 		return err
 	}
+	out.AuthenticationInfo = (*AuthenticationInfo)(unsafe.Pointer(in.AuthenticationInfo))
 	return nil
 }
 
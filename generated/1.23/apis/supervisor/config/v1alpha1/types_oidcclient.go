@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -21,12 +21,29 @@ const (
 // +kubebuilder:validation:Pattern=`^https://.+|^http://(127\.0\.0\.1|\[::1\])(:\d+)?/`
 type RedirectURI string
 
-// +kubebuilder:validation:Enum="authorization_code";"refresh_token";"urn:ietf:params:oauth:grant-type:token-exchange"
+// +kubebuilder:validation:Enum="authorization_code";"refresh_token";"urn:ietf:params:oauth:grant-type:token-exchange";"client_credentials"
 type GrantType string
 
 // +kubebuilder:validation:Enum="openid";"offline_access";"username";"groups";"pinniped:request-audience"
 type Scope string
 
+// +kubebuilder:validation:Enum="client_secret_basic";"private_key_jwt";"tls_client_auth"
+type ClientAuthenticationMethod string
+
+const (
+	// ClientAuthenticationMethodClientSecretBasic is the client authentication method which uses HTTP basic auth
+	// with a client secret.
+	ClientAuthenticationMethodClientSecretBasic ClientAuthenticationMethod = "client_secret_basic"
+
+	// ClientAuthenticationMethodPrivateKeyJWT is the client authentication method which uses a JWT signed by the
+	// private key of the client.
+	ClientAuthenticationMethodPrivateKeyJWT ClientAuthenticationMethod = "private_key_jwt"
+
+	// ClientAuthenticationMethodTLSClientAuth is the client authentication method which uses a client certificate
+	// issued by a certificate authority.
+	ClientAuthenticationMethodTLSClientAuth ClientAuthenticationMethod = "tls_client_auth"
+)
+
 // OIDCClientSpec is a struct that describes an OIDCClient.
 type OIDCClientSpec struct {
 	// allowedRedirectURIs is a list of the allowed redirect_uri param values that should be accepted during OIDC flows with this
@@ -48,6 +65,8 @@ type OIDCClientSpec struct {
 	// - urn:ietf:params:oauth:grant-type:token-exchange: allows the client to perform RFC8693 token exchange,
 	//   which is a step in the process to be able to get a cluster credential for the user.
 	//   This grant must be listed if allowedScopes lists pinniped:request-audience.
+	// - client_credentials: allows the client to authenticate as itself, using the identity configured by serviceIdentity,
+	//   without any user login, e.g. for use by automated systems. This grant must be listed if serviceIdentity is set.
 	// +listType=set
 	// +kubebuilder:validation:MinItems=1
 	AllowedGrantTypes []GrantType `json:"allowedGrantTypes"`
@@ -71,6 +90,139 @@ type OIDCClientSpec struct {
 	// +listType=set
 	// +kubebuilder:validation:MinItems=1
 	AllowedScopes []Scope `json:"allowedScopes"`
+
+	// serviceIdentity is the identity of the client itself, which is used for the tokens issued by the client_credentials
+	// grant. Like the identity of a user, it is included in ID tokens when the username and groups scopes are requested,
+	// and it may be exchanged for cluster-scoped ID tokens using RFC8693 token exchange.
+	// This must be set if allowedGrantTypes lists client_credentials.
+	// +optional
+	ServiceIdentity *OIDCClientServiceIdentity `json:"serviceIdentity,omitempty"`
+
+	// clientAuthentication configures how the client authenticates itself to the token endpoint.
+	// When not set, the client must use HTTP basic auth with one of the client secrets which were generated
+	// by the OIDCClientSecretRequest API.
+	// +optional
+	ClientAuthentication *OIDCClientAuthentication `json:"clientAuthentication,omitempty"`
+
+	// consent configures whether users must approve the scopes which are requested by this client before the
+	// Supervisor issues tokens to this client. When not set, all requested scopes are approved automatically.
+	// +optional
+	Consent *OIDCClientConsent `json:"consent,omitempty"`
+
+	// requirePushedAuthorizationRequests means that this client must push the params of its authorization requests
+	// to the pushed authorization request endpoint (RFC9126) before sending the user to the authorization endpoint.
+	// The authorization endpoint will reject any authorization request for this client which does not use the
+	// request_uri param that was returned by the pushed authorization request endpoint.
+	// +optional
+	RequirePushedAuthorizationRequests bool `json:"requirePushedAuthorizationRequests,omitempty"`
+}
+
+// OIDCClientConsent describes the consent page which is shown to users who log in using an OIDCClient.
+type OIDCClientConsent struct {
+	// required means that after users log in, they are shown a consent page which lists the requested scopes,
+	// and they must approve those scopes before the Supervisor issues tokens to this client. The approval of each
+	// user is remembered, so the consent page is only shown again when the client requests additional scopes,
+	// when the remembered approval expires, or when the client sends the prompt=consent param.
+	// +optional
+	Required bool `json:"required,omitempty"`
+
+	// displayName is the name of the client which is shown to users on the consent page.
+	// Defaults to the name of this OIDCClient.
+	// +kubebuilder:validation:MaxLength=128
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// OIDCClientAuthentication describes how an OIDCClient authenticates itself to the token endpoint.
+type OIDCClientAuthentication struct {
+	// method is the client authentication method.
+	//
+	// Must be one of the following values:
+	// - client_secret_basic: The client uses HTTP basic auth with one of the client secrets which were generated by
+	//   the OIDCClientSecretRequest API.
+	// - private_key_jwt: The client sends a JWT signed by its own private key, as described in RFC7523 and in the
+	//   OpenID Connect Core specification. The corresponding public keys are configured by privateKeyJWT.
+	//   Each JWT may only be used once.
+	// - tls_client_auth: The client presents a client certificate, as described in RFC8705. The certificate
+	//   authority and the subject of the client certificate are configured by tlsClientAuth. The client must
+	//   also send its client ID using the client_id param. This method requires that the HTTPS listener of the
+	//   Supervisor is configured to request client certificates, and that any load balancer or ingress in front of
+	//   the Supervisor passes through TLS connections without terminating them.
+	// Client secrets are not required for the private_key_jwt and tls_client_auth methods.
+	// +kubebuilder:default="client_secret_basic"
+	// +optional
+	Method ClientAuthenticationMethod `json:"method,omitempty"`
+
+	// privateKeyJWT configures the private_key_jwt client authentication method.
+	// This must be set if method is private_key_jwt.
+	// +optional
+	PrivateKeyJWT *OIDCClientPrivateKeyJWT `json:"privateKeyJWT,omitempty"`
+
+	// tlsClientAuth configures the tls_client_auth client authentication method.
+	// This must be set if method is tls_client_auth.
+	// +optional
+	TLSClientAuth *OIDCClientTLSClientAuth `json:"tlsClientAuth,omitempty"`
+}
+
+// OIDCClientPrivateKeyJWT describes the public keys of an OIDCClient which uses the private_key_jwt client
+// authentication method. Exactly one of jwks or jwksURL must be set.
+type OIDCClientPrivateKeyJWT struct {
+	// jwks is a JSON Web Key Set (RFC7517) which contains the public keys of the client, encoded as JSON.
+	// Only keys with "use" set to "sig" will be used to verify the signatures of the client's JWTs.
+	// +optional
+	JWKS string `json:"jwks,omitempty"`
+
+	// jwksURL is the HTTPS URL of a JSON Web Key Set (RFC7517) which contains the public keys of the client.
+	// Only keys with "use" set to "sig" will be used to verify the signatures of the client's JWTs.
+	// The JSON Web Key Set is cached by the Supervisor, and it is fetched again when a JWT is signed by an unknown key.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	JWKSURL string `json:"jwksURL,omitempty"`
+
+	// signingAlgorithm is the algorithm which the client must use to sign its JWTs.
+	// +kubebuilder:validation:Enum=RS256;RS384;RS512;PS256;PS384;PS512;ES256;ES384;ES512
+	// +kubebuilder:default=RS256
+	// +optional
+	SigningAlgorithm string `json:"signingAlgorithm,omitempty"`
+}
+
+// OIDCClientTLSClientAuth describes the client certificates of an OIDCClient which uses the tls_client_auth client
+// authentication method. Exactly one of subjectDN, sanDNS, sanURI, or sanEmail must be set.
+type OIDCClientTLSClientAuth struct {
+	// certificateAuthorityData is the base64-encoded PEM bundle of the certificate authorities which issue the
+	// client certificates of the client.
+	// +kubebuilder:validation:MinLength=1
+	CertificateAuthorityData string `json:"certificateAuthorityData"`
+
+	// subjectDN is the expected subject distinguished name of the client certificate, in the string representation
+	// of RFC4514, e.g. "CN=my-client,O=my-org".
+	// +optional
+	SubjectDN string `json:"subjectDN,omitempty"`
+
+	// sanDNS is a DNS name which is expected to be one of the subject alternative names of the client certificate.
+	// +optional
+	SANDNS string `json:"sanDNS,omitempty"`
+
+	// sanURI is a URI which is expected to be one of the subject alternative names of the client certificate.
+	// +optional
+	SANURI string `json:"sanURI,omitempty"`
+
+	// sanEmail is an email address which is expected to be one of the subject alternative names of the
+	// client certificate.
+	// +optional
+	SANEmail string `json:"sanEmail,omitempty"`
+}
+
+// OIDCClientServiceIdentity describes the identity of an OIDCClient which uses the client_credentials grant.
+type OIDCClientServiceIdentity struct {
+	// username is the username of the client.
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// groups are the group memberships of the client.
+	// +listType=set
+	// +optional
+	Groups []string `json:"groups,omitempty"`
 }
 
 // OIDCClientStatus is a struct that describes the actual state of an OIDCClient.
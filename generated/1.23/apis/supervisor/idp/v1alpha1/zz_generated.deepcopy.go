@@ -1,7 +1,7 @@
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by deepcopy-gen. DO NOT EDIT.
@@ -61,6 +61,11 @@ func (in *ActiveDirectoryIdentityProviderBind) DeepCopy() *ActiveDirectoryIdenti
 func (in *ActiveDirectoryIdentityProviderGroupSearch) DeepCopyInto(out *ActiveDirectoryIdentityProviderGroupSearch) {
 	*out = *in
 	out.Attributes = in.Attributes
+	if in.RefreshCacheSeconds != nil {
+		in, out := &in.RefreshCacheSeconds, &out.RefreshCacheSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -90,6 +95,22 @@ func (in *ActiveDirectoryIdentityProviderGroupSearchAttributes) DeepCopy() *Acti
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ActiveDirectoryIdentityProviderHostDiscovery) DeepCopyInto(out *ActiveDirectoryIdentityProviderHostDiscovery) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ActiveDirectoryIdentityProviderHostDiscovery.
+func (in *ActiveDirectoryIdentityProviderHostDiscovery) DeepCopy() *ActiveDirectoryIdentityProviderHostDiscovery {
+	if in == nil {
+		return nil
+	}
+	out := new(ActiveDirectoryIdentityProviderHostDiscovery)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActiveDirectoryIdentityProviderList) DeepCopyInto(out *ActiveDirectoryIdentityProviderList) {
 	*out = *in
@@ -126,14 +147,24 @@ func (in *ActiveDirectoryIdentityProviderList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActiveDirectoryIdentityProviderSpec) DeepCopyInto(out *ActiveDirectoryIdentityProviderSpec) {
 	*out = *in
+	if in.AdditionalHosts != nil {
+		in, out := &in.AdditionalHosts, &out.AdditionalHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostDiscovery != nil {
+		in, out := &in.HostDiscovery, &out.HostDiscovery
+		*out = new(ActiveDirectoryIdentityProviderHostDiscovery)
+		**out = **in
+	}
 	if in.TLS != nil {
 		in, out := &in.TLS, &out.TLS
 		*out = new(TLSSpec)
 		**out = **in
 	}
 	out.Bind = in.Bind
-	out.UserSearch = in.UserSearch
-	out.GroupSearch = in.GroupSearch
+	in.UserSearch.DeepCopyInto(&out.UserSearch)
+	in.GroupSearch.DeepCopyInto(&out.GroupSearch)
 	return
 }
 
@@ -173,7 +204,7 @@ func (in *ActiveDirectoryIdentityProviderStatus) DeepCopy() *ActiveDirectoryIden
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActiveDirectoryIdentityProviderUserSearch) DeepCopyInto(out *ActiveDirectoryIdentityProviderUserSearch) {
 	*out = *in
-	out.Attributes = in.Attributes
+	in.Attributes.DeepCopyInto(&out.Attributes)
 	return
 }
 
@@ -190,6 +221,11 @@ func (in *ActiveDirectoryIdentityProviderUserSearch) DeepCopy() *ActiveDirectory
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ActiveDirectoryIdentityProviderUserSearchAttributes) DeepCopyInto(out *ActiveDirectoryIdentityProviderUserSearchAttributes) {
 	*out = *in
+	if in.AdditionalAttributes != nil {
+		in, out := &in.AdditionalAttributes, &out.AdditionalAttributes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -251,6 +287,11 @@ func (in *LDAPIdentityProviderBind) DeepCopy() *LDAPIdentityProviderBind {
 func (in *LDAPIdentityProviderGroupSearch) DeepCopyInto(out *LDAPIdentityProviderGroupSearch) {
 	*out = *in
 	out.Attributes = in.Attributes
+	if in.RefreshCacheSeconds != nil {
+		in, out := &in.RefreshCacheSeconds, &out.RefreshCacheSeconds
+		*out = new(int32)
+		**out = **in
+	}
 	return
 }
 
@@ -316,14 +357,19 @@ func (in *LDAPIdentityProviderList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LDAPIdentityProviderSpec) DeepCopyInto(out *LDAPIdentityProviderSpec) {
 	*out = *in
+	if in.AdditionalHosts != nil {
+		in, out := &in.AdditionalHosts, &out.AdditionalHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.TLS != nil {
 		in, out := &in.TLS, &out.TLS
 		*out = new(TLSSpec)
 		**out = **in
 	}
 	out.Bind = in.Bind
-	out.UserSearch = in.UserSearch
-	out.GroupSearch = in.GroupSearch
+	in.UserSearch.DeepCopyInto(&out.UserSearch)
+	in.GroupSearch.DeepCopyInto(&out.GroupSearch)
 	return
 }
 
@@ -363,7 +409,7 @@ func (in *LDAPIdentityProviderStatus) DeepCopy() *LDAPIdentityProviderStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LDAPIdentityProviderUserSearch) DeepCopyInto(out *LDAPIdentityProviderUserSearch) {
 	*out = *in
-	out.Attributes = in.Attributes
+	in.Attributes.DeepCopyInto(&out.Attributes)
 	return
 }
 
@@ -380,6 +426,11 @@ func (in *LDAPIdentityProviderUserSearch) DeepCopy() *LDAPIdentityProviderUserSe
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LDAPIdentityProviderUserSearchAttributes) DeepCopyInto(out *LDAPIdentityProviderUserSearchAttributes) {
 	*out = *in
+	if in.AdditionalAttributes != nil {
+		in, out := &in.AdditionalAttributes, &out.AdditionalAttributes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -406,6 +457,11 @@ func (in *OIDCAuthorizationConfig) DeepCopyInto(out *OIDCAuthorizationConfig) {
 		*out = make([]Parameter, len(*in))
 		copy(*out, *in)
 	}
+	if in.PassthroughAuthorizeParameters != nil {
+		in, out := &in.PassthroughAuthorizeParameters, &out.PassthroughAuthorizeParameters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -429,6 +485,11 @@ func (in *OIDCClaims) DeepCopyInto(out *OIDCClaims) {
 			(*out)[key] = val
 		}
 	}
+	if in.AdditionalClaimsForTransformations != nil {
+		in, out := &in.AdditionalClaimsForTransformations, &out.AdditionalClaimsForTransformations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -1,7 +1,7 @@
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by openapi-gen. DO NOT EDIT.
@@ -18,6 +18,7 @@ import (
 
 func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenAPIDefinition {
 	return map[string]common.OpenAPIDefinition{
+		"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.AuthenticationInfo":        schema_apis_concierge_identity_v1alpha1_AuthenticationInfo(ref),
 		"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.KubernetesUserInfo":        schema_apis_concierge_identity_v1alpha1_KubernetesUserInfo(ref),
 		"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.UserInfo":                  schema_apis_concierge_identity_v1alpha1_UserInfo(ref),
 		"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.WhoAmIRequest":             schema_apis_concierge_identity_v1alpha1_WhoAmIRequest(ref),
@@ -293,6 +294,56 @@ func GetOpenAPIDefinitions(ref common.ReferenceCallback) map[string]common.OpenA
 	}
 }
 
+func schema_apis_concierge_identity_v1alpha1_AuthenticationInfo(ref common.ReferenceCallback) common.OpenAPIDefinition {
+	return common.OpenAPIDefinition{
+		Schema: spec.Schema{
+			SchemaProps: spec.SchemaProps{
+				Description: "AuthenticationInfo describes how the current user authenticated.",
+				Type:        []string{"object"},
+				Properties: map[string]spec.Schema{
+					"impersonationProxy": {
+						SchemaProps: spec.SchemaProps{
+							Description: "ImpersonationProxy is true when the request was made through the Concierge impersonation proxy.",
+							Default:     false,
+							Type:        []string{"boolean"},
+							Format:      "",
+						},
+					},
+					"authenticator": {
+						SchemaProps: spec.SchemaProps{
+							Description: "Authenticator is the Concierge authenticator which authenticated the token that the user exchanged for their current credential using the TokenCredentialRequest API.",
+							Ref:         ref("k8s.io/api/core/v1.TypedLocalObjectReference"),
+						},
+					},
+					"credentialExpiration": {
+						SchemaProps: spec.SchemaProps{
+							Description: "CredentialExpiration is the time at which the current credential expires.",
+							Ref:         ref("k8s.io/apimachinery/pkg/apis/meta/v1.Time"),
+						},
+					},
+					"supervisorIssuer": {
+						SchemaProps: spec.SchemaProps{
+							Description: "SupervisorIssuer is the issuer of the Pinniped Supervisor FederationDomain which issued the token that the user exchanged for their current credential.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+					"upstreamIdentityProviderName": {
+						SchemaProps: spec.SchemaProps{
+							Description: "UpstreamIdentityProviderName is the name of the identity provider, as configured on the Supervisor FederationDomain, with which the user logged in to get the token that they exchanged for their current credential.",
+							Type:        []string{"string"},
+							Format:      "",
+						},
+					},
+				},
+				Required: []string{"impersonationProxy"},
+			},
+		},
+		Dependencies: []string{
+			"k8s.io/api/core/v1.TypedLocalObjectReference", "k8s.io/apimachinery/pkg/apis/meta/v1.Time"},
+	}
+}
+
 func schema_apis_concierge_identity_v1alpha1_KubernetesUserInfo(ref common.ReferenceCallback) common.OpenAPIDefinition {
 	return common.OpenAPIDefinition{
 		Schema: spec.Schema{
@@ -520,12 +571,18 @@ func schema_apis_concierge_identity_v1alpha1_WhoAmIRequestStatus(ref common.Refe
 							Ref:         ref("go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.KubernetesUserInfo"),
 						},
 					},
+					"authenticationInfo": {
+						SchemaProps: spec.SchemaProps{
+							Description: "AuthenticationInfo describes how the current user authenticated, as far as the Concierge can observe it. It is only set when the request was made through the impersonation proxy, because the Kubernetes API server does not tell the Concierge anything about the credential which was used to authenticate.",
+							Ref:         ref("go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.AuthenticationInfo"),
+						},
+					},
 				},
 				Required: []string{"kubernetesUserInfo"},
 			},
 		},
 		Dependencies: []string{
-			"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.KubernetesUserInfo"},
+			"go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.AuthenticationInfo", "go.pinniped.dev/generated/1.23/apis/concierge/identity/v1alpha1.KubernetesUserInfo"},
 	}
 }
 
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by client-gen. DO NOT EDIT.
@@ -7,4 +7,6 @@ package v1alpha1
 
 type FederationDomainExpansion interface{}
 
+type IdentityTransformationLibraryExpansion interface{}
+
 type OIDCClientExpansion interface{}
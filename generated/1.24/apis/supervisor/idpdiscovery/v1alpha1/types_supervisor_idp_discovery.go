@@ -1,4 +1,4 @@
-// Copyright 2021-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -60,7 +60,16 @@ type IDPDiscoveryResponse struct {
 // PinnipedIDP describes a single identity provider as included in the response of a FederationDomain's
 // identity provider discovery endpoint.
 type PinnipedIDP struct {
-	Name  string    `json:"name"`
-	Type  IDPType   `json:"type"`
-	Flows []IDPFlow `json:"flows,omitempty"`
+	Name    string      `json:"name"`
+	Type    IDPType     `json:"type"`
+	Flows   []IDPFlow   `json:"flows,omitempty"`
+	Routing *IDPRouting `json:"routing,omitempty"`
+}
+
+// IDPRouting describes which users are routed to an identity provider when they do not choose an identity provider
+// themselves. A username or email address matches when it is an email address in any of the EmailDomains (compared
+// case-insensitively), or when the entire username matches the UsernameRegex.
+type IDPRouting struct {
+	EmailDomains  []string `json:"email_domains,omitempty"`
+	UsernameRegex string   `json:"username_regex,omitempty"`
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -6,6 +6,7 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 type FederationDomainPhase string
@@ -83,6 +84,19 @@ type FederationDomainTransformsExpression struct {
 	Message string `json:"message,omitempty"`
 }
 
+// FederationDomainTransformsAdditionalClaim defines a custom claim for the downstream ID tokens.
+type FederationDomainTransformsAdditionalClaim struct {
+	// Name is the name of the claim within the additionalClaims claim of the downstream ID tokens.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Expression is a CEL expression which computes the value of the claim. It may return any value which can be
+	// represented in JSON, e.g. a string, a number, a boolean, a list, or a map with string keys. When it returns null,
+	// the claim is omitted from the downstream ID tokens.
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+}
+
 // FederationDomainTransformsExample defines a transform example.
 type FederationDomainTransformsExample struct {
 	// Username is the input username.
@@ -93,8 +107,17 @@ type FederationDomainTransformsExample struct {
 	// +optional
 	Groups []string `json:"groups,omitempty"`
 
+	// UpstreamClaims is the input map of additional upstream claims (for OIDC identity providers) or attributes
+	// (for LDAP and ActiveDirectory identity providers), which are available to expressions as the upstreamClaims
+	// variable. Values may be any JSON value. Note that LDAP and ActiveDirectory attribute values are always lists
+	// of strings. When not specified, the example is evaluated with an empty map of upstream claims.
+	// +optional
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	UpstreamClaims *runtime.RawExtension `json:"upstreamClaims,omitempty"`
+
 	// Expects is the expected output of the entire sequence of transforms when they are run against the
-	// input Username and Groups.
+	// input Username, Groups, and UpstreamClaims.
 	Expects FederationDomainTransformsExampleExpects `json:"expects"`
 }
 
@@ -126,6 +149,17 @@ type FederationDomainTransformsExampleExpects struct {
 
 // FederationDomainTransforms defines identity transformations for an identity provider's usage on a FederationDomain.
 type FederationDomainTransforms struct {
+	// Libraries is an optional list of names of IdentityTransformationLibraries, which are cluster-scoped and may be
+	// used by any FederationDomain. The expressions of each library are evaluated in the order given, before the Expressions
+	// defined here. Each library uses its own constants, so the Constants defined here are not available to the
+	// expressions of the libraries. The Examples defined here are run against the whole sequence of transformations,
+	// including those from the libraries.
+	// If any library cannot be found or has an error status, then this identity provider will not be available for
+	// use within this FederationDomain.
+	// +listType=atomic
+	// +optional
+	Libraries []string `json:"libraries,omitempty"`
+
 	// Constants defines constant variables and their values which will be made available to the transform expressions.
 	// +patchMergeKey=name
 	// +patchStrategy=merge
@@ -166,6 +200,27 @@ type FederationDomainTransforms struct {
 	// +optional
 	Expressions []FederationDomainTransformsExpression `json:"expressions,omitempty"`
 
+	// AdditionalClaims are an optional list of custom claims to be added to the additionalClaims claim of the
+	// downstream ID tokens. Each is a CEL expression which is evaluated after all the transforms (including the
+	// libraries and the access policy) have been applied, during every authentication attempt, including during
+	// every session refresh. The expressions may use the same variables as the Expressions, where `username` and
+	// `groups` are the transformed username and group names, and `upstreamClaims` are the claims (for OIDC) or
+	// attributes (for LDAP and ActiveDirectory) from the identity provider. The constants defined here are available
+	// to these expressions.
+	//
+	// These claims are added to the claims which are copied by the spec.claims.additionalClaimMappings of an
+	// OIDCIdentityProvider. When both define a claim with the same name, then the claim defined here is used.
+	//
+	// Any compilation or static type-checking failure of any expression will cause an error status on the
+	// FederationDomain. During an authentication attempt, any unexpected runtime evaluation errors cause the
+	// authentication attempt to fail.
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	AdditionalClaims []FederationDomainTransformsAdditionalClaim `json:"additionalClaims,omitempty"`
+
 	// Examples can optionally be used to ensure that the sequence of transformation expressions are working as
 	// expected. Examples define sample input identities which are then run through the expression list, and the
 	// results are compared to the expected results. If any example in this list fails, then this
@@ -194,6 +249,156 @@ type FederationDomainIdentityProvider struct {
 	// session refresh.
 	// +optional
 	Transforms FederationDomainTransforms `json:"transforms,omitempty"`
+
+	// AccessPolicy is an optional way to restrict which users may authenticate using this identity provider,
+	// without needing to write policy/v1 transform expressions. It is evaluated during every authentication
+	// attempt, including during every session refresh, after all of the transform expressions have been applied.
+	// Therefore, it is evaluated against the transformed username and group names.
+	// The examples specified by transforms.examples also include the evaluation of this access policy.
+	// +optional
+	AccessPolicy *FederationDomainAccessPolicy `json:"accessPolicy,omitempty"`
+
+	// SecondFactor optionally requires users who authenticate using this identity provider to also present a second
+	// authentication factor, which is checked by the Supervisor itself after the identity provider has accepted the
+	// user's password. It is only supported for LDAPIdentityProvider and ActiveDirectoryIdentityProvider resources.
+	// Configuring it for any other kind of identity provider will cause an error status on the FederationDomain.
+	// +optional
+	SecondFactor *FederationDomainSecondFactor `json:"secondFactor,omitempty"`
+
+	// Routing optionally sends users to this identity provider based on the username or email address which they
+	// entered on the Supervisor's identity provider chooser page, or which the client sent in the login_hint param of
+	// the authorization request, so that they do not need to choose an identity provider themselves. When routing
+	// rules of more than one identity provider match, the first of those identity providers in this list is used.
+	// +optional
+	Routing *FederationDomainRouting `json:"routing,omitempty"`
+}
+
+// FederationDomainAccessPolicy restricts which users may authenticate using an identity provider.
+//
+// When neither AllowedGroups nor AllowedUsernamePatterns are specified, then all users are allowed, except those who
+// are rejected by DeniedGroups. When either is specified, then a user is allowed when they are a member of any of the
+// AllowedGroups, or when their username matches any of the AllowedUsernamePatterns. In all cases, a user who is a
+// member of any of the DeniedGroups is rejected, even when they would otherwise be allowed.
+type FederationDomainAccessPolicy struct {
+	// AllowedGroups is a list of group names. Members of any of these groups are allowed to authenticate.
+	// +listType=set
+	// +optional
+	AllowedGroups []string `json:"allowedGroups,omitempty"`
+
+	// DeniedGroups is a list of group names. Members of any of these groups are not allowed to authenticate.
+	// +listType=set
+	// +optional
+	DeniedGroups []string `json:"deniedGroups,omitempty"`
+
+	// AllowedUsernamePatterns is a list of regular expressions, using the syntax described at
+	// https://github.com/google/re2/wiki/Syntax. Users whose username matches any of these patterns are allowed to
+	// authenticate. Each pattern must match the entire username, e.g. "alice" matches only the username "alice",
+	// while ".*@example\.com" matches any username which ends with "@example.com". An invalid pattern will cause
+	// an error status on the FederationDomain.
+	// +listType=set
+	// +optional
+	AllowedUsernamePatterns []string `json:"allowedUsernamePatterns,omitempty"`
+
+	// RejectionMessage defines an error message to be used when this access policy rejects an authentication
+	// attempt. When empty, a default message will be used.
+	// +optional
+	RejectionMessage string `json:"rejectionMessage,omitempty"`
+}
+
+// FederationDomainSecondFactor describes a second authentication factor which users must present when they log in.
+type FederationDomainSecondFactor struct {
+	// Type is the type of second factor. "TOTP" requires users to enter a time-based one-time password (RFC 6238)
+	// from an authenticator app. "WebAuthn" requires users to use a security key or passkey (W3C Web Authentication),
+	// which is only possible when logging in using a web browser, so the CLI-based login flow cannot be used.
+	// Users who have not yet enrolled are asked to enroll the next time that they log in using a web browser, after
+	// their password was accepted. Enrollment trusts the first login: anyone who knows the password of a user who
+	// has not enrolled yet can enroll their own authenticator for that user.
+	// +kubebuilder:validation:Enum=TOTP;WebAuthn
+	Type string `json:"type"`
+}
+
+// FederationDomainRouting describes which users should use an identity provider, without choosing it themselves.
+// A username or email address matches when it matches either the EmailDomains or the UsernameRegex.
+type FederationDomainRouting struct {
+	// EmailDomains is a list of domain names, e.g. "example.com". Email addresses in any of these domains, compared
+	// case-insensitively, are routed to this identity provider. Subdomains are not included, so they must be listed
+	// separately. An invalid domain name will cause an error status on the FederationDomain.
+	// +listType=set
+	// +optional
+	EmailDomains []string `json:"emailDomains,omitempty"`
+
+	// UsernameRegex is a regular expression, using the syntax described at https://github.com/google/re2/wiki/Syntax.
+	// Usernames which match it are routed to this identity provider. It must match the entire username, e.g.
+	// "[a-z]+\.admin" matches "alice.admin", but not "alice.admin2". An invalid regular expression will cause an
+	// error status on the FederationDomain.
+	// +optional
+	UsernameRegex string `json:"usernameRegex,omitempty"`
+}
+
+// FederationDomainTokenExchangeCondition defines a condition which must be met before a user may exchange
+// their token for a token with the requested audience.
+type FederationDomainTokenExchangeCondition struct {
+	// Expression is a CEL expression which must return a boolean. When it returns false, the token exchange is
+	// rejected. It may use the same language features as the policy/v1 expressions of the identity providers.
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Message defines an error message to be used when the expression rejects a token exchange.
+	// When empty, a default message will be used.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// FederationDomainTokenExchangeAudience describes an audience which may be requested during token exchange.
+type FederationDomainTokenExchangeAudience struct {
+	// Audience is the audience of the ID tokens which are issued by the token exchange, e.g. the audience
+	// of the Concierge JWTAuthenticator of a cluster. It must not be one of the audiences which are reserved by
+	// Pinniped, i.e. it must not contain ".pinniped.dev" and it must not equal "pinniped-cli".
+	// +kubebuilder:validation:MinLength=1
+	Audience string `json:"audience"`
+
+	// Conditions are an optional list of CEL expressions which must all return true for a user to be allowed to
+	// request this audience. The username and groups of the user (after the transforms of their identity provider
+	// have been applied) are available as the variables `username` and `groups`. Note that `groups` will be an empty
+	// list unless the "groups" scope was granted to the client during login, so conditions should grant access based
+	// on group membership rather than deny access based on group membership.
+	//
+	// Any compilation or static type-checking failure of any expression will cause an error status on the FederationDomain.
+	// +optional
+	Conditions []FederationDomainTokenExchangeCondition `json:"conditions,omitempty"`
+
+	// IDTokenSeconds is the lifetime of the ID tokens which are issued for this audience, in seconds.
+	// When not set, the default ID token lifetime of the FederationDomain will be used.
+	// +kubebuilder:validation:Minimum=120
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	IDTokenSeconds *int32 `json:"idTokenSeconds,omitempty"`
+}
+
+// FederationDomainTokenExchange configures the RFC 8693 token exchange grant, which clients use to exchange their
+// access tokens for ID tokens with a different audience.
+type FederationDomainTokenExchange struct {
+	// AllowedAudiences is the list of audiences which may be requested during token exchange. Any other requested
+	// audience will be rejected. When empty, any audience which is not reserved by Pinniped may be requested,
+	// for backwards compatibility with versions of Pinniped which predate this setting.
+	// +patchMergeKey=audience
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=audience
+	// +optional
+	AllowedAudiences []FederationDomainTokenExchangeAudience `json:"allowedAudiences,omitempty"`
+}
+
+// FederationDomainBranding refers to a ConfigMap which customizes the web pages of a FederationDomain.
+type FederationDomainBranding struct {
+	// ConfigMapName is the name of a ConfigMap in the same namespace as the FederationDomain. All of its keys are
+	// optional. "title" is the name of your organization, which is shown on the pages. "logo" is a PNG, JPEG, GIF or
+	// WebP image of at most 100 KiB, which may be provided in either the data or the binaryData of the ConfigMap.
+	// "primaryColor" and "backgroundColor" are colors in CSS hex notation, e.g. "#1a73e8". "helpLinks" is a JSON list
+	// of objects with "text" and "url" keys, which are shown below the login form. "css" is additional CSS for the
+	// pages. "loginTemplate" is a Go html/template which replaces the HTML of the login page.
+	// +kubebuilder:validation:MinLength=1
+	ConfigMapName string `json:"configMapName"`
 }
 
 // FederationDomainSpec is a struct that describes an OIDC Provider.
@@ -238,6 +443,17 @@ type FederationDomainSpec struct {
 	//
 	// +optional
 	IdentityProviders []FederationDomainIdentityProvider `json:"identityProviders,omitempty"`
+
+	// TokenExchange configures which audiences may be requested by clients using the RFC 8693 token exchange grant,
+	// e.g. by the Pinniped CLI when it requests a cluster-scoped ID token for a Concierge JWTAuthenticator.
+	// +optional
+	TokenExchange FederationDomainTokenExchange `json:"tokenExchange,omitempty"`
+
+	// Branding optionally customizes the web pages which are shown to users by this FederationDomain during login,
+	// such as the login page and the identity provider chooser page, e.g. to show the name and logo of your organization.
+	// When the referenced ConfigMap does not exist or is invalid, the FederationDomain will have an error status.
+	// +optional
+	Branding *FederationDomainBranding `json:"branding,omitempty"`
 }
 
 // FederationDomainSecrets holds information about this OIDC Provider's secrets.
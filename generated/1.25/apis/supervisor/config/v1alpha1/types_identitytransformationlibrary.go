@@ -0,0 +1,97 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type IdentityTransformationLibraryPhase string
+
+const (
+	// IdentityTransformationLibraryPhasePending is the default phase for newly-created IdentityTransformationLibrary resources.
+	IdentityTransformationLibraryPhasePending IdentityTransformationLibraryPhase = "Pending"
+
+	// IdentityTransformationLibraryPhaseReady is the phase for an IdentityTransformationLibrary resource in a healthy state.
+	IdentityTransformationLibraryPhaseReady IdentityTransformationLibraryPhase = "Ready"
+
+	// IdentityTransformationLibraryPhaseError is the phase for an IdentityTransformationLibrary in an unhealthy state.
+	IdentityTransformationLibraryPhaseError IdentityTransformationLibraryPhase = "Error"
+)
+
+// IdentityTransformationLibrarySpec is a struct that describes a reusable list of identity transformations.
+type IdentityTransformationLibrarySpec struct {
+	// Constants defines constant variables and their values which will be made available to the expressions of
+	// this library. They are not available to the expressions of the FederationDomains which use this library.
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Constants []FederationDomainTransformsConstant `json:"constants,omitempty"`
+
+	// Expressions are a list of transforms and policies, which have the same types and language features as
+	// the transforms.expressions of the identity providers of a FederationDomain. When an identity provider of a
+	// FederationDomain uses this library, these expressions are evaluated in the order given, before the
+	// identity provider's own transforms.expressions.
+	// +optional
+	Expressions []FederationDomainTransformsExpression `json:"expressions,omitempty"`
+
+	// Examples can optionally be used to ensure that the expressions of this library are working as expected.
+	// They are run against this library's expressions alone. If any example in this list fails, then this library
+	// will have an error status, and any identity provider of a FederationDomain which uses this library will not be
+	// available for use.
+	// +optional
+	Examples []FederationDomainTransformsExample `json:"examples,omitempty"`
+}
+
+// IdentityTransformationLibraryStatus is a struct that describes the actual state of an IdentityTransformationLibrary.
+type IdentityTransformationLibraryStatus struct {
+	// Phase summarizes the overall status of the IdentityTransformationLibrary.
+	// +kubebuilder:default=Pending
+	// +kubebuilder:validation:Enum=Pending;Ready;Error
+	Phase IdentityTransformationLibraryPhase `json:"phase,omitempty"`
+
+	// Conditions represent the observations of an IdentityTransformationLibrary's current state.
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+
+	// ConsumingFederationDomains are the names of the FederationDomains which have at least one identity provider
+	// which uses this library.
+	// +listType=set
+	// +optional
+	ConsumingFederationDomains []string `json:"consumingFederationDomains,omitempty"`
+}
+
+// IdentityTransformationLibrary describes a list of identity transformations which can be shared by the
+// identity providers of many FederationDomains.
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped,scope=Cluster
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type IdentityTransformationLibrary struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec of the IdentityTransformationLibrary.
+	Spec IdentityTransformationLibrarySpec `json:"spec"`
+
+	// Status of the IdentityTransformationLibrary.
+	Status IdentityTransformationLibraryStatus `json:"status,omitempty"`
+}
+
+// List of IdentityTransformationLibrary objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type IdentityTransformationLibraryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []IdentityTransformationLibrary `json:"items"`
+}
@@ -41,11 +41,73 @@ func (in *FederationDomain) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainAccessPolicy) DeepCopyInto(out *FederationDomainAccessPolicy) {
+	*out = *in
+	if in.AllowedGroups != nil {
+		in, out := &in.AllowedGroups, &out.AllowedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DeniedGroups != nil {
+		in, out := &in.DeniedGroups, &out.DeniedGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedUsernamePatterns != nil {
+		in, out := &in.AllowedUsernamePatterns, &out.AllowedUsernamePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainAccessPolicy.
+func (in *FederationDomainAccessPolicy) DeepCopy() *FederationDomainAccessPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainAccessPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainBranding) DeepCopyInto(out *FederationDomainBranding) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainBranding.
+func (in *FederationDomainBranding) DeepCopy() *FederationDomainBranding {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainBranding)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainIdentityProvider) DeepCopyInto(out *FederationDomainIdentityProvider) {
 	*out = *in
 	in.ObjectRef.DeepCopyInto(&out.ObjectRef)
 	in.Transforms.DeepCopyInto(&out.Transforms)
+	if in.AccessPolicy != nil {
+		in, out := &in.AccessPolicy, &out.AccessPolicy
+		*out = new(FederationDomainAccessPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecondFactor != nil {
+		in, out := &in.SecondFactor, &out.SecondFactor
+		*out = new(FederationDomainSecondFactor)
+		**out = **in
+	}
+	if in.Routing != nil {
+		in, out := &in.Routing, &out.Routing
+		*out = new(FederationDomainRouting)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -92,6 +154,43 @@ func (in *FederationDomainList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainRouting) DeepCopyInto(out *FederationDomainRouting) {
+	*out = *in
+	if in.EmailDomains != nil {
+		in, out := &in.EmailDomains, &out.EmailDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainRouting.
+func (in *FederationDomainRouting) DeepCopy() *FederationDomainRouting {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainRouting)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSecondFactor) DeepCopyInto(out *FederationDomainSecondFactor) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSecondFactor.
+func (in *FederationDomainSecondFactor) DeepCopy() *FederationDomainSecondFactor {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSecondFactor)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainSecrets) DeepCopyInto(out *FederationDomainSecrets) {
 	*out = *in
@@ -127,6 +226,12 @@ func (in *FederationDomainSpec) DeepCopyInto(out *FederationDomainSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	in.TokenExchange.DeepCopyInto(&out.TokenExchange)
+	if in.Branding != nil {
+		in, out := &in.Branding, &out.Branding
+		*out = new(FederationDomainBranding)
+		**out = **in
+	}
 	return
 }
 
@@ -180,9 +285,79 @@ func (in *FederationDomainTLSSpec) DeepCopy() *FederationDomainTLSSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTokenExchange) DeepCopyInto(out *FederationDomainTokenExchange) {
+	*out = *in
+	if in.AllowedAudiences != nil {
+		in, out := &in.AllowedAudiences, &out.AllowedAudiences
+		*out = make([]FederationDomainTokenExchangeAudience, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTokenExchange.
+func (in *FederationDomainTokenExchange) DeepCopy() *FederationDomainTokenExchange {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTokenExchange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTokenExchangeAudience) DeepCopyInto(out *FederationDomainTokenExchangeAudience) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]FederationDomainTokenExchangeCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.IDTokenSeconds != nil {
+		in, out := &in.IDTokenSeconds, &out.IDTokenSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTokenExchangeAudience.
+func (in *FederationDomainTokenExchangeAudience) DeepCopy() *FederationDomainTokenExchangeAudience {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTokenExchangeAudience)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTokenExchangeCondition) DeepCopyInto(out *FederationDomainTokenExchangeCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTokenExchangeCondition.
+func (in *FederationDomainTokenExchangeCondition) DeepCopy() *FederationDomainTokenExchangeCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTokenExchangeCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainTransforms) DeepCopyInto(out *FederationDomainTransforms) {
 	*out = *in
+	if in.Libraries != nil {
+		in, out := &in.Libraries, &out.Libraries
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.Constants != nil {
 		in, out := &in.Constants, &out.Constants
 		*out = make([]FederationDomainTransformsConstant, len(*in))
@@ -195,6 +370,11 @@ func (in *FederationDomainTransforms) DeepCopyInto(out *FederationDomainTransfor
 		*out = make([]FederationDomainTransformsExpression, len(*in))
 		copy(*out, *in)
 	}
+	if in.AdditionalClaims != nil {
+		in, out := &in.AdditionalClaims, &out.AdditionalClaims
+		*out = make([]FederationDomainTransformsAdditionalClaim, len(*in))
+		copy(*out, *in)
+	}
 	if in.Examples != nil {
 		in, out := &in.Examples, &out.Examples
 		*out = make([]FederationDomainTransformsExample, len(*in))
@@ -215,6 +395,22 @@ func (in *FederationDomainTransforms) DeepCopy() *FederationDomainTransforms {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainTransformsAdditionalClaim) DeepCopyInto(out *FederationDomainTransformsAdditionalClaim) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainTransformsAdditionalClaim.
+func (in *FederationDomainTransformsAdditionalClaim) DeepCopy() *FederationDomainTransformsAdditionalClaim {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainTransformsAdditionalClaim)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainTransformsConstant) DeepCopyInto(out *FederationDomainTransformsConstant) {
 	*out = *in
@@ -244,6 +440,11 @@ func (in *FederationDomainTransformsExample) DeepCopyInto(out *FederationDomainT
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.UpstreamClaims != nil {
+		in, out := &in.UpstreamClaims, &out.UpstreamClaims
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Expects.DeepCopyInto(&out.Expects)
 	return
 }
@@ -295,6 +496,130 @@ func (in *FederationDomainTransformsExpression) DeepCopy() *FederationDomainTran
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityTransformationLibrary) DeepCopyInto(out *IdentityTransformationLibrary) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityTransformationLibrary.
+func (in *IdentityTransformationLibrary) DeepCopy() *IdentityTransformationLibrary {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityTransformationLibrary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IdentityTransformationLibrary) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityTransformationLibraryList) DeepCopyInto(out *IdentityTransformationLibraryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IdentityTransformationLibrary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityTransformationLibraryList.
+func (in *IdentityTransformationLibraryList) DeepCopy() *IdentityTransformationLibraryList {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityTransformationLibraryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IdentityTransformationLibraryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityTransformationLibrarySpec) DeepCopyInto(out *IdentityTransformationLibrarySpec) {
+	*out = *in
+	if in.Constants != nil {
+		in, out := &in.Constants, &out.Constants
+		*out = make([]FederationDomainTransformsConstant, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Expressions != nil {
+		in, out := &in.Expressions, &out.Expressions
+		*out = make([]FederationDomainTransformsExpression, len(*in))
+		copy(*out, *in)
+	}
+	if in.Examples != nil {
+		in, out := &in.Examples, &out.Examples
+		*out = make([]FederationDomainTransformsExample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityTransformationLibrarySpec.
+func (in *IdentityTransformationLibrarySpec) DeepCopy() *IdentityTransformationLibrarySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityTransformationLibrarySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityTransformationLibraryStatus) DeepCopyInto(out *IdentityTransformationLibraryStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ConsumingFederationDomains != nil {
+		in, out := &in.ConsumingFederationDomains, &out.ConsumingFederationDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityTransformationLibraryStatus.
+func (in *IdentityTransformationLibraryStatus) DeepCopy() *IdentityTransformationLibraryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityTransformationLibraryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClient) DeepCopyInto(out *OIDCClient) {
 	*out = *in
@@ -323,6 +648,48 @@ func (in *OIDCClient) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientAuthentication) DeepCopyInto(out *OIDCClientAuthentication) {
+	*out = *in
+	if in.PrivateKeyJWT != nil {
+		in, out := &in.PrivateKeyJWT, &out.PrivateKeyJWT
+		*out = new(OIDCClientPrivateKeyJWT)
+		**out = **in
+	}
+	if in.TLSClientAuth != nil {
+		in, out := &in.TLSClientAuth, &out.TLSClientAuth
+		*out = new(OIDCClientTLSClientAuth)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientAuthentication.
+func (in *OIDCClientAuthentication) DeepCopy() *OIDCClientAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientConsent) DeepCopyInto(out *OIDCClientConsent) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientConsent.
+func (in *OIDCClientConsent) DeepCopy() *OIDCClientConsent {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientConsent)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClientList) DeepCopyInto(out *OIDCClientList) {
 	*out = *in
@@ -356,6 +723,43 @@ func (in *OIDCClientList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientPrivateKeyJWT) DeepCopyInto(out *OIDCClientPrivateKeyJWT) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientPrivateKeyJWT.
+func (in *OIDCClientPrivateKeyJWT) DeepCopy() *OIDCClientPrivateKeyJWT {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientPrivateKeyJWT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientServiceIdentity) DeepCopyInto(out *OIDCClientServiceIdentity) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientServiceIdentity.
+func (in *OIDCClientServiceIdentity) DeepCopy() *OIDCClientServiceIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientServiceIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClientSpec) DeepCopyInto(out *OIDCClientSpec) {
 	*out = *in
@@ -374,6 +778,21 @@ func (in *OIDCClientSpec) DeepCopyInto(out *OIDCClientSpec) {
 		*out = make([]Scope, len(*in))
 		copy(*out, *in)
 	}
+	if in.ServiceIdentity != nil {
+		in, out := &in.ServiceIdentity, &out.ServiceIdentity
+		*out = new(OIDCClientServiceIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientAuthentication != nil {
+		in, out := &in.ClientAuthentication, &out.ClientAuthentication
+		*out = new(OIDCClientAuthentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Consent != nil {
+		in, out := &in.Consent, &out.Consent
+		*out = new(OIDCClientConsent)
+		**out = **in
+	}
 	return
 }
 
@@ -409,3 +828,19 @@ func (in *OIDCClientStatus) DeepCopy() *OIDCClientStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientTLSClientAuth) DeepCopyInto(out *OIDCClientTLSClientAuth) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientTLSClientAuth.
+func (in *OIDCClientTLSClientAuth) DeepCopy() *OIDCClientTLSClientAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientTLSClientAuth)
+	in.DeepCopyInto(out)
+	return out
+}
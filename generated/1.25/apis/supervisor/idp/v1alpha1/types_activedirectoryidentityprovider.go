@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -41,6 +41,9 @@ type ActiveDirectoryIdentityProviderBind struct {
 	// of type "kubernetes.io/basic-auth" which includes "username" and "password" keys. The username value
 	// should be the full dn (distinguished name) of your bind account, e.g. "cn=bind-account,ou=users,dc=example,dc=com".
 	// The password must be non-empty.
+	// Alternatively, the Secret may be of type "kubernetes.io/tls" which includes "tls.crt" and "tls.key" keys.
+	// In that case, the certificate is presented as a client certificate when connecting to the server, and the bind
+	// uses the SASL EXTERNAL mechanism instead of a password. The server must map the certificate to your bind account.
 	// +kubebuilder:validation:MinLength=1
 	SecretName string `json:"secretName"`
 }
@@ -57,6 +60,17 @@ type ActiveDirectoryIdentityProviderUserSearchAttributes struct {
 	// Optional, when empty this defaults to "objectGUID".
 	// +optional
 	UID string `json:"uid,omitempty"`
+
+	// AdditionalAttributes specifies the names of other attributes in the ActiveDirectory entry which should be read
+	// during login and made available to the FederationDomain's identity transformation expressions as the
+	// upstreamClaims variable. Each attribute's value is provided as a list of strings, since ActiveDirectory attributes
+	// may have multiple values. Attributes which are not present on the user's entry will not be present in
+	// upstreamClaims. The values are read only during the user's initial login, and are reused during refreshes.
+	// The attribute names are case-sensitive and must match the case of the attribute names returned by the
+	// ActiveDirectory server in the user's entry.
+	// +optional
+	// +listType=set
+	AdditionalAttributes []string `json:"additionalAttributes,omitempty"`
 }
 
 type ActiveDirectoryIdentityProviderGroupSearchAttributes struct {
@@ -168,6 +182,27 @@ type ActiveDirectoryIdentityProviderGroupSearch struct {
 	// release notes before upgrading to ensure that the meaning of this field has
 	// not changed.
 	SkipGroupRefresh bool `json:"skipGroupRefresh,omitempty"`
+
+	// RefreshCacheSeconds is how long the group memberships which were found by the group search during a refresh
+	// are remembered, in seconds. While they are remembered, later refreshes of the same user will use them instead of
+	// searching for the user's groups again, so changes to group memberships may take this long to be noticed.
+	// The remembered group memberships are shared by all Supervisor pods. Logins always search for the user's groups.
+	// This is ignored when skipGroupRefresh is true. When not set or zero, each refresh searches for the user's groups.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	RefreshCacheSeconds *int32 `json:"refreshCacheSeconds,omitempty"`
+}
+
+type ActiveDirectoryIdentityProviderHostDiscovery struct {
+	// Domain is the DNS name of the Active Directory domain, e.g. "activedirectory.example.com". The domain
+	// controllers are discovered by looking up the "_ldap._tcp" DNS SRV records of this domain, which Active
+	// Directory publishes for each of its domain controllers. The DNS records are looked up again each time that
+	// the Supervisor validates this identity provider, and the discovered domain controllers are tried in the order
+	// of their SRV record priority and weight. The ports in the SRV records are not used. Instead, the default port
+	// for the protocol which was used to connect to the Host is used, i.e. 636 for TLS or 389 for StartTLS.
+	// +kubebuilder:validation:MinLength=1
+	Domain string `json:"domain"`
 }
 
 // Spec for configuring an ActiveDirectory identity provider.
@@ -176,6 +211,20 @@ type ActiveDirectoryIdentityProviderSpec struct {
 	// +kubebuilder:validation:MinLength=1
 	Host string `json:"host"`
 
+	// AdditionalHosts is an optional ordered list of the hostnames of other domain controllers which serve the same
+	// domain as the Host. Each uses the same format as Host. When the Host cannot be reached, each of these hosts is
+	// tried in order, followed by any hosts found by HostDiscovery. Hosts which recently could not be reached are tried
+	// last, until they become reachable again. The Host continues to identify this Active Directory identity provider
+	// in the subject of the downstream ID tokens, so it should not be changed when adding or removing additional hosts.
+	// +optional
+	// +listType=set
+	AdditionalHosts []string `json:"additionalHosts,omitempty"`
+
+	// HostDiscovery optionally configures the discovery of other domain controllers using DNS SRV records.
+	// The discovered domain controllers are used in the same way as the AdditionalHosts.
+	// +optional
+	HostDiscovery *ActiveDirectoryIdentityProviderHostDiscovery `json:"hostDiscovery,omitempty"`
+
 	// TLS contains the connection settings for how to establish the connection to the Host.
 	TLS *TLSSpec `json:"tls,omitempty"`
 
@@ -0,0 +1,174 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// IdentityTransformationTestRequest can be used to evaluate the identity transformations of an identity provider of
+// a FederationDomain against some input identity, without performing an authentication. Nothing is saved.
+// +genclient
+// +genclient:onlyVerbs=create
+// +kubebuilder:subresource:status
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type IdentityTransformationTestRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec IdentityTransformationTestRequestSpec `json:"spec"`
+
+	// +optional
+	Status IdentityTransformationTestRequestStatus `json:"status"`
+}
+
+// Spec of the IdentityTransformationTestRequest.
+type IdentityTransformationTestRequestSpec struct {
+	// FederationDomainName is the name of a FederationDomain in the same namespace.
+	FederationDomainName string `json:"federationDomainName"`
+
+	// IdentityProviderDisplayName is the displayName of one of the spec.identityProviders of the FederationDomain.
+	IdentityProviderDisplayName string `json:"identityProviderDisplayName"`
+
+	// Username is the input username.
+	Username string `json:"username"`
+
+	// Groups is the input list of group names.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// UpstreamClaims is the input map of upstream claims (for OIDC identity providers) or attributes
+	// (for LDAP and ActiveDirectory identity providers), which are available to the expressions as the
+	// upstreamClaims variable.
+	// +optional
+	UpstreamClaims *runtime.RawExtension `json:"upstreamClaims,omitempty"`
+
+	// Transforms is an optional candidate replacement for the transforms of the identity provider. When set, these
+	// transforms are evaluated instead of the saved transforms of the identity provider, which allows changes
+	// to be tried before they are saved. The accessPolicy of the identity provider is evaluated either way.
+	// +optional
+	Transforms *IdentityTransformationTestRequestTransforms `json:"transforms,omitempty"`
+}
+
+// IdentityTransformationTestRequestTransforms has the same meaning as the transforms of an identity provider
+// of a FederationDomain, except for the examples, which are not needed here.
+type IdentityTransformationTestRequestTransforms struct {
+	// Constants defines constant variables and their values which will be made available to the transform expressions.
+	// +optional
+	Constants []TransformsConstant `json:"constants,omitempty"`
+
+	// Expressions are an optional list of transforms and policies to be executed in the order given.
+	// +optional
+	Expressions []TransformsExpression `json:"expressions,omitempty"`
+
+	// Libraries are the names of IdentityTransformationLibraries whose expressions are
+	// evaluated before the expressions above, in the order given.
+	// +optional
+	Libraries []string `json:"libraries,omitempty"`
+}
+
+// TransformsConstant defines a constant variable and its value which will be made available to
+// the transform expressions.
+type TransformsConstant struct {
+	// Name determines the name of the constant. It must be a valid identifier name.
+	Name string `json:"name"`
+
+	// Type determines the type of the constant, and indicates which other field should be non-empty.
+	// It must be "string" or "stringList".
+	Type string `json:"type"`
+
+	// StringValue should hold the value when Type is "string", and is otherwise ignored.
+	// +optional
+	StringValue string `json:"stringValue,omitempty"`
+
+	// StringListValue should hold the value when Type is "stringList", and is otherwise ignored.
+	// +optional
+	StringListValue []string `json:"stringListValue,omitempty"`
+}
+
+// TransformsExpression defines a transform expression.
+type TransformsExpression struct {
+	// Type determines the type of the expression. It must be "policy/v1", "username/v1", or "groups/v1".
+	Type string `json:"type"`
+
+	// Expression is a CEL expression that will be evaluated based on the Type.
+	Expression string `json:"expression"`
+
+	// Message is only used when Type is policy/v1. It defines an error message to be used when the policy rejects
+	// an authentication attempt. When empty, a default message will be used.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// Status of the IdentityTransformationTestRequest.
+type IdentityTransformationTestRequestStatus struct {
+	// Username is the resulting username, when the authentication was not rejected.
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Groups is the resulting list of group names, when the authentication was not rejected.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Rejected is true when a policy rejected the authentication.
+	Rejected bool `json:"rejected"`
+
+	// Message is the rejection message, when the authentication was rejected.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Error describes the error which happened while evaluating the transforms, if any. This is the error
+	// which would have caused an actual authentication to fail.
+	// +optional
+	Error string `json:"error,omitempty"`
+
+	// Trace lists the results of each transform which was evaluated, in the order that they were evaluated.
+	// +optional
+	Trace []IdentityTransformationTestRequestTraceStep `json:"trace,omitempty"`
+}
+
+// IdentityTransformationTestRequestTraceStep is the result of evaluating one transform.
+type IdentityTransformationTestRequestTraceStep struct {
+	// Source describes where the transform came from, e.g. "transforms.expressions[0]",
+	// "transforms.libraries[0](my-library).expressions[1]", or "accessPolicy".
+	Source string `json:"source"`
+
+	// Type is the type of the transform, e.g. "username/v1", "groups/v1", "policy/v1", or "accessPolicy".
+	Type string `json:"type"`
+
+	// Expression is the CEL expression of the transform, when it has one.
+	// +optional
+	Expression string `json:"expression,omitempty"`
+
+	// Username is the username after this transform was evaluated.
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Groups is the list of group names after this transform was evaluated.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Rejected is true when this transform rejected the authentication.
+	// +optional
+	Rejected bool `json:"rejected,omitempty"`
+
+	// Message is the rejection message, when this transform rejected the authentication.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Error describes the error which happened while evaluating this transform, if any.
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// IdentityTransformationTestRequestList is a list of IdentityTransformationTestRequest objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type IdentityTransformationTestRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of IdentityTransformationTestRequest.
+	Items []IdentityTransformationTestRequest `json:"items"`
+}
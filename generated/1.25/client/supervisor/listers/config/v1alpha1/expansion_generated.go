@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by lister-gen. DO NOT EDIT.
@@ -13,6 +13,10 @@ type FederationDomainListerExpansion interface{}
 // FederationDomainNamespaceLister.
 type FederationDomainNamespaceListerExpansion interface{}
 
+// IdentityTransformationLibraryListerExpansion allows custom methods to be added to
+// IdentityTransformationLibraryLister.
+type IdentityTransformationLibraryListerExpansion interface{}
+
 // OIDCClientListerExpansion allows custom methods to be added to
 // OIDCClientLister.
 type OIDCClientListerExpansion interface{}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by informer-gen. DO NOT EDIT.
@@ -13,6 +13,8 @@ import (
 type Interface interface {
 	// FederationDomains returns a FederationDomainInformer.
 	FederationDomains() FederationDomainInformer
+	// IdentityTransformationLibraries returns a IdentityTransformationLibraryInformer.
+	IdentityTransformationLibraries() IdentityTransformationLibraryInformer
 	// OIDCClients returns a OIDCClientInformer.
 	OIDCClients() OIDCClientInformer
 }
@@ -33,6 +35,11 @@ func (v *version) FederationDomains() FederationDomainInformer {
 	return &federationDomainInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
 
+// IdentityTransformationLibraries returns a IdentityTransformationLibraryInformer.
+func (v *version) IdentityTransformationLibraries() IdentityTransformationLibraryInformer {
+	return &identityTransformationLibraryInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
+
 // OIDCClients returns a OIDCClientInformer.
 func (v *version) OIDCClients() OIDCClientInformer {
 	return &oIDCClientInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
@@ -0,0 +1,8 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +k8s:deepcopy-gen=package
+// +groupName=transformation.supervisor.pinniped.dev
+
+// Package transformation is the internal version of the Pinniped identity transformation API.
+package transformation
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -8,7 +8,7 @@ import (
 )
 
 // StrategyType enumerates a type of "strategy" used to implement credential access on a cluster.
-// +kubebuilder:validation:Enum=KubeClusterSigningCertificate;ImpersonationProxy
+// +kubebuilder:validation:Enum=KubeClusterSigningCertificate;ImpersonationProxy;ExternalSigner;ConciergeManagedCA
 type StrategyType string
 
 // FrontendType enumerates a type of "frontend" used to provide access to users of a cluster.
@@ -20,12 +20,14 @@ type FrontendType string
 type StrategyStatus string
 
 // StrategyReason enumerates the detailed reason why a strategy is in a particular status.
-// +kubebuilder:validation:Enum=Listening;Pending;Disabled;ErrorDuringSetup;CouldNotFetchKey;CouldNotGetClusterInfo;FetchedKey
+// +kubebuilder:validation:Enum=Listening;Pending;Disabled;ErrorDuringSetup;CouldNotFetchKey;CouldNotGetClusterInfo;FetchedKey;SignerConfigured;InvalidSignerConfig;ClientCATrusted;ClientCANotTrusted
 type StrategyReason string
 
 const (
 	KubeClusterSigningCertificateStrategyType = StrategyType("KubeClusterSigningCertificate")
 	ImpersonationProxyStrategyType            = StrategyType("ImpersonationProxy")
+	ExternalSignerStrategyType                = StrategyType("ExternalSigner")
+	ConciergeManagedCAStrategyType            = StrategyType("ConciergeManagedCA")
 
 	TokenCredentialRequestAPIFrontendType = FrontendType("TokenCredentialRequestAPI")
 	ImpersonationProxyFrontendType        = FrontendType("ImpersonationProxy")
@@ -40,12 +42,111 @@ const (
 	CouldNotFetchKeyStrategyReason       = StrategyReason("CouldNotFetchKey")
 	CouldNotGetClusterInfoStrategyReason = StrategyReason("CouldNotGetClusterInfo")
 	FetchedKeyStrategyReason             = StrategyReason("FetchedKey")
+	SignerConfiguredStrategyReason       = StrategyReason("SignerConfigured")
+	InvalidSignerConfigStrategyReason    = StrategyReason("InvalidSignerConfig")
+	ClientCATrustedStrategyReason        = StrategyReason("ClientCATrusted")
+	ClientCANotTrustedStrategyReason     = StrategyReason("ClientCANotTrusted")
 )
 
 // CredentialIssuerSpec describes the intended configuration of the Concierge.
 type CredentialIssuerSpec struct {
 	// ImpersonationProxy describes the intended configuration of the Concierge impersonation proxy.
 	ImpersonationProxy *ImpersonationProxySpec `json:"impersonationProxy"`
+
+	// ExternalSigner describes an optional signer, other than the signing key of the kube-controller-manager,
+	// which the Concierge can use to issue client certificates for the TokenCredentialRequest API.
+	// This is useful on clusters where the kube cert agent cannot fetch the cluster signing key,
+	// such as most managed Kubernetes services.
+	//
+	// +optional
+	ExternalSigner *ExternalSignerSpec `json:"externalSigner,omitempty"`
+
+	// ManagedClientCA configures the Concierge to generate and rotate its own client CA, which it uses to issue
+	// client certificates for the TokenCredentialRequest API once the Kubernetes API server trusts that CA.
+	// The bundle of the CA is published in a ConfigMap in the Concierge namespace so that cluster admins can add
+	// it to the --client-ca-file of the Kubernetes API server. When not set, no CA is generated or published.
+	//
+	// +optional
+	ManagedClientCA *ManagedClientCASpec `json:"managedClientCA,omitempty"`
+}
+
+// ManagedClientCASpec describes how the Concierge generates and rotates its managed client CA.
+type ManagedClientCASpec struct {
+	// DurationSeconds is the validity period, in seconds, of each generated CA. Defaults to 31536000 (1 year).
+	//
+	// +kubebuilder:validation:Minimum=3600
+	// +optional
+	DurationSeconds *int64 `json:"durationSeconds,omitempty"`
+
+	// RotationOverlapSeconds is how long, in seconds, before the current CA expires that the next CA is generated
+	// and published together with the current CA. The next CA is only used to issue client certificates once the
+	// Kubernetes API server trusts it, and the previous CA stays in the published bundle until it expires, so this
+	// is how much time cluster admins have to update the --client-ca-file of the Kubernetes API server.
+	// Must be less than durationSeconds. Defaults to 2592000 (30 days).
+	//
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RotationOverlapSeconds *int64 `json:"rotationOverlapSeconds,omitempty"`
+}
+
+// ExternalSignerSpec describes how the Concierge should issue client certificates through an external signer.
+// Exactly one of CertificateSigningRequest or CertManager must be set.
+type ExternalSignerSpec struct {
+	// CertificateSigningRequest configures the Concierge to issue client certificates through the
+	// Kubernetes CertificateSigningRequest API.
+	//
+	// +optional
+	CertificateSigningRequest *CertificateSigningRequestSignerSpec `json:"certificateSigningRequest,omitempty"`
+
+	// CertManager configures the Concierge to issue client certificates through a cert-manager
+	// Issuer or ClusterIssuer.
+	//
+	// +optional
+	CertManager *CertManagerSignerSpec `json:"certManager,omitempty"`
+}
+
+// CertificateSigningRequestSignerSpec describes how the Concierge should use the Kubernetes
+// CertificateSigningRequest API to issue client certificates.
+type CertificateSigningRequestSignerSpec struct {
+	// SignerName is the signerName of the CertificateSigningRequests created by the Concierge,
+	// e.g. "kubernetes.io/kube-apiserver-client". The certificates issued by this signer must be
+	// trusted for client authentication by the Kubernetes API server.
+	//
+	// +kubebuilder:validation:MinLength=1
+	SignerName string `json:"signerName"`
+
+	// AutoApprove configures the Concierge to approve the CertificateSigningRequests that it creates.
+	// When false, the CertificateSigningRequests must be approved by some other approver for this signer.
+	//
+	// +optional
+	AutoApprove bool `json:"autoApprove,omitempty"`
+}
+
+// CertManagerSignerSpec describes how the Concierge should use cert-manager to issue client certificates.
+type CertManagerSignerSpec struct {
+	// IssuerRef references the cert-manager Issuer or ClusterIssuer which issues the client certificates.
+	// The CertificateRequests are created in the namespace in which the Concierge is installed, so an
+	// Issuer must also be in that namespace.
+	IssuerRef CertManagerIssuerReference `json:"issuerRef"`
+}
+
+// CertManagerIssuerReference references a cert-manager Issuer or ClusterIssuer.
+type CertManagerIssuerReference struct {
+	// Name of the issuer.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Kind of the issuer, either "Issuer" or "ClusterIssuer". Defaults to "Issuer".
+	//
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +optional
+	Kind string `json:"kind,omitempty"`
+
+	// Group of the issuer. Defaults to "cert-manager.io". External issuers for cert-manager use their own group.
+	//
+	// +optional
+	Group string `json:"group,omitempty"`
 }
 
 // ImpersonationProxyMode enumerates the configuration modes for the impersonation proxy.
@@ -129,6 +230,104 @@ type ImpersonationProxySpec struct {
 	//
 	// +optional
 	TLS *ImpersonationProxyTLSSpec `json:"tls,omitempty"`
+
+	// Policy describes optional rules which the impersonation proxy uses to deny or throttle authenticated
+	// requests before they are proxied to the Kubernetes API server.
+	//
+	// +optional
+	Policy *ImpersonationProxyPolicy `json:"policy,omitempty"`
+}
+
+// ImpersonationProxyPolicyAction enumerates the actions which can be taken by an impersonation proxy policy rule.
+//
+// +kubebuilder:validation:Enum=Deny;RateLimit
+type ImpersonationProxyPolicyAction string
+
+const (
+	// ImpersonationProxyPolicyActionDeny rejects matching requests.
+	ImpersonationProxyPolicyActionDeny = ImpersonationProxyPolicyAction("Deny")
+
+	// ImpersonationProxyPolicyActionRateLimit throttles matching requests per user.
+	ImpersonationProxyPolicyActionRateLimit = ImpersonationProxyPolicyAction("RateLimit")
+)
+
+// ImpersonationProxyPolicy describes per-request authorization and rate limiting rules for the Concierge
+// impersonation proxy.
+type ImpersonationProxyPolicy struct {
+	// Rules is a list of rules which are evaluated against every authenticated request.
+	// A request which matches any rule with action "Deny" is rejected, even when the Kubernetes API server
+	// would have otherwise authorized it. A request is counted only against the first rule with action "RateLimit"
+	// that it matches, in the order of this list, using that rule's rate limit for the requesting user. The request
+	// is rejected when that limit is exceeded.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxItems=64
+	Rules []ImpersonationProxyPolicyRule `json:"rules,omitempty"`
+}
+
+// ImpersonationProxyPolicyRule describes which requests are matched by a rule, and the action to take
+// when a request matches.
+//
+// Each of the matching fields is optional. An empty matching field matches all requests.
+type ImpersonationProxyPolicyRule struct {
+	// Name is a unique name for the rule. It is included in the error messages returned to clients.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Action is the action to take when a request matches this rule.
+	Action ImpersonationProxyPolicyAction `json:"action"`
+
+	// Users is a list of usernames. When Users or Groups is non-empty, the rule only matches requests from users
+	// whose username is in this list, or who belong to at least one of the groups in Groups.
+	//
+	// +optional
+	Users []string `json:"users,omitempty"`
+
+	// Groups is a list of group names. When Users or Groups is non-empty, the rule only matches requests from users
+	// whose username is in Users, or who belong to at least one of the groups in this list.
+	//
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// Verbs is a list of Kubernetes API verbs, e.g. "get", "list", "watch", or "create".
+	// "*" matches all verbs.
+	//
+	// +optional
+	Verbs []string `json:"verbs,omitempty"`
+
+	// APIGroups is a list of API groups. The empty string represents the core API group. "*" matches all API groups.
+	// When APIGroups or Resources is non-empty, the rule never matches non-resource requests, e.g. requests to /healthz.
+	//
+	// +optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+
+	// Resources is a list of resources, e.g. "pods" or "secrets". Subresources may be specified using a slash,
+	// e.g. "pods/exec", and "*/exec" matches the exec subresource of any resource. "*" matches all resources and
+	// subresources. When APIGroups or Resources is non-empty, the rule never matches non-resource requests.
+	//
+	// +optional
+	Resources []string `json:"resources,omitempty"`
+
+	// RateLimit configures the rate limit applied by this rule. Required when Action is "RateLimit".
+	//
+	// +optional
+	RateLimit *ImpersonationProxyRateLimit `json:"rateLimit,omitempty"`
+}
+
+// ImpersonationProxyRateLimit describes a token bucket rate limit which is applied separately to each user.
+type ImpersonationProxyRateLimit struct {
+	// RequestsPerSecond is the sustained rate of requests allowed for each user.
+	//
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerSecond int32 `json:"requestsPerSecond"`
+
+	// Burst is the maximum number of requests allowed for each user in a short burst.
+	// Defaults to RequestsPerSecond when not specified.
+	//
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
 }
 
 // ImpersonationProxyServiceSpec describes how the Concierge should provision a Service to expose the impersonation proxy.
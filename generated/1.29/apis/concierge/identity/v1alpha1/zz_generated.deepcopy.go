@@ -1,7 +1,7 @@
 //go:build !ignore_autogenerated
 // +build !ignore_autogenerated
 
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Code generated by deepcopy-gen. DO NOT EDIT.
@@ -9,9 +9,35 @@
 package v1alpha1
 
 import (
+	v1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AuthenticationInfo) DeepCopyInto(out *AuthenticationInfo) {
+	*out = *in
+	if in.Authenticator != nil {
+		in, out := &in.Authenticator, &out.Authenticator
+		*out = new(v1.TypedLocalObjectReference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CredentialExpiration != nil {
+		in, out := &in.CredentialExpiration, &out.CredentialExpiration
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AuthenticationInfo.
+func (in *AuthenticationInfo) DeepCopy() *AuthenticationInfo {
+	if in == nil {
+		return nil
+	}
+	out := new(AuthenticationInfo)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in ExtraValue) DeepCopyInto(out *ExtraValue) {
 	{
@@ -171,6 +197,11 @@ func (in *WhoAmIRequestSpec) DeepCopy() *WhoAmIRequestSpec {
 func (in *WhoAmIRequestStatus) DeepCopyInto(out *WhoAmIRequestStatus) {
 	*out = *in
 	in.KubernetesUserInfo.DeepCopyInto(&out.KubernetesUserInfo)
+	if in.AuthenticationInfo != nil {
+		in, out := &in.AuthenticationInfo, &out.AuthenticationInfo
+		*out = new(AuthenticationInfo)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -0,0 +1,120 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package authenticationinfo carries the details of how a user authenticated from the places where the
+// Concierge can observe them to the WhoAmIRequest API.
+//
+// The TokenCredentialRequest API embeds these details into the client certificates that it issues. The
+// Kubernetes API server ignores them, but the impersonation proxy reads them from the client certificate of
+// the user and passes them along to the WhoAmIRequest API in a reserved user extra.
+package authenticationinfo
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExtraKey is the key of the user extra in which the impersonation proxy passes the Info to the
+// WhoAmIRequest API. The impersonation proxy does not allow its users to set keys with this suffix.
+const ExtraKey = "authentication-info.impersonation-proxy.concierge.pinniped.dev"
+
+// extensionOID identifies the client certificate extension which holds the Info. It is based on a random UUID
+// below the 2.25 arc (see ITU-T X.667), which does not require registration. The UUID is split into 16-bit arcs
+// because Go cannot parse certificates with OID arcs which do not fit into 31 bits.
+var extensionOID = asn1.ObjectIdentifier{2, 25, 11583, 12201, 50155, 17692, 43093, 25394, 50346, 49681} //nolint:gochecknoglobals
+
+// Info describes how a user authenticated.
+type Info struct {
+	// Authenticator is the Concierge authenticator which authenticated the token of the user.
+	Authenticator *corev1.TypedLocalObjectReference `json:"authenticator,omitempty"`
+
+	// SupervisorIssuer is the issuer of the Supervisor FederationDomain which issued the token of the user.
+	SupervisorIssuer string `json:"supervisorIssuer,omitempty"`
+
+	// UpstreamIdentityProviderName is the name of the Supervisor identity provider with which the user logged in.
+	UpstreamIdentityProviderName string `json:"upstreamIdentityProviderName,omitempty"`
+
+	// CredentialExpiration is the time at which the credential of the user expires. It is never embedded
+	// into client certificates, because they already have their own expiration.
+	CredentialExpiration *metav1.Time `json:"credentialExpiration,omitempty"`
+}
+
+// ClientCertExtension returns a non-critical X.509 extension which embeds the Info into a client certificate.
+func (i *Info) ClientCertExtension() (pkix.Extension, error) {
+	embedded := *i
+	embedded.CredentialExpiration = nil
+
+	infoJSON, err := json.Marshal(embedded)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("could not encode authentication info: %w", err)
+	}
+
+	value, err := asn1.Marshal(infoJSON)
+	if err != nil {
+		return pkix.Extension{}, fmt.Errorf("could not encode authentication info: %w", err)
+	}
+
+	return pkix.Extension{Id: extensionOID, Value: value}, nil
+}
+
+// FromClientCert returns the Info embedded into the given client certificate, along with its expiration.
+// Client certificates which were not issued by the TokenCredentialRequest API only report their expiration.
+func FromClientCert(cert *x509.Certificate) (*Info, error) {
+	info := &Info{}
+
+	for _, extension := range cert.Extensions {
+		if !extension.Id.Equal(extensionOID) {
+			continue
+		}
+
+		var infoJSON []byte
+		rest, err := asn1.Unmarshal(extension.Value, &infoJSON)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode authentication info: %w", err)
+		}
+		if len(rest) != 0 {
+			return nil, fmt.Errorf("could not decode authentication info: trailing data")
+		}
+		if err := json.Unmarshal(infoJSON, info); err != nil {
+			return nil, fmt.Errorf("could not decode authentication info: %w", err)
+		}
+		break
+	}
+
+	expiration := metav1.NewTime(cert.NotAfter)
+	info.CredentialExpiration = &expiration
+
+	return info, nil
+}
+
+// ToExtra encodes the Info as the value of the ExtraKey user extra.
+func (i *Info) ToExtra() ([]string, error) {
+	infoJSON, err := json.Marshal(i)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode authentication info: %w", err)
+	}
+	return []string{string(infoJSON)}, nil
+}
+
+// FromExtra decodes the Info from the ExtraKey user extra. It returns nil when the extra is not present.
+func FromExtra(extra map[string][]string) (*Info, error) {
+	values, ok := extra[ExtraKey]
+	if !ok {
+		return nil, nil
+	}
+	if len(values) != 1 {
+		return nil, fmt.Errorf("expected exactly one value for user extra %q but got %d", ExtraKey, len(values))
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal([]byte(values[0]), info); err != nil {
+		return nil, fmt.Errorf("could not decode user extra %q: %w", ExtraKey, err)
+	}
+	return info, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package authenticationinfo
+
+import (
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	"go.pinniped.dev/internal/certauthority"
+)
+
+func TestClientCert(t *testing.T) {
+	t.Parallel()
+
+	ca, err := certauthority.New("test CA", time.Hour)
+	require.NoError(t, err)
+
+	info := &Info{
+		Authenticator: &corev1.TypedLocalObjectReference{
+			APIGroup: ptr.To("authentication.concierge.pinniped.dev"),
+			Kind:     "JWTAuthenticator",
+			Name:     "some-jwt-authenticator",
+		},
+		SupervisorIssuer:             "https://supervisor.example.com",
+		UpstreamIdentityProviderName: "some-idp",
+		CredentialExpiration:         ptr.To(metav1.NewTime(time.Now().Add(time.Hour))), // not embedded
+	}
+
+	tests := []struct {
+		name       string
+		extensions func(t *testing.T) []pkix.Extension
+		want       *Info
+		wantErr    string
+	}{
+		{
+			name: "with authentication info",
+			extensions: func(t *testing.T) []pkix.Extension {
+				extension, err := info.ClientCertExtension()
+				require.NoError(t, err)
+				require.False(t, extension.Critical)
+				return []pkix.Extension{extension}
+			},
+			want: &Info{
+				Authenticator:                info.Authenticator,
+				SupervisorIssuer:             info.SupervisorIssuer,
+				UpstreamIdentityProviderName: info.UpstreamIdentityProviderName,
+			},
+		},
+		{
+			name:       "without authentication info",
+			extensions: func(t *testing.T) []pkix.Extension { return nil },
+			want:       &Info{},
+		},
+		{
+			name: "with invalid authentication info",
+			extensions: func(t *testing.T) []pkix.Extension {
+				return []pkix.Extension{{Id: extensionOID, Value: []byte("not DER")}}
+			},
+			wantErr: "could not decode authentication info: asn1: structure error: tags don't match (4 vs {class:1 tag:14 length:111 isCompound:true}) {optional:false explicit:false application:false private:false defaultValue:<nil> tag:<nil> stringType:0 timeType:0 set:false omitEmpty:false}  @2",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cert, err := ca.IssueClientCert("some-user", []string{"some-group"}, 5*time.Minute, tt.extensions(t)...)
+			require.NoError(t, err)
+
+			got, err := FromClientCert(cert.Leaf)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+
+			tt.want.CredentialExpiration = ptr.To(metav1.NewTime(cert.Leaf.NotAfter))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExtra(t *testing.T) {
+	t.Parallel()
+
+	info := &Info{
+		Authenticator:                &corev1.TypedLocalObjectReference{Kind: "WebhookAuthenticator", Name: "some-webhook-authenticator"},
+		SupervisorIssuer:             "https://supervisor.example.com",
+		UpstreamIdentityProviderName: "some-idp",
+		CredentialExpiration:         ptr.To(metav1.NewTime(time.Date(2026, 4, 13, 9, 57, 0, 0, time.UTC).Local())),
+	}
+	value, err := info.ToExtra()
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		`{"authenticator":{"apiGroup":null,"kind":"WebhookAuthenticator","name":"some-webhook-authenticator"},` +
+			`"supervisorIssuer":"https://supervisor.example.com","upstreamIdentityProviderName":"some-idp",` +
+			`"credentialExpiration":"2026-04-13T09:57:00Z"}`,
+	}, value)
+
+	tests := []struct {
+		name    string
+		extra   map[string][]string
+		want    *Info
+		wantErr string
+	}{
+		{
+			name:  "present",
+			extra: map[string][]string{ExtraKey: value, "some-other-key": {"some-value"}},
+			want:  info,
+		},
+		{
+			name:  "empty info",
+			extra: map[string][]string{ExtraKey: {"{}"}},
+			want:  &Info{},
+		},
+		{
+			name:  "not present",
+			extra: map[string][]string{"some-other-key": {"some-value"}},
+		},
+		{
+			name:    "too many values",
+			extra:   map[string][]string{ExtraKey: {"{}", "{}"}},
+			wantErr: `expected exactly one value for user extra "authentication-info.impersonation-proxy.concierge.pinniped.dev" but got 2`,
+		},
+		{
+			name:    "invalid value",
+			extra:   map[string][]string{ExtraKey: {"not JSON"}},
+			wantErr: `could not decode user extra "authentication-info.impersonation-proxy.concierge.pinniped.dev": invalid character 'o' in literal null (expecting 'u')`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := FromExtra(tt.extra)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package authenticators contains authenticator interfaces.
@@ -6,6 +6,7 @@ package authenticators
 
 import (
 	"context"
+	"errors"
 
 	"k8s.io/apiserver/pkg/authentication/user"
 )
@@ -26,6 +27,10 @@ import (
 // - nil response
 // - false
 // - an error
+// 4. For an unsuccessful authentication which was prevented by the password policy of the upstream, e.g. an expired password:
+// - nil response
+// - false
+// - an error which wraps one of the password policy errors below
 // Other combinations of return values must be avoided.
 //
 // See k8s.io/apiserver/pkg/authentication/authenticator/interfaces.go for the token authenticator
@@ -34,8 +39,22 @@ type UserAuthenticator interface {
 	AuthenticateUser(ctx context.Context, username, password string) (*Response, bool, error)
 }
 
+// These errors are wrapped by the errors returned by AuthenticateUser when the password policy of the upstream
+// prevented an authentication which would otherwise have succeeded. Compare to them using errors.Is().
+var (
+	ErrPasswordExpired    = errors.New("password expired")
+	ErrPasswordMustChange = errors.New("password must be changed")
+	ErrAccountLocked      = errors.New("account locked")
+)
+
 type Response struct {
 	User                   user.Info
 	DN                     string
 	ExtraRefreshAttributes map[string]string
+	// UpstreamAttributes are the values of any additional attributes which were requested from the upstream
+	// directory, keyed by attribute name. nil when no additional attributes were requested.
+	UpstreamAttributes map[string][]string
+	// Warnings are messages for the user about the upstream account which did not prevent the authentication,
+	// e.g. that their password will expire soon.
+	Warnings []string
 }
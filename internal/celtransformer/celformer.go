@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package celtransformer is an implementation of upstream-to-downstream identity transformations
@@ -19,6 +19,7 @@ import (
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types/ref"
 	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"go.pinniped.dev/internal/idtransform"
 )
@@ -28,6 +29,7 @@ const (
 	groupsVariableName          = "groups"
 	constStringVariableName     = "strConst"
 	constStringListVariableName = "strListConst"
+	upstreamClaimsVariableName  = "upstreamClaims"
 
 	DefaultPolicyRejectedAuthMessage = "authentication was rejected by a configured policy"
 )
@@ -91,6 +93,37 @@ func (c *CELTransformer) CompileTransformation(t CELTransformation, consts *Tran
 	return t.compile(c, consts)
 }
 
+// CompileAdditionalClaim compiles a CEL-based expression which computes a custom claim for downstream ID tokens.
+// The compiled claim can be cached in memory and executed repeatedly and in a thread-safe way.
+// The same restrictions on the consts param apply as for CompileTransformation.
+func (c *CELTransformer) CompileAdditionalClaim(a *AdditionalClaimExpression, consts *TransformationConstants) (idtransform.AdditionalClaim, error) {
+	if consts == nil {
+		consts = &TransformationConstants{}
+	}
+	if err := consts.validateVariableNames(); err != nil {
+		return nil, err
+	}
+	program, err := compileProgram(c, cel.DynType, a.Expression)
+	if err != nil {
+		return nil, err
+	}
+	return &compiledAdditionalClaim{
+		baseCompiledTransformation: &baseCompiledTransformation{
+			program:              program,
+			consts:               consts,
+			maxExpressionRuntime: c.maxExpressionRuntime,
+		},
+		source: a,
+	}, nil
+}
+
+// AdditionalClaimExpression is a CEL expression which computes the value of a custom claim for downstream ID tokens.
+// The expression may return any value which can be represented in JSON. When it returns null, the claim is omitted.
+type AdditionalClaimExpression struct {
+	Name       string
+	Expression string
+}
+
 // CELTransformation can be compiled into an IdentityTransformation.
 type CELTransformation interface {
 	compile(transformer *CELTransformer, consts *TransformationConstants) (idtransform.IdentityTransformation, error)
@@ -135,8 +168,11 @@ func compileProgram(transformer *CELTransformer, expectedExpressionType *cel.Typ
 	}
 
 	// The compiler's type checker has determined the type of the expression's result.
-	// Check that it matches the type that we expect.
-	if ast.OutputType().String() != expectedExpressionType.String() {
+	// Check that it matches the type that we expect. Values read from the upstream claims have dynamic type,
+	// so an expression which uses them may only be known to return dyn (or list(dyn)) until it is evaluated.
+	// In that case, the type of the result is checked when it is converted to a native value during evaluation.
+	// When any type is expected, the type of the result is likewise only checked during evaluation.
+	if expectedExpressionType != cel.DynType && !ast.OutputType().IsAssignableType(expectedExpressionType) {
 		return nil, fmt.Errorf("CEL expression should return type %q but returns type %q", expectedExpressionType, ast.OutputType())
 	}
 
@@ -221,11 +257,21 @@ type compiledAllowAuthenticationPolicy struct {
 	rejectedAuthenticationMessage string
 }
 
-func (c *baseCompiledTransformation) evalProgram(ctx context.Context, username string, groups []string) (ref.Val, error) {
+// Implements idtransform.AdditionalClaim.
+type compiledAdditionalClaim struct {
+	*baseCompiledTransformation
+	source *AdditionalClaimExpression
+}
+
+func (c *baseCompiledTransformation) evalProgram(ctx context.Context, username string, groups []string, upstreamClaims idtransform.UpstreamClaims) (ref.Val, error) {
 	// Limit the runtime of a CEL expression to avoid accidental very expensive expressions.
 	timeoutCtx, cancel := context.WithTimeout(ctx, c.maxExpressionRuntime)
 	defer cancel()
 
+	if upstreamClaims == nil {
+		upstreamClaims = idtransform.UpstreamClaims{}
+	}
+
 	// Evaluation is thread-safe and side effect free. Many inputs can be sent to the same cel.Program
 	// and if fields are present in the input, but not referenced in the expression, they are ignored.
 	// The argument to Eval may either be an `interpreter.Activation` or a `map[string]interface{}`.
@@ -234,12 +280,13 @@ func (c *baseCompiledTransformation) evalProgram(ctx context.Context, username s
 		groupsVariableName:          groups,
 		constStringVariableName:     c.consts.StringConstants,
 		constStringListVariableName: c.consts.StringListConstants,
+		upstreamClaimsVariableName:  map[string]interface{}(upstreamClaims),
 	})
 	return val, err
 }
 
-func (c *compiledUsernameTransformation) Evaluate(ctx context.Context, username string, groups []string) (*idtransform.TransformationResult, error) {
-	val, err := c.evalProgram(ctx, username, groups)
+func (c *compiledUsernameTransformation) Evaluate(ctx context.Context, username string, groups []string, upstreamClaims idtransform.UpstreamClaims) (*idtransform.TransformationResult, error) {
+	val, err := c.evalProgram(ctx, username, groups, upstreamClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -258,8 +305,8 @@ func (c *compiledUsernameTransformation) Evaluate(ctx context.Context, username
 	}, nil
 }
 
-func (c *compiledGroupsTransformation) Evaluate(ctx context.Context, username string, groups []string) (*idtransform.TransformationResult, error) {
-	val, err := c.evalProgram(ctx, username, groups)
+func (c *compiledGroupsTransformation) Evaluate(ctx context.Context, username string, groups []string, upstreamClaims idtransform.UpstreamClaims) (*idtransform.TransformationResult, error) {
+	val, err := c.evalProgram(ctx, username, groups, upstreamClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -278,8 +325,8 @@ func (c *compiledGroupsTransformation) Evaluate(ctx context.Context, username st
 	}, nil
 }
 
-func (c *compiledAllowAuthenticationPolicy) Evaluate(ctx context.Context, username string, groups []string) (*idtransform.TransformationResult, error) {
-	val, err := c.evalProgram(ctx, username, groups)
+func (c *compiledAllowAuthenticationPolicy) Evaluate(ctx context.Context, username string, groups []string, upstreamClaims idtransform.UpstreamClaims) (*idtransform.TransformationResult, error) {
+	val, err := c.evalProgram(ctx, username, groups, upstreamClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -306,6 +353,28 @@ func (c *compiledAllowAuthenticationPolicy) Evaluate(ctx context.Context, userna
 	return result, nil
 }
 
+func (c *compiledAdditionalClaim) Name() string {
+	return c.source.Name
+}
+
+func (c *compiledAdditionalClaim) Evaluate(ctx context.Context, username string, groups []string, upstreamClaims idtransform.UpstreamClaims) (interface{}, error) {
+	val, err := c.evalProgram(ctx, username, groups, upstreamClaims)
+	if err != nil {
+		return nil, err
+	}
+	// Converting to a protobuf Value ensures that the result can be represented in JSON. For example, a map with
+	// non-string keys cannot be converted.
+	nativeValue, err := val.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+	if err != nil {
+		return nil, fmt.Errorf("could not convert expression result to a JSON value: %w", err)
+	}
+	jsonValue, ok := nativeValue.(*structpb.Value)
+	if !ok {
+		return nil, fmt.Errorf("could not convert expression result to a JSON value")
+	}
+	return jsonValue.AsInterface(), nil
+}
+
 type CELTransformationSource struct {
 	Expr   CELTransformation
 	Consts *TransformationConstants
@@ -323,6 +392,16 @@ func (c *compiledAllowAuthenticationPolicy) Source() interface{} {
 	return &CELTransformationSource{Expr: c.sourceExpr, Consts: c.consts}
 }
 
+// CELAdditionalClaimSource is the source of a compiled additional claim.
+type CELAdditionalClaimSource struct {
+	Expr   *AdditionalClaimExpression
+	Consts *TransformationConstants
+}
+
+func (c *compiledAdditionalClaim) Source() interface{} {
+	return &CELAdditionalClaimSource{Expr: c.source, Consts: c.consts}
+}
+
 func newEnv() (*cel.Env, error) {
 	// Note that Kubernetes uses CEL in several places, which are helpful to see as an example of
 	// how to configure the CEL compiler for production usage. Examples:
@@ -335,6 +414,9 @@ func newEnv() (*cel.Env, error) {
 		cel.Variable(groupsVariableName, cel.ListType(cel.StringType)),
 		cel.Variable(constStringVariableName, cel.MapType(cel.StringType, cel.StringType)),
 		cel.Variable(constStringListVariableName, cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+		// The claims or attributes from the upstream identity provider can have values of any type,
+		// so their values are dynamically typed.
+		cel.Variable(upstreamClaimsVariableName, cel.MapType(cel.StringType, cel.DynType)),
 
 		// Enable the strings extensions.
 		// See https://github.com/google/cel-go/tree/master/ext#strings
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package celtransformer
@@ -35,6 +35,8 @@ func TestTransformer(t *testing.T) {
 		consts     *TransformationConstants
 		ctx        context.Context
 
+		upstreamClaims idtransform.UpstreamClaims
+
 		wantUsername            string
 		wantGroups              []string
 		wantAuthRejected        bool
@@ -757,6 +759,102 @@ func TestTransformer(t *testing.T) {
 			},
 			wantEvaluationErr: `identity transformation at index 0: no such key: x`,
 		},
+		{
+			name:     "any transformation can use the upstream claims",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"email":          "ryan@example.com",
+				"email_verified": true,
+				"acr":            "phr",
+				"department":     "engineering",
+			},
+			transforms: []CELTransformation{
+				&AllowAuthenticationPolicy{Expression: `upstreamClaims.email_verified == true && upstreamClaims.acr == "phr"`},
+				&UsernameTransformation{Expression: `upstreamClaims.email`},
+				&GroupsTransformation{Expression: `groups + [upstreamClaims.department]`},
+			},
+			wantUsername: "ryan@example.com",
+			wantGroups:   []string{"admins", "developers", "engineering", "other"},
+		},
+		{
+			name:     "policy can reject authentication based on the upstream claims",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"email_verified": false,
+			},
+			transforms: []CELTransformation{
+				&AllowAuthenticationPolicy{
+					Expression:                    `upstreamClaims.email_verified == true`,
+					RejectedAuthenticationMessage: `email not verified`,
+				},
+			},
+			wantUsername:            "ryan",
+			wantGroups:              []string{"admins", "developers", "other"},
+			wantAuthRejected:        true,
+			wantAuthRejectedMessage: `email not verified`,
+		},
+		{
+			name:     "transformations can use LDAP attributes from the upstream claims, which are lists of strings",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"title": []interface{}{"Manager"},
+				"mail":  []interface{}{"ryan@example.com", "ryan.other@example.com"},
+			},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `upstreamClaims.mail[0]`},
+				&GroupsTransformation{Expression: `"Manager" in upstreamClaims.title ? groups + ["managers"] : groups`},
+			},
+			wantUsername: "ryan@example.com",
+			wantGroups:   []string{"admins", "developers", "managers", "other"},
+		},
+		{
+			name:     "the has macro can be used to check for upstream claims which might not exist",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"department": "engineering",
+			},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `has(upstreamClaims.nickname) ? upstreamClaims.nickname : username`},
+				&GroupsTransformation{Expression: `has(upstreamClaims.department) ? groups + [upstreamClaims.department] : groups`},
+			},
+			wantUsername: "ryan",
+			wantGroups:   []string{"admins", "developers", "engineering", "other"},
+		},
+		{
+			name:     "when upstream claims are not provided, the has macro returns false",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `has(upstreamClaims.email) ? upstreamClaims.email : username`},
+			},
+			wantUsername: "ryan",
+			wantGroups:   []string{"admins", "developers", "other"},
+		},
+		{
+			name:     "using upstream claims which were not provided",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `upstreamClaims.email`},
+			},
+			wantEvaluationErr: `identity transformation at index 0: no such key: email`,
+		},
+		{
+			name:     "using an upstream claim which has the wrong type for the transformation",
+			username: "ryan",
+			groups:   []string{"admins", "developers", "other"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"email_verified": true,
+			},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `upstreamClaims.email_verified`},
+			},
+			wantEvaluationErr: `identity transformation at index 0: could not convert expression result to string: type conversion error from bool to 'string'`,
+		},
 		{
 			name:     "using an illegal name for a string constant",
 			username: "ryan",
@@ -814,7 +912,7 @@ func TestTransformer(t *testing.T) {
 				ctx = tt.ctx
 			}
 
-			result, err := pipeline.Evaluate(ctx, tt.username, tt.groups)
+			result, err := pipeline.Evaluate(ctx, tt.username, tt.groups, tt.upstreamClaims)
 			if tt.wantEvaluationErr != "" {
 				require.EqualError(t, err, tt.wantEvaluationErr)
 				return // the rest of the test doesn't make sense when there was an evaluation error
@@ -831,6 +929,164 @@ func TestTransformer(t *testing.T) {
 	}
 }
 
+func TestAdditionalClaims(t *testing.T) {
+	tests := []struct {
+		name             string
+		username         string
+		groups           []string
+		upstreamClaims   idtransform.UpstreamClaims
+		transforms       []CELTransformation
+		additionalClaims []*AdditionalClaimExpression
+		consts           *TransformationConstants
+
+		wantAdditionalClaims map[string]interface{}
+		wantAuthRejected     bool
+		wantCompileErr       string
+		wantEvaluationErr    string
+	}{
+		{
+			name:                 "no additional claims",
+			username:             "ryan",
+			wantAdditionalClaims: nil,
+		},
+		{
+			name:     "additional claims of various types are computed from the transformed identity",
+			username: "ryan",
+			groups:   []string{"b", "a", "b"},
+			upstreamClaims: idtransform.UpstreamClaims{
+				"email":  "ryan@example.com",
+				"tenant": []interface{}{"t1"},
+			},
+			consts: &TransformationConstants{StringConstants: map[string]string{"domain": "corp"}},
+			transforms: []CELTransformation{
+				&UsernameTransformation{Expression: `strConst.domain + ":" + username`},
+			},
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `upstreamClaims.email`},
+				{Name: "displayName", Expression: `username.upperAscii()`},
+				{Name: "tenantID", Expression: `upstreamClaims.tenant[0] + "-" + strConst.domain`},
+				{Name: "groupCount", Expression: `size(groups)`},
+				{Name: "isAdmin", Expression: `"admins" in groups`},
+				{Name: "sortedGroups", Expression: `groups`},
+				{Name: "details", Expression: `{"first": groups[0], "last": groups[1]}`},
+			},
+			wantAdditionalClaims: map[string]interface{}{
+				"email":        "ryan@example.com",
+				"displayName":  "CORP:RYAN",
+				"tenantID":     "t1-corp",
+				"groupCount":   float64(2),
+				"isAdmin":      false,
+				"sortedGroups": []interface{}{"a", "b"},
+				"details":      map[string]interface{}{"first": "a", "last": "b"},
+			},
+		},
+		{
+			name:     "an additional claim which returns null is omitted",
+			username: "ryan",
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `has(upstreamClaims.email) ? upstreamClaims.email : null`},
+				{Name: "present", Expression: `"yes"`},
+			},
+			wantAdditionalClaims: map[string]interface{}{
+				"present": "yes",
+			},
+		},
+		{
+			name:     "additional claims are not computed when auth is rejected",
+			username: "ryan",
+			transforms: []CELTransformation{
+				&AllowAuthenticationPolicy{Expression: `false`},
+			},
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `upstreamClaims.email`},
+			},
+			wantAuthRejected: true,
+		},
+		{
+			name:     "an additional claim which does not compile",
+			username: "ryan",
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `this is not valid cel`},
+			},
+			wantCompileErr: "CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>\n" +
+				" | this is not valid cel\n" +
+				" | .....^",
+		},
+		{
+			name:     "an additional claim with an illegal constant name",
+			username: "ryan",
+			consts:   &TransformationConstants{StringConstants: map[string]string{" illegal": "a"}},
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `username`},
+			},
+			wantCompileErr: `" illegal" is an invalid const variable name (must match [_a-zA-Z][_a-zA-Z0-9]*)`,
+		},
+		{
+			name:     "an additional claim which causes a runtime error",
+			username: "ryan",
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "email", Expression: `upstreamClaims.email`},
+			},
+			wantEvaluationErr: `additional claim "email": no such key: email`,
+		},
+		{
+			name:     "an additional claim which cannot be represented in JSON",
+			username: "ryan",
+			additionalClaims: []*AdditionalClaimExpression{
+				{Name: "intKeys", Expression: `{1: "a"}`},
+			},
+			wantEvaluationErr: `additional claim "intKeys": could not convert expression result to a JSON value: ` +
+				`unsupported type conversion from 'int' to string`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transformer, err := NewCELTransformer(100 * time.Millisecond)
+			require.NoError(t, err)
+
+			pipeline := idtransform.NewTransformationPipeline()
+			for _, transform := range tt.transforms {
+				compiledTransform, err := transformer.CompileTransformation(transform, tt.consts)
+				require.NoError(t, err)
+				pipeline.AppendTransformation(compiledTransform)
+			}
+
+			expectedClaimsSource := []interface{}{}
+			for _, claim := range tt.additionalClaims {
+				compiledClaim, err := transformer.CompileAdditionalClaim(claim, tt.consts)
+				if tt.wantCompileErr != "" {
+					require.EqualError(t, err, tt.wantCompileErr)
+					return // the rest of the test doesn't make sense when there was a compile error
+				}
+				require.NoError(t, err, "got an unexpected compile error")
+				require.Equal(t, claim.Name, compiledClaim.Name())
+				pipeline.AppendAdditionalClaim(compiledClaim)
+
+				expectedClaimSource := &CELAdditionalClaimSource{Expr: claim, Consts: tt.consts}
+				if expectedClaimSource.Consts == nil {
+					expectedClaimSource.Consts = &TransformationConstants{}
+				}
+				expectedClaimsSource = append(expectedClaimsSource, expectedClaimSource)
+			}
+
+			result, err := pipeline.Evaluate(context.Background(), tt.username, tt.groups, tt.upstreamClaims)
+			if tt.wantEvaluationErr != "" {
+				require.EqualError(t, err, tt.wantEvaluationErr)
+				return // the rest of the test doesn't make sense when there was an evaluation error
+			}
+			require.NoError(t, err, "got an unexpected evaluation error")
+
+			require.Equal(t, !tt.wantAuthRejected, result.AuthenticationAllowed, "AuthenticationAllowed had unexpected value")
+			require.Equal(t, tt.wantAdditionalClaims, result.AdditionalClaims)
+			require.Equal(t, expectedClaimsSource, pipeline.Source()[len(tt.transforms):])
+		})
+	}
+}
+
 func TestTypicalPerformanceAndThreadSafety(t *testing.T) {
 	t.Parallel()
 
@@ -859,7 +1115,7 @@ func TestTypicalPerformanceAndThreadSafety(t *testing.T) {
 	sort.Strings(wantGroups)
 
 	// Before looking at performance, check that the behavior of the function is correct.
-	result, err := pipeline.Evaluate(context.Background(), "ryan", groups)
+	result, err := pipeline.Evaluate(context.Background(), "ryan", groups, nil)
 	require.NoError(t, err)
 	require.Equal(t, "username_prefix:ryan", result.Username)
 	require.Equal(t, wantGroups, result.Groups)
@@ -871,7 +1127,7 @@ func TestTypicalPerformanceAndThreadSafety(t *testing.T) {
 	iterations := 1000
 	start := time.Now()
 	for i := 0; i < iterations; i++ {
-		_, _ = pipeline.Evaluate(context.Background(), "ryan", groups)
+		_, _ = pipeline.Evaluate(context.Background(), "ryan", groups, nil)
 	}
 	elapsed := time.Since(start)
 	t.Logf("TestTypicalPerformanceAndThreadSafety %d iterations of Evaluate took %s; average runtime %s", iterations, elapsed, elapsed/time.Duration(iterations))
@@ -890,7 +1146,7 @@ func TestTypicalPerformanceAndThreadSafety(t *testing.T) {
 		go func() {
 			defer wg.Done() // decrement WaitGroup counter when this goroutine finishes
 			for j := 0; j < iterations*2; j++ {
-				localResult, localErr := pipeline.Evaluate(context.Background(), "ryan", groups)
+				localResult, localErr := pipeline.Evaluate(context.Background(), "ryan", groups, nil)
 				require.NoError(t, localErr)
 				require.Equal(t, "username_prefix:ryan", localResult.Username)
 				require.Equal(t, wantGroups, localResult.Groups)
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package certauthority implements a simple x509 certificate authority suitable for use in an aggregated API service.
@@ -168,21 +168,21 @@ func (c *CA) Pool() *x509.CertPool {
 }
 
 // IssueClientCert issues a new client certificate with username and groups included in the Kube-style
-// certificate subject for the given identity and duration.
-func (c *CA) IssueClientCert(username string, groups []string, ttl time.Duration) (*tls.Certificate, error) {
-	return c.issueCert(x509.ExtKeyUsageClientAuth, pkix.Name{CommonName: username, Organization: groups}, nil, nil, ttl)
+// certificate subject for the given identity and duration. Any extensions are added to the certificate as-is.
+func (c *CA) IssueClientCert(username string, groups []string, ttl time.Duration, extensions ...pkix.Extension) (*tls.Certificate, error) {
+	return c.issueCert(x509.ExtKeyUsageClientAuth, pkix.Name{CommonName: username, Organization: groups}, nil, nil, extensions, ttl)
 }
 
 // IssueServerCert issues a new server certificate for the given identity and duration.
 // The dnsNames and ips are each optional, but at least one of them should be specified.
 func (c *CA) IssueServerCert(dnsNames []string, ips []net.IP, ttl time.Duration) (*tls.Certificate, error) {
-	return c.issueCert(x509.ExtKeyUsageServerAuth, pkix.Name{}, dnsNames, ips, ttl)
+	return c.issueCert(x509.ExtKeyUsageServerAuth, pkix.Name{}, dnsNames, ips, nil, ttl)
 }
 
 // IssueClientCertPEM is similar to IssueClientCert, but returns the new cert as a pair of PEM-formatted byte slices
 // for the certificate and private key.
-func (c *CA) IssueClientCertPEM(username string, groups []string, ttl time.Duration) ([]byte, []byte, error) {
-	return toPEM(c.IssueClientCert(username, groups, ttl))
+func (c *CA) IssueClientCertPEM(username string, groups []string, ttl time.Duration, extensions ...pkix.Extension) ([]byte, []byte, error) {
+	return toPEM(c.IssueClientCert(username, groups, ttl, extensions...))
 }
 
 // IssueServerCertPEM is similar to IssueServerCert, but returns the new cert as a pair of PEM-formatted byte slices
@@ -191,7 +191,7 @@ func (c *CA) IssueServerCertPEM(dnsNames []string, ips []net.IP, ttl time.Durati
 	return toPEM(c.IssueServerCert(dnsNames, ips, ttl))
 }
 
-func (c *CA) issueCert(extKeyUsage x509.ExtKeyUsage, subject pkix.Name, dnsNames []string, ips []net.IP, ttl time.Duration) (*tls.Certificate, error) {
+func (c *CA) issueCert(extKeyUsage x509.ExtKeyUsage, subject pkix.Name, dnsNames []string, ips []net.IP, extensions []pkix.Extension, ttl time.Duration) (*tls.Certificate, error) {
 	// Choose a random 128 bit serial number.
 	serialNumber, err := randomSerial(c.env.serialRNG)
 	if err != nil {
@@ -226,6 +226,7 @@ func (c *CA) issueCert(extKeyUsage x509.ExtKeyUsage, subject pkix.Name, dnsNames
 		IsCA:                  false,
 		DNSNames:              dnsNames,
 		IPAddresses:           ips,
+		ExtraExtensions:       extensions,
 	}
 	certBytes, err := x509.CreateCertificate(rand.Reader, &template, caCert, &privateKey.PublicKey, c.signer)
 	if err != nil {
@@ -1,4 +1,4 @@
-// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package dynamiccertauthority implements a x509 certificate authority capable of issuing
@@ -6,6 +6,7 @@
 package dynamiccertauthority
 
 import (
+	"crypto/x509/pkix"
 	"time"
 
 	"k8s.io/apiserver/pkg/server/dynamiccertificates"
@@ -33,7 +34,7 @@ func (c *ca) Name() string {
 
 // IssueClientCertPEM issues a new client certificate for the given identity and duration, returning it as a
 // pair of PEM-formatted byte slices for the certificate and private key.
-func (c *ca) IssueClientCertPEM(username string, groups []string, ttl time.Duration) ([]byte, []byte, error) {
+func (c *ca) IssueClientCertPEM(username string, groups []string, ttl time.Duration, extensions ...pkix.Extension) ([]byte, []byte, error) {
 	caCrtPEM, caKeyPEM := c.provider.CurrentCertKeyContent()
 	// in the future we could split dynamiccert.Private into two interfaces (Private and PrivateRead)
 	// and have this code take PrivateRead as input.  We would then add ourselves as a listener to
@@ -43,5 +44,5 @@ func (c *ca) IssueClientCertPEM(username string, groups []string, ttl time.Durat
 		return nil, nil, err
 	}
 
-	return ca.IssueClientCertPEM(username, groups, ttl)
+	return ca.IssueClientCertPEM(username, groups, ttl, extensions...)
 }
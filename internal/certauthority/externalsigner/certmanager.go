@@ -0,0 +1,138 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package externalsigner
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+// CertificateRequestGVR is the cert-manager CertificateRequest resource. cert-manager is accessed through the
+// dynamic client to avoid depending on its Go module.
+//
+//nolint:gochecknoglobals
+var CertificateRequestGVR = schema.GroupVersionResource{
+	Group:    "cert-manager.io",
+	Version:  "v1",
+	Resource: "certificaterequests",
+}
+
+// CertManagerIssuerRef references a cert-manager Issuer or ClusterIssuer.
+type CertManagerIssuerRef struct {
+	Name  string
+	Kind  string
+	Group string
+}
+
+type certManagerSigner struct {
+	client    dynamic.NamespaceableResourceInterface
+	namespace string
+	issuerRef CertManagerIssuerRef
+}
+
+// NewCertManagerSigner returns a Signer which creates cert-manager CertificateRequests in the given namespace
+// for the given issuer and waits for their certificates to be issued.
+func NewCertManagerSigner(client dynamic.Interface, namespace string, issuerRef CertManagerIssuerRef) Signer {
+	return &certManagerSigner{
+		client:    client.Resource(CertificateRequestGVR),
+		namespace: namespace,
+		issuerRef: issuerRef,
+	}
+}
+
+func (s *certManagerSigner) Name() string {
+	return fmt.Sprintf("cert-manager:%s.%s/%s", s.issuerRef.Kind, s.issuerRef.Group, s.issuerRef.Name)
+}
+
+func (s *certManagerSigner) Sign(ctx context.Context, csrPEM []byte, ttl time.Duration) ([]byte, error) {
+	client := s.client.Namespace(s.namespace)
+
+	cr, err := client.Create(ctx, &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": CertificateRequestGVR.GroupVersion().String(),
+		"kind":       "CertificateRequest",
+		"metadata": map[string]any{
+			"generateName": "pinniped-client-",
+			"namespace":    s.namespace,
+		},
+		"spec": map[string]any{
+			"request":  base64.StdEncoding.EncodeToString(csrPEM), // a []byte field in cert-manager's own types
+			"duration": ttl.String(),
+			"usages":   []any{"digital signature", "client auth"},
+			"issuerRef": map[string]any{
+				"name":  s.issuerRef.Name,
+				"kind":  s.issuerRef.Kind,
+				"group": s.issuerRef.Group,
+			},
+		},
+	}}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create cert-manager certificate request: %w", err)
+	}
+	name := cr.GetName()
+
+	// The certificate is returned to the client, so the request is not needed anymore regardless of the outcome.
+	defer func() {
+		if err := client.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			plog.Warning("could not delete cert-manager certificate request", "namespace", s.namespace, "name", name, "err", err)
+		}
+	}()
+
+	var certPEM []byte
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if err := certManagerRequestFailure(current); err != nil {
+			return false, err
+		}
+		cert, _, err := unstructured.NestedString(current.Object, "status", "certificate")
+		if err != nil || len(cert) == 0 {
+			return false, nil //nolint:nilerr // keep waiting until the issuer has written a well-formed status
+		}
+		certPEM, err = base64.StdEncoding.DecodeString(cert)
+		if err != nil {
+			return false, fmt.Errorf("invalid status.certificate: %w", err)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cert-manager certificate request %s/%s was not signed by %s: %w", s.namespace, name, s.Name(), err)
+	}
+
+	return certPEM, nil
+}
+
+// certManagerRequestFailure returns an error when the CertificateRequest reached a terminal failure state.
+func certManagerRequestFailure(cr *unstructured.Unstructured) error {
+	conditions, _, _ := unstructured.NestedSlice(cr.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		conditionType, _, _ := unstructured.NestedString(condition, "type")
+		status, _, _ := unstructured.NestedString(condition, "status")
+		reason, _, _ := unstructured.NestedString(condition, "reason")
+		message, _, _ := unstructured.NestedString(condition, "message")
+
+		switch {
+		case conditionType == "Denied" && status == "True",
+			conditionType == "InvalidRequest" && status == "True",
+			conditionType == "Ready" && status == "False" && (reason == "Failed" || reason == "Denied"):
+			return fmt.Errorf("certificate request is %s (%s): %s", conditionType, reason, message)
+		}
+	}
+	return nil
+}
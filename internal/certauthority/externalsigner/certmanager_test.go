@@ -0,0 +1,173 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package externalsigner
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestCertManagerSigner(t *testing.T) {
+	t.Parallel()
+
+	sign := testSigningCA(t)
+
+	readyCondition := func(status, reason, message string) map[string]any {
+		return map[string]any{"type": "Ready", "status": status, "reason": reason, "message": message}
+	}
+
+	tests := []struct {
+		name string
+		// issuer acts on the stored CertificateRequest when it is fetched, like cert-manager would.
+		issuer              func(t *testing.T, cr *unstructured.Unstructured)
+		addReactors         func(client *dynamicfake.FakeDynamicClient)
+		wantError           string
+		wantDeleteAttempted bool
+	}{
+		{
+			name: "issued",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				request, _, err := unstructured.NestedString(cr.Object, "spec", "request")
+				require.NoError(t, err)
+				csrPEM, err := base64.StdEncoding.DecodeString(request)
+				require.NoError(t, err)
+				require.NoError(t, unstructured.SetNestedField(cr.Object,
+					base64.StdEncoding.EncodeToString(sign(csrPEM, time.Hour, nil)), "status", "certificate"))
+				require.NoError(t, unstructured.SetNestedSlice(cr.Object,
+					[]any{readyCondition("True", "Issued", "Certificate fetched from issuer successfully")}, "status", "conditions"))
+			},
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "denied",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				require.NoError(t, unstructured.SetNestedSlice(cr.Object, []any{
+					map[string]any{"type": "Denied", "status": "True", "reason": "SomePolicy", "message": "not allowed"},
+					readyCondition("False", "Denied", "The CertificateRequest was denied by an approval controller"),
+				}, "status", "conditions"))
+			},
+			wantError:           "certificate request is Denied (SomePolicy): not allowed",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "invalid request",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				require.NoError(t, unstructured.SetNestedSlice(cr.Object, []any{
+					map[string]any{"type": "InvalidRequest", "status": "True", "reason": "BadConfig", "message": "bad request"},
+				}, "status", "conditions"))
+			},
+			wantError:           "certificate request is InvalidRequest (BadConfig): bad request",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "failed",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				require.NoError(t, unstructured.SetNestedSlice(cr.Object,
+					[]any{readyCondition("False", "Failed", "issuer is broken")}, "status", "conditions"))
+			},
+			wantError:           "cert-manager certificate request some-namespace/ was not signed by cert-manager:ClusterIssuer.cert-manager.io/some-issuer: certificate request is Ready (Failed): issuer is broken",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "pending forever",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				require.NoError(t, unstructured.SetNestedSlice(cr.Object,
+					[]any{readyCondition("False", "Pending", "waiting")}, "status", "conditions"))
+			},
+			wantError:           "context deadline exceeded",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "invalid certificate encoding",
+			issuer: func(t *testing.T, cr *unstructured.Unstructured) {
+				require.NoError(t, unstructured.SetNestedField(cr.Object, "not base64!", "status", "certificate"))
+			},
+			wantError:           "invalid status.certificate: ",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "create fails",
+			addReactors: func(client *dynamicfake.FakeDynamicClient) {
+				client.PrependReactor("create", "certificaterequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			wantError: "could not create cert-manager certificate request: some create error",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{CertificateRequestGVR: "CertificateRequestList"})
+			if tt.issuer != nil {
+				client.PrependReactor("get", "certificaterequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+					obj, err := client.Tracker().Get(action.GetResource(), action.GetNamespace(), action.(coretesting.GetAction).GetName())
+					if err != nil {
+						return true, nil, err
+					}
+					cr := obj.(*unstructured.Unstructured).DeepCopy()
+					tt.issuer(t, cr)
+					return true, cr, nil
+				})
+			}
+			if tt.addReactors != nil {
+				tt.addReactors(client)
+			}
+
+			signer := NewCertManagerSigner(client, "some-namespace", CertManagerIssuerRef{
+				Name:  "some-issuer",
+				Kind:  "ClusterIssuer",
+				Group: "cert-manager.io",
+			})
+			require.Equal(t, "cert-manager:ClusterIssuer.cert-manager.io/some-issuer", signer.Name())
+
+			if tt.wantError == "" {
+				issuer := New()
+				issuer.Set(signer)
+				_, _, err := issuer.IssueClientCertPEM("some-user", []string{"some-group"}, time.Hour)
+				require.NoError(t, err)
+			} else {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				_, err := signer.Sign(ctx, []byte("some-csr"), time.Hour)
+				require.ErrorContains(t, err, tt.wantError)
+			}
+
+			var created *unstructured.Unstructured
+			var deleted bool
+			for _, action := range client.Actions() {
+				require.Equal(t, "some-namespace", action.GetNamespace())
+				switch {
+				case action.Matches("create", "certificaterequests"):
+					created = action.(coretesting.CreateAction).GetObject().(*unstructured.Unstructured)
+				case action.Matches("delete", "certificaterequests"):
+					deleted = true
+				}
+			}
+			require.NotNil(t, created)
+			require.Equal(t, "cert-manager.io/v1", created.GetAPIVersion())
+			require.Equal(t, "CertificateRequest", created.GetKind())
+			require.Equal(t, "pinniped-client-", created.GetGenerateName())
+			spec, _, err := unstructured.NestedMap(created.Object, "spec")
+			require.NoError(t, err)
+			require.Equal(t, "1h0m0s", spec["duration"])
+			require.Equal(t, []any{"digital signature", "client auth"}, spec["usages"])
+			require.Equal(t, map[string]any{"name": "some-issuer", "kind": "ClusterIssuer", "group": "cert-manager.io"}, spec["issuerRef"])
+			require.Equal(t, tt.wantDeleteAttempted, deleted)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package externalsigner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	certificatesv1client "k8s.io/client-go/kubernetes/typed/certificates/v1"
+	"k8s.io/utils/ptr"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+// minCSRExpirationSeconds is the smallest value allowed by the Kubernetes API for spec.expirationSeconds.
+const minCSRExpirationSeconds = 600
+
+type csrSigner struct {
+	client      certificatesv1client.CertificateSigningRequestInterface
+	signerName  string
+	autoApprove bool
+}
+
+// NewCertificateSigningRequestSigner returns a Signer which creates a Kubernetes CertificateSigningRequest for
+// the given signerName and waits for its certificate to be issued. When autoApprove is true, the Concierge
+// approves its own requests, which requires permission to approve requests for the signerName.
+func NewCertificateSigningRequestSigner(
+	client certificatesv1client.CertificateSigningRequestInterface,
+	signerName string,
+	autoApprove bool,
+) Signer {
+	return &csrSigner{client: client, signerName: signerName, autoApprove: autoApprove}
+}
+
+func (s *csrSigner) Name() string {
+	return "csr:" + s.signerName
+}
+
+func (s *csrSigner) Sign(ctx context.Context, csrPEM []byte, ttl time.Duration) ([]byte, error) {
+	expirationSeconds := int32(ttl.Seconds())
+	if expirationSeconds < minCSRExpirationSeconds {
+		expirationSeconds = minCSRExpirationSeconds
+	}
+
+	csr, err := s.client.Create(ctx, &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: "pinniped-client-"},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           csrPEM,
+			SignerName:        s.signerName,
+			ExpirationSeconds: ptr.To(expirationSeconds),
+			Usages: []certificatesv1.KeyUsage{
+				certificatesv1.UsageDigitalSignature,
+				certificatesv1.UsageClientAuth,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create certificate signing request: %w", err)
+	}
+	name := csr.Name
+
+	// The certificate is returned to the client, so the request is not needed anymore regardless of the outcome.
+	defer func() {
+		if err := s.client.Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+			plog.Warning("could not delete certificate signing request", "name", name, "err", err)
+		}
+	}()
+
+	if s.autoApprove {
+		csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+			Type:    certificatesv1.CertificateApproved,
+			Status:  corev1.ConditionTrue,
+			Reason:  "PinnipedConciergeApproved",
+			Message: "approved by the Pinniped Concierge for a TokenCredentialRequest",
+		})
+		if _, err := s.client.UpdateApproval(ctx, name, csr, metav1.UpdateOptions{}); err != nil {
+			return nil, fmt.Errorf("could not approve certificate signing request: %w", err)
+		}
+	}
+
+	var certPEM []byte
+	err = wait.PollUntilContextCancel(ctx, pollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := s.client.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type { //nolint:exhaustive // only the terminal failure conditions are interesting here
+			case certificatesv1.CertificateDenied, certificatesv1.CertificateFailed:
+				return false, fmt.Errorf("certificate signing request was %s: %s", condition.Type, condition.Message)
+			}
+		}
+		if len(current.Status.Certificate) == 0 {
+			return false, nil
+		}
+		certPEM = current.Status.Certificate
+		return true, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("certificate signing request %q was not signed by %q: %w", name, s.signerName, err)
+	}
+
+	return certPEM, nil
+}
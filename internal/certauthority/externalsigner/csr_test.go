@@ -0,0 +1,184 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package externalsigner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestCertificateSigningRequestSigner(t *testing.T) {
+	t.Parallel()
+
+	sign := testSigningCA(t)
+
+	tests := []struct {
+		name        string
+		autoApprove bool
+		ttl         time.Duration
+		// signer acts on the stored CertificateSigningRequest when it is fetched, like a signer controller would.
+		signer              func(t *testing.T, csr *certificatesv1.CertificateSigningRequest)
+		addReactors         func(client *kubernetesfake.Clientset)
+		wantExpirationSecs  int32
+		wantApproved        bool
+		wantError           string
+		wantDeleteAttempted bool
+	}{
+		{
+			name: "signed after being approved by someone else",
+			ttl:  time.Hour,
+			signer: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Certificate = sign(csr.Spec.Request, time.Hour, nil)
+			},
+			wantExpirationSecs:  3600,
+			wantDeleteAttempted: true,
+		},
+		{
+			name:        "auto approved",
+			autoApprove: true,
+			ttl:         5 * time.Minute,
+			signer: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				require.Len(t, csr.Status.Conditions, 1)
+				require.Equal(t, certificatesv1.CertificateApproved, csr.Status.Conditions[0].Type)
+				csr.Status.Certificate = sign(csr.Spec.Request, time.Hour, nil)
+			},
+			wantExpirationSecs:  600,
+			wantApproved:        true,
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "denied",
+			ttl:  time.Hour,
+			signer: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateDenied,
+					Status:  corev1.ConditionTrue,
+					Message: "not today",
+				})
+			},
+			wantExpirationSecs:  3600,
+			wantError:           `certificate signing request "" was not signed by "example.com/some-signer": certificate signing request was Denied: not today`,
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "failed",
+			ttl:  time.Hour,
+			signer: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateFailed,
+					Status:  corev1.ConditionTrue,
+					Message: "signer is broken",
+				})
+			},
+			wantExpirationSecs:  3600,
+			wantError:           "certificate signing request was Failed: signer is broken",
+			wantDeleteAttempted: true,
+		},
+		{
+			name:                "never signed",
+			ttl:                 time.Hour,
+			signer:              func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {},
+			wantExpirationSecs:  3600,
+			wantError:           "context deadline exceeded",
+			wantDeleteAttempted: true,
+		},
+		{
+			name: "create fails",
+			ttl:  time.Hour,
+			addReactors: func(client *kubernetesfake.Clientset) {
+				client.PrependReactor("create", "certificatesigningrequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			wantError: "could not create certificate signing request: some create error",
+		},
+		{
+			name:        "approval fails",
+			autoApprove: true,
+			ttl:         time.Hour,
+			addReactors: func(client *kubernetesfake.Clientset) {
+				client.PrependReactor("update", "certificatesigningrequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some approval error")
+				})
+			},
+			wantExpirationSecs:  3600,
+			wantApproved:        true,
+			wantError:           "could not approve certificate signing request: some approval error",
+			wantDeleteAttempted: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := kubernetesfake.NewSimpleClientset()
+			if tt.signer != nil {
+				client.PrependReactor("get", "certificatesigningrequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+					obj, err := client.Tracker().Get(action.GetResource(), "", action.(coretesting.GetAction).GetName())
+					if err != nil {
+						return true, nil, err
+					}
+					csr := obj.(*certificatesv1.CertificateSigningRequest).DeepCopy()
+					tt.signer(t, csr)
+					return true, csr, nil
+				})
+			}
+			if tt.addReactors != nil {
+				tt.addReactors(client)
+			}
+
+			signer := NewCertificateSigningRequestSigner(client.CertificatesV1().CertificateSigningRequests(), "example.com/some-signer", tt.autoApprove)
+			require.Equal(t, "csr:example.com/some-signer", signer.Name())
+
+			issuer := New()
+			issuer.Set(signer)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			csrPEM := []byte("-----BEGIN CERTIFICATE REQUEST-----\n-----END CERTIFICATE REQUEST-----\n")
+			if tt.wantError == "" {
+				// Use a real certificate request when the request is expected to be signed.
+				_, _, err := issuer.IssueClientCertPEM("some-user", []string{"some-group"}, tt.ttl)
+				require.NoError(t, err)
+			} else {
+				_, err := signer.Sign(ctx, csrPEM, tt.ttl)
+				require.ErrorContains(t, err, tt.wantError)
+			}
+
+			var created *certificatesv1.CertificateSigningRequest
+			var approved, deleted bool
+			for _, action := range client.Actions() {
+				switch {
+				case action.Matches("create", "certificatesigningrequests"):
+					created = action.(coretesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+				case action.Matches("update", "certificatesigningrequests"):
+					require.Equal(t, "approval", action.GetSubresource())
+					approved = true
+				case action.Matches("delete", "certificatesigningrequests"):
+					deleted = true
+				}
+			}
+			require.NotNil(t, created)
+			require.Equal(t, "pinniped-client-", created.GenerateName)
+			require.Equal(t, "example.com/some-signer", created.Spec.SignerName)
+			require.Equal(t, []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth}, created.Spec.Usages)
+			if tt.wantExpirationSecs != 0 {
+				require.Equal(t, tt.wantExpirationSecs, *created.Spec.ExpirationSeconds)
+			}
+			require.Equal(t, tt.wantApproved, approved)
+			require.Equal(t, tt.wantDeleteAttempted, deleted)
+		})
+	}
+}
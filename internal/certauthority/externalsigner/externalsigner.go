@@ -0,0 +1,160 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package externalsigner implements a client certificate issuer which generates the private key and the certificate
+// request locally, but delegates the signing of the certificate to a signer outside the Concierge, such as the
+// Kubernetes CertificateSigningRequest API or cert-manager.
+package externalsigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"go.pinniped.dev/internal/clientcertissuer"
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	// ErrNotConfigured is returned when no external signer is currently configured.
+	ErrNotConfigured = constable.Error("no external signer is configured")
+
+	// signTimeout bounds how long a single TokenCredentialRequest waits for an external signer.
+	signTimeout = 30 * time.Second
+
+	// pollInterval is how often the status of a pending certificate request is checked.
+	pollInterval = 250 * time.Millisecond
+)
+
+// Signer signs x509 certificate requests for client certificates.
+type Signer interface {
+	// Name describes the signer, e.g. for use in error messages.
+	Name() string
+
+	// Sign returns the PEM encoded certificate chain issued for the given PEM encoded certificate request.
+	Sign(ctx context.Context, csrPEM []byte, ttl time.Duration) ([]byte, error)
+}
+
+// Dynamic is a ClientCertIssuer which issues certificates through the most recently Set Signer.
+type Dynamic interface {
+	clientcertissuer.ClientCertIssuer
+
+	// Set changes the Signer. A nil Signer causes all attempts to issue certificates to fail.
+	Set(signer Signer)
+}
+
+type dynamicSigner struct {
+	lock   sync.RWMutex
+	signer Signer
+}
+
+var _ Dynamic = &dynamicSigner{}
+
+// New returns a Dynamic which fails to issue certificates until its Signer is Set.
+func New() Dynamic {
+	return &dynamicSigner{}
+}
+
+func (d *dynamicSigner) Set(signer Signer) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.signer = signer
+}
+
+func (d *dynamicSigner) currentSigner() Signer {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return d.signer
+}
+
+func (d *dynamicSigner) Name() string {
+	if signer := d.currentSigner(); signer != nil {
+		return "external-signer:" + signer.Name()
+	}
+	return "external-signer"
+}
+
+// IssueClientCertPEM issues a new client certificate for the given identity and duration, returning it as a
+// pair of PEM-formatted byte slices for the certificate and private key. The extensions are ignored, because
+// the external signer decides the contents of the certificates that it issues.
+func (d *dynamicSigner) IssueClientCertPEM(username string, groups []string, ttl time.Duration, _ ...pkix.Extension) ([]byte, []byte, error) {
+	signer := d.currentSigner()
+	if signer == nil {
+		return nil, nil, ErrNotConfigured
+	}
+
+	// Generate a new P256 keypair, just like certauthority.CA does for the certificates that it signs.
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate private key: %w", err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: username, Organization: groups},
+	}, privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create certificate request: %w", err)
+	}
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	ctx, cancel := context.WithTimeout(context.Background(), signTimeout)
+	defer cancel()
+
+	certPEM, err := signer.Sign(ctx, csrPEM, ttl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := checkIssuedCert(certPEM, &privateKey.PublicKey, username, groups); err != nil {
+		return nil, nil, err
+	}
+
+	privateKeyPKCS8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal private key into PKCS8: %w", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyPKCS8})
+
+	return certPEM, keyPEM, nil
+}
+
+// checkIssuedCert makes sure that the external signer did not change the identity asserted by the certificate,
+// since that identity is exactly what the Kubernetes API server will use to authenticate the client. It also makes
+// sure that the certificate can be used by the client as a client certificate together with the private key.
+func checkIssuedCert(certPEM []byte, publicKey *ecdsa.PublicKey, username string, groups []string) error {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return constable.Error("external signer did not return a PEM encoded certificate")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("external signer returned an invalid certificate: %w", err)
+	}
+
+	if cert.Subject.CommonName != username || !slices.Equal(cert.Subject.Organization, groups) {
+		return constable.Error("external signer returned a certificate with an unexpected subject")
+	}
+
+	if !publicKey.Equal(cert.PublicKey) {
+		return constable.Error("external signer returned a certificate for an unexpected public key")
+	}
+
+	if !slices.Contains(cert.ExtKeyUsage, x509.ExtKeyUsageClientAuth) {
+		return constable.Error("external signer returned a certificate which cannot be used for client authentication")
+	}
+
+	if !time.Now().Before(cert.NotAfter) {
+		return constable.Error("external signer returned a certificate which has already expired")
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package externalsigner
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSigner struct {
+	sign func(csrPEM []byte, ttl time.Duration) ([]byte, error)
+}
+
+func (f *fakeSigner) Name() string { return "fake" }
+
+func (f *fakeSigner) Sign(_ context.Context, csrPEM []byte, ttl time.Duration) ([]byte, error) {
+	return f.sign(csrPEM, ttl)
+}
+
+// certOverrides changes the certificate issued by testSigningCA to simulate a misbehaving signer.
+type certOverrides struct {
+	subject     *pkix.Name
+	publicKey   any
+	extKeyUsage []x509.ExtKeyUsage
+	notAfter    time.Time
+}
+
+// testSigningCA returns a function which signs PEM encoded certificate requests with a new self-signed CA.
+// The issued certificate can be changed by passing non-nil overrides.
+func testSigningCA(t *testing.T) func(csrPEM []byte, ttl time.Duration, overrides *certOverrides) []byte {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-signer"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	return func(csrPEM []byte, ttl time.Duration, overrides *certOverrides) []byte {
+		block, _ := pem.Decode(csrPEM)
+		require.NotNil(t, block)
+		require.Equal(t, "CERTIFICATE REQUEST", block.Type)
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		require.NoError(t, err)
+		require.NoError(t, csr.CheckSignature())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      csr.Subject,
+			NotBefore:    time.Now().Add(-2 * time.Hour),
+			NotAfter:     time.Now().Add(ttl),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		publicKey := csr.PublicKey
+		if overrides != nil {
+			if overrides.subject != nil {
+				template.Subject = *overrides.subject
+			}
+			if overrides.publicKey != nil {
+				publicKey = overrides.publicKey
+			}
+			if overrides.extKeyUsage != nil {
+				template.ExtKeyUsage = overrides.extKeyUsage
+			}
+			if !overrides.notAfter.IsZero() {
+				template.NotAfter = overrides.notAfter
+			}
+		}
+		certDER, err := x509.CreateCertificate(rand.Reader, template, caCert, publicKey, caKey)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	}
+}
+
+func TestDynamic(t *testing.T) {
+	t.Parallel()
+
+	sign := testSigningCA(t)
+
+	tests := []struct {
+		name      string
+		signer    Signer
+		wantName  string
+		wantError string
+	}{
+		{
+			name:      "not configured",
+			wantName:  "external-signer",
+			wantError: "no external signer is configured",
+		},
+		{
+			name: "success",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				require.Equal(t, 5*time.Minute, ttl)
+				return sign(csrPEM, ttl, nil), nil
+			}},
+			wantName: "external-signer:fake",
+		},
+		{
+			name: "signer error",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return nil, errors.New("some signer error")
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "some signer error",
+		},
+		{
+			name: "signer returns garbage",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return []byte("not a certificate"), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer did not return a PEM encoded certificate",
+		},
+		{
+			name: "signer returns an invalid certificate",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not der")}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned an invalid certificate: ",
+		},
+		{
+			name: "signer changes the username",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return sign(csrPEM, ttl, &certOverrides{subject: &pkix.Name{CommonName: "system:admin", Organization: []string{"group-a", "group-b"}}}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned a certificate with an unexpected subject",
+		},
+		{
+			name: "signer changes the groups",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return sign(csrPEM, ttl, &certOverrides{subject: &pkix.Name{CommonName: "some-user", Organization: []string{"system:masters"}}}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned a certificate with an unexpected subject",
+		},
+		{
+			name: "signer issues the certificate for a different public key",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				require.NoError(t, err)
+				return sign(csrPEM, ttl, &certOverrides{publicKey: &otherKey.PublicKey}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned a certificate for an unexpected public key",
+		},
+		{
+			name: "signer issues a certificate without the client auth extended key usage",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return sign(csrPEM, ttl, &certOverrides{extKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned a certificate which cannot be used for client authentication",
+		},
+		{
+			name: "signer issues a certificate which has already expired",
+			signer: &fakeSigner{sign: func(csrPEM []byte, ttl time.Duration) ([]byte, error) {
+				return sign(csrPEM, ttl, &certOverrides{notAfter: time.Now().Add(-time.Minute)}), nil
+			}},
+			wantName:  "external-signer:fake",
+			wantError: "external signer returned a certificate which has already expired",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			issuer := New()
+			issuer.Set(tt.signer)
+			require.Equal(t, tt.wantName, issuer.Name())
+
+			certPEM, keyPEM, err := issuer.IssueClientCertPEM("some-user", []string{"group-a", "group-b"}, 5*time.Minute)
+			if tt.wantError != "" {
+				require.ErrorContains(t, err, tt.wantError)
+				require.Nil(t, certPEM)
+				require.Nil(t, keyPEM)
+				return
+			}
+			require.NoError(t, err)
+
+			keyBlock, _ := pem.Decode(keyPEM)
+			require.NotNil(t, keyBlock)
+			require.Equal(t, "PRIVATE KEY", keyBlock.Type)
+
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			require.NoError(t, err)
+			leaf, err := x509.ParseCertificate(cert.Certificate[0])
+			require.NoError(t, err)
+			require.Equal(t, "some-user", leaf.Subject.CommonName)
+			require.Equal(t, []string{"group-a", "group-b"}, leaf.Subject.Organization)
+		})
+	}
+
+	t.Run("set nil", func(t *testing.T) {
+		t.Parallel()
+
+		issuer := New()
+		issuer.Set(&fakeSigner{})
+		issuer.Set(nil)
+		_, _, err := issuer.IssueClientCertPEM("some-user", nil, time.Minute)
+		require.ErrorIs(t, err, ErrNotConfigured)
+	})
+}
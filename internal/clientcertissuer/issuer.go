@@ -1,9 +1,10 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package clientcertissuer
 
 import (
+	"crypto/x509/pkix"
 	"fmt"
 	"strings"
 	"time"
@@ -17,7 +18,9 @@ const defaultCertIssuerErr = constable.Error("failed to issue cert")
 
 type ClientCertIssuer interface {
 	Name() string
-	IssueClientCertPEM(username string, groups []string, ttl time.Duration) (certPEM, keyPEM []byte, err error)
+	// IssueClientCertPEM issues a client certificate for the given identity and duration. Issuers which
+	// control the contents of the certificates that they issue add the extensions to them, and others ignore them.
+	IssueClientCertPEM(username string, groups []string, ttl time.Duration, extensions ...pkix.Extension) (certPEM, keyPEM []byte, err error)
 }
 
 var _ ClientCertIssuer = ClientCertIssuers{}
@@ -37,11 +40,11 @@ func (c ClientCertIssuers) Name() string {
 	return strings.Join(names, ",")
 }
 
-func (c ClientCertIssuers) IssueClientCertPEM(username string, groups []string, ttl time.Duration) ([]byte, []byte, error) {
+func (c ClientCertIssuers) IssueClientCertPEM(username string, groups []string, ttl time.Duration, extensions ...pkix.Extension) ([]byte, []byte, error) {
 	errs := make([]error, 0, len(c))
 
 	for _, issuer := range c {
-		certPEM, keyPEM, err := issuer.IssueClientCertPEM(username, groups, ttl)
+		certPEM, keyPEM, err := issuer.IssueClientCertPEM(username, groups, ttl, extensions...)
 		if err == nil {
 			return certPEM, keyPEM, nil
 		}
@@ -0,0 +1,165 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	auditv1 "k8s.io/apiserver/pkg/apis/audit/v1"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/audit/policy"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	auditbuffered "k8s.io/apiserver/plugin/pkg/audit/buffered"
+	auditfake "k8s.io/apiserver/plugin/pkg/audit/fake"
+	auditlog "k8s.io/apiserver/plugin/pkg/audit/log"
+	auditwebhook "k8s.io/apiserver/plugin/pkg/audit/webhook"
+)
+
+// AuditConfig configures where the impersonation proxy sends the audit events for the requests that it handles.
+type AuditConfig struct {
+	// PolicyFile is the path to a standard Kubernetes audit policy file (audit.k8s.io/v1 Policy) which decides
+	// which requests are audited and at which level. When empty, audit events are not sent anywhere.
+	PolicyFile string
+
+	// LogFile is the path to a file to which audit events are appended as JSON lines.
+	// The special value "-" writes the audit events to stdout.
+	LogFile string
+
+	// WebhookKubeconfigFile is the path to a kubeconfig file which describes a webhook to which batches of
+	// audit events are sent, e.g. an audit sink running as a sidecar container.
+	WebhookKubeconfigFile string
+
+	// WebhookBatchBufferSize is the number of audit events which are buffered before they are batched.
+	// When zero, the default of the Kubernetes API server is used.
+	WebhookBatchBufferSize int
+
+	// WebhookBatchMaxSize is the maximum number of audit events sent to the webhook in one batch.
+	// When zero, the default of the Kubernetes API server is used.
+	WebhookBatchMaxSize int
+
+	// WebhookBatchMaxWait is how long to wait for a batch to fill up before sending a partial batch.
+	// When zero, the default of the Kubernetes API server is used.
+	WebhookBatchMaxWait time.Duration
+}
+
+// These match the defaults of the Kubernetes API server's batching webhook audit backend.
+const (
+	auditWebhookBatchBufferSize    = 10000
+	auditWebhookBatchMaxSize       = 400
+	auditWebhookBatchMaxWait       = 30 * time.Second
+	auditWebhookBatchThrottleQPS   = 10
+	auditWebhookBatchThrottleBurst = 15
+)
+
+// newAudit returns the audit policy rule evaluator and backend for the impersonation proxy.
+// The returned closer is non-nil when a log file was opened. The backend closes it during shutdown, so the
+// caller only needs to close it when the server which would have run the backend is never started.
+func newAudit(auditConfig *AuditConfig) (audit.PolicyRuleEvaluator, audit.Backend, io.Closer, error) {
+	if auditConfig == nil || len(auditConfig.PolicyFile) == 0 {
+		// wire up a fake audit backend at the metadata level so we can preserve the original user during nested impersonation
+		return policy.NewFakePolicyRuleEvaluator(auditinternal.LevelMetadata, nil), &auditfake.Backend{}, nil, nil
+	}
+
+	auditPolicy, err := policy.LoadPolicyFromFile(auditConfig.PolicyFile)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not load audit policy: %w", err)
+	}
+
+	if len(auditConfig.LogFile) == 0 && len(auditConfig.WebhookKubeconfigFile) == 0 {
+		return nil, nil, nil, fmt.Errorf("audit policy file %q is configured without a log file or webhook", auditConfig.PolicyFile)
+	}
+
+	var backends []audit.Backend
+
+	if len(auditConfig.WebhookKubeconfigFile) > 0 {
+		webhookBackend, err := auditwebhook.NewBackend(
+			auditConfig.WebhookKubeconfigFile,
+			auditv1.SchemeGroupVersion,
+			wait.Backoff{Duration: auditwebhook.DefaultInitialBackoffDelay, Factor: 1.5, Jitter: 0.2, Steps: 5},
+			nil,
+		)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not load audit webhook: %w", err)
+		}
+		// Send batches asynchronously so that a slow webhook does not slow down the proxied requests.
+		backends = append(backends, auditbuffered.NewBackend(webhookBackend, auditbuffered.BatchConfig{
+			BufferSize:     valueOrDefault(auditConfig.WebhookBatchBufferSize, auditWebhookBatchBufferSize),
+			MaxBatchSize:   valueOrDefault(auditConfig.WebhookBatchMaxSize, auditWebhookBatchMaxSize),
+			MaxBatchWait:   valueOrDefault(auditConfig.WebhookBatchMaxWait, auditWebhookBatchMaxWait),
+			ThrottleEnable: true,
+			ThrottleQPS:    auditWebhookBatchThrottleQPS,
+			ThrottleBurst:  auditWebhookBatchThrottleBurst,
+			AsyncDelegate:  true,
+		}))
+	}
+
+	// Open the log file last so that there is nothing left to fail which would leak the file.
+	var closer io.Closer
+	if len(auditConfig.LogFile) > 0 {
+		var out io.Writer = os.Stdout
+		if auditConfig.LogFile != "-" {
+			f, err := os.OpenFile(auditConfig.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("could not open audit log file: %w", err)
+			}
+			out, closer = f, f
+		}
+		backends = append(backends, &closingBackend{
+			Backend: auditlog.NewBackend(out, auditlog.FormatJson, auditv1.SchemeGroupVersion),
+			closer:  closer,
+		})
+	}
+
+	evaluator := &minimumMetadataPolicyRuleEvaluator{delegate: policy.NewPolicyRuleEvaluator(auditPolicy)}
+	return evaluator, audit.Union(backends...), closer, nil
+}
+
+func valueOrDefault[T int | time.Duration](value, defaultValue T) T {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// minimumMetadataPolicyRuleEvaluator makes sure that every request has an audit event, because the impersonation
+// proxy relies on the audit event to preserve the original user during nested impersonation. Requests which should
+// not be audited according to the delegate get an audit event which omits every stage, so it is never sent to the
+// audit backend.
+type minimumMetadataPolicyRuleEvaluator struct {
+	delegate audit.PolicyRuleEvaluator
+}
+
+func (e *minimumMetadataPolicyRuleEvaluator) EvaluatePolicyRule(attrs authorizer.Attributes) audit.RequestAuditConfig {
+	rac := e.delegate.EvaluatePolicyRule(attrs)
+	if rac.Level.Less(auditinternal.LevelMetadata) {
+		return audit.RequestAuditConfig{
+			Level: auditinternal.LevelMetadata,
+			OmitStages: []auditinternal.Stage{
+				auditinternal.StageRequestReceived,
+				auditinternal.StageResponseStarted,
+				auditinternal.StageResponseComplete,
+				auditinternal.StagePanic,
+			},
+		}
+	}
+	return rac
+}
+
+// closingBackend closes the underlying writer of an audit log backend when it is shut down.
+type closingBackend struct {
+	audit.Backend
+	closer io.Closer
+}
+
+func (b *closingBackend) Shutdown() {
+	b.Backend.Shutdown()
+	if b.closer != nil {
+		_ = b.closer.Close()
+	}
+}
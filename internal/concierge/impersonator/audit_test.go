@@ -0,0 +1,192 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/types"
+	auditinternal "k8s.io/apiserver/pkg/apis/audit"
+	"k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	auditfake "k8s.io/apiserver/plugin/pkg/audit/fake"
+
+	"go.pinniped.dev/internal/here"
+)
+
+func TestNewAudit(t *testing.T) {
+	t.Parallel()
+
+	const validPolicy = `
+		apiVersion: audit.k8s.io/v1
+		kind: Policy
+		omitStages: [RequestReceived]
+		rules:
+		  - level: None
+		    users: ["system:kube-probe"]
+		  - level: Request
+		    resources:
+		      - group: ""
+		        resources: ["configmaps"]
+		  - level: Metadata
+	`
+
+	allStages := []auditinternal.Stage{
+		auditinternal.StageRequestReceived,
+		auditinternal.StageResponseStarted,
+		auditinternal.StageResponseComplete,
+		auditinternal.StagePanic,
+	}
+
+	writeFile := func(t *testing.T, name, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), name)
+		require.NoError(t, os.WriteFile(path, []byte(here.Doc(content)), 0o600))
+		return path
+	}
+
+	t.Run("without audit config", func(t *testing.T) {
+		t.Parallel()
+
+		for _, auditConfig := range []*AuditConfig{nil, {}, {LogFile: "-"}} {
+			evaluator, backend, closer, err := newAudit(auditConfig)
+			require.NoError(t, err)
+			require.Nil(t, closer)
+			require.IsType(t, &auditfake.Backend{}, backend)
+			require.Equal(t,
+				audit.RequestAuditConfig{Level: auditinternal.LevelMetadata},
+				evaluator.EvaluatePolicyRule(&authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "system:kube-probe"}}),
+			)
+		}
+	})
+
+	t.Run("with a log file", func(t *testing.T) {
+		t.Parallel()
+
+		logFile := filepath.Join(t.TempDir(), "audit.log")
+		evaluator, backend, closer, err := newAudit(&AuditConfig{
+			PolicyFile: writeFile(t, "policy.yaml", validPolicy),
+			LogFile:    logFile,
+		})
+		require.NoError(t, err)
+		require.NotNil(t, closer)
+
+		// Requests which should not be audited still get an audit event, but it is never sent to the backend.
+		require.Equal(t,
+			audit.RequestAuditConfig{Level: auditinternal.LevelMetadata, OmitStages: allStages},
+			evaluator.EvaluatePolicyRule(&authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "system:kube-probe"}}),
+		)
+		require.Equal(t,
+			audit.RequestAuditConfig{Level: auditinternal.LevelRequest, OmitStages: []auditinternal.Stage{auditinternal.StageRequestReceived}},
+			evaluator.EvaluatePolicyRule(&authorizer.AttributesRecord{
+				User: &user.DefaultInfo{Name: "some-user"}, Verb: "get", ResourceRequest: true, Resource: "configmaps",
+			}),
+		)
+		require.Equal(t,
+			audit.RequestAuditConfig{Level: auditinternal.LevelMetadata, OmitStages: []auditinternal.Stage{auditinternal.StageRequestReceived}},
+			evaluator.EvaluatePolicyRule(&authorizer.AttributesRecord{
+				User: &user.DefaultInfo{Name: "some-user"}, Verb: "get", ResourceRequest: true, Resource: "secrets",
+			}),
+		)
+
+		stopCh := make(chan struct{})
+		require.NoError(t, backend.Run(stopCh))
+		require.True(t, backend.ProcessEvents(&auditinternal.Event{
+			Level:   auditinternal.LevelMetadata,
+			AuditID: types.UID("some-audit-id"),
+			Stage:   auditinternal.StageResponseComplete,
+			Verb:    "get",
+			User:    authenticationv1.UserInfo{Username: "some-user"},
+		}))
+		close(stopCh)
+		backend.Shutdown()
+
+		logs, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		require.Regexp(t, `^\{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"some-audit-id","stage":"ResponseComplete",.*"verb":"get","user":\{"username":"some-user"\}.*\}\n$`, string(logs))
+
+		// Shutting down the backend closed the log file.
+		require.ErrorIs(t, closer.Close(), os.ErrClosed)
+	})
+
+	t.Run("with a webhook", func(t *testing.T) {
+		t.Parallel()
+
+		_, _, closer, err := newAudit(&AuditConfig{
+			PolicyFile: writeFile(t, "policy.yaml", validPolicy),
+			WebhookKubeconfigFile: writeFile(t, "webhook.kubeconfig", `
+				apiVersion: v1
+				kind: Config
+				clusters:
+				  - name: audit-sink
+				    cluster:
+				      server: https://127.0.0.1:12345/audit
+				contexts:
+				  - name: audit-sink
+				    context:
+				      cluster: audit-sink
+				current-context: audit-sink
+			`),
+		})
+		require.NoError(t, err)
+		require.Nil(t, closer)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+
+		policyFile := writeFile(t, "policy.yaml", validPolicy)
+
+		tests := []struct {
+			name        string
+			auditConfig *AuditConfig
+			wantErr     string
+		}{
+			{
+				name:        "missing policy file",
+				auditConfig: &AuditConfig{PolicyFile: filepath.Join(t.TempDir(), "does-not-exist.yaml"), LogFile: "-"},
+				wantErr:     "could not load audit policy: ",
+			},
+			{
+				name: "invalid policy",
+				auditConfig: &AuditConfig{PolicyFile: writeFile(t, "invalid-policy.yaml", `
+					apiVersion: audit.k8s.io/v1
+					kind: Policy
+					rules:
+					  - level: NotALevel
+				`), LogFile: "-"},
+				wantErr: "could not load audit policy: ",
+			},
+			{
+				name:        "no backends",
+				auditConfig: &AuditConfig{PolicyFile: policyFile},
+				wantErr:     `audit policy file "` + policyFile + `" is configured without a log file or webhook`,
+			},
+			{
+				name:        "missing webhook kubeconfig",
+				auditConfig: &AuditConfig{PolicyFile: policyFile, WebhookKubeconfigFile: filepath.Join(t.TempDir(), "does-not-exist")},
+				wantErr:     "could not load audit webhook: ",
+			},
+			{
+				name:        "log file in missing directory",
+				auditConfig: &AuditConfig{PolicyFile: policyFile, LogFile: filepath.Join(t.TempDir(), "does-not-exist", "audit.log")},
+				wantErr:     "could not open audit log file: ",
+			},
+		}
+		for _, tt := range tests {
+			tt := tt
+			t.Run(tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				_, _, _, err := newAudit(tt.auditConfig)
+				require.ErrorContains(t, err, tt.wantErr)
+			})
+		}
+	})
+}
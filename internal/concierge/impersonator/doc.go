@@ -1,4 +1,4 @@
-// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 /*
@@ -34,6 +34,12 @@ we fail to correctly impersonate the user due to some bug in our proxy logic.
 We rely completely on the Kubernetes API server to perform admission checks on
 the impersonated requests.
 
+Administrators may further restrict the proxy via the optional policy on the
+CredentialIssuer.  Deny rules are evaluated by the authorizer before any subject
+access review is made, so denied requests never reach the Kubernetes API server.
+Rate limit rules are evaluated per user by the reverse proxy after authorization,
+and requests which exceed a limit fail with a 429 Too Many Requests response.
+
 Nested impersonation is handled by performing the same authorization checks the
 Kubernetes API server would (we get this mostly for free by using the aggregated
 API server code).  We preserve the original user in the reserved extra key
@@ -42,9 +48,13 @@ the authenticationv1.UserInfo struct.  This is necessary to make sure that the
 Kubernetes audit log contains all three identities (original user, impersonated
 user and the impersonation proxy's service account).  Capturing the original
 user information requires that we enable the auditing stack (WithImpersonation
-only shares this information with the audit stack).  To keep things simple,
-we use the fake audit backend at the Metadata level for all requests.  This
-guarantees that we always have an audit event on every request.
+only shares this information with the audit stack).  By default, we use the
+fake audit backend at the Metadata level for all requests.  This guarantees
+that we always have an audit event on every request.  When the Concierge is
+configured with an audit policy file, the matching audit events are also written
+to a log file and/or sent to a webhook.  Requests which the policy does not audit
+still get an audit event at the Metadata level, but all of its stages are omitted
+so that it is never sent to the audit backend.
 
 One final wrinkle is that impersonation cannot impersonate UIDs (yet).  This is
 problematic because service account tokens always assert a UID.  To handle this
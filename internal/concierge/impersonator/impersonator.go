@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package impersonator
@@ -32,12 +32,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	auditinternal "k8s.io/apiserver/pkg/apis/audit"
 	"k8s.io/apiserver/pkg/audit"
-	"k8s.io/apiserver/pkg/audit/policy"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	x509request "k8s.io/apiserver/pkg/authentication/request/x509"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	"k8s.io/apiserver/pkg/endpoints/filterlatency"
+	endpointsfilters "k8s.io/apiserver/pkg/endpoints/filters"
 	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	"k8s.io/apiserver/pkg/endpoints/request"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
@@ -45,10 +46,10 @@ import (
 	"k8s.io/apiserver/pkg/server/dynamiccertificates"
 	"k8s.io/apiserver/pkg/server/filters"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
-	auditfake "k8s.io/apiserver/plugin/pkg/audit/fake"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/transport"
 
+	"go.pinniped.dev/internal/authenticationinfo"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/crypto/ptls"
 	"go.pinniped.dev/internal/dynamiccert"
@@ -69,17 +70,35 @@ type FactoryFunc func(
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+	impersonationProxyPolicy PolicyGetter,
 	baseConfig *rest.Config, // for unit testing, should always be nil in production
 ) (func(stopCh <-chan struct{}) error, error)
 
+// New is a FactoryFunc for an impersonator server which does not send its audit events anywhere.
 func New(
 	port int,
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+	impersonationProxyPolicy PolicyGetter,
 	baseConfig *rest.Config, // for unit testing, should always be nil in production
 ) (func(stopCh <-chan struct{}) error, error) {
-	return newInternal(port, dynamicCertProvider, impersonationProxySignerCA, kubeclient.Secure, impersonationProxyTokenCache, baseConfig, nil, nil)
+	return NewWithAudit(nil)(port, dynamicCertProvider, impersonationProxySignerCA, impersonationProxyTokenCache, impersonationProxyPolicy, baseConfig)
+}
+
+// NewWithAudit returns a FactoryFunc for an impersonator server which sends its audit events to the
+// backends described by the audit config. A nil audit config is the same as using New.
+func NewWithAudit(auditConfig *AuditConfig) FactoryFunc {
+	return func(
+		port int,
+		dynamicCertProvider dynamiccert.Private,
+		impersonationProxySignerCA dynamiccert.Public,
+		impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+		impersonationProxyPolicy PolicyGetter,
+		baseConfig *rest.Config,
+	) (func(stopCh <-chan struct{}) error, error) {
+		return newInternal(port, dynamicCertProvider, impersonationProxySignerCA, kubeclient.Secure, impersonationProxyTokenCache, impersonationProxyPolicy, auditConfig, baseConfig, nil, nil)
+	}
 }
 
 //nolint:funlen // It is definitely too complicated. New calls newInternal, which makes another function.
@@ -89,11 +108,14 @@ func newInternal(
 	impersonationProxySignerCA dynamiccert.Public,
 	restConfigFunc ptls.RestConfigFunc, // for unit testing, should always be kubeclient.Secure in production
 	cache tokenclient.ExpiringSingletonTokenCacheGet,
+	impersonationProxyPolicy PolicyGetter,
+	auditConfig *AuditConfig,
 	baseConfig *rest.Config, // for unit testing, should always be nil in production
 	recOpts func(*genericoptions.RecommendedOptions), // for unit testing, should always be nil in production
 	recConfig func(*genericapiserver.RecommendedConfig), // for unit testing, should always be nil in production
 ) (func(stopCh <-chan struct{}) error, error) {
 	var listener net.Listener
+	var auditLogFile io.Closer
 	var err error
 
 	if baseConfig == nil {
@@ -193,9 +215,15 @@ func newInternal(
 			return nil, fmt.Errorf("failed to build reverse proxy client: %w", err)
 		}
 
+		// Verifies client certs against the same CAs as the client cert authenticator above, so that the details of
+		// how a user authenticated are only read from client certs which were actually used to authenticate.
+		clientCertAuthenticator := x509request.NewDynamic(
+			recommendedOptions.Authentication.ClientCert.CAContentProvider.VerifyOptions, x509request.CommonNameUserConversion,
+		)
+
 		// Assume proto config is safe because transport level configs do not use rest.ContentConfig.
 		// Thus if we are interacting with actual APIs, they should be using pre-built clients.
-		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig))
+		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig), impersonationProxyPolicy, clientCertAuthenticator)
 		if err != nil {
 			return nil, err
 		}
@@ -232,9 +260,12 @@ func newInternal(
 			return handler
 		}
 
-		// wire up a fake audit backend at the metadata level so we can preserve the original user during nested impersonation
-		serverConfig.AuditPolicyRuleEvaluator = policy.NewFakePolicyRuleEvaluator(auditinternal.LevelMetadata, nil)
-		serverConfig.AuditBackend = &auditfake.Backend{}
+		// Every request needs an audit event at the metadata level (or higher) so we can preserve the original user
+		// during nested impersonation. The audit config decides if any of those events are sent anywhere.
+		serverConfig.AuditPolicyRuleEvaluator, serverConfig.AuditBackend, auditLogFile, err = newAudit(auditConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure audit: %w", err)
+		}
 
 		// Probe the API server to figure out if anonymous auth is enabled.
 		anonymousAuthEnabled, err := isAnonymousAuthEnabled(kubeClientUnsafeForProxying.JSONConfig)
@@ -298,6 +329,12 @@ func newInternal(
 					// Empty string is disallowed because request info has had bugs in the past where it would leave it empty.
 					return authorizer.DecisionDeny, "invalid verb, " + baseReason, nil
 				default:
+					// Apply the deny rules of the policy from the CredentialIssuer before asking the Kubernetes
+					// API server, so that denied requests do not cost a subject access review.
+					if reason := impersonationProxyPolicy.Deny(a); len(reason) != 0 {
+						return authorizer.DecisionDeny, reason + ", " + baseReason, nil
+					}
+
 					// Since we authenticate the requesting user, we are in the best position to correctly authorize them.
 					// When KAS does the check, it may run the check against our service account and not the requesting user
 					// (due to a bug in the code or any other internal SAR checks that the request processing does).
@@ -345,12 +382,16 @@ func newInternal(
 	}
 
 	result, err := constructServer()
-	// If there was any error during construction, then we would like to close the listener to free up the port.
+	// If there was any error during construction, then we would like to close the listener to free up the port,
+	// and close the audit log file since the audit backend will never run.
 	if err != nil {
 		errs := []error{err}
 		if listener != nil {
 			errs = append(errs, listener.Close())
 		}
+		if auditLogFile != nil {
+			errs = append(errs, auditLogFile.Close())
+		}
 		return nil, errors.NewAggregate(errs)
 	}
 	return result, nil
@@ -461,7 +502,7 @@ type contextKey int
 
 const tokenKey contextKey = iota
 
-func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapiserver.Config) http.Handler, error) {
+func newImpersonationReverseProxyFunc(restConfig *rest.Config, impersonationProxyPolicy PolicyGetter, clientCertAuthenticator authenticator.Request) (func(*genericapiserver.Config) http.Handler, error) {
 	serverURL, err := url.Parse(restConfig.Host)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse host URL from in-cluster config: %w", err)
@@ -526,6 +567,27 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				return
 			}
 
+			attributes, err := endpointsfilters.GetAuthorizerAttributes(r.Context())
+			if err != nil {
+				plog.WarningErr("could not get authorizer attributes", err,
+					"url", r.URL.String(),
+					"method", r.Method,
+				)
+				newInternalErrResponse(w, r, c.Serializer, "invalid request info")
+				return
+			}
+
+			// throttle after authorization so that only requests which could have succeeded count against the limits
+			if reason := impersonationProxyPolicy.Throttle(attributes); len(reason) != 0 {
+				plog.Debug("impersonation proxy throttled request",
+					"url", r.URL.String(),
+					"method", r.Method,
+					"reason", reason,
+				)
+				newStatusErrResponse(w, r, c.Serializer, apierrors.NewTooManyRequests(reason, 1))
+				return
+			}
+
 			// grab the request's bearer token if present.  this is optional and does not fail the request if missing.
 			token := tokenFrom(r.Context())
 
@@ -537,7 +599,13 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				baseRT, baseRTAnonymous = http1RoundTripper, http1RoundTripperAnonymous
 			}
 
-			rt, err := getTransportForUser(r.Context(), userInfo, baseRT, baseRTAnonymous, ae, token, c.Authentication.Authenticator)
+			// tell the WhoAmIRequest API how the user authenticated, since it cannot observe that itself
+			var authenticationInfo *authenticationinfo.Info
+			if isWhoAmIRequest(attributes) {
+				authenticationInfo = getAuthenticationInfo(r, ae, clientCertAuthenticator)
+			}
+
+			rt, err := getTransportForUser(r.Context(), userInfo, baseRT, baseRTAnonymous, ae, token, c.Authentication.Authenticator, authenticationInfo)
 			if err != nil {
 				plog.WarningErr("rejecting request as we cannot act as the current user", err,
 					"url", r.URL.String(),
@@ -638,9 +706,40 @@ func ensureNoImpersonationHeaders(r *http.Request) error {
 	return nil
 }
 
-func getTransportForUser(ctx context.Context, userInfo user.Info, delegate, delegateAnonymous http.RoundTripper, ae *auditinternal.Event, token string, authenticator authenticator.Request) (http.RoundTripper, error) {
+func isWhoAmIRequest(attributes authorizer.Attributes) bool {
+	return attributes.IsResourceRequest() &&
+		attributes.GetVerb() == "create" &&
+		attributes.GetResource() == "whoamirequests" &&
+		strings.HasPrefix(attributes.GetAPIGroup(), "identity.concierge.")
+}
+
+// getAuthenticationInfo describes how the user authenticated to the impersonation proxy. The details of the
+// credential are only known when the user authenticated with a client certificate.
+func getAuthenticationInfo(r *http.Request, ae *auditinternal.Event, clientCertAuthenticator authenticator.Request) *authenticationinfo.Info {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return &authenticationinfo.Info{}
+	}
+
+	// make sure that the client cert is what authenticated the user, and not some other credential
+	resp, ok, err := clientCertAuthenticator.AuthenticateRequest(r)
+	if err != nil || !ok || resp.User.GetName() != ae.User.Username {
+		return &authenticationinfo.Info{}
+	}
+
+	info, err := authenticationinfo.FromClientCert(r.TLS.PeerCertificates[0])
+	if err != nil {
+		plog.WarningErr("could not read authentication info from client certificate", err,
+			"url", r.URL.String(),
+			"method", r.Method,
+		)
+		return &authenticationinfo.Info{}
+	}
+	return info
+}
+
+func getTransportForUser(ctx context.Context, userInfo user.Info, delegate, delegateAnonymous http.RoundTripper, ae *auditinternal.Event, token string, authenticator authenticator.Request, authenticationInfo *authenticationinfo.Info) (http.RoundTripper, error) {
 	if canImpersonateFully(userInfo) {
-		return standardImpersonationRoundTripper(userInfo, ae, delegate)
+		return standardImpersonationRoundTripper(userInfo, ae, delegate, authenticationInfo)
 	}
 
 	return tokenPassthroughRoundTripper(ctx, delegateAnonymous, ae, token, authenticator)
@@ -657,12 +756,27 @@ func canImpersonateFully(userInfo user.Info) bool {
 	return false
 }
 
-func standardImpersonationRoundTripper(userInfo user.Info, ae *auditinternal.Event, delegate http.RoundTripper) (http.RoundTripper, error) {
+func standardImpersonationRoundTripper(userInfo user.Info, ae *auditinternal.Event, delegate http.RoundTripper, authenticationInfo *authenticationinfo.Info) (http.RoundTripper, error) {
 	extra, err := buildExtra(userInfo.GetExtra(), ae)
 	if err != nil {
 		return nil, err
 	}
 
+	if authenticationInfo != nil {
+		authenticationInfoExtra, err := authenticationInfo.ToExtra()
+		if err != nil {
+			return nil, err
+		}
+
+		// avoid mutating input map since buildExtra may have returned it as-is
+		withAuthenticationInfo := make(map[string][]string, len(extra)+1)
+		for k, v := range extra {
+			withAuthenticationInfo[k] = v
+		}
+		withAuthenticationInfo[authenticationinfo.ExtraKey] = authenticationInfoExtra
+		extra = withAuthenticationInfo
+	}
+
 	impersonateConfig := transport.ImpersonationConfig{
 		UserName: userInfo.GetName(),
 		Groups:   userInfo.GetGroups(),
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package impersonator
@@ -32,6 +32,7 @@ import (
 	"k8s.io/apiserver/pkg/audit"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	x509request "k8s.io/apiserver/pkg/authentication/request/x509"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/authorization/authorizer"
 	"k8s.io/apiserver/pkg/endpoints/request"
@@ -42,7 +43,9 @@ import (
 	"k8s.io/client-go/tools/clientcmd/api"
 	"k8s.io/utils/ptr"
 
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
 	loginv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/login/v1alpha1"
+	"go.pinniped.dev/internal/authenticationinfo"
 	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/crypto/ptls"
@@ -863,7 +866,7 @@ func TestImpersonator(t *testing.T) {
 			}
 
 			// Create an impersonator.  Use an invalid port number to make sure our listener override works.
-			runner, constructionErr := newInternal(-1000, certKeyContent, caContent, restConfigFunc, serviceTokenCache, &testKubeAPIServerKubeconfig, recOpts, recConfig)
+			runner, constructionErr := newInternal(-1000, certKeyContent, caContent, restConfigFunc, serviceTokenCache, NewPolicy(), nil, &testKubeAPIServerKubeconfig, recOpts, recConfig)
 			if len(tt.wantConstructionError) > 0 {
 				require.EqualError(t, constructionErr, tt.wantConstructionError)
 				require.Nil(t, runner)
@@ -1066,12 +1069,34 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 		"extra-2": {"some", "more", "extra", "stuff"},
 	}
 
+	clientCA, err := certauthority.New("client CA", time.Hour)
+	require.NoError(t, err)
+	otherClientCA, err := certauthority.New("other client CA", time.Hour)
+	require.NoError(t, err)
+	clientCertAuthenticator := x509request.New(x509.VerifyOptions{Roots: clientCA.Pool(), KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, x509request.CommonNameUserConversion)
+
+	testAuthenticationInfo := &authenticationinfo.Info{
+		Authenticator:                &corev1.TypedLocalObjectReference{APIGroup: ptr.To("authentication.concierge.pinniped.dev"), Kind: "JWTAuthenticator", Name: "some-jwt-authenticator"},
+		SupervisorIssuer:             "https://supervisor.example.com",
+		UpstreamIdentityProviderName: "some-idp",
+	}
+	testAuthenticationInfoExtension, err := testAuthenticationInfo.ClientCertExtension()
+	require.NoError(t, err)
+	clientCert, err := clientCA.IssueClientCert(testUser, testGroups, time.Hour, testAuthenticationInfoExtension)
+	require.NoError(t, err)
+	otherClientCert, err := otherClientCA.IssueClientCert(testUser, testGroups, time.Hour, testAuthenticationInfoExtension)
+	require.NoError(t, err)
+	wantAuthenticationInfoExtra := fmt.Sprintf(`{"authenticator":{"apiGroup":"authentication.concierge.pinniped.dev","kind":"JWTAuthenticator","name":"some-jwt-authenticator"},`+
+		`"supervisorIssuer":"https://supervisor.example.com","upstreamIdentityProviderName":"some-idp","credentialExpiration":%q}`,
+		clientCert.Leaf.NotAfter.UTC().Format(time.RFC3339))
+
 	tests := []struct {
 		name                            string
 		restConfig                      *rest.Config
 		wantCreationErr                 string
 		request                         *http.Request
 		authenticator                   authenticator.Request
+		policy                          PolicyGetter
 		wantHTTPBody                    string
 		wantHTTPStatus                  int
 		wantKubeAPIServerRequestHeaders http.Header
@@ -1143,6 +1168,31 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 			wantHTTPBody:   `{"kind":"Status","apiVersion":"v1","metadata":{},"status":"Failure","message":"Internal error occurred: unimplemented functionality - unable to act as current user","reason":"InternalError","details":{"causes":[{"message":"unimplemented functionality - unable to act as current user"}]},"code":500}` + "\n",
 			wantHTTPStatus: http.StatusInternalServerError,
 		},
+		{
+			name: "authenticated user who has exceeded a rate limit from the policy",
+			request: newRequest(t, map[string][]string{
+				"User-Agent": {"test-user-agent"},
+			}, &user.DefaultInfo{
+				Name:   testUser,
+				Groups: testGroups,
+			}, &auditinternal.Event{User: authenticationv1.UserInfo{Username: testUser}}, ""),
+			policy: func() PolicyGetter {
+				p := NewPolicy()
+				p.Set(&configv1alpha1.ImpersonationProxyPolicy{
+					Rules: []configv1alpha1.ImpersonationProxyPolicyRule{{
+						Name:      "test-rule",
+						Action:    configv1alpha1.ImpersonationProxyPolicyActionRateLimit,
+						Users:     []string{testUser},
+						RateLimit: &configv1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1},
+					}},
+				})
+				// Use up the only request allowed per second for this user.
+				require.Empty(t, p.Throttle(&authorizer.AttributesRecord{User: &user.DefaultInfo{Name: testUser}}))
+				return p
+			}(),
+			wantHTTPBody:   `{"kind":"Status","apiVersion":"v1","metadata":{},"status":"Failure","message":"rate limit exceeded for impersonation proxy policy rule \"test-rule\"","reason":"TooManyRequests","details":{"retryAfterSeconds":1},"code":429}` + "\n",
+			wantHTTPStatus: http.StatusTooManyRequests,
+		},
 		{
 			name: "authenticated user but missing audit event",
 			request: func() *http.Request {
@@ -1772,6 +1822,85 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 			wantHTTPBody:   "successful proxied response",
 			wantHTTPStatus: http.StatusOK,
 		},
+		{
+			name: "authenticated user creating a WhoAmIRequest with a client cert",
+			request: newWhoAmIRequest(t, clientCert.Leaf, map[string][]string{
+				"User-Agent": {"test-user-agent"},
+			}, &user.DefaultInfo{
+				Name:   testUser,
+				Groups: testGroups,
+				Extra:  testExtra,
+			}, &auditinternal.Event{User: authenticationv1.UserInfo{Username: testUser}}),
+			wantKubeAPIServerRequestHeaders: map[string][]string{
+				"Accept-Encoding":           {"gzip"}, // because the rest client used in this test does not disable compression
+				"Authorization":             {"Bearer some-service-account-token"},
+				"Impersonate-Extra-Extra-1": {"some", "extra", "stuff"},
+				"Impersonate-Extra-Extra-2": {"some", "more", "extra", "stuff"},
+				"Impersonate-Group":         {"test-group-1", "test-group-2"},
+				"Impersonate-User":          {"test-user"},
+				"User-Agent":                {"test-user-agent"},
+				"Impersonate-Extra-Authentication-Info.impersonation-Proxy.concierge.pinniped.dev": {wantAuthenticationInfoExtra},
+			},
+			wantHTTPBody:   "successful proxied response",
+			wantHTTPStatus: http.StatusOK,
+		},
+		{
+			name: "authenticated user creating a WhoAmIRequest with an untrusted client cert",
+			request: newWhoAmIRequest(t, otherClientCert.Leaf, map[string][]string{
+				"User-Agent": {"test-user-agent"},
+			}, &user.DefaultInfo{
+				Name:   testUser,
+				Groups: testGroups,
+			}, &auditinternal.Event{User: authenticationv1.UserInfo{Username: testUser}}),
+			wantKubeAPIServerRequestHeaders: map[string][]string{
+				"Accept-Encoding":   {"gzip"}, // because the rest client used in this test does not disable compression
+				"Authorization":     {"Bearer some-service-account-token"},
+				"Impersonate-Group": {"test-group-1", "test-group-2"},
+				"Impersonate-User":  {"test-user"},
+				"User-Agent":        {"test-user-agent"},
+				"Impersonate-Extra-Authentication-Info.impersonation-Proxy.concierge.pinniped.dev": {"{}"},
+			},
+			wantHTTPBody:   "successful proxied response",
+			wantHTTPStatus: http.StatusOK,
+		},
+		{
+			name: "authenticated user creating a WhoAmIRequest with a client cert for a different user",
+			request: newWhoAmIRequest(t, clientCert.Leaf, map[string][]string{
+				"User-Agent": {"test-user-agent"},
+			}, &user.DefaultInfo{
+				Name:   "someone-else",
+				Groups: testGroups,
+			}, &auditinternal.Event{User: authenticationv1.UserInfo{Username: "someone-else"}}),
+			wantKubeAPIServerRequestHeaders: map[string][]string{
+				"Accept-Encoding":   {"gzip"}, // because the rest client used in this test does not disable compression
+				"Authorization":     {"Bearer some-service-account-token"},
+				"Impersonate-Group": {"test-group-1", "test-group-2"},
+				"Impersonate-User":  {"someone-else"},
+				"User-Agent":        {"test-user-agent"},
+				"Impersonate-Extra-Authentication-Info.impersonation-Proxy.concierge.pinniped.dev": {"{}"},
+			},
+			wantHTTPBody:   "successful proxied response",
+			wantHTTPStatus: http.StatusOK,
+		},
+		{
+			name: "authenticated user creating a WhoAmIRequest without a client cert",
+			request: newWhoAmIRequest(t, nil, map[string][]string{
+				"User-Agent": {"test-user-agent"},
+			}, &user.DefaultInfo{
+				Name:   testUser,
+				Groups: testGroups,
+			}, &auditinternal.Event{User: authenticationv1.UserInfo{Username: testUser}}),
+			wantKubeAPIServerRequestHeaders: map[string][]string{
+				"Accept-Encoding":   {"gzip"}, // because the rest client used in this test does not disable compression
+				"Authorization":     {"Bearer some-service-account-token"},
+				"Impersonate-Group": {"test-group-1", "test-group-2"},
+				"Impersonate-User":  {"test-user"},
+				"User-Agent":        {"test-user-agent"},
+				"Impersonate-Extra-Authentication-Info.impersonation-Proxy.concierge.pinniped.dev": {"{}"},
+			},
+			wantHTTPBody:   "successful proxied response",
+			wantHTTPStatus: http.StatusOK,
+		},
 		{
 			name: "user is authenticated but the kube API request returns an error",
 			request: newRequest(t, map[string][]string{
@@ -1854,7 +1983,11 @@ func TestImpersonatorHTTPHandler(t *testing.T) {
 				if err != nil {
 					return nil, err
 				}
-				return newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig))
+				policy := tt.policy
+				if policy == nil {
+					policy = NewPolicy()
+				}
+				return newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig), policy, clientCertAuthenticator)
 			}()
 
 			if tt.wantCreationErr != "" {
@@ -1956,6 +2089,29 @@ func newRequest(t *testing.T, h http.Header, userInfo user.Info, event *auditint
 	return r
 }
 
+func newWhoAmIRequest(t *testing.T, clientCert *x509.Certificate, h http.Header, userInfo user.Info, event *auditinternal.Event) *http.Request {
+	t.Helper()
+
+	r := newRequest(t, h, userInfo, event, "")
+
+	reqInfo := &request.RequestInfo{
+		IsResourceRequest: true,
+		Path:              "/apis/identity.concierge.pinniped.dev/v1alpha1/whoamirequests",
+		Verb:              "create",
+		APIPrefix:         "apis",
+		APIGroup:          "identity.concierge.pinniped.dev",
+		APIVersion:        "v1alpha1",
+		Resource:          "whoamirequests",
+	}
+	r = r.WithContext(request.WithRequestInfo(r.Context(), reqInfo))
+
+	if clientCert != nil {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+	}
+
+	return r
+}
+
 func testTokenAuthenticator(t *testing.T, token string, userInfo user.Info, err error) authenticator.Request {
 	t.Helper()
 
@@ -0,0 +1,248 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/client-go/util/flowcontrol"
+
+	"go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+)
+
+// rateLimiterIdleTTL is how long the rate limiter for a user is remembered after that user's most recent request.
+// Forgetting an idle user's rate limiter is equivalent to refilling their token bucket.
+const rateLimiterIdleTTL = 10 * time.Minute
+
+// PolicyGetter evaluates the current impersonation proxy policy against requests.
+type PolicyGetter interface {
+	// Deny returns a non-empty reason when the request described by the attributes must be rejected.
+	Deny(a authorizer.Attributes) string
+
+	// Throttle returns a non-empty reason when the request described by the attributes has exceeded a rate limit.
+	// Each call counts as a request against the first matching rate limit only.
+	Throttle(a authorizer.Attributes) string
+}
+
+// Policy is a PolicyGetter which can be updated at any time, e.g. when the CredentialIssuer changes.
+type Policy interface {
+	PolicyGetter
+	Set(spec *v1alpha1.ImpersonationProxyPolicy)
+}
+
+type dynamicPolicy struct {
+	lock  sync.RWMutex
+	spec  *v1alpha1.ImpersonationProxyPolicy
+	rules []*policyRule
+}
+
+var _ Policy = &dynamicPolicy{}
+
+// NewPolicy returns an empty Policy, which neither denies nor throttles any requests until it is Set.
+func NewPolicy() Policy {
+	return &dynamicPolicy{}
+}
+
+// Set replaces the rules of the policy. Setting a spec which is equal to the current spec is a no-op, so the
+// state of the existing rate limiters is preserved across repeated calls with the same configuration.
+func (p *dynamicPolicy) Set(spec *v1alpha1.ImpersonationProxyPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if apiequality.Semantic.DeepEqual(p.spec, spec) {
+		return
+	}
+
+	p.spec = spec.DeepCopy()
+	p.rules = nil
+	if spec == nil {
+		return
+	}
+	for _, rule := range spec.Rules {
+		p.rules = append(p.rules, newPolicyRule(rule))
+	}
+}
+
+func (p *dynamicPolicy) Deny(a authorizer.Attributes) string {
+	for _, rule := range p.currentRules() {
+		if rule.action == v1alpha1.ImpersonationProxyPolicyActionDeny && rule.matches(a) {
+			return fmt.Sprintf("denied by impersonation proxy policy rule %q", rule.name)
+		}
+	}
+	return ""
+}
+
+func (p *dynamicPolicy) Throttle(a authorizer.Attributes) string {
+	if a.GetUser() == nil {
+		return "" // rate limits are per user, so there is nothing to count against
+	}
+
+	for _, rule := range p.currentRules() {
+		if rule.action != v1alpha1.ImpersonationProxyPolicyActionRateLimit || !rule.matches(a) {
+			continue
+		}
+		// Only the first matching rate limit applies, so later matching rules do not consume tokens.
+		if !rule.tryAccept(a.GetUser().GetName()) {
+			return fmt.Sprintf("rate limit exceeded for impersonation proxy policy rule %q", rule.name)
+		}
+		return ""
+	}
+	return ""
+}
+
+func (p *dynamicPolicy) currentRules() []*policyRule {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.rules
+}
+
+type policyRule struct {
+	name      string
+	action    v1alpha1.ImpersonationProxyPolicyAction
+	users     sets.Set[string]
+	groups    sets.Set[string]
+	verbs     sets.Set[string]
+	apiGroups sets.Set[string]
+	resources sets.Set[string]
+
+	qps   float32
+	burst int
+
+	limitersLock sync.Mutex
+	limiters     *cache.Expiring
+}
+
+func newPolicyRule(rule v1alpha1.ImpersonationProxyPolicyRule) *policyRule {
+	r := &policyRule{
+		name:      rule.Name,
+		action:    rule.Action,
+		users:     sets.New(rule.Users...),
+		groups:    sets.New(rule.Groups...),
+		verbs:     sets.New(rule.Verbs...),
+		apiGroups: sets.New(rule.APIGroups...),
+		resources: sets.New(rule.Resources...),
+		limiters:  cache.NewExpiring(),
+	}
+	if rule.RateLimit != nil {
+		r.qps = float32(rule.RateLimit.RequestsPerSecond)
+		r.burst = int(rule.RateLimit.Burst)
+		if r.burst == 0 {
+			r.burst = int(rule.RateLimit.RequestsPerSecond)
+		}
+	}
+	return r
+}
+
+func (r *policyRule) matches(a authorizer.Attributes) bool {
+	if !r.matchesUser(a) {
+		return false
+	}
+
+	if r.verbs.Len() > 0 && !r.verbs.Has("*") && !r.verbs.Has(a.GetVerb()) {
+		return false
+	}
+
+	if r.apiGroups.Len() == 0 && r.resources.Len() == 0 {
+		return true
+	}
+
+	if !a.IsResourceRequest() {
+		return false
+	}
+
+	if r.apiGroups.Len() > 0 && !r.apiGroups.Has("*") && !r.apiGroups.Has(a.GetAPIGroup()) {
+		return false
+	}
+
+	return r.resources.Len() == 0 || r.matchesResource(a.GetResource(), a.GetSubresource())
+}
+
+func (r *policyRule) matchesUser(a authorizer.Attributes) bool {
+	if r.users.Len() == 0 && r.groups.Len() == 0 {
+		return true
+	}
+
+	u := a.GetUser()
+	if u == nil {
+		return false
+	}
+
+	return r.users.Has(u.GetName()) || r.groups.HasAny(u.GetGroups()...)
+}
+
+// matchesResource follows the same conventions as RBAC rules for resources and subresources.
+func (r *policyRule) matchesResource(resource, subresource string) bool {
+	if r.resources.Has("*") {
+		return true
+	}
+
+	if len(subresource) == 0 {
+		return r.resources.Has(resource)
+	}
+
+	return r.resources.Has(resource+"/"+subresource) ||
+		r.resources.Has("*/"+subresource) ||
+		r.resources.Has(resource+"/*")
+}
+
+func (r *policyRule) tryAccept(username string) bool {
+	r.limitersLock.Lock()
+	defer r.limitersLock.Unlock()
+
+	limiter, ok := r.limiters.Get(username)
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(r.qps, r.burst)
+	}
+
+	// Always extend the TTL so that only the rate limiters of idle users are forgotten.
+	r.limiters.Set(username, limiter, rateLimiterIdleTTL)
+
+	return limiter.(flowcontrol.RateLimiter).TryAccept()
+}
+
+// ValidatePolicy returns an error when the policy cannot be applied.
+func ValidatePolicy(spec *v1alpha1.ImpersonationProxyPolicy) error {
+	if spec == nil {
+		return nil
+	}
+
+	names := sets.New[string]()
+	for i, rule := range spec.Rules {
+		if len(strings.TrimSpace(rule.Name)) == 0 {
+			return fmt.Errorf("rules[%d] must have a name", i)
+		}
+		if names.Has(rule.Name) {
+			return fmt.Errorf("duplicate rule name %q", rule.Name)
+		}
+		names.Insert(rule.Name)
+
+		switch rule.Action {
+		case v1alpha1.ImpersonationProxyPolicyActionDeny:
+			if rule.RateLimit != nil {
+				return fmt.Errorf("rule %q has action Deny so it must not specify rateLimit", rule.Name)
+			}
+		case v1alpha1.ImpersonationProxyPolicyActionRateLimit:
+			if rule.RateLimit == nil {
+				return fmt.Errorf("rule %q has action RateLimit so it must specify rateLimit", rule.Name)
+			}
+			if rule.RateLimit.RequestsPerSecond < 1 {
+				return fmt.Errorf("rule %q must have a rateLimit.requestsPerSecond of at least 1", rule.Name)
+			}
+			if rule.RateLimit.Burst < 0 {
+				return fmt.Errorf("rule %q must not have a negative rateLimit.burst", rule.Name)
+			}
+		default:
+			return fmt.Errorf("rule %q has invalid action %q (expected Deny or RateLimit)", rule.Name, rule.Action)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+
+	"go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+)
+
+func TestPolicyDeny(t *testing.T) {
+	t.Parallel()
+
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs"}}
+	bob := &user.DefaultInfo{Name: "bob", Groups: []string{"ops"}}
+
+	tests := []struct {
+		name       string
+		spec       *v1alpha1.ImpersonationProxyPolicy
+		attributes authorizer.Attributes
+		wantReason string
+	}{
+		{
+			name:       "nil policy",
+			spec:       nil,
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods"},
+		},
+		{
+			name: "rule without any selectors denies everything",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-all", Action: v1alpha1.ImpersonationProxyPolicyActionDeny},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", Path: "/healthz"},
+			wantReason: `denied by impersonation proxy policy rule "deny-all"`,
+		},
+		{
+			name: "matching user",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-alice", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Users: []string{"alice"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods"},
+			wantReason: `denied by impersonation proxy policy rule "deny-alice"`,
+		},
+		{
+			name: "matching group",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-ops", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Users: []string{"alice"}, Groups: []string{"ops"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: bob, Verb: "get", ResourceRequest: true, Resource: "pods"},
+			wantReason: `denied by impersonation proxy policy rule "deny-ops"`,
+		},
+		{
+			name: "neither user nor group match",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-devs", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Groups: []string{"devs"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: bob, Verb: "get", ResourceRequest: true, Resource: "pods"},
+		},
+		{
+			name: "verb does not match",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-deletes", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Verbs: []string{"delete", "deletecollection"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods"},
+		},
+		{
+			name: "resource rules do not match non-resource requests",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-secrets", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Resources: []string{"*"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", Path: "/healthz"},
+		},
+		{
+			name: "api group does not match",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-apps", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, APIGroups: []string{"apps"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods"},
+		},
+		{
+			name: "api group wildcard and resource match",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-secrets", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, APIGroups: []string{"*"}, Resources: []string{"secrets"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "list", ResourceRequest: true, Resource: "secrets"},
+			wantReason: `denied by impersonation proxy policy rule "deny-secrets"`,
+		},
+		{
+			name: "resource without subresource does not match subresource request",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-pods", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Resources: []string{"pods"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "create", ResourceRequest: true, Resource: "pods", Subresource: "exec"},
+		},
+		{
+			name: "exact subresource match",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-exec", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Resources: []string{"pods/exec"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "create", ResourceRequest: true, Resource: "pods", Subresource: "exec"},
+			wantReason: `denied by impersonation proxy policy rule "deny-exec"`,
+		},
+		{
+			name: "wildcard resource with subresource",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-status", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Resources: []string{"*/status"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "update", ResourceRequest: true, Resource: "deployments", Subresource: "status"},
+			wantReason: `denied by impersonation proxy policy rule "deny-status"`,
+		},
+		{
+			name: "resource with wildcard subresource",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny-pod-subresources", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Resources: []string{"pods/*"}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods", Subresource: "log"},
+			wantReason: `denied by impersonation proxy policy rule "deny-pod-subresources"`,
+		},
+		{
+			name: "rate limit rules never deny",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "throttle", Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit, RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1}},
+			}},
+			attributes: &authorizer.AttributesRecord{User: alice, Verb: "get", ResourceRequest: true, Resource: "pods"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewPolicy()
+			p.Set(tt.spec)
+			require.Equal(t, tt.wantReason, p.Deny(tt.attributes))
+		})
+	}
+}
+
+func TestPolicyThrottle(t *testing.T) {
+	t.Parallel()
+
+	alice := &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "list", ResourceRequest: true, Resource: "pods"}
+	bob := &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "bob"}, Verb: "list", ResourceRequest: true, Resource: "pods"}
+	aliceHealthz := &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "get", Path: "/healthz"}
+
+	spec := &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+		{
+			Name:      "throttle-pods",
+			Action:    v1alpha1.ImpersonationProxyPolicyActionRateLimit,
+			Resources: []string{"pods"},
+			RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1, Burst: 2},
+		},
+	}}
+
+	p := NewPolicy()
+	require.Empty(t, p.Throttle(alice), "empty policy should never throttle")
+
+	p.Set(spec)
+	require.Empty(t, p.Throttle(alice))
+	require.Empty(t, p.Throttle(alice))
+	require.Equal(t, `rate limit exceeded for impersonation proxy policy rule "throttle-pods"`, p.Throttle(alice))
+
+	// Each user has their own rate limit.
+	require.Empty(t, p.Throttle(bob))
+
+	// Requests which do not match the rule are not throttled.
+	require.Empty(t, p.Throttle(aliceHealthz))
+
+	// Anonymous requests without a user cannot be counted.
+	require.Empty(t, p.Throttle(&authorizer.AttributesRecord{Verb: "list", ResourceRequest: true, Resource: "pods"}))
+
+	// Setting an equal spec preserves the state of the rate limiters.
+	p.Set(spec.DeepCopy())
+	require.Equal(t, `rate limit exceeded for impersonation proxy policy rule "throttle-pods"`, p.Throttle(alice))
+
+	// Setting a different spec resets the state of the rate limiters.
+	updatedSpec := spec.DeepCopy()
+	updatedSpec.Rules[0].RateLimit.Burst = 1
+	p.Set(updatedSpec)
+	require.Empty(t, p.Throttle(alice))
+	require.Equal(t, `rate limit exceeded for impersonation proxy policy rule "throttle-pods"`, p.Throttle(alice))
+
+	// Removing the policy removes the rate limits.
+	p.Set(nil)
+	require.Empty(t, p.Throttle(alice))
+}
+
+func TestPolicyThrottleOnlyFirstMatchingRule(t *testing.T) {
+	t.Parallel()
+
+	alicePods := &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "list", ResourceRequest: true, Resource: "pods"}
+	aliceSecrets := &authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "list", ResourceRequest: true, Resource: "secrets"}
+
+	p := NewPolicy()
+	p.Set(&v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+		{
+			Name:      "throttle-pods",
+			Action:    v1alpha1.ImpersonationProxyPolicyActionRateLimit,
+			Resources: []string{"pods"},
+			RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1, Burst: 3},
+		},
+		{
+			Name:      "throttle-everything",
+			Action:    v1alpha1.ImpersonationProxyPolicyActionRateLimit,
+			RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1, Burst: 1},
+		},
+	}})
+
+	// Requests for pods match both rules, but only count against the first one.
+	require.Empty(t, p.Throttle(alicePods))
+	require.Empty(t, p.Throttle(alicePods))
+	require.Empty(t, p.Throttle(alicePods))
+	require.Equal(t, `rate limit exceeded for impersonation proxy policy rule "throttle-pods"`, p.Throttle(alicePods))
+
+	// The second rule's tokens were not consumed by the requests for pods.
+	require.Empty(t, p.Throttle(aliceSecrets))
+	require.Equal(t, `rate limit exceeded for impersonation proxy policy rule "throttle-everything"`, p.Throttle(aliceSecrets))
+}
+
+func TestValidatePolicy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		spec    *v1alpha1.ImpersonationProxyPolicy
+		wantErr string
+	}{
+		{
+			name: "nil policy",
+		},
+		{
+			name: "valid rules",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, Groups: []string{"contractors"}, Resources: []string{"secrets"}},
+				{Name: "throttle", Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit, RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 10}},
+			}},
+		},
+		{
+			name: "missing name",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny", Action: v1alpha1.ImpersonationProxyPolicyActionDeny},
+				{Name: " ", Action: v1alpha1.ImpersonationProxyPolicyActionDeny},
+			}},
+			wantErr: "rules[1] must have a name",
+		},
+		{
+			name: "duplicate name",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny", Action: v1alpha1.ImpersonationProxyPolicyActionDeny},
+				{Name: "deny", Action: v1alpha1.ImpersonationProxyPolicyActionDeny},
+			}},
+			wantErr: `duplicate rule name "deny"`,
+		},
+		{
+			name: "deny with rate limit",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "deny", Action: v1alpha1.ImpersonationProxyPolicyActionDeny, RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1}},
+			}},
+			wantErr: `rule "deny" has action Deny so it must not specify rateLimit`,
+		},
+		{
+			name: "rate limit without rate limit",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "throttle", Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit},
+			}},
+			wantErr: `rule "throttle" has action RateLimit so it must specify rateLimit`,
+		},
+		{
+			name: "rate limit with zero requests per second",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "throttle", Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit, RateLimit: &v1alpha1.ImpersonationProxyRateLimit{}},
+			}},
+			wantErr: `rule "throttle" must have a rateLimit.requestsPerSecond of at least 1`,
+		},
+		{
+			name: "rate limit with negative burst",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "throttle", Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit, RateLimit: &v1alpha1.ImpersonationProxyRateLimit{RequestsPerSecond: 1, Burst: -1}},
+			}},
+			wantErr: `rule "throttle" must not have a negative rateLimit.burst`,
+		},
+		{
+			name: "invalid action",
+			spec: &v1alpha1.ImpersonationProxyPolicy{Rules: []v1alpha1.ImpersonationProxyPolicyRule{
+				{Name: "allow", Action: "Allow"},
+			}},
+			wantErr: `rule "allow" has invalid action "Allow" (expected Deny or RateLimit)`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidatePolicy(tt.spec)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package server is the command line entry point for pinniped-concierge.
@@ -25,6 +25,7 @@ import (
 
 	conciergeopenapi "go.pinniped.dev/generated/latest/client/concierge/openapi"
 	"go.pinniped.dev/internal/certauthority/dynamiccertauthority"
+	"go.pinniped.dev/internal/certauthority/externalsigner"
 	"go.pinniped.dev/internal/clientcertissuer"
 	"go.pinniped.dev/internal/concierge/apiserver"
 	conciergescheme "go.pinniped.dev/internal/concierge/scheme"
@@ -142,6 +143,14 @@ func (a *App) runServer(ctx context.Context) error {
 	// cert issuer used to issue certs to Pinniped clients wishing to log in.
 	impersonationProxySigningCertProvider := dynamiccert.NewCA("impersonation-proxy-signing-cert")
 
+	// This cert issuer will be configured by a controller to issue certs to Pinniped clients wishing
+	// to log in through an external signer, when the CredentialIssuer configures one.
+	externalSignerCertIssuer := externalsigner.New()
+
+	// This cert provider will be used to provide the Concierge-managed client CA's signing key to the
+	// cert issuer used to issue certs to Pinniped clients wishing to log in, once the Kube API server trusts it.
+	managedCASigningCertProvider := dynamiccert.NewCA("concierge-managed-client-ca")
+
 	// Get the "real" name of the login concierge API group (i.e., the API group name with the
 	// injected suffix).
 	scheme, loginGV, identityGV := conciergescheme.New(*cfg.APIGroupSuffix)
@@ -156,7 +165,7 @@ func (a *App) runServer(ctx context.Context) error {
 	tlsClientConfig := rest.TLSClientConfig{}
 
 	if _, err := certutil.NewPool(*cfg.KubernetesServiceCAFile); err != nil {
-		fmt.Errorf("Expected to load root CA config from %s, but got err: %v", *cfg.KubernetesServiceCAFile)
+		plog.Error("expected to load root CA config, but got error", err, "caFile", *cfg.KubernetesServiceCAFile)
 	} else {
 		tlsClientConfig.CAFile = *cfg.KubernetesServiceCAFile
 	}
@@ -181,13 +190,16 @@ func (a *App) runServer(ctx context.Context) error {
 			DynamicServingCertProvider:       dynamicServingCertProvider,
 			DynamicSigningCertProvider:       dynamicSigningCertProvider,
 			ImpersonationSigningCertProvider: impersonationProxySigningCertProvider,
+			ManagedCASigningCertProvider:     managedCASigningCertProvider,
+			ExternalSigner:                   externalSignerCertIssuer,
 			ServingCertDuration:              time.Duration(*cfg.APIConfig.ServingCertificateConfig.DurationSeconds) * time.Second,
 			ServingCertRenewBefore:           time.Duration(*cfg.APIConfig.ServingCertificateConfig.RenewBeforeSeconds) * time.Second,
 			AuthenticatorCache:               authenticators,
 			// This port should be safe to cast because the config reader already validated it.
-			ImpersonationProxyServerPort: int(*cfg.ImpersonationProxyServerPort),
-			ImpersonationProxyTokenCache: impersonationProxyTokenCache,
-			BaseConfig:                   &baseConfig,
+			ImpersonationProxyServerPort:  int(*cfg.ImpersonationProxyServerPort),
+			ImpersonationProxyAuditConfig: &cfg.ImpersonationProxyAudit,
+			ImpersonationProxyTokenCache:  impersonationProxyTokenCache,
+			BaseConfig:                    &baseConfig,
 		},
 	)
 	if err != nil {
@@ -196,6 +208,8 @@ func (a *App) runServer(ctx context.Context) error {
 
 	certIssuer := clientcertissuer.ClientCertIssuers{
 		dynamiccertauthority.New(dynamicSigningCertProvider),            // attempt to use the real Kube CA if possible
+		externalSignerCertIssuer,                                        // then try an external signer, if one is configured
+		dynamiccertauthority.New(managedCASigningCertProvider),          // then try our managed CA, if the Kube API server trusts it
 		dynamiccertauthority.New(impersonationProxySigningCertProvider), // fallback to our internal CA if we need to
 	}
 
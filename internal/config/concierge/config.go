@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package concierge contains functionality to load/store Config's from/to
@@ -75,6 +75,10 @@ func FromPath(ctx context.Context, path string) (*Config, error) {
 		return nil, fmt.Errorf("validate impersonationProxyServerPort: %w", err)
 	}
 
+	if err := validateImpersonationProxyAudit(&config.ImpersonationProxyAudit); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxyAudit: %w", err)
+	}
+
 	if err := validateNames(&config.NamesConfig); err != nil {
 		return nil, fmt.Errorf("validate names: %w", err)
 	}
@@ -167,6 +171,12 @@ func validateNames(names *NamesConfigSpec) error {
 	if names.ImpersonationProxyLegacySecret == "" {
 		missingNames = append(missingNames, "impersonationProxyLegacySecret")
 	}
+	if names.ManagedClientCASecret == "" {
+		missingNames = append(missingNames, "managedClientCASecret")
+	}
+	if names.ManagedClientCABundleConfigMap == "" {
+		missingNames = append(missingNames, "managedClientCABundleConfigMap")
+	}
 	if len(missingNames) > 0 {
 		return constable.Error("missing required names: " + strings.Join(missingNames, ", "))
 	}
@@ -189,6 +199,33 @@ func validateAPIGroupSuffix(apiGroupSuffix string) error {
 	return groupsuffix.Validate(apiGroupSuffix)
 }
 
+func validateImpersonationProxyAudit(audit *ImpersonationProxyAuditSpec) error {
+	hasSink := audit.LogFile != "" || audit.WebhookKubeconfigFile != ""
+	if audit.PolicyFile == "" && hasSink {
+		return constable.Error("policyFile must be specified when logFile or webhookKubeconfigFile is specified")
+	}
+	if audit.PolicyFile != "" && !hasSink {
+		return constable.Error("at least one of logFile or webhookKubeconfigFile must be specified when policyFile is specified")
+	}
+	batch := audit.WebhookBatch
+	if (batch.BufferSize != nil || batch.MaxSize != nil || batch.MaxWaitSeconds != nil) && audit.WebhookKubeconfigFile == "" {
+		return constable.Error("webhookBatch must not be specified when webhookKubeconfigFile is not specified")
+	}
+	for _, field := range []struct {
+		name  string
+		value *int64
+	}{
+		{name: "bufferSize", value: batch.BufferSize},
+		{name: "maxSize", value: batch.MaxSize},
+		{name: "maxWaitSeconds", value: batch.MaxWaitSeconds},
+	} {
+		if field.value != nil && *field.value < 1 {
+			return fmt.Errorf("webhookBatch.%s must be at least 1", field.name)
+		}
+	}
+	return nil
+}
+
 func validateServerPort(port *int64) error {
 	// It cannot be below 1024 because the container is not running as root.
 	if *port < 1024 || *port > 65535 {
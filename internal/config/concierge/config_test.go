@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package concierge
@@ -35,6 +35,14 @@ func TestFromPath(t *testing.T) {
 				apiGroupSuffix: some.suffix.com
 				aggregatedAPIServerPort: 12345
 				impersonationProxyServerPort: 4242
+				impersonationProxyAudit:
+				  policyFile: /etc/audit/policy.yaml
+				  logFile: /var/log/audit.log
+				  webhookKubeconfigFile: /etc/audit/webhook.kubeconfig
+				  webhookBatch:
+				    bufferSize: 100
+				    maxSize: 10
+				    maxWaitSeconds: 5
 				names:
 				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
 				  credentialIssuer: pinniped-config
@@ -49,6 +57,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 				  extraName: extraName-value
 				labels:
 				  myLabelKey1: myLabelValue1
@@ -72,6 +82,16 @@ func TestFromPath(t *testing.T) {
 				APIGroupSuffix:               ptr.To("some.suffix.com"),
 				AggregatedAPIServerPort:      ptr.To[int64](12345),
 				ImpersonationProxyServerPort: ptr.To[int64](4242),
+				ImpersonationProxyAudit: ImpersonationProxyAuditSpec{
+					PolicyFile:            "/etc/audit/policy.yaml",
+					LogFile:               "/var/log/audit.log",
+					WebhookKubeconfigFile: "/etc/audit/webhook.kubeconfig",
+					WebhookBatch: ImpersonationProxyAuditWebhookBatchSpec{
+						BufferSize:     ptr.To[int64](100),
+						MaxSize:        ptr.To[int64](10),
+						MaxWaitSeconds: ptr.To[int64](5),
+					},
+				},
 				NamesConfig: NamesConfigSpec{
 					ServingCertificateSecret:          "pinniped-concierge-api-tls-serving-certificate",
 					CredentialIssuer:                  "pinniped-config",
@@ -84,6 +104,8 @@ func TestFromPath(t *testing.T) {
 					AgentServiceAccount:               "agentServiceAccount-value",
 					ImpersonationProxyServiceAccount:  "impersonationProxyServiceAccount-value",
 					ImpersonationProxyLegacySecret:    "impersonationProxyLegacySecret-value",
+					ManagedClientCASecret:             "managedClientCASecret-value",
+					ManagedClientCABundleConfigMap:    "managedClientCABundleConfigMap-value",
 				},
 				Labels: map[string]string{
 					"myLabelKey1": "myLabelValue1",
@@ -127,6 +149,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 				  extraName: extraName-value
 				labels:
 				  myLabelKey1: myLabelValue1
@@ -164,6 +188,8 @@ func TestFromPath(t *testing.T) {
 					AgentServiceAccount:               "agentServiceAccount-value",
 					ImpersonationProxyServiceAccount:  "impersonationProxyServiceAccount-value",
 					ImpersonationProxyLegacySecret:    "impersonationProxyLegacySecret-value",
+					ManagedClientCASecret:             "managedClientCASecret-value",
+					ManagedClientCABundleConfigMap:    "managedClientCABundleConfigMap-value",
 				},
 				Labels: map[string]string{
 					"myLabelKey1": "myLabelValue1",
@@ -207,6 +233,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 				  extraName: extraName-value
 				labels:
 				  myLabelKey1: myLabelValue1
@@ -245,6 +273,8 @@ func TestFromPath(t *testing.T) {
 					AgentServiceAccount:               "agentServiceAccount-value",
 					ImpersonationProxyServiceAccount:  "impersonationProxyServiceAccount-value",
 					ImpersonationProxyLegacySecret:    "impersonationProxyLegacySecret-value",
+					ManagedClientCASecret:             "managedClientCASecret-value",
+					ManagedClientCABundleConfigMap:    "managedClientCABundleConfigMap-value",
 				},
 				Labels: map[string]string{
 					"myLabelKey1": "myLabelValue1",
@@ -299,6 +329,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantConfig: &Config{
 				DiscoveryInfo: DiscoveryInfoSpec{
@@ -325,6 +357,8 @@ func TestFromPath(t *testing.T) {
 					AgentServiceAccount:               "agentServiceAccount-value",
 					ImpersonationProxyServiceAccount:  "impersonationProxyServiceAccount-value",
 					ImpersonationProxyLegacySecret:    "impersonationProxyLegacySecret-value",
+					ManagedClientCASecret:             "managedClientCASecret-value",
+					ManagedClientCABundleConfigMap:    "managedClientCABundleConfigMap-value",
 				},
 				Labels: map[string]string{},
 				KubeCertAgentConfig: KubeCertAgentSpec{
@@ -339,7 +373,8 @@ func TestFromPath(t *testing.T) {
 			wantError: "validate names: missing required names: servingCertificateSecret, credentialIssuer, " +
 				"apiService, impersonationLoadBalancerService, " +
 				"impersonationClusterIPService, impersonationTLSCertificateSecret, impersonationCACertificateSecret, " +
-				"impersonationSignerSecret, agentServiceAccount, impersonationProxyServiceAccount, impersonationProxyLegacySecret",
+				"impersonationSignerSecret, agentServiceAccount, impersonationProxyServiceAccount, impersonationProxyLegacySecret, " +
+				"managedClientCASecret, managedClientCABundleConfigMap",
 		},
 		{
 			name: "Missing apiService name",
@@ -356,6 +391,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: apiService",
 		},
@@ -374,6 +411,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: credentialIssuer",
 		},
@@ -392,6 +431,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: servingCertificateSecret",
 		},
@@ -410,6 +451,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationLoadBalancerService",
 		},
@@ -428,6 +471,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationClusterIPService",
 		},
@@ -446,6 +491,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationTLSCertificateSecret",
 		},
@@ -464,6 +511,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationCACertificateSecret",
 		},
@@ -482,6 +531,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationSignerSecret",
 		},
@@ -500,6 +551,8 @@ func TestFromPath(t *testing.T) {
 				  impersonationSignerSecret: impersonationSignerSecret-value
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationProxyServiceAccount",
 		},
@@ -518,9 +571,51 @@ func TestFromPath(t *testing.T) {
 				  impersonationSignerSecret: impersonationSignerSecret-value
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: impersonationProxyLegacySecret",
 		},
+		{
+			name: "Missing managedClientCASecret name",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
+			`),
+			wantError: "validate names: missing required names: managedClientCASecret",
+		},
+		{
+			name: "Missing managedClientCABundleConfigMap name",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+			`),
+			wantError: "validate names: missing required names: managedClientCABundleConfigMap",
+		},
 		{
 			name: "Missing several required names",
 			yaml: here.Doc(`
@@ -535,6 +630,8 @@ func TestFromPath(t *testing.T) {
 				  agentServiceAccount: agentServiceAccount-value
 				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
 				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				  managedClientCASecret: managedClientCASecret-value
+				  managedClientCABundleConfigMap: managedClientCABundleConfigMap-value
 			`),
 			wantError: "validate names: missing required names: " +
 				"impersonationTLSCertificateSecret, impersonationCACertificateSecret",
@@ -609,6 +706,48 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate impersonationProxyServerPort: must be within range 1024 to 65535",
 		},
+		{
+			name: "ImpersonationProxyAudit sink without policy",
+			yaml: here.Doc(`
+				---
+				impersonationProxyAudit:
+				  logFile: "-"
+			`),
+			wantError: "validate impersonationProxyAudit: policyFile must be specified when logFile or webhookKubeconfigFile is specified",
+		},
+		{
+			name: "ImpersonationProxyAudit policy without sink",
+			yaml: here.Doc(`
+				---
+				impersonationProxyAudit:
+				  policyFile: /etc/audit/policy.yaml
+			`),
+			wantError: "validate impersonationProxyAudit: at least one of logFile or webhookKubeconfigFile must be specified when policyFile is specified",
+		},
+		{
+			name: "ImpersonationProxyAudit webhook batch without webhook",
+			yaml: here.Doc(`
+				---
+				impersonationProxyAudit:
+				  policyFile: /etc/audit/policy.yaml
+				  logFile: "-"
+				  webhookBatch:
+				    maxSize: 10
+			`),
+			wantError: "validate impersonationProxyAudit: webhookBatch must not be specified when webhookKubeconfigFile is not specified",
+		},
+		{
+			name: "ImpersonationProxyAudit webhook batch with invalid value",
+			yaml: here.Doc(`
+				---
+				impersonationProxyAudit:
+				  policyFile: /etc/audit/policy.yaml
+				  webhookKubeconfigFile: /etc/audit/webhook.kubeconfig
+				  webhookBatch:
+				    maxWaitSeconds: 0
+			`),
+			wantError: "validate impersonationProxyAudit: webhookBatch.maxWaitSeconds must be at least 1",
+		},
 		{
 			name: "ZeroRenewBefore",
 			yaml: here.Doc(`
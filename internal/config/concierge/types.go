@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package concierge
@@ -7,18 +7,19 @@ import "go.pinniped.dev/internal/plog"
 
 // Config contains knobs to set up an instance of the Pinniped Concierge.
 type Config struct {
-	DiscoveryInfo                DiscoveryInfoSpec `json:"discovery"`
-	APIConfig                    APIConfigSpec     `json:"api"`
-	APIGroupSuffix               *string           `json:"apiGroupSuffix,omitempty"`
-	AggregatedAPIServerPort      *int64            `json:"aggregatedAPIServerPort"`
-	ImpersonationProxyServerPort *int64            `json:"impersonationProxyServerPort"`
-	NamesConfig                  NamesConfigSpec   `json:"names"`
-	KubeCertAgentConfig          KubeCertAgentSpec `json:"kubeCertAgent"`
-	Labels                       map[string]string `json:"labels"`
-	KubernetesServiceHost        *string           `json:"kubernetesServiceHost,omitempty"`
-	KubernetesServicePort        *string           `json:"kubernetesServicePort,omitempty"`
-	KubernetesServiceCAFile      *string           `json:"kubernetesServiceCAFile,omitempty"`
-	KubernetesServiceTokenFile   *string           `json:"kubernetesServiceTokenFile,omitempty"`
+	DiscoveryInfo                DiscoveryInfoSpec           `json:"discovery"`
+	APIConfig                    APIConfigSpec               `json:"api"`
+	APIGroupSuffix               *string                     `json:"apiGroupSuffix,omitempty"`
+	AggregatedAPIServerPort      *int64                      `json:"aggregatedAPIServerPort"`
+	ImpersonationProxyServerPort *int64                      `json:"impersonationProxyServerPort"`
+	ImpersonationProxyAudit      ImpersonationProxyAuditSpec `json:"impersonationProxyAudit"`
+	NamesConfig                  NamesConfigSpec             `json:"names"`
+	KubeCertAgentConfig          KubeCertAgentSpec           `json:"kubeCertAgent"`
+	Labels                       map[string]string           `json:"labels"`
+	KubernetesServiceHost        *string                     `json:"kubernetesServiceHost,omitempty"`
+	KubernetesServicePort        *string                     `json:"kubernetesServicePort,omitempty"`
+	KubernetesServiceCAFile      *string                     `json:"kubernetesServiceCAFile,omitempty"`
+	KubernetesServiceTokenFile   *string                     `json:"kubernetesServiceTokenFile,omitempty"`
 
 	// Deprecated: use log.level instead
 	LogLevel *plog.LogLevel `json:"logLevel"`
@@ -52,6 +53,8 @@ type NamesConfigSpec struct {
 	AgentServiceAccount               string `json:"agentServiceAccount"`
 	ImpersonationProxyServiceAccount  string `json:"impersonationProxyServiceAccount"`
 	ImpersonationProxyLegacySecret    string `json:"impersonationProxyLegacySecret"`
+	ManagedClientCASecret             string `json:"managedClientCASecret"`
+	ManagedClientCABundleConfigMap    string `json:"managedClientCABundleConfigMap"`
 }
 
 // ServingCertificateConfigSpec contains the configuration knobs for the API's
@@ -72,6 +75,40 @@ type ServingCertificateConfigSpec struct {
 	RenewBeforeSeconds *int64 `json:"renewBeforeSeconds,omitempty"`
 }
 
+// ImpersonationProxyAuditSpec configures audit events for the requests handled by the impersonation proxy.
+// This is useful on clusters where the audit configuration of the Kubernetes API server cannot be changed.
+type ImpersonationProxyAuditSpec struct {
+	// PolicyFile is the path to a standard Kubernetes audit policy file (audit.k8s.io/v1 Policy).
+	// When this is not set, the impersonation proxy does not emit any audit events.
+	PolicyFile string `json:"policyFile,omitempty"`
+
+	// LogFile is the path to a file to which audit events are appended as JSON lines.
+	// The special value "-" writes the audit events to stdout.
+	LogFile string `json:"logFile,omitempty"`
+
+	// WebhookKubeconfigFile is the path to a kubeconfig file which describes a webhook to
+	// which audit events are sent, for example an audit sink running as a sidecar container.
+	WebhookKubeconfigFile string `json:"webhookKubeconfigFile,omitempty"`
+
+	// WebhookBatch configures how audit events are batched before they are sent to the webhook.
+	WebhookBatch ImpersonationProxyAuditWebhookBatchSpec `json:"webhookBatch"`
+}
+
+// ImpersonationProxyAuditWebhookBatchSpec configures the batching of audit events which are sent to a webhook.
+// Each unset field uses the same default as the batching webhook audit backend of the Kubernetes API server.
+type ImpersonationProxyAuditWebhookBatchSpec struct {
+	// BufferSize is the number of audit events which are buffered before they are batched.
+	// When the buffer is full, new audit events are dropped. The default is 10000.
+	BufferSize *int64 `json:"bufferSize,omitempty"`
+
+	// MaxSize is the maximum number of audit events sent to the webhook in one batch. The default is 400.
+	MaxSize *int64 `json:"maxSize,omitempty"`
+
+	// MaxWaitSeconds is how long to wait for a batch to fill up before sending a partial batch.
+	// The default is 30 seconds.
+	MaxWaitSeconds *int64 `json:"maxWaitSeconds,omitempty"`
+}
+
 type KubeCertAgentSpec struct {
 	// NamePrefix is the prefix of the name of the kube-cert-agent pods. For example, if this field is
 	// set to "some-prefix-", then the name of the pods will look like "some-prefix-blah". The default
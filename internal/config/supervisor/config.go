@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package supervisor contains functionality to load/store Config's from/to
@@ -152,6 +152,9 @@ func validateEndpoint(endpoint Endpoint) error {
 }
 
 func validateAdditionalHTTPEndpointRequirements(endpoint Endpoint, allowExternalHTTP stringOrBoolAsBool) error {
+	if endpoint.RequestClientCertificates {
+		return constable.Error("requestClientCertificates may only be set for the https listener")
+	}
 	if endpoint.Network == NetworkTCP && !addrIsOnlyOnLoopback(endpoint.Address) {
 		if allowExternalHTTP {
 			// Log that the validation should have been triggered.
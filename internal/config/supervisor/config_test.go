@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisor
@@ -238,6 +238,52 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: `validate https endpoint: unknown network "foo"`,
 		},
+		{
+			name: "https endpoint requests client certificates",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				endpoints:
+				  https:
+				    network: tcp
+				    address: :8443
+				    requestClientCertificates: true
+			`),
+			wantConfig: &Config{
+				APIGroupSuffix: ptr.To("pinniped.dev"),
+				Labels:         map[string]string{},
+				NamesConfig: NamesConfigSpec{
+					DefaultTLSCertificateSecret: "my-secret-name",
+				},
+				Endpoints: &Endpoints{
+					HTTPS: &Endpoint{
+						Network:                   "tcp",
+						Address:                   ":8443",
+						RequestClientCertificates: true,
+					},
+					HTTP: &Endpoint{
+						Network: "disabled",
+					},
+				},
+				AllowExternalHTTP:       false,
+				AggregatedAPIServerPort: ptr.To[int64](10250),
+			},
+		},
+		{
+			name: "http endpoint requests client certificates",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				endpoints:
+				  http:
+				    network: tcp
+				    address: 127.0.0.1:8080
+				    requestClientCertificates: true
+			`),
+			wantError: "validate http endpoint: requestClientCertificates may only be set for the https listener",
+		},
 		{
 			name: "invalid http endpoint",
 			yaml: here.Doc(`
@@ -1,4 +1,4 @@
-// Copyright 2020-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisor
@@ -36,6 +36,9 @@ type Endpoints struct {
 type Endpoint struct {
 	Network string `json:"network"`
 	Address string `json:"address"`
+	// RequestClientCertificates makes the listener request client certificates, which are needed by OIDCClients
+	// that use the tls_client_auth client authentication method. It may only be set for the HTTPS listener.
+	RequestClientCertificates bool `json:"requestClientCertificates,omitempty"`
 }
 
 type stringOrBoolAsBool bool
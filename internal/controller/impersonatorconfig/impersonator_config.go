@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package impersonatorconfig
@@ -90,7 +90,8 @@ type impersonatorConfigController struct {
 	debugLog                          logr.Logger
 
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet
-	baseConfig *rest.Config // for unit testing, should always be nil in production
+	impersonationProxyPolicy     impersonator.Policy
+	baseConfig                   *rest.Config // for unit testing, should always be nil in production
 }
 
 func NewImpersonatorConfigController(
@@ -143,6 +144,7 @@ func NewImpersonatorConfigController(
 				infoLog:                           log.V(plog.KlogLevelInfo),
 				debugLog:                          log.V(plog.KlogLevelDebug),
 				impersonationProxyTokenCache:      impersonationProxyTokenCache,
+				impersonationProxyPolicy:          impersonator.NewPolicy(),
 				baseConfig:                        baseConfig,
 			},
 		},
@@ -255,6 +257,9 @@ func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, cre
 		return nil, err
 	}
 
+	// The running impersonator reads the policy dynamically, so update it before possibly starting the impersonator.
+	c.impersonationProxyPolicy.Set(impersonationSpec.Policy)
+
 	// Make a live API call to avoid the cost of having an informer watch all node changes on the cluster,
 	// since there could be lots, and we don't especially care about node changes.
 	// Once we have concluded that there is or is not a visible control plane, then cache that decision
@@ -497,6 +502,7 @@ func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx contr
 		c.tlsServingCertDynamicCertProvider,
 		c.impersonationSigningCertProvider,
 		c.impersonationProxyTokenCache,
+		c.impersonationProxyPolicy,
 		c.baseConfig,
 	)
 	if err != nil {
@@ -1225,5 +1231,9 @@ func validateCredentialIssuerSpec(spec *v1alpha1.ImpersonationProxySpec) error {
 		}
 	}
 
+	if err := impersonator.ValidatePolicy(spec.Policy); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
 	return nil
 }
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package impersonatorconfig
@@ -32,6 +32,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/intstr"
 	k8sinformers "k8s.io/client-go/informers"
 	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
 	coretesting "k8s.io/client-go/testing"
 	clocktesting "k8s.io/utils/clock/testing"
 
@@ -39,6 +40,7 @@ import (
 	pinnipedfake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
 	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/concierge/impersonator"
 	"go.pinniped.dev/internal/controller/apicerts"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/dynamiccert"
@@ -95,6 +97,7 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 				nil,
 				plog.Logr(), //nolint:staticcheck // old test with no log assertions
 				nil,
+				nil,
 			)
 			credIssuerInformerFilter = observableWithInformerOption.GetFilterForInformer(credIssuerInformer)
 			servicesInformerFilter = observableWithInformerOption.GetFilterForInformer(servicesInformer)
@@ -312,12 +315,16 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			dynamicCertProvider dynamiccert.Private,
 			impersonationProxySignerCAProvider dynamiccert.Public,
 			expiringSingletonTokenCacheGet tokenclient.ExpiringSingletonTokenCacheGet,
+			impersonationProxyPolicy impersonator.PolicyGetter,
+			baseConfig *rest.Config,
 		) (func(stopCh <-chan struct{}) error, error) {
 			impersonatorFuncWasCalled++
 			r.Equal(8444, port)
 			r.NotNil(dynamicCertProvider)
 			r.NotNil(impersonationProxySignerCAProvider)
 			r.Equal(fakeExpiringSingletonTokenCacheGet, expiringSingletonTokenCacheGet)
+			r.NotNil(impersonationProxyPolicy)
+			r.Nil(baseConfig)
 
 			if impersonatorFuncError != nil {
 				return nil, impersonatorFuncError
@@ -587,6 +594,7 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				mTLSClientCertProvider,
 				plog.Logr(), //nolint:staticcheck  // old test with no log assertions,
 				fakeExpiringSingletonTokenCacheGet,
+				nil,
 			)
 			controllerlib.TestWrap(t, subject, func(syncer controllerlib.Syncer) controllerlib.Syncer {
 				tlsServingCertDynamicCertProvider = syncer.(*impersonatorConfigController).tlsServingCertDynamicCertProvider
@@ -3515,6 +3523,38 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			})
 		})
 
+		when("the CredentialIssuer has an invalid policy", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: v1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &v1alpha1.ImpersonationProxySpec{
+							Mode: v1alpha1.ImpersonationProxyModeEnabled,
+							Service: v1alpha1.ImpersonationProxyServiceSpec{
+								Type: v1alpha1.ImpersonationProxyServiceTypeNone,
+							},
+							ExternalEndpoint: localhostIP,
+							Policy: &v1alpha1.ImpersonationProxyPolicy{
+								Rules: []v1alpha1.ImpersonationProxyPolicyRule{{
+									Name:   "throttle-everyone",
+									Action: v1alpha1.ImpersonationProxyPolicyActionRateLimit,
+								}},
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid policy: rule "throttle-everyone" has action RateLimit so it must specify rateLimit`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireMTLSClientCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
 		when("the CredentialIssuer has invalid ExternalEndpoint", func() {
 			it.Before(func() {
 				addCredentialIssuerToTrackers(v1alpha1.CredentialIssuer{
@@ -0,0 +1,223 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/dynamic"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	configv1alpha1informers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions/config/v1alpha1"
+	"go.pinniped.dev/internal/certauthority/externalsigner"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/issuerconfig"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+const (
+	defaultCertManagerIssuerKind  = "Issuer"
+	defaultCertManagerIssuerGroup = "cert-manager.io"
+)
+
+type externalSignerController struct {
+	cfg                  AgentConfig
+	client               *kubeclient.Client
+	signerKubeClient     kubernetes.Interface
+	signerDynamicClient  dynamic.Interface
+	kubePublicConfigMaps corev1informers.ConfigMapInformer
+	credentialIssuers    configv1alpha1informers.CredentialIssuerInformer
+	externalSigner       externalsigner.Dynamic
+	clock                clock.Clock
+}
+
+// NewExternalSignerController returns a controller that configures the external signer described by the
+// spec.externalSigner of the CredentialIssuer, and reports the status of the ExternalSigner strategy.
+//
+// The signer clients are used to request certificates from the external signer on every Concierge pod, so they
+// must not be subject to leader election.
+func NewExternalSignerController(
+	cfg AgentConfig,
+	client *kubeclient.Client,
+	signerKubeClient kubernetes.Interface,
+	signerDynamicClient dynamic.Interface,
+	kubePublicConfigMaps corev1informers.ConfigMapInformer,
+	credentialIssuers configv1alpha1informers.CredentialIssuerInformer,
+	externalSigner externalsigner.Dynamic,
+) controllerlib.Controller {
+	return newExternalSignerController(
+		cfg,
+		client,
+		signerKubeClient,
+		signerDynamicClient,
+		kubePublicConfigMaps,
+		credentialIssuers,
+		externalSigner,
+		&clock.RealClock{},
+	)
+}
+
+func newExternalSignerController(
+	cfg AgentConfig,
+	client *kubeclient.Client,
+	signerKubeClient kubernetes.Interface,
+	signerDynamicClient dynamic.Interface,
+	kubePublicConfigMaps corev1informers.ConfigMapInformer,
+	credentialIssuers configv1alpha1informers.CredentialIssuerInformer,
+	externalSigner externalsigner.Dynamic,
+	clock clock.Clock,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "external-signer-controller",
+			Syncer: &externalSignerController{
+				cfg:                  cfg,
+				client:               client,
+				signerKubeClient:     signerKubeClient,
+				signerDynamicClient:  signerDynamicClient,
+				kubePublicConfigMaps: kubePublicConfigMaps,
+				credentialIssuers:    credentialIssuers,
+				externalSigner:       externalSigner,
+				clock:                clock,
+			},
+		},
+		controllerlib.WithInformer(
+			kubePublicConfigMaps,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == ClusterInfoNamespace && obj.GetName() == clusterInfoName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			credentialIssuers,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetName() == cfg.CredentialIssuerName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInitialEvent(controllerlib.Key{}),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *externalSignerController) Sync(ctx controllerlib.Context) error {
+	credIssuer, err := c.credentialIssuers.Lister().Get(c.cfg.CredentialIssuerName)
+	if err != nil {
+		return fmt.Errorf("could not get CredentialIssuer to update: %w", err)
+	}
+
+	spec := credIssuer.Spec.ExternalSigner
+	if spec == nil {
+		c.externalSigner.Set(nil)
+
+		// Only report this strategy on clusters where it was previously configured, to avoid cluttering the
+		// status of every CredentialIssuer with a strategy that most clusters do not use.
+		if !hasStrategy(credIssuer, configv1alpha1.ExternalSignerStrategyType) {
+			return nil
+		}
+		return issuerconfig.Update(ctx.Context, c.client.PinnipedConcierge, credIssuer, configv1alpha1.CredentialIssuerStrategy{
+			Type:           configv1alpha1.ExternalSignerStrategyType,
+			Status:         configv1alpha1.ErrorStrategyStatus,
+			Reason:         configv1alpha1.DisabledStrategyReason,
+			Message:        "external signer is not configured",
+			LastUpdateTime: metav1.NewTime(c.clock.Now()),
+		})
+	}
+
+	signer, err := c.newSigner(spec)
+	if err != nil {
+		c.externalSigner.Set(nil)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.InvalidSignerConfigStrategyReason)
+	}
+	c.externalSigner.Set(signer)
+
+	// Load the Kubernetes API info from the kube-public/cluster-info ConfigMap.
+	configMap, err := c.kubePublicConfigMaps.Lister().ConfigMaps(ClusterInfoNamespace).Get(clusterInfoName)
+	if err != nil {
+		err := fmt.Errorf("failed to get %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	apiInfo, err := extractAPIInfo(configMap, c.cfg.DiscoveryURLOverride)
+	if err != nil {
+		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	return issuerconfig.Update(ctx.Context, c.client.PinnipedConcierge, credIssuer, configv1alpha1.CredentialIssuerStrategy{
+		Type:           configv1alpha1.ExternalSignerStrategyType,
+		Status:         configv1alpha1.SuccessStrategyStatus,
+		Reason:         configv1alpha1.SignerConfiguredStrategyReason,
+		Message:        fmt.Sprintf("client certificates are issued by %s", signer.Name()),
+		LastUpdateTime: metav1.NewTime(c.clock.Now()),
+		Frontend: &configv1alpha1.CredentialIssuerFrontend{
+			Type:                          configv1alpha1.TokenCredentialRequestAPIFrontendType,
+			TokenCredentialRequestAPIInfo: apiInfo,
+		},
+	})
+}
+
+func (c *externalSignerController) newSigner(spec *configv1alpha1.ExternalSignerSpec) (externalsigner.Signer, error) {
+	switch {
+	case spec.CertificateSigningRequest != nil && spec.CertManager != nil:
+		return nil, fmt.Errorf("only one of certificateSigningRequest or certManager may be configured")
+
+	case spec.CertificateSigningRequest != nil:
+		if len(spec.CertificateSigningRequest.SignerName) == 0 {
+			return nil, fmt.Errorf("certificateSigningRequest.signerName must not be empty")
+		}
+		return externalsigner.NewCertificateSigningRequestSigner(
+			c.signerKubeClient.CertificatesV1().CertificateSigningRequests(),
+			spec.CertificateSigningRequest.SignerName,
+			spec.CertificateSigningRequest.AutoApprove,
+		), nil
+
+	case spec.CertManager != nil:
+		issuerRef := externalsigner.CertManagerIssuerRef{
+			Name:  spec.CertManager.IssuerRef.Name,
+			Kind:  spec.CertManager.IssuerRef.Kind,
+			Group: spec.CertManager.IssuerRef.Group,
+		}
+		if len(issuerRef.Name) == 0 {
+			return nil, fmt.Errorf("certManager.issuerRef.name must not be empty")
+		}
+		if len(issuerRef.Kind) == 0 {
+			issuerRef.Kind = defaultCertManagerIssuerKind
+		}
+		if len(issuerRef.Group) == 0 {
+			issuerRef.Group = defaultCertManagerIssuerGroup
+		}
+		return externalsigner.NewCertManagerSigner(c.signerDynamicClient, c.cfg.Namespace, issuerRef), nil
+
+	default:
+		return nil, fmt.Errorf("one of certificateSigningRequest or certManager must be configured")
+	}
+}
+
+func (c *externalSignerController) failStrategyAndErr(ctx context.Context, credIssuer *configv1alpha1.CredentialIssuer, err error, reason configv1alpha1.StrategyReason) error {
+	updateErr := issuerconfig.Update(ctx, c.client.PinnipedConcierge, credIssuer, configv1alpha1.CredentialIssuerStrategy{
+		Type:           configv1alpha1.ExternalSignerStrategyType,
+		Status:         configv1alpha1.ErrorStrategyStatus,
+		Reason:         reason,
+		Message:        err.Error(),
+		LastUpdateTime: metav1.NewTime(c.clock.Now()),
+	})
+	return utilerrors.NewAggregate([]error{err, updateErr})
+}
+
+func hasStrategy(credIssuer *configv1alpha1.CredentialIssuer, strategyType configv1alpha1.StrategyType) bool {
+	for _, strategy := range credIssuer.Status.Strategies {
+		if strategy.Type == strategyType {
+			return true
+		}
+	}
+	return false
+}
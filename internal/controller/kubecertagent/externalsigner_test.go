@@ -0,0 +1,325 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	conciergefake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
+	conciergeinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority/externalsigner"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+type recordingExternalSigner struct {
+	externalsigner.Dynamic
+	signer    externalsigner.Signer
+	setCalled bool
+}
+
+func (r *recordingExternalSigner) Set(signer externalsigner.Signer) {
+	r.signer = signer
+	r.setCalled = true
+}
+
+func TestExternalSignerController(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2026, 4, 13, 9, 57, 0, 0, time.UTC)
+
+	credentialIssuer := func(spec *configv1alpha1.ExternalSignerSpec, strategies ...configv1alpha1.CredentialIssuerStrategy) *configv1alpha1.CredentialIssuer {
+		return &configv1alpha1.CredentialIssuer{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinniped-concierge-config"},
+			Spec:       configv1alpha1.CredentialIssuerSpec{ExternalSigner: spec},
+			Status:     configv1alpha1.CredentialIssuerStatus{Strategies: strategies},
+		}
+	}
+
+	validClusterInfoConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+		Data: map[string]string{"kubeconfig": here.Docf(`
+			kind: Config
+			apiVersion: v1
+			clusters:
+			- name: ""
+			  cluster:
+				certificate-authority-data: dGVzdC1rdWJlcm5ldGVzLWNh # "test-kubernetes-ca"
+				server: https://test-kubernetes-endpoint.example.com
+			`),
+		},
+	}
+
+	csrSpec := &configv1alpha1.ExternalSignerSpec{
+		CertificateSigningRequest: &configv1alpha1.CertificateSigningRequestSignerSpec{SignerName: "example.com/some-signer"},
+	}
+
+	tests := []struct {
+		name                 string
+		discoveryURLOverride *string
+		pinnipedObjects      []runtime.Object
+		kubeObjects          []runtime.Object
+		wantErr              string
+		wantSetCalled        bool
+		wantSignerName       string
+		wantStrategy         *configv1alpha1.CredentialIssuerStrategy
+		wantNoStrategies     bool
+	}{
+		{
+			name:    "missing CredentialIssuer",
+			wantErr: `could not get CredentialIssuer to update: credentialissuer.config.concierge.pinniped.dev "pinniped-concierge-config" not found`,
+		},
+		{
+			name:             "not configured and never configured before",
+			pinnipedObjects:  []runtime.Object{credentialIssuer(nil)},
+			wantSetCalled:    true,
+			wantNoStrategies: true,
+		},
+		{
+			name: "not configured anymore",
+			pinnipedObjects: []runtime.Object{credentialIssuer(nil, configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.SuccessStrategyStatus,
+				Reason:         configv1alpha1.SignerConfiguredStrategyReason,
+				Message:        "client certificates are issued by csr:example.com/some-signer",
+				LastUpdateTime: metav1.NewTime(now.Add(-time.Hour)),
+			})},
+			wantSetCalled: true,
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.DisabledStrategyReason,
+				Message:        "external signer is not configured",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "neither signer configured",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{})},
+			kubeObjects:     []runtime.Object{validClusterInfoConfigMap},
+			wantErr:         "one of certificateSigningRequest or certManager must be configured",
+			wantSetCalled:   true,
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.InvalidSignerConfigStrategyReason,
+				Message:        "one of certificateSigningRequest or certManager must be configured",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name: "both signers configured",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{
+				CertificateSigningRequest: csrSpec.CertificateSigningRequest,
+				CertManager: &configv1alpha1.CertManagerSignerSpec{
+					IssuerRef: configv1alpha1.CertManagerIssuerReference{Name: "some-issuer"},
+				},
+			})},
+			kubeObjects:   []runtime.Object{validClusterInfoConfigMap},
+			wantErr:       "only one of certificateSigningRequest or certManager may be configured",
+			wantSetCalled: true,
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.InvalidSignerConfigStrategyReason,
+				Message:        "only one of certificateSigningRequest or certManager may be configured",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name: "empty signer name",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{
+				CertificateSigningRequest: &configv1alpha1.CertificateSigningRequestSignerSpec{},
+			})},
+			kubeObjects:   []runtime.Object{validClusterInfoConfigMap},
+			wantErr:       "certificateSigningRequest.signerName must not be empty",
+			wantSetCalled: true,
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.InvalidSignerConfigStrategyReason,
+				Message:        "certificateSigningRequest.signerName must not be empty",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name: "empty issuer name",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{
+				CertManager: &configv1alpha1.CertManagerSignerSpec{},
+			})},
+			kubeObjects:   []runtime.Object{validClusterInfoConfigMap},
+			wantErr:       "certManager.issuerRef.name must not be empty",
+			wantSetCalled: true,
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.InvalidSignerConfigStrategyReason,
+				Message:        "certManager.issuerRef.name must not be empty",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "missing cluster-info",
+			pinnipedObjects: []runtime.Object{credentialIssuer(csrSpec)},
+			wantErr:         `failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`,
+			wantSetCalled:   true,
+			wantSignerName:  "csr:example.com/some-signer",
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        `failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`,
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "invalid cluster-info",
+			pinnipedObjects: []runtime.Object{credentialIssuer(csrSpec)},
+			kubeObjects: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+				Data:       map[string]string{},
+			}},
+			wantErr:        `could not extract Kubernetes API endpoint info from kube-public/cluster-info configmap: missing "kubeconfig" key`,
+			wantSetCalled:  true,
+			wantSignerName: "csr:example.com/some-signer",
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.ErrorStrategyStatus,
+				Reason:         configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        `could not extract Kubernetes API endpoint info from kube-public/cluster-info configmap: missing "kubeconfig" key`,
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "certificate signing request signer",
+			pinnipedObjects: []runtime.Object{credentialIssuer(csrSpec)},
+			kubeObjects:     []runtime.Object{validClusterInfoConfigMap},
+			wantSetCalled:   true,
+			wantSignerName:  "csr:example.com/some-signer",
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.SuccessStrategyStatus,
+				Reason:         configv1alpha1.SignerConfiguredStrategyReason,
+				Message:        "client certificates are issued by csr:example.com/some-signer",
+				LastUpdateTime: metav1.NewTime(now),
+				Frontend: &configv1alpha1.CredentialIssuerFrontend{
+					Type: configv1alpha1.TokenCredentialRequestAPIFrontendType,
+					TokenCredentialRequestAPIInfo: &configv1alpha1.TokenCredentialRequestAPIInfo{
+						Server:                   "https://test-kubernetes-endpoint.example.com",
+						CertificateAuthorityData: "dGVzdC1rdWJlcm5ldGVzLWNh",
+					},
+				},
+			},
+		},
+		{
+			name: "cert-manager signer with defaulted kind and group and a discovery URL override",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{
+				CertManager: &configv1alpha1.CertManagerSignerSpec{
+					IssuerRef: configv1alpha1.CertManagerIssuerReference{Name: "some-issuer"},
+				},
+			})},
+			kubeObjects:          []runtime.Object{validClusterInfoConfigMap},
+			discoveryURLOverride: ptr.To("https://overridden-server.example.com/some/path"),
+			wantSetCalled:        true,
+			wantSignerName:       "cert-manager:Issuer.cert-manager.io/some-issuer",
+			wantStrategy: &configv1alpha1.CredentialIssuerStrategy{
+				Type:           configv1alpha1.ExternalSignerStrategyType,
+				Status:         configv1alpha1.SuccessStrategyStatus,
+				Reason:         configv1alpha1.SignerConfiguredStrategyReason,
+				Message:        "client certificates are issued by cert-manager:Issuer.cert-manager.io/some-issuer",
+				LastUpdateTime: metav1.NewTime(now),
+				Frontend: &configv1alpha1.CredentialIssuerFrontend{
+					Type: configv1alpha1.TokenCredentialRequestAPIFrontendType,
+					TokenCredentialRequestAPIInfo: &configv1alpha1.TokenCredentialRequestAPIInfo{
+						Server:                   "https://overridden-server.example.com/some/path",
+						CertificateAuthorityData: "dGVzdC1rdWJlcm5ldGVzLWNh",
+					},
+				},
+			},
+		},
+		{
+			name: "cert-manager signer with a ClusterIssuer",
+			pinnipedObjects: []runtime.Object{credentialIssuer(&configv1alpha1.ExternalSignerSpec{
+				CertManager: &configv1alpha1.CertManagerSignerSpec{
+					IssuerRef: configv1alpha1.CertManagerIssuerReference{Name: "some-issuer", Kind: "ClusterIssuer", Group: "example.com"},
+				},
+			})},
+			kubeObjects:    []runtime.Object{validClusterInfoConfigMap},
+			wantSetCalled:  true,
+			wantSignerName: "cert-manager:ClusterIssuer.example.com/some-issuer",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			conciergeClientset := conciergefake.NewSimpleClientset(tt.pinnipedObjects...)
+			conciergeInformers := conciergeinformers.NewSharedInformerFactory(conciergeClientset, 0)
+			kubeClientset := kubefake.NewSimpleClientset(tt.kubeObjects...)
+			kubeInformers := informers.NewSharedInformerFactory(kubeClientset, 0)
+
+			signer := &recordingExternalSigner{}
+			controller := newExternalSignerController(
+				AgentConfig{
+					Namespace:            "concierge",
+					CredentialIssuerName: "pinniped-concierge-config",
+					DiscoveryURLOverride: tt.discoveryURLOverride,
+				},
+				&kubeclient.Client{Kubernetes: kubeClientset, PinnipedConcierge: conciergeClientset},
+				kubefake.NewSimpleClientset(),
+				dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+				kubeInformers.Core().V1().ConfigMaps(),
+				conciergeInformers.Config().V1alpha1().CredentialIssuers(),
+				signer,
+				clocktesting.NewFakeClock(now),
+			)
+
+			kubeInformers.Start(ctx.Done())
+			conciergeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			err := controllerlib.TestSync(t, controller, controllerlib.Context{Context: ctx})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantSetCalled, signer.setCalled)
+			if tt.wantSignerName == "" {
+				require.Nil(t, signer.signer)
+			} else {
+				require.NotNil(t, signer.signer)
+				require.Equal(t, tt.wantSignerName, signer.signer.Name())
+			}
+
+			if tt.wantStrategy != nil || tt.wantNoStrategies {
+				credIssuer, err := conciergeClientset.ConfigV1alpha1().CredentialIssuers().Get(ctx, "pinniped-concierge-config", metav1.GetOptions{})
+				require.NoError(t, err)
+				if tt.wantNoStrategies {
+					require.Empty(t, credIssuer.Status.Strategies)
+				} else {
+					require.Equal(t, []configv1alpha1.CredentialIssuerStrategy{*tt.wantStrategy}, credIssuer.Status.Strategies)
+				}
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package kubecertagent provides controllers that ensure a pod (the kube-cert-agent), is
@@ -312,7 +312,7 @@ func (c *agentController) Sync(ctx controllerlib.Context) error {
 		return c.failStrategyAndErr(ctx.Context, credIssuer, firstErr(depErr, err), configv1alpha1.CouldNotGetClusterInfoStrategyReason)
 	}
 
-	apiInfo, err := c.extractAPIInfo(configMap)
+	apiInfo, err := extractAPIInfo(configMap, c.cfg.DiscoveryURLOverride)
 	if err != nil {
 		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
 		return c.failStrategyAndErr(ctx.Context, credIssuer, firstErr(depErr, err), configv1alpha1.CouldNotGetClusterInfoStrategyReason)
@@ -458,7 +458,8 @@ func (c *agentController) failStrategyAndErr(ctx context.Context, credIssuer *co
 	return utilerrors.NewAggregate([]error{err, updateErr})
 }
 
-func (c *agentController) extractAPIInfo(configMap *corev1.ConfigMap) (*configv1alpha1.TokenCredentialRequestAPIInfo, error) {
+// extractAPIInfo reads the Kubernetes API endpoint info from the kube-public/cluster-info ConfigMap.
+func extractAPIInfo(configMap *corev1.ConfigMap, discoveryURLOverride *string) (*configv1alpha1.TokenCredentialRequestAPIInfo, error) {
 	kubeConfigYAML, kubeConfigPresent := configMap.Data[clusterInfoConfigMapKey]
 	if !kubeConfigPresent {
 		return nil, fmt.Errorf("missing %q key", clusterInfoConfigMapKey)
@@ -475,8 +476,8 @@ func (c *agentController) extractAPIInfo(configMap *corev1.ConfigMap) (*configv1
 			Server:                   v.Server,
 			CertificateAuthorityData: base64.StdEncoding.EncodeToString(v.CertificateAuthorityData),
 		}
-		if c.cfg.DiscoveryURLOverride != nil {
-			result.Server = *c.cfg.DiscoveryURLOverride
+		if discoveryURLOverride != nil {
+			result.Server = *discoveryURLOverride
 		}
 		return result, nil
 	}
@@ -0,0 +1,584 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	configv1alpha1informers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions/config/v1alpha1"
+	"go.pinniped.dev/internal/certauthority"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controller/issuerconfig"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/dynamiccert"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+const (
+	// ExtensionAPIServerAuthenticationNamespace and ExtensionAPIServerAuthenticationName identify the ConfigMap in
+	// which the Kubernetes API server publishes the contents of its --client-ca-file.
+	ExtensionAPIServerAuthenticationNamespace = "kube-system"
+	ExtensionAPIServerAuthenticationName      = "extension-apiserver-authentication"
+	extensionAPIServerAuthenticationClientCA  = "client-ca-file"
+
+	// ManagedClientCABundleConfigMapKey is the key of the ConfigMap in which the managed client CA bundle is published.
+	ManagedClientCABundleConfigMapKey = "ca.crt"
+
+	// The current CA is saved using the same Secret keys as the CAs generated by the apicerts controllers.
+	managedCANextCertificateSecretKey           = "nextCACertificate"
+	managedCANextCertificatePrivateKeySecretKey = "nextCACertificatePrivateKey"
+	managedCAPreviousCertificateSecretKey       = "previousCACertificate"
+
+	managedCACommonName             = "Pinniped Concierge Managed Client CA"
+	defaultManagedCADuration        = 365 * 24 * time.Hour
+	defaultManagedCARotationOverlap = 30 * 24 * time.Hour
+)
+
+// ManagedCAConfig is the configuration for the Concierge-managed client CA controller.
+type ManagedCAConfig struct {
+	// SecretName is the name of the Secret in the Concierge namespace which holds the managed client CA.
+	SecretName string
+
+	// BundleConfigMapName is the name of the ConfigMap in the Concierge namespace in which the CA bundle is published.
+	BundleConfigMapName string
+}
+
+type managedCAController struct {
+	cfg                              AgentConfig
+	managedCAConfig                  ManagedCAConfig
+	client                           *kubeclient.Client
+	secrets                          corev1informers.SecretInformer
+	configMaps                       corev1informers.ConfigMapInformer
+	kubePublicConfigMaps             corev1informers.ConfigMapInformer
+	extensionAPIServerAuthentication corev1informers.ConfigMapInformer
+	credentialIssuers                configv1alpha1informers.CredentialIssuerInformer
+	dynamicCertProvider              dynamiccert.Private
+	clock                            clock.Clock
+}
+
+// NewManagedCAController returns a controller that generates and rotates the Concierge-managed client CA when the
+// spec.managedClientCA of the CredentialIssuer is set, publishes the bundle of the current, next, and previous CAs,
+// loads the current CA into the dynamicCertProvider once the Kubernetes API server trusts it, and reports the status
+// of the ConciergeManagedCA strategy.
+func NewManagedCAController(
+	cfg AgentConfig,
+	managedCAConfig ManagedCAConfig,
+	client *kubeclient.Client,
+	secrets corev1informers.SecretInformer,
+	configMaps corev1informers.ConfigMapInformer,
+	kubePublicConfigMaps corev1informers.ConfigMapInformer,
+	extensionAPIServerAuthentication corev1informers.ConfigMapInformer,
+	credentialIssuers configv1alpha1informers.CredentialIssuerInformer,
+	dynamicCertProvider dynamiccert.Private,
+) controllerlib.Controller {
+	return newManagedCAController(
+		cfg,
+		managedCAConfig,
+		client,
+		secrets,
+		configMaps,
+		kubePublicConfigMaps,
+		extensionAPIServerAuthentication,
+		credentialIssuers,
+		dynamicCertProvider,
+		&clock.RealClock{},
+	)
+}
+
+func newManagedCAController(
+	cfg AgentConfig,
+	managedCAConfig ManagedCAConfig,
+	client *kubeclient.Client,
+	secrets corev1informers.SecretInformer,
+	configMaps corev1informers.ConfigMapInformer,
+	kubePublicConfigMaps corev1informers.ConfigMapInformer,
+	extensionAPIServerAuthentication corev1informers.ConfigMapInformer,
+	credentialIssuers configv1alpha1informers.CredentialIssuerInformer,
+	dynamicCertProvider dynamiccert.Private,
+	clock clock.Clock,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "managed-client-ca-controller",
+			Syncer: &managedCAController{
+				cfg:                              cfg,
+				managedCAConfig:                  managedCAConfig,
+				client:                           client,
+				secrets:                          secrets,
+				configMaps:                       configMaps,
+				kubePublicConfigMaps:             kubePublicConfigMaps,
+				extensionAPIServerAuthentication: extensionAPIServerAuthentication,
+				credentialIssuers:                credentialIssuers,
+				dynamicCertProvider:              dynamicCertProvider,
+				clock:                            clock,
+			},
+		},
+		controllerlib.WithInformer(
+			secrets,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == cfg.Namespace && obj.GetName() == managedCAConfig.SecretName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			configMaps,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == cfg.Namespace && obj.GetName() == managedCAConfig.BundleConfigMapName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			kubePublicConfigMaps,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == ClusterInfoNamespace && obj.GetName() == clusterInfoName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			extensionAPIServerAuthentication,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == ExtensionAPIServerAuthenticationNamespace && obj.GetName() == ExtensionAPIServerAuthenticationName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			credentialIssuers,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetName() == cfg.CredentialIssuerName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInitialEvent(controllerlib.Key{}),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *managedCAController) Sync(ctx controllerlib.Context) error {
+	credIssuer, err := c.credentialIssuers.Lister().Get(c.cfg.CredentialIssuerName)
+	if err != nil {
+		return fmt.Errorf("could not get CredentialIssuer to update: %w", err)
+	}
+
+	spec := credIssuer.Spec.ManagedClientCA
+	if spec == nil {
+		c.dynamicCertProvider.UnsetCertKeyContent()
+
+		// Only report this strategy on clusters where it was previously configured, to avoid cluttering the
+		// status of every CredentialIssuer with a strategy that most clusters do not use.
+		if !hasStrategy(credIssuer, configv1alpha1.ConciergeManagedCAStrategyType) {
+			return nil
+		}
+		return c.updateStrategy(ctx.Context, credIssuer, configv1alpha1.ErrorStrategyStatus,
+			configv1alpha1.DisabledStrategyReason, "managed client CA is not configured", nil)
+	}
+
+	duration, overlap, err := managedCADurations(spec)
+	if err != nil {
+		c.dynamicCertProvider.UnsetCertKeyContent()
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+
+	state, err := c.loadState()
+	if err != nil {
+		c.dynamicCertProvider.UnsetCertKeyContent()
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+
+	trustedCAs, err := c.trustedClientCAs()
+	if err != nil {
+		c.dynamicCertProvider.UnsetCertKeyContent()
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+
+	now := c.clock.Now()
+
+	// Generate and rotate the CA. Saving fails on pods which are not the leader, in which case keep using the
+	// saved CAs until the leader has saved the rotated CAs.
+	rotated, changed, err := rotateManagedCA(state, now, duration, overlap, trustedCAs.trusts)
+	if err != nil {
+		c.dynamicCertProvider.UnsetCertKeyContent()
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+	var saveErr error
+	if changed {
+		if saveErr = c.saveState(ctx.Context, rotated); saveErr == nil {
+			state = rotated
+		}
+	}
+
+	// Publish the CA bundle so that cluster admins can configure the Kubernetes API server to trust it.
+	// It is fine if this fails on pods which are not the leader, as long as the leader publishes it, so keep going.
+	var publishErr error
+	if state.current != nil {
+		publishErr = c.publishBundle(ctx.Context, state.bundle())
+	}
+
+	if state.current == nil || !trustedCAs.trusts(state.current.cert) || !now.Before(state.current.cert.NotAfter) {
+		// Client certificates signed by an untrusted CA would be rejected by the Kubernetes API server, so stop
+		// issuing them to allow the next certificate issuer to be used instead.
+		c.dynamicCertProvider.UnsetCertKeyContent()
+		if err := utilerrors.NewAggregate([]error{saveErr, publishErr}); err != nil {
+			return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+		}
+		return c.updateStrategy(ctx.Context, credIssuer, configv1alpha1.ErrorStrategyStatus,
+			configv1alpha1.ClientCANotTrustedStrategyReason,
+			fmt.Sprintf("the Kubernetes API server does not trust the managed client CA: add the %q key of the %s/%s configmap to its --client-ca-file",
+				ManagedClientCABundleConfigMapKey, c.cfg.Namespace, c.managedCAConfig.BundleConfigMapName),
+			nil)
+	}
+
+	// Loading the current CA on every sync is how rotation takes effect, because the client certificate issuer
+	// always signs with the latest content of the dynamicCertProvider.
+	if err := c.dynamicCertProvider.SetCertKeyContent(state.current.certPEM, state.current.keyPEM); err != nil {
+		err := fmt.Errorf("could not load the managed client CA from secret %s/%s: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+
+	if err := utilerrors.NewAggregate([]error{saveErr, publishErr}); err != nil {
+		// The CA is trusted and loaded, but keep retrying until the saved CAs and the published bundle are up-to-date.
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+
+	// Load the Kubernetes API info from the kube-public/cluster-info ConfigMap.
+	configMap, err := c.kubePublicConfigMaps.Lister().ConfigMaps(ClusterInfoNamespace).Get(clusterInfoName)
+	if err != nil {
+		err := fmt.Errorf("failed to get %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	apiInfo, err := extractAPIInfo(configMap, c.cfg.DiscoveryURLOverride)
+	if err != nil {
+		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, configv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	message := "the Kubernetes API server trusts the managed client CA"
+	if state.next != nil && !trustedCAs.trusts(state.next.cert) {
+		message = fmt.Sprintf("the Kubernetes API server trusts the managed client CA, but not the next CA which must replace it before %s: add the %q key of the %s/%s configmap to its --client-ca-file",
+			state.current.cert.NotAfter.UTC().Format(time.RFC3339), ManagedClientCABundleConfigMapKey, c.cfg.Namespace, c.managedCAConfig.BundleConfigMapName)
+	}
+
+	return c.updateStrategy(ctx.Context, credIssuer, configv1alpha1.SuccessStrategyStatus,
+		configv1alpha1.ClientCATrustedStrategyReason, message,
+		&configv1alpha1.CredentialIssuerFrontend{
+			Type:                          configv1alpha1.TokenCredentialRequestAPIFrontendType,
+			TokenCredentialRequestAPIInfo: apiInfo,
+		})
+}
+
+func managedCADurations(spec *configv1alpha1.ManagedClientCASpec) (time.Duration, time.Duration, error) {
+	duration := defaultManagedCADuration
+	if spec.DurationSeconds != nil {
+		duration = time.Duration(*spec.DurationSeconds) * time.Second
+	}
+	overlap := defaultManagedCARotationOverlap
+	if spec.RotationOverlapSeconds != nil {
+		overlap = time.Duration(*spec.RotationOverlapSeconds) * time.Second
+	}
+	if overlap >= duration {
+		return 0, 0, fmt.Errorf("managedClientCA.rotationOverlapSeconds (%d) must be less than managedClientCA.durationSeconds (%d)",
+			int64(overlap.Seconds()), int64(duration.Seconds()))
+	}
+	return duration, overlap, nil
+}
+
+// rotateManagedCA returns the desired state of the managed client CA at the given time, and whether it differs
+// from the given state. The given state is not modified.
+//
+// The next CA is generated when the current CA enters its overlap window, so that both can be published together.
+// The next CA replaces the current CA once the Kubernetes API server trusts it, and the replaced CA stays in the
+// published bundle until it expires, so that the bundle always contains every CA which may have signed a client
+// certificate which is still valid.
+func rotateManagedCA(
+	state *managedCAState,
+	now time.Time,
+	duration, overlap time.Duration,
+	isTrusted func(*x509.Certificate) bool,
+) (*managedCAState, bool, error) {
+	desired := *state
+	changed := false
+
+	if desired.current == nil {
+		current, err := newManagedCA(duration)
+		if err != nil {
+			return nil, false, err
+		}
+		desired.current = current
+		changed = true
+	}
+
+	if desired.next == nil && !now.Before(desired.current.cert.NotAfter.Add(-overlap)) {
+		next, err := newManagedCA(duration)
+		if err != nil {
+			return nil, false, err
+		}
+		desired.next = next
+		changed = true
+	}
+
+	if desired.next != nil && isTrusted(desired.next.cert) {
+		desired.previous = &managedCA{certPEM: desired.current.certPEM, cert: desired.current.cert}
+		desired.current, desired.next = desired.next, nil
+		changed = true
+	}
+
+	if desired.previous != nil && !now.Before(desired.previous.cert.NotAfter) {
+		desired.previous = nil
+		changed = true
+	}
+
+	return &desired, changed, nil
+}
+
+func (c *managedCAController) publishBundle(ctx context.Context, caPEM []byte) error {
+	existing, err := c.configMaps.Lister().ConfigMaps(c.cfg.Namespace).Get(c.managedCAConfig.BundleConfigMapName)
+	notFound := k8serrors.IsNotFound(err)
+	if err != nil && !notFound {
+		return fmt.Errorf("failed to get %s/%s configmap: %w", c.cfg.Namespace, c.managedCAConfig.BundleConfigMapName, err)
+	}
+
+	if notFound {
+		_, err := c.client.Kubernetes.CoreV1().ConfigMaps(c.cfg.Namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.managedCAConfig.BundleConfigMapName,
+				Namespace: c.cfg.Namespace,
+				Labels:    c.cfg.Labels,
+			},
+			Data: map[string]string{ManagedClientCABundleConfigMapKey: string(caPEM)},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create %s/%s configmap: %w", c.cfg.Namespace, c.managedCAConfig.BundleConfigMapName, err)
+		}
+		return nil
+	}
+
+	if existing.Data[ManagedClientCABundleConfigMapKey] == string(caPEM) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	if updated.Data == nil {
+		updated.Data = map[string]string{}
+	}
+	updated.Data[ManagedClientCABundleConfigMapKey] = string(caPEM)
+	if _, err := c.client.Kubernetes.CoreV1().ConfigMaps(c.cfg.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update %s/%s configmap: %w", c.cfg.Namespace, c.managedCAConfig.BundleConfigMapName, err)
+	}
+	return nil
+}
+
+// clientCAs is the set of raw certificates in the client CA bundle of the Kubernetes API server.
+type clientCAs map[string]struct{}
+
+func (c clientCAs) trusts(cert *x509.Certificate) bool {
+	_, ok := c[string(cert.Raw)]
+	return ok
+}
+
+// trustedClientCAs returns the certificates which are part of the client CA bundle of the Kubernetes API server.
+func (c *managedCAController) trustedClientCAs() (clientCAs, error) {
+	trusted := clientCAs{}
+
+	configMap, err := c.extensionAPIServerAuthentication.Lister().ConfigMaps(ExtensionAPIServerAuthenticationNamespace).Get(ExtensionAPIServerAuthenticationName)
+	if k8serrors.IsNotFound(err) {
+		return trusted, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s/%s configmap: %w", ExtensionAPIServerAuthenticationNamespace, ExtensionAPIServerAuthenticationName, err)
+	}
+
+	rest := []byte(configMap.Data[extensionAPIServerAuthenticationClientCA])
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return trusted, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		// Compare the parsed certificates rather than the PEM text, which may be formatted differently.
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		trusted[string(cert.Raw)] = struct{}{}
+	}
+}
+
+// managedCA is one generation of the managed client CA. The private key is not kept for the previous CA.
+type managedCA struct {
+	certPEM []byte
+	keyPEM  []byte
+	cert    *x509.Certificate
+}
+
+// managedCAState is the set of managed client CAs which is saved in the Secret.
+type managedCAState struct {
+	// current is the CA which signs client certificates.
+	current *managedCA
+	// next is the CA which will replace current once the Kubernetes API server trusts it.
+	next *managedCA
+	// previous is the CA which was replaced by current, until it expires.
+	previous *managedCA
+}
+
+// bundle returns the PEM encoded certificates of all CAs, starting with the current CA.
+func (s *managedCAState) bundle() []byte {
+	var bundle []byte
+	for _, ca := range []*managedCA{s.current, s.next, s.previous} {
+		if ca != nil {
+			bundle = append(bundle, ca.certPEM...)
+		}
+	}
+	return bundle
+}
+
+func newManagedCA(duration time.Duration) (*managedCA, error) {
+	ca, err := certauthority.New(managedCACommonName, duration)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate managed client CA: %w", err)
+	}
+	keyPEM, err := ca.PrivateKeyToPEM()
+	if err != nil {
+		return nil, fmt.Errorf("could not encode managed client CA private key: %w", err)
+	}
+	return parseManagedCA(ca.Bundle(), keyPEM)
+}
+
+func parseManagedCA(certPEM, keyPEM []byte) (*managedCA, error) {
+	if keyPEM != nil {
+		if _, err := certauthority.Load(string(certPEM), string(keyPEM)); err != nil {
+			return nil, err
+		}
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("does not contain a PEM encoded CA certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &managedCA{certPEM: certPEM, keyPEM: keyPEM, cert: cert}, nil
+}
+
+func (c *managedCAController) loadState() (*managedCAState, error) {
+	secret, err := c.secrets.Lister().Secrets(c.cfg.Namespace).Get(c.managedCAConfig.SecretName)
+	if k8serrors.IsNotFound(err) {
+		return &managedCAState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s/%s secret: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, err)
+	}
+
+	var state managedCAState
+	for _, ca := range []struct {
+		certKey, keyKey string
+		into            **managedCA
+	}{
+		{certKey: apicerts.CACertificateSecretKey, keyKey: apicerts.CACertificatePrivateKeySecretKey, into: &state.current},
+		{certKey: managedCANextCertificateSecretKey, keyKey: managedCANextCertificatePrivateKeySecretKey, into: &state.next},
+		{certKey: managedCAPreviousCertificateSecretKey, into: &state.previous},
+	} {
+		certPEM := secret.Data[ca.certKey]
+		if len(certPEM) == 0 {
+			continue
+		}
+		var keyPEM []byte
+		if ca.keyKey != "" {
+			keyPEM = secret.Data[ca.keyKey]
+			if len(keyPEM) == 0 {
+				return nil, fmt.Errorf("secret %s/%s has %q without %q", c.cfg.Namespace, c.managedCAConfig.SecretName, ca.certKey, ca.keyKey)
+			}
+		}
+		parsed, err := parseManagedCA(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s has an invalid %q: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, ca.certKey, err)
+		}
+		*ca.into = parsed
+	}
+
+	if state.current == nil && (state.next != nil || state.previous != nil) {
+		return nil, fmt.Errorf("secret %s/%s does not have %q", c.cfg.Namespace, c.managedCAConfig.SecretName, apicerts.CACertificateSecretKey)
+	}
+
+	return &state, nil
+}
+
+func (c *managedCAController) saveState(ctx context.Context, state *managedCAState) error {
+	data := map[string][]byte{
+		apicerts.CACertificateSecretKey:           state.current.certPEM,
+		apicerts.CACertificatePrivateKeySecretKey: state.current.keyPEM,
+	}
+	if state.next != nil {
+		data[managedCANextCertificateSecretKey] = state.next.certPEM
+		data[managedCANextCertificatePrivateKeySecretKey] = state.next.keyPEM
+	}
+	if state.previous != nil {
+		data[managedCAPreviousCertificateSecretKey] = state.previous.certPEM
+	}
+
+	existing, err := c.secrets.Lister().Secrets(c.cfg.Namespace).Get(c.managedCAConfig.SecretName)
+	if k8serrors.IsNotFound(err) {
+		_, err := c.client.Kubernetes.CoreV1().Secrets(c.cfg.Namespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.managedCAConfig.SecretName,
+				Namespace: c.cfg.Namespace,
+				Labels:    c.cfg.Labels,
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("could not create %s/%s secret: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get %s/%s secret: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, err)
+	}
+
+	updated := existing.DeepCopy()
+	updated.Data = data
+	if _, err := c.client.Kubernetes.CoreV1().Secrets(c.cfg.Namespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not update %s/%s secret: %w", c.cfg.Namespace, c.managedCAConfig.SecretName, err)
+	}
+	return nil
+}
+
+func (c *managedCAController) updateStrategy(
+	ctx context.Context,
+	credIssuer *configv1alpha1.CredentialIssuer,
+	status configv1alpha1.StrategyStatus,
+	reason configv1alpha1.StrategyReason,
+	message string,
+	frontend *configv1alpha1.CredentialIssuerFrontend,
+) error {
+	return issuerconfig.Update(ctx, c.client.PinnipedConcierge, credIssuer, configv1alpha1.CredentialIssuerStrategy{
+		Type:           configv1alpha1.ConciergeManagedCAStrategyType,
+		Status:         status,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.NewTime(c.clock.Now()),
+		Frontend:       frontend,
+	})
+}
+
+func (c *managedCAController) failStrategyAndErr(ctx context.Context, credIssuer *configv1alpha1.CredentialIssuer, err error, reason configv1alpha1.StrategyReason) error {
+	updateErr := c.updateStrategy(ctx, credIssuer, configv1alpha1.ErrorStrategyStatus, reason, err.Error(), nil)
+	return utilerrors.NewAggregate([]error{err, updateErr})
+}
@@ -0,0 +1,636 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package kubecertagent
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	conciergefake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
+	conciergeinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/dynamiccert"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+func TestManagedCAController(t *testing.T) {
+	t.Parallel()
+	now := time.Now().Truncate(time.Second)
+	const year = 365 * 24 * time.Hour
+
+	newCA := func(t *testing.T, ttl time.Duration) ([]byte, []byte) {
+		t.Helper()
+		ca, err := certauthority.New("Pinniped Concierge Client CA", ttl)
+		require.NoError(t, err)
+		keyPEM, err := ca.PrivateKeyToPEM()
+		require.NoError(t, err)
+		return ca.Bundle(), keyPEM
+	}
+	caPEM, caKeyPEM := newCA(t, year)
+	otherCAPEM, otherCAKeyPEM := newCA(t, year)
+	expiredCAPEM, _ := newCA(t, time.Hour)
+
+	caNotAfter := func() time.Time {
+		block, _ := pem.Decode(caPEM)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		require.NoError(t, err)
+		return cert.NotAfter
+	}()
+
+	// Rotation starts 30 days before the CA expires by default.
+	inOverlapWindow := year - 29*24*time.Hour
+
+	unconfiguredCredentialIssuer := &configv1alpha1.CredentialIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "pinniped-concierge-config"},
+	}
+	credentialIssuer := &configv1alpha1.CredentialIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "pinniped-concierge-config"},
+		Spec:       configv1alpha1.CredentialIssuerSpec{ManagedClientCA: &configv1alpha1.ManagedClientCASpec{}},
+	}
+
+	caSecretMeta := metav1.ObjectMeta{Namespace: "concierge", Name: "managed-client-ca-certificate"}
+	caSecret := &corev1.Secret{
+		ObjectMeta: caSecretMeta,
+		Data: map[string][]byte{
+			apicerts.CACertificateSecretKey:           caPEM,
+			apicerts.CACertificatePrivateKeySecretKey: caKeyPEM,
+		},
+	}
+
+	bundleConfigMap := func(bundle []byte) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "concierge",
+				Name:      "managed-client-ca-bundle",
+				Labels:    map[string]string{"app": "concierge"},
+			},
+			Data: map[string]string{"ca.crt": string(bundle)},
+		}
+	}
+
+	extensionAPIServerAuthentication := func(bundles ...[]byte) *corev1.ConfigMap {
+		var clientCA string
+		for _, bundle := range bundles {
+			clientCA += string(bundle)
+		}
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "extension-apiserver-authentication"},
+			Data:       map[string]string{"client-ca-file": clientCA},
+		}
+	}
+
+	validClusterInfoConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+		Data: map[string]string{"kubeconfig": here.Docf(`
+			kind: Config
+			apiVersion: v1
+			clusters:
+			- name: ""
+			  cluster:
+				certificate-authority-data: dGVzdC1rdWJlcm5ldGVzLWNh # "test-kubernetes-ca"
+				server: https://test-kubernetes-endpoint.example.com
+			`),
+		},
+	}
+
+	notTrustedStrategy := func(now time.Time) *configv1alpha1.CredentialIssuerStrategy {
+		return &configv1alpha1.CredentialIssuerStrategy{
+			Type:           configv1alpha1.ConciergeManagedCAStrategyType,
+			Status:         configv1alpha1.ErrorStrategyStatus,
+			Reason:         configv1alpha1.ClientCANotTrustedStrategyReason,
+			Message:        `the Kubernetes API server does not trust the managed client CA: add the "ca.crt" key of the concierge/managed-client-ca-bundle configmap to its --client-ca-file`,
+			LastUpdateTime: metav1.NewTime(now),
+		}
+	}
+
+	trustedStrategy := func(now time.Time, message string) *configv1alpha1.CredentialIssuerStrategy {
+		return &configv1alpha1.CredentialIssuerStrategy{
+			Type:           configv1alpha1.ConciergeManagedCAStrategyType,
+			Status:         configv1alpha1.SuccessStrategyStatus,
+			Reason:         configv1alpha1.ClientCATrustedStrategyReason,
+			Message:        message,
+			LastUpdateTime: metav1.NewTime(now),
+			Frontend: &configv1alpha1.CredentialIssuerFrontend{
+				Type: configv1alpha1.TokenCredentialRequestAPIFrontendType,
+				TokenCredentialRequestAPIInfo: &configv1alpha1.TokenCredentialRequestAPIInfo{
+					Server:                   "https://test-kubernetes-endpoint.example.com",
+					CertificateAuthorityData: "dGVzdC1rdWJlcm5ldGVzLWNh",
+				},
+			},
+		}
+	}
+
+	errorStrategy := func(now time.Time, reason configv1alpha1.StrategyReason, message string) *configv1alpha1.CredentialIssuerStrategy {
+		return &configv1alpha1.CredentialIssuerStrategy{
+			Type:           configv1alpha1.ConciergeManagedCAStrategyType,
+			Status:         configv1alpha1.ErrorStrategyStatus,
+			Reason:         reason,
+			Message:        message,
+			LastUpdateTime: metav1.NewTime(now),
+		}
+	}
+
+	tests := []struct {
+		name                   string
+		clockOffset            time.Duration
+		pinnipedObjects        []runtime.Object
+		kubeObjects            []runtime.Object
+		addKubeReactions       func(*kubefake.Clientset)
+		wantErr                string
+		wantLoadedCA           []byte
+		wantLoadedKey          []byte
+		wantNoCredentialIssuer bool
+		wantSecretActions      []string
+		wantSecretData         map[string][]byte
+		wantGeneratedCurrent   bool
+		wantGeneratedNext      bool
+		wantConfigMapActions   []string
+		wantPublishedBundle    []byte
+		wantStrategies         func(now time.Time) []configv1alpha1.CredentialIssuerStrategy
+	}{
+		{
+			name:                   "missing CredentialIssuer",
+			wantErr:                `could not get CredentialIssuer to update: credentialissuer.config.concierge.pinniped.dev "pinniped-concierge-config" not found`,
+			wantLoadedCA:           otherCAPEM,
+			wantLoadedKey:          otherCAKeyPEM,
+			wantNoCredentialIssuer: true,
+		},
+		{
+			name:            "not configured, so nothing is generated or published and the strategy is not reported",
+			pinnipedObjects: []runtime.Object{unconfiguredCredentialIssuer},
+			kubeObjects:     []runtime.Object{validClusterInfoConfigMap, extensionAPIServerAuthentication(otherCAPEM)},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return nil
+			},
+		},
+		{
+			name: "no longer configured, so the strategy is reported as disabled",
+			pinnipedObjects: []runtime.Object{&configv1alpha1.CredentialIssuer{
+				ObjectMeta: unconfiguredCredentialIssuer.ObjectMeta,
+				Status: configv1alpha1.CredentialIssuerStatus{Strategies: []configv1alpha1.CredentialIssuerStrategy{
+					*notTrustedStrategy(now.Add(-time.Hour)),
+				}},
+			}},
+			kubeObjects: []runtime.Object{caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM)},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.DisabledStrategyReason, "managed client CA is not configured"),
+				}
+			},
+		},
+		{
+			name: "rotation overlap is not less than the duration",
+			pinnipedObjects: []runtime.Object{&configv1alpha1.CredentialIssuer{
+				ObjectMeta: credentialIssuer.ObjectMeta,
+				Spec: configv1alpha1.CredentialIssuerSpec{ManagedClientCA: &configv1alpha1.ManagedClientCASpec{
+					DurationSeconds:        ptr.To[int64](3600),
+					RotationOverlapSeconds: ptr.To[int64](3600),
+				}},
+			}},
+			wantErr: "managedClientCA.rotationOverlapSeconds (3600) must be less than managedClientCA.durationSeconds (3600)",
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						"managedClientCA.rotationOverlapSeconds (3600) must be less than managedClientCA.durationSeconds (3600)"),
+				}
+			},
+		},
+		{
+			name:                 "CA not generated yet",
+			pinnipedObjects:      []runtime.Object{credentialIssuer},
+			kubeObjects:          []runtime.Object{validClusterInfoConfigMap},
+			wantSecretActions:    []string{"create"},
+			wantGeneratedCurrent: true,
+			wantConfigMapActions: []string{"create"},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*notTrustedStrategy(now)}
+			},
+		},
+		{
+			name:            "CA cannot be saved",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects:     []runtime.Object{validClusterInfoConfigMap},
+			addKubeReactions: func(clientset *kubefake.Clientset) {
+				clientset.PrependReactor("create", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			wantErr:           "could not create concierge/managed-client-ca-certificate secret: some create error",
+			wantSecretActions: []string{"create"},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						"could not create concierge/managed-client-ca-certificate secret: some create error"),
+				}
+			},
+		},
+		{
+			name:            "invalid CA",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: caSecretMeta,
+					Data: map[string][]byte{
+						apicerts.CACertificateSecretKey:           []byte("not a cert"),
+						apicerts.CACertificatePrivateKeySecretKey: caKeyPEM,
+					},
+				},
+				validClusterInfoConfigMap,
+			},
+			wantErr: `secret concierge/managed-client-ca-certificate has an invalid "caCertificate": could not load CA: tls: failed to find any PEM data in certificate input`,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						`secret concierge/managed-client-ca-certificate has an invalid "caCertificate": could not load CA: tls: failed to find any PEM data in certificate input`),
+				}
+			},
+		},
+		{
+			name:                 "client CA bundle of the API server is not available",
+			pinnipedObjects:      []runtime.Object{credentialIssuer},
+			kubeObjects:          []runtime.Object{caSecret, validClusterInfoConfigMap},
+			wantConfigMapActions: []string{"create"},
+			wantPublishedBundle:  caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*notTrustedStrategy(now)}
+			},
+		},
+		{
+			name:                 "not trusted by the API server",
+			pinnipedObjects:      []runtime.Object{credentialIssuer},
+			kubeObjects:          []runtime.Object{caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(otherCAPEM)},
+			wantConfigMapActions: []string{"create"},
+			wantPublishedBundle:  caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*notTrustedStrategy(now)}
+			},
+		},
+		{
+			name:                 "trusted by the API server",
+			pinnipedObjects:      []runtime.Object{credentialIssuer},
+			kubeObjects:          []runtime.Object{caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(otherCAPEM, caPEM)},
+			wantLoadedCA:         caPEM,
+			wantLoadedKey:        caKeyPEM,
+			wantConfigMapActions: []string{"create"},
+			wantPublishedBundle:  caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, "the Kubernetes API server trusts the managed client CA")}
+			},
+		},
+		{
+			name:            "trusted by the API server and the bundle is already published",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM), bundleConfigMap(caPEM),
+			},
+			wantLoadedCA:        caPEM,
+			wantLoadedKey:       caKeyPEM,
+			wantPublishedBundle: caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, "the Kubernetes API server trusts the managed client CA")}
+			},
+		},
+		{
+			name:            "outdated bundle is published again",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(otherCAPEM), bundleConfigMap(otherCAPEM),
+			},
+			wantConfigMapActions: []string{"update"},
+			wantPublishedBundle:  caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*notTrustedStrategy(now)}
+			},
+		},
+		{
+			name:            "trusted but cluster-info is missing",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				caSecret, extensionAPIServerAuthentication(caPEM), bundleConfigMap(caPEM),
+			},
+			wantErr:             `failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`,
+			wantLoadedCA:        caPEM,
+			wantLoadedKey:       caKeyPEM,
+			wantPublishedBundle: caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.CouldNotGetClusterInfoStrategyReason,
+						`failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`),
+				}
+			},
+		},
+		{
+			name:            "trusted but the bundle cannot be published",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects:     []runtime.Object{caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM)},
+			addKubeReactions: func(clientset *kubefake.Clientset) {
+				clientset.PrependReactor("create", "configmaps", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			wantErr:              "could not create concierge/managed-client-ca-bundle configmap: some create error",
+			wantLoadedCA:         caPEM,
+			wantLoadedKey:        caKeyPEM,
+			wantConfigMapActions: []string{"create"},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						"could not create concierge/managed-client-ca-bundle configmap: some create error"),
+				}
+			},
+		},
+		{
+			name:            "not trusted and the bundle cannot be published",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects:     []runtime.Object{caSecret, validClusterInfoConfigMap, bundleConfigMap(otherCAPEM)},
+			addKubeReactions: func(clientset *kubefake.Clientset) {
+				clientset.PrependReactor("update", "configmaps", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some update error")
+				})
+			},
+			wantErr:              "could not update concierge/managed-client-ca-bundle configmap: some update error",
+			wantConfigMapActions: []string{"update"},
+			wantPublishedBundle:  otherCAPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						"could not update concierge/managed-client-ca-bundle configmap: some update error"),
+				}
+			},
+		},
+		{
+			name:            "the next CA is generated and published together with the current CA when the overlap window starts",
+			clockOffset:     inOverlapWindow,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM), bundleConfigMap(caPEM),
+			},
+			wantLoadedCA:         caPEM,
+			wantLoadedKey:        caKeyPEM,
+			wantSecretActions:    []string{"update"},
+			wantGeneratedNext:    true,
+			wantConfigMapActions: []string{"update"},
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, fmt.Sprintf(
+					`the Kubernetes API server trusts the managed client CA, but not the next CA which must replace it before %s: add the "ca.crt" key of the concierge/managed-client-ca-bundle configmap to its --client-ca-file`,
+					caNotAfter.UTC().Format(time.RFC3339)))}
+			},
+		},
+		{
+			name:            "the next CA is not generated again when it already exists",
+			clockOffset:     inOverlapWindow,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: caSecretMeta,
+					Data: map[string][]byte{
+						"caCertificate":               caPEM,
+						"caCertificatePrivateKey":     caKeyPEM,
+						"nextCACertificate":           otherCAPEM,
+						"nextCACertificatePrivateKey": otherCAKeyPEM,
+					},
+				},
+				validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM), bundleConfigMap(append(append([]byte{}, caPEM...), otherCAPEM...)),
+			},
+			wantLoadedCA:        caPEM,
+			wantLoadedKey:       caKeyPEM,
+			wantPublishedBundle: append(append([]byte{}, caPEM...), otherCAPEM...),
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, fmt.Sprintf(
+					`the Kubernetes API server trusts the managed client CA, but not the next CA which must replace it before %s: add the "ca.crt" key of the concierge/managed-client-ca-bundle configmap to its --client-ca-file`,
+					caNotAfter.UTC().Format(time.RFC3339)))}
+			},
+		},
+		{
+			name:            "the next CA replaces the current CA once it is trusted, and the replaced CA stays in the bundle",
+			clockOffset:     inOverlapWindow,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: caSecretMeta,
+					Data: map[string][]byte{
+						"caCertificate":               caPEM,
+						"caCertificatePrivateKey":     caKeyPEM,
+						"nextCACertificate":           otherCAPEM,
+						"nextCACertificatePrivateKey": otherCAKeyPEM,
+					},
+				},
+				validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM, otherCAPEM), bundleConfigMap(append(append([]byte{}, caPEM...), otherCAPEM...)),
+			},
+			wantLoadedCA:      otherCAPEM,
+			wantLoadedKey:     otherCAKeyPEM,
+			wantSecretActions: []string{"update"},
+			wantSecretData: map[string][]byte{
+				"caCertificate":           otherCAPEM,
+				"caCertificatePrivateKey": otherCAKeyPEM,
+				"previousCACertificate":   caPEM,
+			},
+			wantConfigMapActions: []string{"update"},
+			wantPublishedBundle:  append(append([]byte{}, otherCAPEM...), caPEM...),
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, "the Kubernetes API server trusts the managed client CA")}
+			},
+		},
+		{
+			name:            "the next CA is not used while it is not trusted, even after the current CA expired",
+			clockOffset:     year + time.Hour,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: caSecretMeta,
+					Data: map[string][]byte{
+						"caCertificate":               caPEM,
+						"caCertificatePrivateKey":     caKeyPEM,
+						"nextCACertificate":           otherCAPEM,
+						"nextCACertificatePrivateKey": otherCAKeyPEM,
+					},
+				},
+				validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM), bundleConfigMap(append(append([]byte{}, caPEM...), otherCAPEM...)),
+			},
+			wantPublishedBundle: append(append([]byte{}, caPEM...), otherCAPEM...),
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*notTrustedStrategy(now)}
+			},
+		},
+		{
+			name:            "the previous CA is removed from the bundle once it expires",
+			clockOffset:     2 * time.Hour,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: caSecretMeta,
+					Data: map[string][]byte{
+						"caCertificate":           caPEM,
+						"caCertificatePrivateKey": caKeyPEM,
+						"previousCACertificate":   expiredCAPEM,
+					},
+				},
+				validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM, expiredCAPEM), bundleConfigMap(append(append([]byte{}, caPEM...), expiredCAPEM...)),
+			},
+			wantLoadedCA:      caPEM,
+			wantLoadedKey:     caKeyPEM,
+			wantSecretActions: []string{"update"},
+			wantSecretData: map[string][]byte{
+				"caCertificate":           caPEM,
+				"caCertificatePrivateKey": caKeyPEM,
+			},
+			wantConfigMapActions: []string{"update"},
+			wantPublishedBundle:  caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{*trustedStrategy(now, "the Kubernetes API server trusts the managed client CA")}
+			},
+		},
+		{
+			name:            "the saved CAs keep being used when the rotated CAs cannot be saved",
+			clockOffset:     inOverlapWindow,
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{
+				caSecret, validClusterInfoConfigMap, extensionAPIServerAuthentication(caPEM), bundleConfigMap(caPEM),
+			},
+			addKubeReactions: func(clientset *kubefake.Clientset) {
+				clientset.PrependReactor("update", "secrets", func(action coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some update error")
+				})
+			},
+			wantErr:             "could not update concierge/managed-client-ca-certificate secret: some update error",
+			wantLoadedCA:        caPEM,
+			wantLoadedKey:       caKeyPEM,
+			wantSecretActions:   []string{"update"},
+			wantPublishedBundle: caPEM,
+			wantStrategies: func(now time.Time) []configv1alpha1.CredentialIssuerStrategy {
+				return []configv1alpha1.CredentialIssuerStrategy{
+					*errorStrategy(now, configv1alpha1.ErrorDuringSetupStrategyReason,
+						"could not update concierge/managed-client-ca-certificate secret: some update error"),
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			syncTime := now.Add(tt.clockOffset)
+
+			conciergeClientset := conciergefake.NewSimpleClientset(tt.pinnipedObjects...)
+			conciergeInformers := conciergeinformers.NewSharedInformerFactory(conciergeClientset, 0)
+			kubeClientset := kubefake.NewSimpleClientset(tt.kubeObjects...)
+			if tt.addKubeReactions != nil {
+				tt.addKubeReactions(kubeClientset)
+			}
+			kubeInformers := informers.NewSharedInformerFactory(kubeClientset, 0)
+
+			provider := dynamiccert.NewCA(t.Name())
+			// Start with a loaded CA to make sure that the controller unloads it when it should not be used.
+			require.NoError(t, provider.SetCertKeyContent(otherCAPEM, otherCAKeyPEM))
+
+			controller := newManagedCAController(
+				AgentConfig{
+					Namespace:            "concierge",
+					CredentialIssuerName: "pinniped-concierge-config",
+					Labels:               map[string]string{"app": "concierge"},
+				},
+				ManagedCAConfig{
+					SecretName:          "managed-client-ca-certificate",
+					BundleConfigMapName: "managed-client-ca-bundle",
+				},
+				&kubeclient.Client{Kubernetes: kubeClientset, PinnipedConcierge: conciergeClientset},
+				kubeInformers.Core().V1().Secrets(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				conciergeInformers.Config().V1alpha1().CredentialIssuers(),
+				provider,
+				clocktesting.NewFakeClock(syncTime),
+			)
+
+			kubeInformers.Start(ctx.Done())
+			conciergeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			err := controllerlib.TestSync(t, controller, controllerlib.Context{Context: ctx})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			loadedCA, loadedKey := provider.CurrentCertKeyContent()
+			require.Equal(t, tt.wantLoadedCA, loadedCA)
+			require.Equal(t, tt.wantLoadedKey, loadedKey)
+
+			var secretActions, configMapActions []string
+			for _, action := range kubeClientset.Actions() {
+				if action.GetVerb() == "list" || action.GetVerb() == "watch" {
+					continue
+				}
+				switch action.GetResource().Resource {
+				case "secrets":
+					secretActions = append(secretActions, action.GetVerb())
+				case "configmaps":
+					configMapActions = append(configMapActions, action.GetVerb())
+				}
+			}
+			require.Equal(t, tt.wantSecretActions, secretActions)
+			require.Equal(t, tt.wantConfigMapActions, configMapActions)
+
+			wantPublishedBundle := tt.wantPublishedBundle
+			if tt.wantSecretData != nil || tt.wantGeneratedCurrent || tt.wantGeneratedNext {
+				saved, err := kubeClientset.CoreV1().Secrets("concierge").Get(ctx, "managed-client-ca-certificate", metav1.GetOptions{})
+				require.NoError(t, err)
+				switch {
+				case tt.wantGeneratedCurrent:
+					require.Len(t, saved.Data, 2)
+					_, err := certauthority.Load(string(saved.Data["caCertificate"]), string(saved.Data["caCertificatePrivateKey"]))
+					require.NoError(t, err)
+					require.Equal(t, map[string]string{"app": "concierge"}, saved.Labels)
+					wantPublishedBundle = saved.Data["caCertificate"]
+				case tt.wantGeneratedNext:
+					require.Len(t, saved.Data, 4)
+					require.Equal(t, caPEM, saved.Data["caCertificate"])
+					require.Equal(t, caKeyPEM, saved.Data["caCertificatePrivateKey"])
+					_, err := certauthority.Load(string(saved.Data["nextCACertificate"]), string(saved.Data["nextCACertificatePrivateKey"]))
+					require.NoError(t, err)
+					wantPublishedBundle = append(append([]byte{}, caPEM...), saved.Data["nextCACertificate"]...)
+				default:
+					require.Equal(t, tt.wantSecretData, saved.Data)
+				}
+			}
+
+			if wantPublishedBundle != nil {
+				published, err := kubeClientset.CoreV1().ConfigMaps("concierge").Get(ctx, "managed-client-ca-bundle", metav1.GetOptions{})
+				require.NoError(t, err)
+				require.Equal(t, bundleConfigMap(wantPublishedBundle), published)
+			}
+
+			if !tt.wantNoCredentialIssuer {
+				credIssuer, err := conciergeClientset.ConfigV1alpha1().CredentialIssuers().Get(ctx, "pinniped-concierge-config", metav1.GetOptions{})
+				require.NoError(t, err)
+				require.Equal(t, tt.wantStrategies(syncTime), credIssuer.Status.Strategies)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package activedirectoryupstreamwatcher implements a controller which watches ActiveDirectoryIdentityProviders.
@@ -8,15 +8,20 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-ldap/ldap/v3"
 	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
@@ -71,8 +76,19 @@ const (
 	accountDisabledBitmapValue = 2
 	// 0x0010 UF_LOCKOUT in msDS-User-Account-Control-Computed bitmap.
 	accountLockedBitmapValue = 16
+
+	// Active Directory publishes a DNS SRV record with this service and protocol for each domain controller.
+	// https://learn.microsoft.com/en-us/troubleshoot/windows-server/networking/verify-srv-dns-records-have-been-created
+	ldapSRVService  = "ldap"
+	ldapSRVProtocol = "tcp"
+
+	typeHostsDiscovered      = "HostsDiscovered"
+	reasonHostDiscoveryError = "HostDiscoveryError"
 )
 
+// SRVLookupFunc looks up DNS SRV records. It has the same signature as net.Resolver.LookupSRV.
+type SRVLookupFunc func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
 type activeDirectoryUpstreamGenericLDAPImpl struct {
 	activeDirectoryIdentityProvider v1alpha1.ActiveDirectoryIdentityProvider
 }
@@ -231,7 +247,11 @@ type UpstreamActiveDirectoryIdentityProviderICache interface {
 type activeDirectoryWatcherController struct {
 	cache                                   UpstreamActiveDirectoryIdentityProviderICache
 	validatedSettingsCache                  upstreamwatchers.ValidatedSettingsCacheI
+	groupCache                              upstreamldap.GroupCache
+	connectionPools                         *upstreamwatchers.ConnectionPools
+	hostHealth                              *upstreamldap.HostHealth
 	ldapDialer                              upstreamldap.LDAPDialer
+	lookupSRV                               SRVLookupFunc
 	client                                  supervisorclientset.Interface
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer
 	secretInformer                          corev1informers.SecretInformer
@@ -240,6 +260,7 @@ type activeDirectoryWatcherController struct {
 // New instantiates a new controllerlib.Controller which will populate the provided UpstreamActiveDirectoryIdentityProviderICache.
 func New(
 	idpCache UpstreamActiveDirectoryIdentityProviderICache,
+	groupCache upstreamldap.GroupCache,
 	client supervisorclientset.Interface,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
@@ -251,6 +272,8 @@ func New(
 		upstreamwatchers.NewValidatedSettingsCache(),
 		// nil means to use a real production dialer when creating objects to add to the cache
 		nil,
+		net.DefaultResolver.LookupSRV,
+		groupCache,
 		client,
 		activeDirectoryIdentityProviderInformer,
 		secretInformer,
@@ -263,6 +286,8 @@ func newInternal(
 	idpCache UpstreamActiveDirectoryIdentityProviderICache,
 	validatedSettingsCache upstreamwatchers.ValidatedSettingsCacheI,
 	ldapDialer upstreamldap.LDAPDialer,
+	lookupSRV SRVLookupFunc,
+	groupCache upstreamldap.GroupCache,
 	client supervisorclientset.Interface,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
@@ -271,7 +296,11 @@ func newInternal(
 	c := activeDirectoryWatcherController{
 		cache:                                   idpCache,
 		validatedSettingsCache:                  validatedSettingsCache,
+		groupCache:                              groupCache,
+		connectionPools:                         upstreamwatchers.NewConnectionPools(),
+		hostHealth:                              upstreamldap.NewHostHealth(),
 		ldapDialer:                              ldapDialer,
+		lookupSRV:                               lookupSRV,
 		client:                                  client,
 		activeDirectoryIdentityProviderInformer: activeDirectoryIdentityProviderInformer,
 		secretInformer:                          secretInformer,
@@ -285,7 +314,10 @@ func newInternal(
 		),
 		withInformer(
 			secretInformer,
-			pinnipedcontroller.MatchAnySecretOfTypeFilter(upstreamwatchers.LDAPBindAccountSecretType, pinnipedcontroller.SingletonQueue()),
+			pinnipedcontroller.MatchAnySecretOfTypesFilter(
+				[]corev1.SecretType{upstreamwatchers.LDAPBindAccountSecretType, upstreamwatchers.LDAPBindClientCertificateSecretType},
+				pinnipedcontroller.SingletonQueue(),
+			),
 			controllerlib.InformerOption{},
 		),
 	)
@@ -300,7 +332,9 @@ func (c *activeDirectoryWatcherController) Sync(ctx controllerlib.Context) error
 
 	requeue := false
 	validatedUpstreams := make([]upstreamprovider.UpstreamLDAPIdentityProviderI, 0, len(actualUpstreams))
+	upstreamNames := sets.New[string]()
 	for _, upstream := range actualUpstreams {
+		upstreamNames.Insert(upstream.Name)
 		valid, requestedRequeue := c.validateUpstream(ctx.Context, upstream)
 		if valid != nil {
 			validatedUpstreams = append(validatedUpstreams, valid)
@@ -311,6 +345,7 @@ func (c *activeDirectoryWatcherController) Sync(ctx controllerlib.Context) error
 	}
 
 	c.cache.SetActiveDirectoryIdentityProviders(validatedUpstreams)
+	c.connectionPools.CloseAllExcept(upstreamNames)
 
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
@@ -324,14 +359,16 @@ func (c *activeDirectoryWatcherController) validateUpstream(ctx context.Context,
 	adUpstreamImpl := &activeDirectoryUpstreamGenericLDAPImpl{activeDirectoryIdentityProvider: *upstream}
 
 	config := &upstreamldap.ProviderConfig{
-		Name:        upstream.Name,
-		ResourceUID: upstream.UID,
-		Host:        spec.Host,
+		Name:            upstream.Name,
+		ResourceUID:     upstream.UID,
+		Host:            spec.Host,
+		AdditionalHosts: spec.AdditionalHosts,
 		UserSearch: upstreamldap.UserSearchConfig{
-			Base:              spec.UserSearch.Base,
-			Filter:            adUpstreamImpl.Spec().UserSearch().Filter(),
-			UsernameAttribute: adUpstreamImpl.Spec().UserSearch().UsernameAttribute(),
-			UIDAttribute:      adUpstreamImpl.Spec().UserSearch().UIDAttribute(),
+			Base:                 spec.UserSearch.Base,
+			Filter:               adUpstreamImpl.Spec().UserSearch().Filter(),
+			UsernameAttribute:    adUpstreamImpl.Spec().UserSearch().UsernameAttribute(),
+			UIDAttribute:         adUpstreamImpl.Spec().UserSearch().UIDAttribute(),
+			AdditionalAttributes: spec.UserSearch.Attributes.AdditionalAttributes,
 		},
 		GroupSearch: upstreamldap.GroupSearchConfig{
 			Base:                   spec.GroupSearch.Base,
@@ -340,7 +377,9 @@ func (c *activeDirectoryWatcherController) validateUpstream(ctx context.Context,
 			GroupNameAttribute:     adUpstreamImpl.Spec().GroupSearch().GroupNameAttribute(),
 			SkipGroupRefresh:       spec.GroupSearch.SkipGroupRefresh,
 		},
-		Dialer: c.ldapDialer,
+		Dialer:         c.ldapDialer,
+		ConnectionPool: c.connectionPools.Get(upstream.Name),
+		GroupCache:     c.groupCache,
 		UIDAttributeParsingOverrides: map[string]func(*ldap.Entry) (string, error){
 			"objectGUID": microsoftUUIDFromBinaryAttr("objectGUID"),
 		},
@@ -357,13 +396,78 @@ func (c *activeDirectoryWatcherController) validateUpstream(ctx context.Context,
 		}
 	}
 
+	var hostsDiscoveredCondition *metav1.Condition
+	if spec.HostDiscovery != nil {
+		hostsDiscoveredCondition = c.discoverHosts(ctx, spec.HostDiscovery.Domain, config)
+	}
+
+	if spec.GroupSearch.RefreshCacheSeconds != nil {
+		config.GroupSearch.RefreshCacheTTL = time.Duration(*spec.GroupSearch.RefreshCacheSeconds) * time.Second
+	}
+
+	if len(config.AdditionalHosts) > 0 {
+		// Only track the health of the hosts when there are other hosts to try instead.
+		config.HostHealth = c.hostHealth
+	}
+
 	conditions := upstreamwatchers.ValidateGenericLDAP(ctx, adUpstreamImpl, c.secretInformer, c.validatedSettingsCache, config)
+	if hostsDiscoveredCondition != nil {
+		// Failing to discover hosts is not fatal, since the Host and the AdditionalHosts can still be used.
+		conditions.Append(hostsDiscoveredCondition, false)
+	}
 
 	c.updateStatus(ctx, upstream, conditions.Conditions())
 
 	return upstreamwatchers.EvaluateConditions(conditions, config)
 }
 
+// discoverHosts looks up the domain controllers of the domain using DNS SRV records, and appends their hostnames
+// to the config's AdditionalHosts in the order of their priority. The lookup shuffles the records of each priority
+// by weight, so the hosts are sorted by name within each priority to keep the config and the status stable between
+// syncs. Their weights are kept in the config instead, so that the weighted choice is made each time that a
+// connection is dialed.
+func (c *activeDirectoryWatcherController) discoverHosts(ctx context.Context, domain string, config *upstreamldap.ProviderConfig) *metav1.Condition {
+	_, records, err := c.lookupSRV(ctx, ldapSRVService, ldapSRVProtocol, domain)
+	if err != nil {
+		return &metav1.Condition{
+			Type:    typeHostsDiscovered,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonHostDiscoveryError,
+			Message: fmt.Sprintf("error looking up DNS SRV records for domain %q: %s", domain, err.Error()),
+		}
+	}
+
+	sortedRecords := append([]*net.SRV{}, records...)
+	sort.SliceStable(sortedRecords, func(i, j int) bool {
+		if sortedRecords[i].Priority != sortedRecords[j].Priority {
+			return sortedRecords[i].Priority < sortedRecords[j].Priority
+		}
+		return sortedRecords[i].Target < sortedRecords[j].Target
+	})
+
+	discoveredHosts := make([]string, 0, len(sortedRecords))
+	weights := make(map[string]upstreamldap.SRVWeight, len(sortedRecords))
+	for _, record := range sortedRecords {
+		// The targets of SRV records are fully qualified, so remove the trailing dot. The port of the records
+		// is always the plain LDAP port, so leave it out to use the default port of the connection protocol instead.
+		host := strings.TrimSuffix(record.Target, ".")
+		if _, ok := weights[host]; ok {
+			continue // the same host was listed more than once
+		}
+		discoveredHosts = append(discoveredHosts, host)
+		weights[host] = upstreamldap.SRVWeight{Priority: record.Priority, Weight: record.Weight}
+	}
+	config.AdditionalHosts = append(append([]string{}, config.AdditionalHosts...), discoveredHosts...)
+	config.DiscoveredHostWeights = weights
+
+	return &metav1.Condition{
+		Type:    typeHostsDiscovered,
+		Status:  metav1.ConditionTrue,
+		Reason:  upstreamwatchers.ReasonSuccess,
+		Message: fmt.Sprintf("discovered hosts %q using DNS SRV records for domain %q", discoveredHosts, domain),
+	}
+}
+
 func (c *activeDirectoryWatcherController) updateStatus(ctx context.Context, upstream *v1alpha1.ActiveDirectoryIdentityProvider, conditions []*metav1.Condition) {
 	log := plog.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
 	updated := upstream.DeepCopy()
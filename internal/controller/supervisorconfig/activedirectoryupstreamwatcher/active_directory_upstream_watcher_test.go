@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package activedirectoryupstreamwatcher
@@ -8,6 +8,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net"
 	"reflect"
 	"sort"
 	"testing"
@@ -31,6 +32,7 @@ import (
 	"go.pinniped.dev/internal/endpointaddr"
 	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/fositestorage/ldapgroupcache"
 	"go.pinniped.dev/internal/mocks/mockldapconn"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/upstreamldap"
@@ -56,6 +58,16 @@ func TestActiveDirectoryUpstreamWatcherControllerFilterSecrets(t *testing.T) {
 			wantUpdate: true,
 			wantDelete: true,
 		},
+		{
+			name: "a secret of the right type for a client certificate bind",
+			secret: &corev1.Secret{
+				Type:       corev1.SecretTypeTLS,
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+			},
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
 		{
 			name: "a secret of the wrong type",
 			secret: &corev1.Secret{
@@ -83,7 +95,7 @@ func TestActiveDirectoryUpstreamWatcherControllerFilterSecrets(t *testing.T) {
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(secretInformer)
@@ -128,7 +140,7 @@ func TestActiveDirectoryUpstreamWatcherControllerFilterActiveDirectoryIdentityPr
 			secretInformer := kubeInformers.Core().V1().Secrets()
 			withInformer := testutil.NewObservableWithInformerOption()
 
-			New(nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
+			New(nil, nil, nil, activeDirectoryIDPInformer, secretInformer, withInformer.WithInformer)
 
 			unrelated := corev1.Secret{}
 			filter := withInformer.GetFilterForInformer(activeDirectoryIDPInformer)
@@ -373,6 +385,8 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 		inputSecrets             []runtime.Object
 		setupMocks               func(conn *mockldapconn.MockConn)
 		dialErrors               map[string]error
+		srvRecords               []*net.SRV
+		srvErr                   error
 		wantErr                  string
 		wantResultingCache       []*upstreamldap.ProviderConfig
 		wantResultingUpstreams   []v1alpha1.ActiveDirectoryIdentityProvider
@@ -409,6 +423,115 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
 			}},
 		},
+		{
+			name: "host discovery adds the hosts from the DNS SRV records of the domain as additional hosts, sorted by priority and name",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.ActiveDirectoryIdentityProvider) {
+				upstream.Spec.HostDiscovery = &v1alpha1.ActiveDirectoryIdentityProviderHostDiscovery{Domain: "example.com"}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			srvRecords: []*net.SRV{
+				// The lookup shuffles the records of each priority by weight, so they may be in any order.
+				{Target: "dc3.example.com.", Port: 389, Priority: 0, Weight: 50},
+				{Target: "dc1.example.com.", Port: 389, Priority: 10, Weight: 100},
+				{Target: "dc2.example.com.", Port: 389, Priority: 0, Weight: 100},
+			},
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind, and then probe each of the hosts.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(5)
+				conn.EXPECT().Close().Times(5)
+			},
+			wantResultingCache: []*upstreamldap.ProviderConfig{func() *upstreamldap.ProviderConfig {
+				config := *providerConfigForValidUpstreamWithTLS
+				config.AdditionalHosts = []string{"dc2.example.com", "dc3.example.com", "dc1.example.com"}
+				config.DiscoveredHostWeights = map[string]upstreamldap.SRVWeight{
+					"dc1.example.com": {Priority: 10, Weight: 100},
+					"dc2.example.com": {Priority: 0, Weight: 100},
+					"dc3.example.com": {Priority: 0, Weight: 50},
+				}
+				return &config
+			}()},
+			wantResultingUpstreams: []v1alpha1.ActiveDirectoryIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, UID: testResourceUID, Generation: 1234},
+				Status: v1alpha1.ActiveDirectoryIdentityProviderStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "HostsDiscovered",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `discovered hosts ["dc2.example.com" "dc3.example.com" "dc1.example.com"] using DNS SRV records for domain "example.com"`,
+							ObservedGeneration: 1234,
+						},
+						{
+							Type:               "HostsReachable",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `successfully able to connect to and bind to all hosts ["ldap.example.com:123" "dc2.example.com" "dc3.example.com" "dc1.example.com"]`,
+							ObservedGeneration: 1234,
+						},
+						activeDirectoryConnectionValidTrueCondition(1234, "4242"),
+						searchBaseFoundInConfigCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion: "4242",
+				LDAPConnectionProtocol:    upstreamldap.TLS,
+				UserSearchBase:            testUserSearchBase,
+				GroupSearchBase:           testGroupSearchBase,
+				IDPSpecGeneration:         1234,
+				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+			}},
+		},
+		{
+			name: "host discovery which fails to look up the DNS SRV records still loads the upstream using the configured host",
+			inputUpstreams: []runtime.Object{editedValidUpstream(func(upstream *v1alpha1.ActiveDirectoryIdentityProvider) {
+				upstream.Spec.HostDiscovery = &v1alpha1.ActiveDirectoryIdentityProviderHostDiscovery{Domain: "example.com"}
+			})},
+			inputSecrets: []runtime.Object{validBindUserSecret("4242")},
+			srvErr:       errors.New("some DNS error"),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				// Should perform a test dial and bind.
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			wantErr:            controllerlib.ErrSyntheticRequeue.Error(),
+			wantResultingCache: []*upstreamldap.ProviderConfig{providerConfigForValidUpstreamWithTLS},
+			wantResultingUpstreams: []v1alpha1.ActiveDirectoryIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, UID: testResourceUID, Generation: 1234},
+				Status: v1alpha1.ActiveDirectoryIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						bindSecretValidTrueCondition(1234),
+						{
+							Type:               "HostsDiscovered",
+							Status:             "False",
+							LastTransitionTime: now,
+							Reason:             "HostDiscoveryError",
+							Message:            `error looking up DNS SRV records for domain "example.com": some DNS error`,
+							ObservedGeneration: 1234,
+						},
+						activeDirectoryConnectionValidTrueCondition(1234, "4242"),
+						searchBaseFoundInConfigCondition(1234),
+						tlsConfigurationValidLoadedTrueCondition(1234),
+					},
+				},
+			}},
+			wantValidatedSettings: map[string]upstreamwatchers.ValidatedSettings{testName: {
+				BindSecretResourceVersion: "4242",
+				LDAPConnectionProtocol:    upstreamldap.TLS,
+				UserSearchBase:            testUserSearchBase,
+				GroupSearchBase:           testGroupSearchBase,
+				IDPSpecGeneration:         1234,
+				ConnectionValidCondition:  condPtr(activeDirectoryConnectionValidTrueConditionWithoutTimeOrGeneration("4242")),
+				SearchBaseFoundCondition:  condPtr(withoutTime(searchBaseFoundInConfigCondition(0))),
+			}},
+		},
 		{
 			name:               "missing secret",
 			inputUpstreams:     []runtime.Object{validUpstream},
@@ -453,7 +576,7 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 							Status:             "False",
 							LastTransitionTime: now,
 							Reason:             "SecretWrongType",
-							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth")`, testBindSecretName),
+							Message:            fmt.Sprintf(`referenced Secret "%s" has wrong type "some-other-type" (should be "kubernetes.io/basic-auth" or "kubernetes.io/tls")`, testBindSecretName),
 							ObservedGeneration: 1234,
 						},
 						tlsConfigurationValidLoadedTrueCondition(1234),
@@ -2033,6 +2156,12 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				return conn, nil
 			})}
 
+			lookupSRV := func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+				require.Equal(t, "ldap", service)
+				require.Equal(t, "tcp", proto)
+				return "", tt.srvRecords, tt.srvErr
+			}
+
 			var validatedSettingsCache *upstreamwatchers.ValidatedSettingsCache
 			if tt.initialValidatedSettings != nil {
 				validatedSettingsCache = &upstreamwatchers.ValidatedSettingsCache{
@@ -2044,10 +2173,14 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				}
 			}
 
+			groupCache := ldapgroupcache.New(fakeKubeClient.CoreV1().Secrets(testNamespace), time.Now)
+
 			controller := newInternal(
 				cache,
 				validatedSettingsCache,
 				dialer,
+				lookupSRV,
+				groupCache,
 				fakePinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				kubeInformers.Core().V1().Secrets(),
@@ -2077,10 +2210,20 @@ func TestActiveDirectoryUpstreamWatcherControllerSync(t *testing.T) {
 				// The dialer that was passed in to the controller's constructor should always have been
 				// passed through to the provider.
 				copyOfExpectedValueForResultingCache.Dialer = dialer
+				// The group cache that was passed in to the controller's constructor should always have been
+				// passed through to the provider.
+				copyOfExpectedValueForResultingCache.GroupCache = groupCache
+
+				// The HostHealth is shared state which is only given to providers which have more than one host.
+				actualConfig := actualIDP.GetConfig()
+				require.Equal(t, len(copyOfExpectedValueForResultingCache.AdditionalHosts) > 0, actualConfig.HostHealth != nil)
+				copyOfExpectedValueForResultingCache.HostHealth = actualConfig.HostHealth
+				// The ConnectionPool is shared state which is given to every provider.
+				require.NotNil(t, actualConfig.ConnectionPool)
+				copyOfExpectedValueForResultingCache.ConnectionPool = actualConfig.ConnectionPool
 
 				// function equality is awkward. Do the check for equality separately from the rest of the config.
 				expectedUIDAttributeParsingOverrides := copyOfExpectedValueForResultingCache.UIDAttributeParsingOverrides
-				actualConfig := actualIDP.GetConfig()
 				actualUIDAttributeParsingOverrides := actualConfig.UIDAttributeParsingOverrides
 				copyOfExpectedValueForResultingCache.UIDAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){}
 				actualConfig.UIDAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorconfig
@@ -19,6 +19,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	errorsutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/utils/clock"
 
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
@@ -29,7 +30,12 @@ import (
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controller/conditionsutil"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
+	"go.pinniped.dev/internal/federationdomain/endpoints/tokenexchange"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/plog"
 )
@@ -47,6 +53,10 @@ const (
 	typeIdentityProvidersObjectRefKindValid  = "IdentityProvidersObjectRefKindValid"
 	typeTransformsExpressionsValid           = "TransformsExpressionsValid"
 	typeTransformsExamplesPassed             = "TransformsExamplesPassed"
+	typeTokenExchangeAudiencesValid          = "TokenExchangeAudiencesValid"
+	typeIdentityProvidersSecondFactorValid   = "IdentityProvidersSecondFactorValid"
+	typeIdentityProvidersRoutingValid        = "IdentityProvidersRoutingValid"
+	typeBrandingValid                        = "BrandingValid"
 
 	reasonSuccess                                     = "Success"
 	reasonNotReady                                    = "NotReady"
@@ -63,6 +73,11 @@ const (
 	reasonKindUnrecognized                            = "KindUnrecognized"
 	reasonInvalidTransformsExpressions                = "InvalidTransformsExpressions"
 	reasonTransformsExamplesFailed                    = "TransformsExamplesFailed"
+	reasonInvalidTokenExchangeAudiences               = "InvalidTokenExchangeAudiences"
+	reasonSecondFactorUnsupported                     = "SecondFactorUnsupported"
+	reasonInvalidRouting                              = "InvalidRouting"
+	reasonBrandingConfigMapNotFound                   = "BrandingConfigMapNotFound"
+	reasonInvalidBranding                             = "InvalidBranding"
 
 	kindLDAPIdentityProvider            = "LDAPIdentityProvider"
 	kindOIDCIdentityProvider            = "OIDCIdentityProvider"
@@ -88,9 +103,12 @@ type federationDomainWatcherController struct {
 	oidcIdentityProviderInformer            idpinformers.OIDCIdentityProviderInformer
 	ldapIdentityProviderInformer            idpinformers.LDAPIdentityProviderInformer
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer
+	identityTransformationLibraryInformer   configinformers.IdentityTransformationLibraryInformer
+	configMapInformer                       corev1informers.ConfigMapInformer
 
-	celTransformer *celtransformer.CELTransformer
-	allowedKinds   sets.Set[string]
+	identityTransformationLibraryCache *transformpipeline.LibraryCache
+	celTransformer                     *celtransformer.CELTransformer
+	allowedKinds                       sets.Set[string]
 }
 
 // NewFederationDomainWatcherController creates a controllerlib.Controller that watches
@@ -104,6 +122,9 @@ func NewFederationDomainWatcherController(
 	oidcIdentityProviderInformer idpinformers.OIDCIdentityProviderInformer,
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
+	identityTransformationLibraryInformer configinformers.IdentityTransformationLibraryInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	identityTransformationLibraryCache *transformpipeline.LibraryCache,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
 	allowedKinds := sets.New(kindActiveDirectoryIdentityProvider, kindLDAPIdentityProvider, kindOIDCIdentityProvider)
@@ -119,6 +140,9 @@ func NewFederationDomainWatcherController(
 				oidcIdentityProviderInformer:            oidcIdentityProviderInformer,
 				ldapIdentityProviderInformer:            ldapIdentityProviderInformer,
 				activeDirectoryIdentityProviderInformer: activeDirectoryIdentityProviderInformer,
+				identityTransformationLibraryInformer:   identityTransformationLibraryInformer,
+				configMapInformer:                       configMapInformer,
+				identityTransformationLibraryCache:      identityTransformationLibraryCache,
 				allowedKinds:                            allowedKinds,
 			},
 		},
@@ -148,6 +172,20 @@ func NewFederationDomainWatcherController(
 			pinnipedcontroller.MatchAnythingIgnoringUpdatesFilter(pinnipedcontroller.SingletonQueue()),
 			controllerlib.InformerOption{},
 		),
+		withInformer(
+			identityTransformationLibraryInformer,
+			// The contents of the libraries are compiled into the transforms of the identity providers,
+			// so any change to a library requires a Sync.
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			configMapInformer,
+			// The contents of the branding ConfigMaps are loaded into the FederationDomains,
+			// so any change to a ConfigMap requires a Sync.
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
 	)
 }
 
@@ -242,9 +280,143 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuer(
 		}
 	}
 
+	tokenExchangeAudiences, tokenExchangeAudiencesErrors := c.makeTokenExchangeAudiences(federationDomain)
+	if federationDomainIssuer != nil {
+		federationDomainIssuer.SetTokenExchangeAudiences(tokenExchangeAudiences)
+	}
+	conditions = appendTokenExchangeAudiencesValidCondition(tokenExchangeAudiencesErrors, conditions)
+
+	pageBranding, conditions, err := c.makeBranding(federationDomain, conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+	if federationDomainIssuer != nil {
+		federationDomainIssuer.SetBranding(pageBranding)
+	}
+
 	return federationDomainIssuer, conditions, nil
 }
 
+func (c *federationDomainWatcherController) makeBranding(
+	federationDomain *configv1alpha1.FederationDomain,
+	conditions []*metav1.Condition,
+) (*branding.Branding, []*metav1.Condition, error) {
+	if federationDomain.Spec.Branding == nil {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeBrandingValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "no branding was specified by .spec.branding: using the default pages",
+		})
+		return nil, conditions, nil
+	}
+
+	configMapName := federationDomain.Spec.Branding.ConfigMapName
+	configMap, err := c.configMapInformer.Lister().ConfigMaps(federationDomain.Namespace).Get(configMapName)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, nil, err // unexpected error from the informer
+		}
+		conditions = append(conditions, &metav1.Condition{
+			Type:   typeBrandingValid,
+			Status: metav1.ConditionFalse,
+			Reason: reasonBrandingConfigMapNotFound,
+			Message: fmt.Sprintf("the ConfigMap specified by .spec.branding.configMapName %q was not found",
+				configMapName),
+		})
+		return nil, conditions, nil
+	}
+
+	pageBranding, err := branding.FromConfigMap(configMap)
+	if err == nil {
+		// Check the login template now, so that a broken template cannot break the login page.
+		_, err = loginhtml.TemplateWithBranding(pageBranding)
+	}
+	if err != nil {
+		conditions = append(conditions, &metav1.Condition{
+			Type:   typeBrandingValid,
+			Status: metav1.ConditionFalse,
+			Reason: reasonInvalidBranding,
+			Message: fmt.Sprintf("the ConfigMap specified by .spec.branding.configMapName %q is invalid: %s",
+				configMapName, err.Error()),
+		})
+		return nil, conditions, nil
+	}
+
+	conditions = append(conditions, &metav1.Condition{
+		Type:    typeBrandingValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonSuccess,
+		Message: fmt.Sprintf("the ConfigMap specified by .spec.branding.configMapName %q is valid", configMapName),
+	})
+	return pageBranding, conditions, nil
+}
+
+func (c *federationDomainWatcherController) makeTokenExchangeAudiences(
+	federationDomain *configv1alpha1.FederationDomain,
+) (tokenexchange.AllowedAudiences, []string) {
+	allowedAudiences := federationDomain.Spec.TokenExchange.AllowedAudiences
+	if len(allowedAudiences) == 0 {
+		// Any audience which is not reserved by Pinniped may be requested.
+		return nil, nil
+	}
+
+	audiences := tokenexchange.AllowedAudiences{}
+	validationErrorMessages := []string{}
+	// Conditions cannot declare constants, so compile them all with the same empty constants.
+	consts := &celtransformer.TransformationConstants{
+		StringConstants:     map[string]string{},
+		StringListConstants: map[string][]string{},
+	}
+
+	for audienceIndex, allowedAudience := range allowedAudiences {
+		// Reserved audiences would be rejected by the token exchange anyway, so do not allow them to be configured.
+		if allowedAudience.Audience == "pinniped-cli" || strings.Contains(allowedAudience.Audience, ".pinniped.dev") {
+			validationErrorMessages = append(validationErrorMessages,
+				fmt.Sprintf("spec.tokenExchange.allowedAudiences[%d].audience %q is reserved by Pinniped",
+					audienceIndex, allowedAudience.Audience))
+			continue
+		}
+
+		pipeline := idtransform.NewTransformationPipeline()
+		expressionsValid := true
+		for conditionIndex, condition := range allowedAudience.Conditions {
+			message := condition.Message
+			if message == "" {
+				message = "token exchange was rejected by configuration"
+			}
+			compiledTransform, err := c.celTransformer.CompileTransformation(&celtransformer.AllowAuthenticationPolicy{
+				Expression:                    condition.Expression,
+				RejectedAuthenticationMessage: message,
+			}, consts)
+			if err != nil {
+				validationErrorMessages = append(validationErrorMessages,
+					fmt.Sprintf("spec.tokenExchange.allowedAudiences[%d].conditions[%d].expression was invalid:\n%s",
+						audienceIndex, conditionIndex, err.Error()))
+				expressionsValid = false
+				continue
+			}
+			pipeline.AppendTransformation(compiledTransform)
+		}
+		if !expressionsValid {
+			continue
+		}
+
+		var idTokenLifespan time.Duration
+		if allowedAudience.IDTokenSeconds != nil {
+			idTokenLifespan = time.Duration(*allowedAudience.IDTokenSeconds) * time.Second
+		}
+
+		audiences[allowedAudience.Audience] = &tokenexchange.AllowedAudience{
+			Audience:        allowedAudience.Audience,
+			Conditions:      pipeline,
+			IDTokenLifespan: idTokenLifespan,
+		}
+	}
+
+	return audiences, validationErrorMessages
+}
+
 func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 	federationDomain *configv1alpha1.FederationDomain,
 	conditions []*metav1.Condition,
@@ -330,6 +502,8 @@ func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 	conditions = appendIdentityProviderObjectRefKindCondition(c.sortedAllowedKinds(), []string{}, conditions)
 	conditions = appendTransformsExpressionsValidCondition([]string{}, conditions)
 	conditions = appendTransformsExamplesPassedCondition([]string{}, conditions)
+	conditions = appendIdentityProvidersSecondFactorValidCondition([]string{}, conditions)
+	conditions = appendIdentityProvidersRoutingValidCondition([]string{}, conditions)
 
 	return federationDomainIssuer, conditions, nil
 }
@@ -346,6 +520,8 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 	duplicateDisplayNames := sets.Set[string]{}
 	badAPIGroupNames := []string{}
 	badKinds := []string{}
+	badSecondFactorMessages := []string{}
+	badRoutingMessages := []string{}
 	validationErrorMessages := &transformsValidationErrorMessages{}
 
 	for index, idp := range federationDomain.Spec.IdentityProviders {
@@ -406,6 +582,38 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 			idpIsValid = false
 		}
 
+		// A second factor can only be checked during logins which happen on the Supervisor's own login page,
+		// which is only used by LDAP and ActiveDirectory identity providers.
+		requireTOTP, requireWebAuthn := false, false
+		if idp.SecondFactor != nil {
+			switch {
+			case idp.ObjectRef.Kind != kindLDAPIdentityProvider && idp.ObjectRef.Kind != kindActiveDirectoryIdentityProvider:
+				badSecondFactorMessages = append(badSecondFactorMessages, fmt.Sprintf(
+					".spec.identityProviders[%d].secondFactor is only supported for identity providers of kind %s or %s",
+					index, kindActiveDirectoryIdentityProvider, kindLDAPIdentityProvider))
+				idpIsValid = false
+			case idp.SecondFactor.Type == "TOTP":
+				requireTOTP = true
+			case idp.SecondFactor.Type == "WebAuthn":
+				requireWebAuthn = true
+			default:
+				badSecondFactorMessages = append(badSecondFactorMessages, fmt.Sprintf(
+					".spec.identityProviders[%d].secondFactor.type %q is not supported", index, idp.SecondFactor.Type))
+				idpIsValid = false
+			}
+		}
+
+		var routing *idprouting.Rules
+		if idp.Routing != nil {
+			var err error
+			routing, err = idprouting.New(idp.Routing.EmailDomains, idp.Routing.UsernameRegex)
+			if err != nil {
+				badRoutingMessages = append(badRoutingMessages,
+					fmt.Sprintf(".spec.identityProviders[%d].routing.%s", index, err.Error()))
+				idpIsValid = false
+			}
+		}
+
 		if !idpIsValid {
 			// Something about the IDP was not valid. Don't add it.
 			continue
@@ -413,9 +621,12 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 
 		// For a valid IDP (unique displayName, valid objectRef, valid transforms), add it to the list.
 		federationDomainIdentityProviders = append(federationDomainIdentityProviders, &federationdomainproviders.FederationDomainIdentityProvider{
-			DisplayName: idp.DisplayName,
-			UID:         idpResourceUID,
-			Transforms:  pipeline,
+			DisplayName:     idp.DisplayName,
+			UID:             idpResourceUID,
+			Transforms:      pipeline,
+			RequireTOTP:     requireTOTP,
+			RequireWebAuthn: requireWebAuthn,
+			Routing:         routing,
 		})
 	}
 
@@ -430,6 +641,8 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 
 	conditions = appendTransformsExpressionsValidCondition(validationErrorMessages.errorsForExpressions, conditions)
 	conditions = appendTransformsExamplesPassedCondition(validationErrorMessages.errorsForExamples, conditions)
+	conditions = appendIdentityProvidersSecondFactorValidCondition(badSecondFactorMessages, conditions)
+	conditions = appendIdentityProvidersRoutingValidCondition(badRoutingMessages, conditions)
 
 	return federationDomainIssuer, conditions, nil
 }
@@ -468,12 +681,19 @@ func (c *federationDomainWatcherController) makeTransformationPipelineAndEvaluat
 	idpIndex int,
 	validationErrorMessages *transformsValidationErrorMessages,
 ) (*idtransform.TransformationPipeline, bool, error) {
-	consts, err := c.makeTransformsConstantsForIdentityProvider(idp)
+	paths := &transformpipeline.Paths{
+		ConstantsType:   "spec.identityProvider[].transforms.constants",
+		ExpressionsType: "spec.identityProvider[].transforms.expressions",
+		Expressions:     fmt.Sprintf("spec.identityProvider[%d].transforms.expressions", idpIndex),
+		Examples:        fmt.Sprintf(".spec.identityProviders[%d].transforms.examples", idpIndex),
+	}
+
+	consts, err := transformpipeline.MakeConstants(idp.Transforms.Constants, paths)
 	if err != nil {
 		return nil, false, err
 	}
 
-	pipeline, errorsForExpressions, err := c.makeTransformationPipelineForIdentityProvider(idp, idpIndex, consts)
+	pipeline, errorsForExpressions, err := c.makeTransformationPipelineForIdentityProvider(ctx, idp, idpIndex, consts, paths)
 	if err != nil {
 		return nil, false, err
 	}
@@ -481,7 +701,7 @@ func (c *federationDomainWatcherController) makeTransformationPipelineAndEvaluat
 		validationErrorMessages.errorsForExpressions = append(validationErrorMessages.errorsForExpressions, errorsForExpressions)
 	}
 
-	allExamplesPassed, errorsForExamples := c.evaluateExamplesForIdentityProvider(ctx, idp, idpIndex, pipeline)
+	allExamplesPassed, errorsForExamples := transformpipeline.EvaluateExamples(ctx, idp.Transforms.Examples, pipeline, paths)
 	if len(errorsForExamples) > 0 {
 		validationErrorMessages.errorsForExamples = append(validationErrorMessages.errorsForExamples, errorsForExamples)
 	}
@@ -489,68 +709,56 @@ func (c *federationDomainWatcherController) makeTransformationPipelineAndEvaluat
 	return pipeline, allExamplesPassed, nil
 }
 
-func (c *federationDomainWatcherController) makeTransformsConstantsForIdentityProvider(
-	idp configv1alpha1.FederationDomainIdentityProvider,
-) (*celtransformer.TransformationConstants, error) {
-	consts := &celtransformer.TransformationConstants{
-		StringConstants:     map[string]string{},
-		StringListConstants: map[string][]string{},
-	}
-	constNames := sets.Set[string]{}
-
-	// Read all the declared constants.
-	for _, constant := range idp.Transforms.Constants {
-		// The CRD requires the name field, and validates that it has at least one character,
-		// and validates that the names are unique within the list.
-		constNames.Insert(constant.Name)
-		switch constant.Type {
-		case "string":
-			consts.StringConstants[constant.Name] = constant.StringValue
-		case "stringList":
-			consts.StringListConstants[constant.Name] = constant.StringListValue
-		default:
-			// This shouldn't really happen since the CRD validates it, but handle it as an error.
-			return nil, fmt.Errorf("one of spec.identityProvider[].transforms.constants[].type is invalid: %q", constant.Type)
-		}
-	}
-
-	return consts, nil
-}
-
 func (c *federationDomainWatcherController) makeTransformationPipelineForIdentityProvider(
+	ctx context.Context,
 	idp configv1alpha1.FederationDomainIdentityProvider,
 	idpIndex int,
 	consts *celtransformer.TransformationConstants,
+	paths *transformpipeline.Paths,
 ) (*idtransform.TransformationPipeline, string, error) {
 	pipeline := idtransform.NewTransformationPipeline()
 	expressionsCompileErrors := []string{}
 
+	// The transformations of the libraries come first, in the order that the libraries were listed.
+	_, errorsForLibraries, err := c.identityTransformationLibraryCache.AppendLibraries(ctx,
+		c.identityTransformationLibraryInformer.Lister(), idp.Transforms.Libraries,
+		fmt.Sprintf("spec.identityProvider[%d].transforms.libraries", idpIndex), pipeline)
+	if err != nil {
+		return nil, "", err
+	}
+	expressionsCompileErrors = append(expressionsCompileErrors, errorsForLibraries...)
+
 	// Compile all the expressions and add them to the pipeline.
-	for exprIndex, expr := range idp.Transforms.Expressions {
-		var rawTransform celtransformer.CELTransformation
-		switch expr.Type {
-		case "username/v1":
-			rawTransform = &celtransformer.UsernameTransformation{Expression: expr.Expression}
-		case "groups/v1":
-			rawTransform = &celtransformer.GroupsTransformation{Expression: expr.Expression}
-		case "policy/v1":
-			rawTransform = &celtransformer.AllowAuthenticationPolicy{
-				Expression:                    expr.Expression,
-				RejectedAuthenticationMessage: expr.Message,
-			}
-		default:
-			// This shouldn't really happen since the CRD validates it, but handle it as an error.
-			return nil, "", fmt.Errorf("one of spec.identityProvider[].transforms.expressions[].type is invalid: %q", expr.Type)
-		}
+	errorsForExpressions, err := transformpipeline.CompileExpressions(c.celTransformer, idp.Transforms.Expressions, consts, pipeline, paths)
+	if err != nil {
+		return nil, "", err
+	}
+	expressionsCompileErrors = append(expressionsCompileErrors, errorsForExpressions...)
 
-		compiledTransform, err := c.celTransformer.CompileTransformation(rawTransform, consts)
+	// The access policy is evaluated after all the expressions, so it sees the transformed username and groups.
+	if idp.AccessPolicy != nil {
+		accessPolicy, err := transformpipeline.NewAccessPolicy(idp.AccessPolicy)
 		if err != nil {
 			expressionsCompileErrors = append(expressionsCompileErrors,
-				fmt.Sprintf("spec.identityProvider[%d].transforms.expressions[%d].expression was invalid:\n%s",
-					idpIndex, exprIndex, err.Error()))
+				fmt.Sprintf("spec.identityProvider[%d].accessPolicy was invalid:\n%s", idpIndex, err.Error()))
+		} else {
+			pipeline.AppendTransformation(accessPolicy)
 		}
+	}
 
-		pipeline.AppendTransformation(compiledTransform)
+	// The additional claims are computed from the result of all the transformations above.
+	for claimIndex, claim := range idp.Transforms.AdditionalClaims {
+		compiledClaim, err := c.celTransformer.CompileAdditionalClaim(&celtransformer.AdditionalClaimExpression{
+			Name:       claim.Name,
+			Expression: claim.Expression,
+		}, consts)
+		if err != nil {
+			expressionsCompileErrors = append(expressionsCompileErrors,
+				fmt.Sprintf("spec.identityProvider[%d].transforms.additionalClaims[%d].expression was invalid:\n%s",
+					idpIndex, claimIndex, err.Error()))
+			continue
+		}
+		pipeline.AppendAdditionalClaim(compiledClaim)
 	}
 
 	if len(expressionsCompileErrors) > 0 {
@@ -562,84 +770,6 @@ func (c *federationDomainWatcherController) makeTransformationPipelineForIdentit
 	return pipeline, "", nil
 }
 
-func (c *federationDomainWatcherController) evaluateExamplesForIdentityProvider(
-	ctx context.Context,
-	idp configv1alpha1.FederationDomainIdentityProvider,
-	idpIndex int,
-	pipeline *idtransform.TransformationPipeline,
-) (bool, string) {
-	const errorFmt = ".spec.identityProviders[%d].transforms.examples[%d] example failed:\nexpected: %s\nactual:   %s"
-	examplesErrors := []string{}
-
-	if pipeline == nil {
-		// Unable to evaluate the conditions where the pipeline of expressions was invalid.
-		return false, fmt.Sprintf(
-			"unable to check if the examples specified by .spec.identityProviders[%d].transforms.examples[] had errors because an expression was invalid",
-			idpIndex)
-	}
-
-	// Run all the provided transform examples. If any fail, put errors on the FederationDomain status.
-	for exIndex, e := range idp.Transforms.Examples {
-		result, err := pipeline.Evaluate(ctx, e.Username, e.Groups)
-		if err != nil {
-			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, idpIndex, exIndex,
-				"no transformation errors",
-				fmt.Sprintf("transformations resulted in an unexpected error %q", err.Error())))
-			continue
-		}
-		resultWasAuthRejected := !result.AuthenticationAllowed
-
-		if e.Expects.Rejected && !resultWasAuthRejected {
-			examplesErrors = append(examplesErrors,
-				fmt.Sprintf(errorFmt, idpIndex, exIndex, "authentication to be rejected", "authentication was not rejected"))
-			continue
-		}
-
-		if !e.Expects.Rejected && resultWasAuthRejected {
-			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, idpIndex, exIndex,
-				"authentication not to be rejected",
-				fmt.Sprintf("authentication was rejected with message %q", result.RejectedAuthenticationMessage)))
-			continue
-		}
-
-		expectedRejectionMessage := e.Expects.Message
-		if len(expectedRejectionMessage) == 0 {
-			expectedRejectionMessage = celtransformer.DefaultPolicyRejectedAuthMessage
-		}
-		if e.Expects.Rejected && resultWasAuthRejected && expectedRejectionMessage != result.RejectedAuthenticationMessage {
-			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, idpIndex, exIndex,
-				fmt.Sprintf("authentication rejection message %q", expectedRejectionMessage),
-				fmt.Sprintf("authentication rejection message %q", result.RejectedAuthenticationMessage)))
-			continue
-		}
-
-		if result.AuthenticationAllowed {
-			// In the case where the user expected the auth to be allowed and it was allowed, then compare
-			// the expected username and group names to the actual username and group names.
-			if e.Expects.Username != result.Username {
-				examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, idpIndex, exIndex,
-					fmt.Sprintf("username %q", e.Expects.Username),
-					fmt.Sprintf("username %q", result.Username)))
-			}
-			expectedGroups := e.Expects.Groups
-			if expectedGroups == nil {
-				expectedGroups = []string{}
-			}
-			if !stringSetsEqual(expectedGroups, result.Groups) {
-				examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, idpIndex, exIndex,
-					fmt.Sprintf("groups [%s]", strings.Join(sortAndQuote(expectedGroups), ", ")),
-					fmt.Sprintf("groups [%s]", strings.Join(sortAndQuote(result.Groups), ", "))))
-			}
-		}
-	}
-
-	if len(examplesErrors) > 0 {
-		return false, strings.Join(examplesErrors, "\n\n")
-	}
-
-	return true, ""
-}
-
 func appendIdentityProviderObjectRefKindCondition(expectedKinds []string, badSuffixNames []string, conditions []*metav1.Condition) []*metav1.Condition {
 	if len(badSuffixNames) > 0 {
 		conditions = append(conditions, &metav1.Condition{
@@ -746,6 +876,63 @@ func appendTransformsExamplesPassedCondition(messages []string, conditions []*me
 	return conditions
 }
 
+func appendIdentityProvidersSecondFactorValidCondition(messages []string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(messages) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeIdentityProvidersSecondFactorValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonSecondFactorUnsupported,
+			Message: strings.Join(messages, "\n\n"),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeIdentityProvidersSecondFactorValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the second factors specified by .spec.identityProviders[].secondFactor are supported",
+		})
+	}
+	return conditions
+}
+
+func appendIdentityProvidersRoutingValidCondition(messages []string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(messages) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeIdentityProvidersRoutingValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidRouting,
+			Message: strings.Join(messages, "\n\n"),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeIdentityProvidersRoutingValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the routing rules specified by .spec.identityProviders[].routing are valid",
+		})
+	}
+	return conditions
+}
+
+func appendTokenExchangeAudiencesValidCondition(messages []string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(messages) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeTokenExchangeAudiencesValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidTokenExchangeAudiences,
+			Message: strings.Join(messages, "\n\n"),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeTokenExchangeAudiencesValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the audiences specified by .spec.tokenExchange.allowedAudiences[] are valid",
+		})
+	}
+	return conditions
+}
+
 func appendIdentityProviderDuplicateDisplayNamesCondition(duplicateDisplayNames sets.Set[string], conditions []*metav1.Condition) []*metav1.Condition {
 	if duplicateDisplayNames.Len() > 0 {
 		conditions = append(conditions, &metav1.Condition{
@@ -949,9 +1136,3 @@ func newCrossFederationDomainConfigValidator(federationDomains []*configv1alpha1
 		uniqueSecretNamesPerIssuerAddress: uniqueSecretNamesPerIssuerAddress,
 	}
 }
-
-func stringSetsEqual(a []string, b []string) bool {
-	aSet := sets.New(a...)
-	bSet := sets.New(b...)
-	return aSet.Equal(bSet)
-}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorconfig
@@ -18,6 +18,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	k8sinformers "k8s.io/client-go/informers"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
 	coretesting "k8s.io/client-go/testing"
 	clocktesting "k8s.io/utils/clock/testing"
 	"k8s.io/utils/ptr"
@@ -28,7 +30,11 @@ import (
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
 	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/endpoints/tokenexchange"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/testutil"
@@ -42,6 +48,8 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 	oidcIdentityProviderInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().OIDCIdentityProviders()
 	ldapIdentityProviderInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().LDAPIdentityProviders()
 	adIdentityProviderInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().ActiveDirectoryIdentityProviders()
+	identityTransformationLibraryInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().IdentityTransformationLibraries()
+	configMapInformer := k8sinformers.NewSharedInformerFactoryWithOptions(nil, 0).Core().V1().ConfigMaps()
 
 	tests := []struct {
 		name       string
@@ -83,6 +91,22 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 			wantUpdate: false,
 			wantDelete: true,
 		},
+		{
+			name:       "any IdentityTransformationLibrary changes",
+			obj:        &configv1alpha1.IdentityTransformationLibrary{},
+			informer:   identityTransformationLibraryInformer,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name:       "any ConfigMap changes",
+			obj:        &corev1.ConfigMap{},
+			informer:   configMapInformer,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
 	}
 	for _, test := range tests {
 		test := test
@@ -100,6 +124,9 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 				oidcIdentityProviderInformer,
 				ldapIdentityProviderInformer,
 				adIdentityProviderInformer,
+				identityTransformationLibraryInformer,
+				configMapInformer,
+				nil,
 				withInformer.WithInformer, // make it possible to observe the behavior of the Filters
 			)
 
@@ -195,6 +222,16 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		return fdIssuer
 	}
 
+	federationDomainIssuerWithTokenExchangeAudiences := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, audiences tokenexchange.AllowedAudiences) *federationdomainproviders.FederationDomainIssuer {
+		fdIssuer.SetTokenExchangeAudiences(audiences)
+		return fdIssuer
+	}
+
+	federationDomainIssuerWithBranding := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, b *branding.Branding) *federationdomainproviders.FederationDomainIssuer {
+		fdIssuer.SetBranding(b)
+		return fdIssuer
+	}
+
 	happyReadyCondition := func(issuer string, time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "Ready",
@@ -445,6 +482,105 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	happyTokenExchangeAudiencesCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "TokenExchangeAudiencesValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "the audiences specified by .spec.tokenExchange.allowedAudiences[] are valid",
+		}
+	}
+
+	sadTokenExchangeAudiencesCondition := func(errorMessages string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "TokenExchangeAudiencesValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "InvalidTokenExchangeAudiences",
+			Message:            errorMessages,
+		}
+	}
+
+	happyBrandingCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "BrandingValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "no branding was specified by .spec.branding: using the default pages",
+		}
+	}
+
+	happyBrandingConditionWithConfigMap := func(configMapName string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "BrandingValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            fmt.Sprintf("the ConfigMap specified by .spec.branding.configMapName %q is valid", configMapName),
+		}
+	}
+
+	sadBrandingCondition := func(reason string, errorMessage string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "BrandingValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             reason,
+			Message:            errorMessage,
+		}
+	}
+
+	happySecondFactorCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "IdentityProvidersSecondFactorValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "the second factors specified by .spec.identityProviders[].secondFactor are supported",
+		}
+	}
+
+	sadSecondFactorCondition := func(errorMessages string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "IdentityProvidersSecondFactorValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "SecondFactorUnsupported",
+			Message:            errorMessages,
+		}
+	}
+
+	happyRoutingCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "IdentityProvidersRoutingValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "the routing rules specified by .spec.identityProviders[].routing are valid",
+		}
+	}
+
+	sadRoutingCondition := func(errorMessages string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "IdentityProvidersRoutingValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "InvalidRouting",
+			Message:            errorMessages,
+		}
+	}
+
 	happyAPIGroupSuffixCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "IdentityProvidersObjectRefAPIGroupSuffixValid",
@@ -493,6 +629,10 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 
 	allHappyConditionsSuccess := func(issuer string, time metav1.Time, observedGeneration int64) []metav1.Condition {
 		return conditionstestutil.SortByType([]metav1.Condition{
+			happyTokenExchangeAudiencesCondition(frozenMetav1Now, 123),
+			happyBrandingCondition(frozenMetav1Now, 123),
+			happySecondFactorCondition(frozenMetav1Now, 123),
+			happyRoutingCondition(frozenMetav1Now, 123),
 			happyTransformationExamplesCondition(frozenMetav1Now, 123),
 			happyTransformationExpressionsCondition(frozenMetav1Now, 123),
 			happyKindCondition(frozenMetav1Now, 123),
@@ -522,6 +662,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 	tests := []struct {
 		name              string
 		inputObjects      []runtime.Object
+		inputKubeObjects  []runtime.Object
 		configClient      func(*pinnipedfake.Clientset)
 		wantErr           string
 		wantStatusUpdates []*configv1alpha1.FederationDomain
@@ -1361,6 +1502,127 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "the federation domain has an access policy with username patterns which don't compile",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AccessPolicy: &configv1alpha1.FederationDomainAccessPolicy{
+									AllowedUsernamePatterns: []string{"ryan", "(", "[a-z]+"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTransformationExpressionsCondition(here.Doc(
+								"spec.identityProvider[0].accessPolicy was invalid:\n"+
+									"allowedUsernamePatterns[1] \"(\" is invalid: error parsing regexp: missing closing ): `^(?:()$`",
+							), frozenMetav1Now, 123),
+							sadTransformationExamplesCondition(
+								"unable to check if the examples specified by .spec.identityProviders[0].transforms.examples[] had errors because an expression was invalid",
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has an access policy which causes transformation examples to fail",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Examples: []configv1alpha1.FederationDomainTransformsExample{
+										{ // should pass
+											Username: "ryan",
+											Groups:   []string{"admins"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "ryan",
+												Groups:   []string{"admins"},
+											},
+										},
+										{ // should fail because the user is not in an allowed group
+											Username: "josh",
+											Groups:   []string{"devs"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "josh",
+												Groups:   []string{"devs"},
+											},
+										},
+										{ // should fail because the rejection message is not the default message
+											Username: "josh",
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Rejected: true,
+											},
+										},
+									},
+								},
+								AccessPolicy: &configv1alpha1.FederationDomainAccessPolicy{
+									AllowedGroups:    []string{"admins"},
+									RejectionMessage: "only admins may log in",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTransformationExamplesCondition(here.Doc(
+								`.spec.identityProviders[0].transforms.examples[1] example failed:
+								 expected: authentication not to be rejected
+								 actual:   authentication was rejected with message "only admins may log in"
+
+								 .spec.identityProviders[0].transforms.examples[2] example failed:
+								 expected: authentication rejection message "authentication was rejected by a configured policy"
+								 actual:   authentication rejection message "only admins may log in"`,
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
 		{
 			name: "the federation domain has transformation examples which don't pass",
 			inputObjects: []runtime.Object{
@@ -1525,6 +1787,85 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "the federation domain has transformation examples which use upstream claims",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+										{Type: "policy/v1", Expression: `upstreamClaims.email_verified == true`, Message: "email not verified"},
+										{Type: "username/v1", Expression: `upstreamClaims.email`},
+									},
+									Examples: []configv1alpha1.FederationDomainTransformsExample{
+										{ // this example should pass
+											Username:       "ryan",
+											UpstreamClaims: &runtime.RawExtension{Raw: []byte(`{"email":"ryan@example.com","email_verified":true}`)},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "ryan@example.com",
+											},
+										},
+										{ // this example should pass
+											Username:       "ryan",
+											UpstreamClaims: &runtime.RawExtension{Raw: []byte(`{"email":"ryan@example.com","email_verified":false}`)},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Rejected: true,
+												Message:  "email not verified",
+											},
+										},
+										{ // this example should fail because it does not provide the upstream claims used by the expressions
+											Username: "ryan",
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "ryan@example.com",
+											},
+										},
+										{ // this example should fail because its upstream claims are not a JSON object, which shouldn't really happen since the CRD validates it
+											Username:       "ryan",
+											UpstreamClaims: &runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "ryan@example.com",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTransformationExamplesCondition(here.Doc(
+								`.spec.identityProviders[0].transforms.examples[2] example failed:
+								 expected: no transformation errors
+								 actual:   transformations resulted in an unexpected error "identity transformation at index 0: no such key: email_verified"
+
+								 .spec.identityProviders[0].transforms.examples[3].upstreamClaims could not be read as a JSON object: json: cannot unmarshal array into Go value of type idtransform.UpstreamClaims`,
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
 		{
 			name: "the federation domain has transformation expressions that return illegal values with examples which exercise them",
 			inputObjects: []runtime.Object{
@@ -1937,7 +2278,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 			},
 		},
 		{
-			name: "the federation domain specifies illegal const type, which shouldn't really happen since the CRD validates it",
+			name: "the federation domain has an access policy which is evaluated after the transformation expressions",
 			inputObjects: []runtime.Object{
 				oidcIdentityProvider,
 				&configv1alpha1.FederationDomain{
@@ -1946,19 +2287,938 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 						Issuer: "https://issuer1.com",
 						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
 							{
-								DisplayName: "can-find-me",
+								DisplayName: "name1",
 								ObjectRef: corev1.TypedLocalObjectReference{
 									APIGroup: ptr.To(apiGroupSupervisor),
 									Kind:     "OIDCIdentityProvider",
 									Name:     oidcIdentityProvider.Name,
 								},
 								Transforms: configv1alpha1.FederationDomainTransforms{
-									Constants: []configv1alpha1.FederationDomainTransformsConstant{
-										{
-											Type: "this is illegal",
-										},
+									Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+										{Type: "groups/v1", Expression: `groups.map(g, "pre:" + g)`},
 									},
-								},
+									Examples: []configv1alpha1.FederationDomainTransformsExample{
+										{
+											Username: "ryan",
+											Groups:   []string{"admins"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "ryan",
+												Groups:   []string{"pre:admins"},
+											},
+										},
+										{
+											Username: "ryan",
+											Groups:   []string{"admins", "suspended"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Rejected: true,
+												Message:  "you may not log in",
+											},
+										},
+										{
+											Username: "someone@example.com",
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "someone@example.com",
+											},
+										},
+										{
+											Username: "someone@example.com.other.com",
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Rejected: true,
+												Message:  "you may not log in",
+											},
+										},
+									},
+								},
+								AccessPolicy: &configv1alpha1.FederationDomainAccessPolicy{
+									AllowedGroups:           []string{"pre:admins"},
+									DeniedGroups:            []string{"pre:suspended"},
+									AllowedUsernamePatterns: []string{`.*@example\.com`},
+									RejectionMessage:        "you may not log in",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         oidcIdentityProvider.UID,
+						Transforms: withAccessPolicy(t,
+							newTransformationPipeline(t, &celtransformer.TransformationConstants{},
+								&celtransformer.GroupsTransformation{Expression: `groups.map(g, "pre:" + g)`},
+							),
+							&idtransform.AccessPolicySource{
+								AllowedGroups:                 []string{"pre:admins"},
+								DeniedGroups:                  []string{"pre:suspended"},
+								AllowedUsernamePatterns:       []string{`.*@example\.com`},
+								RejectedAuthenticationMessage: "you may not log in",
+							},
+						),
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has an access policy without a rejection message",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AccessPolicy: &configv1alpha1.FederationDomainAccessPolicy{
+									DeniedGroups: []string{"suspended"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         oidcIdentityProvider.UID,
+						Transforms: withAccessPolicy(t,
+							idtransform.NewTransformationPipeline(),
+							&idtransform.AccessPolicySource{
+								DeniedGroups:                  []string{"suspended"},
+								RejectedAuthenticationMessage: "authentication was rejected by a configured policy",
+							},
+						),
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain requires a TOTP or WebAuthn second factor for LDAP and ActiveDirectory identity providers",
+			inputObjects: []runtime.Object{
+				ldapIdentityProvider,
+				adIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								SecondFactor: &configv1alpha1.FederationDomainSecondFactor{Type: "TOTP"},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "ActiveDirectoryIdentityProvider",
+									Name:     adIdentityProvider.Name,
+								},
+								SecondFactor: &configv1alpha1.FederationDomainSecondFactor{Type: "WebAuthn"},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         ldapIdentityProvider.UID,
+						Transforms:  idtransform.NewTransformationPipeline(),
+						RequireTOTP: true,
+					},
+					{
+						DisplayName:     "name2",
+						UID:             adIdentityProvider.UID,
+						Transforms:      idtransform.NewTransformationPipeline(),
+						RequireWebAuthn: true,
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain requires a second factor for an OIDC identity provider, which is not supported",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				ldapIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								SecondFactor: &configv1alpha1.FederationDomainSecondFactor{Type: "TOTP"},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								SecondFactor: &configv1alpha1.FederationDomainSecondFactor{Type: "SMS"},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadSecondFactorCondition(here.Doc(
+								`.spec.identityProviders[0].secondFactor is only supported for identity providers of kind ActiveDirectoryIdentityProvider or LDAPIdentityProvider
+
+								 .spec.identityProviders[1].secondFactor.type "SMS" is not supported`,
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has routing rules for its identity providers",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				ldapIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Routing: &configv1alpha1.FederationDomainRouting{
+									EmailDomains: []string{"example.com", "Corp.Example.com"},
+								},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								Routing: &configv1alpha1.FederationDomainRouting{
+									UsernameRegex: `[a-z]+\.admin`,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         oidcIdentityProvider.UID,
+						Transforms:  idtransform.NewTransformationPipeline(),
+						Routing:     mustIDPRouting(t, []string{"example.com", "corp.example.com"}, ""),
+					},
+					{
+						DisplayName: "name2",
+						UID:         ldapIdentityProvider.UID,
+						Transforms:  idtransform.NewTransformationPipeline(),
+						Routing:     mustIDPRouting(t, nil, `[a-z]+\.admin`),
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has invalid routing rules for its identity providers",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				ldapIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Routing: &configv1alpha1.FederationDomainRouting{
+									EmailDomains: []string{"example.com", "not a domain"},
+								},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								Routing: &configv1alpha1.FederationDomainRouting{
+									UsernameRegex: `admin(`,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadRoutingCondition(here.Doc(
+								`.spec.identityProviders[0].routing.emailDomains[1] "not a domain" is not a valid domain name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')
+
+								 .spec.identityProviders[1].routing.usernameRegex is not a valid regular expression: error parsing regexp: missing closing ): `+"`^(?:admin()$`",
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has additional claims",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Constants: []configv1alpha1.FederationDomainTransformsConstant{
+										{Name: "tenant", Type: "string", StringValue: "acme"},
+									},
+									Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+										{Type: "username/v1", Expression: `"pre:" + username`},
+									},
+									AdditionalClaims: []configv1alpha1.FederationDomainTransformsAdditionalClaim{
+										{Name: "email", Expression: `upstreamClaims.email`},
+										{Name: "tenantID", Expression: `strConst.tenant + ":" + username`},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         oidcIdentityProvider.UID,
+						Transforms: withAdditionalClaims(t,
+							newTransformationPipeline(t,
+								&celtransformer.TransformationConstants{StringConstants: map[string]string{"tenant": "acme"}},
+								&celtransformer.UsernameTransformation{Expression: `"pre:" + username`},
+							),
+							&celtransformer.TransformationConstants{
+								StringConstants:     map[string]string{"tenant": "acme"},
+								StringListConstants: map[string][]string{},
+							},
+							&celtransformer.AdditionalClaimExpression{Name: "email", Expression: `upstreamClaims.email`},
+							&celtransformer.AdditionalClaimExpression{Name: "tenantID", Expression: `strConst.tenant + ":" + username`},
+						),
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has additional claims which don't compile",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									AdditionalClaims: []configv1alpha1.FederationDomainTransformsAdditionalClaim{
+										{Name: "email", Expression: `upstreamClaims.email`},
+										{Name: "broken", Expression: `this is not valid cel`},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTransformationExpressionsCondition(here.Doc(
+								"spec.identityProvider[0].transforms.additionalClaims[1].expression was invalid:\n"+
+									"CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>\n"+
+									" | this is not valid cel\n"+
+									" | .....^",
+							), frozenMetav1Now, 123),
+							sadTransformationExamplesCondition(
+								"unable to check if the examples specified by .spec.identityProviders[0].transforms.examples[] had errors because an expression was invalid",
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has valid token exchange audiences",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						TokenExchange: configv1alpha1.FederationDomainTokenExchange{
+							AllowedAudiences: []configv1alpha1.FederationDomainTokenExchangeAudience{
+								{Audience: "cluster-1"},
+								{
+									Audience: "cluster-2",
+									Conditions: []configv1alpha1.FederationDomainTokenExchangeCondition{
+										{Expression: `"admins" in groups`, Message: "only admins may access cluster-2"},
+										{Expression: `username != "ryan"`},
+									},
+									IDTokenSeconds: ptr.To[int32](600),
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithTokenExchangeAudiences(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					tokenexchange.AllowedAudiences{
+						"cluster-1": {
+							Audience:   "cluster-1",
+							Conditions: idtransform.NewTransformationPipeline(),
+						},
+						"cluster-2": {
+							Audience: "cluster-2",
+							Conditions: newTransformationPipeline(t, &celtransformer.TransformationConstants{},
+								&celtransformer.AllowAuthenticationPolicy{
+									Expression:                    `"admins" in groups`,
+									RejectedAuthenticationMessage: "only admins may access cluster-2",
+								},
+								&celtransformer.AllowAuthenticationPolicy{
+									Expression:                    `username != "ryan"`,
+									RejectedAuthenticationMessage: "token exchange was rejected by configuration",
+								},
+							),
+							IDTokenLifespan: 10 * time.Minute,
+						},
+					},
+				),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has token exchange audiences which are reserved or have conditions which don't compile",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						TokenExchange: configv1alpha1.FederationDomainTokenExchange{
+							AllowedAudiences: []configv1alpha1.FederationDomainTokenExchangeAudience{
+								{Audience: "pinniped-cli"},
+								{Audience: "client.oauth.pinniped.dev-some-client"},
+								{
+									Audience: "cluster-1",
+									Conditions: []configv1alpha1.FederationDomainTokenExchangeCondition{
+										{Expression: `username == "ryan"`}, // valid
+										{Expression: "this is not a valid cel expression"},
+									},
+								},
+								{Audience: "cluster-2"}, // valid
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTokenExchangeAudiencesCondition(here.Doc(
+								`spec.tokenExchange.allowedAudiences[0].audience "pinniped-cli" is reserved by Pinniped
+
+								 spec.tokenExchange.allowedAudiences[1].audience "client.oauth.pinniped.dev-some-client" is reserved by Pinniped
+
+								 spec.tokenExchange.allowedAudiences[2].conditions[1].expression was invalid:
+								 CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>
+								  | this is not a valid cel expression
+								  | .....^`,
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has a valid branding ConfigMap",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &configv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-branding", Namespace: namespace},
+					Data:       map[string]string{"title": "Acme Corp", "primaryColor": "#1a73e8"},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithBranding(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					mustBrandingFromConfigMap(t, &corev1.ConfigMap{
+						Data: map[string]string{"title": "Acme Corp", "primaryColor": "#1a73e8"},
+					}),
+				),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							happyBrandingConditionWithConfigMap("some-branding", frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has a branding ConfigMap which does not exist",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &configv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-branding", Namespace: "other-namespace"},
+					Data:       map[string]string{"title": "Acme Corp"},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("BrandingConfigMapNotFound",
+								`the ConfigMap specified by .spec.branding.configMapName "some-branding" was not found`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has invalid branding ConfigMaps",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &configv1alpha1.FederationDomainBranding{ConfigMapName: "bad-color"},
+					},
+				},
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config2", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer2.com",
+						Branding: &configv1alpha1.FederationDomainBranding{ConfigMapName: "bad-template"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "bad-color", Namespace: namespace},
+					Data:       map[string]string{"primaryColor": "blue"},
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "bad-template", Namespace: namespace},
+					Data:       map[string]string{"loginTemplate": "<p>{{.NoSuchField}}</p>"},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("InvalidBranding",
+								`the ConfigMap specified by .spec.branding.configMapName "bad-color" is invalid: `+
+									`"primaryColor" must be a color in CSS hex notation, e.g. "#1a73e8"`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config2", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer2.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("InvalidBranding",
+								`the ConfigMap specified by .spec.branding.configMapName "bad-template" is invalid: `+
+									`could not render "loginTemplate": template: loginTemplate:1:5: executing "loginTemplate" at <.NoSuchField>: `+
+									`can't evaluate field NoSuchField in type *loginhtml.PageData`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain uses identity transformation libraries, which are evaluated before its own expressions",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "prefix-usernames", UID: "lib-uid-1", Generation: 3},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Constants: []configv1alpha1.FederationDomainTransformsConstant{
+							{Name: "prefix", Type: "string", StringValue: "corp:"},
+						},
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "username/v1", Expression: `strConst.prefix + username`},
+						},
+						Examples: []configv1alpha1.FederationDomainTransformsExample{
+							{
+								Username: "ryan",
+								Expects:  configv1alpha1.FederationDomainTransformsExampleExpects{Username: "corp:ryan"},
+							},
+						},
+					},
+				},
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "reject-suspended", UID: "lib-uid-2", Generation: 1},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "policy/v1", Expression: `!("suspended" in groups)`, Message: "suspended"},
+						},
+					},
+				},
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Libraries: []string{"prefix-usernames", "reject-suspended"},
+									Constants: []configv1alpha1.FederationDomainTransformsConstant{
+										{Name: "prefix", Type: "string", StringValue: "pre:"},
+									},
+									Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+										{Type: "groups/v1", Expression: `groups.map(g, strConst.prefix + g)`},
+									},
+									Examples: []configv1alpha1.FederationDomainTransformsExample{
+										{
+											Username: "ryan",
+											Groups:   []string{"admins"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Username: "corp:ryan",
+												Groups:   []string{"pre:admins"},
+											},
+										},
+										{
+											Username: "ryan",
+											Groups:   []string{"admins", "suspended"},
+											Expects: configv1alpha1.FederationDomainTransformsExampleExpects{
+												Rejected: true,
+												Message:  "suspended",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName: "name1",
+						UID:         oidcIdentityProvider.UID,
+						Transforms: func() *idtransform.TransformationPipeline {
+							pipeline := newTransformationPipeline(t,
+								&celtransformer.TransformationConstants{StringConstants: map[string]string{"prefix": "corp:"}},
+								&celtransformer.UsernameTransformation{Expression: `strConst.prefix + username`},
+							)
+							pipeline.AppendTransformationPipeline(newTransformationPipeline(t, &celtransformer.TransformationConstants{},
+								&celtransformer.AllowAuthenticationPolicy{Expression: `!("suspended" in groups)`, RejectedAuthenticationMessage: "suspended"},
+							))
+							pipeline.AppendTransformationPipeline(newTransformationPipeline(t,
+								&celtransformer.TransformationConstants{StringConstants: map[string]string{"prefix": "pre:"}},
+								&celtransformer.GroupsTransformation{Expression: `groups.map(g, strConst.prefix + g)`},
+							))
+							return pipeline
+						}(),
+					},
+				}),
+			},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain uses identity transformation libraries which are not found or are not valid",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				ldapIdentityProvider,
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "invalid-library", UID: "lib-uid-1", Generation: 1},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "username/v1", Expression: `this is not a valid cel expression`},
+						},
+					},
+				},
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "library-with-failing-examples", UID: "lib-uid-2", Generation: 1},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "username/v1", Expression: `"pre:" + username`},
+						},
+						Examples: []configv1alpha1.FederationDomainTransformsExample{
+							{
+								Username: "ryan",
+								Expects:  configv1alpha1.FederationDomainTransformsExampleExpects{Username: "ryan"},
+							},
+						},
+					},
+				},
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Libraries: []string{"library-which-does-not-exist", "invalid-library"},
+								},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Libraries: []string{"library-with-failing-examples"},
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*configv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&configv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					configv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadTransformationExpressionsCondition(
+								`spec.identityProvider[0].transforms.libraries[0] refers to an IdentityTransformationLibrary named "library-which-does-not-exist" which was not found`+"\n\n"+
+									`spec.identityProvider[0].transforms.libraries[1] refers to an IdentityTransformationLibrary named "invalid-library" which is not valid: see its status for details`+"\n\n"+
+									`spec.identityProvider[1].transforms.libraries[0] refers to an IdentityTransformationLibrary named "library-with-failing-examples" which is not valid: see its status for details`,
+								frozenMetav1Now, 123),
+							sadTransformationExamplesCondition(
+								"unable to check if the examples specified by .spec.identityProviders[0].transforms.examples[] had errors because an expression was invalid\n\n"+
+									"unable to check if the examples specified by .spec.identityProviders[1].transforms.examples[] had errors because an expression was invalid",
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain specifies illegal const type, which shouldn't really happen since the CRD validates it",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&configv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: configv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "can-find-me",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								Transforms: configv1alpha1.FederationDomainTransforms{
+									Constants: []configv1alpha1.FederationDomainTransformsConstant{
+										{
+											Type: "this is illegal",
+										},
+									},
+								},
 							},
 						},
 					},
@@ -2014,6 +3274,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				tt.configClient(pinnipedAPIClient)
 			}
 			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+			kubeInformers := k8sinformers.NewSharedInformerFactory(kubernetesfake.NewSimpleClientset(tt.inputKubeObjects...), 0)
 
 			controller := NewFederationDomainWatcherController(
 				federationDomainsSetter,
@@ -2024,6 +3285,9 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				pinnipedInformers.IDP().V1alpha1().OIDCIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
+				pinnipedInformers.Config().V1alpha1().IdentityTransformationLibraries(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				transformpipeline.NewLibraryCache(),
 				controllerlib.WithInformer,
 			)
 
@@ -2031,6 +3295,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 			defer cancel()
 
 			pinnipedInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
 			controllerlib.TestRunSynchronously(t, controller)
 
 			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{Namespace: namespace, Name: "config-name"}}
@@ -2076,12 +3341,35 @@ type comparableFederationDomainIssuer struct {
 	issuer                  string
 	identityProviders       []*comparableFederationDomainIdentityProvider
 	defaultIdentityProvider *comparableFederationDomainIdentityProvider
+	tokenExchangeAudiences  map[string]*comparableTokenExchangeAudience
+	branding                *branding.Branding
+}
+
+func mustIDPRouting(t *testing.T, emailDomains []string, usernameRegex string) *idprouting.Rules {
+	t.Helper()
+	rules, err := idprouting.New(emailDomains, usernameRegex)
+	require.NoError(t, err)
+	return rules
+}
+
+func mustBrandingFromConfigMap(t *testing.T, configMap *corev1.ConfigMap) *branding.Branding {
+	t.Helper()
+	b, err := branding.FromConfigMap(configMap)
+	require.NoError(t, err)
+	return b
+}
+
+type comparableTokenExchangeAudience struct {
+	Audience         string
+	ConditionsSource []interface{}
+	IDTokenLifespan  time.Duration
 }
 
 type comparableFederationDomainIdentityProvider struct {
 	DisplayName      string
 	UID              types.UID
 	TransformsSource []interface{}
+	Routing          *idprouting.Rules
 }
 
 func makeFederationDomainIdentityProviderComparable(fdi *federationdomainproviders.FederationDomainIdentityProvider) *comparableFederationDomainIdentityProvider {
@@ -2092,6 +3380,7 @@ func makeFederationDomainIdentityProviderComparable(fdi *federationdomainprovide
 		DisplayName:      fdi.DisplayName,
 		UID:              fdi.UID,
 		TransformsSource: fdi.Transforms.Source(),
+		Routing:          fdi.Routing,
 	}
 }
 
@@ -2106,6 +3395,17 @@ func convertToComparableType(fdis []*federationdomainproviders.FederationDomainI
 			issuer:                  fdi.Issuer(),
 			identityProviders:       comparableFDIs,
 			defaultIdentityProvider: makeFederationDomainIdentityProviderComparable(fdi.DefaultIdentityProvider()),
+			branding:                fdi.Branding(),
+		}
+		if fdi.TokenExchangeAudiences() != nil {
+			converted.tokenExchangeAudiences = map[string]*comparableTokenExchangeAudience{}
+			for name, audience := range fdi.TokenExchangeAudiences() {
+				converted.tokenExchangeAudiences[name] = &comparableTokenExchangeAudience{
+					Audience:         audience.Audience,
+					ConditionsSource: audience.Conditions.Source(),
+					IDTokenLifespan:  audience.IDTokenLifespan,
+				}
+			}
 		}
 		result = append(result, converted)
 	}
@@ -2185,6 +3485,34 @@ func newTransformationPipeline(
 	return pipeline
 }
 
+func withAccessPolicy(
+	t *testing.T,
+	pipeline *idtransform.TransformationPipeline,
+	source *idtransform.AccessPolicySource,
+) *idtransform.TransformationPipeline {
+	accessPolicy, err := idtransform.NewAccessPolicy(source)
+	require.NoError(t, err)
+	pipeline.AppendTransformation(accessPolicy)
+	return pipeline
+}
+
+func withAdditionalClaims(
+	t *testing.T,
+	pipeline *idtransform.TransformationPipeline,
+	consts *celtransformer.TransformationConstants,
+	additionalClaims ...*celtransformer.AdditionalClaimExpression,
+) *idtransform.TransformationPipeline {
+	compiler, err := celtransformer.NewCELTransformer(celTransformerMaxExpressionRuntime)
+	require.NoError(t, err)
+
+	for _, additionalClaim := range additionalClaims {
+		compiledClaim, err := compiler.CompileAdditionalClaim(additionalClaim, consts)
+		require.NoError(t, err)
+		pipeline.AppendAdditionalClaim(compiledClaim)
+	}
+	return pipeline
+}
+
 func TestTransformationPipelinesCanBeTestedForEqualityUsingSourceToMakeTestingEasier(t *testing.T) {
 	compiler, err := celtransformer.NewCELTransformer(5 * time.Second)
 	require.NoError(t, err)
@@ -0,0 +1,212 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	errorsutil "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/clock"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	configinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	identityTransformationLibraryControllerName = "IdentityTransformationLibraryWatcherController"
+
+	typeExpressionsValid = "ExpressionsValid"
+	typeExamplesPassed   = "ExamplesPassed"
+
+	reasonInvalidExpressions = "InvalidExpressions"
+	reasonExamplesFailed     = "ExamplesFailed"
+)
+
+type identityTransformationLibraryWatcherController struct {
+	clock  clock.Clock
+	client supervisorclientset.Interface
+
+	identityTransformationLibraryInformer configinformers.IdentityTransformationLibraryInformer
+	federationDomainInformer              configinformers.FederationDomainInformer
+
+	identityTransformationLibraryCache *transformpipeline.LibraryCache
+}
+
+// NewIdentityTransformationLibraryWatcherController creates a controllerlib.Controller that watches
+// IdentityTransformationLibrary objects and updates their status with validation errors and with the
+// names of the FederationDomains which use them.
+func NewIdentityTransformationLibraryWatcherController(
+	clock clock.Clock,
+	client supervisorclientset.Interface,
+	identityTransformationLibraryInformer configinformers.IdentityTransformationLibraryInformer,
+	federationDomainInformer configinformers.FederationDomainInformer,
+	identityTransformationLibraryCache *transformpipeline.LibraryCache,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: identityTransformationLibraryControllerName,
+			Syncer: &identityTransformationLibraryWatcherController{
+				clock:                                 clock,
+				client:                                client,
+				identityTransformationLibraryInformer: identityTransformationLibraryInformer,
+				federationDomainInformer:              federationDomainInformer,
+				identityTransformationLibraryCache:    identityTransformationLibraryCache,
+			},
+		},
+		withInformer(
+			identityTransformationLibraryInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			federationDomainInformer,
+			// Any FederationDomain could start or stop using a library.
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *identityTransformationLibraryWatcherController) Sync(ctx controllerlib.Context) error {
+	libraries, err := c.identityTransformationLibraryInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	federationDomains, err := c.federationDomainInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return err
+	}
+
+	// Find which FederationDomains use each library.
+	consumers := map[string]sets.Set[string]{}
+	for _, federationDomain := range federationDomains {
+		for _, idp := range federationDomain.Spec.IdentityProviders {
+			for _, libName := range idp.Transforms.Libraries {
+				if consumers[libName] == nil {
+					consumers[libName] = sets.New[string]()
+				}
+				consumers[libName].Insert(federationDomain.Name)
+			}
+		}
+	}
+
+	c.identityTransformationLibraryCache.ForgetAllExcept(libraries)
+
+	var errs []error
+	for _, library := range libraries {
+		compiled, err := c.identityTransformationLibraryCache.Compile(ctx.Context, library)
+		if err != nil {
+			return err
+		}
+
+		conditions := []*metav1.Condition{}
+		conditions = appendLibraryExpressionsValidCondition(compiled.ErrorsForExpressions(), conditions)
+		conditions = appendLibraryExamplesPassedCondition(compiled.ErrorsForExamples(), conditions)
+
+		var consumingFederationDomains []string
+		if names := consumers[library.Name]; names.Len() > 0 {
+			consumingFederationDomains = sets.List(names)
+		}
+
+		if err = c.updateStatus(ctx.Context, library, conditions, consumingFederationDomains); err != nil {
+			errs = append(errs, fmt.Errorf("could not update status: %w", err))
+		}
+	}
+
+	return errorsutil.NewAggregate(errs)
+}
+
+func appendLibraryExpressionsValidCondition(message string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(message) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeExpressionsValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidExpressions,
+			Message: message,
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeExpressionsValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the expressions specified by .spec.expressions[] are valid",
+		})
+	}
+	return conditions
+}
+
+func appendLibraryExamplesPassedCondition(message string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(message) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeExamplesPassed,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonExamplesFailed,
+			Message: message,
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeExamplesPassed,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the examples specified by .spec.examples[] had no errors",
+		})
+	}
+	return conditions
+}
+
+func (c *identityTransformationLibraryWatcherController) updateStatus(
+	ctx context.Context,
+	library *configv1alpha1.IdentityTransformationLibrary,
+	conditions []*metav1.Condition,
+	consumingFederationDomains []string,
+) error {
+	updated := library.DeepCopy()
+
+	if conditionsutil.HadErrorCondition(conditions) {
+		updated.Status.Phase = configv1alpha1.IdentityTransformationLibraryPhaseError
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonNotReady,
+			Message: "the IdentityTransformationLibrary is not ready: see other conditions for details",
+		})
+	} else {
+		updated.Status.Phase = configv1alpha1.IdentityTransformationLibraryPhaseReady
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeReady,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: "the IdentityTransformationLibrary is ready for use by FederationDomains",
+		})
+	}
+
+	_ = conditionsutil.MergeConfigConditions(conditions,
+		library.Generation, &updated.Status.Conditions, plog.New().WithName(identityTransformationLibraryControllerName), metav1.NewTime(c.clock.Now()))
+
+	updated.Status.ConsumingFederationDomains = consumingFederationDomains
+
+	if equality.Semantic.DeepEqual(library, updated) {
+		return nil
+	}
+
+	_, err := c.client.
+		ConfigV1alpha1().
+		IdentityTransformationLibraries().
+		UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
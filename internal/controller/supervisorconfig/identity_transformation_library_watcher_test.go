@@ -0,0 +1,305 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorconfig
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestIdentityTransformationLibraryWatcherControllerInformerFilters(t *testing.T) {
+	t.Parallel()
+
+	identityTransformationLibraryInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().IdentityTransformationLibraries()
+	federationDomainInformer := pinnipedinformers.NewSharedInformerFactoryWithOptions(nil, 0).Config().V1alpha1().FederationDomains()
+
+	tests := []struct {
+		name     string
+		obj      metav1.Object
+		informer controllerlib.InformerGetter
+	}{
+		{
+			name:     "any IdentityTransformationLibrary changes",
+			obj:      &configv1alpha1.IdentityTransformationLibrary{},
+			informer: identityTransformationLibraryInformer,
+		},
+		{
+			name:     "any FederationDomain changes",
+			obj:      &configv1alpha1.FederationDomain{},
+			informer: federationDomainInformer,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			withInformer := testutil.NewObservableWithInformerOption()
+
+			NewIdentityTransformationLibraryWatcherController(
+				nil,
+				nil,
+				identityTransformationLibraryInformer,
+				federationDomainInformer,
+				nil,
+				withInformer.WithInformer, // make it possible to observe the behavior of the Filters
+			)
+
+			unrelatedObj := corev1.Secret{}
+			filter := withInformer.GetFilterForInformer(test.informer)
+			require.True(t, filter.Add(test.obj))
+			require.True(t, filter.Update(&unrelatedObj, test.obj))
+			require.True(t, filter.Update(test.obj, &unrelatedObj))
+			require.True(t, filter.Delete(test.obj))
+		})
+	}
+}
+
+func TestIdentityTransformationLibraryWatcherControllerSync(t *testing.T) {
+	t.Parallel()
+
+	const namespace = "some-namespace"
+
+	frozenNow := time.Date(2020, time.September, 23, 7, 42, 0, 0, time.Local)
+	frozenMetav1Now := metav1.NewTime(frozenNow)
+
+	libraryGVR := schema.GroupVersionResource{
+		Group:    configv1alpha1.SchemeGroupVersion.Group,
+		Version:  configv1alpha1.SchemeGroupVersion.Version,
+		Resource: "identitytransformationlibraries",
+	}
+
+	validLibrary := &configv1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "valid-library", UID: "uid-1", Generation: 4},
+		Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+			Constants: []configv1alpha1.FederationDomainTransformsConstant{
+				{Name: "prefix", Type: "string", StringValue: "corp:"},
+			},
+			Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+				{Type: "username/v1", Expression: `strConst.prefix + username`},
+			},
+			Examples: []configv1alpha1.FederationDomainTransformsExample{
+				{
+					Username: "ryan",
+					Expects:  configv1alpha1.FederationDomainTransformsExampleExpects{Username: "corp:ryan"},
+				},
+			},
+		},
+	}
+
+	federationDomainUsingLibraries := func(name, namespace string, libraries ...string) *configv1alpha1.FederationDomain {
+		return &configv1alpha1.FederationDomain{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec: configv1alpha1.FederationDomainSpec{
+				IdentityProviders: []configv1alpha1.FederationDomainIdentityProvider{
+					{DisplayName: "unused"},
+					{DisplayName: "some-idp", Transforms: configv1alpha1.FederationDomainTransforms{Libraries: libraries}},
+				},
+			},
+		}
+	}
+
+	condition := func(conditionType, status, reason, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               conditionType,
+			Status:             metav1.ConditionStatus(status),
+			ObservedGeneration: 4,
+			LastTransitionTime: frozenMetav1Now,
+			Reason:             reason,
+			Message:            message,
+		}
+	}
+
+	happyExpressionsCondition := condition("ExpressionsValid", "True", "Success", "the expressions specified by .spec.expressions[] are valid")
+	happyExamplesCondition := condition("ExamplesPassed", "True", "Success", "the examples specified by .spec.examples[] had no errors")
+	happyReadyCondition := condition("Ready", "True", "Success", "the IdentityTransformationLibrary is ready for use by FederationDomains")
+	sadReadyCondition := condition("Ready", "False", "NotReady", "the IdentityTransformationLibrary is not ready: see other conditions for details")
+
+	tests := []struct {
+		name         string
+		inputObjects []runtime.Object
+
+		wantStatusUpdates []*configv1alpha1.IdentityTransformationLibrary
+	}{
+		{
+			name:         "no libraries",
+			inputObjects: []runtime.Object{federationDomainUsingLibraries("fd1", namespace, "some-library")},
+		},
+		{
+			name: "a valid library which is used by FederationDomains",
+			inputObjects: []runtime.Object{
+				validLibrary,
+				federationDomainUsingLibraries("fd2", namespace, "other-library", "valid-library"),
+				federationDomainUsingLibraries("fd1", namespace, "valid-library"),
+				federationDomainUsingLibraries("fd3", namespace, "other-library"),
+			},
+			wantStatusUpdates: []*configv1alpha1.IdentityTransformationLibrary{
+				{
+					ObjectMeta: validLibrary.ObjectMeta,
+					Status: configv1alpha1.IdentityTransformationLibraryStatus{
+						Phase:                      configv1alpha1.IdentityTransformationLibraryPhaseReady,
+						Conditions:                 []metav1.Condition{happyExamplesCondition, happyExpressionsCondition, happyReadyCondition},
+						ConsumingFederationDomains: []string{"fd1", "fd2"},
+					},
+				},
+			},
+		},
+		{
+			name: "a valid library whose status is already up to date",
+			inputObjects: []runtime.Object{
+				func() *configv1alpha1.IdentityTransformationLibrary {
+					library := validLibrary.DeepCopy()
+					library.Status = configv1alpha1.IdentityTransformationLibraryStatus{
+						Phase:      configv1alpha1.IdentityTransformationLibraryPhaseReady,
+						Conditions: []metav1.Condition{happyExamplesCondition, happyExpressionsCondition, happyReadyCondition},
+					}
+					return library
+				}(),
+			},
+		},
+		{
+			name: "a library with invalid expressions",
+			inputObjects: []runtime.Object{
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "invalid-library", UID: "uid-2", Generation: 4},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "username/v1", Expression: `username`},
+							{Type: "groups/v1", Expression: `this is not a valid cel expression`},
+						},
+						Examples: []configv1alpha1.FederationDomainTransformsExample{
+							{Username: "ryan", Expects: configv1alpha1.FederationDomainTransformsExampleExpects{Username: "ryan"}},
+						},
+					},
+				},
+				federationDomainUsingLibraries("fd1", namespace, "invalid-library"),
+			},
+			wantStatusUpdates: []*configv1alpha1.IdentityTransformationLibrary{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "invalid-library", UID: "uid-2", Generation: 4},
+					Status: configv1alpha1.IdentityTransformationLibraryStatus{
+						Phase: configv1alpha1.IdentityTransformationLibraryPhaseError,
+						Conditions: []metav1.Condition{
+							condition("ExamplesPassed", "False", "ExamplesFailed",
+								"unable to check if the examples specified by .spec.examples[] had errors because an expression was invalid"),
+							condition("ExpressionsValid", "False", "InvalidExpressions", here.Doc(
+								`spec.expressions[1].expression was invalid:
+								CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>
+								 | this is not a valid cel expression
+								 | .....^`,
+							)),
+							sadReadyCondition,
+						},
+						ConsumingFederationDomains: []string{"fd1"},
+					},
+				},
+			},
+		},
+		{
+			name: "a library with failing examples",
+			inputObjects: []runtime.Object{
+				&configv1alpha1.IdentityTransformationLibrary{
+					ObjectMeta: metav1.ObjectMeta{Name: "failing-library", UID: "uid-3", Generation: 4},
+					Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+						Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+							{Type: "policy/v1", Expression: `username != "ryan"`, Message: "no ryans"},
+						},
+						Examples: []configv1alpha1.FederationDomainTransformsExample{
+							{Username: "ryan", Expects: configv1alpha1.FederationDomainTransformsExampleExpects{Username: "ryan"}},
+							{Username: "josh", Expects: configv1alpha1.FederationDomainTransformsExampleExpects{Username: "josh"}},
+						},
+					},
+				},
+			},
+			wantStatusUpdates: []*configv1alpha1.IdentityTransformationLibrary{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "failing-library", UID: "uid-3", Generation: 4},
+					Status: configv1alpha1.IdentityTransformationLibraryStatus{
+						Phase: configv1alpha1.IdentityTransformationLibraryPhaseError,
+						Conditions: []metav1.Condition{
+							condition("ExamplesPassed", "False", "ExamplesFailed", here.Doc(
+								`.spec.examples[0] example failed:
+								expected: authentication not to be rejected
+								actual:   authentication was rejected with message "no ryans"`,
+							)),
+							happyExpressionsCondition,
+							sadReadyCondition,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pinnipedAPIClient := pinnipedfake.NewSimpleClientset()
+			pinnipedInformerClient := pinnipedfake.NewSimpleClientset()
+			for _, o := range tt.inputObjects {
+				require.NoError(t, pinnipedAPIClient.Tracker().Add(o))
+				require.NoError(t, pinnipedInformerClient.Tracker().Add(o))
+			}
+			pinnipedInformers := pinnipedinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+
+			controller := NewIdentityTransformationLibraryWatcherController(
+				clocktesting.NewFakeClock(frozenNow),
+				pinnipedAPIClient,
+				pinnipedInformers.Config().V1alpha1().IdentityTransformationLibraries(),
+				pinnipedInformers.Config().V1alpha1().FederationDomains(),
+				transformpipeline.NewLibraryCache(),
+				controllerlib.WithInformer,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pinnipedInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{Name: "some-name"}}
+			require.NoError(t, controllerlib.TestSync(t, controller, syncCtx))
+
+			actualUpdates := []*configv1alpha1.IdentityTransformationLibrary{}
+			for _, action := range pinnipedAPIClient.Actions() {
+				updateAction, ok := action.(coretesting.UpdateAction)
+				require.True(t, ok, "failed to cast an action as an coretesting.UpdateAction: %#v", action)
+				require.Equal(t, libraryGVR, updateAction.GetResource())
+				require.Equal(t, "status", updateAction.GetSubresource())
+
+				library, ok := updateAction.GetObject().(*configv1alpha1.IdentityTransformationLibrary)
+				require.True(t, ok)
+				// We don't care about the spec in an update status action, so clear it out to make it easier to write expected values.
+				library = library.DeepCopy()
+				library.Spec = configv1alpha1.IdentityTransformationLibrarySpec{}
+				actualUpdates = append(actualUpdates, library)
+			}
+
+			if tt.wantStatusUpdates == nil {
+				require.Empty(t, actualUpdates)
+			} else {
+				require.Equal(t, tt.wantStatusUpdates, actualUpdates)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package ldapupstreamwatcher implements a controller which watches LDAPIdentityProviders.
@@ -7,10 +7,13 @@ package ldapupstreamwatcher
 import (
 	"context"
 	"fmt"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
@@ -139,6 +142,9 @@ type UpstreamLDAPIdentityProviderICache interface {
 type ldapWatcherController struct {
 	cache                        UpstreamLDAPIdentityProviderICache
 	validatedSettingsCache       upstreamwatchers.ValidatedSettingsCacheI
+	groupCache                   upstreamldap.GroupCache
+	connectionPools              *upstreamwatchers.ConnectionPools
+	hostHealth                   *upstreamldap.HostHealth
 	ldapDialer                   upstreamldap.LDAPDialer
 	client                       supervisorclientset.Interface
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer
@@ -148,6 +154,7 @@ type ldapWatcherController struct {
 // New instantiates a new controllerlib.Controller which will populate the provided UpstreamLDAPIdentityProviderICache.
 func New(
 	idpCache UpstreamLDAPIdentityProviderICache,
+	groupCache upstreamldap.GroupCache,
 	client supervisorclientset.Interface,
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
@@ -159,6 +166,7 @@ func New(
 		upstreamwatchers.NewValidatedSettingsCache(),
 		// nil means to use a real production dialer when creating objects to add to the cache
 		nil,
+		groupCache,
 		client,
 		ldapIdentityProviderInformer,
 		secretInformer,
@@ -171,6 +179,7 @@ func newInternal(
 	idpCache UpstreamLDAPIdentityProviderICache,
 	validatedSettingsCache upstreamwatchers.ValidatedSettingsCacheI,
 	ldapDialer upstreamldap.LDAPDialer,
+	groupCache upstreamldap.GroupCache,
 	client supervisorclientset.Interface,
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	secretInformer corev1informers.SecretInformer,
@@ -179,6 +188,9 @@ func newInternal(
 	c := ldapWatcherController{
 		cache:                        idpCache,
 		validatedSettingsCache:       validatedSettingsCache,
+		groupCache:                   groupCache,
+		connectionPools:              upstreamwatchers.NewConnectionPools(),
+		hostHealth:                   upstreamldap.NewHostHealth(),
 		ldapDialer:                   ldapDialer,
 		client:                       client,
 		ldapIdentityProviderInformer: ldapIdentityProviderInformer,
@@ -193,7 +205,10 @@ func newInternal(
 		),
 		withInformer(
 			secretInformer,
-			pinnipedcontroller.MatchAnySecretOfTypeFilter(upstreamwatchers.LDAPBindAccountSecretType, pinnipedcontroller.SingletonQueue()),
+			pinnipedcontroller.MatchAnySecretOfTypesFilter(
+				[]corev1.SecretType{upstreamwatchers.LDAPBindAccountSecretType, upstreamwatchers.LDAPBindClientCertificateSecretType},
+				pinnipedcontroller.SingletonQueue(),
+			),
 			controllerlib.InformerOption{},
 		),
 	)
@@ -208,7 +223,9 @@ func (c *ldapWatcherController) Sync(ctx controllerlib.Context) error {
 
 	requeue := false
 	validatedUpstreams := make([]upstreamprovider.UpstreamLDAPIdentityProviderI, 0, len(actualUpstreams))
+	upstreamNames := sets.New[string]()
 	for _, upstream := range actualUpstreams {
+		upstreamNames.Insert(upstream.Name)
 		validProvider, requestedRequeue := c.validateUpstream(ctx.Context, upstream)
 		if validProvider != nil {
 			validatedUpstreams = append(validatedUpstreams, validProvider)
@@ -219,6 +236,7 @@ func (c *ldapWatcherController) Sync(ctx controllerlib.Context) error {
 	}
 
 	c.cache.SetLDAPIdentityProviders(validatedUpstreams)
+	c.connectionPools.CloseAllExcept(upstreamNames)
 
 	if requeue {
 		return controllerlib.ErrSyntheticRequeue
@@ -230,14 +248,16 @@ func (c *ldapWatcherController) validateUpstream(ctx context.Context, upstream *
 	spec := upstream.Spec
 
 	config := &upstreamldap.ProviderConfig{
-		Name:        upstream.Name,
-		ResourceUID: upstream.UID,
-		Host:        spec.Host,
+		Name:            upstream.Name,
+		ResourceUID:     upstream.UID,
+		Host:            spec.Host,
+		AdditionalHosts: spec.AdditionalHosts,
 		UserSearch: upstreamldap.UserSearchConfig{
-			Base:              spec.UserSearch.Base,
-			Filter:            spec.UserSearch.Filter,
-			UsernameAttribute: spec.UserSearch.Attributes.Username,
-			UIDAttribute:      spec.UserSearch.Attributes.UID,
+			Base:                 spec.UserSearch.Base,
+			Filter:               spec.UserSearch.Filter,
+			UsernameAttribute:    spec.UserSearch.Attributes.Username,
+			UIDAttribute:         spec.UserSearch.Attributes.UID,
+			AdditionalAttributes: spec.UserSearch.Attributes.AdditionalAttributes,
 		},
 		GroupSearch: upstreamldap.GroupSearchConfig{
 			Base:                   spec.GroupSearch.Base,
@@ -246,7 +266,18 @@ func (c *ldapWatcherController) validateUpstream(ctx context.Context, upstream *
 			GroupNameAttribute:     spec.GroupSearch.Attributes.GroupName,
 			SkipGroupRefresh:       spec.GroupSearch.SkipGroupRefresh,
 		},
-		Dialer: c.ldapDialer,
+		Dialer:         c.ldapDialer,
+		ConnectionPool: c.connectionPools.Get(upstream.Name),
+		GroupCache:     c.groupCache,
+	}
+
+	if spec.GroupSearch.RefreshCacheSeconds != nil {
+		config.GroupSearch.RefreshCacheTTL = time.Duration(*spec.GroupSearch.RefreshCacheSeconds) * time.Second
+	}
+
+	if len(config.AdditionalHosts) > 0 {
+		// Only track the health of the hosts when there are other hosts to try instead.
+		config.HostHealth = c.hostHealth
 	}
 
 	conditions := upstreamwatchers.ValidateGenericLDAP(ctx, &ldapUpstreamGenericLDAPImpl{*upstream}, c.secretInformer, c.validatedSettingsCache, config)
@@ -1,14 +1,20 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcclientwatcher
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +25,7 @@ import (
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	pinnipedfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	pinnipedinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/testutil"
 )
@@ -191,6 +198,17 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	happyClientAuthenticationCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientAuthenticationValid",
+			Status:             "True",
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            `client authentication method "client_secret_basic" is valid`,
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
 	happyClientSecretsCondition := func(howMany int, time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "ClientSecretExists",
@@ -246,6 +264,37 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	sadClientAuthenticationCondition := func(time metav1.Time, observedGeneration int64, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientAuthenticationValid",
+			Status:             "False",
+			LastTransitionTime: time,
+			Reason:             "InvalidClientAuthentication",
+			Message:            message,
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	clientSecretNotRequiredCondition := func(method string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientSecretExists",
+			Status:             "True",
+			LastTransitionTime: time,
+			Reason:             "ClientSecretNotRequired",
+			Message:            fmt.Sprintf(`no client secret is required when "clientAuthentication.method" is %q`, method),
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	testCA, err := certauthority.New("test CA", time.Hour)
+	require.NoError(t, err)
+	testCABundle := base64.StdEncoding.EncodeToString(testCA.Bundle())
+
+	testKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	testJWKS, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: testKey.Public(), KeyID: "some-key", Algorithm: "ES256", Use: "sig"}}})
+	require.NoError(t, err)
+
 	tests := []struct {
 		name                     string
 		inputObjects             []runtime.Object
@@ -295,6 +344,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						Conditions: []metav1.Condition{
 							happyAllowedGrantTypesCondition(now, 1234),
 							happyAllowedScopesCondition(now, 1234),
+							happyClientAuthenticationCondition(now, 1234),
 							happyClientSecretsCondition(1, now, 1234),
 						},
 						TotalClientSecrets: 1,
@@ -320,6 +370,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(2, now, 1234),
 					},
 					TotalClientSecrets: 2,
@@ -339,6 +390,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(earlier, 1234),
 						happyAllowedScopesCondition(earlier, 1234),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -353,6 +405,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(earlier, 1234),
 						happyAllowedScopesCondition(earlier, 1234),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -373,6 +426,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234, `"authorization_code" must always be included in "allowedGrantTypes"`),
 						sadAllowedScopesCondition(now, 1234, `"openid" must always be included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "no client secret found (no Secret storage found)"),
 					},
 				},
@@ -396,6 +450,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "error reading client secret storage: OIDC client secret storage data has wrong version: OIDC client secret storage has version wrong-version instead of 1"),
 					},
 				},
@@ -419,6 +474,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "no client secret found (empty list in storage)"),
 					},
 					TotalClientSecrets: 0,
@@ -446,6 +502,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadInvalidClientSecretsCondition(now, 1234,
 							"3 stored client secrets found, but some were invalid, so none will be used: "+
 								"hashed client secret at index 1: bcrypt cost 11 is below the required minimum of 12; "+
@@ -480,6 +537,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						Conditions: []metav1.Condition{
 							happyAllowedGrantTypesCondition(now, 1234),
 							happyAllowedScopesCondition(now, 1234),
+							happyClientAuthenticationCondition(now, 1234),
 							happyClientSecretsCondition(1, now, 1234),
 						},
 						TotalClientSecrets: 1,
@@ -492,6 +550,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						Conditions: []metav1.Condition{
 							sadAllowedGrantTypesCondition(now, 4567, `"authorization_code" must always be included in "allowedGrantTypes"`),
 							sadAllowedScopesCondition(now, 4567, `"openid" must always be included in "allowedScopes"`),
+							happyClientAuthenticationCondition(now, 4567),
 							sadNoClientSecretsCondition(now, 4567, "no client secret found (no Secret storage found)"),
 						},
 						TotalClientSecrets: 0,
@@ -513,6 +572,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(earlier, 1234, `"authorization_code" must always be included in "allowedGrantTypes"`),
 						sadAllowedScopesCondition(earlier, 1234, `"openid" must always be included in "allowedScopes"`),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -528,6 +588,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 4567),
 						happyAllowedScopesCondition(now, 4567),
+						happyClientAuthenticationCondition(earlier, 4567),
 						happyClientSecretsCondition(1, earlier, 4567), // was already validated earlier
 					},
 					TotalClientSecrets: 1,
@@ -552,6 +613,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234, `"refresh_token" must be included in "allowedGrantTypes" when "offline_access" is included in "allowedScopes"`),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -581,6 +643,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 							`"openid" must always be included in "allowedScopes"; `+
 								`"offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"; `+
 								`"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -609,6 +672,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						sadAllowedScopesCondition(now, 1234,
 							`"openid" must always be included in "allowedScopes"; `+
 								`"pinniped:request-audience" must be included in "allowedScopes" when "urn:ietf:params:oauth:grant-type:token-exchange" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -633,6 +697,84 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234, `"urn:ietf:params:oauth:grant-type:token-exchange" must be included in "allowedGrantTypes" when "pinniped:request-audience" is included in "allowedScopes"`),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "serviceIdentity must be set when client_credentials is included in allowedGrantTypes",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code", "client_credentials"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						sadAllowedGrantTypesCondition(now, 1234, `"serviceIdentity" must be set when "client_credentials" is included in "allowedGrantTypes"`),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "client_credentials must be included in allowedGrantTypes when serviceIdentity is set",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ServiceIdentity:   &configv1alpha1.OIDCClientServiceIdentity{Username: "some-service"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						sadAllowedGrantTypesCondition(now, 1234, `"client_credentials" must be included in "allowedGrantTypes" when "serviceIdentity" is set`),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "successfully validate an OIDCClient with a serviceIdentity for the client_credentials grant",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code", "client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid", "pinniped:request-audience", "username", "groups"},
+					ServiceIdentity:   &configv1alpha1.OIDCClientServiceIdentity{Username: "some-service", Groups: []string{"some-group"}},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -657,6 +799,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -681,6 +824,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -705,6 +849,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -729,6 +874,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -753,12 +899,201 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"pinniped:request-audience" must be included in "allowedScopes" when "urn:ietf:params:oauth:grant-type:token-exchange" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
 				},
 			}},
 		},
+		{
+			name: "successfully validate an OIDCClient which uses private_key_jwt without any client secrets",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+						Method:        "private_key_jwt",
+						PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{JWKS: string(testJWKS), SigningAlgorithm: "ES256"},
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						{
+							Type:               "ClientAuthenticationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `client authentication method "private_key_jwt" is valid`,
+							ObservedGeneration: 1234,
+						},
+						clientSecretNotRequiredCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "successfully validate an OIDCClient which uses tls_client_auth without any client secrets",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+						Method:        "tls_client_auth",
+						TLSClientAuth: &configv1alpha1.OIDCClientTLSClientAuth{CertificateAuthorityData: testCABundle, SANDNS: "client.example.com"},
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						{
+							Type:               "ClientAuthenticationValid",
+							Status:             "True",
+							LastTransitionTime: now,
+							Reason:             "Success",
+							Message:            `client authentication method "tls_client_auth" is valid`,
+							ObservedGeneration: 1234,
+						},
+						clientSecretNotRequiredCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt requires valid configuration",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+						Method:        "private_key_jwt",
+						PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{JWKS: `{"keys":[]}`},
+						TLSClientAuth: &configv1alpha1.OIDCClientTLSClientAuth{CertificateAuthorityData: testCABundle, SANDNS: "client.example.com"},
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234,
+							`"clientAuthentication.privateKeyJWT" has invalid jwks: must contain at least one public key with "use" set to "sig"; `+
+								`"clientAuthentication.tlsClientAuth" must only be set when "clientAuthentication.method" is "tls_client_auth"`),
+						clientSecretNotRequiredCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt requires exactly one of jwks or jwksURL",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+						Method:        "private_key_jwt",
+						PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{JWKS: string(testJWKS), JWKSURL: "https://example.com/jwks.json"},
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, `exactly one of jwks or jwksURL must be set in "clientAuthentication.privateKeyJWT"`),
+						clientSecretNotRequiredCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "tls_client_auth requires valid configuration",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+						Method: "tls_client_auth",
+						TLSClientAuth: &configv1alpha1.OIDCClientTLSClientAuth{
+							CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("not a PEM bundle")),
+							SubjectDN:                "CN=client",
+							SANEmail:                 "client@example.com",
+						},
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234,
+							`"clientAuthentication.tlsClientAuth" has invalid certificateAuthorityData: no certificates found; `+
+								`exactly one of subjectDN, sanDNS, sanURI, or sanEmail must be set in "clientAuthentication.tlsClientAuth"`),
+						clientSecretNotRequiredCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "the configuration of the client authentication method is required",
+			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: configv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:    []configv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:        []configv1alpha1.Scope{"openid"},
+					ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{Method: "tls_client_auth"},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []configv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: configv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, `"clientAuthentication.tlsClientAuth" must be set when "clientAuthentication.method" is "tls_client_auth"`),
+						clientSecretNotRequiredCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
 		{
 			name: "successfully validate an OIDCClient with all allowedGrantTypes and all allowedScopes",
 			inputObjects: []runtime.Object{&configv1alpha1.OIDCClient{
@@ -777,6 +1112,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -801,6 +1137,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -825,6 +1162,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -849,6 +1187,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -873,6 +1212,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -897,6 +1237,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -921,6 +1262,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -945,6 +1287,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package oidcupstreamwatcher implements a controller which watches OIDCIdentityProviders.
@@ -87,6 +87,17 @@ var (
 		// performs the corresponding validation on the ID token.
 		"hd": true,
 	}
+
+	allowedPassthroughAuthorizeParameters = map[string]bool{ //nolint:gochecknoglobals
+		// Only allow copying these params of the downstream authorization request into upstream authcode
+		// authorization requests. They are hints about how to show the login page to the user, which do not
+		// change the resulting identity or tokens. This map should be treated as read-only since it is a global variable.
+		"login_hint":  true,
+		"domain_hint": true,
+		"prompt":      true,
+		"ui_locales":  true,
+		"display":     true,
+	}
 )
 
 // UpstreamOIDCIdentityProviderICache is a thread safe cache that holds a list of validated upstream OIDC IDP configurations.
@@ -206,30 +217,59 @@ func (c *oidcWatcherController) validateUpstream(ctx controllerlib.Context, upst
 		}
 	}
 
+	var passthroughAuthcodeAuthorizeParameters, rejectedPassthroughAuthorizeParameters, conflictingPassthroughAuthorizeParameters []string
+	for _, name := range authorizationConfig.PassthroughAuthorizeParameters {
+		switch {
+		case !allowedPassthroughAuthorizeParameters[name]:
+			rejectedPassthroughAuthorizeParameters = append(rejectedPassthroughAuthorizeParameters, name)
+		case hasAdditionalAuthorizeParameter(authorizationConfig.AdditionalAuthorizeParameters, name):
+			conflictingPassthroughAuthorizeParameters = append(conflictingPassthroughAuthorizeParameters, name)
+		default:
+			passthroughAuthcodeAuthorizeParameters = append(passthroughAuthcodeAuthorizeParameters, name)
+		}
+	}
+
 	result := upstreamoidc.ProviderConfig{
 		Name: upstream.Name,
 		Config: &oauth2.Config{
 			Scopes: computeScopes(authorizationConfig.AdditionalScopes),
 		},
-		UsernameClaim:            upstream.Spec.Claims.Username,
-		GroupsClaim:              upstream.Spec.Claims.Groups,
-		AllowPasswordGrant:       authorizationConfig.AllowPasswordGrant,
-		AdditionalAuthcodeParams: additionalAuthcodeAuthorizeParameters,
-		AdditionalClaimMappings:  upstream.Spec.Claims.AdditionalClaimMappings,
-		ResourceUID:              upstream.UID,
+		UsernameClaim:                      upstream.Spec.Claims.Username,
+		GroupsClaim:                        upstream.Spec.Claims.Groups,
+		AllowPasswordGrant:                 authorizationConfig.AllowPasswordGrant,
+		AdditionalAuthcodeParams:           additionalAuthcodeAuthorizeParameters,
+		PassthroughAuthcodeParams:          passthroughAuthcodeAuthorizeParameters,
+		AdditionalClaimMappings:            upstream.Spec.Claims.AdditionalClaimMappings,
+		AdditionalClaimsForTransformations: upstream.Spec.Claims.AdditionalClaimsForTransformations,
+		ResourceUID:                        upstream.UID,
 	}
 
 	conditions := []*metav1.Condition{
 		c.validateSecret(upstream, &result),
 		c.validateIssuer(ctx.Context, upstream, &result),
 	}
+	var rejectedParameterMessages []string
 	if len(rejectedAuthcodeAuthorizeParameters) > 0 {
+		rejectedParameterMessages = append(rejectedParameterMessages,
+			fmt.Sprintf("the following additionalAuthorizeParameters are not allowed: %s",
+				strings.Join(rejectedAuthcodeAuthorizeParameters, ",")))
+	}
+	if len(rejectedPassthroughAuthorizeParameters) > 0 {
+		rejectedParameterMessages = append(rejectedParameterMessages,
+			fmt.Sprintf("the following passthroughAuthorizeParameters are not allowed: %s",
+				strings.Join(rejectedPassthroughAuthorizeParameters, ",")))
+	}
+	if len(conflictingPassthroughAuthorizeParameters) > 0 {
+		rejectedParameterMessages = append(rejectedParameterMessages,
+			fmt.Sprintf("the following passthroughAuthorizeParameters are also configured in additionalAuthorizeParameters: %s",
+				strings.Join(conflictingPassthroughAuthorizeParameters, ",")))
+	}
+	if len(rejectedParameterMessages) > 0 {
 		conditions = append(conditions, &metav1.Condition{
-			Type:   typeAdditionalAuthorizeParametersValid,
-			Status: metav1.ConditionFalse,
-			Reason: reasonDisallowedParameterName,
-			Message: fmt.Sprintf("the following additionalAuthorizeParameters are not allowed: %s",
-				strings.Join(rejectedAuthcodeAuthorizeParameters, ",")),
+			Type:    typeAdditionalAuthorizeParametersValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonDisallowedParameterName,
+			Message: strings.Join(rejectedParameterMessages, "; "),
 		})
 	} else {
 		conditions = append(conditions, &metav1.Condition{
@@ -456,6 +496,15 @@ func defaultClientShortTimeout(rootCAs *x509.CertPool) *http.Client {
 	return c
 }
 
+func hasAdditionalAuthorizeParameter(params []v1alpha1.Parameter, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func computeScopes(additionalScopes []string) []string {
 	// If none are set then provide a reasonable default which only tries to use scopes defined in the OIDC spec.
 	if len(additionalScopes) == 0 {
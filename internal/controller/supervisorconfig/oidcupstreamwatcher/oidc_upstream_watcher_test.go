@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcupstreamwatcher
@@ -1197,9 +1197,10 @@ Get "` + testIssuerURL + `/valid-url-that-is-really-really-long-nananananananana
 					TLS:    &v1alpha1.TLSSpec{CertificateAuthorityData: testIssuerCABase64},
 					Client: v1alpha1.OIDCClient{SecretName: testSecretName},
 					AuthorizationConfig: v1alpha1.OIDCAuthorizationConfig{
-						AdditionalScopes:              testAdditionalScopes,
-						AdditionalAuthorizeParameters: testAdditionalParams,
-						AllowPasswordGrant:            true,
+						AdditionalScopes:               testAdditionalScopes,
+						AdditionalAuthorizeParameters:  testAdditionalParams,
+						PassthroughAuthorizeParameters: []string{"login_hint", "ui_locales"},
+						AllowPasswordGrant:             true,
 					},
 					Claims: v1alpha1.OIDCClaims{
 						Groups:   testGroupsClaim,
@@ -1207,6 +1208,7 @@ Get "` + testIssuerURL + `/valid-url-that-is-really-really-long-nananananananana
 						AdditionalClaimMappings: map[string]string{
 							"downstream": "upstream",
 						},
+						AdditionalClaimsForTransformations: []string{"email_verified", "acr"},
 					},
 				},
 				Status: v1alpha1.OIDCIdentityProviderStatus{
@@ -1230,19 +1232,21 @@ Get "` + testIssuerURL + `/valid-url-that-is-really-really-long-nananananananana
 			},
 			wantResultingCache: []*oidctestutil.TestUpstreamOIDCIdentityProvider{
 				{
-					Name:                     testName,
-					ClientID:                 testClientID,
-					AuthorizationURL:         *testIssuerAuthorizeURL,
-					RevocationURL:            testIssuerRevocationURL,
-					Scopes:                   testExpectedScopes, // does not include the default scopes
-					UsernameClaim:            testUsernameClaim,
-					GroupsClaim:              testGroupsClaim,
-					AllowPasswordGrant:       true,
-					AdditionalAuthcodeParams: testExpectedAdditionalParams,
+					Name:                      testName,
+					ClientID:                  testClientID,
+					AuthorizationURL:          *testIssuerAuthorizeURL,
+					RevocationURL:             testIssuerRevocationURL,
+					Scopes:                    testExpectedScopes, // does not include the default scopes
+					UsernameClaim:             testUsernameClaim,
+					GroupsClaim:               testGroupsClaim,
+					AllowPasswordGrant:        true,
+					AdditionalAuthcodeParams:  testExpectedAdditionalParams,
+					PassthroughAuthcodeParams: []string{"login_hint", "ui_locales"},
 					AdditionalClaimMappings: map[string]string{
 						"downstream": "upstream",
 					},
-					ResourceUID: testUID,
+					AdditionalClaimsForTransforms: []string{"email_verified", "acr"},
+					ResourceUID:                   testUID,
 				},
 			},
 			wantResultingUpstreams: []v1alpha1.OIDCIdentityProvider{{
@@ -1308,6 +1312,51 @@ Get "` + testIssuerURL + `/valid-url-that-is-really-really-long-nananananananana
 				},
 			}},
 		},
+		{
+			name: "has disallowed passthroughAuthorizeParameters names",
+			inputUpstreams: []runtime.Object{&v1alpha1.OIDCIdentityProvider{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: v1alpha1.OIDCIdentityProviderSpec{
+					Issuer: testIssuerURL,
+					TLS:    &v1alpha1.TLSSpec{CertificateAuthorityData: testIssuerCABase64},
+					Client: v1alpha1.OIDCClient{SecretName: testSecretName},
+					AuthorizationConfig: v1alpha1.OIDCAuthorizationConfig{
+						AdditionalAuthorizeParameters: []v1alpha1.Parameter{
+							{Name: "prompt", Value: "consent"},
+							{Name: "hd", Value: "foo"},
+						},
+						PassthroughAuthorizeParameters: []string{"login_hint", "nonce", "scope", "prompt", "hd", "ui_locales"},
+					},
+				},
+			}},
+			inputSecrets: []runtime.Object{&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testSecretName},
+				Type:       "secrets.pinniped.dev/oidc-client",
+				Data:       testValidSecretData,
+			}},
+			wantErr: controllerlib.ErrSyntheticRequeue.Error(),
+			wantLogs: []string{
+				`oidc-upstream-observer "level"=0 "msg"="updated condition" "name"="test-name" "namespace"="test-namespace" "message"="loaded client credentials" "reason"="Success" "status"="True" "type"="ClientCredentialsValid"`,
+				`oidc-upstream-observer "level"=0 "msg"="updated condition" "name"="test-name" "namespace"="test-namespace" "message"="discovered issuer configuration" "reason"="Success" "status"="True" "type"="OIDCDiscoverySucceeded"`,
+				`oidc-upstream-observer "level"=0 "msg"="updated condition" "name"="test-name" "namespace"="test-namespace" "message"="the following additionalAuthorizeParameters are not allowed: hd; the following passthroughAuthorizeParameters are not allowed: nonce,scope,hd; the following passthroughAuthorizeParameters are also configured in additionalAuthorizeParameters: prompt" "reason"="DisallowedParameterName" "status"="False" "type"="AdditionalAuthorizeParametersValid"`,
+				`oidc-upstream-observer "msg"="found failing condition" "error"="OIDCIdentityProvider has a failing condition" "message"="the following additionalAuthorizeParameters are not allowed: hd; the following passthroughAuthorizeParameters are not allowed: nonce,scope,hd; the following passthroughAuthorizeParameters are also configured in additionalAuthorizeParameters: prompt" "name"="test-name" "namespace"="test-namespace" "reason"="DisallowedParameterName" "type"="AdditionalAuthorizeParametersValid"`,
+			},
+			wantResultingCache: []*oidctestutil.TestUpstreamOIDCIdentityProvider{},
+			wantResultingUpstreams: []v1alpha1.OIDCIdentityProvider{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: v1alpha1.OIDCIdentityProviderStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						{Type: "AdditionalAuthorizeParametersValid", Status: "False", LastTransitionTime: now, Reason: "DisallowedParameterName",
+							Message: "the following additionalAuthorizeParameters are not allowed: hd; " +
+								"the following passthroughAuthorizeParameters are not allowed: nonce,scope,hd; " +
+								"the following passthroughAuthorizeParameters are also configured in additionalAuthorizeParameters: prompt", ObservedGeneration: 1234},
+						{Type: "ClientCredentialsValid", Status: "True", LastTransitionTime: now, Reason: "Success", Message: "loaded client credentials", ObservedGeneration: 1234},
+						{Type: "OIDCDiscoverySucceeded", Status: "True", LastTransitionTime: now, Reason: "Success", Message: "discovered issuer configuration", ObservedGeneration: 1234},
+					},
+				},
+			}},
+		},
 		{
 			name: "issuer is invalid URL, missing trailing slash when the OIDC discovery endpoint returns the URL with a trailing slash",
 			inputUpstreams: []runtime.Object{&v1alpha1.OIDCIdentityProvider{
@@ -1457,7 +1506,9 @@ oidc: issuer did not match the issuer returned by provider, expected "` + testIs
 				require.Equal(t, tt.wantResultingCache[i].GetGroupsClaim(), actualIDP.GetGroupsClaim())
 				require.Equal(t, tt.wantResultingCache[i].AllowsPasswordGrant(), actualIDP.AllowsPasswordGrant())
 				require.Equal(t, tt.wantResultingCache[i].GetAdditionalAuthcodeParams(), actualIDP.GetAdditionalAuthcodeParams())
+				require.Equal(t, tt.wantResultingCache[i].GetPassthroughAuthcodeParams(), actualIDP.GetPassthroughAuthcodeParams())
 				require.Equal(t, tt.wantResultingCache[i].GetAdditionalClaimMappings(), actualIDP.GetAdditionalClaimMappings())
+				require.Equal(t, tt.wantResultingCache[i].GetAdditionalClaimsForTransformations(), actualIDP.GetAdditionalClaimsForTransformations())
 				require.Equal(t, tt.wantResultingCache[i].GetResourceUID(), actualIDP.GetResourceUID())
 				require.Equal(t, tt.wantResultingCache[i].GetRevocationURL(), actualIDP.GetRevocationURL())
 				require.ElementsMatch(t, tt.wantResultingCache[i].GetScopes(), actualIDP.GetScopes())
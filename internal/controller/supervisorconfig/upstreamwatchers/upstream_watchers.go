@@ -1,10 +1,11 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package upstreamwatchers
 
 import (
 	"context"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
 	"fmt"
@@ -12,6 +13,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	corev1informers "k8s.io/client-go/informers/core/v1"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
@@ -25,20 +27,24 @@ const (
 	ReasonNotFound         = "SecretNotFound"
 	ReasonWrongType        = "SecretWrongType"
 	ReasonMissingKeys      = "SecretMissingKeys"
+	ReasonInvalidKeyPair   = "SecretInvalidKeyPair"
 	ReasonSuccess          = "Success"
 	ReasonInvalidTLSConfig = "InvalidTLSConfig"
 
 	ErrNoCertificates = constable.Error("no certificates found")
 
-	LDAPBindAccountSecretType = corev1.SecretTypeBasicAuth
-	probeLDAPTimeout          = 90 * time.Second
+	LDAPBindAccountSecretType           = corev1.SecretTypeBasicAuth
+	LDAPBindClientCertificateSecretType = corev1.SecretTypeTLS
+	probeLDAPTimeout                    = 90 * time.Second
 
 	// Constants related to conditions.
 	typeBindSecretValid              = "BindSecretValid"
 	typeTLSConfigurationValid        = "TLSConfigurationValid"
 	typeLDAPConnectionValid          = "LDAPConnectionValid"
+	typeHostsReachable               = "HostsReachable"
 	TypeSearchBaseFound              = "SearchBaseFound"
 	reasonLDAPConnectionError        = "LDAPConnectionError"
+	reasonHostsUnreachable           = "HostsUnreachable"
 	noTLSConfigurationMessage        = "no TLS configuration provided"
 	loadedTLSConfigurationMessage    = "loaded TLS configuration"
 	ReasonUsingConfigurationFromSpec = "UsingConfigurationFromSpec"
@@ -99,6 +105,37 @@ func (s *ValidatedSettingsCache) Set(upstreamName string, settings ValidatedSett
 	s.ValidatedSettingsByName[upstreamName] = settings
 }
 
+// ConnectionPools keeps an upstreamldap.ConnectionPool for each upstream provider, so that the providers
+// which are created for an upstream during each sync can reuse the connections of the previous providers.
+// It is not safe for concurrent use, so it should only be used by a controller with a single worker.
+type ConnectionPools struct {
+	poolsByName map[string]*upstreamldap.ConnectionPool
+}
+
+func NewConnectionPools() *ConnectionPools {
+	return &ConnectionPools{poolsByName: map[string]*upstreamldap.ConnectionPool{}}
+}
+
+// Get returns the pool for a given upstream, creating it when needed.
+func (c *ConnectionPools) Get(upstreamName string) *upstreamldap.ConnectionPool {
+	pool, found := c.poolsByName[upstreamName]
+	if !found {
+		pool = upstreamldap.NewConnectionPool()
+		c.poolsByName[upstreamName] = pool
+	}
+	return pool
+}
+
+// CloseAllExcept closes and forgets the pools of the upstreams which no longer exist.
+func (c *ConnectionPools) CloseAllExcept(upstreamNames sets.Set[string]) {
+	for name, pool := range c.poolsByName {
+		if !upstreamNames.Has(name) {
+			pool.Close()
+			delete(c.poolsByName, name)
+		}
+	}
+}
+
 // UpstreamGenericLDAPIDP is a read-only interface for abstracting the differences between LDAP and Active Directory IDP types.
 type UpstreamGenericLDAPIDP interface {
 	Spec() UpstreamGenericLDAPSpec
@@ -235,16 +272,23 @@ func ValidateSecret(secretInformer corev1informers.SecretInformer, secretName st
 		}, ""
 	}
 
-	if secret.Type != corev1.SecretTypeBasicAuth {
+	switch secret.Type {
+	case LDAPBindAccountSecretType:
+		return validateBindAccountSecret(secret, config), secret.ResourceVersion
+	case LDAPBindClientCertificateSecretType:
+		return validateBindClientCertificateSecret(secret, config), secret.ResourceVersion
+	default:
 		return &metav1.Condition{
 			Type:   typeBindSecretValid,
 			Status: metav1.ConditionFalse,
 			Reason: ReasonWrongType,
-			Message: fmt.Sprintf("referenced Secret %q has wrong type %q (should be %q)",
-				secretName, secret.Type, corev1.SecretTypeBasicAuth),
+			Message: fmt.Sprintf("referenced Secret %q has wrong type %q (should be %q or %q)",
+				secretName, secret.Type, LDAPBindAccountSecretType, LDAPBindClientCertificateSecretType),
 		}, secret.ResourceVersion
 	}
+}
 
+func validateBindAccountSecret(secret *corev1.Secret, config *upstreamldap.ProviderConfig) *metav1.Condition {
 	config.BindUsername = string(secret.Data[corev1.BasicAuthUsernameKey])
 	config.BindPassword = string(secret.Data[corev1.BasicAuthPasswordKey])
 	if len(config.BindUsername) == 0 || len(config.BindPassword) == 0 {
@@ -253,16 +297,62 @@ func ValidateSecret(secretInformer corev1informers.SecretInformer, secretName st
 			Status: metav1.ConditionFalse,
 			Reason: ReasonMissingKeys,
 			Message: fmt.Sprintf("referenced Secret %q is missing required keys %q",
-				secretName, []string{corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey}),
-		}, secret.ResourceVersion
+				secret.Name, []string{corev1.BasicAuthUsernameKey, corev1.BasicAuthPasswordKey}),
+		}
+	}
+
+	return &metav1.Condition{
+		Type:    typeBindSecretValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonSuccess,
+		Message: "loaded bind secret",
+	}
+}
+
+func validateBindClientCertificateSecret(secret *corev1.Secret, config *upstreamldap.ProviderConfig) *metav1.Condition {
+	certPEM := secret.Data[corev1.TLSCertKey]
+	keyPEM := secret.Data[corev1.TLSPrivateKeyKey]
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		return &metav1.Condition{
+			Type:   typeBindSecretValid,
+			Status: metav1.ConditionFalse,
+			Reason: ReasonMissingKeys,
+			Message: fmt.Sprintf("referenced Secret %q is missing required keys %q",
+				secret.Name, []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey}),
+		}
 	}
 
+	clientCert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return &metav1.Condition{
+			Type:    typeBindSecretValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonInvalidKeyPair,
+			Message: fmt.Sprintf("referenced Secret %q has invalid client certificate or key: %s", secret.Name, err.Error()),
+		}
+	}
+	leaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		return &metav1.Condition{
+			Type:    typeBindSecretValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  ReasonInvalidKeyPair,
+			Message: fmt.Sprintf("referenced Secret %q has invalid client certificate or key: %s", secret.Name, err.Error()),
+		}
+	}
+
+	// The server maps the client certificate to the bind account, so describe the bind account by the
+	// subject of the certificate in messages.
+	config.BindUsername = leaf.Subject.String()
+	config.BindClientCertificate = certPEM
+	config.BindClientKey = keyPEM
+
 	return &metav1.Condition{
 		Type:    typeBindSecretValid,
 		Status:  metav1.ConditionTrue,
 		Reason:  ReasonSuccess,
 		Message: "loaded bind secret",
-	}, secret.ResourceVersion
+	}
 }
 
 // gradatedCondition is a condition and a boolean that tells you whether the condition is fatal or just a warning.
@@ -311,10 +401,41 @@ func ValidateGenericLDAP(
 		if searchBaseFoundCondition != nil { // currently, only used for AD, so may be nil
 			conditions.Append(searchBaseFoundCondition, true)
 		}
+		if hostsReachableCondition := probeHosts(ctx, config); hostsReachableCondition != nil { // only used for multiple hosts, so may be nil
+			conditions.Append(hostsReachableCondition, false)
+		}
 	}
 	return conditions
 }
 
+// probeHosts checks which of the hosts can be reached, when there is more than one host. Every host is probed on
+// each sync, so that the condition notices hosts which stopped working.
+func probeHosts(ctx context.Context, config *upstreamldap.ProviderConfig) *metav1.Condition {
+	if len(config.AdditionalHosts) == 0 {
+		return nil
+	}
+
+	probeTimeout, cancelFunc := context.WithTimeout(ctx, probeLDAPTimeout)
+	defer cancelFunc()
+	reachable, unreachable := upstreamldap.New(*config).ProbeHosts(probeTimeout)
+
+	if len(unreachable) > 0 {
+		return &metav1.Condition{
+			Type:    typeHostsReachable,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonHostsUnreachable,
+			Message: fmt.Sprintf("could not connect to and bind to hosts %q, but was able to for hosts %q", unreachable, reachable),
+		}
+	}
+
+	return &metav1.Condition{
+		Type:    typeHostsReachable,
+		Status:  metav1.ConditionTrue,
+		Reason:  ReasonSuccess,
+		Message: fmt.Sprintf("successfully able to connect to and bind to all hosts %q", reachable),
+	}
+}
+
 func validateAndSetLDAPServerConnectivityAndSearchBase(
 	ctx context.Context,
 	validatedSettingsCache ValidatedSettingsCacheI,
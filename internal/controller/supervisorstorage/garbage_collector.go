@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorstorage
@@ -25,9 +25,17 @@ import (
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/consentgrant"
+	"go.pinniped.dev/internal/fositestorage/consentrequest"
+	"go.pinniped.dev/internal/fositestorage/ldapgroupcache"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizerequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/fositestorage/secondfactorrequest"
+	"go.pinniped.dev/internal/fositestorage/totpenrollment"
+	"go.pinniped.dev/internal/fositestorage/webauthnenrollment"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/psession"
 )
@@ -231,6 +239,42 @@ func (c *garbageCollectorController) maybeRevokeUpstreamOIDCToken(ctx context.Co
 		// be revoked by one of the other cases above.
 		return nil
 
+	case clientassertion.TypeLabelValue:
+		// For client assertion storage, there is no upstream token, so there is nothing to revoke.
+		return nil
+
+	case consentrequest.TypeLabelValue:
+		// For consent request storage, its existence means that the user never approved the request, because
+		// approved requests are deleted before the downstream authcode is issued. Therefore, no other storage
+		// holds the upstream token of its session, so always revoke it.
+		consentRequest, err := consentrequest.ReadFromSecret(secret)
+		if err != nil {
+			return err
+		}
+		return c.tryRevokeUpstreamOIDCToken(ctx, consentRequest.Session.Custom, secret)
+
+	case consentgrant.TypeLabelValue:
+		// For consent grant storage, there is no upstream token, so there is nothing to revoke.
+		return nil
+
+	case pushedauthorizerequest.TypeLabelValue:
+		// For pushed authorization request storage, the user has not logged in yet, so there is no upstream token.
+		return nil
+
+	case ldapgroupcache.TypeLabelValue:
+		// For LDAP group cache storage, there is no upstream token, so there is nothing to revoke.
+		return nil
+
+	case secondfactorrequest.TypeLabelValue:
+		// For second factor request storage, the session is always for an LDAP or Active Directory
+		// identity provider, so there is no upstream token.
+		return nil
+
+	case totpenrollment.TypeLabelValue, webauthnenrollment.TypeLabelValue:
+		// For TOTP and WebAuthn enrollment storage, there is no upstream token, so there is nothing to revoke.
+		// These are never garbage collected in practice, because they are stored without a lifetime.
+		return nil
+
 	default:
 		// There are no other storage types, so this should never happen in practice.
 		return errors.New("garbage collector saw invalid label on Secret when trying to determine if upstream revocation was needed")
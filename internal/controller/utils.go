@@ -1,9 +1,11 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controller
 
 import (
+	"slices"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -54,6 +56,18 @@ func MatchAnySecretOfTypeFilter(secretType corev1.SecretType, parentFunc control
 	return SimpleFilter(isSecretOfType, parentFunc)
 }
 
+// MatchAnySecretOfTypesFilter is like MatchAnySecretOfTypeFilter, except that it matches Secrets of any of the given types.
+func MatchAnySecretOfTypesFilter(secretTypes []corev1.SecretType, parentFunc controllerlib.ParentFunc) controllerlib.Filter {
+	isSecretOfTypes := func(obj metav1.Object) bool {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			return false
+		}
+		return slices.Contains(secretTypes, secret.Type)
+	}
+	return SimpleFilter(isSecretOfTypes, parentFunc)
+}
+
 func SecretIsControlledByParentFunc(matchFunc func(obj metav1.Object) bool) func(obj metav1.Object) controllerlib.Key {
 	return func(obj metav1.Object) controllerlib.Key {
 		if matchFunc(obj) {
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package controllermanager provides an entrypoint into running all of the controllers that run as
@@ -10,13 +10,18 @@ import (
 	"fmt"
 	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
 	k8sinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 
 	conciergeclientset "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned"
 	conciergeinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
 	"go.pinniped.dev/internal/apiserviceref"
+	"go.pinniped.dev/internal/certauthority/externalsigner"
 	"go.pinniped.dev/internal/concierge/impersonator"
 	"go.pinniped.dev/internal/config/concierge"
 	"go.pinniped.dev/internal/controller/apicerts"
@@ -66,6 +71,10 @@ type Config struct {
 	// ImpersonationProxyServerPort decides which port the impersonation proxy should bind.
 	ImpersonationProxyServerPort int
 
+	// ImpersonationProxyAuditConfig comes from the Pinniped config API (see api.Config). It configures
+	// where the impersonation proxy sends the audit events for the requests that it handles.
+	ImpersonationProxyAuditConfig *concierge.ImpersonationProxyAuditSpec
+
 	// DiscoveryURLOverride allows a caller to inject a hardcoded discovery URL into Pinniped
 	// discovery document.
 	DiscoveryURLOverride *string
@@ -85,6 +94,15 @@ type Config struct {
 	// (Note that the impersonation proxy also accepts client certs signed by the Kube API server's cert.)
 	ImpersonationSigningCertProvider dynamiccert.Provider
 
+	// ManagedCASigningCertProvider provides a setter and a getter to the Concierge-managed client CA.
+	// It is filled by a controller only once the Kube API server trusts that CA, so that the
+	// TokenCredentialRequest never issues certs which the Kube API server would reject.
+	ManagedCASigningCertProvider dynamiccert.Private
+
+	// ExternalSigner is configured by a controller to issue client certs through the external signer
+	// described by the CredentialIssuer, if any.
+	ExternalSigner externalsigner.Dynamic
+
 	// ImpersonationProxyTokenCache holds short-lived tokens for the impersonation proxy service account.
 	ImpersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet
 
@@ -130,6 +148,16 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 		return nil, fmt.Errorf("could not create clients for the controllers: %w", err)
 	}
 
+	// The external signer requests certificates on every pod, so it needs a client without leader election.
+	externalSignerClient, err := kubeclient.New(kubeclient.WithConfig(c.BaseConfig))
+	if err != nil {
+		return nil, fmt.Errorf("could not create client for the external signer: %w", err)
+	}
+	externalSignerDynamicClient, err := dynamic.NewForConfig(externalSignerClient.JSONConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create dynamic client for the external signer: %w", err)
+	}
+
 	// Create informers. Don't forget to make sure they get started in the function returned below.
 	informers := createInformers(c.ServerInstallationInfo.Namespace, client.Kubernetes, client.PinnipedConcierge)
 
@@ -224,6 +252,40 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 			),
 			singletonWorker,
 		).
+		// The external signer controller is responsible for configuring the external signer described by the
+		// CredentialIssuer, as well as reporting status on this cluster integration strategy.
+		WithController(
+			kubecertagent.NewExternalSignerController(
+				agentConfig,
+				client,
+				externalSignerClient.Kubernetes,
+				externalSignerDynamicClient,
+				informers.kubePublicNamespaceK8s.Core().V1().ConfigMaps(),
+				informers.pinniped.Config().V1alpha1().CredentialIssuers(),
+				c.ExternalSigner,
+			),
+			singletonWorker,
+		).
+		// The managed client CA controller is responsible for generating and rotating the Concierge-managed
+		// client CA when the CredentialIssuer configures it, publishing its bundle, and loading it into memory once
+		// the Kube API server trusts it, as well as reporting status on this cluster integration strategy.
+		WithController(
+			kubecertagent.NewManagedCAController(
+				agentConfig,
+				kubecertagent.ManagedCAConfig{
+					SecretName:          c.NamesConfig.ManagedClientCASecret,
+					BundleConfigMapName: c.NamesConfig.ManagedClientCABundleConfigMap,
+				},
+				client,
+				informers.installationNamespaceK8s.Core().V1().Secrets(),
+				informers.installationNamespaceK8s.Core().V1().ConfigMaps(),
+				informers.kubePublicNamespaceK8s.Core().V1().ConfigMaps(),
+				informers.extensionAPIServerAuthenticationK8s.Core().V1().ConfigMaps(),
+				informers.pinniped.Config().V1alpha1().CredentialIssuers(),
+				c.ManagedCASigningCertProvider,
+			),
+			singletonWorker,
+		).
 		// The kube-cert-agent legacy pod cleaner controller is responsible for cleaning up pods that were deployed by
 		// versions of Pinniped prior to v0.7.0. If we stop supporting upgrades from v0.7.0, we can safely remove this.
 		WithController(
@@ -286,7 +348,14 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 				c.NamesConfig.ImpersonationCACertificateSecret,
 				c.Labels,
 				clock.RealClock{},
-				impersonator.New,
+				impersonator.NewWithAudit(&impersonator.AuditConfig{
+					PolicyFile:             c.ImpersonationProxyAuditConfig.PolicyFile,
+					LogFile:                c.ImpersonationProxyAuditConfig.LogFile,
+					WebhookKubeconfigFile:  c.ImpersonationProxyAuditConfig.WebhookKubeconfigFile,
+					WebhookBatchBufferSize: int(ptr.Deref(c.ImpersonationProxyAuditConfig.WebhookBatch.BufferSize, 0)),
+					WebhookBatchMaxSize:    int(ptr.Deref(c.ImpersonationProxyAuditConfig.WebhookBatch.MaxSize, 0)),
+					WebhookBatchMaxWait:    time.Duration(ptr.Deref(c.ImpersonationProxyAuditConfig.WebhookBatch.MaxWaitSeconds, 0)) * time.Second,
+				}),
 				c.NamesConfig.ImpersonationSignerSecret,
 				c.ImpersonationSigningCertProvider,
 				plog.Logr(), //nolint:staticcheck // old controller with lots of log statements
@@ -339,15 +408,17 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 		informers.kubePublicNamespaceK8s,
 		informers.kubeSystemNamespaceK8s,
 		informers.installationNamespaceK8s,
+		informers.extensionAPIServerAuthenticationK8s,
 		informers.pinniped,
 	), nil
 }
 
 type informers struct {
-	kubePublicNamespaceK8s   k8sinformers.SharedInformerFactory
-	kubeSystemNamespaceK8s   k8sinformers.SharedInformerFactory
-	installationNamespaceK8s k8sinformers.SharedInformerFactory
-	pinniped                 conciergeinformers.SharedInformerFactory
+	kubePublicNamespaceK8s              k8sinformers.SharedInformerFactory
+	kubeSystemNamespaceK8s              k8sinformers.SharedInformerFactory
+	installationNamespaceK8s            k8sinformers.SharedInformerFactory
+	extensionAPIServerAuthenticationK8s k8sinformers.SharedInformerFactory
+	pinniped                            conciergeinformers.SharedInformerFactory
 }
 
 // Create the informers that will be used by the controllers.
@@ -372,6 +443,15 @@ func createInformers(
 			defaultResyncInterval,
 			k8sinformers.WithNamespace(serverInstallationNamespace),
 		),
+		// We are only allowed to read this one ConfigMap in kube-system, so only watch that one.
+		extensionAPIServerAuthenticationK8s: k8sinformers.NewSharedInformerFactoryWithOptions(
+			k8sClient,
+			defaultResyncInterval,
+			k8sinformers.WithNamespace(kubecertagent.ExtensionAPIServerAuthenticationNamespace),
+			k8sinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", kubecertagent.ExtensionAPIServerAuthenticationName).String()
+			}),
+		),
 		pinniped: conciergeinformers.NewSharedInformerFactoryWithOptions(
 			pinnipedClient,
 			defaultResyncInterval,
@@ -0,0 +1,188 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package branding customizes the web pages which are shown to users by a FederationDomain, using the settings
+// from the ConfigMap which is referenced by the FederationDomain's spec.branding.
+package branding
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"go.pinniped.dev/internal/federationdomain/csp"
+)
+
+const (
+	TitleKey           = "title"
+	LogoKey            = "logo"
+	PrimaryColorKey    = "primaryColor"
+	BackgroundColorKey = "backgroundColor"
+	HelpLinksKey       = "helpLinks"
+	CSSKey             = "css"
+	LoginTemplateKey   = "loginTemplate"
+
+	maxTitleLength = 100
+	maxLogoBytes   = 100 * 1024
+
+	// layoutCSS styles the elements which the pages only show when they are branded.
+	layoutCSS = `.branding{flex-direction:column;align-items:center}` +
+		`.branding-logo{display:block;max-width:100%;max-height:80px;margin:0 auto}` +
+		`.branding-title{font-size:16px;font-weight:700;margin-top:10px}` +
+		`.help-links ul{margin:0;padding:0;list-style:none}.help-links li{margin-bottom:5px}`
+)
+
+//nolint:gochecknoglobals // These are effectively constants.
+var (
+	colorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+	allowedLogoContentTypes = map[string]bool{
+		"image/png":  true,
+		"image/jpeg": true,
+		"image/gif":  true,
+		"image/webp": true,
+	}
+
+	allowedHelpLinkSchemes = map[string]bool{
+		"https":  true,
+		"http":   true,
+		"mailto": true,
+	}
+)
+
+// HelpLink is a link which is shown below the login form, e.g. to a page which explains how to reset a password.
+type HelpLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Branding is the validated content of a branding ConfigMap. A nil *Branding means that the default pages are shown.
+type Branding struct {
+	// Title is the name of the organization, which is shown on the pages. It may be empty.
+	Title string
+
+	// Logo is a data URI of the logo image. It may be empty.
+	Logo template.URL
+
+	// HelpLinks are shown below the login form.
+	HelpLinks []HelpLink
+
+	// LoginTemplate is the source of a Go html/template which replaces the login page. It may be empty.
+	LoginTemplate string
+
+	// css is the inline CSS which is added to every page, after the page's own CSS.
+	css string
+}
+
+// FromConfigMap validates the branding ConfigMap and returns its settings.
+func FromConfigMap(configMap *corev1.ConfigMap) (*Branding, error) {
+	b := &Branding{}
+
+	b.Title = strings.TrimSpace(configMap.Data[TitleKey])
+	if len(b.Title) > maxTitleLength {
+		return nil, fmt.Errorf("%q must be at most %d characters", TitleKey, maxTitleLength)
+	}
+
+	logo, err := logoDataURI(configMap)
+	if err != nil {
+		return nil, err
+	}
+	b.Logo = logo
+
+	cssRules := []string{layoutCSS}
+	if color := configMap.Data[BackgroundColorKey]; color != "" {
+		if !colorRegexp.MatchString(color) {
+			return nil, fmt.Errorf("%q must be a color in CSS hex notation, e.g. \"#1a73e8\"", BackgroundColorKey)
+		}
+		cssRules = append(cssRules, "body{background:"+color+"}")
+	}
+	if color := configMap.Data[PrimaryColorKey]; color != "" {
+		if !colorRegexp.MatchString(color) {
+			return nil, fmt.Errorf("%q must be a color in CSS hex notation, e.g. \"#1a73e8\"", PrimaryColorKey)
+		}
+		cssRules = append(cssRules, `.form-field input[type="submit"],.form-field button{background-color:`+color+"}")
+	}
+	if customCSS := strings.TrimSpace(configMap.Data[CSSKey]); customCSS != "" {
+		// The CSS is placed inside a <style> element, so it must not be able to end that element.
+		if strings.Contains(strings.ToLower(customCSS), "</style") {
+			return nil, fmt.Errorf("%q must not contain \"</style\"", CSSKey)
+		}
+		cssRules = append(cssRules, customCSS)
+	}
+	b.css = strings.Join(cssRules, "\n")
+
+	if helpLinksJSON := configMap.Data[HelpLinksKey]; helpLinksJSON != "" {
+		if err := json.Unmarshal([]byte(helpLinksJSON), &b.HelpLinks); err != nil {
+			return nil, fmt.Errorf("%q must be a JSON list of objects with \"text\" and \"url\" keys: %w", HelpLinksKey, err)
+		}
+		for i, link := range b.HelpLinks {
+			if link.Text == "" {
+				return nil, fmt.Errorf("%q[%d] must have text", HelpLinksKey, i)
+			}
+			parsedURL, err := url.Parse(link.URL)
+			if err != nil || !allowedHelpLinkSchemes[parsedURL.Scheme] {
+				return nil, fmt.Errorf("%q[%d] must have a url with scheme https, http, or mailto", HelpLinksKey, i)
+			}
+		}
+	}
+
+	b.LoginTemplate = configMap.Data[LoginTemplateKey]
+
+	return b, nil
+}
+
+func logoDataURI(configMap *corev1.ConfigMap) (template.URL, error) {
+	logo, hasBinaryLogo := configMap.BinaryData[LogoKey]
+	if !hasBinaryLogo {
+		encodedLogo, hasLogo := configMap.Data[LogoKey]
+		if !hasLogo {
+			return "", nil
+		}
+		var err error
+		logo, err = base64.StdEncoding.DecodeString(strings.TrimSpace(encodedLogo))
+		if err != nil {
+			return "", fmt.Errorf("%q in the data of the ConfigMap must be base64 encoded: %w", LogoKey, err)
+		}
+	}
+
+	if len(logo) > maxLogoBytes {
+		return "", fmt.Errorf("%q must be at most %d bytes", LogoKey, maxLogoBytes)
+	}
+	contentType := http.DetectContentType(logo)
+	if !allowedLogoContentTypes[contentType] {
+		return "", fmt.Errorf("%q must be a PNG, JPEG, GIF or WebP image, but its content type is %q", LogoKey, contentType)
+	}
+
+	// The content type was detected from a fixed list, and base64 does not contain any characters which need escaping.
+	return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(logo)), nil //nolint:gosec // see comment above
+}
+
+// CSS returns the inline CSS which is added to every page. Templates must not change it, otherwise it would not
+// match the hash in the Content-Security-Policy.
+func (b *Branding) CSS() template.CSS {
+	if b == nil {
+		return ""
+	}
+	return template.CSS(b.css) //nolint:gosec // This is configured by the administrator, not attacker-controlled.
+}
+
+// StyleSources returns the extra sources for the style-src directive of the Content-Security-Policy of the pages.
+func (b *Branding) StyleSources() []string {
+	if b == nil {
+		return nil
+	}
+	return []string{"'" + csp.Hash(b.css) + "'"}
+}
+
+// HasLogo returns true when the pages show a logo image, which requires the img-src directive of the
+// Content-Security-Policy of the pages to allow data URIs.
+func (b *Branding) HasLogo() bool {
+	return b != nil && b.Logo != ""
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package branding
+
+import (
+	"encoding/base64"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"go.pinniped.dev/internal/federationdomain/csp"
+)
+
+func TestFromConfigMap(t *testing.T) {
+	t.Parallel()
+
+	pngLogo := []byte("\x89PNG\r\n\x1a\n" + "some-png-data")
+	pngLogoDataURI := template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(pngLogo))
+
+	tests := []struct {
+		name         string
+		data         map[string]string
+		binaryData   map[string][]byte
+		wantBranding *Branding
+		wantErr      string
+	}{
+		{
+			name:         "empty ConfigMap",
+			wantBranding: &Branding{css: layoutCSS},
+		},
+		{
+			name: "all settings",
+			data: map[string]string{
+				"title":           "  Acme Corp  ",
+				"logo":            base64.StdEncoding.EncodeToString(pngLogo),
+				"primaryColor":    "#1a73e8",
+				"backgroundColor": "#FFF",
+				"helpLinks":       `[{"text":"Reset password","url":"https://example.com/reset"},{"text":"Help","url":"mailto:help@example.com"}]`,
+				"css":             "  h1{color:red}  ",
+				"loginTemplate":   "<p>{{.IDPName}}</p>",
+			},
+			wantBranding: &Branding{
+				Title: "Acme Corp",
+				Logo:  pngLogoDataURI,
+				HelpLinks: []HelpLink{
+					{Text: "Reset password", URL: "https://example.com/reset"},
+					{Text: "Help", URL: "mailto:help@example.com"},
+				},
+				LoginTemplate: "<p>{{.IDPName}}</p>",
+				css: layoutCSS + "\n" +
+					"body{background:#FFF}\n" +
+					`.form-field input[type="submit"],.form-field button{background-color:#1a73e8}` + "\n" +
+					"h1{color:red}",
+			},
+		},
+		{
+			name:         "logo in the binaryData",
+			binaryData:   map[string][]byte{"logo": pngLogo},
+			wantBranding: &Branding{Logo: pngLogoDataURI, css: layoutCSS},
+		},
+		{
+			name:    "title too long",
+			data:    map[string]string{"title": strings.Repeat("a", 101)},
+			wantErr: `"title" must be at most 100 characters`,
+		},
+		{
+			name:    "logo which is not base64 encoded",
+			data:    map[string]string{"logo": "not base64!"},
+			wantErr: `"logo" in the data of the ConfigMap must be base64 encoded: illegal base64 data at input byte 3`,
+		},
+		{
+			name:       "logo which is too large",
+			binaryData: map[string][]byte{"logo": append(pngLogo, make([]byte, 100*1024)...)},
+			wantErr:    `"logo" must be at most 102400 bytes`,
+		},
+		{
+			name:       "logo which is not an allowed image type",
+			binaryData: map[string][]byte{"logo": []byte("<svg></svg>")},
+			wantErr:    `"logo" must be a PNG, JPEG, GIF or WebP image, but its content type is "text/plain; charset=utf-8"`,
+		},
+		{
+			name:    "invalid primary color",
+			data:    map[string]string{"primaryColor": "red"},
+			wantErr: `"primaryColor" must be a color in CSS hex notation, e.g. "#1a73e8"`,
+		},
+		{
+			name:    "background color which tries to add more CSS",
+			data:    map[string]string{"backgroundColor": "#fff}body{display:none"},
+			wantErr: `"backgroundColor" must be a color in CSS hex notation, e.g. "#1a73e8"`,
+		},
+		{
+			name:    "css which tries to end the style element",
+			data:    map[string]string{"css": "h1{color:red}</STYLE><script>alert(1)</script>"},
+			wantErr: `"css" must not contain "</style"`,
+		},
+		{
+			name:    "help links which are not JSON",
+			data:    map[string]string{"helpLinks": "not json"},
+			wantErr: `"helpLinks" must be a JSON list of objects with "text" and "url" keys: invalid character 'o' in literal null (expecting 'u')`,
+		},
+		{
+			name:    "help link without text",
+			data:    map[string]string{"helpLinks": `[{"url":"https://example.com"}]`},
+			wantErr: `"helpLinks"[0] must have text`,
+		},
+		{
+			name:    "help link with a javascript url",
+			data:    map[string]string{"helpLinks": `[{"text":"ok","url":"https://example.com"},{"text":"bad","url":"javascript:alert(1)"}]`},
+			wantErr: `"helpLinks"[1] must have a url with scheme https, http, or mailto`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := FromConfigMap(&corev1.ConfigMap{Data: tt.data, BinaryData: tt.binaryData})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, b)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBranding, b)
+		})
+	}
+}
+
+func TestNilBranding(t *testing.T) {
+	t.Parallel()
+
+	var b *Branding
+	require.Empty(t, b.CSS())
+	require.Nil(t, b.StyleSources())
+	require.False(t, b.HasLogo())
+}
+
+func TestStyleSources(t *testing.T) {
+	t.Parallel()
+
+	b, err := FromConfigMap(&corev1.ConfigMap{Data: map[string]string{"css": "h1{color:red}"}})
+	require.NoError(t, err)
+	require.False(t, b.HasLogo())
+	require.Equal(t, []string{"'" + csp.Hash(string(b.CSS())) + "'"}, b.StyleSources())
+}
@@ -0,0 +1,109 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clientauthentication provides the client authentication strategy of the OIDC token endpoint.
+package clientauthentication
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/pkg/errors"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+)
+
+// NewStrategy returns a fosite.ClientAuthenticationStrategy which adds RFC8705 mutual TLS client authentication
+// for clients which use the tls_client_auth method. All other requests are authenticated by the default strategy,
+// which supports client secrets and the private_key_jwt method.
+func NewStrategy(clients fosite.ClientManager, defaultStrategy fosite.ClientAuthenticationStrategy) fosite.ClientAuthenticationStrategy {
+	return func(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error) {
+		clientID := form.Get("client_id")
+		if _, _, basicOK := r.BasicAuth(); basicOK || clientID == "" || form.Get("client_secret") != "" || form.Get("client_assertion_type") != "" {
+			// The request includes other credentials, or it does not identify the client,
+			// so let the default strategy handle it.
+			return defaultStrategy(ctx, r, form)
+		}
+
+		client, err := clients.GetClient(ctx, clientID)
+		if err != nil {
+			// Let the default strategy return the usual errors for unknown clients.
+			return defaultStrategy(ctx, r, form)
+		}
+
+		pinnipedClient, ok := client.(*clientregistry.Client)
+		if !ok || pinnipedClient.GetTokenEndpointAuthMethod() != string(configv1alpha1.ClientAuthenticationMethodTLSClientAuth) {
+			// This also handles public clients, like the pinniped-cli client, which only send their client ID.
+			return defaultStrategy(ctx, r, form)
+		}
+
+		if err := verifyClientCertificate(r.TLS, pinnipedClient.TLSClientAuth, time.Now()); err != nil {
+			return nil, err
+		}
+
+		return client, nil
+	}
+}
+
+func verifyClientCertificate(state *tls.ConnectionState, config *configv1alpha1.OIDCClientTLSClientAuth, now time.Time) error {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return errors.WithStack(fosite.ErrInvalidClient.WithHintf(
+			"The OAuth 2.0 Client supports client authentication method '%s', but no client certificate was presented.",
+			configv1alpha1.ClientAuthenticationMethodTLSClientAuth))
+	}
+
+	if config == nil {
+		// This shouldn't really happen, because the client would have been invalid.
+		return errors.WithStack(fosite.ErrServerError.WithHint("The OAuth 2.0 Client has no client certificate configuration."))
+	}
+
+	roots, err := oidcclientvalidator.ParseCertificateAuthorityData(config.CertificateAuthorityData)
+	if err != nil {
+		// This shouldn't really happen, because the client would have been invalid.
+		return errors.WithStack(fosite.ErrServerError.WithHint("The OAuth 2.0 Client has invalid certificate authority data.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	leaf := state.PeerCertificates[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return errors.WithStack(fosite.ErrInvalidClient.WithHint("The client certificate could not be verified.").WithWrap(err).WithDebug(err.Error()))
+	}
+
+	if !certificateMatchesSubject(leaf, config) {
+		return errors.WithStack(fosite.ErrInvalidClient.WithHint("The client certificate does not match the subject which is configured for the OAuth 2.0 Client."))
+	}
+
+	return nil
+}
+
+func certificateMatchesSubject(cert *x509.Certificate, config *configv1alpha1.OIDCClientTLSClientAuth) bool {
+	switch {
+	case config.SubjectDN != "":
+		return cert.Subject.String() == config.SubjectDN
+	case config.SANDNS != "":
+		return slices.Contains(cert.DNSNames, config.SANDNS)
+	case config.SANURI != "":
+		return slices.ContainsFunc(cert.URIs, func(uri *url.URL) bool { return uri.String() == config.SANURI })
+	case config.SANEmail != "":
+		return slices.Contains(cert.EmailAddresses, config.SANEmail)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientauthentication
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+)
+
+func TestCertificateMatchesSubject(t *testing.T) {
+	spiffeURI, err := url.Parse("spiffe://example.com/some-workload")
+	require.NoError(t, err)
+
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "some-client", Organization: []string{"some-org"}},
+		DNSNames:       []string{"client.example.com", "other-client.example.com"},
+		URIs:           []*url.URL{spiffeURI},
+		EmailAddresses: []string{"client@example.com"},
+	}
+
+	tests := []struct {
+		name   string
+		config *configv1alpha1.OIDCClientTLSClientAuth
+		want   bool
+	}{
+		{
+			name:   "matching subject DN",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SubjectDN: "CN=some-client,O=some-org"},
+			want:   true,
+		},
+		{
+			name:   "subject DN which only matches the common name",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SubjectDN: "CN=some-client"},
+			want:   false,
+		},
+		{
+			name:   "matching DNS SAN",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANDNS: "other-client.example.com"},
+			want:   true,
+		},
+		{
+			name:   "DNS SAN which does not match",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANDNS: "example.com"},
+			want:   false,
+		},
+		{
+			name:   "matching URI SAN",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANURI: "spiffe://example.com/some-workload"},
+			want:   true,
+		},
+		{
+			name:   "URI SAN which does not match",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANURI: "spiffe://example.com/other-workload"},
+			want:   false,
+		},
+		{
+			name:   "matching email SAN",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANEmail: "client@example.com"},
+			want:   true,
+		},
+		{
+			name:   "email SAN which does not match",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{SANEmail: "other@example.com"},
+			want:   false,
+		},
+		{
+			name:   "no subject configured",
+			config: &configv1alpha1.OIDCClientTLSClientAuth{},
+			want:   false,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, certificateMatchesSubject(cert, tt.config))
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package clientregistry defines Pinniped's OAuth2/OIDC clients.
@@ -27,6 +27,24 @@ import (
 // or a dynamic client defined by an OIDCClient CR.
 type Client struct {
 	fosite.DefaultOpenIDConnectClient
+
+	// ServiceIdentity is the identity of the client itself, which is used by the client_credentials grant.
+	// It is only set for dynamic clients which are allowed to use that grant. It is not serialized into
+	// session storage, because it is only needed while handling the token request.
+	ServiceIdentity *configv1alpha1.OIDCClientServiceIdentity `json:"-"`
+
+	// TLSClientAuth configures the client certificates of the client. It is only set for dynamic clients which use
+	// the tls_client_auth client authentication method. Like ServiceIdentity, it is not serialized into session storage.
+	TLSClientAuth *configv1alpha1.OIDCClientTLSClientAuth `json:"-"`
+
+	// Consent configures the consent page of the client. It is only set for dynamic clients which configure it.
+	// Like ServiceIdentity, it is not serialized into session storage.
+	Consent *configv1alpha1.OIDCClientConsent `json:"-"`
+
+	// RequirePushedAuthorizationRequests means that the authorization endpoint must reject authorization requests of
+	// this client which were not first pushed to the pushed authorization request endpoint. Like ServiceIdentity,
+	// it is not serialized into session storage.
+	RequirePushedAuthorizationRequests bool `json:"-"`
 }
 
 // Client implements the base, OIDC, and response_mode client interfaces of Fosite.
@@ -45,6 +63,16 @@ func (c *Client) GetResponseModes() []fosite.ResponseModeType {
 	return []fosite.ResponseModeType{fosite.ResponseModeDefault, fosite.ResponseModeQuery}
 }
 
+// GetRequestObjectSigningAlgorithm returns the algorithm which must be used to sign request objects.
+// Fosite accepts unsigned request objects when this is empty or "none", so those values are never returned.
+func (c *Client) GetRequestObjectSigningAlgorithm() string {
+	alg := c.DefaultOpenIDConnectClient.GetRequestObjectSigningAlgorithm()
+	if alg == "" || alg == "none" {
+		return coreosoidc.RS256
+	}
+	return alg
+}
+
 // ClientManager is a fosite.ClientManager with a statically-defined client and with dynamically-defined clients.
 type ClientManager struct {
 	oidcClientsClient supervisorclient.OIDCClientInterface
@@ -110,14 +138,15 @@ func (m *ClientManager) GetClient(ctx context.Context, id string) (fosite.Client
 		return nil, fmt.Errorf("client %q exists but is invalid or not ready", id)
 	}
 
-	// Everything is valid, so return the client. Note that it has at least one client secret to be considered valid.
+	// Everything is valid, so return the client. Note that it has at least one client secret to be considered valid,
+	// unless it uses a client authentication method which does not require client secrets.
 	return oidcClientCRToFositeClient(oidcClient, clientSecrets), nil
 }
 
 // ClientAssertionJWTValid returns an error if the JTI is
 // known or the DB check failed and nil if the JTI is not known.
 //
-// This functionality is not supported by the ClientManager.
+// This functionality is not supported by the ClientManager. See storage.KubeStorage instead.
 func (*ClientManager) ClientAssertionJWTValid(_ctx context.Context, _jti string) error {
 	return fmt.Errorf("not implemented")
 }
@@ -127,7 +156,7 @@ func (*ClientManager) ClientAssertionJWTValid(_ctx context.Context, _jti string)
 // up any existing JTIs that have expired as those tokens can
 // not be replayed due to the expiry.
 //
-// This functionality is not supported by the ClientManager.
+// This functionality is not supported by the ClientManager. See storage.KubeStorage instead.
 func (*ClientManager) SetClientAssertionJWT(_ctx context.Context, _jti string, _exp time.Time) error {
 	return fmt.Errorf("not implemented")
 }
@@ -158,10 +187,11 @@ func PinnipedCLI() *Client {
 				Audience: nil,
 				Public:   true,
 			},
-			RequestURIs:                       nil,
-			JSONWebKeys:                       nil,
-			JSONWebKeysURI:                    "",
-			RequestObjectSigningAlgorithm:     "",
+			RequestURIs:    nil,
+			JSONWebKeys:    nil,
+			JSONWebKeysURI: "",
+			// This client has no keys, so it cannot send request objects anyway.
+			RequestObjectSigningAlgorithm:     coreosoidc.RS256,
 			TokenEndpointAuthSigningAlgorithm: coreosoidc.RS256,
 			TokenEndpointAuthMethod:           "none",
 		},
@@ -169,7 +199,12 @@ func PinnipedCLI() *Client {
 }
 
 func oidcClientCRToFositeClient(oidcClient *configv1alpha1.OIDCClient, clientSecrets []string) *Client {
-	return &Client{
+	var serviceIdentity *configv1alpha1.OIDCClientServiceIdentity
+	if oidcClient.Spec.ServiceIdentity != nil {
+		serviceIdentity = oidcClient.Spec.ServiceIdentity.DeepCopy()
+	}
+
+	client := &Client{
 		DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
 			DefaultClient: &fosite.DefaultClient{
 				ID: oidcClient.Name,
@@ -185,14 +220,41 @@ func oidcClientCRToFositeClient(oidcClient *configv1alpha1.OIDCClient, clientSec
 				Audience:       nil,
 				Public:         false,
 			},
-			RequestURIs:                       nil,
-			JSONWebKeys:                       nil,
-			JSONWebKeysURI:                    "",
-			RequestObjectSigningAlgorithm:     "",
+			RequestURIs:    nil,
+			JSONWebKeys:    nil,
+			JSONWebKeysURI: "",
+			// Updated below to match the algorithm of the client's keys.
+			RequestObjectSigningAlgorithm:     coreosoidc.RS256,
 			TokenEndpointAuthSigningAlgorithm: coreosoidc.RS256,
-			TokenEndpointAuthMethod:           "client_secret_basic",
+			TokenEndpointAuthMethod:           string(oidcclientvalidator.ClientAuthenticationMethod(oidcClient)),
 		},
+		ServiceIdentity:                    serviceIdentity,
+		RequirePushedAuthorizationRequests: oidcClient.Spec.RequirePushedAuthorizationRequests,
+	}
+
+	if clientAuthentication := oidcClient.Spec.ClientAuthentication; clientAuthentication != nil {
+		if privateKeyJWT := clientAuthentication.PrivateKeyJWT; privateKeyJWT != nil {
+			if privateKeyJWT.JWKS != "" {
+				// This was already validated, so it should never fail to parse here.
+				client.JSONWebKeys, _ = oidcclientvalidator.ParseJWKS(privateKeyJWT.JWKS)
+			}
+			client.JSONWebKeysURI = privateKeyJWT.JWKSURL
+			if privateKeyJWT.SigningAlgorithm != "" {
+				// Request objects are verified with the same keys as client assertions, so require the same algorithm.
+				client.TokenEndpointAuthSigningAlgorithm = privateKeyJWT.SigningAlgorithm
+				client.RequestObjectSigningAlgorithm = privateKeyJWT.SigningAlgorithm
+			}
+		}
+		if clientAuthentication.TLSClientAuth != nil {
+			client.TLSClientAuth = clientAuthentication.TLSClientAuth.DeepCopy()
+		}
 	}
+
+	if oidcClient.Spec.Consent != nil {
+		client.Consent = oidcClient.Spec.Consent.DeepCopy()
+	}
+
+	return client
 }
 
 func scopesToArguments(scopes []configv1alpha1.Scope) fosite.Arguments {
@@ -1,10 +1,11 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package clientregistry
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -21,6 +22,7 @@ import (
 
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
 	"go.pinniped.dev/internal/testutil"
@@ -35,6 +37,9 @@ func TestClientManager(t *testing.T) {
 		testUID       = "test-uid-123"
 	)
 
+	testCA, err := certauthority.New("test CA", time.Hour)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name                   string
 		secrets                []*corev1.Secret
@@ -217,12 +222,144 @@ func TestClientManager(t *testing.T) {
 				require.Nil(t, c.GetRequestURIs())
 				require.Nil(t, c.GetJSONWebKeys())
 				require.Equal(t, "", c.GetJSONWebKeysURI())
-				require.Equal(t, "", c.GetRequestObjectSigningAlgorithm())
+				require.Equal(t, "RS256", c.GetRequestObjectSigningAlgorithm())
 				require.Equal(t, "client_secret_basic", c.GetTokenEndpointAuthMethod())
 				require.Equal(t, "RS256", c.GetTokenEndpointAuthSigningAlgorithm())
 				require.Equal(t, []fosite.ResponseModeType{"", "query"}, c.GetResponseModes())
 			},
 		},
+		{
+			name: "find a valid dynamic client which uses private_key_jwt",
+			oidcClients: []*configv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: configv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []configv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:       []configv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs: []configv1alpha1.RedirectURI{"https://foobar.com/callback"},
+						ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+							Method: "private_key_jwt",
+							PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{
+								JWKSURL:          "https://foobar.com/jwks.json",
+								SigningAlgorithm: "ES256",
+							},
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Equal(t, testName, c.GetID())
+				require.Empty(t, c.GetRotatedHashes())
+				require.Nil(t, c.GetJSONWebKeys())
+				require.Equal(t, "https://foobar.com/jwks.json", c.GetJSONWebKeysURI())
+				require.Equal(t, "private_key_jwt", c.GetTokenEndpointAuthMethod())
+				require.Equal(t, "ES256", c.GetTokenEndpointAuthSigningAlgorithm())
+				require.Equal(t, "ES256", c.GetRequestObjectSigningAlgorithm())
+				require.Nil(t, c.TLSClientAuth)
+			},
+		},
+		{
+			name: "find a valid dynamic client which uses tls_client_auth",
+			oidcClients: []*configv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: configv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []configv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:       []configv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs: []configv1alpha1.RedirectURI{"https://foobar.com/callback"},
+						ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+							Method: "tls_client_auth",
+							TLSClientAuth: &configv1alpha1.OIDCClientTLSClientAuth{
+								CertificateAuthorityData: base64.StdEncoding.EncodeToString(testCA.Bundle()),
+								SubjectDN:                "CN=some-client",
+							},
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Equal(t, testName, c.GetID())
+				require.Empty(t, c.GetRotatedHashes())
+				require.Nil(t, c.GetJSONWebKeys())
+				require.Equal(t, "", c.GetJSONWebKeysURI())
+				require.Equal(t, "tls_client_auth", c.GetTokenEndpointAuthMethod())
+				require.Equal(t, &configv1alpha1.OIDCClientTLSClientAuth{
+					CertificateAuthorityData: base64.StdEncoding.EncodeToString(testCA.Bundle()),
+					SubjectDN:                "CN=some-client",
+				}, c.TLSClientAuth)
+			},
+		},
+		{
+			name: "find a valid dynamic client which requires consent",
+			oidcClients: []*configv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: configv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []configv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:       []configv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs: []configv1alpha1.RedirectURI{"https://foobar.com/callback"},
+						ClientAuthentication: &configv1alpha1.OIDCClientAuthentication{
+							Method: "private_key_jwt",
+							PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{
+								JWKSURL: "https://foobar.com/jwks.json",
+							},
+						},
+						Consent: &configv1alpha1.OIDCClientConsent{
+							Required:    true,
+							DisplayName: "Some Client",
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Equal(t, testName, c.GetID())
+				require.Equal(t, &configv1alpha1.OIDCClientConsent{
+					Required:    true,
+					DisplayName: "Some Client",
+				}, c.Consent)
+			},
+		},
+		{
+			name: "find a valid dynamic client which requires pushed authorization requests",
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			oidcClients: []*configv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: configv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:                  []configv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:                      []configv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:                []configv1alpha1.RedirectURI{"https://foobar.com/callback"},
+						RequirePushedAuthorizationRequests: true,
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Equal(t, testName, c.GetID())
+				require.True(t, c.RequirePushedAuthorizationRequests)
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -275,7 +412,7 @@ func requireEqualsPinnipedCLI(t *testing.T, c *Client) {
 	require.Nil(t, c.GetRequestURIs())
 	require.Nil(t, c.GetJSONWebKeys())
 	require.Equal(t, "", c.GetJSONWebKeysURI())
-	require.Equal(t, "", c.GetRequestObjectSigningAlgorithm())
+	require.Equal(t, "RS256", c.GetRequestObjectSigningAlgorithm())
 	require.Equal(t, "none", c.GetTokenEndpointAuthMethod())
 	require.Equal(t, "RS256", c.GetTokenEndpointAuthSigningAlgorithm())
 	require.Equal(t, []fosite.ResponseModeType{"", "query", "form_post"}, c.GetResponseModes())
@@ -311,7 +448,30 @@ func requireEqualsPinnipedCLI(t *testing.T, c *Client) {
 		  "jwks": null,
 		  "token_endpoint_auth_method": "none",
 		  "request_uris": null,
-		  "request_object_signing_alg": "",
+		  "request_object_signing_alg": "RS256",
 		  "token_endpoint_auth_signing_alg": "RS256"
 		}`, string(marshaled))
 }
+
+func TestGetRequestObjectSigningAlgorithm(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		want       string
+	}{
+		{name: "unset", configured: "", want: "RS256"},
+		{name: "unsigned request objects are never allowed", configured: "none", want: "RS256"},
+		{name: "configured", configured: "ES256", want: "ES256"},
+	}
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+				DefaultClient:                 &fosite.DefaultClient{},
+				RequestObjectSigningAlgorithm: test.configured,
+			}}
+			require.Equal(t, test.want, c.GetRequestObjectSigningAlgorithm())
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package downstreamsession provides some shared helpers for creating downstream OIDC sessions.
@@ -7,6 +7,7 @@ package downstreamsession
 import (
 	"context"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/ory/fosite"
@@ -45,8 +46,8 @@ func NewPinnipedSession(
 ) (*psession.PinnipedSession, error) {
 	now := time.Now().UTC()
 
-	downstreamUsername, downstreamGroups, err := applyIdentityTransformations(ctx,
-		idp.GetTransforms(), c.UpstreamIdentity.UpstreamUsername, c.UpstreamIdentity.UpstreamGroups)
+	downstreamUsername, downstreamGroups, additionalClaimsFromTransforms, err := applyIdentityTransformations(ctx,
+		idp.GetTransforms(), c.UpstreamIdentity.UpstreamUsername, c.UpstreamIdentity.UpstreamGroups, c.UpstreamIdentity.UpstreamClaims)
 	if err != nil {
 		return nil, err
 	}
@@ -55,10 +56,14 @@ func NewPinnipedSession(
 		Username:         downstreamUsername,
 		UpstreamUsername: c.UpstreamIdentity.UpstreamUsername,
 		UpstreamGroups:   c.UpstreamIdentity.UpstreamGroups,
+		UpstreamClaims:   c.UpstreamIdentity.UpstreamClaims,
 		ProviderUID:      idp.GetProvider().GetResourceUID(),
 		ProviderName:     idp.GetProvider().GetName(),
 		ProviderType:     idp.GetSessionProviderType(),
 		Warnings:         c.UpstreamLoginExtras.Warnings,
+
+		AdditionalClaimsFromTransforms: additionalClaimsFromTransforms,
+		DownstreamGroups:               downstreamGroups,
 	}
 	idp.ApplyIDPSpecificSessionDataToSession(customSessionData, c.UpstreamIdentity.IDPSpecificSessionData)
 
@@ -88,8 +93,13 @@ func NewPinnipedSession(
 		extras[oidcapi.IDTokenClaimGroups] = downstreamGroups
 	}
 
-	if len(c.UpstreamLoginExtras.DownstreamAdditionalClaims) > 0 {
-		extras[oidcapi.IDTokenClaimAdditionalClaims] = c.UpstreamLoginExtras.DownstreamAdditionalClaims
+	// The additional claims which were computed by the identity transformations take precedence over the
+	// additional claims which were determined by the identity provider.
+	additionalClaims := map[string]interface{}{}
+	maps.Copy(additionalClaims, c.UpstreamLoginExtras.DownstreamAdditionalClaims)
+	maps.Copy(additionalClaims, additionalClaimsFromTransforms)
+	if len(additionalClaims) > 0 {
+		extras[oidcapi.IDTokenClaimAdditionalClaims] = additionalClaims
 	}
 
 	pinnipedSession.IDTokenClaims().Extra = extras
@@ -124,21 +134,22 @@ func AutoApproveScopes(authorizeRequester fosite.AuthorizeRequester) {
 }
 
 // applyIdentityTransformations applies an identity transformation pipeline to an upstream identity to transform
-// or potentially reject the identity.
+// or potentially reject the identity. It also returns the additional claims computed by the pipeline, if any.
 func applyIdentityTransformations(
 	ctx context.Context,
 	transforms *idtransform.TransformationPipeline,
 	username string,
 	groups []string,
-) (string, []string, error) {
-	transformationResult, err := transforms.Evaluate(ctx, username, groups)
+	upstreamClaims map[string]interface{},
+) (string, []string, map[string]interface{}, error) {
+	transformationResult, err := transforms.Evaluate(ctx, username, groups, upstreamClaims)
 	if err != nil {
 		plog.Error("unexpected identity transformation error during authentication", err, "inputUsername", username)
-		return "", nil, idTransformUnexpectedErr
+		return "", nil, nil, idTransformUnexpectedErr
 	}
 	if !transformationResult.AuthenticationAllowed {
 		plog.Debug("authentication rejected by configured policy", "inputUsername", username, "inputGroups", groups)
-		return "", nil, fmt.Errorf("configured identity policy rejected this authentication: %s", transformationResult.RejectedAuthenticationMessage)
+		return "", nil, nil, fmt.Errorf("configured identity policy rejected this authentication: %s", transformationResult.RejectedAuthenticationMessage)
 	}
 	plog.Debug("identity transformation successfully applied during authentication",
 		"originalUsername", username,
@@ -146,5 +157,5 @@ func applyIdentityTransformations(
 		"originalGroups", groups,
 		"newGroups", transformationResult.Groups,
 	)
-	return transformationResult.Username, transformationResult.Groups, nil
+	return transformationResult.Username, transformationResult.Groups, transformationResult.AdditionalClaims, nil
 }
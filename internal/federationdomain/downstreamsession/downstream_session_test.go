@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package downstreamsession
@@ -16,13 +16,16 @@ import (
 
 func TestApplyIdentityTransformations(t *testing.T) {
 	tests := []struct {
-		name         string
-		transforms   []celtransformer.CELTransformation
-		username     string
-		groups       []string
-		wantUsername string
-		wantGroups   []string
-		wantErr      string
+		name                 string
+		transforms           []celtransformer.CELTransformation
+		additionalClaims     []*celtransformer.AdditionalClaimExpression
+		username             string
+		groups               []string
+		claims               map[string]interface{}
+		wantUsername         string
+		wantGroups           []string
+		wantAdditionalClaims map[string]interface{}
+		wantErr              string
 	}{
 		{
 			name: "unexpected errors",
@@ -65,6 +68,46 @@ func TestApplyIdentityTransformations(t *testing.T) {
 			wantUsername: "pre:ryan",
 			wantGroups:   []string{"pre:a", "pre:b"},
 		},
+		{
+			name: "successful auth using upstream claims",
+			transforms: []celtransformer.CELTransformation{
+				&celtransformer.AllowAuthenticationPolicy{Expression: `upstreamClaims.email_verified == true`},
+				&celtransformer.UsernameTransformation{Expression: `upstreamClaims.email`},
+			},
+			username:     "ryan",
+			groups:       []string{"a", "b"},
+			claims:       map[string]interface{}{"email": "ryan@example.com", "email_verified": true},
+			wantUsername: "ryan@example.com",
+			wantGroups:   []string{"a", "b"},
+		},
+		{
+			name: "successful auth with additional claims",
+			transforms: []celtransformer.CELTransformation{
+				&celtransformer.UsernameTransformation{Expression: `"pre:" + username`},
+			},
+			additionalClaims: []*celtransformer.AdditionalClaimExpression{
+				{Name: "email", Expression: `upstreamClaims.email`},
+				{Name: "displayName", Expression: `username`},
+			},
+			username:     "ryan",
+			groups:       []string{"a", "b"},
+			claims:       map[string]interface{}{"email": "ryan@example.com"},
+			wantUsername: "pre:ryan",
+			wantGroups:   []string{"a", "b"},
+			wantAdditionalClaims: map[string]interface{}{
+				"email":       "ryan@example.com",
+				"displayName": "pre:ryan",
+			},
+		},
+		{
+			name: "unexpected errors from additional claims",
+			additionalClaims: []*celtransformer.AdditionalClaimExpression{
+				{Name: "email", Expression: `upstreamClaims.email`},
+			},
+			username: "ryan",
+			groups:   []string{"a", "b"},
+			wantErr:  "configured identity transformation or policy resulted in unexpected error",
+		},
 	}
 
 	for _, test := range tests {
@@ -81,16 +124,23 @@ func TestApplyIdentityTransformations(t *testing.T) {
 				require.NoError(t, err)
 				pipeline.AppendTransformation(compiledTransform)
 			}
+			for _, claim := range tt.additionalClaims {
+				compiledClaim, err := transformer.CompileAdditionalClaim(claim, nil)
+				require.NoError(t, err)
+				pipeline.AppendAdditionalClaim(compiledClaim)
+			}
 
-			gotUsername, gotGroups, err := applyIdentityTransformations(context.Background(), pipeline, tt.username, tt.groups)
+			gotUsername, gotGroups, gotAdditionalClaims, err := applyIdentityTransformations(context.Background(), pipeline, tt.username, tt.groups, tt.claims)
 			if tt.wantErr != "" {
 				require.EqualError(t, err, tt.wantErr)
 				require.Empty(t, gotUsername)
 				require.Nil(t, gotGroups)
+				require.Nil(t, gotAdditionalClaims)
 			} else {
 				require.NoError(t, err)
 				require.Equal(t, tt.wantUsername, gotUsername)
 				require.Equal(t, tt.wantGroups, gotGroups)
+				require.Equal(t, tt.wantAdditionalClaims, gotAdditionalClaims)
 			}
 		})
 	}
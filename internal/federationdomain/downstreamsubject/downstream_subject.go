@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package downstreamsubject
@@ -24,3 +24,9 @@ func OIDC(upstreamIssuerAsString string, upstreamSubject string, idpDisplayName
 		oidc.IDTokenClaimSubject, url.QueryEscape(upstreamSubject),
 	)
 }
+
+func ServiceIdentity(downstreamIssuer string, clientID string) string {
+	return fmt.Sprintf("%s?%s=%s", downstreamIssuer,
+		oidc.IDTokenSubClaimClientIDQueryParam, url.QueryEscape(clientID),
+	)
+}
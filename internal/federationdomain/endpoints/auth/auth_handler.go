@@ -1,13 +1,15 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package auth provides a handler for the OIDC authorization endpoint.
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ory/fosite"
@@ -15,12 +17,16 @@ import (
 	"github.com/ory/fosite/token/jwt"
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/formposthtml"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
 	"go.pinniped.dev/internal/httputil/responseutil"
 	"go.pinniped.dev/internal/httputil/securityheader"
 	"go.pinniped.dev/internal/plog"
@@ -30,8 +36,50 @@ import (
 )
 
 const (
-	promptParamName = "prompt"
-	promptParamNone = "none"
+	promptParamName     = "prompt"
+	promptParamNone     = "none"
+	requestURIParamName = "request_uri"
+	requestParamName    = "request"
+)
+
+// These are the errors returned by the authorization endpoint when a browserless login requires a second factor.
+var (
+	// errOneTimePasswordRequired tells the client to send the request again with the user's one-time password
+	// in the oidcapi.AuthorizeOneTimePasswordHeaderName header.
+	errOneTimePasswordRequired = &fosite.RFC6749Error{
+		ErrorField:       oidcapi.AuthorizeErrorOneTimePasswordRequired,
+		DescriptionField: "A one-time password is required for this identity provider.",
+		CodeField:        http.StatusUnauthorized,
+	}
+
+	errOneTimePasswordNotEnrolled = &fosite.RFC6749Error{
+		ErrorField:       "access_denied", // this string matches what fosite uses for access denied errors
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "An authenticator app has not been enrolled yet. Log in using a web browser to enroll one.",
+		CodeField:        http.StatusForbidden,
+	}
+
+	errOneTimePasswordNotAccepted = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "One-time password not accepted.",
+		CodeField:        http.StatusForbidden,
+	}
+
+	errOneTimePasswordLockedOut = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "Too many incorrect one-time passwords. Wait a few minutes before trying again.",
+		CodeField:        http.StatusForbidden,
+	}
+
+	// errSecurityKeyRequiresBrowser is returned because security keys can only be used by web browsers.
+	errSecurityKeyRequiresBrowser = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "A security key is required for this identity provider. Log in using a web browser.",
+		CodeField:        http.StatusForbidden,
+	}
 )
 
 type authorizeHandler struct {
@@ -39,11 +87,13 @@ type authorizeHandler struct {
 	idpFinder                 federationdomainproviders.FederationDomainIdentityProvidersFinderI
 	oauthHelperWithoutStorage fosite.OAuth2Provider
 	oauthHelperWithStorage    fosite.OAuth2Provider
+	parStorage                fosite.PARStorage
 	generateCSRF              func() (csrftoken.CSRFToken, error)
 	generatePKCE              func() (pkce.Code, error)
 	generateNonce             func() (nonce.Nonce, error)
 	upstreamStateEncoder      oidc.Encoder
 	cookieCodec               oidc.Codec
+	secondFactorStorage       *secondfactor.Storage
 }
 
 func NewHandler(
@@ -51,27 +101,32 @@ func NewHandler(
 	idpFinder federationdomainproviders.FederationDomainIdentityProvidersFinderI,
 	oauthHelperWithoutStorage fosite.OAuth2Provider,
 	oauthHelperWithStorage fosite.OAuth2Provider,
+	parStorage fosite.PARStorage,
 	generateCSRF func() (csrftoken.CSRFToken, error),
 	generatePKCE func() (pkce.Code, error),
 	generateNonce func() (nonce.Nonce, error),
 	upstreamStateEncoder oidc.Encoder,
 	cookieCodec oidc.Codec,
+	secondFactorStorage *secondfactor.Storage,
+	pageBranding *branding.Branding,
 ) http.Handler {
 	h := &authorizeHandler{
 		downstreamIssuerURL:       downstreamIssuerURL,
 		idpFinder:                 idpFinder,
 		oauthHelperWithoutStorage: oauthHelperWithoutStorage,
 		oauthHelperWithStorage:    oauthHelperWithStorage,
+		parStorage:                parStorage,
 		generateCSRF:              generateCSRF,
 		generatePKCE:              generatePKCE,
 		generateNonce:             generateNonce,
 		upstreamStateEncoder:      upstreamStateEncoder,
 		cookieCodec:               cookieCodec,
+		secondFactorStorage:       secondFactorStorage,
 	}
 	// During a response_mode=form_post auth request using the browser flow, the custom form_post html page may
 	// be used to post certain errors back to the CLI from this handler's response, so allow the form_post
 	// page's CSS and JS to run.
-	return securityheader.WrapWithCustomCSP(h, formposthtml.ContentSecurityPolicy())
+	return securityheader.WrapWithCustomCSP(h, formposthtml.ContentSecurityPolicy(pageBranding))
 }
 
 func (h *authorizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -112,16 +167,33 @@ func (h *authorizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// When the client pushed the params of this authorization request to the pushed authorization request endpoint,
+	// then the request_uri param identifies the pushed params.
+	pushedParams, err := h.readPushedAuthorizeRequest(r)
+	if err != nil {
+		oidc.WriteAuthorizeError(r, w, h.oauthHelperWithoutStorage, fosite.NewAuthorizeRequest(), err, requestedBrowserlessFlow)
+		return
+	}
+
 	// Note that the client might have used oidcapi.AuthorizeUpstreamIDPNameParamName and
 	// oidcapi.AuthorizeUpstreamIDPTypeParamName query (or form) params to request a certain upstream IDP.
 	// The Pinniped CLI has been sending these params since v0.9.0.
 	idpNameQueryParamValue := r.Form.Get(oidcapi.AuthorizeUpstreamIDPNameParamName)
+	if idpNameQueryParamValue == "" && pushedParams != nil {
+		idpNameQueryParamValue = pushedParams.Get(oidcapi.AuthorizeUpstreamIDPNameParamName)
+	}
+
+	// When the client did not request a specific IDP, then the routing rules of the IDPs might choose one.
+	if idpNameQueryParamValue == "" && !h.idpFinder.HasDefaultIDP() {
+		idpNameQueryParamValue = routeToUpstreamIDP(r, pushedParams, h.idpFinder, requestedBrowserlessFlow)
+	}
 
 	// Check if we are in a special case where we should inject an interstitial page to ask the user
 	// which IDP they would like to use.
 	if shouldShowIDPChooser(h.idpFinder, idpNameQueryParamValue, requestedBrowserlessFlow) {
 		// Redirect to the IDP chooser page with all the same query/form params. When the user chooses an IDP,
 		// it will redirect back to here with all the same params again, with the pinniped_idp_name param added.
+		// When the params were pushed, this includes the request_uri param, which has not been used up yet.
 		http.Redirect(w, r,
 			fmt.Sprintf("%s%s?%s", h.downstreamIssuerURL, oidc.ChooseIDPEndpointPath, r.Form.Encode()),
 			http.StatusSeeOther,
@@ -141,7 +213,44 @@ func (h *authorizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.authorize(w, r, requestedBrowserlessFlow, idpNameQueryParamValue, idp)
+	if pushedParams != nil {
+		// Each request_uri may only be used once, so delete the pushed params before using them.
+		if err := h.parStorage.DeletePARSession(r.Context(), r.Form.Get(requestURIParamName)); err != nil {
+			oidc.WriteAuthorizeError(r, w, h.oauthHelperWithoutStorage, fosite.NewAuthorizeRequest(),
+				fosite.ErrInvalidRequestURI.WithHint("The 'request_uri' was already used.").WithWrap(err).WithDebug(err.Error()),
+				requestedBrowserlessFlow)
+			return
+		}
+		// Only the pushed params are used, except for the IDP name, which might have been added by the IDP chooser page.
+		r.Form = pushedParams
+		if idpNameQueryParamValue != "" {
+			r.Form.Set(oidcapi.AuthorizeUpstreamIDPNameParamName, idpNameQueryParamValue)
+		}
+	}
+
+	h.authorize(w, r, requestedBrowserlessFlow, idpNameQueryParamValue, idp, pushedParams != nil)
+}
+
+// readPushedAuthorizeRequest returns the params which were pushed to the pushed authorization request endpoint,
+// or nil when the request does not have a request_uri param which was returned by that endpoint.
+func (h *authorizeHandler) readPushedAuthorizeRequest(r *http.Request) (url.Values, error) {
+	requestURI := r.Form.Get(requestURIParamName)
+	if !strings.HasPrefix(requestURI, oidc.PushedAuthorizeRequestURIPrefix) {
+		return nil, nil
+	}
+
+	pushedAuthorizeRequest, err := h.parStorage.GetPARSession(r.Context(), requestURI)
+	if err != nil {
+		return nil, fosite.ErrInvalidRequestURI.
+			WithHint("The 'request_uri' is invalid, was already used, or has expired.").
+			WithWrap(err).WithDebug(err.Error())
+	}
+
+	if pushedAuthorizeRequest.GetClient().GetID() != r.Form.Get("client_id") {
+		return nil, fosite.ErrInvalidRequest.WithHint("The 'client_id' must match the one sent in the pushed authorization request.")
+	}
+
+	return pushedAuthorizeRequest.GetRequestForm(), nil
 }
 
 func (h *authorizeHandler) authorize(
@@ -150,6 +259,7 @@ func (h *authorizeHandler) authorize(
 	requestedBrowserlessFlow bool,
 	idpNameQueryParamValue string,
 	idp resolvedprovider.FederationDomainResolvedIdentityProvider,
+	usedPushedAuthorizeRequest bool,
 ) {
 	// Browser flows do not need session storage at this step. For browser flows, the request parameters
 	// should be forwarded to the next step as upstream state parameters to avoid storing session state
@@ -165,6 +275,13 @@ func (h *authorizeHandler) authorize(
 		return
 	}
 
+	if !usedPushedAuthorizeRequest && requiresPushedAuthorizeRequest(authorizeRequester.GetClient()) {
+		oidc.WriteAuthorizeError(r, w, oauthHelper, authorizeRequester,
+			fosite.ErrInvalidRequest.WithHint("Pushed Authorization Requests are required by this client but no such request was sent."),
+			requestedBrowserlessFlow)
+		return
+	}
+
 	maybeLogDeprecationWarningForMissingIDPParam(idpNameQueryParamValue, authorizeRequester)
 
 	// Automatically grant certain scopes, but only if they were requested.
@@ -216,11 +333,44 @@ func (h *authorizeHandler) authorizeWithoutBrowser(
 		return fosite.ErrAccessDenied.WithHintf("Reason: %s.", err.Error())
 	}
 
+	if loginExtras != nil && loginExtras.RequireWebAuthn {
+		return errSecurityKeyRequiresBrowser
+	}
+
+	if loginExtras != nil && loginExtras.RequireTOTP {
+		if err := h.verifyOneTimePasswordHeader(r, session.Fosite.Claims.Subject); err != nil {
+			return err
+		}
+	}
+
 	oidc.PerformAuthcodeRedirect(r, w, oauthHelper, authorizeRequester, session, true)
 
 	return nil
 }
 
+// verifyOneTimePasswordHeader checks the one-time password which the client sent along with the username and password.
+// Users can only enroll their authenticator app on the login page, because they need to see their new TOTP secret.
+func (h *authorizeHandler) verifyOneTimePasswordHeader(r *http.Request, subject string) error {
+	oneTimePassword := r.Header.Get(oidcapi.AuthorizeOneTimePasswordHeaderName)
+	if oneTimePassword == "" {
+		return errOneTimePasswordRequired
+	}
+
+	err := h.secondFactorStorage.VerifyTOTP(r.Context(), subject, oneTimePassword)
+	switch {
+	case errors.Is(err, secondfactor.ErrNotEnrolled):
+		return errOneTimePasswordNotEnrolled
+	case errors.Is(err, secondfactor.ErrIncorrectPassword):
+		return errOneTimePasswordNotAccepted
+	case errors.Is(err, secondfactor.ErrTooManyAttempts):
+		return errOneTimePasswordLockedOut
+	case err != nil:
+		plog.Error("error checking one-time password", err)
+		return fosite.ErrServerError.WithHint("Error checking one-time password.").WithWrap(err)
+	}
+	return nil
+}
+
 func (h *authorizeHandler) authorizeWithBrowser(
 	r *http.Request,
 	w http.ResponseWriter,
@@ -269,6 +419,39 @@ func shouldShowIDPChooser(
 		!inBackwardsCompatMode && federationDomainSpecHasSomeValidIDPs
 }
 
+// routeToUpstreamIDP returns the display name of the first IDP whose routing rules match the user's login hint,
+// or an empty string when there is no login hint or when no IDP matches it. The login hint is the login_hint param,
+// or for browserless flows without a login_hint param, the username header.
+func routeToUpstreamIDP(
+	r *http.Request,
+	pushedParams url.Values,
+	idpFinder federationdomainproviders.FederationDomainIdentityProvidersFinderI,
+	requestedBrowserlessFlow bool,
+) string {
+	loginHint := r.Form.Get(idprouting.LoginHintParamName)
+	if loginHint == "" && pushedParams != nil {
+		loginHint = pushedParams.Get(idprouting.LoginHintParamName)
+	}
+	if loginHint == "" && requestedBrowserlessFlow {
+		loginHint = r.Header.Get(oidcapi.AuthorizeUsernameHeaderName)
+	}
+	if loginHint == "" {
+		return ""
+	}
+
+	idp, err := idpFinder.FindUpstreamIDPByLoginHint(loginHint)
+	if err != nil {
+		return ""
+	}
+	plog.Debug("routing rules chose an identity provider for authorization request", "idpDisplayName", idp.GetDisplayName())
+	return idp.GetDisplayName()
+}
+
+func requiresPushedAuthorizeRequest(client fosite.Client) bool {
+	pinnipedClient, ok := client.(*clientregistry.Client)
+	return ok && pinnipedClient.RequirePushedAuthorizationRequests
+}
+
 func requireStaticClientForUsernameAndPasswordHeaders(authorizeRequester fosite.AuthorizeRequester) error {
 	if !(authorizeRequester.GetClient().GetID() == oidcapi.ClientIDPinnipedCLI) {
 		return fosite.ErrAccessDenied.WithHint("This client is not allowed to submit username or password headers to this endpoint.")
@@ -414,6 +597,7 @@ func generateUpstreamAuthorizeRequestState(
 		EncodedStateParam: encodedStateParamValue,
 		PKCE:              pkceValue,
 		Nonce:             nonceValue,
+		AuthParams:        authorizeRequester.GetRequestForm(),
 	}, nil
 }
 
@@ -452,7 +636,8 @@ func upstreamStateParam(
 		// that are reading from the encoded upstream state param being built here.
 		// The UpstreamName and UpstreamType struct fields can be used instead.
 		// Remove those params here to avoid potential confusion about which should be used later.
-		AuthParams:    removeCustomIDPParams(authorizeRequester.GetRequestForm()).Encode(),
+		// The request object (JAR) is also removed, because its claims were already copied into the other params.
+		AuthParams:    removeUnnecessaryParams(authorizeRequester.GetRequestForm()).Encode(),
 		UpstreamName:  upstreamDisplayName,
 		UpstreamType:  upstreamType,
 		Nonce:         nonceValue,
@@ -467,7 +652,7 @@ func upstreamStateParam(
 	return encodedStateParamValue, nil
 }
 
-func removeCustomIDPParams(params url.Values) url.Values {
+func removeUnnecessaryParams(params url.Values) url.Values {
 	p := url.Values{}
 	// Copy all params.
 	for k, v := range params {
@@ -476,6 +661,7 @@ func removeCustomIDPParams(params url.Values) url.Values {
 	// Remove the unnecessary params.
 	delete(p, oidcapi.AuthorizeUpstreamIDPNameParamName)
 	delete(p, oidcapi.AuthorizeUpstreamIDPTypeParamName)
+	delete(p, requestParamName)
 	return p
 }
 
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package auth
@@ -21,6 +21,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes/fake"
@@ -32,9 +33,13 @@ import (
 	"go.pinniped.dev/internal/authenticators"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
 	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/fositestorage/totpenrollment"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/internal/testutil"
@@ -83,6 +88,8 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		dynamicClientID     = "client.oauth.pinniped.dev-test-name"
 		dynamicClientUID    = "fake-client-uid"
 
+		pushedRequestURI = "urn:ietf:params:oauth:request_uri:some-pushed-request"
+
 		transformationUsernamePrefix = "username_prefix:"
 		transformationGroupsPrefix   = "groups_prefix:"
 	)
@@ -106,6 +113,26 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			}
 		`)
 
+		fositeInvalidPushedRequestURIErrorBody = here.Doc(`
+			{
+				"error":             "invalid_request_uri",
+				"error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. The 'request_uri' is invalid, was already used, or has expired."
+			}
+		`)
+
+		fositePushedRequestClientMismatchErrorBody = here.Doc(`
+			{
+				"error":             "invalid_request",
+				"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The 'client_id' must match the one sent in the pushed authorization request."
+			}
+		`)
+
+		fositePushedAuthorizationRequestRequiredErrorQuery = map[string]string{
+			"error":             "invalid_request",
+			"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. Pushed Authorization Requests are required by this client but no such request was sent.",
+			"state":             happyState,
+		}
+
 		fositePromptHasNoneAndOtherValueErrorQuery = map[string]string{
 			"error":             "invalid_request",
 			"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. Parameter 'prompt' was set to 'none', but contains other values as well which is not allowed.",
@@ -166,6 +193,36 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			"state":             happyState,
 		}
 
+		oneTimePasswordRequiredErrorQuery = map[string]string{
+			"error":             "pinniped_one_time_password_required",
+			"error_description": "A one-time password is required for this identity provider.",
+			"state":             happyState,
+		}
+
+		fositeAccessDeniedWithOneTimePasswordNotAcceptedHintErrorQuery = map[string]string{
+			"error":             "access_denied",
+			"error_description": "The resource owner or authorization server denied the request. One-time password not accepted.",
+			"state":             happyState,
+		}
+
+		fositeAccessDeniedWithOneTimePasswordLockedOutHintErrorQuery = map[string]string{
+			"error":             "access_denied",
+			"error_description": "The resource owner or authorization server denied the request. Too many incorrect one-time passwords. Wait a few minutes before trying again.",
+			"state":             happyState,
+		}
+
+		fositeAccessDeniedWithSecurityKeyRequiresBrowserHintErrorQuery = map[string]string{
+			"error":             "access_denied",
+			"error_description": "The resource owner or authorization server denied the request. A security key is required for this identity provider. Log in using a web browser.",
+			"state":             happyState,
+		}
+
+		fositeAccessDeniedWithOneTimePasswordNotEnrolledHintErrorQuery = map[string]string{
+			"error":             "access_denied",
+			"error_description": "The resource owner or authorization server denied the request. An authenticator app has not been enrolled yet. Log in using a web browser to enroll one.",
+			"state":             happyState,
+		}
+
 		fositeAccessDeniedWithMissingUsernamePasswordHintErrorQuery = map[string]string{
 			"error":             "access_denied",
 			"error_description": "The resource owner or authorization server denied the request. Missing or blank username or password.",
@@ -266,14 +323,14 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		// Inject this into our test subject at the last second so we get a fresh storage for every test.
 		// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
 		kubeOauthStore := storage.NewKubeStorage(secretsClient, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
-		return oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration), kubeOauthStore
+		return oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration, nil, nil), kubeOauthStore
 	}
 
 	createOauthHelperWithNullStorage := func(secretsClient v1.SecretInterface, oidcClientsClient v1alpha1.OIDCClientInterface) (fosite.OAuth2Provider, *storage.NullStorage) {
 		// Configure fosite the same way that the production code would, using NullStorage to turn off storage.
 		// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
 		nullOauthStore := storage.NewNullStorage(secretsClient, oidcClientsClient, bcrypt.MinCost)
-		return oidc.FositeOauth2Helper(nullOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration), nullOauthStore
+		return oidc.FositeOauth2Helper(nullOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration, nil, nil), nullOauthStore
 	}
 
 	upstreamAuthURL, err := url.Parse("https://some-upstream-idp:8443/auth")
@@ -352,6 +409,11 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		return nil, false, nil
 	}
 
+	exampleDotComRouting, err := idprouting.New([]string{"example.com"}, "")
+	require.NoError(t, err)
+	ldapUsernameRouting, err := idprouting.New(nil, "some-ldap-.*")
+	require.NoError(t, err)
+
 	upstreamLDAPIdentityProviderBuilder := func() *oidctestutil.TestUpstreamLDAPIdentityProviderBuilder {
 		return oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().
 			WithName(ldapUpstreamName).
@@ -537,6 +599,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		Username:         happyLDAPUsernameFromAuthenticator,
 		UpstreamUsername: happyLDAPUsernameFromAuthenticator,
 		UpstreamGroups:   happyLDAPGroups,
+		DownstreamGroups: happyLDAPGroups,
 		ProviderUID:      activeDirectoryUpstreamResourceUID,
 		ProviderName:     activeDirectoryUpstreamName,
 		ProviderType:     psession.ProviderTypeActiveDirectory,
@@ -552,6 +615,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		Username:         happyLDAPUsernameFromAuthenticator,
 		UpstreamUsername: happyLDAPUsernameFromAuthenticator,
 		UpstreamGroups:   happyLDAPGroups,
+		DownstreamGroups: happyLDAPGroups,
 		ProviderUID:      ldapUpstreamResourceUID,
 		ProviderName:     ldapUpstreamName,
 		ProviderType:     psession.ProviderTypeLDAP,
@@ -567,6 +631,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		Username:         oidcUpstreamUsername,
 		UpstreamUsername: oidcUpstreamUsername,
 		UpstreamGroups:   oidcUpstreamGroupMembership,
+		DownstreamGroups: oidcUpstreamGroupMembership,
 		ProviderUID:      oidcPasswordGrantUpstreamResourceUID,
 		ProviderName:     oidcPasswordGrantUpstreamName,
 		ProviderType:     psession.ProviderTypeOIDC,
@@ -581,6 +646,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		Username:         oidcUpstreamUsername,
 		UpstreamUsername: oidcUpstreamUsername,
 		UpstreamGroups:   oidcUpstreamGroupMembership,
+		DownstreamGroups: oidcUpstreamGroupMembership,
 		ProviderUID:      oidcPasswordGrantUpstreamResourceUID,
 		ProviderName:     oidcPasswordGrantUpstreamName,
 		ProviderType:     psession.ProviderTypeOIDC,
@@ -591,7 +657,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		},
 	}
 
-	withUsernameAndGroupsInCustomSession := func(expectedCustomSessionData *psession.CustomSessionData, wantDownstreamUsername string, wantUpstreamUsername string, wantUpstreamGroups []string) *psession.CustomSessionData {
+	withUsernameAndGroupsInCustomSession := func(expectedCustomSessionData *psession.CustomSessionData, wantDownstreamUsername string, wantUpstreamUsername string, wantUpstreamGroups []string, wantDownstreamGroups []string) *psession.CustomSessionData {
 		copyOfCustomSession := *expectedCustomSessionData
 		if expectedCustomSessionData.LDAP != nil {
 			copyOfLDAP := *(expectedCustomSessionData.LDAP)
@@ -608,6 +674,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		copyOfCustomSession.Username = wantDownstreamUsername
 		copyOfCustomSession.UpstreamUsername = wantUpstreamUsername
 		copyOfCustomSession.UpstreamGroups = wantUpstreamGroups
+		copyOfCustomSession.DownstreamGroups = wantDownstreamGroups
 		return &copyOfCustomSession
 	}
 
@@ -619,6 +686,15 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		require.NoError(t, kubeClient.Tracker().Add(secret))
 	}
 
+	addDynamicClientWhichRequiresPushedAuthorizationRequestsAndSecretToKubeResources := func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+			"some-namespace", dynamicClientID, dynamicClientUID, downstreamRedirectURI,
+			[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+		oidcClient.Spec.RequirePushedAuthorizationRequests = true
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+	}
+
 	// Note that fosite puts the granted scopes as a param in the redirect URI even though the spec doesn't seem to require it
 	happyAuthcodeDownstreamRedirectLocationRegexp := downstreamRedirectURI + `\?code=([^&]+)&scope=openid\+username\+groups&state=` + happyState
 
@@ -629,6 +705,31 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
 	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
 
+	const happyTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+	happyOneTimePassword, err := totp.Code(happyTOTPSecret, totp.TimeStep(time.Now()))
+	require.NoError(t, err)
+
+	enrollHappyLDAPUserTOTP := func(t *testing.T, _ *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		enrollments := totpenrollment.New(kubeClient.CoreV1().Secrets("some-namespace"), time.Now)
+		require.NoError(t, enrollments.CreateEnrollment(context.Background(), &totpenrollment.Enrollment{
+			Subject: upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			Secret:  happyTOTPSecret,
+		}))
+		// Creating the enrollment is not part of the authorization request.
+		kubeClient.ClearActions()
+	}
+
+	lockOutHappyLDAPUserTOTP := func(t *testing.T, _ *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		enrollments := totpenrollment.New(kubeClient.CoreV1().Secrets("some-namespace"), time.Now)
+		require.NoError(t, enrollments.CreateEnrollment(context.Background(), &totpenrollment.Enrollment{
+			Subject:        upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			Secret:         happyTOTPSecret,
+			FailedAttempts: secondfactor.MaxFailedAttempts,
+			LockedUntil:    time.Now().Add(time.Minute),
+		}))
+		kubeClient.ClearActions()
+	}
+
 	type testCase struct {
 		name string
 
@@ -646,6 +747,10 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		csrfCookie           string
 		customUsernameHeader *string // nil means do not send header, empty means send header with empty value
 		customPasswordHeader *string // nil means do not send header, empty means send header with empty value
+		// nil means do not send header, empty means send header with empty value
+		customOneTimePasswordHeader *string
+		// params which were pushed to the pushed authorization request endpoint, which returned pushedRequestURI
+		pushedAuthorizeRequest map[string]string
 
 		wantStatus                             int
 		wantContentType                        string
@@ -674,6 +779,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		wantPasswordGrantCall             *expectedPasswordGrant
 		wantDownstreamCustomSessionData   *psession.CustomSessionData
 		wantDownstreamAdditionalClaims    map[string]interface{}
+		wantPushedAuthorizeRequestDeleted bool
 	}
 	tests := []testCase{
 		{
@@ -765,6 +871,63 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantUpstreamStateParamInLocationHeader: false, // it should copy the params of the original request, not add a new state param
 			wantBodyStringWithLocationInHref:       true,
 		},
+		{
+			name: "with multiple IDPs available, request does not choose which IDP to use, but the login_hint matches the routing rules of the OIDC IDP",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRoutingForFederationDomain(ldapUsernameRouting).Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPath(map[string]string{"login_hint": "pinny@Example.com"}),
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"login_hint": "pinny@Example.com"}, "", oidcUpstreamName, "oidc"), nil),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name: "with multiple IDPs available, request does not choose which IDP to use, but the login_hint matches the routing rules of the LDAP IDP",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRoutingForFederationDomain(ldapUsernameRouting).Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPath(map[string]string{"login_hint": happyLDAPUsername}),
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     urlWithQuery(downstreamIssuer+"/login", map[string]string{"state": expectedUpstreamStateParam(map[string]string{"login_hint": happyLDAPUsername}, "", ldapUpstreamName, "ldap")}),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+		},
+		{
+			name: "with multiple IDPs available, request does not choose which IDP to use, and the login_hint does not match any routing rules",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRoutingForFederationDomain(ldapUsernameRouting).Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPath(map[string]string{"login_hint": "pinny@example.org"}),
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            "", // there should not be a CSRF cookie set on the response
+			wantLocationHeader:                     urlWithQuery(downstreamIssuer+"/choose_identity_provider", modifiedHappyGetRequestQueryMap(map[string]string{"login_hint": "pinny@example.org"})),
+			wantUpstreamStateParamInLocationHeader: false,
+			wantBodyStringWithLocationInHref:       true,
+		},
 		{
 			name: "with multiple IDPs available, request chooses to use OIDC browser flow",
 			idps: testidplister.NewUpstreamIDPListerBuilder().
@@ -904,6 +1067,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				transformationUsernamePrefix+oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				oidcUpstreamGroupMembership,
+				testutil.AddPrefixToEach(transformationGroupsPrefix, oidcUpstreamGroupMembership),
 			),
 		},
 		{
@@ -1003,6 +1167,52 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
 			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
 		},
+		{
+			name: "LDAP cli upstream happy path using GET without choosing an IDP, when the username matches the routing rules of the LDAP IDP",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRoutingForFederationDomain(ldapUsernameRouting).Build()),
+			method:                            http.MethodGet,
+			path:                              happyGetRequestPath, // does not include pinniped_idp_name param
+			customUsernameHeader:              ptr.To(happyLDAPUsername),
+			customPasswordHeader:              ptr.To(happyLDAPPassword),
+			wantStatus:                        http.StatusFound,
+			wantContentType:                   htmlContentType,
+			wantRedirectLocationRegexp:        happyAuthcodeDownstreamRedirectLocationRegexp,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
+		},
+		{
+			name:                              "LDAP cli upstream happy path using GET with a one-time password",
+			idps:                              testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()),
+			kubeResources:                     enrollHappyLDAPUserTOTP,
+			method:                            http.MethodGet,
+			path:                              happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader:              ptr.To(happyLDAPUsername),
+			customPasswordHeader:              ptr.To(happyLDAPPassword),
+			customOneTimePasswordHeader:       ptr.To(happyOneTimePassword),
+			wantStatus:                        http.StatusFound,
+			wantContentType:                   htmlContentType,
+			wantRedirectLocationRegexp:        happyAuthcodeDownstreamRedirectLocationRegexp,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
+		},
 		{
 			name: "LDAP cli upstream happy path using GET with identity transformations which change username and groups",
 			idps: testidplister.NewUpstreamIDPListerBuilder().
@@ -1028,6 +1238,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				transformationUsernamePrefix+happyLDAPUsernameFromAuthenticator,
 				happyLDAPUsernameFromAuthenticator,
 				happyLDAPGroups,
+				testutil.AddPrefixToEach(transformationGroupsPrefix, happyLDAPGroups),
 			),
 		},
 		{
@@ -1354,6 +1565,23 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"prompt": "login"}, "", oidcUpstreamName, "oidc"), map[string]string{"prompt": "consent", "abc": "123", "def": "456"}),
 			wantUpstreamStateParamInLocationHeader: true,
 		},
+		{
+			name:                                   "OIDC upstream browser flow happy path with allow-listed downstream params that get passed through",
+			idps:                                   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().WithAdditionalAuthcodeParams(map[string]string{"abc": "123"}).WithPassthroughAuthcodeParams([]string{"login_hint", "ui_locales", "display"}).Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   modifiedHappyGetRequestPathForOIDCUpstream(map[string]string{"login_hint": "pinny@example.com", "ui_locales": "de", "domain_hint": "example.com"}),
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantBodyStringWithLocationInHref:       true,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"login_hint": "pinny@example.com", "ui_locales": "de", "domain_hint": "example.com"}, "", oidcUpstreamName, "oidc"), map[string]string{"abc": "123", "login_hint": "pinny@example.com", "ui_locales": "de"}),
+			wantUpstreamStateParamInLocationHeader: true,
+		},
 		{
 			name:               "OIDC upstream browser flow with prompt param none throws an error because we want to independently decide the upstream prompt param",
 			idps:               testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
@@ -1369,6 +1597,134 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantLocationHeader: urlWithQuery(downstreamRedirectURI, fositeLoginRequiredErrorQuery),
 			wantBodyString:     "",
 		},
+		{
+			name:                                   "OIDC upstream browser flow happy path using a pushed authorization request",
+			idps:                                   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   pathWithQuery("/some/path", map[string]string{"client_id": pinnipedCLIClientID, "request_uri": pushedRequestURI}),
+			pushedAuthorizeRequest:                 happyGetRequestQueryMapForOIDCUpstream,
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(nil, "", oidcUpstreamName, "oidc"), nil),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+			wantPushedAuthorizeRequestDeleted:      true,
+		},
+		{
+			name:                                   "OIDC upstream browser flow happy path using a pushed authorization request for a dynamic client which requires them",
+			idps:                                   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources:                          addDynamicClientWhichRequiresPushedAuthorizationRequestsAndSecretToKubeResources,
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   pathWithQuery("/some/path", map[string]string{"client_id": dynamicClientID, "request_uri": pushedRequestURI}),
+			pushedAuthorizeRequest:                 modifiedHappyGetRequestQueryMapForOIDCUpstream(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}),
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}, "", oidcUpstreamName, "oidc"), nil),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+			wantPushedAuthorizeRequestDeleted:      true,
+		},
+		{
+			name: "with multiple IDPs available, a pushed authorization request which does not choose which IDP to use keeps its request_uri for the IDP chooser page",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   pathWithQuery("/some/path", map[string]string{"client_id": pinnipedCLIClientID, "request_uri": pushedRequestURI}),
+			pushedAuthorizeRequest:                 happyGetRequestQueryMap, // does not include pinniped_idp_name param
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            "", // there should not be a CSRF cookie set on the response
+			wantLocationHeader:                     urlWithQuery(downstreamIssuer+"/choose_identity_provider", map[string]string{"client_id": pinnipedCLIClientID, "request_uri": pushedRequestURI}),
+			wantUpstreamStateParamInLocationHeader: false,
+			wantBodyStringWithLocationInHref:       true,
+			wantPushedAuthorizeRequestDeleted:      false, // it will be used when the browser comes back from the IDP chooser page
+		},
+		{
+			name: "with multiple IDPs available, a pushed authorization request uses the IDP which was chosen on the IDP chooser page",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()).
+				WithLDAP(upstreamLDAPIdentityProviderBuilder().Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   pathWithQuery("/some/path", map[string]string{"client_id": pinnipedCLIClientID, "request_uri": pushedRequestURI, "pinniped_idp_name": ldapUpstreamName}),
+			pushedAuthorizeRequest:                 happyGetRequestQueryMap, // does not include pinniped_idp_name param
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     urlWithQuery(downstreamIssuer+"/login", map[string]string{"state": expectedUpstreamStateParam(nil, "", ldapUpstreamName, "ldap")}),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+			wantPushedAuthorizeRequestDeleted:      true,
+		},
+		{
+			name:               "dynamic client which requires pushed authorization requests did not use one",
+			idps:               testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources:      addDynamicClientWhichRequiresPushedAuthorizationRequestsAndSecretToKubeResources,
+			generateCSRF:       happyCSRFGenerator,
+			generatePKCE:       happyPKCEGenerator,
+			generateNonce:      happyNonceGenerator,
+			stateEncoder:       happyStateEncoder,
+			cookieEncoder:      happyCookieEncoder,
+			method:             http.MethodGet,
+			path:               modifiedHappyGetRequestPathForOIDCUpstream(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}),
+			wantStatus:         http.StatusSeeOther,
+			wantContentType:    jsonContentType,
+			wantLocationHeader: urlWithQuery(downstreamRedirectURI, fositePushedAuthorizationRequestRequiredErrorQuery),
+			wantBodyString:     "",
+		},
+		{
+			name:                   "pushed authorization request was not found, for example because it was already used or it expired",
+			idps:                   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			generateCSRF:           happyCSRFGenerator,
+			generatePKCE:           happyPKCEGenerator,
+			generateNonce:          happyNonceGenerator,
+			stateEncoder:           happyStateEncoder,
+			cookieEncoder:          happyCookieEncoder,
+			method:                 http.MethodGet,
+			path:                   pathWithQuery("/some/path", map[string]string{"client_id": pinnipedCLIClientID, "request_uri": pushedRequestURI + "-other"}),
+			pushedAuthorizeRequest: happyGetRequestQueryMapForOIDCUpstream,
+			wantStatus:             http.StatusBadRequest,
+			wantContentType:        jsonContentType,
+			wantBodyJSON:           fositeInvalidPushedRequestURIErrorBody,
+		},
+		{
+			name:                   "pushed authorization request was pushed by a different client",
+			idps:                   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources:          addFullyCapableDynamicClientAndSecretToKubeResources,
+			generateCSRF:           happyCSRFGenerator,
+			generatePKCE:           happyPKCEGenerator,
+			generateNonce:          happyNonceGenerator,
+			stateEncoder:           happyStateEncoder,
+			cookieEncoder:          happyCookieEncoder,
+			method:                 http.MethodGet,
+			path:                   pathWithQuery("/some/path", map[string]string{"client_id": dynamicClientID, "request_uri": pushedRequestURI}),
+			pushedAuthorizeRequest: happyGetRequestQueryMapForOIDCUpstream,
+			wantStatus:             http.StatusBadRequest,
+			wantContentType:        jsonContentType,
+			wantBodyJSON:           fositePushedRequestClientMismatchErrorBody,
+		},
 		{
 			name:            "OIDC upstream browser flow with error while decoding CSRF cookie just generates a new cookie and succeeds as usual",
 			idps:            testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
@@ -1547,6 +1903,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				Username:         oidcUpstreamUsername,
 				UpstreamUsername: oidcUpstreamUsername,
 				UpstreamGroups:   oidcUpstreamGroupMembership,
+				DownstreamGroups: oidcUpstreamGroupMembership,
 				ProviderUID:      oidcPasswordGrantUpstreamResourceUID,
 				ProviderName:     oidcPasswordGrantUpstreamName,
 				ProviderType:     psession.ProviderTypeOIDC,
@@ -1639,6 +1996,72 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantLocationHeader:   urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithBadUsernamePasswordHintErrorQuery),
 			wantBodyString:       "",
 		},
+		{
+			name:                 "missing one-time password for LDAP authentication which requires a second factor",
+			idps:                 testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()),
+			kubeResources:        enrollHappyLDAPUserTOTP,
+			method:               http.MethodGet,
+			path:                 happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader: ptr.To(happyLDAPUsername),
+			customPasswordHeader: ptr.To(happyLDAPPassword),
+			wantStatus:           http.StatusFound,
+			wantContentType:      jsonContentType,
+			wantLocationHeader:   urlWithQuery(downstreamRedirectURI, oneTimePasswordRequiredErrorQuery),
+			wantBodyString:       "",
+		},
+		{
+			name:                        "wrong one-time password for LDAP authentication which requires a second factor",
+			idps:                        testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()),
+			kubeResources:               enrollHappyLDAPUserTOTP,
+			method:                      http.MethodGet,
+			path:                        happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader:        ptr.To(happyLDAPUsername),
+			customPasswordHeader:        ptr.To(happyLDAPPassword),
+			customOneTimePasswordHeader: ptr.To("wrong-one-time-password"),
+			wantStatus:                  http.StatusFound,
+			wantContentType:             jsonContentType,
+			wantLocationHeader:          urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithOneTimePasswordNotAcceptedHintErrorQuery),
+			wantBodyString:              "",
+		},
+		{
+			name:                        "correct one-time password for LDAP authentication when the user is locked out after too many incorrect one-time passwords",
+			idps:                        testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()),
+			kubeResources:               lockOutHappyLDAPUserTOTP,
+			method:                      http.MethodGet,
+			path:                        happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader:        ptr.To(happyLDAPUsername),
+			customPasswordHeader:        ptr.To(happyLDAPPassword),
+			customOneTimePasswordHeader: ptr.To(happyOneTimePassword),
+			wantStatus:                  http.StatusFound,
+			wantContentType:             jsonContentType,
+			wantLocationHeader:          urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithOneTimePasswordLockedOutHintErrorQuery),
+			wantBodyString:              "",
+		},
+		{
+			name:                        "one-time password for LDAP authentication when the user has not enrolled an authenticator app",
+			idps:                        testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()),
+			method:                      http.MethodGet,
+			path:                        happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader:        ptr.To(happyLDAPUsername),
+			customPasswordHeader:        ptr.To(happyLDAPPassword),
+			customOneTimePasswordHeader: ptr.To(happyOneTimePassword),
+			wantStatus:                  http.StatusFound,
+			wantContentType:             jsonContentType,
+			wantLocationHeader:          urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithOneTimePasswordNotEnrolledHintErrorQuery),
+			wantBodyString:              "",
+		},
+		{
+			name:                 "LDAP authentication without a browser when the identity provider requires a security key",
+			idps:                 testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderBuilder().WithRequireWebAuthnForFederationDomain().Build()),
+			method:               http.MethodGet,
+			path:                 happyGetRequestPathForLDAPUpstream,
+			customUsernameHeader: ptr.To(happyLDAPUsername),
+			customPasswordHeader: ptr.To(happyLDAPPassword),
+			wantStatus:           http.StatusFound,
+			wantContentType:      jsonContentType,
+			wantLocationHeader:   urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithSecurityKeyRequiresBrowserHintErrorQuery),
+			wantBodyString:       "",
+		},
 		{
 			name:                 "wrong upstream password for Active Directory authentication",
 			idps:                 testidplister.NewUpstreamIDPListerBuilder().WithActiveDirectory(upstreamActiveDirectoryIdentityProviderBuilder().Build()),
@@ -2764,6 +3187,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oidcUpstreamIssuer+"?sub="+oidcUpstreamSubjectQueryEscaped,
 				oidcUpstreamIssuer+"?sub="+oidcUpstreamSubjectQueryEscaped,
 				nil,
+				nil,
 			),
 		},
 		{
@@ -2795,6 +3219,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				"joe@whitehouse.gov",
 				"joe@whitehouse.gov",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 		},
 		{
@@ -2827,6 +3252,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				"joe@whitehouse.gov",
 				"joe@whitehouse.gov",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 		},
 		{
@@ -2860,6 +3286,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				"joe",
 				"joe",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 		},
 		{
@@ -2925,6 +3352,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oidcUpstreamSubject,
 				oidcUpstreamSubject,
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 		},
 		{
@@ -2955,6 +3383,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				[]string{"notAnArrayGroup1 notAnArrayGroup2"},
+				[]string{"notAnArrayGroup1 notAnArrayGroup2"},
 			),
 		},
 		{
@@ -2985,6 +3414,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				[]string{"group1", "group2"},
+				[]string{"group1", "group2"},
 			),
 		},
 		{
@@ -3029,6 +3459,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				nil,
+				nil,
 			),
 		},
 		{
@@ -3421,6 +3852,20 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			test.kubeResources(t, supervisorClient, kubeClient)
 		}
 
+		if test.pushedAuthorizeRequest != nil {
+			pushedRequest := fosite.NewAuthorizeRequest()
+			pushedRequest.Client = &fosite.DefaultClient{ID: test.pushedAuthorizeRequest["client_id"]}
+			pushedRequest.Form = url.Values{}
+			for k, v := range test.pushedAuthorizeRequest {
+				pushedRequest.Form.Set(k, v)
+			}
+			pushedRequest.Session = psession.NewPinnipedSession()
+			pushedRequest.Session.SetExpiresAt(fosite.PushedAuthorizeRequestContext, time.Now().Add(time.Minute))
+			require.NoError(t, kubeOauthStore.CreatePARSession(context.Background(), pushedRequestURI, pushedRequest))
+			// Creating the pushed request is not part of the authorization request.
+			kubeClient.ClearActions()
+		}
+
 		reqContext := context.WithValue(context.Background(), struct{ name string }{name: "test"}, "request-context")
 		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body)).WithContext(reqContext)
 		req.Header.Set("Content-Type", test.contentType)
@@ -3433,6 +3878,9 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		if test.customPasswordHeader != nil {
 			req.Header.Set("Pinniped-Password", *test.customPasswordHeader)
 		}
+		if test.customOneTimePasswordHeader != nil {
+			req.Header.Set("Pinniped-One-Time-Password", *test.customOneTimePasswordHeader)
+		}
 		rsp := httptest.NewRecorder()
 		subject.ServeHTTP(rsp, req)
 		t.Logf("response: %#v", rsp)
@@ -3533,6 +3981,15 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		} else {
 			require.Empty(t, rsp.Header().Values("Set-Cookie"))
 		}
+
+		if test.pushedAuthorizeRequest != nil {
+			_, err := kubeOauthStore.GetPARSession(context.Background(), pushedRequestURI)
+			if test.wantPushedAuthorizeRequestDeleted {
+				require.True(t, apierrors.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+			}
+		}
 	}
 
 	for _, test := range tests {
@@ -3560,9 +4017,11 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			subject := NewHandler(
 				downstreamIssuer,
 				idps,
-				oauthHelperWithNullStorage, oauthHelperWithRealStorage,
+				oauthHelperWithNullStorage, oauthHelperWithRealStorage, kubeOauthStore,
 				test.generateCSRF, test.generatePKCE, test.generateNonce,
 				test.stateEncoder, test.cookieEncoder,
+				secondfactor.NewStorage(secretsClient, oidc.DefaultOIDCTimeoutsConfiguration()),
+				nil,
 			)
 			runOneTestCase(t, test, subject, kubeOauthStore, supervisorClient, kubeClient, secretsClient)
 		})
@@ -3583,9 +4042,11 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		subject := NewHandler(
 			downstreamIssuer,
 			idpLister,
-			oauthHelperWithNullStorage, oauthHelperWithRealStorage,
+			oauthHelperWithNullStorage, oauthHelperWithRealStorage, kubeOauthStore,
 			test.generateCSRF, test.generatePKCE, test.generateNonce,
 			test.stateEncoder, test.cookieEncoder,
+			secondfactor.NewStorage(secretsClient, oidc.DefaultOIDCTimeoutsConfiguration()),
+			nil,
 		)
 
 		runOneTestCase(t, test, subject, kubeOauthStore, supervisorClient, kubeClient, secretsClient)
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package callback provides a handler for the OIDC callback endpoint.
@@ -10,7 +10,9 @@ import (
 
 	"github.com/ory/fosite"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
@@ -24,6 +26,8 @@ func NewHandler(
 	oauthHelper fosite.OAuth2Provider,
 	stateDecoder, cookieDecoder oidc.Decoder,
 	redirectURI string,
+	consentRequester *consent.Requester,
+	pageBranding *branding.Branding,
 ) http.Handler {
 	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		state, err := validateRequest(r, stateDecoder, cookieDecoder)
@@ -72,6 +76,15 @@ func NewHandler(
 			return httperr.Wrap(http.StatusUnprocessableEntity, err.Error(), err)
 		}
 
+		redirectedToConsent, err := consentRequester.RedirectIfRequired(w, r, authorizeRequester, session, state.CSRFToken)
+		if err != nil {
+			plog.Error("error while requesting consent", err)
+			return httperr.Wrap(http.StatusInternalServerError, "error while requesting consent", err)
+		}
+		if redirectedToConsent {
+			return nil
+		}
+
 		authorizeResponder, err := oauthHelper.NewAuthorizeResponse(r.Context(), authorizeRequester, session)
 		if err != nil {
 			plog.WarningErr("error while generating and saving authcode", err,
@@ -79,11 +92,11 @@ func NewHandler(
 			return httperr.Wrap(http.StatusInternalServerError, "error while generating and saving authcode", err)
 		}
 
-		oauthHelper.WriteAuthorizeResponse(r.Context(), w, authorizeRequester, authorizeResponder)
+		oauthHelper.WriteAuthorizeResponse(oidc.LocalizedContext(r, authorizeRequester), w, authorizeRequester, authorizeResponder)
 
 		return nil
 	})
-	return securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy())
+	return securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy(pageBranding))
 }
 
 func authcode(r *http.Request) string {
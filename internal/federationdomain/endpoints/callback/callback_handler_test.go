@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package callback
@@ -21,6 +21,7 @@ import (
 
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
@@ -98,6 +99,7 @@ var (
 		Username:         oidcUpstreamUsername,
 		UpstreamUsername: oidcUpstreamUsername,
 		UpstreamGroups:   oidcUpstreamGroupMembership,
+		DownstreamGroups: oidcUpstreamGroupMembership,
 		ProviderUID:      happyUpstreamIDPResourceUID,
 		ProviderName:     happyUpstreamIDPName,
 		ProviderType:     psession.ProviderTypeOIDC,
@@ -107,19 +109,21 @@ var (
 			UpstreamSubject:      oidcUpstreamSubject,
 		},
 	}
-	happyDownstreamCustomSessionDataWithUsernameAndGroups = func(wantDownstreamUsername, wantUpstreamUsername string, wantUpstreamGroups []string) *psession.CustomSessionData {
+	happyDownstreamCustomSessionDataWithUsernameAndGroups = func(wantDownstreamUsername, wantUpstreamUsername string, wantUpstreamGroups, wantDownstreamGroups []string) *psession.CustomSessionData {
 		copyOfCustomSession := *happyDownstreamCustomSessionData
 		copyOfOIDC := *(happyDownstreamCustomSessionData.OIDC)
 		copyOfCustomSession.OIDC = &copyOfOIDC
 		copyOfCustomSession.Username = wantDownstreamUsername
 		copyOfCustomSession.UpstreamUsername = wantUpstreamUsername
 		copyOfCustomSession.UpstreamGroups = wantUpstreamGroups
+		copyOfCustomSession.DownstreamGroups = wantDownstreamGroups
 		return &copyOfCustomSession
 	}
 	happyDownstreamAccessTokenCustomSessionData = &psession.CustomSessionData{
 		Username:         oidcUpstreamUsername,
 		UpstreamUsername: oidcUpstreamUsername,
 		UpstreamGroups:   oidcUpstreamGroupMembership,
+		DownstreamGroups: oidcUpstreamGroupMembership,
 		ProviderUID:      happyUpstreamIDPResourceUID,
 		ProviderName:     happyUpstreamIDPName,
 		ProviderType:     psession.ProviderTypeOIDC,
@@ -239,6 +243,37 @@ func TestCallbackEndpoint(t *testing.T) {
 				args:                    happyExchangeAndValidateTokensArgs,
 			},
 		},
+		{
+			name:   "GET with good state and cookie and successful upstream token exchange with response_mode=form_post returns the HTML+JS form in the language of the ui_locales param",
+			idps:   testidplister.NewUpstreamIDPListerBuilder().WithOIDC(happyUpstream().Build()),
+			method: http.MethodGet,
+			path: newRequestPath().WithState(
+				happyUpstreamStateParam().WithAuthorizeRequestParams(
+					shallowCopyAndModifyQuery(
+						happyDownstreamRequestParamsQuery,
+						map[string]string{"response_mode": "form_post", "ui_locales": "fr es"},
+					).Encode(),
+				).Build(t, happyStateCodec),
+			).String(),
+			csrfCookie:                        happyCSRFCookie,
+			wantStatus:                        http.StatusOK,
+			wantContentType:                   "text/html;charset=UTF-8",
+			wantBodyFormResponseRegexp:        `(?s)<html lang="fr">.*<h1>Connexion réussie</h1>.*<code id="manual-auth-code">(.+)</code>`,
+			wantDownstreamIDTokenSubject:      oidcUpstreamIssuer + "?idpName=" + happyUpstreamIDPName + "&sub=" + oidcUpstreamSubjectQueryEscaped,
+			wantDownstreamIDTokenUsername:     oidcUpstreamUsername,
+			wantDownstreamIDTokenGroups:       oidcUpstreamGroupMembership,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamClientID:            downstreamPinnipedClientID,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData:   happyDownstreamCustomSessionData,
+			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
+				performedByUpstreamName: happyUpstreamIDPName,
+				args:                    happyExchangeAndValidateTokensArgs,
+			},
+		},
 		{
 			name: "GET with good state and cookie with additional params",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(happyUpstream().
@@ -412,6 +447,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				Username:         oidcUpstreamUsername,
 				UpstreamUsername: oidcUpstreamUsername,
 				UpstreamGroups:   oidcUpstreamGroupMembership,
+				DownstreamGroups: oidcUpstreamGroupMembership,
 				ProviderUID:      happyUpstreamIDPResourceUID,
 				ProviderName:     happyUpstreamIDPName,
 				ProviderType:     psession.ProviderTypeOIDC,
@@ -451,6 +487,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				oidcUpstreamIssuer+"?sub="+oidcUpstreamSubjectQueryEscaped,
 				oidcUpstreamIssuer+"?sub="+oidcUpstreamSubjectQueryEscaped,
 				nil,
+				nil,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -481,6 +518,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				"joe@whitehouse.gov",
 				"joe@whitehouse.gov",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -513,12 +551,46 @@ func TestCallbackEndpoint(t *testing.T) {
 				"joe@whitehouse.gov",
 				"joe@whitehouse.gov",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
 				args:                    happyExchangeAndValidateTokensArgs,
 			},
 		},
+		{
+			name: "upstream IDP configures additional claims for transformations, which are stored in the session when present",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				happyUpstream().
+					WithAdditionalClaimsForTransformations([]string{"email_verified", "acr", "missing-claim"}).
+					WithIDTokenClaim("email_verified", true).
+					WithIDTokenClaim("acr", "phr").Build(),
+			),
+			method:                            http.MethodGet,
+			path:                              newRequestPath().WithState(happyState).String(),
+			csrfCookie:                        happyCSRFCookie,
+			wantStatus:                        http.StatusSeeOther,
+			wantRedirectLocationRegexp:        happyDownstreamRedirectLocationRegexp,
+			wantBody:                          "",
+			wantDownstreamIDTokenSubject:      oidcUpstreamIssuer + "?idpName=" + happyUpstreamIDPName + "&sub=" + oidcUpstreamSubjectQueryEscaped,
+			wantDownstreamIDTokenUsername:     oidcUpstreamUsername,
+			wantDownstreamIDTokenGroups:       oidcUpstreamGroupMembership,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamClientID:            downstreamPinnipedClientID,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData: func() *psession.CustomSessionData {
+				data := happyDownstreamCustomSessionDataWithUsernameAndGroups(oidcUpstreamUsername, oidcUpstreamUsername, oidcUpstreamGroupMembership, oidcUpstreamGroupMembership)
+				data.UpstreamClaims = map[string]interface{}{"email_verified": true, "acr": "phr"}
+				return data
+			}(),
+			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
+				performedByUpstreamName: happyUpstreamIDPName,
+				args:                    happyExchangeAndValidateTokensArgs,
+			},
+		},
 		{
 			name: "upstream IDP configures username claim as anything other than special claim `email` and `email_verified` upstream claim is present with false value",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
@@ -546,6 +618,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				"joe",
 				"joe",
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -681,6 +754,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				oidcUpstreamSubject,
 				oidcUpstreamSubject,
 				oidcUpstreamGroupMembership,
+				oidcUpstreamGroupMembership,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -711,6 +785,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				[]string{"notAnArrayGroup1 notAnArrayGroup2"},
+				[]string{"notAnArrayGroup1 notAnArrayGroup2"},
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -741,6 +816,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				[]string{"group1", "group2"},
+				[]string{"group1", "group2"},
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -916,6 +992,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				transformationUsernamePrefix+oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				oidcUpstreamGroupMembership,
+				testutil.AddPrefixToEach(transformationGroupsPrefix, oidcUpstreamGroupMembership),
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -1329,6 +1406,7 @@ func TestCallbackEndpoint(t *testing.T) {
 				oidcUpstreamUsername,
 				oidcUpstreamUsername,
 				nil,
+				nil,
 			),
 			wantAuthcodeExchangeCall: &expectedAuthcodeExchange{
 				performedByUpstreamName: happyUpstreamIDPName,
@@ -1549,9 +1627,10 @@ func TestCallbackEndpoint(t *testing.T) {
 			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
 			require.GreaterOrEqual(t, len(hmacSecretFunc()), 32, "fosite requires that hmac secrets have at least 32 bytes")
 			jwksProviderIsUnused := jwks.NewDynamicJWKSProvider()
-			oauthHelper := oidc.FositeOauth2Helper(oauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration, nil, nil)
 
-			subject := NewHandler(test.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, happyStateCodec, happyCookieCodec, happyUpstreamRedirectURI)
+			consentRequester := consent.NewRequester(downstreamIssuer, consent.NewStorage(secrets, timeoutsConfiguration))
+			subject := NewHandler(test.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, happyStateCodec, happyCookieCodec, happyUpstreamRedirectURI, consentRequester, nil)
 			reqContext := context.WithValue(context.Background(), struct{ name string }{name: "test"}, "request-context")
 			req := httptest.NewRequest(test.method, test.path, nil).WithContext(reqContext)
 			if test.csrfCookie != "" {
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidp
@@ -10,8 +10,11 @@ import (
 	"sort"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/endpoints/chooseidp/chooseidphtml"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
+	"go.pinniped.dev/internal/federationdomain/locale"
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/httputil/securityheader"
 )
@@ -20,8 +23,14 @@ import (
 // to this page, copying all the same parameters from the original authorization request. Each button on this page
 // simply adds the IDP's name as an additional request parameter to the original authorization request's parameters,
 // and sends the user back to the authorization endpoint, where the authorization flow can start from scratch using
-// the original params with the extra pinniped_idp_name param added.
-func NewHandler(authURL string, upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersListerI) http.Handler {
+// the original params with the extra pinniped_idp_name param added. When any of the IDPs has routing rules, then the
+// page also asks for the user's username or email address, and sends it to the authorization endpoint as the
+// login_hint param instead. The pageBranding may be nil.
+func NewHandler(
+	authURL string,
+	upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersListerI,
+	pageBranding *branding.Branding,
+) http.Handler {
 	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method != http.MethodGet {
 			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET)", r.Method)
@@ -29,9 +38,13 @@ func NewHandler(authURL string, upstreamIDPs federationdomainproviders.Federatio
 
 		// This is just a sanity check that it appears to be an authorize request.
 		// Actual enforcement of parameters will happen at the authorization endpoint.
+		// When the client used a pushed authorization request, then the other params are stored on the server.
 		query := r.URL.Query()
-		if !(query.Has("client_id") && query.Has("redirect_uri") && query.Has("scope") && query.Has("response_type")) {
-			return httperr.New(http.StatusBadRequest, "missing required query params (must include client_id, redirect_uri, scope, and response_type)")
+		isAuthorizeRequest := query.Has("client_id") && query.Has("redirect_uri") && query.Has("scope") && query.Has("response_type")
+		isPushedAuthorizeRequest := query.Has("client_id") && query.Has("request_uri")
+		if !isAuthorizeRequest && !isPushedAuthorizeRequest {
+			return httperr.New(http.StatusBadRequest,
+				"missing required query params (must include client_id, redirect_uri, scope, and response_type, or must include client_id and request_uri)")
 		}
 
 		newIDPForPageData := func(displayName string) chooseidphtml.IdentityProvider {
@@ -43,8 +56,12 @@ func NewHandler(authURL string, upstreamIDPs federationdomainproviders.Federatio
 		}
 
 		var idps []chooseidphtml.IdentityProvider
+		hasRouting := false
 		for _, p := range upstreamIDPs.GetIdentityProviders() {
 			idps = append(idps, newIDPForPageData(p.GetDisplayName()))
+			if p.GetRouting() != nil {
+				hasRouting = true
+			}
 		}
 
 		sort.SliceStable(idps, func(i, j int) bool {
@@ -57,15 +74,51 @@ func NewHandler(authURL string, upstreamIDPs federationdomainproviders.Federatio
 				"please check the server's configuration: no valid identity providers found for this FederationDomain")
 		}
 
-		return chooseidphtml.Template().Execute(w, &chooseidphtml.PageData{IdentityProviders: idps})
+		pageData := &chooseidphtml.PageData{
+			IdentityProviders: idps,
+			Branding:          pageBranding,
+			Locale:            locale.ForRequest(r, query),
+		}
+		if hasRouting {
+			// When any IDP has routing rules, then also offer to choose the IDP by username or email address.
+			pageData.UsernameForm = newUsernameForm(authURL, query)
+		}
+
+		return chooseidphtml.Template().Execute(w, pageData)
 	})
 
-	return wrapSecurityHeaders(handler)
+	return wrapSecurityHeaders(handler, chooseidphtml.ContentSecurityPolicy(pageBranding))
+}
+
+// newUsernameForm returns a form which sends the params of the original authorization request back to the
+// authorization endpoint, with the login_hint param entered by the user.
+func newUsernameForm(authURL string, query url.Values) *chooseidphtml.UsernameForm {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		if name == idprouting.LoginHintParamName || name == oidc.AuthorizeUpstreamIDPNameParamName {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var params []chooseidphtml.Param
+	for _, name := range names {
+		for _, value := range query[name] {
+			params = append(params, chooseidphtml.Param{Name: name, Value: value})
+		}
+	}
+
+	return &chooseidphtml.UsernameForm{
+		Action:    authURL,
+		Params:    params,
+		LoginHint: query.Get(idprouting.LoginHintParamName),
+	}
 }
 
-func wrapSecurityHeaders(handler http.Handler) http.Handler {
+func wrapSecurityHeaders(handler http.Handler, cspValue string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wrapped := securityheader.WrapWithCustomCSP(handler, chooseidphtml.ContentSecurityPolicy())
+		wrapped := securityheader.WrapWithCustomCSP(handler, cspValue)
 		wrapped.ServeHTTP(w, r)
 	})
 }
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidp
@@ -13,6 +13,7 @@ import (
 
 	"go.pinniped.dev/internal/federationdomain/endpoints/chooseidp/chooseidphtml"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
@@ -30,6 +31,15 @@ func TestChooseIDPHandler(t *testing.T) {
 	}
 	testIssuerWithTestReqQuery := testIssuer + "?" + testReqQuery.Encode()
 
+	testPushedReqQuery := url.Values{
+		"client_id":   []string{"foo"},
+		"request_uri": []string{"urn:ietf:params:oauth:request_uri:bar"},
+	}
+	testIssuerWithTestPushedReqQuery := testIssuer + "?" + testPushedReqQuery.Encode()
+
+	exampleDotComRouting, err := idprouting.New([]string{"example.com"}, "")
+	require.NoError(t, err)
+
 	tests := []struct {
 		name string
 
@@ -37,9 +47,12 @@ func TestChooseIDPHandler(t *testing.T) {
 		reqTarget string
 		idps      federationdomainproviders.FederationDomainIdentityProvidersListerI
 
-		wantStatus      int
-		wantContentType string
-		wantBodyString  string
+		acceptLanguage string
+
+		wantStatus       int
+		wantContentType  string
+		wantBodyString   string
+		wantBodyContains []string
 	}{
 		{
 			name:      "happy path",
@@ -87,6 +100,94 @@ func TestChooseIDPHandler(t *testing.T) {
 				},
 			}),
 		},
+		{
+			name:      "happy path when the client used a pushed authorization request",
+			method:    http.MethodGet,
+			reqTarget: "/some/path" + oidc.ChooseIDPEndpointPath + "?" + testPushedReqQuery.Encode(),
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().WithName("oidc1").Build()).
+				WithLDAP(oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().WithName("ldap1").Build()).
+				BuildFederationDomainIdentityProvidersListerFinder(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBodyString: testutil.ExpectedChooseIDPPageHTML(chooseidphtml.CSS(), chooseidphtml.JS(), []testutil.ChooseIDPPageExpectedValue{
+				{DisplayName: "ldap1", URL: testIssuerWithTestPushedReqQuery + "&pinniped_idp_name=ldap1"},
+				{DisplayName: "oidc1", URL: testIssuerWithTestPushedReqQuery + "&pinniped_idp_name=oidc1"},
+			}),
+		},
+		{
+			name:      "happy path in the language of the Accept-Language header",
+			method:    http.MethodGet,
+			reqTarget: "/some/path" + oidc.ChooseIDPEndpointPath + "?" + testReqQuery.Encode(),
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithLDAP(oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().WithName("ldap1").Build()).
+				BuildFederationDomainIdentityProvidersListerFinder(),
+			acceptLanguage:  "es-ES,es;q=0.9",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBodyContains: []string{
+				`<html lang="es">`,
+				`<title>Elegir proveedor de identidad</title>`,
+				`<h1>Elija un proveedor de identidad para iniciar sesión</h1>`,
+				`<span>ldap1</span>`,
+			},
+		},
+		{
+			name:      "happy path in the language of the ui_locales param, which takes precedence over the Accept-Language header",
+			method:    http.MethodGet,
+			reqTarget: "/some/path" + oidc.ChooseIDPEndpointPath + "?" + testReqQuery.Encode() + "&ui_locales=de",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithLDAP(oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().WithName("ldap1").Build()).
+				BuildFederationDomainIdentityProvidersListerFinder(),
+			acceptLanguage:  "es",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBodyContains: []string{
+				`<html lang="de">`,
+				`<h1>Wählen Sie einen Identitätsanbieter für die Anmeldung</h1>`,
+				`ui_locales=de&amp;pinniped_idp_name=ldap1`,
+			},
+		},
+		{
+			name:      "happy path when an IDP has routing rules shows a form to enter the username",
+			method:    http.MethodGet,
+			reqTarget: "/some/path" + oidc.ChooseIDPEndpointPath + "?" + testReqQuery.Encode(),
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().WithName("oidc1").WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().WithName("ldap1").Build()).
+				BuildFederationDomainIdentityProvidersListerFinder(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBodyContains: []string{
+				`<form id="username-form" action="https://pinniped.dev/issuer" method="get">
+        <input type="hidden" name="client_id" value="foo">
+        <input type="hidden" name="redirect_uri" value="bar">
+        <input type="hidden" name="response_type" value="bat">
+        <input type="hidden" name="scope" value="baz">
+        <div class="form-field">`,
+				`<input type="text" name="login_hint" aria-label="Username or email address" placeholder="Username or email address" value="" autocomplete="username" required autofocus>`,
+				`<button data-url="https://pinniped.dev/issuer?client_id=foo&amp;redirect_uri=bar&amp;response_type=bat&amp;scope=baz&amp;pinniped_idp_name=ldap1"><span>ldap1</span></button>`,
+				`<button data-url="https://pinniped.dev/issuer?client_id=foo&amp;redirect_uri=bar&amp;response_type=bat&amp;scope=baz&amp;pinniped_idp_name=oidc1"><span>oidc1</span></button>`,
+			},
+		},
+		{
+			name:   "happy path when the login_hint did not match the routing rules of any IDP",
+			method: http.MethodGet,
+			reqTarget: "/some/path" + oidc.ChooseIDPEndpointPath + "?" + testReqQuery.Encode() +
+				"&login_hint=" + url.QueryEscape("pinny@example.org") + "&pinniped_idp_name=ignored",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().WithName("oidc1").WithRoutingForFederationDomain(exampleDotComRouting).Build()).
+				WithLDAP(oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().WithName("ldap1").Build()).
+				BuildFederationDomainIdentityProvidersListerFinder(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html; charset=utf-8",
+			wantBodyContains: []string{
+				`<span class="alert" role="alert">No identity provider was found for pinny@example.org. Please try again or choose an identity provider below.</span>`,
+				`<input type="hidden" name="scope" value="baz">
+        <div class="form-field">`,
+				`value="pinny@example.org" autocomplete="username"`,
+			},
+		},
 		{
 			name:      "no valid IDPs are configured on the FederationDomain",
 			method:    http.MethodGet,
@@ -106,7 +207,7 @@ func TestChooseIDPHandler(t *testing.T) {
 				BuildFederationDomainIdentityProvidersListerFinder(),
 			wantStatus:      http.StatusBadRequest,
 			wantContentType: "text/plain; charset=utf-8",
-			wantBodyString:  "Bad Request: missing required query params (must include client_id, redirect_uri, scope, and response_type)\n",
+			wantBodyString:  "Bad Request: missing required query params (must include client_id, redirect_uri, scope, and response_type, or must include client_id and request_uri)\n",
 		},
 		{
 			name:      "missing required query param(s) on the request",
@@ -117,7 +218,7 @@ func TestChooseIDPHandler(t *testing.T) {
 				BuildFederationDomainIdentityProvidersListerFinder(),
 			wantStatus:      http.StatusBadRequest,
 			wantContentType: "text/plain; charset=utf-8",
-			wantBodyString:  "Bad Request: missing required query params (must include client_id, redirect_uri, scope, and response_type)\n",
+			wantBodyString:  "Bad Request: missing required query params (must include client_id, redirect_uri, scope, and response_type, or must include client_id and request_uri)\n",
 		},
 		{
 			name:      "bad request method",
@@ -136,15 +237,24 @@ func TestChooseIDPHandler(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			handler := NewHandler(testIssuer, test.idps)
+			handler := NewHandler(testIssuer, test.idps, nil)
 
 			req := httptest.NewRequest(test.method, test.reqTarget, nil)
+			if test.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", test.acceptLanguage)
+			}
 			rsp := httptest.NewRecorder()
 			handler.ServeHTTP(rsp, req)
 
 			require.Equal(t, test.wantStatus, rsp.Code)
 			require.Equal(t, test.wantContentType, rsp.Header().Get("Content-Type"))
-			require.Equal(t, test.wantBodyString, rsp.Body.String())
+			if test.wantBodyContains != nil {
+				for _, want := range test.wantBodyContains {
+					require.Contains(t, rsp.Body.String(), want)
+				}
+			} else {
+				require.Equal(t, test.wantBodyString, rsp.Body.String())
+			}
 			testutil.RequireSecurityHeadersWithIDPChooserPageCSPs(t, rsp)
 		})
 	}
@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidphtml
@@ -10,7 +10,9 @@ import (
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/locale"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -33,14 +35,18 @@ var (
 	}).Parse(rawHTMLTemplate))
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
+	cspValue = contentSecurityPolicy(nil)
+)
+
+func contentSecurityPolicy(b *branding.Branding) string {
+	return strings.Join([]string{
 		`default-src 'none'`,
 		`script-src '` + csp.Hash(minifiedJS) + `'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
+		`style-src ` + strings.Join(append([]string{`'` + csp.Hash(minifiedCSS) + `'`}, b.StyleSources()...), " "),
 		`img-src data:`,
 		`frame-ancestors 'none'`,
 	}, "; ")
-)
+}
 
 func panicOnError(s string, err error) string {
 	if err != nil {
@@ -49,10 +55,16 @@ func panicOnError(s string, err error) string {
 	return s
 }
 
-// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly
+// for the given branding, which may be nil.
 //
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
-func ContentSecurityPolicy() string { return cspValue }
+func ContentSecurityPolicy(b *branding.Branding) string {
+	if b == nil {
+		return cspValue
+	}
+	return contentSecurityPolicy(b)
+}
 
 // Template returns the html/template.Template for rendering the login page.
 func Template() *template.Template { return parsedHTMLTemplate }
@@ -68,7 +80,32 @@ type IdentityProvider struct {
 	URL         string
 }
 
+// UsernameForm is a form which asks for the user's username or email address, and sends it to the authorization
+// endpoint as the login_hint param, so the authorization endpoint can choose the identity provider using the routing
+// rules of the identity providers.
+type UsernameForm struct {
+	// Action is the URL of the authorization endpoint.
+	Action string
+	// Params are the params of the original authorization request, which are sent again as hidden form fields.
+	Params []Param
+	// LoginHint is the login_hint of the original authorization request, if any. When it is not empty,
+	// then the page explains that no identity provider matched it.
+	LoginHint string
+}
+
+type Param struct {
+	Name  string
+	Value string
+}
+
 // PageData represents the inputs to the template.
 type PageData struct {
 	IdentityProviders []IdentityProvider
+	Branding          *branding.Branding
+
+	// UsernameForm is shown above the IDP buttons when it is not nil.
+	UsernameForm *UsernameForm
+
+	// Locale translates the text of the page. When it is nil, the page is shown in English.
+	Locale *locale.Localizer
 }
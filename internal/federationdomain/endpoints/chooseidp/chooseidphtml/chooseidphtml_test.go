@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidphtml
@@ -9,12 +9,15 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/csp"
 	"go.pinniped.dev/internal/testutil"
 )
 
 var (
-	testExpectedCSS = `html{height:100%}body{font-family:metropolis-light,Helvetica,sans-serif;display:flex;flex-flow:column wrap;justify-content:flex-start;align-items:center;background:linear-gradient(to top,#f8f8f8,white);min-height:100%}h1{font-size:20px;margin:0}.box{display:flex;flex-direction:column;flex-wrap:nowrap;border-radius:4px;border-color:#ddd;border-width:1px;border-style:solid;width:400px;padding:30px 30px 0;margin:60px 20px 0;background:#fff;font-size:14px}button{color:inherit;font:inherit;border:0;margin:0;outline:0;padding:0}.form-field{display:flex;margin-bottom:30px}.form-field button{width:100%;padding:1em;background-color:#218fcf;color:#eee;font-weight:700;cursor:pointer;transition:all .3s}.form-field button:focus,.form-field button:hover{background-color:#1abfd3}.form-field button:active{transform:scale(.99)}`
+	testExpectedCSS = `html{height:100%}body{font-family:metropolis-light,Helvetica,sans-serif;display:flex;flex-flow:column wrap;justify-content:flex-start;align-items:center;background:linear-gradient(to top,#f8f8f8,white);min-height:100%}h1{font-size:20px;margin:0}.box{display:flex;flex-direction:column;flex-wrap:nowrap;border-radius:4px;border-color:#ddd;border-width:1px;border-style:solid;width:400px;padding:30px 30px 0;margin:60px 20px 0;background:#fff;font-size:14px}button,input{color:inherit;font:inherit;border:0;margin:0;outline:0;padding:0}.form-field{display:flex;margin-bottom:30px}.form-field input[type=text]{width:100%;padding:1em;border-radius:3px;border-width:1px;border-style:solid;border-color:#a6a6a6}.form-field button,.form-field input[type=submit]{width:100%;padding:1em;background-color:#218fcf;color:#eee;font-weight:700;cursor:pointer;transition:all .3s}.form-field button:focus,.form-field button:hover,.form-field input[type=submit]:focus,.form-field input[type=submit]:hover{background-color:#1abfd3}.form-field button:active,.form-field input[type=submit]:active{transform:scale(.99)}.alert{color:crimson}`
 
 	testExpectedJS = `window.onload=()=>{Array.from(document.querySelectorAll("button")).forEach(e=>{e.onclick=()=>window.location.href=e.dataset.url}),document.getElementById("choose-idp-form-buttons").hidden=!1}`
 
@@ -22,7 +25,7 @@ var (
 	// Our browser-based integration tests should find any incompatibilities.
 	testExpectedCSP = `default-src 'none'; ` +
 		`script-src 'sha256-eyuE+qQfuMn4WbDizGOp1wSGReaMYRYmRMXpyEo+8ps='; ` +
-		`style-src 'sha256-SgeTG5HEbHNFgjH+EvLrC+VKZRZQ6iAI3oFnW7i/Tm4='; ` +
+		`style-src 'sha256-ZwHQ4ahT5Si7GuEci0+Zr5cP4WgBn9I4NNPlztaor/M='; ` +
 		`img-src data:; ` +
 		`frame-ancestors 'none'`
 )
@@ -52,8 +55,66 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, expectedHTML, buf.String())
 }
 
+func TestTemplateWithBranding(t *testing.T) {
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{
+		"title":     "Acme Corp",
+		"helpLinks": `[{"text":"Help","url":"https://example.com/help"}]`,
+	}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Template().Execute(&buf, &PageData{
+		IdentityProviders: []IdentityProvider{{DisplayName: "test-idp-name", URL: "https://pinniped.dev/path"}},
+		Branding:          b,
+	}))
+
+	require.Contains(t, buf.String(), "<title>Acme Corp - Choose Identity Provider</title>")
+	require.Contains(t, buf.String(), "<style>"+string(b.CSS())+"</style>")
+	require.Contains(t, buf.String(), `<span class="branding-title">Acme Corp</span>`)
+	require.NotContains(t, buf.String(), `<img class="branding-logo"`)
+	require.Contains(t, buf.String(), `<li><a href="https://example.com/help">Help</a></li>`)
+}
+
+func TestTemplateWithUsernameForm(t *testing.T) {
+	pageInputs := &PageData{
+		IdentityProviders: []IdentityProvider{{DisplayName: "test-idp-name", URL: "https://pinniped.dev/path"}},
+		UsernameForm: &UsernameForm{
+			Action: "https://pinniped.dev/issuer/oauth2/authorize",
+			Params: []Param{
+				{Name: "client_id", Value: "pinniped-cli"},
+				{Name: "scope", Value: "openid groups"},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	require.Contains(t, buf.String(), `<form id="username-form" action="https://pinniped.dev/issuer/oauth2/authorize" method="get">`)
+	require.Contains(t, buf.String(), `<input type="hidden" name="client_id" value="pinniped-cli">`)
+	require.Contains(t, buf.String(), `<input type="hidden" name="scope" value="openid groups">`)
+	require.Contains(t, buf.String(), `<input type="text" name="login_hint" aria-label="Username or email address" placeholder="Username or email address" value="" autocomplete="username" required autofocus>`)
+	require.Contains(t, buf.String(), `<span>Or choose an identity provider:</span>`)
+	require.NotContains(t, buf.String(), `role="alert"`)
+
+	// When the login_hint did not match any IDP, then the page says so and fills in the login_hint.
+	pageInputs.UsernameForm.LoginHint = "pinny@example.com"
+	buf.Reset()
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	require.Contains(t, buf.String(), `<span class="alert" role="alert">No identity provider was found for pinny@example.com. Please try again or choose an identity provider below.</span>`)
+	require.Contains(t, buf.String(), `value="pinny@example.com"`)
+}
+
 func TestContentSecurityPolicy(t *testing.T) {
-	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
+
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{"backgroundColor": "#fafafa"}})
+	require.NoError(t, err)
+	require.Equal(t, `default-src 'none'; `+
+		`script-src 'sha256-eyuE+qQfuMn4WbDizGOp1wSGReaMYRYmRMXpyEo+8ps='; `+
+		`style-src 'sha256-ZwHQ4ahT5Si7GuEci0+Zr5cP4WgBn9I4NNPlztaor/M=' '`+csp.Hash(string(b.CSS()))+`'; `+
+		`img-src data:; `+
+		`frame-ancestors 'none'`,
+		ContentSecurityPolicy(b))
 }
 
 func TestCSS(t *testing.T) {
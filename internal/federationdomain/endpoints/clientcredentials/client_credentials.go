@@ -0,0 +1,132 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clientcredentials provides a handler for the client_credentials grant of the OIDC token endpoint.
+package clientcredentials
+
+import (
+	"context"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
+	"github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/pkg/errors"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
+	"go.pinniped.dev/internal/psession"
+)
+
+// HandlerFactory returns a compose.Factory for the client_credentials grant handler. The handler issues tokens
+// for the service identity of the authenticated client, which may then be used for token exchange.
+func HandlerFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+	return &clientCredentialsHandler{
+		ClientCredentialsGrantHandler: &oauth2.ClientCredentialsGrantHandler{
+			HandleHelper: &oauth2.HandleHelper{
+				AccessTokenStrategy: strategy.(oauth2.AccessTokenStrategy),
+				AccessTokenStorage:  storage.(oauth2.AccessTokenStorage),
+				Config:              config,
+			},
+			Config: config,
+		},
+		idTokenHelper: &openid.IDTokenHandleHelper{IDTokenStrategy: strategy.(openid.OpenIDConnectTokenStrategy)},
+		fositeConfig:  config,
+	}
+}
+
+var _ compose.Factory = HandlerFactory
+
+type clientCredentialsHandler struct {
+	*oauth2.ClientCredentialsGrantHandler
+	idTokenHelper *openid.IDTokenHandleHelper
+	fositeConfig  fosite.Configurator
+}
+
+var _ fosite.TokenEndpointHandler = (*clientCredentialsHandler)(nil)
+
+func (c *clientCredentialsHandler) HandleTokenEndpointRequest(ctx context.Context, requester fosite.AccessRequester) error {
+	// Validates the requested scopes and sets the expiration of the access token.
+	if err := c.ClientCredentialsGrantHandler.HandleTokenEndpointRequest(ctx, requester); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Check that the client is allowed to perform this grant type.
+	client, ok := requester.GetClient().(*clientregistry.Client)
+	if !ok || !client.GetGrantTypes().Has(oidcapi.GrantTypeClientCredentials) || client.ServiceIdentity == nil {
+		// This error message is copied from the similar check in fosite's flow_client_credentials.go.
+		return errors.WithStack(fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant '%s'.", oidcapi.GrantTypeClientCredentials))
+	}
+
+	// Refresh tokens are never issued for this grant, because the client can always authenticate again.
+	if requester.GetRequestedScopes().Has(oidcapi.ScopeOfflineAccess) {
+		return errors.WithStack(fosite.ErrInvalidScope.WithHintf("The '%s' scope is not allowed for authorization grant '%s'.", oidcapi.ScopeOfflineAccess, oidcapi.GrantTypeClientCredentials))
+	}
+
+	// There is no end user to approve the scopes, and the client is allowed to request all of them,
+	// so grant all the requested scopes.
+	for _, scope := range requester.GetRequestedScopes() {
+		requester.GrantScope(scope)
+	}
+
+	return c.populateSession(ctx, requester, client)
+}
+
+func (c *clientCredentialsHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) error {
+	// Issues and stores the access token, which can be used for token exchange.
+	if err := c.ClientCredentialsGrantHandler.PopulateTokenEndpointResponse(ctx, requester, responder); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !requester.GetGrantedScopes().Has(oidcapi.ScopeOpenID) {
+		return nil
+	}
+
+	// Like the authorization code grant, issue an ID token for the client itself when the openid scope was granted.
+	return c.idTokenHelper.IssueExplicitIDToken(ctx, c.fositeConfig.GetIDTokenLifespan(ctx), requester, responder)
+}
+
+// populateSession fills in the session, which was empty because there was no user login, using the service
+// identity of the client.
+func (c *clientCredentialsHandler) populateSession(ctx context.Context, requester fosite.AccessRequester, client *clientregistry.Client) error {
+	pSession, ok := requester.GetSession().(*psession.PinnipedSession)
+	if !ok {
+		// This shouldn't really happen.
+		return errors.WithStack(fosite.ErrServerError.WithHint("Invalid session storage."))
+	}
+
+	now := time.Now().UTC()
+	pSession.Fosite.Claims = &jwt.IDTokenClaims{
+		Subject:     downstreamsubject.ServiceIdentity(c.fositeConfig.GetIDTokenIssuer(ctx), client.GetID()),
+		RequestedAt: now,
+		AuthTime:    now,
+	}
+
+	groups := client.ServiceIdentity.Groups
+	if groups == nil {
+		groups = []string{}
+	}
+
+	// There is no upstream identity provider, so only the downstream username and groups are stored. The groups
+	// are needed by the conditions of the token exchange audiences. This session can never be refreshed, and the
+	// session storage garbage collector ignores sessions without a provider type.
+	pSession.Custom = &psession.CustomSessionData{
+		Username:         client.ServiceIdentity.Username,
+		DownstreamGroups: groups,
+	}
+
+	extras := map[string]interface{}{}
+	extras[oidcapi.IDTokenClaimAuthorizedParty] = client.GetID()
+	if requester.GetGrantedScopes().Has(oidcapi.ScopeUsername) {
+		extras[oidcapi.IDTokenClaimUsername] = client.ServiceIdentity.Username
+	}
+	if requester.GetGrantedScopes().Has(oidcapi.ScopeGroups) {
+		extras[oidcapi.IDTokenClaimGroups] = groups
+	}
+	pSession.IDTokenClaims().Extra = extras
+
+	return nil
+}
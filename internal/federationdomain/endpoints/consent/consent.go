@@ -0,0 +1,155 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package consent provides a handler for the consent page, which asks users to approve the scopes which were
+// requested by clients that require consent.
+package consent
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/fositestorage/consentgrant"
+	"go.pinniped.dev/internal/fositestorage/consentrequest"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	// RequestIDParamName is the name of the param which identifies the pending consent request.
+	RequestIDParamName = "consent_request"
+
+	// DecisionParamName is the name of the form param which holds the user's decision on the consent page.
+	DecisionParamName = "decision"
+	DecisionApprove   = "approve"
+	DecisionDeny      = "deny"
+
+	promptParamName    = "prompt"
+	promptParamConsent = "consent"
+)
+
+// Storage holds the pending consent requests and the scopes which users have already approved.
+type Storage struct {
+	Requests consentrequest.Storage
+	Grants   consentgrant.Storage
+}
+
+func NewStorage(secrets corev1client.SecretInterface, timeoutsConfiguration timeouts.Configuration) *Storage {
+	return &Storage{
+		Requests: consentrequest.New(secrets, time.Now, timeoutsConfiguration.ConsentRequestStorageLifetime),
+		Grants:   consentgrant.New(secrets, time.Now, timeoutsConfiguration.ConsentGrantStorageLifetime),
+	}
+}
+
+// Requester sends users to the consent page after they log in, when required by the client.
+type Requester struct {
+	consentURL        string
+	storage           *Storage
+	generateRequestID func() (string, error)
+}
+
+func NewRequester(issuerURL string, storage *Storage) *Requester {
+	return &Requester{
+		consentURL:        issuerURL + oidc.ConsentEndpointPath,
+		storage:           storage,
+		generateRequestID: generateRequestID,
+	}
+}
+
+// RedirectIfRequired redirects the browser to the consent page when the client of the authorization request
+// requires consent and the user has not already approved all granted scopes for that client, or when the
+// client asked for consent using prompt=consent. It returns true when it wrote the redirect, in which case the
+// caller must not issue an authcode, because the consent handler will issue it after the user approves.
+func (c *Requester) RedirectIfRequired(
+	w http.ResponseWriter,
+	r *http.Request,
+	authorizeRequester fosite.AuthorizeRequester,
+	session *psession.PinnipedSession,
+	csrfToken csrftoken.CSRFToken,
+) (bool, error) {
+	if !requiresConsent(authorizeRequester.GetClient()) {
+		return false, nil
+	}
+
+	clientID := authorizeRequester.GetClient().GetID()
+	if !promptForConsent(authorizeRequester) {
+		approvedScopes, err := c.storage.Grants.GetApprovedScopes(r.Context(), session.Fosite.Claims.Subject, clientID)
+		if err != nil {
+			return false, err
+		}
+		if sets.New(approvedScopes...).HasAll(authorizeRequester.GetGrantedScopes()...) {
+			plog.Debug("user already approved the scopes of the client", "clientID", clientID)
+			return false, nil
+		}
+	}
+
+	requestID, err := c.generateRequestID()
+	if err != nil {
+		return false, err
+	}
+
+	err = c.storage.Requests.CreateConsentRequest(r.Context(), requestID, &consentrequest.ConsentRequest{
+		AuthParams: authorizeRequester.GetRequestForm().Encode(),
+		CSRFToken:  csrfToken,
+		Session:    session,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	redirectURL, err := url.Parse(c.consentURL)
+	if err != nil {
+		return false, err
+	}
+	redirectURL.RawQuery = url.Values{RequestIDParamName: []string{requestID}}.Encode()
+
+	http.Redirect(w, r,
+		redirectURL.String(),
+		http.StatusSeeOther, // match fosite and https://tools.ietf.org/id/draft-ietf-oauth-security-topics-18.html#section-4.11
+	)
+	return true, nil
+}
+
+func requiresConsent(client fosite.Client) bool {
+	pinnipedClient, ok := client.(*clientregistry.Client)
+	return ok && pinnipedClient.Consent != nil && pinnipedClient.Consent.Required
+}
+
+func promptForConsent(authorizeRequester fosite.AuthorizeRequester) bool {
+	prompts := strings.Fields(authorizeRequester.GetRequestForm().Get(promptParamName))
+	return sets.New(prompts...).Has(promptParamConsent)
+}
+
+// displayName returns the name of the client which is shown to the user on the consent page.
+func displayName(client fosite.Client) string {
+	if pinnipedClient, ok := client.(*clientregistry.Client); ok && pinnipedClient.Consent != nil && pinnipedClient.Consent.DisplayName != "" {
+		return pinnipedClient.Consent.DisplayName
+	}
+	return client.GetID()
+}
+
+func generateRequestID() (string, error) {
+	return generateRequestIDFromReader(rand.Reader)
+}
+
+func generateRequestIDFromReader(entropySource io.Reader) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(entropySource, buf); err != nil {
+		return "", fmt.Errorf("could not generate consent request ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent/consenthtml"
+	"go.pinniped.dev/internal/federationdomain/formposthtml"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/fositestorage/consentrequest"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/plog"
+)
+
+//nolint:gochecknoglobals // This is effectively a constant.
+var scopeDescriptions = map[string]string{
+	oidcapi.ScopeOpenID:          "Confirm your identity",
+	oidcapi.ScopeOfflineAccess:   "Stay signed in, even when you are not using the application",
+	oidcapi.ScopeUsername:        "See your username",
+	oidcapi.ScopeGroups:          "See your group memberships",
+	oidcapi.ScopeRequestAudience: "Access Kubernetes clusters as you",
+}
+
+// NewHandler returns a http.Handler that serves the consent page.
+//
+// GET requests show the scopes which the client requested to the user. POST requests either issue the authcode
+// when the user approved the request, or return an access_denied error to the client when the user denied it.
+// Both methods require the same CSRF cookie which was used when the user logged in, so only the browser which
+// was used to log in can see or answer the consent request. The pageBranding, which may be nil, is only
+// used by the form_post page.
+func NewHandler(
+	consentPath string,
+	oauthHelper fosite.OAuth2Provider,
+	storage *Storage,
+	cookieDecoder oidc.Decoder,
+	pageBranding *branding.Branding,
+) http.Handler {
+	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
+		}
+
+		requestID := r.FormValue(RequestIDParamName)
+		if requestID == "" {
+			return httperr.New(http.StatusBadRequest, "consent_request param not found")
+		}
+
+		consentRequest, err := readConsentRequest(r, storage, cookieDecoder, requestID)
+		if err != nil {
+			return err
+		}
+
+		// Get the original params that were used at the authorization endpoint.
+		downstreamAuthParams, err := url.ParseQuery(consentRequest.AuthParams)
+		if err != nil {
+			// This shouldn't really happen because the callback and login endpoints encoded these params correctly.
+			plog.Error("error reading consent request downstream auth params", err)
+			return httperr.New(http.StatusBadRequest, "error reading consent request downstream auth params")
+		}
+
+		// Recreate enough of the original authorize request so we can pass it to NewAuthorizeRequest().
+		reconstitutedAuthRequest := &http.Request{Form: downstreamAuthParams}
+		authorizeRequester, err := oauthHelper.NewAuthorizeRequest(r.Context(), reconstitutedAuthRequest)
+		if err != nil {
+			// This could happen if the client was changed after the user logged in.
+			plog.Error("error using consent request downstream auth params", err,
+				"fositeErr", oidc.FositeErrorForLog(err))
+			return httperr.New(http.StatusBadRequest, "error using consent request downstream auth params")
+		}
+
+		// Grant the same scopes that were granted when the session was created during login.
+		downstreamsession.AutoApproveScopes(authorizeRequester)
+
+		if r.Method == http.MethodGet {
+			return consenthtml.Template().Execute(w, &consenthtml.PageData{
+				ClientName: displayName(authorizeRequester.GetClient()),
+				Username:   consentRequest.Session.Custom.Username,
+				Scopes:     scopesForPage(authorizeRequester.GetGrantedScopes()),
+				RequestID:  requestID,
+				PostPath:   consentPath,
+			})
+		}
+
+		switch r.PostFormValue(DecisionParamName) {
+		case DecisionApprove:
+			// Delete the request first, so it cannot be used to issue a second authcode.
+			if err := storage.Requests.DeleteConsentRequest(r.Context(), requestID); err != nil {
+				plog.Error("error deleting consent request", err)
+				return httperr.New(http.StatusInternalServerError, "error deleting consent request")
+			}
+			err := storage.Grants.ApproveScopes(r.Context(),
+				consentRequest.Session.Fosite.Claims.Subject,
+				authorizeRequester.GetClient().GetID(),
+				authorizeRequester.GetGrantedScopes(),
+			)
+			if err != nil {
+				// The user approved this request, so do not fail it. They will be asked again next time.
+				plog.Error("error remembering approved scopes", err)
+			}
+			oidc.PerformAuthcodeRedirect(r, w, oauthHelper, authorizeRequester, consentRequest.Session, false)
+			return nil
+		case DecisionDeny:
+			// Keep the denied request until it is garbage collected, so the garbage collector can revoke the
+			// upstream tokens which are held by its session.
+			if err := storage.Requests.DenyConsentRequest(r.Context(), requestID); err != nil {
+				plog.Error("error denying consent request", err)
+				return httperr.New(http.StatusInternalServerError, "error denying consent request")
+			}
+			oidc.WriteAuthorizeError(r, w, oauthHelper, authorizeRequester,
+				fosite.ErrAccessDenied.WithHint("The user denied the request."), false)
+			return nil
+		default:
+			return httperr.New(http.StatusBadRequest, "decision param must be approve or deny")
+		}
+	})
+
+	return wrapSecurityHeaders(handler, formposthtml.ContentSecurityPolicy(pageBranding))
+}
+
+func readConsentRequest(r *http.Request, storage *Storage, cookieDecoder oidc.Decoder, requestID string) (*consentrequest.ConsentRequest, error) {
+	csrfFromCookie, err := oidc.ReadCSRFCookie(r, cookieDecoder)
+	if err != nil {
+		plog.InfoErr("CSRF error", err)
+		return nil, err
+	}
+
+	consentRequest, err := storage.Requests.GetConsentRequest(r.Context(), requestID)
+	if errors.IsNotFound(err) {
+		return nil, httperr.New(http.StatusBadRequest, "consent request not found")
+	}
+	if err != nil {
+		plog.Error("error reading consent request", err)
+		return nil, httperr.New(http.StatusInternalServerError, "error reading consent request")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(consentRequest.CSRFToken), []byte(csrfFromCookie)) != 1 {
+		return nil, httperr.New(http.StatusForbidden, "CSRF value does not match")
+	}
+
+	if consentRequest.Denied {
+		return nil, httperr.New(http.StatusBadRequest, "consent request was already denied")
+	}
+
+	return consentRequest, nil
+}
+
+func scopesForPage(grantedScopes fosite.Arguments) []consenthtml.Scope {
+	scopes := make([]consenthtml.Scope, 0, len(grantedScopes))
+	for _, scope := range grantedScopes {
+		description, ok := scopeDescriptions[scope]
+		if !ok {
+			description = scope
+		}
+		scopes = append(scopes, consenthtml.Scope{Name: scope, Description: description})
+	}
+	return scopes
+}
+
+func wrapSecurityHeaders(handler http.Handler, formPostCSPValue string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := securityheader.WrapWithCustomCSP(handler, consenthtml.ContentSecurityPolicy())
+		if r.Method == http.MethodPost {
+			// POST requests can result in the form_post html page, so allow it with CSP headers.
+			wrapped = securityheader.WrapWithCustomCSP(handler, formPostCSPValue)
+		}
+		wrapped.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,291 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent/consenthtml"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/fositestorage/consentrequest"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestConsentEndpoint(t *testing.T) {
+	const (
+		htmlContentType = "text/html; charset=utf-8"
+		consentPath     = "/some-path/consent"
+	)
+
+	cookieCodec := securecookie.New([]byte("fake-hash-secret2"), []byte("0123456789ABCDE2"))
+	cookieCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	encodedCSRFCookie, err := cookieCodec.Encode(oidc.CSRFCookieEncodingName, testCSRFToken)
+	require.NoError(t, err)
+	happyCSRFCookie := oidc.CSRFCookieName + "=" + encodedCSRFCookie
+
+	encodedWrongCSRFCookie, err := cookieCodec.Encode(oidc.CSRFCookieEncodingName, "wrong-csrf")
+	require.NoError(t, err)
+	wrongCSRFCookie := oidc.CSRFCookieName + "=" + encodedWrongCSRFCookie
+
+	happyConsent := &configv1alpha1.OIDCClientConsent{Required: true, DisplayName: "Some Client"}
+
+	tests := []struct {
+		name           string
+		consent        *configv1alpha1.OIDCClientConsent
+		method         string
+		requestID      string
+		decision       string
+		csrfCookie     string
+		alreadyDenied  bool
+		authParams     map[string]string
+		wantStatus     int
+		wantBody       string
+		wantLocation   string
+		wantPageClient string
+
+		wantLocationRegexp      string
+		wantRequestDeleted      bool
+		wantRequestDenied       bool
+		wantApprovedScopes      []string
+		wantFormPostPageCSPs    bool
+		wantConsentPageCSPs     bool
+		wantNoDownstreamStorage bool
+	}{
+		{
+			name:                "GET renders the consent page using the display name of the client",
+			consent:             happyConsent,
+			method:              http.MethodGet,
+			requestID:           testRequestID,
+			csrfCookie:          happyCSRFCookie,
+			wantStatus:          http.StatusOK,
+			wantPageClient:      "Some Client",
+			wantConsentPageCSPs: true,
+		},
+		{
+			name:                "GET renders the consent page using the client ID when there is no display name",
+			consent:             &configv1alpha1.OIDCClientConsent{Required: true},
+			method:              http.MethodGet,
+			requestID:           testRequestID,
+			csrfCookie:          happyCSRFCookie,
+			wantStatus:          http.StatusOK,
+			wantPageClient:      testClientID,
+			wantConsentPageCSPs: true,
+		},
+		{
+			name:                 "POST approve issues the authcode and remembers the approved scopes",
+			consent:              happyConsent,
+			method:               http.MethodPost,
+			requestID:            testRequestID,
+			decision:             DecisionApprove,
+			csrfCookie:           happyCSRFCookie,
+			wantStatus:           http.StatusSeeOther,
+			wantLocationRegexp:   `^` + testRedirectURI + `\?code=[^&]+&scope=openid\+username\+groups&state=` + testDownstreamState + `$`,
+			wantRequestDeleted:   true,
+			wantApprovedScopes:   []string{"groups", "openid", "username"},
+			wantFormPostPageCSPs: true,
+		},
+		{
+			name:                    "POST deny returns an error to the client",
+			consent:                 happyConsent,
+			method:                  http.MethodPost,
+			requestID:               testRequestID,
+			decision:                DecisionDeny,
+			csrfCookie:              happyCSRFCookie,
+			wantStatus:              http.StatusSeeOther,
+			wantLocation:            testRedirectURI + "?error=access_denied&error_description=The+resource+owner+or+authorization+server+denied+the+request.+The+user+denied+the+request.&state=" + testDownstreamState,
+			wantRequestDenied:       true,
+			wantFormPostPageCSPs:    true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "POST with an invalid decision",
+			consent:                 happyConsent,
+			method:                  http.MethodPost,
+			requestID:               testRequestID,
+			decision:                "maybe",
+			csrfCookie:              happyCSRFCookie,
+			wantStatus:              http.StatusBadRequest,
+			wantBody:                "Bad Request: decision param must be approve or deny\n",
+			wantFormPostPageCSPs:    true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "a consent request which was already denied cannot be used again",
+			consent:                 happyConsent,
+			method:                  http.MethodPost,
+			requestID:               testRequestID,
+			decision:                DecisionApprove,
+			csrfCookie:              happyCSRFCookie,
+			alreadyDenied:           true,
+			wantStatus:              http.StatusBadRequest,
+			wantBody:                "Bad Request: consent request was already denied\n",
+			wantFormPostPageCSPs:    true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "consent request not found",
+			consent:                 happyConsent,
+			method:                  http.MethodGet,
+			requestID:               "some-other-request-id",
+			csrfCookie:              happyCSRFCookie,
+			wantStatus:              http.StatusBadRequest,
+			wantBody:                "Bad Request: consent request not found\n",
+			wantConsentPageCSPs:     true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "consent request param is missing",
+			consent:                 happyConsent,
+			method:                  http.MethodGet,
+			csrfCookie:              happyCSRFCookie,
+			wantStatus:              http.StatusBadRequest,
+			wantBody:                "Bad Request: consent_request param not found\n",
+			wantConsentPageCSPs:     true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "CSRF cookie is missing",
+			consent:                 happyConsent,
+			method:                  http.MethodGet,
+			requestID:               testRequestID,
+			wantStatus:              http.StatusForbidden,
+			wantBody:                "Forbidden: CSRF cookie is missing\n",
+			wantConsentPageCSPs:     true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "CSRF cookie does not match the browser which logged in",
+			consent:                 happyConsent,
+			method:                  http.MethodPost,
+			requestID:               testRequestID,
+			decision:                DecisionApprove,
+			csrfCookie:              wrongCSRFCookie,
+			wantStatus:              http.StatusForbidden,
+			wantBody:                "Forbidden: CSRF value does not match\n",
+			wantFormPostPageCSPs:    true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "the client is no longer allowed to request the scopes",
+			consent:                 happyConsent,
+			method:                  http.MethodGet,
+			requestID:               testRequestID,
+			csrfCookie:              happyCSRFCookie,
+			authParams:              map[string]string{"scope": "openid some-unknown-scope"},
+			wantStatus:              http.StatusBadRequest,
+			wantBody:                "Bad Request: error using consent request downstream auth params\n",
+			wantConsentPageCSPs:     true,
+			wantNoDownstreamStorage: true,
+		},
+		{
+			name:                    "PUT is not allowed",
+			consent:                 happyConsent,
+			method:                  http.MethodPut,
+			requestID:               testRequestID,
+			csrfCookie:              happyCSRFCookie,
+			wantStatus:              http.StatusMethodNotAllowed,
+			wantBody:                "Method Not Allowed: PUT (try GET or POST)\n",
+			wantConsentPageCSPs:     true,
+			wantNoDownstreamStorage: true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			subject := newTestSubject(t, tt.consent)
+			ctx := context.Background()
+
+			require.NoError(t, subject.storage.Requests.CreateConsentRequest(ctx, testRequestID, &consentrequest.ConsentRequest{
+				AuthParams: happyAuthParams(tt.authParams).Encode(),
+				CSRFToken:  testCSRFToken,
+				Session:    happySession(),
+			}))
+			if tt.alreadyDenied {
+				require.NoError(t, subject.storage.Requests.DenyConsentRequest(ctx, testRequestID))
+			}
+			subject.kubeClient.ClearActions()
+
+			handler := NewHandler(consentPath, subject.oauthHelper, subject.storage, cookieCodec, nil)
+
+			form := url.Values{}
+			if tt.requestID != "" {
+				form.Set(RequestIDParamName, tt.requestID)
+			}
+			if tt.decision != "" {
+				form.Set(DecisionParamName, tt.decision)
+			}
+			var req *http.Request
+			if tt.method == http.MethodPost {
+				req = httptest.NewRequest(tt.method, consentPath, strings.NewReader(form.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, consentPath+"?"+form.Encode(), nil)
+			}
+			if tt.csrfCookie != "" {
+				req.Header.Set("Cookie", tt.csrfCookie)
+			}
+			rsp := httptest.NewRecorder()
+
+			handler.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, tt.wantStatus, rsp.Code)
+
+			if tt.wantConsentPageCSPs {
+				require.Equal(t, consenthtml.ContentSecurityPolicy(), rsp.Header().Get("Content-Security-Policy"))
+			}
+			if tt.wantFormPostPageCSPs {
+				testutil.RequireSecurityHeadersWithFormPostPageCSPs(t, rsp)
+			}
+
+			switch {
+			case tt.wantPageClient != "":
+				testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), htmlContentType)
+				require.Equal(t, testutil.ExpectedConsentPageHTML(consenthtml.CSS(), tt.wantPageClient, testUsername,
+					[]testutil.ConsentPageExpectedScope{
+						{Name: "openid", Description: "Confirm your identity"},
+						{Name: "username", Description: "See your username"},
+						{Name: "groups", Description: "See your group memberships"},
+					}, consentPath, testRequestID,
+				), rsp.Body.String())
+			case tt.wantLocationRegexp != "":
+				require.Regexp(t, tt.wantLocationRegexp, rsp.Header().Get("Location"))
+			case tt.wantLocation != "":
+				require.Equal(t, tt.wantLocation, rsp.Header().Get("Location"))
+			default:
+				require.Equal(t, tt.wantBody, rsp.Body.String())
+			}
+
+			storedRequest, err := subject.storage.Requests.GetConsentRequest(ctx, testRequestID)
+			if tt.wantRequestDeleted {
+				require.True(t, errors.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantRequestDenied || tt.alreadyDenied, storedRequest.Denied)
+			}
+
+			approvedScopes, err := subject.storage.Grants.GetApprovedScopes(ctx, testDownstreamSubject, testClientID)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantApprovedScopes, approvedScopes)
+
+			if tt.wantNoDownstreamStorage {
+				for _, action := range subject.kubeClient.Actions() {
+					require.NotEqual(t, "create", action.GetVerb(), "should not have created any storage")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consent
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	testNamespace         = "some-namespace"
+	testIssuer            = "https://my-downstream-issuer.com/some-path"
+	testClientID          = "client.oauth.pinniped.dev-test-name"
+	testClientUID         = "fake-client-uid"
+	testRedirectURI       = "http://127.0.0.1/callback"
+	testDownstreamSubject = "https://some-upstream.com?sub=some-user"
+	testUsername          = "some-username"
+	testCSRFToken         = "test-csrf"
+	testRequestID         = "test-request-id"
+	testPKCEChallenge     = "some-challenge"
+	testNonce             = "some-nonce-value-with-enough-bytes-to-exceed-min-allowed"
+	testDownstreamState   = "8b-state"
+	testRequestedScopes   = "openid username groups"
+	consentRequestSecret  = "pinniped-storage-consent-request-"
+)
+
+type testSubject struct {
+	kubeClient  *fake.Clientset
+	oauthHelper fosite.OAuth2Provider
+	storage     *Storage
+}
+
+func newTestSubject(t *testing.T, consent *configv1alpha1.OIDCClientConsent) *testSubject {
+	t.Helper()
+
+	kubeClient := fake.NewSimpleClientset()
+	supervisorClient := supervisorfake.NewSimpleClientset()
+	secrets := kubeClient.CoreV1().Secrets(testNamespace)
+	oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients(testNamespace)
+
+	oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+		testNamespace, testClientID, testClientUID, testRedirectURI,
+		[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+	oidcClient.Spec.Consent = consent
+	require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+	require.NoError(t, kubeClient.Tracker().Add(secret))
+
+	timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+	// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+	oauthStore := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+	hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+	oauthHelper := oidc.FositeOauth2Helper(oauthStore, testIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration, nil, nil)
+
+	return &testSubject{
+		kubeClient:  kubeClient,
+		oauthHelper: oauthHelper,
+		storage:     NewStorage(secrets, timeoutsConfiguration),
+	}
+}
+
+func happyAuthParams(modifications map[string]string) url.Values {
+	params := url.Values{
+		"response_type":         []string{"code"},
+		"scope":                 []string{testRequestedScopes},
+		"client_id":             []string{testClientID},
+		"state":                 []string{testDownstreamState},
+		"nonce":                 []string{testNonce},
+		"code_challenge":        []string{testPKCEChallenge},
+		"code_challenge_method": []string{"S256"},
+		"redirect_uri":          []string{testRedirectURI},
+	}
+	for key, value := range modifications {
+		params.Set(key, value)
+	}
+	return params
+}
+
+func happySession() *psession.PinnipedSession {
+	now := time.Now().UTC()
+	return &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &jwt.IDTokenClaims{
+				Subject:     testDownstreamSubject,
+				RequestedAt: now,
+				AuthTime:    now,
+			},
+		},
+		Custom: &psession.CustomSessionData{
+			Username:     testUsername,
+			ProviderUID:  "some-provider-uid",
+			ProviderName: "some-provider-name",
+			ProviderType: psession.ProviderTypeLDAP,
+			LDAP:         &psession.LDAPSessionData{UserDN: "cn=some-user"},
+		},
+	}
+}
+
+func (s *testSubject) newAuthorizeRequester(t *testing.T, params url.Values) fosite.AuthorizeRequester {
+	t.Helper()
+
+	authorizeRequester, err := s.oauthHelper.NewAuthorizeRequest(context.Background(), &http.Request{Form: params})
+	require.NoError(t, err)
+	downstreamsession.AutoApproveScopes(authorizeRequester)
+	return authorizeRequester
+}
+
+func (s *testSubject) requireConsentRequestCount(t *testing.T, want int) {
+	t.Helper()
+
+	count := 0
+	for _, action := range s.kubeClient.Actions() {
+		if action.GetVerb() != "create" {
+			continue
+		}
+		if strings.HasPrefix(action.(coretesting.CreateAction).GetObject().(*corev1.Secret).Name, consentRequestSecret) {
+			count++
+		}
+	}
+	require.Equal(t, want, count)
+}
+
+func TestRedirectIfRequired(t *testing.T) {
+	tests := []struct {
+		name               string
+		consent            *configv1alpha1.OIDCClientConsent
+		approvedScopes     []string
+		authParams         map[string]string
+		generateRequestErr error
+		wantRedirect       bool
+		wantErr            string
+	}{
+		{
+			name:         "client does not configure consent",
+			consent:      nil,
+			wantRedirect: false,
+		},
+		{
+			name:         "client does not require consent",
+			consent:      &configv1alpha1.OIDCClientConsent{Required: false, DisplayName: "Some Client"},
+			wantRedirect: false,
+		},
+		{
+			name:         "pinniped-cli never requires consent",
+			consent:      &configv1alpha1.OIDCClientConsent{Required: true},
+			authParams:   map[string]string{"client_id": "pinniped-cli"},
+			wantRedirect: false,
+		},
+		{
+			name:         "client requires consent and the user has not approved any scopes",
+			consent:      &configv1alpha1.OIDCClientConsent{Required: true},
+			wantRedirect: true,
+		},
+		{
+			name:           "client requires consent and the user has only approved some of the scopes",
+			consent:        &configv1alpha1.OIDCClientConsent{Required: true},
+			approvedScopes: []string{"openid", "username"},
+			wantRedirect:   true,
+		},
+		{
+			name:           "client requires consent and the user has already approved all of the scopes",
+			consent:        &configv1alpha1.OIDCClientConsent{Required: true},
+			approvedScopes: []string{"openid", "username", "groups", "offline_access"},
+			wantRedirect:   false,
+		},
+		{
+			name:           "client requires consent and asks for it again using prompt=consent",
+			consent:        &configv1alpha1.OIDCClientConsent{Required: true},
+			approvedScopes: []string{"openid", "username", "groups"},
+			authParams:     map[string]string{"prompt": "login consent"},
+			wantRedirect:   true,
+		},
+		{
+			name:               "error generating the request ID",
+			consent:            &configv1alpha1.OIDCClientConsent{Required: true},
+			generateRequestErr: errors.New("some random error"),
+			wantErr:            "some random error",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			subject := newTestSubject(t, tt.consent)
+			requester := NewRequester(testIssuer, subject.storage)
+			requester.generateRequestID = func() (string, error) { return testRequestID, tt.generateRequestErr }
+
+			ctx := context.Background()
+			if tt.approvedScopes != nil {
+				require.NoError(t, subject.storage.Grants.ApproveScopes(ctx, testDownstreamSubject, testClientID, tt.approvedScopes))
+			}
+
+			params := happyAuthParams(tt.authParams)
+			authorizeRequester := subject.newAuthorizeRequester(t, params)
+			req := httptest.NewRequest(http.MethodPost, "/ignored", nil)
+			rsp := httptest.NewRecorder()
+
+			redirected, err := requester.RedirectIfRequired(rsp, req, authorizeRequester, happySession(), testCSRFToken)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.False(t, redirected)
+				subject.requireConsentRequestCount(t, 0)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRedirect, redirected)
+
+			if !tt.wantRedirect {
+				require.Empty(t, rsp.Header().Get("Location"))
+				subject.requireConsentRequestCount(t, 0)
+				return
+			}
+
+			require.Equal(t, http.StatusSeeOther, rsp.Code)
+			require.Equal(t, testIssuer+"/consent?consent_request="+testRequestID, rsp.Header().Get("Location"))
+			subject.requireConsentRequestCount(t, 1)
+
+			consentRequest, err := subject.storage.Requests.GetConsentRequest(ctx, testRequestID)
+			require.NoError(t, err)
+			require.Equal(t, params, mustParseQuery(t, consentRequest.AuthParams))
+			require.Equal(t, testCSRFToken, string(consentRequest.CSRFToken))
+			require.Equal(t, testUsername, consentRequest.Session.Custom.Username)
+			require.Equal(t, testDownstreamSubject, consentRequest.Session.Fosite.Claims.Subject)
+			require.False(t, consentRequest.Denied)
+		})
+	}
+}
+
+func TestGenerateRequestID(t *testing.T) {
+	id, err := generateRequestIDFromReader(strings.NewReader(strings.Repeat("a", 32)))
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("61", 32), id)
+
+	_, err = generateRequestIDFromReader(strings.NewReader("not enough"))
+	require.EqualError(t, err, "could not generate consent request ID: unexpected EOF")
+}
+
+func mustParseQuery(t *testing.T, query string) url.Values {
+	t.Helper()
+
+	values, err := url.ParseQuery(query)
+	require.NoError(t, err)
+	return values
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consenthtml
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/testutil"
+)
+
+var (
+	testExpectedCSS = `html{height:100%}body{font-family:metropolis-light,Helvetica,sans-serif;display:flex;flex-flow:column wrap;justify-content:flex-start;align-items:center;background:linear-gradient(to top,#f8f8f8,white);min-height:100%}h1{font-size:20px;margin:0}.box{display:flex;flex-direction:column;flex-wrap:nowrap;border-radius:4px;border-color:#ddd;border-width:1px;border-style:solid;width:400px;padding:30px 30px 0;margin:60px 20px 0;background:#fff;font-size:14px}.form-field{display:flex;margin-bottom:30px}.scopes{margin:0;padding-left:1.5em}.scope-name{font-weight:700}button{width:100%;padding:1em;border:0;font:inherit;background-color:#218fcf;color:#eee;font-weight:700;cursor:pointer;transition:all .3s}button:focus,button:hover{background-color:#1abfd3}button:active{transform:scale(.99)}button.deny{background-color:#a6a6a6}`
+
+	// It's okay if this changes in the future, but this gives us a chance to eyeball the formatting.
+	// Our browser-based integration tests should find any incompatibilities.
+	testExpectedCSP = `default-src 'none'; ` +
+		`style-src 'sha256-mTNu993yCw2qIRxt+qexpublEKw3fcKSy6x1icOMyCg='; ` +
+		`frame-ancestors 'none'`
+)
+
+func TestTemplate(t *testing.T) {
+	const (
+		testClientName = "test-client-name"
+		testUsername   = "test-username"
+		testPath       = "test-post-path"
+		testRequestID  = "test-request-id"
+	)
+
+	pageInputs := &PageData{
+		ClientName: testClientName,
+		Username:   testUsername,
+		Scopes: []Scope{
+			{Name: "openid", Description: "test-openid-description"},
+			{Name: "groups", Description: "test-groups-description"},
+		},
+		RequestID: testRequestID,
+		PostPath:  testPath,
+	}
+
+	expectedHTML := testutil.ExpectedConsentPageHTML(testExpectedCSS, testClientName, testUsername, []testutil.ConsentPageExpectedScope{
+		{Name: "openid", Description: "test-openid-description"},
+		{Name: "groups", Description: "test-groups-description"},
+	}, testPath, testRequestID)
+
+	var buf bytes.Buffer
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	// t.Logf("actual value:\n%s", buf.String()) // useful when updating minify library causes new output
+	require.Equal(t, expectedHTML, buf.String())
+}
+
+func TestTemplateEscapesValues(t *testing.T) {
+	pageInputs := &PageData{
+		ClientName: `<script>alert("client")</script>`,
+		Username:   `<b>user</b>`,
+		Scopes:     []Scope{{Name: "openid", Description: "<i>description</i>"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	require.NotContains(t, buf.String(), "<script>")
+	require.NotContains(t, buf.String(), "<b>")
+	require.NotContains(t, buf.String(), "<i>")
+	require.Contains(t, buf.String(), "&lt;script&gt;alert(&#34;client&#34;)&lt;/script&gt; would like to access your account")
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+}
+
+func TestCSS(t *testing.T) {
+	require.Equal(t, testExpectedCSS, CSS())
+}
+
+func TestHelpers(t *testing.T) {
+	require.Equal(t, "test", panicOnError("test", nil))
+	require.PanicsWithError(t, "some error", func() { panicOnError("", fmt.Errorf("some error")) })
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package discovery provides a handler for the OIDC discovery endpoint.
@@ -35,13 +35,23 @@ type Metadata struct {
 
 	// vvv Optional vvv
 
-	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
-	ScopesSupported                   []string `json:"scopes_supported"`
-	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
 
 	// https://datatracker.ietf.org/doc/html/rfc8414#section-2 says, “If omitted, the authorization server does not support PKCE.”
 	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
 
+	// https://datatracker.ietf.org/doc/html/rfc9126#section-5
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests bool   `json:"require_pushed_authorization_requests"`
+
+	// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+	RequestParameterSupported              bool     `json:"request_parameter_supported"`
+	RequestURIParameterSupported           bool     `json:"request_uri_parameter_supported"`
+	RequestObjectSigningAlgValuesSupported []string `json:"request_object_signing_alg_values_supported"`
+
 	// ^^^ Optional ^^^
 
 	// vvv Custom vvv
@@ -53,6 +63,9 @@ type Metadata struct {
 
 // NewHandler returns an http.Handler that serves an OIDC discovery endpoint.
 func NewHandler(issuerURL string) http.Handler {
+	// The algorithms which clients may use to sign JWTs, e.g. for private_key_jwt client authentication or request objects.
+	clientSigningAlgs := []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
 	oidcConfig := Metadata{
 		Issuer:                issuerURL,
 		AuthorizationEndpoint: issuerURL + oidc.AuthorizationEndpointPath,
@@ -63,14 +76,24 @@ func NewHandler(issuerURL string) http.Handler {
 				PinnipedIDPsEndpoint: issuerURL + oidc.PinnipedIDPsPathV1Alpha1,
 			},
 		},
-		ResponseTypesSupported:            []string{"code"},
-		ResponseModesSupported:            []string{"query", "form_post"},
-		SubjectTypesSupported:             []string{"public"},
-		IDTokenSigningAlgValuesSupported:  []string{"ES256"},
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic"},
-		CodeChallengeMethodsSupported:     []string{"S256"},
-		ScopesSupported:                   []string{oidcapi.ScopeOpenID, oidcapi.ScopeOfflineAccess, oidcapi.ScopeRequestAudience, oidcapi.ScopeUsername, oidcapi.ScopeGroups},
-		ClaimsSupported:                   []string{oidcapi.IDTokenClaimUsername, oidcapi.IDTokenClaimGroups, oidcapi.IDTokenClaimAdditionalClaims},
+		ResponseTypesSupported:                     []string{"code"},
+		ResponseModesSupported:                     []string{"query", "form_post"},
+		SubjectTypesSupported:                      []string{"public"},
+		IDTokenSigningAlgValuesSupported:           []string{"ES256"},
+		TokenEndpointAuthMethodsSupported:          []string{"client_secret_basic", "private_key_jwt", "tls_client_auth"},
+		TokenEndpointAuthSigningAlgValuesSupported: clientSigningAlgs,
+		CodeChallengeMethodsSupported:              []string{"S256"},
+		PushedAuthorizationRequestEndpoint:         issuerURL + oidc.PushedAuthorizeEndpointPath,
+		// Clients may be individually configured to require pushed authorization requests.
+		RequirePushedAuthorizationRequests: false,
+		// Request objects (JAR) are accepted from clients which have registered their keys using private_key_jwt.
+		// Request objects are not fetched by reference, since the request_uri param is only used for pushed
+		// authorization requests.
+		RequestParameterSupported:              true,
+		RequestURIParameterSupported:           false,
+		RequestObjectSigningAlgValuesSupported: clientSigningAlgs,
+		ScopesSupported:                        []string{oidcapi.ScopeOpenID, oidcapi.ScopeOfflineAccess, oidcapi.ScopeRequestAudience, oidcapi.ScopeUsername, oidcapi.ScopeGroups},
+		ClaimsSupported:                        []string{oidcapi.IDTokenClaimUsername, oidcapi.IDTokenClaimGroups, oidcapi.IDTokenClaimAdditionalClaims},
 	}
 
 	var b bytes.Buffer
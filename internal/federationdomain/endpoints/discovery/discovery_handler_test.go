@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package discovery
@@ -44,9 +44,15 @@ func TestDiscovery(t *testing.T) {
 				"response_modes_supported": ["query", "form_post"],
 				"subject_types_supported": ["public"],
 				"id_token_signing_alg_values_supported": ["ES256"],
-				"token_endpoint_auth_methods_supported": ["client_secret_basic"],
+				"token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt", "tls_client_auth"],
+"token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
 				"scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
 				"code_challenge_methods_supported": ["S256"],
+				"pushed_authorization_request_endpoint": "https://some-issuer.com/some/path/oauth2/par",
+				"require_pushed_authorization_requests": false,
+				"request_parameter_supported": true,
+				"request_uri_parameter_supported": false,
+				"request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
 				"claims_supported": ["username", "groups", "additionalClaims"],
 				"discovery.supervisor.pinniped.dev/v1alpha1": {
 					"pinniped_identity_providers_endpoint": "https://some-issuer.com/some/path/v1alpha1/pinniped_identity_providers"
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package idpdiscovery provides a handler for the upstream IDP discovery endpoint.
@@ -12,6 +12,7 @@ import (
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 )
 
 // NewHandler returns an http.Handler that serves the upstream IDP discovery endpoint.
@@ -42,9 +43,10 @@ func responseAsJSON(upstreamIDPs federationdomainproviders.FederationDomainIdent
 	// The cache of IDPs could change at any time, so always recalculate the list.
 	for _, federationDomainIdentityProvider := range upstreamIDPs.GetIdentityProviders() {
 		r.PinnipedIDPs = append(r.PinnipedIDPs, v1alpha1.PinnipedIDP{
-			Name:  federationDomainIdentityProvider.GetDisplayName(),
-			Type:  federationDomainIdentityProvider.GetIDPDiscoveryType(),
-			Flows: federationDomainIdentityProvider.GetIDPDiscoveryFlows(),
+			Name:    federationDomainIdentityProvider.GetDisplayName(),
+			Type:    federationDomainIdentityProvider.GetIDPDiscoveryType(),
+			Flows:   federationDomainIdentityProvider.GetIDPDiscoveryFlows(),
+			Routing: routingForIDPDiscovery(federationDomainIdentityProvider.GetRouting()),
 		})
 	}
 
@@ -59,3 +61,15 @@ func responseAsJSON(upstreamIDPs federationdomainproviders.FederationDomainIdent
 
 	return encodedMetadata, encodeErr
 }
+
+// routingForIDPDiscovery returns the routing rules as shown by the IDP discovery endpoint, so that clients can
+// choose an identity provider for a username in the same way as the authorization endpoint.
+func routingForIDPDiscovery(routing *idprouting.Rules) *v1alpha1.IDPRouting {
+	if routing == nil {
+		return nil
+	}
+	return &v1alpha1.IDPRouting{
+		EmailDomains:  routing.EmailDomains(),
+		UsernameRegex: routing.UsernameRegex(),
+	}
+}
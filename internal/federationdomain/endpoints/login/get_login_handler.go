@@ -1,24 +1,104 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
+	"go.pinniped.dev/internal/federationdomain/locale"
 	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/federationdomain/webauthn"
+	"go.pinniped.dev/internal/fositestorage/secondfactorrequest"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
 )
 
-const (
-	internalErrorMessage                    = "An internal error occurred. Please contact your administrator for help."
-	incorrectUsernameOrPasswordErrorMessage = "Incorrect username or password."
-)
+// NewGetHandler returns a HandlerFunc which renders the login page. When the second_factor param is present,
+// it renders the page which asks for the user's one-time password or security key instead. The issuerURL is used
+// to label the enrollment in the user's authenticator app, and it is the relying party of security keys. The pageBranding, which may be nil, customizes both pages.
+func NewGetHandler(
+	loginPath string,
+	issuerURL string,
+	secondFactorStorage *secondfactor.Storage,
+	pageBranding *branding.Branding,
+) HandlerFunc {
+	totpIssuerName := issuerURL
+	if parsedIssuerURL, err := url.Parse(issuerURL); err == nil && parsedIssuerURL.Host != "" {
+		totpIssuerName = parsedIssuerURL.Host
+	}
+
+	// The FederationDomain watcher already rejects brandings whose login template does not work,
+	// so this should not fail in practice. Fall back to the default login page just in case.
+	loginTemplate, err := loginhtml.TemplateWithBranding(pageBranding)
+	if err != nil {
+		plog.Error("could not use the login template of the branding, using the default login page instead", err,
+			"issuer", issuerURL)
+		loginTemplate = loginhtml.Template()
+	}
 
-func NewGetHandler(loginPath string) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, encodedState string, decodedState *oidc.UpstreamStateParamData) error {
-		alertMessage, hasAlert := getAlert(r)
+		// The params of the original authorization request may include the ui_locales param.
+		downstreamAuthParams, err := url.ParseQuery(decodedState.AuthParams)
+		if err != nil {
+			// This shouldn't really happen because the authorization endpoint encoded these query params correctly.
+			plog.Error("error reading state downstream auth params", err)
+			return httperr.New(http.StatusBadRequest, "error reading state downstream auth params")
+		}
+		localizer := locale.ForRequest(r, downstreamAuthParams)
+
+		alertMessage, hasAlert := getAlert(r, localizer)
+
+		if secondFactorRequestID := r.URL.Query().Get(loginurl.SecondFactorParamName); secondFactorRequestID != "" {
+			secondFactorRequest, err := readSecondFactorRequest(r, secondFactorStorage, secondFactorRequestID, decodedState)
+			if err != nil {
+				return err
+			}
+
+			if secondFactorRequest.WebAuthnChallenge != "" {
+				return renderWebAuthnPage(w, r, &loginhtml.WebAuthnPageData{
+					PostPath:      loginPath,
+					State:         encodedState,
+					IDPName:       decodedState.UpstreamName,
+					RequestID:     secondFactorRequestID,
+					HasAlertError: hasAlert,
+					AlertMessage:  alertMessage,
+					Branding:      pageBranding,
+					Locale:        localizer,
+				}, issuerURL, secondFactorStorage, secondFactorRequest)
+			}
+
+			pageInputs := &loginhtml.SecondFactorPageData{
+				PostPath:         loginPath,
+				State:            encodedState,
+				IDPName:          decodedState.UpstreamName,
+				RequestID:        secondFactorRequestID,
+				HasAlertError:    hasAlert,
+				AlertMessage:     alertMessage,
+				EnrollmentSecret: secondFactorRequest.PendingTOTPSecret,
+				Branding:         pageBranding,
+				Locale:           localizer,
+			}
+			if secondFactorRequest.PendingTOTPSecret != "" {
+				pageInputs.EnrollmentURI = template.URL(totp.KeyURI( //nolint:gosec // the URI is built by totp.KeyURI, which escapes its parts
+					totpIssuerName,
+					secondFactorRequest.Session.Custom.Username,
+					secondFactorRequest.PendingTOTPSecret,
+				))
+			}
+			return loginhtml.SecondFactorTemplate().Execute(w, pageInputs)
+		}
 
 		pageInputs := &loginhtml.PageData{
 			PostPath:      loginPath,
@@ -26,18 +106,85 @@ func NewGetHandler(loginPath string) HandlerFunc {
 			IDPName:       decodedState.UpstreamName,
 			HasAlertError: hasAlert,
 			AlertMessage:  alertMessage,
+			Branding:      pageBranding,
+			Locale:        localizer,
 		}
-		return loginhtml.Template().Execute(w, pageInputs)
+		return loginTemplate.Execute(w, pageInputs)
 	}
 }
 
-func getAlert(r *http.Request) (string, bool) {
+// renderWebAuthnPage renders the page which asks the user to register or to use their security key or passkey.
+func renderWebAuthnPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	pageInputs *loginhtml.WebAuthnPageData,
+	issuerURL string,
+	secondFactorStorage *secondfactor.Storage,
+	secondFactorRequest *secondfactorrequest.SecondFactorRequest,
+) error {
+	rp, err := webauthn.NewRelyingParty(issuerURL)
+	if err != nil {
+		plog.Error("error reading issuer URL as WebAuthn relying party", err)
+		return httperr.New(http.StatusInternalServerError, "error reading issuer URL as WebAuthn relying party")
+	}
+
+	subject := secondFactorRequest.Session.Fosite.Claims.Subject
+	pageInputs.Register = secondFactorRequest.PendingWebAuthnRegistration
+	pageInputs.Challenge = secondFactorRequest.WebAuthnChallenge
+	pageInputs.RPID = rp.ID
+	// The user handle must not contain personal information, so it is the hash of the downstream subject.
+	userHandle := sha256.Sum256([]byte(subject))
+	pageInputs.UserID = base64.RawURLEncoding.EncodeToString(userHandle[:])
+	pageInputs.UserName = secondFactorRequest.Session.Custom.Username
+
+	algorithms := make([]string, 0, len(webauthn.SupportedAlgorithms()))
+	for _, algorithm := range webauthn.SupportedAlgorithms() {
+		algorithms = append(algorithms, strconv.Itoa(algorithm))
+	}
+	pageInputs.Algorithms = strings.Join(algorithms, ",")
+
+	if !pageInputs.Register {
+		credentialID, err := secondFactorStorage.WebAuthnCredentialID(r.Context(), subject)
+		if err != nil {
+			plog.Error("error reading WebAuthn enrollment", err)
+			return httperr.New(http.StatusInternalServerError, "error reading WebAuthn enrollment")
+		}
+		if credentialID == nil {
+			// The user's enrollment was deleted after they entered their password, so they must start over.
+			plog.Info("user's second factor enrollment was deleted during login", "subject", subject)
+			return redirectToLoginPage(r, w, issuerURL, pageInputs.State, loginurl.ShowInternalError)
+		}
+		pageInputs.CredentialID = base64.RawURLEncoding.EncodeToString(credentialID)
+	}
+
+	return loginhtml.WebAuthnTemplate().Execute(w, pageInputs)
+}
+
+func getAlert(r *http.Request, localizer *locale.Localizer) (string, bool) {
 	errorParamValue := r.URL.Query().Get(loginurl.ErrParamName)
 
-	message := internalErrorMessage
-	if errorParamValue == string(loginurl.ShowBadUserPassErr) {
-		message = incorrectUsernameOrPasswordErrorMessage
+	messageKey := "login.error.internal"
+	switch loginurl.ErrorParamValue(errorParamValue) {
+	case loginurl.ShowBadUserPassErr:
+		messageKey = "login.error.incorrectUsernameOrPassword"
+	case loginurl.ShowPasswordExpiredErr:
+		messageKey = "login.error.passwordExpired"
+	case loginurl.ShowPasswordMustChangeErr:
+		messageKey = "login.error.passwordMustChange"
+	case loginurl.ShowAccountLockedErr:
+		messageKey = "login.error.accountLocked"
+	case loginurl.ShowBadOTPErr:
+		messageKey = "login.error.incorrectOneTimePassword"
+	case loginurl.ShowTooManyOTPAttemptsErr:
+		messageKey = "login.error.tooManyOneTimePasswords"
+	case loginurl.ShowOTPLockedOutErr:
+		messageKey = "login.error.oneTimePasswordsLockedOut"
+	case loginurl.ShowBadSecurityKeyErr:
+		messageKey = "login.error.securityKeyNotAccepted"
+	case loginurl.ShowTooManySecurityKeyAttemptsErr:
+		messageKey = "login.error.tooManySecurityKeyAttempts"
+	case loginurl.ShowNoError, loginurl.ShowInternalError: // this is just here to avoid a lint error about not handling all cases
 	}
 
-	return message, errorParamValue != ""
+	return localizer.T(messageKey), errorParamValue != ""
 }
@@ -1,18 +1,27 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/ory/fosite/handler/openid"
+	"github.com/ory/fosite/token/jwt"
 	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
+	"go.pinniped.dev/internal/fositestorage/secondfactorrequest"
+	"go.pinniped.dev/internal/fositestorage/webauthnenrollment"
+	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/internal/testutil"
 )
 
@@ -22,17 +31,57 @@ func TestGetLogin(t *testing.T) {
 		testUpstreamName = "some-ldap-idp"
 		testUpstreamType = "ldap"
 		testEncodedState = "fake-encoded-state-value"
+		testIssuer       = "https://my-issuer.com/some/path"
+		testRequestID    = "some-second-factor-request-id"
+		testTOTPSecret   = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
 	)
 
+	secondFactorRequest := func(pendingTOTPSecret string) *secondfactorrequest.SecondFactorRequest {
+		return &secondfactorrequest.SecondFactorRequest{
+			AuthParams: "client_id=pinniped-cli",
+			CSRFToken:  "some-csrf",
+			Session: &psession.PinnipedSession{
+				Fosite: &openid.DefaultSession{Claims: &jwt.IDTokenClaims{Subject: "some-subject"}},
+				Custom: &psession.CustomSessionData{Username: "some-username"},
+			},
+			PendingTOTPSecret: pendingTOTPSecret,
+		}
+	}
+
+	webAuthnRequest := func(pendingRegistration bool) *secondfactorrequest.SecondFactorRequest {
+		request := secondFactorRequest("")
+		request.WebAuthnChallenge = "some-challenge"
+		request.PendingWebAuthnRegistration = pendingRegistration
+		return request
+	}
+
+	secondFactorState := &oidc.UpstreamStateParamData{
+		AuthParams:   "client_id=pinniped-cli",
+		CSRFToken:    "some-csrf",
+		UpstreamName: testUpstreamName,
+		UpstreamType: testUpstreamType,
+	}
+
 	tests := []struct {
-		name            string
-		decodedState    *oidc.UpstreamStateParamData
-		encodedState    string
-		errParam        string
-		idps            idplister.UpstreamIdentityProvidersLister
-		wantStatus      int
-		wantContentType string
-		wantBody        string
+		name         string
+		decodedState *oidc.UpstreamStateParamData
+		encodedState string
+		errParam     string
+		idps         idplister.UpstreamIdentityProvidersLister
+
+		secondFactorParam   string
+		secondFactorRequest *secondfactorrequest.SecondFactorRequest
+		webAuthnEnrollment  *webauthnenrollment.Enrollment
+
+		branding       *branding.Branding
+		acceptLanguage string
+
+		wantErr          string
+		wantStatus       int
+		wantContentType  string
+		wantBody         string
+		wantBodyContains []string
+		wantLocation     string
 	}{
 		{
 			name: "Happy path ldap",
@@ -73,6 +122,210 @@ func TestGetLogin(t *testing.T) {
 				"An internal error occurred. Please contact your administrator for help.",
 			),
 		},
+		{
+			name: "displays error banner when err=password_expired param is sent",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "password_expired",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"Your password has expired. Please change your password and try again.",
+			),
+		},
+		{
+			name: "displays error banner when err=password_must_change param is sent",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "password_must_change",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"You must change your password before you can log in. Please change your password and try again.",
+			),
+		},
+		{
+			name: "displays error banner when err=account_locked param is sent",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "account_locked",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"Your account is locked. Please contact your administrator for help.",
+			),
+		},
+		{
+			name: "displays error banner when err=too_many_otp_attempts param is sent",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "too_many_otp_attempts",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"Too many incorrect one-time passwords. Please log in again.",
+			),
+		},
+		{
+			name: "displays error banner when err=otp_locked_out param is sent",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "otp_locked_out",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"Too many incorrect one-time passwords. Please wait a few minutes before logging in again.",
+			),
+		},
+		{
+			name:                "displays the one-time password page when the second_factor param is sent",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(""),
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBody: testutil.ExpectedSecondFactorPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState, testRequestID,
+				"", "", "",
+			),
+		},
+		{
+			name:                "displays the one-time password page with the enrollment when the user has not enrolled an authenticator app",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(testTOTPSecret),
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBody: testutil.ExpectedSecondFactorPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState, testRequestID,
+				testTOTPSecret,
+				"otpauth://totp/my-issuer.com:some-username?algorithm=SHA1&amp;digits=6&amp;issuer=my-issuer.com&amp;period=30&amp;secret="+testTOTPSecret,
+				"",
+			),
+		},
+		{
+			name:                "displays error banner on the one-time password page when err=otp_error param is sent",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			errParam:            "otp_error",
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(""),
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBody: testutil.ExpectedSecondFactorPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState, testRequestID,
+				"", "", "Incorrect one-time password.",
+			),
+		},
+		{
+			name:                "displays the security key page with the registration when the user has not registered a security key",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: webAuthnRequest(true),
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBodyContains: []string{
+				`<script>`,
+				`<p id="webauthn-instructions">Register a security key or passkey to finish logging in.`,
+				// The user ID is the hash of the subject, and the relying party is the host of the issuer.
+				`data-register="true" data-challenge="some-challenge" data-rp-id="my-issuer.com" ` +
+					`data-user-id="bIxIBe8XfWCMYe8-6I0Z_rLyNMcrRfbUedc-PGQ4W6U"`,
+				`data-user-name="some-username" data-credential-id="" data-algorithms="-7,-8,-257"`,
+				`<input type="hidden" name="second_factor" id="second_factor" value="` + testRequestID + `">`,
+				`<input type="hidden" name="webauthn_attestation_object" id="webauthn_attestation_object">`,
+			},
+		},
+		{
+			name:                "displays the security key page when the user has registered a security key",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: webAuthnRequest(false),
+			webAuthnEnrollment: &webauthnenrollment.Enrollment{
+				Subject:      "some-subject",
+				CredentialID: []byte("some-credential-id"),
+				PublicKey:    []byte("some-public-key"),
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBodyContains: []string{
+				`<p id="webauthn-instructions">Use your security key or passkey to finish logging in.</p>`,
+				`data-register="false" data-challenge="some-challenge"`,
+				`data-credential-id="c29tZS1jcmVkZW50aWFsLWlk"`,
+				`<input type="hidden" name="webauthn_signature" id="webauthn_signature">`,
+			},
+		},
+		{
+			name:                "displays error banner on the security key page when err=security_key_error param is sent",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			errParam:            "security_key_error",
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: webAuthnRequest(true),
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBodyContains: []string{
+				`id="alert">Your security key was not accepted. Please try again.</span>`,
+			},
+		},
+		{
+			name:                "redirects to the login page when the user's security key was deleted during the login",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: webAuthnRequest(false),
+			wantStatus:          http.StatusSeeOther,
+			wantContentType:     htmlContentType,
+			wantLocation:        testIssuer + "/login?err=internal_error&state=" + testEncodedState,
+		},
+		{
+			name:              "second factor request is not found",
+			decodedState:      secondFactorState,
+			encodedState:      testEncodedState,
+			secondFactorParam: testRequestID,
+			wantErr:           "second factor request not found",
+		},
+		{
+			name: "second factor request belongs to a different browser",
+			decodedState: &oidc.UpstreamStateParamData{
+				AuthParams:   "client_id=pinniped-cli",
+				CSRFToken:    "some-other-csrf",
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(""),
+			wantErr:             "CSRF value does not match",
+		},
+		{
+			name: "second factor request belongs to a different authorization request",
+			decodedState: &oidc.UpstreamStateParamData{
+				AuthParams:   "client_id=some-other-client",
+				CSRFToken:    "some-csrf",
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:        testEncodedState,
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(""),
+			wantErr:             "second factor request does not match state param",
+		},
 		{
 			// If we get an error that we don't recognize, that's also an error, so we
 			// should probably just tell you to contact your administrator...
@@ -89,6 +342,117 @@ func TestGetLogin(t *testing.T) {
 				"An internal error occurred. Please contact your administrator for help.",
 			),
 		},
+		{
+			name: "renders the login page in the language of the Accept-Language header",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "login_error",
+			acceptLanguage:  "de-DE,de;q=0.9,en;q=0.8",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBodyContains: []string{
+				`<html lang="de">`,
+				`<title>Pinniped-Anmeldung</title>`,
+				`<h1>Bei some-ldap-idp anmelden</h1>`,
+				`id="alert">Falscher Benutzername oder falsches Passwort.</span>`,
+				`placeholder="Benutzername"`,
+				`placeholder="Passwort"`,
+				`value="Anmelden"`,
+			},
+		},
+		{
+			name: "renders the login page in the language of the ui_locales param of the authorization request, which takes precedence over the Accept-Language header",
+			decodedState: &oidc.UpstreamStateParamData{
+				AuthParams:   "ui_locales=fr-CA+en",
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			acceptLanguage:  "de",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBodyContains: []string{
+				`<html lang="fr">`,
+				`<h1>Se connecter à some-ldap-idp</h1>`,
+				`placeholder="Nom d’utilisateur"`,
+			},
+		},
+		{
+			name: "renders the login page in English when none of the preferred languages are supported",
+			decodedState: &oidc.UpstreamStateParamData{
+				AuthParams:   "ui_locales=xx",
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			errParam:        "login_error",
+			acceptLanguage:  "zz",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState,
+				"Incorrect username or password.",
+			),
+		},
+		{
+			name:                "renders the one-time password page in the language of the Accept-Language header",
+			decodedState:        secondFactorState,
+			encodedState:        testEncodedState,
+			errParam:            "otp_error",
+			secondFactorParam:   testRequestID,
+			secondFactorRequest: secondFactorRequest(testTOTPSecret),
+			acceptLanguage:      "es",
+			wantStatus:          http.StatusOK,
+			wantContentType:     htmlContentType,
+			wantBodyContains: []string{
+				`<html lang="es">`,
+				`id="alert">Contraseña de un solo uso incorrecta.</span>`,
+				`Añada esta clave a su aplicación de autenticación, o <a href="otpauth://`,
+				`>ábrala en su aplicación de autenticación</a>. Después, introduzca`,
+				`placeholder="Contraseña de un solo uso"`,
+				`value="Verificar"`,
+			},
+		},
+		{
+			name: "the downstream auth params of the state param cannot be parsed",
+			decodedState: &oidc.UpstreamStateParamData{
+				AuthParams:   "%z",
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState: testEncodedState,
+			wantErr:      "error reading state downstream auth params",
+		},
+		{
+			name: "renders the login template of the branding",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState: testEncodedState,
+			errParam:     "login_error",
+			branding: &branding.Branding{
+				LoginTemplate: `<form action="{{.PostPath}}"><input name="state" value="{{.State}}">{{.IDPName}}: {{.AlertMessage}}</form>`,
+			},
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody: `<form action="/some/path/login"><input name="state" value="fake-encoded-state-value">` +
+				`some-ldap-idp: Incorrect username or password.</form>`,
+		},
+		{
+			name: "falls back to the default login page when the login template of the branding is broken",
+			decodedState: &oidc.UpstreamStateParamData{
+				UpstreamName: testUpstreamName,
+				UpstreamType: testUpstreamType,
+			},
+			encodedState:    testEncodedState,
+			branding:        &branding.Branding{LoginTemplate: `{{.NoSuchField}}`},
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBody:        testutil.ExpectedLoginPageHTML(loginhtml.CSS(), testUpstreamName, testPath, testEncodedState, ""),
+		},
 	}
 
 	for _, test := range tests {
@@ -97,20 +461,48 @@ func TestGetLogin(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			handler := NewGetHandler(testPath)
+			secondFactorStorage := secondfactor.NewStorage(fake.NewSimpleClientset().CoreV1().Secrets("some-namespace"), oidc.DefaultOIDCTimeoutsConfiguration())
+			if tt.secondFactorRequest != nil {
+				require.NoError(t, secondFactorStorage.Requests.CreateSecondFactorRequest(context.Background(), tt.secondFactorParam, tt.secondFactorRequest))
+			}
+			if tt.webAuthnEnrollment != nil {
+				require.NoError(t, secondFactorStorage.WebAuthnEnrollments.CreateEnrollment(context.Background(), tt.webAuthnEnrollment))
+			}
+
+			handler := NewGetHandler(testPath, testIssuer, secondFactorStorage, tt.branding)
 			target := testPath + "?state=" + tt.encodedState
 			if tt.errParam != "" {
 				target += "&err=" + tt.errParam
 			}
+			if tt.secondFactorParam != "" {
+				target += "&second_factor=" + tt.secondFactorParam
+			}
 			req := httptest.NewRequest(http.MethodGet, target, nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
 			rsp := httptest.NewRecorder()
 			err := handler(rsp, req, tt.encodedState, tt.decodedState)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
 			require.NoError(t, err)
 
 			require.Equal(t, tt.wantStatus, rsp.Code)
 			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), tt.wantContentType)
+			if tt.wantLocation != "" {
+				require.Equal(t, tt.wantLocation, rsp.Header().Get("Location"))
+				return
+			}
 			body := rsp.Body.String()
 			// t.Log("actual body:", body) // useful when updating expected values
+			if tt.wantBodyContains != nil {
+				for _, want := range tt.wantBodyContains {
+					require.Contains(t, body, want)
+				}
+				return
+			}
 			require.Equal(t, tt.wantBody, body)
 		})
 	}
@@ -1,4 +1,4 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
@@ -7,6 +7,7 @@ import (
 	"net/http"
 
 	idpdiscoveryv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
 	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
@@ -33,11 +34,13 @@ type HandlerFunc func(
 // Users should always initially get redirected to this page from the authorization endpoint, and never need
 // to navigate directly to this page in their browser without going through the authorization endpoint first.
 // Once their browser has landed on this page, it should be okay for the user to refresh the browser.
+// The pageBranding, which may be nil, must be the same branding which was given to the handler functions.
 func NewHandler(
 	stateDecoder oidc.Decoder,
 	cookieDecoder oidc.Decoder,
 	getHandler HandlerFunc, // use NewGetHandler() for production
 	postHandler HandlerFunc, // use NewPostHandler() for production
+	pageBranding *branding.Branding,
 ) http.Handler {
 	loginHandler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		var handler HandlerFunc
@@ -66,15 +69,17 @@ func NewHandler(
 		return handler(w, r, encodedState, decodedState)
 	})
 
-	return wrapSecurityHeaders(loginHandler)
+	return wrapSecurityHeaders(loginHandler, pageBranding)
 }
 
-func wrapSecurityHeaders(handler http.Handler) http.Handler {
+func wrapSecurityHeaders(handler http.Handler, pageBranding *branding.Branding) http.Handler {
+	loginCSPValue := loginhtml.ContentSecurityPolicy(pageBranding)
+	formPostCSPValue := formposthtml.ContentSecurityPolicy(pageBranding)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wrapped := securityheader.WrapWithCustomCSP(handler, loginhtml.ContentSecurityPolicy())
+		wrapped := securityheader.WrapWithCustomCSP(handler, loginCSPValue)
 		if r.Method == http.MethodPost {
 			// POST requests can result in the form_post html page, so allow it with CSP headers.
-			wrapped = securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy())
+			wrapped = securityheader.WrapWithCustomCSP(handler, formPostCSPValue)
 		}
 		wrapped.ServeHTTP(w, r)
 	})
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
@@ -412,7 +412,7 @@ func TestLoginEndpoint(t *testing.T) {
 				return tt.postHandlerErr
 			}
 
-			subject := NewHandler(happyStateCodec, happyCookieCodec, testGetHandler, testPostHandler)
+			subject := NewHandler(happyStateCodec, happyCookieCodec, testGetHandler, testPostHandler, nil)
 
 			subject.ServeHTTP(rsp, req)
 
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package loginhtml defines HTML templates used by the Supervisor.
@@ -6,12 +6,16 @@ package loginhtml
 
 import (
 	_ "embed" // Needed to trigger //go:embed directives below.
+	"fmt"
 	"html/template"
+	"io"
 	"strings"
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/locale"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -20,22 +24,50 @@ var (
 	rawCSS      string
 	minifiedCSS = panicOnError(minify.CSS(rawCSS))
 
+	//go:embed webauthn.js
+	rawJS      string
+	minifiedJS = panicOnError(minify.JS(rawJS))
+
 	//go:embed login_form.gohtml
 	rawHTMLTemplate string
 
-	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-	parsedHTMLTemplate = template.Must(template.New("login_form.gohtml").Funcs(template.FuncMap{
+	// The functions which are available to the templates, including to the login templates of brandings.
+	templateFuncs = template.FuncMap{
 		"minifiedCSS": func() template.CSS { return template.CSS(CSS()) },
-	}).Parse(rawHTMLTemplate))
+		"minifiedJS":  func() template.JS { return template.JS(minifiedJS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
+	}
+
+	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
+	parsedHTMLTemplate = template.Must(template.New("login_form.gohtml").Funcs(templateFuncs).Parse(rawHTMLTemplate))
+
+	//go:embed second_factor_form.gohtml
+	rawSecondFactorHTMLTemplate string
+
+	// The second factor page uses the same minified inline CSS as the login page, so the same CSP allows both pages.
+	parsedSecondFactorHTMLTemplate = template.Must(template.New("second_factor_form.gohtml").Funcs(templateFuncs).Parse(rawSecondFactorHTMLTemplate))
+
+	//go:embed webauthn_form.gohtml
+	rawWebAuthnHTMLTemplate string
+
+	// The security key page also uses the minified inline JS, which uses WebAuthn in the browser.
+	parsedWebAuthnHTMLTemplate = template.Must(template.New("webauthn_form.gohtml").Funcs(templateFuncs).Parse(rawWebAuthnHTMLTemplate))
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
-		`default-src 'none'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
-		`frame-ancestors 'none'`,
-	}, "; ")
+	cspValue = contentSecurityPolicy(nil)
 )
 
+func contentSecurityPolicy(b *branding.Branding) string {
+	directives := []string{
+		`default-src 'none'`,
+		`script-src '` + csp.Hash(minifiedJS) + `'`,
+		`style-src ` + strings.Join(append([]string{`'` + csp.Hash(minifiedCSS) + `'`}, b.StyleSources()...), " "),
+	}
+	if b.HasLogo() {
+		directives = append(directives, `img-src data:`)
+	}
+	return strings.Join(append(directives, `frame-ancestors 'none'`), "; ")
+}
+
 func panicOnError(s string, err error) string {
 	if err != nil {
 		panic(err)
@@ -43,14 +75,56 @@ func panicOnError(s string, err error) string {
 	return s
 }
 
-// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly
+// for the given branding, which may be nil.
 //
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
-func ContentSecurityPolicy() string { return cspValue }
+func ContentSecurityPolicy(b *branding.Branding) string {
+	if b == nil {
+		return cspValue
+	}
+	return contentSecurityPolicy(b)
+}
 
 // Template returns the html/template.Template for rendering the login page.
 func Template() *template.Template { return parsedHTMLTemplate }
 
+// TemplateWithBranding returns the html/template.Template for rendering the login page with the given branding,
+// which may be nil. When the branding has a login template, it replaces the default login page. Returns an error
+// when the login template of the branding cannot be parsed or cannot render the login page.
+func TemplateWithBranding(b *branding.Branding) (*template.Template, error) {
+	if b == nil || b.LoginTemplate == "" {
+		return parsedHTMLTemplate, nil
+	}
+
+	loginTemplate, err := template.New(branding.LoginTemplateKey).Funcs(templateFuncs).Parse(b.LoginTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", branding.LoginTemplateKey, err)
+	}
+
+	// Render the page once, so that templates which refer to unknown fields are rejected now instead of during logins.
+	if err := loginTemplate.Execute(io.Discard, &PageData{
+		State:         "state",
+		IDPName:       "idp-name",
+		HasAlertError: true,
+		AlertMessage:  "alert",
+		PostPath:      "/login",
+		Branding:      b,
+	}); err != nil {
+		return nil, fmt.Errorf("could not render %q: %w", branding.LoginTemplateKey, err)
+	}
+
+	return loginTemplate, nil
+}
+
+// SecondFactorTemplate returns the html/template.Template for rendering the page which asks for the user's
+// one-time password, after the user's password was accepted.
+func SecondFactorTemplate() *template.Template { return parsedSecondFactorHTMLTemplate }
+
+// WebAuthnTemplate returns the html/template.Template for rendering the page which asks for the user's
+// security key or passkey, after the user's password was accepted.
+func WebAuthnTemplate() *template.Template { return parsedWebAuthnHTMLTemplate }
+
 // CSS returns the minified CSS that will be embedded into the page template.
 func CSS() string { return minifiedCSS }
 
@@ -62,4 +136,68 @@ type PageData struct {
 	AlertMessage  string
 	MinifiedCSS   template.CSS
 	PostPath      string
+	Branding      *branding.Branding
+
+	// Locale translates the text of the page. When it is nil, the page is shown in English.
+	Locale *locale.Localizer
+}
+
+// SecondFactorPageData represents the inputs to the second factor template.
+type SecondFactorPageData struct {
+	State         string
+	IDPName       string
+	RequestID     string
+	HasAlertError bool
+	AlertMessage  string
+	PostPath      string
+
+	// EnrollmentSecret is the TOTP secret which the user should add to their authenticator app,
+	// when the user has not enrolled an authenticator app yet. Otherwise, it is empty.
+	EnrollmentSecret string
+
+	// EnrollmentURI is the otpauth:// URI of the EnrollmentSecret, which can be opened by authenticator apps.
+	// It is a template.URL because html/template would otherwise not allow the otpauth scheme in links.
+	EnrollmentURI template.URL
+
+	Branding *branding.Branding
+
+	// Locale translates the text of the page. When it is nil, the page is shown in English.
+	Locale *locale.Localizer
+}
+
+// WebAuthnPageData represents the inputs to the security key template.
+type WebAuthnPageData struct {
+	State         string
+	IDPName       string
+	RequestID     string
+	HasAlertError bool
+	AlertMessage  string
+	PostPath      string
+
+	// Register is true when the user has not registered a security key or passkey yet, so they are asked to
+	// register one. Otherwise, they are asked to use the one which they registered.
+	Register bool
+
+	// Challenge is the base64url encoded challenge which the security key must sign.
+	Challenge string
+
+	// RPID is the relying party ID, which is the host name of the FederationDomain's issuer.
+	RPID string
+
+	// UserID is the base64url encoded user handle which identifies the user to the security key during registration.
+	UserID string
+
+	// UserName is shown by the browser during registration, to tell the user which account they are registering for.
+	UserName string
+
+	// CredentialID is the base64url encoded ID of the credential which the user registered, when Register is false.
+	CredentialID string
+
+	// Algorithms are the comma separated COSE algorithms of the credential public keys which are supported.
+	Algorithms string
+
+	Branding *branding.Branding
+
+	// Locale translates the text of the page. When it is nil, the page is shown in English.
+	Locale *locale.Localizer
 }
@@ -1,15 +1,20 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package loginhtml
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
+	"html/template"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/csp"
 	"go.pinniped.dev/internal/testutil"
 )
 
@@ -19,6 +24,7 @@ var (
 	// It's okay if this changes in the future, but this gives us a chance to eyeball the formatting.
 	// Our browser-based integration tests should find any incompatibilities.
 	testExpectedCSP = `default-src 'none'; ` +
+		`script-src 'sha256-2KfcLpPY+ph2rvgOwDD4UK280XeLuxFfj/qsURzC248='; ` +
 		`style-src 'sha256-QC9ckaUFAdcN0Ysmu8q8iqCazYFgrJSQDJPa/przPXU='; ` +
 		`frame-ancestors 'none'`
 )
@@ -54,8 +60,189 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, expectedHTMLWithoutAlert, buf.String())
 }
 
+func TestSecondFactorTemplate(t *testing.T) {
+	const (
+		testUpstreamName = "test-idp-name"
+		testPath         = "test-post-path"
+		testEncodedState = "test-encoded-state"
+		testRequestID    = "test-request-id"
+		testSecret       = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+		testAlert        = "test-alert-message"
+	)
+
+	var buf bytes.Buffer
+	pageInputs := &SecondFactorPageData{
+		PostPath:         testPath,
+		State:            testEncodedState,
+		IDPName:          testUpstreamName,
+		RequestID:        testRequestID,
+		HasAlertError:    true,
+		AlertMessage:     testAlert,
+		EnrollmentSecret: testSecret,
+		EnrollmentURI:    template.URL("otpauth://totp/Pinniped:ryan?issuer=Pinniped&secret=" + testSecret),
+	}
+
+	// Render with an alert and an enrollment.
+	expectedHTML := testutil.ExpectedSecondFactorPageHTML(testExpectedCSS, testUpstreamName, testPath, testEncodedState, testRequestID,
+		testSecret, "otpauth://totp/Pinniped:ryan?issuer=Pinniped&amp;secret="+testSecret, testAlert)
+	require.NoError(t, SecondFactorTemplate().Execute(&buf, pageInputs))
+	require.Equal(t, expectedHTML, buf.String())
+
+	// Render again without an alert, for a user who has already enrolled.
+	pageInputs.HasAlertError = false
+	pageInputs.EnrollmentSecret = ""
+	pageInputs.EnrollmentURI = ""
+	expectedHTML = testutil.ExpectedSecondFactorPageHTML(testExpectedCSS, testUpstreamName, testPath, testEncodedState, testRequestID, "", "", "")
+	buf = bytes.Buffer{} // clear previous result from buffer
+	require.NoError(t, SecondFactorTemplate().Execute(&buf, pageInputs))
+	require.Equal(t, expectedHTML, buf.String())
+}
+
+func TestWebAuthnTemplate(t *testing.T) {
+	pageInputs := &WebAuthnPageData{
+		PostPath:      "test-post-path",
+		State:         "test-encoded-state",
+		IDPName:       "test-idp-name",
+		RequestID:     "test-request-id",
+		HasAlertError: true,
+		AlertMessage:  "test-alert-message",
+		Register:      true,
+		Challenge:     "test-challenge",
+		RPID:          "issuer.example.com",
+		UserID:        "test-user-id",
+		UserName:      "test-user-name",
+		Algorithms:    "-7,-8,-257",
+	}
+
+	// Render the registration of a new security key, with an alert.
+	var buf bytes.Buffer
+	require.NoError(t, WebAuthnTemplate().Execute(&buf, pageInputs))
+	html := buf.String()
+	// The inline CSS and JS must be allowed by the CSP, otherwise browsers would ignore them.
+	require.Contains(t, html, "<style>"+testExpectedCSS+"</style>")
+	require.Contains(t, html, "<script>"+minifiedJS+"</script>")
+	require.Contains(t, testExpectedCSP, csp.Hash(minifiedJS))
+	require.Contains(t, html, `<h1>Log in to test-idp-name</h1>`)
+	require.Contains(t, html, `id="alert">test-alert-message</span>`)
+	require.Contains(t, html, `<form action="test-post-path" method="post" id="webauthn-form"
+          data-register="true" data-challenge="test-challenge" data-rp-id="issuer.example.com" data-user-id="test-user-id"
+          data-user-name="test-user-name" data-credential-id="" data-algorithms="-7,-8,-257">`)
+	require.Contains(t, html, `<input type="hidden" name="state" id="state" value="test-encoded-state">`)
+	require.Contains(t, html, `<input type="hidden" name="second_factor" id="second_factor" value="test-request-id">`)
+	require.Contains(t, html, `<input type="hidden" name="webauthn_attestation_object" id="webauthn_attestation_object">`)
+	require.NotContains(t, html, `name="webauthn_signature"`)
+	require.Contains(t, html, `value="Register security key"`)
+
+	// Render again without an alert, for a user who has already registered a security key.
+	pageInputs.HasAlertError = false
+	pageInputs.Register = false
+	pageInputs.CredentialID = "test-credential-id"
+	buf = bytes.Buffer{} // clear previous result from buffer
+	require.NoError(t, WebAuthnTemplate().Execute(&buf, pageInputs))
+	html = buf.String()
+	require.NotContains(t, html, `id="alert"`)
+	require.Contains(t, html, `data-register="false"`)
+	require.Contains(t, html, `data-credential-id="test-credential-id"`)
+	require.Contains(t, html, `<input type="hidden" name="webauthn_credential_id" id="webauthn_credential_id">`)
+	require.Contains(t, html, `<input type="hidden" name="webauthn_authenticator_data" id="webauthn_authenticator_data">`)
+	require.Contains(t, html, `<input type="hidden" name="webauthn_signature" id="webauthn_signature">`)
+	require.NotContains(t, html, `name="webauthn_attestation_object"`)
+	require.Contains(t, html, `value="Use security key"`)
+}
+
+func TestTemplateWithBrandedPageData(t *testing.T) {
+	pngLogo := []byte("\x89PNG\r\n\x1a\n" + "some-png-data")
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{
+		"title":     "Acme Corp",
+		"logo":      base64.StdEncoding.EncodeToString(pngLogo),
+		"helpLinks": `[{"text":"Forgot password?","url":"https://example.com/reset?a=1&b=2"}]`,
+		"css":       "h1{color:red}",
+	}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, Template().Execute(&buf, &PageData{
+		PostPath: "test-post-path",
+		State:    "test-encoded-state",
+		IDPName:  "test-idp-name",
+		Branding: b,
+	}))
+
+	require.Contains(t, buf.String(), "<title>Acme Corp Login</title>")
+	require.Contains(t, buf.String(), "<style>"+string(b.CSS())+"</style>")
+	require.Contains(t, buf.String(),
+		`<img class="branding-logo" src="data:image/png;base64,`+base64.StdEncoding.EncodeToString(pngLogo)+`" alt="Logo">`)
+	require.Contains(t, buf.String(), `<span class="branding-title">Acme Corp</span>`)
+	require.Contains(t, buf.String(), `<li><a href="https://example.com/reset?a=1&amp;b=2">Forgot password?</a></li>`)
+
+	// The branded CSS must be allowed by the CSP, otherwise browsers would ignore it.
+	require.Equal(t, `default-src 'none'; `+
+		`script-src 'sha256-2KfcLpPY+ph2rvgOwDD4UK280XeLuxFfj/qsURzC248='; `+
+		`style-src 'sha256-QC9ckaUFAdcN0Ysmu8q8iqCazYFgrJSQDJPa/przPXU=' '`+csp.Hash(string(b.CSS()))+`'; `+
+		`img-src data:; `+
+		`frame-ancestors 'none'`,
+		ContentSecurityPolicy(b))
+}
+
+func TestTemplateWithBranding(t *testing.T) {
+	tests := []struct {
+		name          string
+		loginTemplate string
+		wantHTML      string
+		wantErr       string
+	}{
+		{
+			name:     "without a login template",
+			wantHTML: testutil.ExpectedLoginPageHTML(testExpectedCSS, "test-idp-name", "test-post-path", "test-encoded-state", ""),
+		},
+		{
+			name:          "with a login template",
+			loginTemplate: `<style>{{minifiedCSS}}</style><form action="{{.PostPath}}"><h1>{{.IDPName}}</h1></form>`,
+			wantHTML:      `<style>` + testExpectedCSS + `</style><form action="test-post-path"><h1>test-idp-name</h1></form>`,
+		},
+		{
+			name:          "with a login template which does not parse",
+			loginTemplate: `<h1>{{.IDPName}</h1>`,
+			wantErr:       `could not parse "loginTemplate": template: loginTemplate:1: bad character U+007D '}'`,
+		},
+		{
+			name:          "with a login template which does not render",
+			loginTemplate: `<h1>{{.Username}}</h1>`,
+			wantErr: `could not render "loginTemplate": template: loginTemplate:1:6: executing "loginTemplate" at <.Username>: ` +
+				`can't evaluate field Username in type *loginhtml.PageData`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{"loginTemplate": tt.loginTemplate}})
+			require.NoError(t, err)
+
+			tmpl, err := TemplateWithBranding(b)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, tmpl)
+				return
+			}
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			require.NoError(t, tmpl.Execute(&buf, &PageData{
+				PostPath: "test-post-path",
+				State:    "test-encoded-state",
+				IDPName:  "test-idp-name",
+			}))
+			require.Equal(t, tt.wantHTML, buf.String())
+		})
+	}
+
+	tmpl, err := TemplateWithBranding(nil)
+	require.NoError(t, err)
+	require.Same(t, Template(), tmpl)
+}
+
 func TestContentSecurityPolicy(t *testing.T) {
-	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
 }
 
 func TestCSS(t *testing.T) {
@@ -0,0 +1,62 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+window.onload = () => {
+    const form = document.getElementById('webauthn-form');
+    const options = form.dataset;
+
+    // WebAuthn uses ArrayBuffers, which are sent to and from the server as base64url without padding.
+    const fromBase64URL = (s) => Uint8Array.from(atob(s.replace(/-/g, '+').replace(/_/g, '/')), c => c.charCodeAt(0));
+    const toBase64URL = (buffer) => btoa(String.fromCharCode(...new Uint8Array(buffer)))
+        .replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+
+    // Browsers only allow security keys to be used after the user clicked something, so wait for the button.
+    form.onsubmit = (event) => {
+        event.preventDefault();
+        document.getElementById('webauthn-error').hidden = true;
+
+        const challenge = fromBase64URL(options.challenge);
+        let credential;
+        if (options.register === 'true') {
+            credential = navigator.credentials.create({
+                publicKey: {
+                    rp: {id: options.rpId, name: options.rpId},
+                    user: {id: fromBase64URL(options.userId), name: options.userName, displayName: options.userName},
+                    challenge: challenge,
+                    pubKeyCredParams: options.algorithms.split(',').map(alg => ({type: 'public-key', alg: Number(alg)})),
+                    authenticatorSelection: {userVerification: 'preferred'},
+                    attestation: 'none',
+                },
+            });
+        } else {
+            credential = navigator.credentials.get({
+                publicKey: {
+                    rpId: options.rpId,
+                    challenge: challenge,
+                    allowCredentials: [{type: 'public-key', id: fromBase64URL(options.credentialId)}],
+                    userVerification: 'preferred',
+                },
+            });
+        }
+
+        credential
+            .then(c => {
+                const fields = form.elements;
+                fields['webauthn_client_data'].value = toBase64URL(c.response.clientDataJSON);
+                if (options.register === 'true') {
+                    fields['webauthn_attestation_object'].value = toBase64URL(c.response.attestationObject);
+                } else {
+                    fields['webauthn_credential_id'].value = toBase64URL(c.rawId);
+                    fields['webauthn_authenticator_data'].value = toBase64URL(c.response.authenticatorData);
+                    fields['webauthn_signature'].value = toBase64URL(c.response.signature);
+                }
+                // Submitting the form this way does not call onsubmit again.
+                form.submit();
+            })
+            .catch(e => {
+                // The user cancelled, the request timed out, or the browser does not support security keys.
+                console.error('could not use security key: ' + e);
+                document.getElementById('webauthn-error').hidden = false;
+            });
+    };
+};
@@ -1,4 +1,4 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
@@ -11,15 +11,24 @@ import (
 	"github.com/ory/fosite"
 
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
 	"go.pinniped.dev/internal/httputil/httperr"
 	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
 )
 
-func NewPostHandler(issuerURL string, upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersFinderI, oauthHelper fosite.OAuth2Provider) HandlerFunc {
+func NewPostHandler(
+	issuerURL string,
+	upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersFinderI,
+	oauthHelper fosite.OAuth2Provider,
+	consentRequester *consent.Requester,
+	secondFactorStorage *secondfactor.Storage,
+) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, encodedState string, decodedState *oidc.UpstreamStateParamData) error {
 		// Note that the login handler prevents this handler from being called with OIDC upstreams.
 		idp, err := upstreamIDPs.FindUpstreamIDPByDisplayName(decodedState.UpstreamName)
@@ -54,6 +63,12 @@ func NewPostHandler(issuerURL string, upstreamIDPs federationdomainproviders.Fed
 		// an error if the client requested a scope that they are not allowed to request, so we don't need to worry about that here.
 		downstreamsession.AutoApproveScopes(authorizeRequester)
 
+		// The user's password was already accepted, and now the user is presenting their one-time password.
+		if secondFactorRequestID := r.PostFormValue(loginurl.SecondFactorParamName); secondFactorRequestID != "" {
+			return handleSecondFactor(w, r, issuerURL, encodedState, decodedState,
+				secondFactorRequestID, oauthHelper, authorizeRequester, consentRequester, secondFactorStorage)
+		}
+
 		// Get the username and password form params from the POST body.
 		submittedUsername := r.PostFormValue(loginurl.UsernameParamName)
 		submittedPassword := r.PostFormValue(loginurl.PasswordParamName)
@@ -77,6 +92,14 @@ func NewPostHandler(issuerURL string, upstreamIDPs federationdomainproviders.Fed
 				// The upstream did not accept the username/password combination.
 				// The user may try to log in again if they'd like, so redirect back to the login page with an error.
 				return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowBadUserPassErr)
+			case err == resolvedldap.ErrAccessDeniedDueToPasswordExpired:
+				// The password policy of the upstream did not allow the login, so tell the user why.
+				// The user may try to log in again after they changed their password.
+				return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowPasswordExpiredErr)
+			case err == resolvedldap.ErrAccessDeniedDueToPasswordMustChange:
+				return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowPasswordMustChangeErr)
+			case err == resolvedldap.ErrAccessDeniedDueToAccountLocked:
+				return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowAccountLockedErr)
 			default:
 				// Some other error happened.
 				oidc.WriteAuthorizeError(r, w, oauthHelper, authorizeRequester, err, false)
@@ -96,10 +119,122 @@ func NewPostHandler(issuerURL string, upstreamIDPs federationdomainproviders.Fed
 			return nil
 		}
 
-		oidc.PerformAuthcodeRedirect(r, w, oauthHelper, authorizeRequester, session, false)
+		if loginExtras != nil && (loginExtras.RequireTOTP || loginExtras.RequireWebAuthn) {
+			// The FederationDomain requires a second factor for this identity provider, so ask the user for it
+			// before finishing the login.
+			return requestSecondFactor(r, w, issuerURL, encodedState, decodedState, session, secondFactorStorage,
+				loginExtras.RequireWebAuthn)
+		}
+
+		return finishLogin(w, r, oauthHelper, authorizeRequester, session, consentRequester, decodedState)
+	}
+}
+
+// handleSecondFactor checks the one-time password or the security key response which the user submitted for the
+// second factor request. When the user has not enrolled an authenticator app or a security key yet, then a correct
+// one-time password or an accepted security key response also enrolls it.
+func handleSecondFactor(
+	w http.ResponseWriter,
+	r *http.Request,
+	issuerURL string,
+	encodedState string,
+	decodedState *oidc.UpstreamStateParamData,
+	secondFactorRequestID string,
+	oauthHelper fosite.OAuth2Provider,
+	authorizeRequester fosite.AuthorizeRequester,
+	consentRequester *consent.Requester,
+	secondFactorStorage *secondfactor.Storage,
+) error {
+	secondFactorRequest, err := readSecondFactorRequest(r, secondFactorStorage, secondFactorRequestID, decodedState)
+	if err != nil {
+		return err
+	}
+
+	subject := secondFactorRequest.Session.Fosite.Claims.Subject
+	usesSecurityKey := secondFactorRequest.WebAuthnChallenge != ""
+	badSecondFactorErr, tooManyAttemptsErr := loginurl.ShowBadOTPErr, loginurl.ShowTooManyOTPAttemptsErr
+	switch {
+	case usesSecurityKey:
+		badSecondFactorErr, tooManyAttemptsErr = loginurl.ShowBadSecurityKeyErr, loginurl.ShowTooManySecurityKeyAttemptsErr
+		err = checkSecurityKey(r, issuerURL, secondFactorStorage, secondFactorRequest)
+	case secondFactorRequest.PendingTOTPSecret != "":
+		err = secondFactorStorage.EnrollTOTP(r.Context(), subject, secondFactorRequest.PendingTOTPSecret,
+			r.PostFormValue(loginurl.TOTPCodeParamName))
+	default:
+		err = secondFactorStorage.VerifyTOTP(r.Context(), subject, r.PostFormValue(loginurl.TOTPCodeParamName))
+	}
+
+	switch {
+	case errors.Is(err, secondfactor.ErrIncorrectPassword), errors.Is(err, secondfactor.ErrSecurityKeyNotAccepted):
+		if usesSecurityKey {
+			plog.Info("security key response not accepted", "subject", subject, "reason", err.Error())
+		}
+		failedAttempts, err := secondFactorStorage.Requests.RecordFailedAttempt(r.Context(), secondFactorRequestID)
+		if err != nil {
+			plog.Error("error updating second factor request", err)
+			return httperr.New(http.StatusInternalServerError, "error updating second factor request")
+		}
+		if failedAttempts >= secondfactor.MaxFailedAttempts {
+			// Too many guesses, so the user must start over by entering their password again.
+			if err := secondFactorStorage.Requests.DeleteSecondFactorRequest(r.Context(), secondFactorRequestID); err != nil {
+				plog.Error("error deleting second factor request", err)
+				return httperr.New(http.StatusInternalServerError, "error deleting second factor request")
+			}
+			return redirectToLoginPage(r, w, issuerURL, encodedState, tooManyAttemptsErr)
+		}
+		// The user may try again, so redirect back to the second factor page with an error.
+		return redirectToSecondFactorPage(r, w, issuerURL, encodedState, secondFactorRequestID, badSecondFactorErr)
+	case errors.Is(err, secondfactor.ErrTooManyAttempts):
+		// The user is locked out after too many incorrect one-time passwords, so they must start over later.
+		if err := secondFactorStorage.Requests.DeleteSecondFactorRequest(r.Context(), secondFactorRequestID); err != nil {
+			plog.Error("error deleting second factor request", err)
+			return httperr.New(http.StatusInternalServerError, "error deleting second factor request")
+		}
+		return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowOTPLockedOutErr)
+	case errors.Is(err, secondfactor.ErrNotEnrolled):
+		// The user's enrollment was deleted after they entered their password, so they must start over.
+		plog.Info("user's second factor enrollment was deleted during login", "subject", subject)
+		if err := secondFactorStorage.Requests.DeleteSecondFactorRequest(r.Context(), secondFactorRequestID); err != nil {
+			plog.Error("error deleting second factor request", err)
+			return httperr.New(http.StatusInternalServerError, "error deleting second factor request")
+		}
+		return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowInternalError)
+	case err != nil:
+		plog.Error("error checking second factor", err)
+		return httperr.New(http.StatusInternalServerError, "error checking second factor")
+	}
+
+	// Each second factor request may only be used once.
+	if err := secondFactorStorage.Requests.DeleteSecondFactorRequest(r.Context(), secondFactorRequestID); err != nil {
+		plog.Error("error deleting second factor request", err)
+		return httperr.New(http.StatusInternalServerError, "error deleting second factor request")
+	}
+
+	return finishLogin(w, r, oauthHelper, authorizeRequester, secondFactorRequest.Session, consentRequester, decodedState)
+}
 
+// finishLogin redirects to the consent page when the client requires consent, or otherwise issues the authcode.
+func finishLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	oauthHelper fosite.OAuth2Provider,
+	authorizeRequester fosite.AuthorizeRequester,
+	session *psession.PinnipedSession,
+	consentRequester *consent.Requester,
+	decodedState *oidc.UpstreamStateParamData,
+) error {
+	redirectedToConsent, err := consentRequester.RedirectIfRequired(w, r, authorizeRequester, session, decodedState.CSRFToken)
+	if err != nil {
+		plog.Error("error while requesting consent", err)
+		return httperr.Wrap(http.StatusInternalServerError, "error while requesting consent", err)
+	}
+	if redirectedToConsent {
 		return nil
 	}
+
+	oidc.PerformAuthcodeRedirect(r, w, oauthHelper, authorizeRequester, session, false)
+
+	return nil
 }
 
 // redirectToLoginPage redirects to the GET /login page of the specified issuer.
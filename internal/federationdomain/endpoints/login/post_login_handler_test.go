@@ -1,19 +1,24 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/client-go/kubernetes/fake"
 
@@ -21,15 +26,20 @@ import (
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	"go.pinniped.dev/internal/authenticators"
 	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
 	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/fositestorage/totpenrollment"
 	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/internal/testutil"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
 	"go.pinniped.dev/internal/testutil/testidplister"
 	"go.pinniped.dev/internal/testutil/transformtestutil"
+	"go.pinniped.dev/internal/testutil/webauthntestutil"
 )
 
 func TestPostLoginEndpoint(t *testing.T) {
@@ -60,10 +70,13 @@ func TestPostLoginEndpoint(t *testing.T) {
 		activeDirectoryUpstreamResourceUID = "active-directory-resource-uid"
 		upstreamLDAPURL                    = "ldaps://some-ldap-host:123?base=ou%3Dusers%2Cdc%3Dpinniped%2Cdc%3Ddev"
 
-		userParam                = "username"
-		passParam                = "password"
-		badUserPassErrParamValue = "login_error"
-		internalErrParamValue    = "internal_error"
+		userParam                       = "username"
+		passParam                       = "password"
+		badUserPassErrParamValue        = "login_error"
+		internalErrParamValue           = "internal_error"
+		passwordExpiredErrParamValue    = "password_expired"
+		passwordMustChangeErrParamValue = "password_must_change"
+		accountLockedErrParamValue      = "account_locked"
 
 		transformationUsernamePrefix = "username_prefix:"
 		transformationGroupsPrefix   = "groups_prefix:"
@@ -208,6 +221,16 @@ func TestPostLoginEndpoint(t *testing.T) {
 		}).
 		Build()
 
+	passwordPolicyUpstreamLDAPIdentityProvider := func(passwordPolicyErr error) *oidctestutil.TestUpstreamLDAPIdentityProvider {
+		return oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().
+			WithName(ldapUpstreamName).
+			WithResourceUID(ldapUpstreamResourceUID).
+			WithAuthenticateFunc(func(ctx context.Context, username, password string) (*authenticators.Response, bool, error) {
+				return nil, false, fmt.Errorf("some ldap upstream bind error: %w", passwordPolicyErr)
+			}).
+			Build()
+	}
+
 	expectedHappyActiveDirectoryUpstreamCustomSession := &psession.CustomSessionData{
 		Username:         happyLDAPUsernameFromAuthenticator,
 		ProviderUID:      activeDirectoryUpstreamResourceUID,
@@ -215,6 +238,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 		ProviderType:     psession.ProviderTypeActiveDirectory,
 		UpstreamUsername: happyLDAPUsernameFromAuthenticator,
 		UpstreamGroups:   happyLDAPGroups,
+		DownstreamGroups: happyLDAPGroups,
 		OIDC:             nil,
 		LDAP:             nil,
 		ActiveDirectory: &psession.ActiveDirectorySessionData{
@@ -230,6 +254,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 		ProviderType:     psession.ProviderTypeLDAP,
 		UpstreamUsername: happyLDAPUsernameFromAuthenticator,
 		UpstreamGroups:   happyLDAPGroups,
+		DownstreamGroups: happyLDAPGroups,
 		OIDC:             nil,
 		LDAP: &psession.LDAPSessionData{
 			UserDN:                 happyLDAPUserDN,
@@ -238,7 +263,25 @@ func TestPostLoginEndpoint(t *testing.T) {
 		ActiveDirectory: nil,
 	}
 
-	withUsernameAndGroupsInCustomSession := func(expectedCustomSessionData *psession.CustomSessionData, wantDownstreamUsername string, wantUpstreamUsername string, wantUpstreamGroups []string) *psession.CustomSessionData {
+	passwordExpiringSoonWarning := "Your password will expire in 3 days. Please change your password."
+
+	upstreamLDAPIdentityProviderWithPasswordPolicyWarning := oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().
+		WithName(ldapUpstreamName).
+		WithResourceUID(ldapUpstreamResourceUID).
+		WithURL(parsedUpstreamLDAPURL).
+		WithAuthenticateFunc(func(ctx context.Context, username, password string) (*authenticators.Response, bool, error) {
+			response, authenticated, err := ldapAuthenticateFunc(ctx, username, password)
+			if response != nil {
+				response.Warnings = []string{passwordExpiringSoonWarning}
+			}
+			return response, authenticated, err
+		}).
+		Build()
+
+	expectedHappyLDAPUpstreamCustomSessionWithWarning := *expectedHappyLDAPUpstreamCustomSession
+	expectedHappyLDAPUpstreamCustomSessionWithWarning.Warnings = []string{passwordExpiringSoonWarning}
+
+	withUsernameAndGroupsInCustomSession := func(expectedCustomSessionData *psession.CustomSessionData, wantDownstreamUsername string, wantUpstreamUsername string, wantUpstreamGroups []string, wantDownstreamGroups []string) *psession.CustomSessionData {
 		copyOfCustomSession := *expectedCustomSessionData
 		if expectedCustomSessionData.LDAP != nil {
 			copyOfLDAP := *(expectedCustomSessionData.LDAP)
@@ -255,6 +298,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 		copyOfCustomSession.Username = wantDownstreamUsername
 		copyOfCustomSession.UpstreamUsername = wantUpstreamUsername
 		copyOfCustomSession.UpstreamGroups = wantUpstreamGroups
+		copyOfCustomSession.DownstreamGroups = wantDownstreamGroups
 		return &copyOfCustomSession
 	}
 
@@ -286,6 +330,15 @@ func TestPostLoginEndpoint(t *testing.T) {
 		require.NoError(t, kubeClient.Tracker().Add(secret))
 	}
 
+	addDynamicClientWhichRequiresConsentAndSecretToKubeResources := func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+			"some-namespace", downstreamDynamicClientID, downstreamDynamicClientUID, downstreamRedirectURI,
+			[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+		oidcClient.Spec.Consent = &configv1alpha1.OIDCClientConsent{Required: true}
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+	}
+
 	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
 	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
 
@@ -297,6 +350,8 @@ func TestPostLoginEndpoint(t *testing.T) {
 		formParams    url.Values
 		reqURIQuery   url.Values
 
+		acceptLanguage string
+
 		wantStatus      int
 		wantContentType string
 		wantBodyString  string
@@ -305,6 +360,9 @@ func TestPostLoginEndpoint(t *testing.T) {
 		// Assertion that the response should be a redirect to the login page with an error param.
 		wantRedirectToLoginPageError string
 
+		// Assertion that the response should be a redirect to the consent page.
+		wantRedirectToConsentPage bool
+
 		// Assertions for when an authcode should be returned, i.e. the request was authenticated by an
 		// upstream LDAP or AD provider.
 		wantRedirectLocationRegexp        string // for loose matching
@@ -352,6 +410,27 @@ func TestPostLoginEndpoint(t *testing.T) {
 			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
 			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
 		},
+		{
+			name:                              "happy LDAP login with a password policy warning",
+			idps:                              testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProviderWithPasswordPolicyWarning),
+			decodedState:                      happyLDAPDecodedState,
+			formParams:                        happyUsernamePasswordFormParams,
+			wantStatus:                        http.StatusSeeOther,
+			wantContentType:                   htmlContentType,
+			wantBodyString:                    "",
+			wantRedirectLocationRegexp:        happyAuthcodeDownstreamRedirectLocationRegexp,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamClient:              downstreamPinnipedCLIClientID,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData:   &expectedHappyLDAPUpstreamCustomSessionWithWarning,
+		},
 		{
 			name: "happy LDAP login with identity transformations which modify the username and group names",
 			idps: testidplister.NewUpstreamIDPListerBuilder().
@@ -378,6 +457,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 				transformationUsernamePrefix+happyLDAPUsernameFromAuthenticator,
 				happyLDAPUsernameFromAuthenticator,
 				happyLDAPGroups,
+				testutil.AddPrefixToEach(transformationGroupsPrefix, happyLDAPGroups),
 			),
 		},
 		{
@@ -404,6 +484,18 @@ func TestPostLoginEndpoint(t *testing.T) {
 			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
 			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
 		},
+		{
+			name: "happy LDAP login with dynamic client which requires consent redirects to the consent page",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithLDAP(upstreamLDAPIdentityProvider). // should pick this one
+				WithActiveDirectory(erroringUpstreamLDAPIdentityProvider),
+			kubeResources:             addDynamicClientWhichRequiresConsentAndSecretToKubeResources,
+			decodedState:              happyLDAPDecodedStateForDynamicClient,
+			formParams:                happyUsernamePasswordFormParams,
+			wantStatus:                http.StatusSeeOther,
+			wantContentType:           htmlContentType,
+			wantRedirectToConsentPage: true,
+		},
 		{
 			name: "happy AD login",
 			idps: testidplister.NewUpstreamIDPListerBuilder().
@@ -453,6 +545,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 				transformationUsernamePrefix+happyLDAPUsernameFromAuthenticator,
 				happyLDAPUsernameFromAuthenticator,
 				happyLDAPGroups,
+				testutil.AddPrefixToEach(transformationGroupsPrefix, happyLDAPGroups),
 			),
 		},
 		{
@@ -504,6 +597,32 @@ func TestPostLoginEndpoint(t *testing.T) {
 			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
 			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
 		},
+		{
+			name: "happy LDAP login when downstream response_mode=form_post returns the HTML+JS form in the language of the Accept-Language header",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProvider),
+			decodedState: modifyHappyLDAPDecodedState(func(data *oidc.UpstreamStateParamData) {
+				data.AuthParams = shallowCopyAndModifyQuery(happyDownstreamRequestParamsQuery,
+					map[string]string{"response_mode": "form_post"},
+				).Encode()
+			}),
+			formParams:      happyUsernamePasswordFormParams,
+			acceptLanguage:  "de-CH",
+			wantStatus:      http.StatusOK,
+			wantContentType: htmlContentType,
+			wantBodyFormResponseRegexp: `(?s)<html lang="de">.*<script>.*Um die Anmeldung abzuschließen, fügen Sie diesen Autorisierungscode` +
+				`.*<form>.*<h1>Anmeldung erfolgreich</h1>.*<code id="manual-auth-code">(.+)</code>.*</html>`,
+			wantDownstreamIDTokenSubject:      upstreamLDAPURL + "&idpName=" + ldapUpstreamName + "&sub=" + happyLDAPUID,
+			wantDownstreamIDTokenUsername:     happyLDAPUsernameFromAuthenticator,
+			wantDownstreamIDTokenGroups:       happyLDAPGroups,
+			wantDownstreamRequestedScopes:     happyDownstreamScopesRequested,
+			wantDownstreamRedirectURI:         downstreamRedirectURI,
+			wantDownstreamGrantedScopes:       happyDownstreamScopesGranted,
+			wantDownstreamNonce:               downstreamNonce,
+			wantDownstreamClient:              downstreamPinnipedCLIClientID,
+			wantDownstreamPKCEChallenge:       downstreamPKCEChallenge,
+			wantDownstreamPKCEChallengeMethod: downstreamPKCEChallengeMethod,
+			wantDownstreamCustomSessionData:   expectedHappyLDAPUpstreamCustomSession,
+		},
 		{
 			name: "happy LDAP login when downstream redirect uri matches what is configured for client except for the port number",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProvider),
@@ -831,6 +950,36 @@ func TestPostLoginEndpoint(t *testing.T) {
 			wantBodyString:               "",
 			wantRedirectToLoginPageError: internalErrParamValue,
 		},
+		{
+			name:                         "upstream LDAP authentication fails because the password expired",
+			idps:                         testidplister.NewUpstreamIDPListerBuilder().WithLDAP(passwordPolicyUpstreamLDAPIdentityProvider(authenticators.ErrPasswordExpired)),
+			decodedState:                 happyLDAPDecodedState,
+			formParams:                   happyUsernamePasswordFormParams,
+			wantStatus:                   http.StatusSeeOther,
+			wantContentType:              htmlContentType,
+			wantBodyString:               "",
+			wantRedirectToLoginPageError: passwordExpiredErrParamValue,
+		},
+		{
+			name:                         "upstream LDAP authentication fails because the password must be changed",
+			idps:                         testidplister.NewUpstreamIDPListerBuilder().WithLDAP(passwordPolicyUpstreamLDAPIdentityProvider(authenticators.ErrPasswordMustChange)),
+			decodedState:                 happyLDAPDecodedState,
+			formParams:                   happyUsernamePasswordFormParams,
+			wantStatus:                   http.StatusSeeOther,
+			wantContentType:              htmlContentType,
+			wantBodyString:               "",
+			wantRedirectToLoginPageError: passwordMustChangeErrParamValue,
+		},
+		{
+			name:                         "upstream LDAP authentication fails because the account is locked",
+			idps:                         testidplister.NewUpstreamIDPListerBuilder().WithLDAP(passwordPolicyUpstreamLDAPIdentityProvider(authenticators.ErrAccountLocked)),
+			decodedState:                 happyLDAPDecodedState,
+			formParams:                   happyUsernamePasswordFormParams,
+			wantStatus:                   http.StatusSeeOther,
+			wantContentType:              htmlContentType,
+			wantBodyString:               "",
+			wantRedirectToLoginPageError: accountLockedErrParamValue,
+		},
 		{
 			name: "downstream redirect uri does not match what is configured for client",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProvider),
@@ -1136,17 +1285,22 @@ func TestPostLoginEndpoint(t *testing.T) {
 			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
 			require.GreaterOrEqual(t, len(hmacSecretFunc()), 32, "fosite requires that hmac secrets have at least 32 bytes")
 			jwksProviderIsUnused := jwks.NewDynamicJWKSProvider()
-			oauthHelper := oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration)
+			oauthHelper := oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration, nil, nil)
 
 			req := httptest.NewRequest(http.MethodPost, "/ignored", strings.NewReader(tt.formParams.Encode()))
 			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
 			if tt.reqURIQuery != nil {
 				req.URL.RawQuery = tt.reqURIQuery.Encode()
 			}
 
 			rsp := httptest.NewRecorder()
 
-			subject := NewPostHandler(downstreamIssuer, tt.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper)
+			consentRequester := consent.NewRequester(downstreamIssuer, consent.NewStorage(secretsClient, timeoutsConfiguration))
+			secondFactorStorage := secondfactor.NewStorage(secretsClient, timeoutsConfiguration)
+			subject := NewPostHandler(downstreamIssuer, tt.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, consentRequester, secondFactorStorage)
 
 			err := subject(rsp, req, happyEncodedUpstreamState, tt.decodedState)
 			if tt.wantErr != "" {
@@ -1187,6 +1341,14 @@ func TestPostLoginEndpoint(t *testing.T) {
 					tt.wantDownstreamCustomSessionData,
 					map[string]interface{}{},
 				)
+			case tt.wantRedirectToConsentPage:
+				// Expecting a redirect to the consent page, which stores the consent request instead of an authcode.
+				require.Regexp(t, "^"+regexp.QuoteMeta(downstreamIssuer+oidc.ConsentEndpointPath)+`\?consent_request=[0-9a-f]{64}$`, actualLocation)
+				consentRequestSecrets, err := kubeClient.CoreV1().Secrets("some-namespace").List(context.Background(), metav1.ListOptions{
+					LabelSelector: "storage.pinniped.dev/type=consent-request",
+				})
+				require.NoError(t, err)
+				require.Len(t, consentRequestSecrets.Items, 1)
 			case tt.wantRedirectToLoginPageError != "":
 				// Expecting an error redirect to the login UI page.
 				require.Equal(t, tt.wantBodyString, rsp.Body.String())
@@ -1231,6 +1393,363 @@ func TestPostLoginEndpoint(t *testing.T) {
 	}
 }
 
+func TestPostLoginEndpointWithSecondFactor(t *testing.T) {
+	const (
+		downstreamIssuer      = "https://my-downstream-issuer.com/path"
+		downstreamRedirectURI = "http://127.0.0.1/callback"
+		downstreamState       = "8b-state"
+		encodedUpstreamState  = "fake-encoded-state-param-value"
+		ldapUpstreamName      = "some-ldap-idp"
+		ldapUsername          = "some-ldap-user"
+		ldapPassword          = "some-ldap-password" //nolint:gosec // this is not a credential
+		wrongOneTimePassword  = "000000"             //nolint:gosec // this is not a credential
+		securityKeyOrigin     = "https://my-downstream-issuer.com"
+		securityKeyRPID       = "my-downstream-issuer.com"
+	)
+
+	parsedUpstreamLDAPURL, err := url.Parse("ldaps://some-ldap-host:123?base=ou%3Dusers%2Cdc%3Dpinniped%2Cdc%3Ddev")
+	require.NoError(t, err)
+
+	upstreamLDAPIdentityProviderBuilder := func() *oidctestutil.TestUpstreamLDAPIdentityProviderBuilder {
+		return oidctestutil.NewTestUpstreamLDAPIdentityProviderBuilder().
+			WithName(ldapUpstreamName).
+			WithResourceUID("ldap-resource-uid").
+			WithURL(parsedUpstreamLDAPURL).
+			WithAuthenticateFunc(func(ctx context.Context, username, password string) (*authenticators.Response, bool, error) {
+				if username != ldapUsername || password != ldapPassword {
+					return nil, false, nil
+				}
+				return &authenticators.Response{
+					User: &user.DefaultInfo{Name: username, UID: "some-ldap-uid", Groups: []string{"group1"}},
+					DN:   "cn=foo,dn=bar",
+				}, true, nil
+			})
+	}
+	totpUpstreamLDAPIdentityProvider := upstreamLDAPIdentityProviderBuilder().WithRequireTOTPForFederationDomain().Build()
+	webAuthnUpstreamLDAPIdentityProvider := upstreamLDAPIdentityProviderBuilder().WithRequireWebAuthnForFederationDomain().Build()
+
+	decodedState := &oidc.UpstreamStateParamData{
+		AuthParams: url.Values{
+			"response_type":         []string{"code"},
+			"scope":                 []string{"openid username groups"},
+			"client_id":             []string{"pinniped-cli"},
+			"state":                 []string{downstreamState},
+			"nonce":                 []string{"some-nonce-value"},
+			"code_challenge":        []string{"some-challenge"},
+			"code_challenge_method": []string{"S256"},
+			"redirect_uri":          []string{downstreamRedirectURI},
+		}.Encode(),
+		UpstreamName:  ldapUpstreamName,
+		UpstreamType:  "ldap",
+		Nonce:         "test-nonce",
+		CSRFToken:     "test-csrf",
+		PKCECode:      "test-pkce",
+		FormatVersion: "2",
+	}
+
+	// Note that fosite puts the granted scopes as a param in the redirect URI even though the spec doesn't seem to require it
+	authcodeRedirectLocationRegexp := "^" + regexp.QuoteMeta(downstreamRedirectURI) +
+		`\?code=pin_ac_[^&]+&scope=openid\+username\+groups&state=` + downstreamState + "$"
+	secondFactorPageRegexp := "^" + regexp.QuoteMeta(downstreamIssuer+oidc.PinnipedLoginPath) +
+		`\?second_factor=([0-9a-f]{64})&state=` + encodedUpstreamState + "$"
+
+	type testSubject struct {
+		handler             HandlerFunc
+		kubeClient          *fake.Clientset
+		secondFactorStorage *secondfactor.Storage
+	}
+
+	newTestSubject := func(upstreamLDAPIdentityProvider *oidctestutil.TestUpstreamLDAPIdentityProvider) *testSubject {
+		kubeClient := fake.NewSimpleClientset()
+		secretsClient := kubeClient.CoreV1().Secrets("some-namespace")
+		oidcClientsClient := supervisorfake.NewSimpleClientset().ConfigV1alpha1().OIDCClients("some-namespace")
+		timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+		kubeOauthStore := storage.NewKubeStorage(secretsClient, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+		hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+		oauthHelper := oidc.FositeOauth2Helper(kubeOauthStore, downstreamIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration, nil, nil)
+		consentRequester := consent.NewRequester(downstreamIssuer, consent.NewStorage(secretsClient, timeoutsConfiguration))
+		secondFactorStorage := secondfactor.NewStorage(secretsClient, timeoutsConfiguration)
+		idps := testidplister.NewUpstreamIDPListerBuilder().WithLDAP(upstreamLDAPIdentityProvider)
+		return &testSubject{
+			handler:             NewPostHandler(downstreamIssuer, idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, consentRequester, secondFactorStorage),
+			kubeClient:          kubeClient,
+			secondFactorStorage: secondFactorStorage,
+		}
+	}
+
+	post := func(subject *testSubject, formParams url.Values) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodPost, "/ignored", strings.NewReader(formParams.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rsp := httptest.NewRecorder()
+		err := subject.handler(rsp, req, encodedUpstreamState, decodedState)
+		return rsp, err
+	}
+
+	// logInWithPassword submits the username and password, and returns the ID of the resulting second factor request.
+	logInWithPassword := func(t *testing.T, subject *testSubject) string {
+		rsp, err := post(subject, url.Values{"username": []string{ldapUsername}, "password": []string{ldapPassword}})
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		submatches := regexp.MustCompile(secondFactorPageRegexp).FindStringSubmatch(rsp.Header().Get("Location"))
+		require.Len(t, submatches, 2, "unexpected redirect location %q", rsp.Header().Get("Location"))
+		return submatches[1]
+	}
+
+	currentCode := func(t *testing.T, secret string) string {
+		code, err := totp.Code(secret, totp.TimeStep(time.Now()))
+		require.NoError(t, err)
+		return code
+	}
+
+	submitOneTimePassword := func(subject *testSubject, requestID string, password string) (*httptest.ResponseRecorder, error) {
+		return post(subject, url.Values{"second_factor": []string{requestID}, "totp_code": []string{password}})
+	}
+
+	requireNoAuthcodes := func(t *testing.T, subject *testSubject) {
+		authcodes, err := subject.kubeClient.CoreV1().Secrets("some-namespace").List(context.Background(), metav1.ListOptions{
+			LabelSelector: "storage.pinniped.dev/type=authcode",
+		})
+		require.NoError(t, err)
+		require.Empty(t, authcodes.Items)
+	}
+
+	t.Run("a user who has not enrolled an authenticator app enrolls one while logging in", func(t *testing.T) {
+		subject := newTestSubject(totpUpstreamLDAPIdentityProvider)
+		requestID := logInWithPassword(t, subject)
+		requireNoAuthcodes(t, subject)
+
+		secondFactorRequest, err := subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.NotEmpty(t, secondFactorRequest.PendingTOTPSecret)
+		require.Equal(t, ldapUsername, secondFactorRequest.Session.Custom.Username)
+
+		rsp, err := submitOneTimePassword(subject, requestID, wrongOneTimePassword)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=otp_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+		enrolled, err := subject.secondFactorStorage.IsEnrolled(context.Background(), secondFactorRequest.Session.Fosite.Claims.Subject)
+		require.NoError(t, err)
+		require.False(t, enrolled)
+
+		rsp, err = submitOneTimePassword(subject, requestID, currentCode(t, secondFactorRequest.PendingTOTPSecret))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Regexp(t, authcodeRedirectLocationRegexp, rsp.Header().Get("Location"))
+
+		enrolled, err = subject.secondFactorStorage.IsEnrolled(context.Background(), secondFactorRequest.Session.Fosite.Claims.Subject)
+		require.NoError(t, err)
+		require.True(t, enrolled)
+
+		// The second factor request may only be used once.
+		_, err = submitOneTimePassword(subject, requestID, currentCode(t, secondFactorRequest.PendingTOTPSecret))
+		require.EqualError(t, err, "second factor request not found")
+	})
+
+	t.Run("a user who has enrolled an authenticator app logs in", func(t *testing.T) {
+		subject := newTestSubject(totpUpstreamLDAPIdentityProvider)
+		secret, err := totp.GenerateSecret()
+		require.NoError(t, err)
+		requestID := logInWithPassword(t, subject)
+
+		secondFactorRequest, err := subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.NotEmpty(t, secondFactorRequest.PendingTOTPSecret)
+
+		// Enroll after the first login started, and start another login, which should not ask to enroll again.
+		require.NoError(t, subject.secondFactorStorage.Enrollments.CreateEnrollment(context.Background(), &totpenrollment.Enrollment{
+			Subject: secondFactorRequest.Session.Fosite.Claims.Subject,
+			Secret:  secret,
+		}))
+		requestID = logInWithPassword(t, subject)
+		secondFactorRequest, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.Empty(t, secondFactorRequest.PendingTOTPSecret)
+
+		rsp, err := submitOneTimePassword(subject, requestID, currentCode(t, secret))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Regexp(t, authcodeRedirectLocationRegexp, rsp.Header().Get("Location"))
+	})
+
+	t.Run("too many incorrect one-time passwords", func(t *testing.T) {
+		subject := newTestSubject(totpUpstreamLDAPIdentityProvider)
+		requestID := logInWithPassword(t, subject)
+
+		for i := 1; i < secondfactor.MaxFailedAttempts; i++ {
+			rsp, err := submitOneTimePassword(subject, requestID, wrongOneTimePassword)
+			require.NoError(t, err)
+			require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=otp_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+				rsp.Header().Get("Location"))
+		}
+
+		rsp, err := submitOneTimePassword(subject, requestID, wrongOneTimePassword)
+		require.NoError(t, err)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=too_many_otp_attempts&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+
+		_, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.True(t, apierrors.IsNotFound(err))
+		requireNoAuthcodes(t, subject)
+	})
+
+	t.Run("logging in again does not reset the incorrect one-time passwords of a user who has enrolled an authenticator app", func(t *testing.T) {
+		subject := newTestSubject(totpUpstreamLDAPIdentityProvider)
+		secret, err := totp.GenerateSecret()
+		require.NoError(t, err)
+		requestID := logInWithPassword(t, subject)
+
+		secondFactorRequest, err := subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.NoError(t, subject.secondFactorStorage.Enrollments.CreateEnrollment(context.Background(), &totpenrollment.Enrollment{
+			Subject: secondFactorRequest.Session.Fosite.Claims.Subject,
+			Secret:  secret,
+		}))
+
+		requestID = logInWithPassword(t, subject)
+		for i := 1; i < secondfactor.MaxFailedAttempts; i++ {
+			rsp, err := submitOneTimePassword(subject, requestID, wrongOneTimePassword)
+			require.NoError(t, err)
+			require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=otp_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+				rsp.Header().Get("Location"))
+		}
+		rsp, err := submitOneTimePassword(subject, requestID, wrongOneTimePassword)
+		require.NoError(t, err)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=too_many_otp_attempts&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+
+		// The user is now locked out, so even a correct one-time password is not accepted after logging in again.
+		requestID = logInWithPassword(t, subject)
+		rsp, err = submitOneTimePassword(subject, requestID, currentCode(t, secret))
+		require.NoError(t, err)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=otp_locked_out&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+
+		_, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.True(t, apierrors.IsNotFound(err))
+		requireNoAuthcodes(t, subject)
+	})
+
+	submitSecurityKeyResponse := func(subject *testSubject, requestID string, responseParams url.Values) (*httptest.ResponseRecorder, error) {
+		formParams := url.Values{"second_factor": []string{requestID}}
+		for key, values := range responseParams {
+			formParams[key] = values
+		}
+		return post(subject, formParams)
+	}
+
+	registration := func(t *testing.T, authenticator *webauthntestutil.Authenticator, challenge string) url.Values {
+		clientDataJSON, attestationObject := authenticator.Register(t, securityKeyOrigin, securityKeyRPID, challenge)
+		return url.Values{
+			"webauthn_client_data":        []string{base64.RawURLEncoding.EncodeToString(clientDataJSON)},
+			"webauthn_attestation_object": []string{base64.RawURLEncoding.EncodeToString(attestationObject)},
+		}
+	}
+
+	assertion := func(t *testing.T, authenticator *webauthntestutil.Authenticator, challenge string) url.Values {
+		clientDataJSON, authenticatorData, signature := authenticator.Assert(t, securityKeyOrigin, securityKeyRPID, challenge)
+		return url.Values{
+			"webauthn_client_data":        []string{base64.RawURLEncoding.EncodeToString(clientDataJSON)},
+			"webauthn_credential_id":      []string{base64.RawURLEncoding.EncodeToString(authenticator.CredentialID)},
+			"webauthn_authenticator_data": []string{base64.RawURLEncoding.EncodeToString(authenticatorData)},
+			"webauthn_signature":          []string{base64.RawURLEncoding.EncodeToString(signature)},
+		}
+	}
+
+	t.Run("a user who has not registered a security key registers one while logging in", func(t *testing.T) {
+		subject := newTestSubject(webAuthnUpstreamLDAPIdentityProvider)
+		authenticator := webauthntestutil.NewAuthenticator(t)
+		requestID := logInWithPassword(t, subject)
+		requireNoAuthcodes(t, subject)
+
+		secondFactorRequest, err := subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.NotEmpty(t, secondFactorRequest.WebAuthnChallenge)
+		require.True(t, secondFactorRequest.PendingWebAuthnRegistration)
+		require.Empty(t, secondFactorRequest.PendingTOTPSecret)
+
+		// A registration which answers a different challenge is not accepted.
+		rsp, err := submitSecurityKeyResponse(subject, requestID, registration(t, authenticator, "some-other-challenge"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=security_key_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+		credentialID, err := subject.secondFactorStorage.WebAuthnCredentialID(context.Background(), secondFactorRequest.Session.Fosite.Claims.Subject)
+		require.NoError(t, err)
+		require.Nil(t, credentialID)
+
+		rsp, err = submitSecurityKeyResponse(subject, requestID, registration(t, authenticator, secondFactorRequest.WebAuthnChallenge))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Regexp(t, authcodeRedirectLocationRegexp, rsp.Header().Get("Location"))
+
+		credentialID, err = subject.secondFactorStorage.WebAuthnCredentialID(context.Background(), secondFactorRequest.Session.Fosite.Claims.Subject)
+		require.NoError(t, err)
+		require.Equal(t, authenticator.CredentialID, credentialID)
+
+		// The next login uses the registered security key.
+		requestID = logInWithPassword(t, subject)
+		secondFactorRequest, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		require.False(t, secondFactorRequest.PendingWebAuthnRegistration)
+
+		rsp, err = submitSecurityKeyResponse(subject, requestID, assertion(t, authenticator, secondFactorRequest.WebAuthnChallenge))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusSeeOther, rsp.Code)
+		require.Regexp(t, authcodeRedirectLocationRegexp, rsp.Header().Get("Location"))
+
+		// The second factor request may only be used once.
+		_, err = submitSecurityKeyResponse(subject, requestID, assertion(t, authenticator, secondFactorRequest.WebAuthnChallenge))
+		require.EqualError(t, err, "second factor request not found")
+	})
+
+	t.Run("a security key which was not registered by the user is not accepted", func(t *testing.T) {
+		subject := newTestSubject(webAuthnUpstreamLDAPIdentityProvider)
+		requestID := logInWithPassword(t, subject)
+		secondFactorRequest, err := subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		_, err = submitSecurityKeyResponse(subject, requestID, registration(t, webauthntestutil.NewAuthenticator(t), secondFactorRequest.WebAuthnChallenge))
+		require.NoError(t, err)
+
+		requestID = logInWithPassword(t, subject)
+		secondFactorRequest, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.NoError(t, err)
+		rsp, err := submitSecurityKeyResponse(subject, requestID, assertion(t, webauthntestutil.NewAuthenticator(t), secondFactorRequest.WebAuthnChallenge))
+		require.NoError(t, err)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=security_key_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+	})
+
+	t.Run("too many security key responses which were not accepted", func(t *testing.T) {
+		subject := newTestSubject(webAuthnUpstreamLDAPIdentityProvider)
+		requestID := logInWithPassword(t, subject)
+
+		// A response which cannot be decoded counts as a response which was not accepted.
+		undecodable := url.Values{"webauthn_client_data": []string{"not base64url!"}, "webauthn_attestation_object": []string{""}}
+		for i := 1; i < secondfactor.MaxFailedAttempts; i++ {
+			rsp, err := submitSecurityKeyResponse(subject, requestID, undecodable)
+			require.NoError(t, err)
+			require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=security_key_error&second_factor="+requestID+"&state="+encodedUpstreamState,
+				rsp.Header().Get("Location"))
+		}
+
+		rsp, err := submitSecurityKeyResponse(subject, requestID, undecodable)
+		require.NoError(t, err)
+		require.Equal(t, downstreamIssuer+oidc.PinnipedLoginPath+"?err=too_many_security_key_attempts&state="+encodedUpstreamState,
+			rsp.Header().Get("Location"))
+
+		_, err = subject.secondFactorStorage.Requests.GetSecondFactorRequest(context.Background(), requestID)
+		require.True(t, apierrors.IsNotFound(err))
+		requireNoAuthcodes(t, subject)
+	})
+
+	t.Run("second factor request does not exist", func(t *testing.T) {
+		subject := newTestSubject(totpUpstreamLDAPIdentityProvider)
+		_, err := submitOneTimePassword(subject, "does-not-exist", wrongOneTimePassword)
+		require.EqualError(t, err, "second factor request not found")
+	})
+}
+
 func shallowCopyAndModifyQuery(query url.Values, modifications map[string]string) url.Values {
 	copied := url.Values{}
 	for key, value := range query {
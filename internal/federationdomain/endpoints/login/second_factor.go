@@ -0,0 +1,211 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package login
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/federationdomain/webauthn"
+	"go.pinniped.dev/internal/fositestorage/secondfactorrequest"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// requestSecondFactor is called after the user's password was accepted, when the user must also present a
+// second factor. It remembers the user's session and redirects to the login page, which asks for the second factor.
+// When requireWebAuthn is true, the second factor is a security key or passkey, and otherwise it is a one-time
+// password. Users who have not enrolled an authenticator app or a security key yet are asked to enroll one first.
+func requestSecondFactor(
+	r *http.Request,
+	w http.ResponseWriter,
+	issuerURL string,
+	encodedState string,
+	decodedState *oidc.UpstreamStateParamData,
+	session *psession.PinnipedSession,
+	secondFactorStorage *secondfactor.Storage,
+	requireWebAuthn bool,
+) error {
+	secondFactorRequest := &secondfactorrequest.SecondFactorRequest{
+		AuthParams: decodedState.AuthParams,
+		CSRFToken:  decodedState.CSRFToken,
+		Session:    session,
+	}
+
+	var err error
+	if requireWebAuthn {
+		err = prepareWebAuthn(r, secondFactorStorage, secondFactorRequest)
+	} else {
+		err = prepareTOTP(r, secondFactorStorage, secondFactorRequest)
+	}
+	if err != nil {
+		return err
+	}
+
+	requestID, err := secondfactor.GenerateRequestID()
+	if err != nil {
+		plog.Error("error generating second factor request ID", err)
+		return httperr.New(http.StatusInternalServerError, "error generating second factor request ID")
+	}
+
+	if err := secondFactorStorage.Requests.CreateSecondFactorRequest(r.Context(), requestID, secondFactorRequest); err != nil {
+		plog.Error("error creating second factor request", err)
+		return httperr.New(http.StatusInternalServerError, "error creating second factor request")
+	}
+
+	return redirectToSecondFactorPage(r, w, issuerURL, encodedState, requestID, loginurl.ShowNoError)
+}
+
+// prepareTOTP generates a new TOTP secret for the second factor request when the user has not enrolled an
+// authenticator app yet.
+func prepareTOTP(
+	r *http.Request,
+	secondFactorStorage *secondfactor.Storage,
+	secondFactorRequest *secondfactorrequest.SecondFactorRequest,
+) error {
+	enrolled, err := secondFactorStorage.IsEnrolled(r.Context(), secondFactorRequest.Session.Fosite.Claims.Subject)
+	if err != nil {
+		plog.Error("error reading TOTP enrollment", err)
+		return httperr.New(http.StatusInternalServerError, "error reading TOTP enrollment")
+	}
+	if enrolled {
+		return nil
+	}
+
+	secondFactorRequest.PendingTOTPSecret, err = totp.GenerateSecret()
+	if err != nil {
+		plog.Error("error generating TOTP secret", err)
+		return httperr.New(http.StatusInternalServerError, "error generating TOTP secret")
+	}
+	return nil
+}
+
+// prepareWebAuthn generates a new challenge for the second factor request, which the user's security key or
+// passkey must sign. The user is asked to register one when they have not registered one yet.
+func prepareWebAuthn(
+	r *http.Request,
+	secondFactorStorage *secondfactor.Storage,
+	secondFactorRequest *secondfactorrequest.SecondFactorRequest,
+) error {
+	credentialID, err := secondFactorStorage.WebAuthnCredentialID(r.Context(), secondFactorRequest.Session.Fosite.Claims.Subject)
+	if err != nil {
+		plog.Error("error reading WebAuthn enrollment", err)
+		return httperr.New(http.StatusInternalServerError, "error reading WebAuthn enrollment")
+	}
+
+	secondFactorRequest.WebAuthnChallenge, err = webauthn.GenerateChallenge()
+	if err != nil {
+		plog.Error("error generating WebAuthn challenge", err)
+		return httperr.New(http.StatusInternalServerError, "error generating WebAuthn challenge")
+	}
+	secondFactorRequest.PendingWebAuthnRegistration = credentialID == nil
+	return nil
+}
+
+// checkSecurityKey checks the response of the user's security key or passkey, which the login page submitted.
+// Responses which cannot be decoded are not accepted, like responses which fail verification.
+func checkSecurityKey(
+	r *http.Request,
+	issuerURL string,
+	secondFactorStorage *secondfactor.Storage,
+	secondFactorRequest *secondfactorrequest.SecondFactorRequest,
+) error {
+	rp, err := webauthn.NewRelyingParty(issuerURL)
+	if err != nil {
+		return err
+	}
+
+	var decodeErr error
+	decode := func(paramName string) []byte {
+		decoded, err := base64.RawURLEncoding.DecodeString(r.PostFormValue(paramName))
+		if err != nil && decodeErr == nil {
+			decodeErr = fmt.Errorf("%w: could not decode %s: %v", secondfactor.ErrSecurityKeyNotAccepted, paramName, err)
+		}
+		return decoded
+	}
+
+	subject := secondFactorRequest.Session.Fosite.Claims.Subject
+	challenge := secondFactorRequest.WebAuthnChallenge
+	if secondFactorRequest.PendingWebAuthnRegistration {
+		response := &webauthn.RegistrationResponse{
+			ClientDataJSON:    decode(loginurl.WebAuthnClientDataParamName),
+			AttestationObject: decode(loginurl.WebAuthnAttestationObjectParamName),
+		}
+		if decodeErr != nil {
+			return decodeErr
+		}
+		return secondFactorStorage.EnrollWebAuthn(r.Context(), rp, subject, challenge, response)
+	}
+
+	response := &webauthn.AssertionResponse{
+		CredentialID:      decode(loginurl.WebAuthnCredentialIDParamName),
+		ClientDataJSON:    decode(loginurl.WebAuthnClientDataParamName),
+		AuthenticatorData: decode(loginurl.WebAuthnAuthenticatorDataParamName),
+		Signature:         decode(loginurl.WebAuthnSignatureParamName),
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return secondFactorStorage.VerifyWebAuthn(r.Context(), rp, subject, challenge, response)
+}
+
+// readSecondFactorRequest reads the second factor request, and checks that it belongs to the same authorization
+// request and the same browser as the state param.
+func readSecondFactorRequest(
+	r *http.Request,
+	secondFactorStorage *secondfactor.Storage,
+	requestID string,
+	decodedState *oidc.UpstreamStateParamData,
+) (*secondfactorrequest.SecondFactorRequest, error) {
+	secondFactorRequest, err := secondFactorStorage.Requests.GetSecondFactorRequest(r.Context(), requestID)
+	if errors.IsNotFound(err) {
+		return nil, httperr.New(http.StatusBadRequest, "second factor request not found")
+	}
+	if err != nil {
+		plog.Error("error reading second factor request", err)
+		return nil, httperr.New(http.StatusInternalServerError, "error reading second factor request")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secondFactorRequest.CSRFToken), []byte(decodedState.CSRFToken)) != 1 {
+		return nil, httperr.New(http.StatusForbidden, "CSRF value does not match")
+	}
+
+	if secondFactorRequest.AuthParams != decodedState.AuthParams {
+		return nil, httperr.New(http.StatusBadRequest, "second factor request does not match state param")
+	}
+
+	return secondFactorRequest, nil
+}
+
+// redirectToSecondFactorPage redirects to the GET /login page of the specified issuer, which asks for the
+// second factor of the specified second factor request.
+func redirectToSecondFactorPage(
+	r *http.Request,
+	w http.ResponseWriter,
+	downstreamIssuer string,
+	encodedStateParamValue string,
+	secondFactorRequestID string,
+	errToDisplay loginurl.ErrorParamValue,
+) error {
+	secondFactorURL, err := loginurl.SecondFactorURL(downstreamIssuer, encodedStateParamValue, secondFactorRequestID, errToDisplay)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r,
+		secondFactorURL,
+		http.StatusSeeOther, // match fosite and https://tools.ietf.org/id/draft-ietf-oauth-security-topics-18.html#section-4.11
+	)
+
+	return nil
+}
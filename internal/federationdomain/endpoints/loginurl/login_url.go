@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package loginurl
@@ -15,9 +15,33 @@ const (
 	StateParamName    = "state"
 	ErrParamName      = "err"
 
-	ShowNoError        ErrorParamValue = ""
-	ShowInternalError  ErrorParamValue = "internal_error"
-	ShowBadUserPassErr ErrorParamValue = "login_error"
+	// SecondFactorParamName is the name of the param which identifies the second factor request, when the
+	// user's password was accepted and the user is now asked for their one-time password.
+	SecondFactorParamName = "second_factor"
+	TOTPCodeParamName     = "totp_code"
+
+	// These are the names of the params which hold the base64url encoded response of the user's security key
+	// or passkey. The attestation object is only sent when a security key is registered, and the credential ID,
+	// authenticator data and signature are only sent when a registered security key is used.
+	WebAuthnClientDataParamName        = "webauthn_client_data"
+	WebAuthnAttestationObjectParamName = "webauthn_attestation_object"
+	WebAuthnCredentialIDParamName      = "webauthn_credential_id"
+	WebAuthnAuthenticatorDataParamName = "webauthn_authenticator_data"
+	WebAuthnSignatureParamName         = "webauthn_signature"
+
+	ShowNoError               ErrorParamValue = ""
+	ShowInternalError         ErrorParamValue = "internal_error"
+	ShowBadUserPassErr        ErrorParamValue = "login_error"
+	ShowPasswordExpiredErr    ErrorParamValue = "password_expired"
+	ShowPasswordMustChangeErr ErrorParamValue = "password_must_change"
+	ShowAccountLockedErr      ErrorParamValue = "account_locked"
+	ShowBadOTPErr             ErrorParamValue = "otp_error"
+	ShowTooManyOTPAttemptsErr ErrorParamValue = "too_many_otp_attempts"
+	ShowOTPLockedOutErr       ErrorParamValue = "otp_locked_out"
+
+	// These are shown when the response of the user's security key or passkey was not accepted.
+	ShowBadSecurityKeyErr             ErrorParamValue = "security_key_error"
+	ShowTooManySecurityKeyAttemptsErr ErrorParamValue = "too_many_security_key_attempts"
 )
 
 type ErrorParamValue string
@@ -29,6 +53,18 @@ func URL(
 	downstreamIssuer string,
 	encodedStateParamValue string,
 	errToDisplay ErrorParamValue,
+) (string, error) {
+	return SecondFactorURL(downstreamIssuer, encodedStateParamValue, "", errToDisplay)
+}
+
+// SecondFactorURL returns the URL for the GET /login page of the specified issuer, which asks for the user's
+// one-time password for the specified second factor request. When the secondFactorRequestID is empty, it
+// returns the same URL as URL.
+func SecondFactorURL(
+	downstreamIssuer string,
+	encodedStateParamValue string,
+	secondFactorRequestID string,
+	errToDisplay ErrorParamValue,
 ) (string, error) {
 	loginURL, err := url.Parse(downstreamIssuer + oidc.PinnipedLoginPath)
 	if err != nil {
@@ -37,6 +73,9 @@ func URL(
 
 	q := loginURL.Query()
 	q.Set(StateParamName, encodedStateParamValue)
+	if secondFactorRequestID != "" {
+		q.Set(SecondFactorParamName, secondFactorRequestID)
+	}
 	if errToDisplay != ShowNoError {
 		q.Set(ErrParamName, string(errToDisplay))
 	}
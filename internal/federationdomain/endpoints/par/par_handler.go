@@ -0,0 +1,56 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package par provides a handler for the pushed authorization request endpoint (RFC9126).
+package par
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ory/fosite"
+
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// NewHandler returns an http.Handler that serves the pushed authorization request endpoint.
+//
+// Clients authenticate to this endpoint in the same way as they authenticate to the token endpoint, and push the
+// params of an authorization request. The params are validated as if they were sent to the authorization endpoint,
+// and then stored until the user's browser brings the returned request_uri to the authorization endpoint.
+func NewHandler(oauthHelper fosite.OAuth2Provider) http.Handler {
+	return httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		authorizeRequester, err := oauthHelper.NewPushedAuthorizeRequest(r.Context(), r)
+		if err != nil {
+			plog.Info("pushed authorization request error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, authorizeRequester, err)
+			return nil
+		}
+
+		removeParamsWhichShouldNotBeStored(authorizeRequester.GetRequestForm())
+
+		// The session is only used by fosite to remember when the request_uri expires.
+		pushedAuthorizeResponder, err := oauthHelper.NewPushedAuthorizeResponse(r.Context(), authorizeRequester, psession.NewPinnipedSession())
+		if err != nil {
+			plog.Info("pushed authorization response error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, authorizeRequester, err)
+			return nil
+		}
+
+		oauthHelper.WritePushedAuthorizeResponse(r.Context(), w, authorizeRequester, pushedAuthorizeResponder)
+		return nil
+	})
+}
+
+// removeParamsWhichShouldNotBeStored removes the client's credentials, which were only needed to authenticate the
+// client at this endpoint. It also removes the request object (JAR), because fosite already validated it and
+// copied its claims into the params, so the authorization endpoint does not need to validate it again.
+func removeParamsWhichShouldNotBeStored(params url.Values) {
+	params.Del("client_secret")
+	params.Del("client_assertion")
+	params.Del("client_assertion_type")
+	params.Del("request")
+}
@@ -0,0 +1,323 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package par
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	josejwt "github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/client-go/kubernetes/fake"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	testNamespace   = "some-namespace"
+	testIssuer      = "https://my-downstream-issuer.com/some-path"
+	testClientID    = "client.oauth.pinniped.dev-test-name"
+	testClientUID   = "fake-client-uid"
+	testRedirectURI = "http://127.0.0.1/callback"
+)
+
+func happyParams(modifications map[string]string) url.Values {
+	params := url.Values{
+		"response_type":         []string{"code"},
+		"scope":                 []string{"openid username groups"},
+		"client_id":             []string{testClientID},
+		"state":                 []string{"8b-state"},
+		"nonce":                 []string{"some-nonce-value-with-enough-bytes-to-exceed-min-allowed"},
+		"code_challenge":        []string{testutil.SHA256("some-pkce-verifier-that-must-be-at-least-43-characters-to-meet-entropy-requirements")},
+		"code_challenge_method": []string{"S256"},
+		"redirect_uri":          []string{testRedirectURI},
+	}
+	for key, value := range modifications {
+		params.Set(key, value)
+	}
+	return params
+}
+
+func TestPushedAuthorizeRequestEndpoint(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		params       url.Values
+		clientSecret string
+
+		wantStatus     int
+		wantBodyJSON   string
+		wantStoredForm url.Values
+	}{
+		{
+			name:           "happy path",
+			method:         http.MethodPost,
+			params:         happyParams(nil),
+			clientSecret:   testutil.PlaintextPassword1,
+			wantStatus:     http.StatusCreated,
+			wantStoredForm: happyParams(nil),
+		},
+		{
+			name:       "client's credentials in the form instead of in a basic auth header",
+			method:     http.MethodPost,
+			params:     happyParams(map[string]string{"client_secret": testutil.PlaintextPassword1}),
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_client",
+					"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method). The OAuth 2.0 Client supports client authentication method 'client_secret_basic', but method 'client_secret_post' was requested. You must configure the OAuth 2.0 client's 'token_endpoint_auth_method' value to accept 'client_secret_post'."
+				}
+			`),
+		},
+		{
+			name:         "wrong client secret",
+			method:       http.MethodPost,
+			params:       happyParams(nil),
+			clientSecret: "wrong-secret",
+			wantStatus:   http.StatusUnauthorized,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_client",
+					"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method)."
+				}
+			`),
+		},
+		{
+			name:         "redirect_uri which is not registered for the client",
+			method:       http.MethodPost,
+			params:       happyParams(map[string]string{"redirect_uri": "http://127.0.0.1/wrong"}),
+			clientSecret: testutil.PlaintextPassword1,
+			wantStatus:   http.StatusBadRequest,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The 'redirect_uri' parameter does not match any of the OAuth 2.0 Client's pre-registered redirect urls."
+				}
+			`),
+		},
+		{
+			name:         "request_uri is not allowed in a pushed authorization request",
+			method:       http.MethodPost,
+			params:       happyParams(map[string]string{"request_uri": oidc.PushedAuthorizeRequestURIPrefix + "something"}),
+			clientSecret: testutil.PlaintextPassword1,
+			wantStatus:   http.StatusBadRequest,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The request must not contain 'request_uri'."
+				}
+			`),
+		},
+		{
+			name:         "GET is not allowed",
+			method:       http.MethodGet,
+			params:       happyParams(nil),
+			clientSecret: testutil.PlaintextPassword1,
+			wantStatus:   http.StatusBadRequest,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request",
+					"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. HTTP method is 'GET', expected 'POST'."
+				}
+			`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			supervisorClient := supervisorfake.NewSimpleClientset()
+			secrets := kubeClient.CoreV1().Secrets(testNamespace)
+			oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients(testNamespace)
+
+			oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				testNamespace, testClientID, testClientUID, testRedirectURI,
+				[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+			require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+			require.NoError(t, kubeClient.Tracker().Add(secret))
+
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			oauthStore := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, testIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration, nil, nil)
+
+			subject := NewHandler(oauthHelper)
+
+			req := httptest.NewRequest(test.method, "/some/path", strings.NewReader(test.params.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.clientSecret != "" {
+				req.SetBasicAuth(testClientID, test.clientSecret)
+			}
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			require.Equal(t, "application/json;charset=UTF-8", rsp.Header().Get("Content-Type"))
+
+			if test.wantBodyJSON != "" {
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			}
+
+			if test.wantStoredForm != nil {
+				var response struct {
+					RequestURI string `json:"request_uri"`
+					ExpiresIn  int    `json:"expires_in"`
+				}
+				require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &response))
+				require.True(t, strings.HasPrefix(response.RequestURI, oidc.PushedAuthorizeRequestURIPrefix))
+				require.Equal(t, int(timeoutsConfiguration.PushedAuthorizeRequestLifespan.Seconds()), response.ExpiresIn)
+
+				storedRequest, err := oauthStore.GetPARSession(context.Background(), response.RequestURI)
+				require.NoError(t, err)
+				require.Equal(t, testClientID, storedRequest.GetClient().GetID())
+				require.Equal(t, test.wantStoredForm, storedRequest.GetRequestForm())
+			}
+		})
+	}
+}
+
+func TestPushedAuthorizeRequestEndpointRequestObjects(t *testing.T) {
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwksJSON, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: clientKey.Public(), KeyID: "client-key", Algorithm: "ES256", Use: "sig"},
+	}})
+	require.NoError(t, err)
+
+	sign := func(t *testing.T, claims any) string {
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: clientKey},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "client-key"),
+		)
+		require.NoError(t, err)
+		signed, err := josejwt.Signed(signer).Claims(claims).CompactSerialize()
+		require.NoError(t, err)
+		return signed
+	}
+
+	unsigned := func(t *testing.T, claims any) string {
+		header, err := json.Marshal(map[string]string{"alg": "none", "typ": "JWT"})
+		require.NoError(t, err)
+		payload, err := json.Marshal(claims)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+	}
+
+	requestObjectClaims := map[string]any{"state": "state-from-request-object"}
+
+	tests := []struct {
+		name          string
+		requestObject func(t *testing.T) string
+
+		wantStatus     int
+		wantBodyJSON   string
+		wantStoredForm url.Values
+	}{
+		{
+			name:          "request object signed by the client",
+			requestObject: func(t *testing.T) string { return sign(t, requestObjectClaims) },
+			wantStatus:    http.StatusCreated,
+			wantStoredForm: happyParams(map[string]string{
+				"state": "state-from-request-object",
+			}),
+		},
+		{
+			name:          "unsigned request object",
+			requestObject: func(t *testing.T) string { return unsigned(t, requestObjectClaims) },
+			wantStatus:    http.StatusBadRequest,
+			wantBodyJSON: here.Doc(`
+				{
+					"error":             "invalid_request_object",
+					"error_description": "The request parameter contains an invalid Request Object. The request object uses signing algorithm 'none', but the requested OAuth 2.0 Client enforces signing algorithm 'ES256'."
+				}
+			`),
+		},
+	}
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := fake.NewSimpleClientset()
+			supervisorClient := supervisorfake.NewSimpleClientset()
+			secrets := kubeClient.CoreV1().Secrets(testNamespace)
+			oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients(testNamespace)
+
+			oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				testNamespace, testClientID, testClientUID, testRedirectURI,
+				[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+			oidcClient.Spec.ClientAuthentication = &configv1alpha1.OIDCClientAuthentication{
+				Method: configv1alpha1.ClientAuthenticationMethodPrivateKeyJWT,
+				PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{
+					JWKS:             string(jwksJSON),
+					SigningAlgorithm: "ES256",
+				},
+			}
+			require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+			require.NoError(t, kubeClient.Tracker().Add(secret))
+
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			oauthStore := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, testIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration, nil, nil)
+
+			subject := NewHandler(oauthHelper)
+
+			params := happyParams(map[string]string{
+				"client_assertion_type": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+				"client_assertion": sign(t, josejwt.Claims{
+					Issuer:   testClientID,
+					Subject:  testClientID,
+					Audience: josejwt.Audience{testIssuer + oidc.TokenEndpointPath},
+					ID:       "some-jti",
+					Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Minute)),
+					IssuedAt: josejwt.NewNumericDate(time.Now()),
+				}),
+				"request": test.requestObject(t),
+			})
+			req := httptest.NewRequest(http.MethodPost, "/some/path", strings.NewReader(params.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+
+			if test.wantBodyJSON != "" {
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			}
+
+			if test.wantStoredForm != nil {
+				var response struct {
+					RequestURI string `json:"request_uri"`
+				}
+				require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &response))
+				storedRequest, err := oauthStore.GetPARSession(context.Background(), response.RequestURI)
+				require.NoError(t, err)
+				require.Equal(t, test.wantStoredForm, storedRequest.GetRequestForm())
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package token provides a handler for the OIDC token endpoint.
@@ -175,11 +175,12 @@ func upstreamRefresh(
 		refreshedIdentity.UpstreamGroups = oldUntransformedGroups
 	}
 
-	refreshedTransformedGroups, err := applyIdentityTransformationsDuringRefresh(ctx,
+	refreshedTransformedGroups, refreshedAdditionalClaims, err := applyIdentityTransformationsDuringRefresh(ctx,
 		idp.GetTransforms(),
 		oldTransformedUsername, // this function validates that the old and new transformed usernames match
 		refreshedIdentity.UpstreamUsername,
 		refreshedIdentity.UpstreamGroups,
+		session.Custom.UpstreamClaims, // reuse the claims from the initial login, since upstream refreshes may not return them
 		session.Custom.ProviderName,
 		session.Custom.ProviderType,
 	)
@@ -192,10 +193,37 @@ func upstreamRefresh(
 		// Replace the old value for the downstream groups in the user's session with the new value.
 		session.Fosite.Claims.Extra[oidcapi.IDTokenClaimGroups] = refreshedTransformedGroups
 	}
+	// Always remember the new downstream groups, even when they are not shared with the client.
+	session.Custom.DownstreamGroups = refreshedTransformedGroups
+
+	// Replace the additional claims which were computed by the identity transformations with their new values.
+	updateAdditionalClaimsFromTransformsInSession(session, refreshedAdditionalClaims)
 
 	return nil
 }
 
+// updateAdditionalClaimsFromTransformsInSession replaces the additional claims in the user's session which were
+// previously computed by the identity transformations, leaving any other additional claims unchanged.
+func updateAdditionalClaimsFromTransformsInSession(session *psession.PinnipedSession, newClaimsFromTransforms map[string]interface{}) {
+	additionalClaims, _ := session.Fosite.Claims.Extra[oidcapi.IDTokenClaimAdditionalClaims].(map[string]interface{})
+	if additionalClaims == nil {
+		additionalClaims = map[string]interface{}{}
+	}
+	for claimName := range session.Custom.AdditionalClaimsFromTransforms {
+		delete(additionalClaims, claimName)
+	}
+	for claimName, claimValue := range newClaimsFromTransforms {
+		additionalClaims[claimName] = claimValue
+	}
+
+	if len(additionalClaims) > 0 {
+		session.Fosite.Claims.Extra[oidcapi.IDTokenClaimAdditionalClaims] = additionalClaims
+	} else {
+		delete(session.Fosite.Claims.Extra, oidcapi.IDTokenClaimAdditionalClaims)
+	}
+	session.Custom.AdditionalClaimsFromTransforms = newClaimsFromTransforms
+}
+
 // findProviderByNameAndType finds the IDP by its resource name and IDP type,
 // and validates that its resource UID matches the expected UID.
 func findProviderByNameAndType(
@@ -228,37 +256,39 @@ func validateSessionHasUsername(session *psession.PinnipedSession) error {
 
 // applyIdentityTransformationsDuringRefresh is similar to downstreamsession.applyIdentityTransformations
 // but with validation that the username has not changed, and with slightly different error messaging.
+// It returns the transformed groups and the additional claims computed by the pipeline, if any.
 func applyIdentityTransformationsDuringRefresh(
 	ctx context.Context,
 	transforms *idtransform.TransformationPipeline,
 	oldTransformedUsername string,
 	upstreamUsername string,
 	upstreamGroups []string,
+	upstreamClaims map[string]interface{},
 	providerName string,
 	providerType psession.ProviderType,
-) ([]string, error) {
-	transformationResult, err := transforms.Evaluate(ctx, upstreamUsername, upstreamGroups)
+) ([]string, map[string]interface{}, error) {
+	transformationResult, err := transforms.Evaluate(ctx, upstreamUsername, upstreamGroups, upstreamClaims)
 	if err != nil {
-		return nil, errUpstreamRefreshError().WithHintf(
+		return nil, nil, errUpstreamRefreshError().WithHintf(
 			"Upstream refresh error while applying configured identity transformations.").
 			WithTrace(err).
 			WithDebugf("provider name: %q, provider type: %q", providerName, providerType)
 	}
 
 	if !transformationResult.AuthenticationAllowed {
-		return nil, errUpstreamRefreshError().WithHintf(
+		return nil, nil, errUpstreamRefreshError().WithHintf(
 			"Upstream refresh rejected by configured identity policy: %s.", transformationResult.RejectedAuthenticationMessage).
 			WithDebugf("provider name: %q, provider type: %q", providerName, providerType)
 	}
 
 	if oldTransformedUsername != transformationResult.Username {
-		return nil, errUpstreamRefreshError().WithHintf(
+		return nil, nil, errUpstreamRefreshError().WithHintf(
 			"Upstream refresh failed.").
 			WithTrace(errors.New("username in upstream refresh does not match previous value")).
 			WithDebugf("provider name: %q, provider type: %q", providerName, providerType)
 	}
 
-	return transformationResult.Groups, nil
+	return transformationResult.Groups, transformationResult.AdditionalClaims, nil
 }
 
 func validateAndGetDownstreamGroupsFromSession(session *psession.PinnipedSession) ([]string, error) {
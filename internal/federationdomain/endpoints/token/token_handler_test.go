@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package token
@@ -9,6 +9,8 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base32"
 	"encoding/base64"
 	"encoding/json"
@@ -43,9 +45,11 @@ import (
 	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/endpoints/tokenexchange"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
@@ -299,6 +303,7 @@ type authcodeExchangeInputs struct {
 	makeJwksSigningKeyAndProvider MakeJwksSigningKeyAndProviderFunc
 	customSessionData             *psession.CustomSessionData
 	modifySession                 func(*psession.PinnipedSession)
+	tokenExchangeAudiences        tokenexchange.AllowedAudiences
 	want                          tokenEndpointResponseExpectedValues
 }
 
@@ -989,6 +994,21 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 		want: successfulAuthCodeExchange,
 	}
 
+	// The downstream groups are remembered in the session even when the groups scope was not granted.
+	customSessionDataWithDownstreamGroups := func(downstreamGroups []string) *psession.CustomSessionData {
+		return &psession.CustomSessionData{Username: goodUsername, DownstreamGroups: downstreamGroups}
+	}
+
+	successfulAuthCodeExchangeWithCustomSessionData := func(want tokenEndpointResponseExpectedValues, customSessionData *psession.CustomSessionData) tokenEndpointResponseExpectedValues {
+		want.wantCustomSessionDataStored = customSessionData
+		return want
+	}
+
+	successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope := successfulAuthCodeExchangeUsingDynamicClient
+	successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope.wantRequestedScopes = []string{"openid", "pinniped:request-audience", "username"}
+	successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope.wantGrantedScopes = []string{"openid", "pinniped:request-audience", "username"}
+	successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope.wantGroups = nil
+
 	doValidAuthCodeExchangeUsingDynamicClient := authcodeExchangeInputs{
 		modifyAuthRequest: func(authRequest *http.Request) {
 			addDynamicClientIDToFormPostBody(authRequest)
@@ -1008,9 +1028,10 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 		requestedAudience    string
 		kubeResources        func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset)
 
-		wantStatus            int
-		wantErrorType         string
-		wantErrorDescContains string
+		wantStatus                   int
+		wantErrorType                string
+		wantErrorDescContains        string
+		wantIDTokenExpirationSeconds int
 	}{
 		{
 			name:              "happy path",
@@ -1018,6 +1039,183 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 			requestedAudience: "some-workload-cluster",
 			wantStatus:        http.StatusOK,
 		},
+		{
+			name: "happy path with an allowed audience",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-other-workload-cluster": {Audience: "some-other-workload-cluster"},
+					"some-workload-cluster":       {Audience: "some-workload-cluster"},
+				},
+				want: successfulAuthCodeExchange,
+			},
+			requestedAudience: "some-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
+		{
+			name: "happy path with an allowed audience which has a custom ID token lifetime",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {Audience: "some-workload-cluster", IDTokenLifespan: 10 * time.Minute},
+				},
+				want: successfulAuthCodeExchange,
+			},
+			requestedAudience:            "some-workload-cluster",
+			wantStatus:                   http.StatusOK,
+			wantIDTokenExpirationSeconds: 10 * 60,
+		},
+		{
+			name: "happy path with an allowed audience whose conditions allow the user",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				customSessionData: customSessionDataWithDownstreamGroups(goodGroups),
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {
+						Audience: "some-workload-cluster",
+						Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{Expression: `username == "some-username"`},
+							&celtransformer.AllowAuthenticationPolicy{Expression: `"group1" in groups`},
+						}),
+					},
+				},
+				want: successfulAuthCodeExchangeWithCustomSessionData(successfulAuthCodeExchange, customSessionDataWithDownstreamGroups(goodGroups)),
+			},
+			requestedAudience: "some-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
+		{
+			name:          "happy path with an allowed audience whose conditions allow the user by their groups when the groups scope was not granted",
+			kubeResources: addFullyCapableDynamicClientAndSecretToKubeResources,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					addDynamicClientIDToFormPostBody(authRequest)
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				customSessionData:  customSessionDataWithDownstreamGroups(goodGroups),
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {
+						Audience: "some-workload-cluster",
+						Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{Expression: `"group1" in groups`},
+						}),
+					},
+				},
+				want: successfulAuthCodeExchangeWithCustomSessionData(successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope, customSessionDataWithDownstreamGroups(goodGroups)),
+			},
+			modifyRequestParams: func(t *testing.T, params url.Values) {
+				params.Del("client_id") // client auth for dynamic clients must be in basic auth header
+			},
+			modifyRequestHeaders: func(r *http.Request) {
+				r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			},
+			requestedAudience: "some-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
+		{
+			name:          "allowed audience whose conditions reject the user by their groups when the groups scope was not granted",
+			kubeResources: addFullyCapableDynamicClientAndSecretToKubeResources,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					addDynamicClientIDToFormPostBody(authRequest)
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				customSessionData:  customSessionDataWithDownstreamGroups([]string{"contractors"}),
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {
+						Audience: "some-workload-cluster",
+						Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{
+								Expression:                    `!("contractors" in groups)`,
+								RejectedAuthenticationMessage: "contractors may not access this cluster",
+							},
+						}),
+					},
+				},
+				want: successfulAuthCodeExchangeWithCustomSessionData(successfulAuthCodeExchangeUsingDynamicClientWithoutGroupsScope, customSessionDataWithDownstreamGroups([]string{"contractors"})),
+			},
+			modifyRequestParams: func(t *testing.T, params url.Values) {
+				params.Del("client_id") // client auth for dynamic clients must be in basic auth header
+			},
+			modifyRequestHeaders: func(r *http.Request) {
+				r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			},
+			requestedAudience:     "some-workload-cluster",
+			wantStatus:            http.StatusForbidden,
+			wantErrorType:         "access_denied",
+			wantErrorDescContains: `The requested audience 'some-workload-cluster' was rejected: contractors may not access this cluster`,
+		},
+		{
+			name: "audience which is not allowed",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-other-workload-cluster": {Audience: "some-other-workload-cluster"},
+				},
+				want: successfulAuthCodeExchange,
+			},
+			requestedAudience:     "some-workload-cluster",
+			wantStatus:            http.StatusBadRequest,
+			wantErrorType:         "invalid_target",
+			wantErrorDescContains: `The requested audience is invalid, unknown, or not allowed. The requested audience 'some-workload-cluster' is not allowed by this FederationDomain.`,
+		},
+		{
+			name: "allowed audience whose conditions reject the user",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				customSessionData: customSessionDataWithDownstreamGroups(goodGroups),
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {
+						Audience: "some-workload-cluster",
+						Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{Expression: `username == "some-username"`},
+							&celtransformer.AllowAuthenticationPolicy{
+								Expression:                    `"admins" in groups`,
+								RejectedAuthenticationMessage: "only admins may access this cluster",
+							},
+						}),
+					},
+				},
+				want: successfulAuthCodeExchangeWithCustomSessionData(successfulAuthCodeExchange, customSessionDataWithDownstreamGroups(goodGroups)),
+			},
+			requestedAudience:     "some-workload-cluster",
+			wantStatus:            http.StatusForbidden,
+			wantErrorType:         "access_denied",
+			wantErrorDescContains: `The requested audience 'some-workload-cluster' was rejected: only admins may access this cluster`,
+		},
+		{
+			name: "allowed audience whose conditions fail to evaluate",
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(authRequest *http.Request) {
+					authRequest.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+					"some-workload-cluster": {
+						Audience: "some-workload-cluster",
+						Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{Expression: `groups[5] == "admins"`},
+						}),
+					},
+				},
+				want: successfulAuthCodeExchange,
+			},
+			requestedAudience:     "some-workload-cluster",
+			wantStatus:            http.StatusInternalServerError,
+			wantErrorType:         "server_error",
+			wantErrorDescContains: `Could not evaluate the conditions for the requested audience.`,
+		},
 		{
 			name: "happy path with additional claims",
 			authcodeExchange: authcodeExchangeInputs{
@@ -1708,7 +1906,11 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 			expiresAtAsFloat, ok := tokenClaims["exp"].(float64)
 			require.True(t, ok, "expected exp claim to be a float64")
 			expiresAt := time.Unix(int64(expiresAtAsFloat), 0)
-			testutil.RequireTimeInDelta(t, approxRequestTime.UTC().Add(idTokenExpirationSeconds*time.Second), expiresAt, timeComparisonFudge)
+			wantIDTokenExpirationSeconds := test.wantIDTokenExpirationSeconds
+			if wantIDTokenExpirationSeconds == 0 {
+				wantIDTokenExpirationSeconds = idTokenExpirationSeconds
+			}
+			testutil.RequireTimeInDelta(t, approxRequestTime.UTC().Add(time.Duration(wantIDTokenExpirationSeconds)*time.Second), expiresAt, timeComparisonFudge)
 			issuedAtAsFloat, ok := tokenClaims["iat"].(float64)
 			require.True(t, ok, "expected iat claim to be a float64")
 			issuedAt := time.Unix(int64(issuedAtAsFloat), 0)
@@ -1722,6 +1924,494 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 	}
 }
 
+func TestTokenEndpointClientCredentials(t *testing.T) { // tests for grant_type "client_credentials"
+	serviceIdentity := &configv1alpha1.OIDCClientServiceIdentity{
+		Username: "some-ci-system",
+		Groups:   []string{"ci-group1", "ci-group2"},
+	}
+
+	addServiceClientAndSecretToKubeResources := func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		oidcClient, secret := testutil.ServiceOIDCClientAndStorageSecret(t,
+			"some-namespace",
+			dynamicClientID,
+			dynamicClientUID,
+			goodRedirectURI,
+			serviceIdentity,
+			[]string{testutil.HashedPassword1AtGoMinCost, testutil.HashedPassword2AtGoMinCost},
+			oidcclientvalidator.Validate,
+		)
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+	}
+
+	tests := []struct {
+		name          string
+		kubeResources func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset)
+		scopes        string
+		clientSecret  string
+
+		tokenExchangeAudiences tokenexchange.AllowedAudiences
+
+		wantStatus                         int
+		wantErrorType                      string
+		wantErrorDescContains              string
+		wantBodyFields                     []string
+		wantUsername                       string
+		wantGroups                         []string
+		wantTokenExchange                  bool
+		wantTokenExchangeErrorDescContains string
+	}{
+		{
+			name:              "happy path with all scopes issues an ID token and an access token which can be used for token exchange",
+			kubeResources:     addServiceClientAndSecretToKubeResources,
+			scopes:            "openid pinniped:request-audience username groups",
+			wantStatus:        http.StatusOK,
+			wantBodyFields:    []string{"id_token", "access_token", "token_type", "expires_in", "scope"},
+			wantUsername:      serviceIdentity.Username,
+			wantGroups:        serviceIdentity.Groups,
+			wantTokenExchange: true,
+		},
+		{
+			name:          "happy path with an allowed audience whose conditions allow the service identity by its groups",
+			kubeResources: addServiceClientAndSecretToKubeResources,
+			scopes:        "openid pinniped:request-audience username groups",
+			tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+				"some-workload-cluster": {
+					Audience: "some-workload-cluster",
+					Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+						&celtransformer.AllowAuthenticationPolicy{Expression: `"ci-group1" in groups`},
+					}),
+				},
+			},
+			wantStatus:        http.StatusOK,
+			wantBodyFields:    []string{"id_token", "access_token", "token_type", "expires_in", "scope"},
+			wantUsername:      serviceIdentity.Username,
+			wantGroups:        serviceIdentity.Groups,
+			wantTokenExchange: true,
+		},
+		{
+			name:          "allowed audience whose conditions reject the service identity by its groups",
+			kubeResources: addServiceClientAndSecretToKubeResources,
+			scopes:        "openid pinniped:request-audience username groups",
+			tokenExchangeAudiences: tokenexchange.AllowedAudiences{
+				"some-workload-cluster": {
+					Audience: "some-workload-cluster",
+					Conditions: transformtestutil.NewPipeline(t, []celtransformer.CELTransformation{
+						&celtransformer.AllowAuthenticationPolicy{
+							Expression:                    `!("ci-group2" in groups)`,
+							RejectedAuthenticationMessage: "ci systems may not access this cluster",
+						},
+					}),
+				},
+			},
+			wantStatus:                         http.StatusOK,
+			wantBodyFields:                     []string{"id_token", "access_token", "token_type", "expires_in", "scope"},
+			wantUsername:                       serviceIdentity.Username,
+			wantGroups:                         serviceIdentity.Groups,
+			wantTokenExchange:                  true,
+			wantTokenExchangeErrorDescContains: `The requested audience 'some-workload-cluster' was rejected: ci systems may not access this cluster`,
+		},
+		{
+			name:           "happy path without username and groups scopes",
+			kubeResources:  addServiceClientAndSecretToKubeResources,
+			scopes:         "openid",
+			wantStatus:     http.StatusOK,
+			wantBodyFields: []string{"id_token", "access_token", "token_type", "expires_in", "scope"},
+		},
+		{
+			name:           "happy path without openid scope does not issue an ID token",
+			kubeResources:  addServiceClientAndSecretToKubeResources,
+			scopes:         "username groups",
+			wantStatus:     http.StatusOK,
+			wantBodyFields: []string{"access_token", "token_type", "expires_in", "scope"},
+		},
+		{
+			name:                  "offline_access scope is not allowed",
+			kubeResources:         addServiceClientAndSecretToKubeResources,
+			scopes:                "openid offline_access",
+			wantStatus:            http.StatusBadRequest,
+			wantErrorType:         "invalid_scope",
+			wantErrorDescContains: "The 'offline_access' scope is not allowed for authorization grant 'client_credentials'.",
+		},
+		{
+			name:                  "wrong client secret",
+			kubeResources:         addServiceClientAndSecretToKubeResources,
+			scopes:                "openid",
+			clientSecret:          "wrong client secret",
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "Client authentication failed",
+		},
+		{
+			name:                  "client is not allowed to use the client_credentials grant",
+			kubeResources:         addFullyCapableDynamicClientAndSecretToKubeResources,
+			scopes:                "openid",
+			wantStatus:            http.StatusBadRequest,
+			wantErrorType:         "unauthorized_client",
+			wantErrorDescContains: "The OAuth 2.0 Client is not allowed to use authorization grant 'client_credentials'.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeClient := fake.NewSimpleClientset()
+			supervisorClient := supervisorfake.NewSimpleClientset()
+			secrets := kubeClient.CoreV1().Secrets("some-namespace")
+			test.kubeResources(t, supervisorClient, kubeClient)
+
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			oauthStore := storage.NewKubeStorage(secrets, supervisorClient.ConfigV1alpha1().OIDCClients("some-namespace"), oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+			jwtSigningKey, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration(), test.tokenExchangeAudiences, nil)
+			subject := NewHandler(testidplister.NewUpstreamIDPListerBuilder().BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper)
+
+			clientSecret := test.clientSecret
+			if clientSecret == "" {
+				clientSecret = testutil.PlaintextPassword1
+			}
+			req := httptest.NewRequest("POST", "/path/shouldn't/matter",
+				body(url.Values{"grant_type": {"client_credentials"}, "scope": {test.scopes}}).ReadCloser())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(dynamicClientID, clientSecret)
+			rsp := httptest.NewRecorder()
+
+			approxRequestTime := time.Now()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+
+			var parsedResponseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedResponseBody))
+
+			if rsp.Code != http.StatusOK {
+				require.Equal(t, test.wantErrorType, parsedResponseBody["error"])
+				require.Contains(t, parsedResponseBody["error_description"], test.wantErrorDescContains)
+				// No tokens should have been stored.
+				testutil.RequireNumberOfSecretsExcludingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: oidcclientsecretstorage.TypeLabelValue}, 0)
+				return
+			}
+
+			require.ElementsMatch(t, test.wantBodyFields, getMapKeys(parsedResponseBody))
+			require.Equal(t, "bearer", parsedResponseBody["token_type"])
+			require.Equal(t, test.scopes, parsedResponseBody["scope"])
+			require.InDelta(t, accessTokenExpirationSeconds, parsedResponseBody["expires_in"], 2)
+
+			// Only the access token is stored, since there is no authorize request and refresh tokens are never issued.
+			testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 1)
+			testutil.RequireNumberOfSecretsExcludingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: oidcclientsecretstorage.TypeLabelValue}, 1)
+
+			wantSubject := goodIssuer + "?clientID=" + url.QueryEscape(dynamicClientID)
+
+			if slices.Contains(test.wantBodyFields, "id_token") {
+				idToken, ok := parsedResponseBody["id_token"].(string)
+				require.True(t, ok)
+				token := oidctestutil.VerifyECDSAIDToken(t, goodIssuer, dynamicClientID, jwtSigningKey, idToken)
+				var claims map[string]interface{}
+				require.NoError(t, token.Claims(&claims))
+
+				require.Equal(t, wantSubject, claims["sub"])
+				require.Equal(t, dynamicClientID, claims["azp"])
+				require.Equal(t, []interface{}{dynamicClientID}, claims["aud"])
+				if test.wantUsername != "" {
+					require.Equal(t, test.wantUsername, claims["username"])
+				} else {
+					require.NotContains(t, claims, "username")
+				}
+				if test.wantGroups != nil {
+					require.Equal(t, toSliceOfInterface(test.wantGroups), claims["groups"])
+				} else {
+					require.NotContains(t, claims, "groups")
+				}
+				issuedAt := time.Unix(int64(claims["iat"].(float64)), 0)
+				testutil.RequireTimeInDelta(t, approxRequestTime.UTC(), issuedAt, timeComparisonFudge)
+			}
+
+			if !test.wantTokenExchange {
+				return
+			}
+
+			// Exchange the access token for a cluster-scoped ID token.
+			exchangeRequest := happyTokenExchangeRequest("some-workload-cluster", parsedResponseBody["access_token"].(string))
+			exchangeRequest.Form.Del("client_id")
+			req = httptest.NewRequest("POST", "/path/shouldn't/matter", body(exchangeRequest.Form).ReadCloser())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			rsp = httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("token exchange response body: %q", rsp.Body.String())
+
+			var parsedExchangeResponseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedExchangeResponseBody))
+			if test.wantTokenExchangeErrorDescContains != "" {
+				require.Equal(t, http.StatusForbidden, rsp.Code)
+				require.Equal(t, "access_denied", parsedExchangeResponseBody["error"])
+				require.Contains(t, parsedExchangeResponseBody["error_description"], test.wantTokenExchangeErrorDescContains)
+				return
+			}
+			require.Equal(t, http.StatusOK, rsp.Code)
+			token := oidctestutil.VerifyECDSAIDToken(t, goodIssuer, "some-workload-cluster", jwtSigningKey, parsedExchangeResponseBody["access_token"].(string))
+			var claims map[string]interface{}
+			require.NoError(t, token.Claims(&claims))
+			require.Equal(t, wantSubject, claims["sub"])
+			require.Equal(t, test.wantUsername, claims["username"])
+			require.Equal(t, toSliceOfInterface(test.wantGroups), claims["groups"])
+		})
+	}
+}
+
+func TestTokenEndpointClientAuthentication(t *testing.T) { // tests for the private_key_jwt and tls_client_auth methods
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherClientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwksJSON, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+		{Key: clientKey.Public(), KeyID: "client-key", Algorithm: "ES256", Use: "sig"},
+	}})
+	require.NoError(t, err)
+
+	testCA, err := certauthority.New("test CA", time.Hour)
+	require.NoError(t, err)
+	otherCA, err := certauthority.New("other CA", time.Hour)
+	require.NoError(t, err)
+
+	addClientToKubeResources := func(clientAuthentication *configv1alpha1.OIDCClientAuthentication) func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+		return func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset) {
+			oidcClient, secret := testutil.ServiceOIDCClientAndStorageSecret(t,
+				"some-namespace",
+				dynamicClientID,
+				dynamicClientUID,
+				goodRedirectURI,
+				&configv1alpha1.OIDCClientServiceIdentity{Username: "some-ci-system"},
+				[]string{testutil.HashedPassword1AtGoMinCost},
+				oidcclientvalidator.Validate,
+			)
+			oidcClient.Spec.ClientAuthentication = clientAuthentication
+			valid, conditions, _ := oidcclientvalidator.Validate(oidcClient, secret, bcrypt.MinCost)
+			require.True(t, valid, "Test's OIDCClient should have been valid. See conditions for errors: %s", conditions)
+			require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+			require.NoError(t, kubeClient.Tracker().Add(secret))
+		}
+	}
+
+	privateKeyJWTClient := addClientToKubeResources(&configv1alpha1.OIDCClientAuthentication{
+		Method: configv1alpha1.ClientAuthenticationMethodPrivateKeyJWT,
+		PrivateKeyJWT: &configv1alpha1.OIDCClientPrivateKeyJWT{
+			JWKS:             string(jwksJSON),
+			SigningAlgorithm: "ES256",
+		},
+	})
+
+	tlsClientAuthClient := addClientToKubeResources(&configv1alpha1.OIDCClientAuthentication{
+		Method: configv1alpha1.ClientAuthenticationMethodTLSClientAuth,
+		TLSClientAuth: &configv1alpha1.OIDCClientTLSClientAuth{
+			CertificateAuthorityData: base64.StdEncoding.EncodeToString(testCA.Bundle()),
+			SubjectDN:                "CN=some-client",
+		},
+	})
+
+	clientAssertion := func(t *testing.T, key *ecdsa.PrivateKey, jti string, audience string, expiry time.Time) string {
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: key},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "client-key"),
+		)
+		require.NoError(t, err)
+		assertion, err := josejwt.Signed(signer).Claims(josejwt.Claims{
+			Issuer:   dynamicClientID,
+			Subject:  dynamicClientID,
+			Audience: josejwt.Audience{audience},
+			ID:       jti,
+			Expiry:   josejwt.NewNumericDate(expiry),
+			IssuedAt: josejwt.NewNumericDate(time.Now()),
+		}).CompactSerialize()
+		require.NoError(t, err)
+		return assertion
+	}
+
+	clientCertificates := func(t *testing.T, ca *certauthority.CA, commonName string) []*x509.Certificate {
+		cert, err := ca.IssueClientCert(commonName, nil, time.Hour)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return []*x509.Certificate{leaf}
+	}
+
+	tokenURL := goodIssuer + oidc.TokenEndpointPath
+
+	tests := []struct {
+		name          string
+		kubeResources func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *fake.Clientset)
+		modifyRequest func(t *testing.T, req *http.Request, form url.Values)
+		// Optionally send a second request with the same credentials, and expect the results to be about the second request.
+		repeatRequest bool
+
+		wantStatus            int
+		wantErrorType         string
+		wantErrorDescContains string
+	}{
+		{
+			name:          "private_key_jwt happy path",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+				form.Set("client_assertion", clientAssertion(t, clientKey, "some-jti", tokenURL, time.Now().Add(time.Minute)))
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "private_key_jwt client assertion cannot be used twice",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+				form.Set("client_assertion", clientAssertion(t, clientKey, "some-jti", tokenURL, time.Now().Add(time.Minute)))
+			},
+			repeatRequest:         true,
+			wantStatus:            http.StatusBadRequest,
+			wantErrorType:         "jti_known",
+			wantErrorDescContains: "Claim 'jti' from 'client_assertion' MUST only be used once.",
+		},
+		{
+			name:          "private_key_jwt client assertion signed by the wrong key",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+				form.Set("client_assertion", clientAssertion(t, otherClientKey, "some-jti", tokenURL, time.Now().Add(time.Minute)))
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "Unable to verify the integrity of the 'client_assertion' value.",
+		},
+		{
+			name:          "private_key_jwt client assertion for the wrong audience",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+				form.Set("client_assertion", clientAssertion(t, clientKey, "some-jti", "https://other-issuer.com/oauth2/token", time.Now().Add(time.Minute)))
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "Claim 'audience' from 'client_assertion' must match the authorization server's token endpoint 'https://some-issuer.com/oauth2/token'.",
+		},
+		{
+			name:          "private_key_jwt client assertion which expires too far in the future",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+				form.Set("client_assertion", clientAssertion(t, clientKey, "some-jti", tokenURL, time.Now().Add(2*time.Hour)))
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "Claim 'exp' from 'client_assertion' must not be more than 1h0m0s in the future.",
+		},
+		{
+			name:          "private_key_jwt client cannot use a client secret",
+			kubeResources: privateKeyJWTClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				req.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "The OAuth 2.0 Client supports client authentication method 'private_key_jwt', but method 'client_secret_basic' was requested.",
+		},
+		{
+			name:          "tls_client_auth happy path",
+			kubeResources: tlsClientAuthClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_id", dynamicClientID)
+				req.TLS = &tls.ConnectionState{PeerCertificates: clientCertificates(t, testCA, "some-client")}
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "tls_client_auth without a client certificate",
+			kubeResources: tlsClientAuthClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_id", dynamicClientID)
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "The OAuth 2.0 Client supports client authentication method 'tls_client_auth', but no client certificate was presented.",
+		},
+		{
+			name:          "tls_client_auth with a client certificate from the wrong CA",
+			kubeResources: tlsClientAuthClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_id", dynamicClientID)
+				req.TLS = &tls.ConnectionState{PeerCertificates: clientCertificates(t, otherCA, "some-client")}
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "The client certificate could not be verified.",
+		},
+		{
+			name:          "tls_client_auth with a client certificate for the wrong subject",
+			kubeResources: tlsClientAuthClient,
+			modifyRequest: func(t *testing.T, req *http.Request, form url.Values) {
+				form.Set("client_id", dynamicClientID)
+				req.TLS = &tls.ConnectionState{PeerCertificates: clientCertificates(t, testCA, "some-other-client")}
+			},
+			wantStatus:            http.StatusUnauthorized,
+			wantErrorType:         "invalid_client",
+			wantErrorDescContains: "The client certificate does not match the subject which is configured for the OAuth 2.0 Client.",
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeClient := fake.NewSimpleClientset()
+			supervisorClient := supervisorfake.NewSimpleClientset()
+			secrets := kubeClient.CoreV1().Secrets("some-namespace")
+			test.kubeResources(t, supervisorClient, kubeClient)
+
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			oauthStore := storage.NewKubeStorage(secrets, supervisorClient.ConfigV1alpha1().OIDCClients("some-namespace"), oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+			_, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration(), nil, nil)
+			subject := NewHandler(testidplister.NewUpstreamIDPListerBuilder().BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper)
+
+			form := url.Values{"grant_type": {"client_credentials"}, "scope": {"openid"}}
+			req := httptest.NewRequest("POST", "/path/shouldn't/matter", nil)
+			test.modifyRequest(t, req, form)
+			sendRequest := func() *httptest.ResponseRecorder {
+				req.Body = body(form).ReadCloser()
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				rsp := httptest.NewRecorder()
+				subject.ServeHTTP(rsp, req)
+				t.Logf("response body: %q", rsp.Body.String())
+				return rsp
+			}
+
+			rsp := sendRequest()
+			if test.repeatRequest {
+				require.Equal(t, http.StatusOK, rsp.Code)
+				rsp = sendRequest()
+			}
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+
+			var parsedResponseBody map[string]interface{}
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedResponseBody))
+
+			if rsp.Code != http.StatusOK {
+				require.Equal(t, test.wantErrorType, parsedResponseBody["error"])
+				require.Contains(t, parsedResponseBody["error_description"], test.wantErrorDescContains)
+				return
+			}
+
+			require.Contains(t, parsedResponseBody, "access_token")
+			require.Contains(t, parsedResponseBody, "id_token")
+			testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 1)
+		})
+	}
+}
+
 type refreshRequestInputs struct {
 	modifyTokenRequest func(tokenRequest *http.Request, refreshToken string, accessToken string)
 	want               tokenEndpointResponseExpectedValues
@@ -1767,6 +2457,7 @@ func TestRefreshGrant(t *testing.T) {
 			Username:         goodUsername,
 			UpstreamUsername: goodUsername,
 			UpstreamGroups:   goodGroups,
+			DownstreamGroups: goodGroups,
 			ProviderName:     oidcUpstreamName,
 			ProviderUID:      oidcUpstreamResourceUID,
 			ProviderType:     oidcUpstreamType,
@@ -1789,6 +2480,7 @@ func TestRefreshGrant(t *testing.T) {
 			Username:         goodUsername,
 			UpstreamUsername: goodUsername,
 			UpstreamGroups:   goodGroups,
+			DownstreamGroups: goodGroups,
 			ProviderName:     oidcUpstreamName,
 			ProviderUID:      oidcUpstreamResourceUID,
 			ProviderType:     oidcUpstreamType,
@@ -1962,6 +2654,7 @@ func TestRefreshGrant(t *testing.T) {
 		Username:         goodUsername,
 		UpstreamUsername: goodUsername,
 		UpstreamGroups:   goodGroups,
+		DownstreamGroups: goodGroups,
 		ProviderUID:      activeDirectoryUpstreamResourceUID,
 		ProviderName:     activeDirectoryUpstreamName,
 		ProviderType:     activeDirectoryUpstreamType,
@@ -1974,6 +2667,7 @@ func TestRefreshGrant(t *testing.T) {
 		Username:         goodUsername,
 		UpstreamUsername: goodUsername,
 		UpstreamGroups:   goodGroups,
+		DownstreamGroups: goodGroups,
 		ProviderUID:      ldapUpstreamResourceUID,
 		ProviderName:     ldapUpstreamName,
 		ProviderType:     ldapUpstreamType,
@@ -1982,6 +2676,12 @@ func TestRefreshGrant(t *testing.T) {
 		},
 	}
 
+	withDownstreamGroups := func(customSessionData *psession.CustomSessionData, downstreamGroups []string) *psession.CustomSessionData {
+		copyOfCustomSession := *customSessionData
+		copyOfCustomSession.DownstreamGroups = downstreamGroups
+		return &copyOfCustomSession
+	}
+
 	happyLDAPCustomSessionDataWithUsername := func(wantDownstreamUsername string) *psession.CustomSessionData {
 		copyOfCustomSession := *happyLDAPCustomSessionData
 		copyOfLDAP := *(happyLDAPCustomSessionData.LDAP)
@@ -2063,7 +2763,7 @@ func TestRefreshGrant(t *testing.T) {
 			},
 			refreshRequest: refreshRequestInputs{
 				want: happyRefreshTokenResponseForOpenIDAndOfflineAccessWithUsernameAndGroups(
-					upstreamOIDCCustomSessionDataWithNewRefreshTokenWithUsername(oidcUpstreamRefreshedRefreshToken, transformationUsernamePrefix+goodUsername),
+					withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshTokenWithUsername(oidcUpstreamRefreshedRefreshToken, transformationUsernamePrefix+goodUsername), testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups)),
 					refreshedUpstreamTokensWithIDAndRefreshTokens(),
 					transformationUsernamePrefix+goodUsername,
 					testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups),
@@ -2105,7 +2805,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups),
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithRefreshTokenWithoutIDToken(), false),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshTokenWithUsername(oidcUpstreamRefreshedRefreshToken, transformationUsernamePrefix+goodUsername),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshTokenWithUsername(oidcUpstreamRefreshedRefreshToken, transformationUsernamePrefix+goodUsername), testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups)),
 				},
 			},
 		},
@@ -2189,6 +2889,56 @@ func TestRefreshGrant(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "refresh grant with OIDC upstream with identity transformations which use the upstream claims from the initial login",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]interface{}{
+							"sub":            goodUpstreamSubject,
+							"email_verified": true, // refreshed claims are not used by the transformations
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithIDAndRefreshTokens()).
+					WithTransformsForFederationDomain(transformtestutil.NewPipeline(t,
+						[]celtransformer.CELTransformation{
+							&celtransformer.AllowAuthenticationPolicy{
+								Expression:                    `upstreamClaims.email_verified == true`,
+								RejectedAuthenticationMessage: `email not verified`,
+							},
+						}),
+					).Build()),
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access username groups") },
+				customSessionData: func() *psession.CustomSessionData {
+					data := initialUpstreamOIDCRefreshTokenCustomSessionData()
+					data.UpstreamClaims = map[string]interface{}{"email_verified": false}
+					return data
+				}(),
+				want: happyAuthcodeExchangeTokenResponseForOpenIDAndOfflineAccessWithUsernameAndGroups(
+					func() *psession.CustomSessionData {
+						data := initialUpstreamOIDCRefreshTokenCustomSessionData()
+						data.UpstreamClaims = map[string]interface{}{"email_verified": false}
+						return data
+					}(),
+					goodUsername,
+					goodGroups,
+				),
+			},
+			refreshRequest: refreshRequestInputs{
+				want: tokenEndpointResponseExpectedValues{
+					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
+					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
+					wantStatus:                        http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "error",
+							"error_description": "Error during upstream refresh. Upstream refresh rejected by configured identity policy: email not verified."
+						}
+					`),
+				},
+			},
+		},
 		{
 			name: "happy path refresh grant with openid scope granted (id token returned) and additionalClaims",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
@@ -2252,6 +3002,77 @@ func TestRefreshGrant(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "happy path refresh grant with OIDC upstream with additional claims from identity transformations, which are recomputed during refresh",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]interface{}{
+							"sub": goodUpstreamSubject,
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithIDAndRefreshTokens()).
+					WithTransformsForFederationDomain(transformtestutil.NewPipelineWithAdditionalClaims(t,
+						[]celtransformer.CELTransformation{},
+						[]*celtransformer.AdditionalClaimExpression{
+							{Name: "email", Expression: `upstreamClaims.email`},
+							{Name: "displayName", Expression: `username.upperAscii()`},
+							{Name: "removedClaim", Expression: `null`},
+						}),
+					).Build()),
+			authcodeExchange: authcodeExchangeInputs{
+				customSessionData: func() *psession.CustomSessionData {
+					data := initialUpstreamOIDCRefreshTokenCustomSessionData()
+					data.UpstreamClaims = map[string]interface{}{"email": "joe@example.com"}
+					data.AdditionalClaimsFromTransforms = map[string]interface{}{"email": "old-value", "removedClaim": "old-value"}
+					return data
+				}(),
+				modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access username groups") },
+				modifySession: func(session *psession.PinnipedSession) {
+					session.IDTokenClaims().Extra["additionalClaims"] = map[string]interface{}{
+						"upstreamString": "string value",
+						"email":          "old-value",
+						"removedClaim":   "old-value",
+					}
+				},
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus:            http.StatusOK,
+					wantClientID:          pinnipedCLIClientID,
+					wantSuccessBodyFields: []string{"id_token", "refresh_token", "access_token", "token_type", "expires_in", "scope"},
+					wantRequestedScopes:   []string{"openid", "offline_access", "username", "groups"},
+					wantGrantedScopes:     []string{"openid", "offline_access", "username", "groups"},
+					wantCustomSessionDataStored: func() *psession.CustomSessionData {
+						data := initialUpstreamOIDCRefreshTokenCustomSessionData()
+						data.UpstreamClaims = map[string]interface{}{"email": "joe@example.com"}
+						data.AdditionalClaimsFromTransforms = map[string]interface{}{"email": "old-value", "removedClaim": "old-value"}
+						return data
+					}(),
+					wantUsername: goodUsername,
+					wantGroups:   goodGroups,
+					wantAdditionalClaims: map[string]interface{}{
+						"upstreamString": "string value",
+						"email":          "old-value",
+						"removedClaim":   "old-value",
+					},
+				},
+			},
+			refreshRequest: refreshRequestInputs{
+				want: happyRefreshTokenResponseForOpenIDAndOfflineAccessWithAdditionalClaims(
+					func() *psession.CustomSessionData {
+						data := upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken)
+						data.UpstreamClaims = map[string]interface{}{"email": "joe@example.com"}
+						data.AdditionalClaimsFromTransforms = map[string]interface{}{"email": "joe@example.com", "displayName": strings.ToUpper(goodUsername)}
+						return data
+					}(),
+					refreshedUpstreamTokensWithIDAndRefreshTokens(),
+					map[string]interface{}{
+						"upstreamString": "string value",
+						"email":          "joe@example.com",
+						"displayName":    strings.ToUpper(goodUsername),
+					},
+				),
+			},
+		},
 		{
 			name: "happy path refresh grant with openid scope granted (id token returned) using dynamic client",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
@@ -2544,7 +3365,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -2585,7 +3406,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings:                      nil, // dynamic clients should not get these warnings which are intended for the pinniped-cli client
 				},
 			},
@@ -2613,7 +3434,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -2644,7 +3465,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        []string{}, // the user no longer belongs to any groups
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), nil),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
 					},
@@ -2698,7 +3519,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantUsername:                goodUsername,
 					wantGroups:                  []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:     happyLDAPUpstreamRefreshCall(),
-					wantCustomSessionDataStored: happyLDAPCustomSessionData,
+					wantCustomSessionDataStored: withDownstreamGroups(happyLDAPCustomSessionData, []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -2736,7 +3557,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantUsername:                goodUsername,
 					wantGroups:                  []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:     happyLDAPUpstreamRefreshCall(),
-					wantCustomSessionDataStored: happyLDAPCustomSessionData,
+					wantCustomSessionDataStored: withDownstreamGroups(happyLDAPCustomSessionData, []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings:                nil, // dynamic clients should not get these warnings which are intended for the pinniped-cli client
 				},
 			},
@@ -2761,7 +3582,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantUsername:                goodUsername,
 					wantGroups:                  []string{},
 					wantUpstreamRefreshCall:     happyLDAPUpstreamRefreshCall(),
-					wantCustomSessionDataStored: happyLDAPCustomSessionData,
+					wantCustomSessionDataStored: withDownstreamGroups(happyLDAPCustomSessionData, nil),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
 					},
@@ -2804,7 +3625,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantUsername:                goodUsername,
 					wantGroups:                  []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:     happyLDAPUpstreamRefreshCall(),
-					wantCustomSessionDataStored: happyLDAPCustomSessionData,
+					wantCustomSessionDataStored: withDownstreamGroups(happyLDAPCustomSessionData, []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -2851,7 +3672,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        []string{"new-group1", "new-group2", "new-group3"},
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -2904,7 +3725,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantGroups:                        nil,
 					wantUpstreamRefreshCall:           happyOIDCUpstreamRefreshCall(),
 					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
-					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+					wantCustomSessionDataStored:       withDownstreamGroups(upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken), []string{"new-group1", "new-group2", "new-group3"}),
 				},
 			},
 		},
@@ -3004,7 +3825,7 @@ func TestRefreshGrant(t *testing.T) {
 					wantUsername:                goodUsername,
 					wantGroups:                  []string{"new-group1", "new-group2", "new-group3"}, // groups are updated even though the scope was not included
 					wantUpstreamRefreshCall:     happyLDAPUpstreamRefreshCall(),
-					wantCustomSessionDataStored: happyLDAPCustomSessionData,
+					wantCustomSessionDataStored: withDownstreamGroups(happyLDAPCustomSessionData, []string{"new-group1", "new-group2", "new-group3"}),
 					wantWarnings: []RecordedWarning{
 						{Text: `User "some-username" has been added to the following groups: ["new-group1" "new-group2" "new-group3"]`},
 						{Text: `User "some-username" has been removed from the following groups: ["group1" "groups2"]`},
@@ -3791,7 +4612,7 @@ func TestRefreshGrant(t *testing.T) {
 			},
 			refreshRequest: refreshRequestInputs{
 				want: happyRefreshTokenResponseForLDAPWithUsernameAndGroups(
-					happyLDAPCustomSessionDataWithUsername(transformationUsernamePrefix+goodUsername),
+					withDownstreamGroups(happyLDAPCustomSessionDataWithUsername(transformationUsernamePrefix+goodUsername), testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups)),
 					transformationUsernamePrefix+goodUsername,
 					testutil.AddPrefixToEach(transformationGroupsPrefix, goodGroups),
 				),
@@ -4590,7 +5411,7 @@ func exchangeAuthcodeForTokens(
 	}
 
 	// Note that makeHappyOauthHelper() calls simulateAuthEndpointHavingAlreadyRun() to preload the session storage.
-	oauthHelper, authCode, jwtSigningKey = makeHappyOauthHelper(t, authRequest, oauthStore, test.makeJwksSigningKeyAndProvider, test.customSessionData, test.modifySession)
+	oauthHelper, authCode, jwtSigningKey = makeHappyOauthHelper(t, authRequest, oauthStore, test.makeJwksSigningKeyAndProvider, test.customSessionData, test.modifySession, test.tokenExchangeAudiences)
 
 	subject = NewHandler(idps, oauthHelper)
 
@@ -4783,11 +5604,12 @@ func makeHappyOauthHelper(
 	makeJwksSigningKeyAndProvider MakeJwksSigningKeyAndProviderFunc,
 	initialCustomSessionData *psession.CustomSessionData,
 	modifySession func(session *psession.PinnipedSession),
+	tokenExchangeAudiences tokenexchange.AllowedAudiences,
 ) (fosite.OAuth2Provider, string, *ecdsa.PrivateKey) {
 	t.Helper()
 
 	jwtSigningKey, jwkProvider := makeJwksSigningKeyAndProvider(t, goodIssuer)
-	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration())
+	oauthHelper := oidc.FositeOauth2Helper(store, goodIssuer, hmacSecretFunc, jwkProvider, oidc.DefaultOIDCTimeoutsConfiguration(), tokenExchangeAudiences, nil)
 	authResponder := simulateAuthEndpointHavingAlreadyRun(t, authRequest, oauthHelper, initialCustomSessionData, modifySession)
 	return oauthHelper, authResponder.GetCode(), jwtSigningKey
 }
@@ -1,19 +1,23 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package tokenexchange
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/ory/fosite"
+	"github.com/ory/fosite/compose"
 	"github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
 	"github.com/pkg/errors"
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/psession"
 )
 
@@ -27,12 +31,34 @@ type stsParams struct {
 	requestedAudience  string
 }
 
-func HandlerFactory(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
-	return &tokenExchangeHandler{
-		idTokenStrategy:     strategy.(openid.OpenIDConnectTokenStrategy),
-		accessTokenStrategy: strategy.(oauth2.AccessTokenStrategy),
-		accessTokenStorage:  storage.(oauth2.AccessTokenStorage),
-		fositeConfig:        config,
+// AllowedAudience is an audience which may be requested during token exchange.
+type AllowedAudience struct {
+	// Audience is the audience which may be requested.
+	Audience string
+
+	// Conditions must all allow the user before they may request the audience. May be nil.
+	Conditions *idtransform.TransformationPipeline
+
+	// IDTokenLifespan is the lifespan of the ID tokens issued for the audience. When zero, the default
+	// ID token lifespan is used.
+	IDTokenLifespan time.Duration
+}
+
+// AllowedAudiences restricts which audiences may be requested during token exchange. A nil AllowedAudiences allows
+// any audience which is not reserved.
+type AllowedAudiences map[string]*AllowedAudience
+
+// HandlerFactory returns a compose.Factory for the token exchange handler. It only allows the given audiences to be
+// requested, or any audience which is not reserved when allowedAudiences is nil.
+func HandlerFactory(allowedAudiences AllowedAudiences) compose.Factory {
+	return func(config fosite.Configurator, storage interface{}, strategy interface{}) interface{} {
+		return &tokenExchangeHandler{
+			idTokenStrategy:     strategy.(openid.OpenIDConnectTokenStrategy),
+			accessTokenStrategy: strategy.(oauth2.AccessTokenStrategy),
+			accessTokenStorage:  storage.(oauth2.AccessTokenStorage),
+			fositeConfig:        config,
+			allowedAudiences:    allowedAudiences,
+		}
 	}
 }
 
@@ -41,6 +67,16 @@ type tokenExchangeHandler struct {
 	accessTokenStrategy oauth2.AccessTokenStrategy
 	accessTokenStorage  oauth2.AccessTokenStorage
 	fositeConfig        fosite.Configurator
+	allowedAudiences    AllowedAudiences
+}
+
+// errInvalidTarget is the error defined by RFC 8693 for when the requested audience is not acceptable.
+func errInvalidTarget() *fosite.RFC6749Error {
+	return &fosite.RFC6749Error{
+		ErrorField:       "invalid_target",
+		DescriptionField: "The requested audience is invalid, unknown, or not allowed.",
+		CodeField:        http.StatusBadRequest,
+	}
 }
 
 var _ fosite.TokenEndpointHandler = (*tokenExchangeHandler)(nil)
@@ -95,8 +131,14 @@ func (t *tokenExchangeHandler) PopulateTokenEndpointResponse(ctx context.Context
 		return errors.WithStack(err)
 	}
 
+	// Check that the user is allowed to request the audience.
+	idTokenLifespan, err := t.authorizeAudience(ctx, originalRequester, params.requestedAudience)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
 	// Use the original authorize request information, along with the requested audience, to mint a new JWT.
-	responseToken, err := t.mintJWT(ctx, originalRequester, params.requestedAudience)
+	responseToken, err := t.mintJWT(ctx, originalRequester, params.requestedAudience, idTokenLifespan)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -108,17 +150,62 @@ func (t *tokenExchangeHandler) PopulateTokenEndpointResponse(ctx context.Context
 	return nil
 }
 
-func (t *tokenExchangeHandler) mintJWT(ctx context.Context, requester fosite.Requester, audience string) (string, error) {
+func (t *tokenExchangeHandler) mintJWT(ctx context.Context, requester fosite.Requester, audience string, idTokenLifespan time.Duration) (string, error) {
 	downscoped := fosite.NewAccessRequest(requester.GetSession())
 	downscoped.Client.(*fosite.DefaultClient).ID = audience
 
 	// Note: if we wanted to support clients with custom token lifespans, then we would need to call
 	// fosite.GetEffectiveLifespan() to determine the lifespan here.
-	idTokenLifespan := t.fositeConfig.GetIDTokenLifespan(ctx)
+	if idTokenLifespan == 0 {
+		idTokenLifespan = t.fositeConfig.GetIDTokenLifespan(ctx)
+	}
 
 	return t.idTokenStrategy.GenerateIDToken(ctx, idTokenLifespan, downscoped)
 }
 
+// authorizeAudience checks that the audience is allowed for the user of the session, and returns the lifespan of
+// the ID token to issue for the audience, where zero means the default lifespan.
+func (t *tokenExchangeHandler) authorizeAudience(ctx context.Context, requester fosite.Requester, audience string) (time.Duration, error) {
+	if t.allowedAudiences == nil {
+		// No audiences were configured, so allow any audience which is not reserved.
+		return 0, nil
+	}
+
+	allowedAudience, ok := t.allowedAudiences[audience]
+	if !ok {
+		return 0, errInvalidTarget().WithHintf("The requested audience %q is not allowed by this FederationDomain.", audience)
+	}
+
+	if allowedAudience.Conditions == nil {
+		return allowedAudience.IDTokenLifespan, nil
+	}
+
+	// validateSession already checked that the session has a username.
+	pSession := requester.GetSession().(*psession.PinnipedSession)
+	username := pSession.IDTokenClaims().Extra[oidcapi.IDTokenClaimUsername].(string)
+
+	// Use the downstream groups remembered in the session instead of the groups claim, which is only present
+	// when the groups scope was granted, so the conditions cannot be bypassed by not requesting the groups scope.
+	groups := []string{}
+	var upstreamClaims map[string]interface{}
+	if pSession.Custom != nil {
+		if pSession.Custom.DownstreamGroups != nil {
+			groups = pSession.Custom.DownstreamGroups
+		}
+		upstreamClaims = pSession.Custom.UpstreamClaims
+	}
+
+	result, err := allowedAudience.Conditions.Evaluate(ctx, username, groups, upstreamClaims)
+	if err != nil {
+		return 0, fosite.ErrServerError.WithHint("Could not evaluate the conditions for the requested audience.").WithWrap(err)
+	}
+	if !result.AuthenticationAllowed {
+		return 0, fosite.ErrAccessDenied.WithHintf("The requested audience %q was rejected: %s", audience, result.RejectedAuthenticationMessage)
+	}
+
+	return allowedAudience.IDTokenLifespan, nil
+}
+
 func (t *tokenExchangeHandler) validateSession(requester fosite.Requester) error {
 	pSession, ok := requester.GetSession().(*psession.PinnipedSession)
 	if !ok {
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package endpointsmanager
@@ -16,15 +16,18 @@ import (
 	"go.pinniped.dev/internal/federationdomain/endpoints/auth"
 	"go.pinniped.dev/internal/federationdomain/endpoints/callback"
 	"go.pinniped.dev/internal/federationdomain/endpoints/chooseidp"
+	"go.pinniped.dev/internal/federationdomain/endpoints/consent"
 	"go.pinniped.dev/internal/federationdomain/endpoints/discovery"
 	"go.pinniped.dev/internal/federationdomain/endpoints/idpdiscovery"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/endpoints/login"
+	"go.pinniped.dev/internal/federationdomain/endpoints/par"
 	"go.pinniped.dev/internal/federationdomain/endpoints/token"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/secondfactor"
 	"go.pinniped.dev/internal/federationdomain/storage"
 	"go.pinniped.dev/internal/httputil/requestutil"
 	"go.pinniped.dev/internal/plog"
@@ -100,6 +103,8 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 
 		timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
 
+		pageBranding := incomingFederationDomain.Branding()
+
 		// Use NullStorage for the authorize endpoint because we do not actually want to store anything until
 		// the upstream callback endpoint is called later.
 		oauthHelperWithNullStorage := oidc.FositeOauth2Helper(
@@ -108,15 +113,20 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 			tokenHMACKeyGetter,
 			nil,
 			timeoutsConfiguration,
+			incomingFederationDomain.TokenExchangeAudiences(),
+			pageBranding,
 		)
 
 		// For all the other endpoints, make another oauth helper with exactly the same settings except use real storage.
+		kubeStorage := storage.NewKubeStorage(m.secretsClient, m.oidcClientsClient, timeoutsConfiguration, oidcclientvalidator.DefaultMinBcryptCost)
 		oauthHelperWithKubeStorage := oidc.FositeOauth2Helper(
-			storage.NewKubeStorage(m.secretsClient, m.oidcClientsClient, timeoutsConfiguration, oidcclientvalidator.DefaultMinBcryptCost),
+			kubeStorage,
 			issuerURL,
 			tokenHMACKeyGetter,
 			m.dynamicJWKSProvider,
 			timeoutsConfiguration,
+			incomingFederationDomain.TokenExchangeAudiences(),
+			pageBranding,
 		)
 
 		upstreamStateEncoder := dynamiccodec.New(
@@ -127,6 +137,11 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 
 		idpLister := federationdomainproviders.NewFederationDomainIdentityProvidersListerFinder(incomingFederationDomain, m.upstreamIDPs)
 
+		consentStorage := consent.NewStorage(m.secretsClient, timeoutsConfiguration)
+		consentRequester := consent.NewRequester(issuerURL, consentStorage)
+
+		secondFactorStorage := secondfactor.NewStorage(m.secretsClient, timeoutsConfiguration)
+
 		m.providerHandlers[(issuerHostWithPath + oidc.WellKnownEndpointPath)] = discovery.NewHandler(issuerURL)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.JWKSEndpointPath)] = jwks.NewHandler(issuerURL, m.dynamicJWKSProvider)
@@ -138,11 +153,14 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 			idpLister,
 			oauthHelperWithNullStorage,
 			oauthHelperWithKubeStorage,
+			kubeStorage,
 			csrftoken.Generate,
 			pkce.Generate,
 			nonce.Generate,
 			upstreamStateEncoder,
 			csrfCookieEncoder,
+			secondFactorStorage,
+			pageBranding,
 		)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.CallbackEndpointPath)] = callback.NewHandler(
@@ -151,11 +169,14 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 			upstreamStateEncoder,
 			csrfCookieEncoder,
 			issuerURL+oidc.CallbackEndpointPath,
+			consentRequester,
+			pageBranding,
 		)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.ChooseIDPEndpointPath)] = chooseidp.NewHandler(
 			issuerURL+oidc.AuthorizationEndpointPath,
 			idpLister,
+			pageBranding,
 		)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.TokenEndpointPath)] = token.NewHandler(
@@ -163,11 +184,24 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 			oauthHelperWithKubeStorage,
 		)
 
+		m.providerHandlers[(issuerHostWithPath + oidc.PushedAuthorizeEndpointPath)] = par.NewHandler(
+			oauthHelperWithKubeStorage,
+		)
+
 		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedLoginPath)] = login.NewHandler(
 			upstreamStateEncoder,
 			csrfCookieEncoder,
-			login.NewGetHandler(incomingFederationDomain.IssuerPath()+oidc.PinnipedLoginPath),
-			login.NewPostHandler(issuerURL, idpLister, oauthHelperWithKubeStorage),
+			login.NewGetHandler(incomingFederationDomain.IssuerPath()+oidc.PinnipedLoginPath, issuerURL, secondFactorStorage, pageBranding),
+			login.NewPostHandler(issuerURL, idpLister, oauthHelperWithKubeStorage, consentRequester, secondFactorStorage),
+			pageBranding,
+		)
+
+		m.providerHandlers[(issuerHostWithPath + oidc.ConsentEndpointPath)] = consent.NewHandler(
+			incomingFederationDomain.IssuerPath()+oidc.ConsentEndpointPath,
+			oauthHelperWithKubeStorage,
+			consentStorage,
+			csrfCookieEncoder,
+			pageBranding,
 		)
 
 		plog.Debug("oidc provider manager added or updated issuer", "issuer", issuerURL)
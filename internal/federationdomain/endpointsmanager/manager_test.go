@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package endpointsmanager
@@ -219,6 +219,20 @@ func TestManager(t *testing.T) {
 			return actualLocationQueryParams.Get("code")
 		}
 
+		requirePushedAuthorizeRequestToBeHandled := func(requestIssuer, authRequestParams string) {
+			recorder := httptest.NewRecorder()
+
+			subject.ServeHTTP(recorder, newPostRequest(requestIssuer+oidc.PushedAuthorizeEndpointPath, strings.TrimPrefix(authRequestParams, "?")))
+
+			r.False(fallbackHandlerWasCalled)
+
+			// Minimal check to ensure that the right endpoint was called
+			r.Equal(http.StatusCreated, recorder.Code, "unexpected response:", recorder)
+			var body map[string]interface{}
+			r.NoError(json.Unmarshal(recorder.Body.Bytes(), &body))
+			r.Contains(body, "request_uri")
+		}
+
 		requireTokenRequestToBeHandled := func(requestIssuer, authCode string, jwks *jose.JSONWebKeySet, jwkIssuer string) {
 			recorder := httptest.NewRecorder()
 
@@ -433,6 +447,9 @@ func TestManager(t *testing.T) {
 				"redirect_uri":          []string{downstreamRedirectURL},
 			}.Encode()
 
+			requirePushedAuthorizeRequestToBeHandled(issuer1, authRequestParamsIDP1)
+			requirePushedAuthorizeRequestToBeHandled(issuer2DifferentCaseHostname, authRequestParamsIDP2)
+
 			requireAuthorizationRequestToBeHandled(issuer1, authRequestParamsIDP1, upstreamIDPAuthorizationURL1)
 			requireAuthorizationRequestToBeHandled(issuer2, authRequestParamsIDP1, upstreamIDPAuthorizationURL1)
 			requireAuthorizationRequestToBeHandled(issuer1, authRequestParamsIDP2, upstreamIDPAuthorizationURL2)
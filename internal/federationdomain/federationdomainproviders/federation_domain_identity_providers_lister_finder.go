@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package federationdomainproviders
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	"go.pinniped.dev/internal/federationdomain/idplister"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoidc"
@@ -18,17 +19,26 @@ import (
 )
 
 // FederationDomainIdentityProvider represents an identity provider as configured in a FederationDomain's spec.
-// All the fields are required and must be non-zero values. Note that this might be a reference to an IDP
+// All the fields except RequireTOTP, RequireWebAuthn and Routing are required and must be non-zero values. Note that this might be a reference to an IDP
 // which is not currently loaded into the cache of available IDPs, e.g. due to the IDP's CR having validation errors.
 type FederationDomainIdentityProvider struct {
 	DisplayName string
 	UID         types.UID
 	Transforms  *idtransform.TransformationPipeline
+	// RequireTOTP is true when users of this identity provider must also present a TOTP one-time password.
+	// It is only supported for LDAP and ActiveDirectory identity providers.
+	RequireTOTP bool
+	// RequireWebAuthn is true when users of this identity provider must also present a WebAuthn security key or passkey.
+	// It is only supported for LDAP and ActiveDirectory identity providers.
+	RequireWebAuthn bool
+	// Routing is the optional routing rules which send users to this identity provider based on their login hint.
+	Routing *idprouting.Rules
 }
 
 type FederationDomainIdentityProvidersFinderI interface {
 	FindDefaultIDP() (resolvedprovider.FederationDomainResolvedIdentityProvider, error)
 	FindUpstreamIDPByDisplayName(upstreamIDPDisplayName string) (resolvedprovider.FederationDomainResolvedIdentityProvider, error)
+	FindUpstreamIDPByLoginHint(loginHint string) (resolvedprovider.FederationDomainResolvedIdentityProvider, error)
 	HasDefaultIDP() bool
 	IDPCount() int
 }
@@ -119,6 +129,20 @@ func (u *FederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByDisplay
 	return nil, fmt.Errorf("identity provider not available: %q", upstreamIDPDisplayName)
 }
 
+// FindUpstreamIDPByLoginHint selects the first available IDP whose routing rules match the login hint, in the order
+// in which the IDPs are listed on the FederationDomain, or returns an error when there is no such IDP.
+func (u *FederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByLoginHint(loginHint string) (
+	resolvedprovider.FederationDomainResolvedIdentityProvider,
+	error,
+) {
+	for _, p := range u.GetIdentityProviders() {
+		if p.GetRouting().Matches(loginHint) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("identity provider not found: no routing rules match the login hint")
+}
+
 func (u *FederationDomainIdentityProvidersListerFinder) HasDefaultIDP() bool {
 	return u.defaultIdentityProvider != nil
 }
@@ -157,6 +181,7 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 					Provider:            p,
 					SessionProviderType: psession.ProviderTypeOIDC,
 					Transforms:          idp.Transforms,
+					Routing:             idp.Routing,
 				})
 			}
 		}
@@ -169,6 +194,9 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 					Provider:            p,
 					SessionProviderType: psession.ProviderTypeLDAP,
 					Transforms:          idp.Transforms,
+					RequireTOTP:         idp.RequireTOTP,
+					RequireWebAuthn:     idp.RequireWebAuthn,
+					Routing:             idp.Routing,
 				})
 			}
 		}
@@ -181,6 +209,9 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 					Provider:            p,
 					SessionProviderType: psession.ProviderTypeActiveDirectory,
 					Transforms:          idp.Transforms,
+					RequireTOTP:         idp.RequireTOTP,
+					RequireWebAuthn:     idp.RequireWebAuthn,
+					Routing:             idp.Routing,
 				})
 			}
 		}
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package federationdomainproviders
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/federationdomain/idplister"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoidc"
@@ -269,6 +270,91 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 		})
 	}
 
+	exampleDotComRouting, err := idprouting.New([]string{"example.com"}, "")
+	require.NoError(t, err)
+	adminRouting, err := idprouting.New([]string{"admin.example.com"}, `.*\.admin`)
+	require.NoError(t, err)
+	fdIssuerWithRouting, err := NewFederationDomainIssuer(fakeIssuerURL, []*FederationDomainIdentityProvider{
+		{DisplayName: "my-oidc-idp1", UID: "my-oidc-uid-idp1"},
+		{DisplayName: "my-ldap-idp1", UID: "my-ldap-uid-idp1", Routing: adminRouting},
+		{DisplayName: "my-oidc-idp2", UID: "my-oidc-uid-idp2", Routing: exampleDotComRouting},
+		{DisplayName: "my-ad-idp1", UID: "my-ad-uid-idp1", Routing: exampleDotComRouting},
+	})
+	require.NoError(t, err)
+	wrappedListerForRouting := testidplister.NewUpstreamIDPListerBuilder().
+		WithOIDC(myOIDCIDP1).
+		WithOIDC(myOIDCIDP2).
+		WithLDAP(myLDAPIDP1).
+		WithActiveDirectory(myADIDP1).
+		BuildDynamicUpstreamIDPProvider()
+
+	testFindUpstreamIDPByLoginHint := []struct {
+		name               string
+		wrappedLister      idplister.UpstreamIdentityProvidersLister
+		loginHint          string
+		wantIDPDisplayName string
+		wantError          string
+	}{
+		{
+			name:               "FindUpstreamIDPByLoginHint will find an upstream IDP by email domain",
+			wrappedLister:      wrappedListerForRouting,
+			loginHint:          "pinny@admin.example.com",
+			wantIDPDisplayName: "my-ldap-idp1",
+		},
+		{
+			name:               "FindUpstreamIDPByLoginHint will find an upstream IDP by username regex",
+			wrappedLister:      wrappedListerForRouting,
+			loginHint:          "pinny.admin",
+			wantIDPDisplayName: "my-ldap-idp1",
+		},
+		{
+			name:               "FindUpstreamIDPByLoginHint will find the first matching upstream IDP in the order of the FederationDomain",
+			wrappedLister:      wrappedListerForRouting,
+			loginHint:          "pinny@example.com",
+			wantIDPDisplayName: "my-oidc-idp2",
+		},
+		{
+			name: "FindUpstreamIDPByLoginHint will skip matching IDPs which are not available",
+			wrappedLister: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(myOIDCIDP1).
+				WithActiveDirectory(myADIDP1).
+				BuildDynamicUpstreamIDPProvider(),
+			loginHint:          "pinny@example.com",
+			wantIDPDisplayName: "my-ad-idp1",
+		},
+		{
+			name:          "FindUpstreamIDPByLoginHint will error if no routing rules match",
+			wrappedLister: wrappedListerForRouting,
+			loginHint:     "pinny@example.org",
+			wantError:     "identity provider not found: no routing rules match the login hint",
+		},
+		{
+			name:          "FindUpstreamIDPByLoginHint will error if the login hint is empty",
+			wrappedLister: wrappedListerForRouting,
+			loginHint:     "",
+			wantError:     "identity provider not found: no routing rules match the login hint",
+		},
+	}
+
+	for _, tt := range testFindUpstreamIDPByLoginHint {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := NewFederationDomainIdentityProvidersListerFinder(fdIssuerWithRouting, tt.wrappedLister)
+			foundIDP, err := subject.FindUpstreamIDPByLoginHint(tt.loginHint)
+
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				require.Nil(t, foundIDP)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantIDPDisplayName, foundIDP.GetDisplayName())
+			require.NotNil(t, foundIDP.GetRouting())
+		})
+	}
+
 	testFindDefaultIDP := []struct {
 		name                   string
 		wrappedLister          idplister.UpstreamIdentityProvidersLister
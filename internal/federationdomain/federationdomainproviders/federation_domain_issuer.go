@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package federationdomainproviders
@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/endpoints/tokenexchange"
 )
 
 // FederationDomainIssuer is a parsed FederationDomain representing all the settings for a downstream OIDC provider
@@ -24,6 +26,13 @@ type FederationDomainIssuer struct {
 	// are not explicitly specified in the FederationDomain's spec, and there is exactly one IDP CR defined in the
 	// Supervisor's namespace.
 	defaultIdentityProvider *FederationDomainIdentityProvider
+
+	// tokenExchangeAudiences restricts the audiences which may be requested using token exchange. When nil,
+	// any audience which is not reserved may be requested.
+	tokenExchangeAudiences tokenexchange.AllowedAudiences
+
+	// branding customizes the web pages of the FederationDomain. When nil, the default pages are shown.
+	branding *branding.Branding
 }
 
 // NewFederationDomainIssuer returns a FederationDomainIssuer.
@@ -116,3 +125,23 @@ func (p *FederationDomainIssuer) IdentityProviders() []*FederationDomainIdentity
 func (p *FederationDomainIssuer) DefaultIdentityProvider() *FederationDomainIdentityProvider {
 	return p.defaultIdentityProvider
 }
+
+// TokenExchangeAudiences will return nil when any audience which is not reserved may be requested using token exchange.
+func (p *FederationDomainIssuer) TokenExchangeAudiences() tokenexchange.AllowedAudiences {
+	return p.tokenExchangeAudiences
+}
+
+// SetTokenExchangeAudiences restricts the audiences which may be requested using token exchange.
+func (p *FederationDomainIssuer) SetTokenExchangeAudiences(audiences tokenexchange.AllowedAudiences) {
+	p.tokenExchangeAudiences = audiences
+}
+
+// Branding will return nil when the default web pages should be shown.
+func (p *FederationDomainIssuer) Branding() *branding.Branding {
+	return p.branding
+}
+
+// SetBranding customizes the web pages of the FederationDomain.
+func (p *FederationDomainIssuer) SetBranding(b *branding.Branding) {
+	p.branding = b
+}
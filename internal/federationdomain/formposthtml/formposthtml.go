@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package formposthtml defines HTML templates used by the Supervisor.
@@ -11,7 +11,9 @@ import (
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/locale"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -27,22 +29,40 @@ var (
 	//go:embed form_post.gohtml
 	rawHTMLTemplate string
 
-	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-	parsedHTMLTemplate = template.Must(template.New("form_post.gohtml").Funcs(template.FuncMap{
-		"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) },
-		"minifiedJS":  func() template.JS { return template.JS(minifiedJS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
-	}).Parse(rawHTMLTemplate))
+	// Parse the Go templated HTML for each language and inject functions providing the minified inline CSS and JS.
+	parsedHTMLTemplates = parseTemplates(nil)
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
+	cspValue = contentSecurityPolicy(nil)
+)
+
+// parseTemplates parses the template for each supported language with the given branding, which may be nil.
+// The branding and the language are provided by functions instead of by the template's data, because fosite
+// provides the data when it renders the template.
+func parseTemplates(b *branding.Branding) map[string]*template.Template {
+	templates := make(map[string]*template.Template)
+	for _, l := range locale.Supported() {
+		l := l
+		templates[l.Lang()] = template.Must(template.New("form_post.gohtml").Funcs(template.FuncMap{
+			"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) },
+			"minifiedJS":  func() template.JS { return template.JS(minifiedJS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
+			"branding":    func() *branding.Branding { return b },
+			"locale":      func() *locale.Localizer { return l },
+		}).Parse(rawHTMLTemplate))
+	}
+	return templates
+}
+
+func contentSecurityPolicy(b *branding.Branding) string {
+	return strings.Join([]string{
 		`default-src 'none'`,
 		`script-src '` + csp.Hash(minifiedJS) + `'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
+		`style-src ` + strings.Join(append([]string{`'` + csp.Hash(minifiedCSS) + `'`}, b.StyleSources()...), " "),
 		`img-src data:`,
 		`connect-src *`,
 		`frame-ancestors 'none'`,
 	}, "; ")
-)
+}
 
 func panicOnError(s string, err error) string {
 	if err != nil {
@@ -51,10 +71,40 @@ func panicOnError(s string, err error) string {
 	return s
 }
 
-// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly
+// for the given branding, which may be nil.
 //
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
-func ContentSecurityPolicy() string { return cspValue }
+func ContentSecurityPolicy(b *branding.Branding) string {
+	if b == nil {
+		return cspValue
+	}
+	return contentSecurityPolicy(b)
+}
 
-// Template returns the html/template.Template for rendering the response_type=form_post response page.
-func Template() *template.Template { return parsedHTMLTemplate }
+// Template returns the html/template.Template for rendering the response_type=form_post response page
+// in English with the given branding, which may be nil.
+func Template(b *branding.Branding) *template.Template {
+	return Templates(b).Template(nil)
+}
+
+// LocalizedTemplates holds the templates for rendering the response_type=form_post response page
+// in each supported language.
+type LocalizedTemplates struct {
+	templates map[string]*template.Template
+}
+
+// Templates returns the templates for rendering the response_type=form_post response page in each supported
+// language with the given branding, which may be nil.
+func Templates(b *branding.Branding) *LocalizedTemplates {
+	if b == nil {
+		return &LocalizedTemplates{templates: parsedHTMLTemplates}
+	}
+	return &LocalizedTemplates{templates: parseTemplates(b)}
+}
+
+// Template returns the template for the language of the given Localizer. When the Localizer is nil,
+// it returns the template for English.
+func (t *LocalizedTemplates) Template(l *locale.Localizer) *template.Template {
+	return t.templates[l.Lang()]
+}
@@ -1,17 +1,23 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package formposthtml
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/locale"
 	"go.pinniped.dev/internal/here"
 )
 
@@ -76,11 +82,11 @@ var (
 func TestTemplate(t *testing.T) {
 	// Use the Fosite helper to render the form, ensuring that the parameters all have the same names + types.
 	var buf bytes.Buffer
-	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(), &buf)
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(nil), &buf)
 
 	// Render again so we can confirm that there is no error returned (Fosite ignores any error).
 	var buf2 bytes.Buffer
-	require.NoError(t, Template().Execute(&buf2, struct {
+	require.NoError(t, Template(nil).Execute(&buf2, struct {
 		RedirURL   string
 		Parameters url.Values
 	}{
@@ -93,8 +99,77 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, testExpectedFormPostOutput, buf.String())
 }
 
+func TestTemplateWithBranding(t *testing.T) {
+	pngLogo := []byte("\x89PNG\r\n\x1a\n" + "some-png-data")
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{
+		"logo":         base64.StdEncoding.EncodeToString(pngLogo),
+		"primaryColor": "#1a73e8",
+	}})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(b), &buf)
+
+	// The branded page is the default page with the branded CSS and the logo added.
+	logoDataURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngLogo)
+	expectedOutput := strings.Replace(testExpectedFormPostOutput, "</style>\n", "</style><style>"+string(b.CSS())+"</style>\n", 1)
+	expectedOutput = strings.Replace(expectedOutput, "<body>\n", `<body>`+"\n"+`<img class="branding-logo" src="`+logoDataURI+`" alt="Logo">`+"\n", 1)
+	require.Equal(t, expectedOutput, buf.String())
+
+	// The default template is not changed by rendering a branded template.
+	require.NotSame(t, Template(nil), Template(b))
+	buf = bytes.Buffer{}
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, Template(nil), &buf)
+	require.Equal(t, testExpectedFormPostOutput, buf.String())
+}
+
+func TestLocalizedTemplates(t *testing.T) {
+	templates := Templates(nil)
+	require.Same(t, Template(nil), templates.Template(nil))
+	require.Same(t, Template(nil), templates.Template(locale.Default()))
+
+	var buf bytes.Buffer
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, templates.Template(locale.Negotiate("de", "")), &buf)
+
+	// The German page is the default page with all the text translated.
+	expectedOutput := testExpectedFormPostOutput
+	for _, replacement := range [][2]string{
+		{`<html lang="en">`, `<html lang="de">`},
+		{"To finish logging in, paste this authorization code into your command-line session:", "Um die Anmeldung abzuschließen, fügen Sie diesen Autorisierungscode in Ihre Kommandozeilensitzung ein:"},
+		{"Logging in...", "Anmeldung läuft..."},
+		{"Login succeeded", "Anmeldung erfolgreich"},
+		{"You have successfully logged in. You may now close this tab.", "Sie haben sich erfolgreich angemeldet. Sie können diesen Tab jetzt schließen."},
+		{"Finish your login", "Anmeldung abschließen"},
+		{"Error during login", "Fehler bei der Anmeldung"},
+		{"Please try again.", "Bitte versuchen Sie es erneut."},
+	} {
+		require.Contains(t, expectedOutput, replacement[0])
+		expectedOutput = strings.ReplaceAll(expectedOutput, replacement[0], replacement[1])
+	}
+	require.Equal(t, expectedOutput, buf.String())
+
+	// Every language uses the same CSS and JS, so they all work with the same Content-Security-Policy.
+	for _, l := range locale.Supported() {
+		buf = bytes.Buffer{}
+		fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams, templates.Template(l), &buf)
+		require.Contains(t, buf.String(), `<html lang="`+l.Lang()+`">`)
+		require.Contains(t, buf.String(), "<style>"+minifiedCSS+"</style>")
+		require.Contains(t, buf.String(), "<script>"+minifiedJS+"</script>")
+	}
+}
+
 func TestContentSecurityPolicyHashes(t *testing.T) {
-	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy(nil))
+
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{"css": "h1{color:red}"}})
+	require.NoError(t, err)
+	require.Equal(t, `default-src 'none'; `+
+		`script-src 'sha256-fiAdxAQHPoodG4cbENki/1TI+cjBOXxw+ADCoCtepQo='; `+
+		`style-src 'sha256-p+fPKq5SYyVeT46EkDVZx28MRQ6wlWHdDm3o3qZFGTA=' '`+csp.Hash(string(b.CSS()))+`'; `+
+		`img-src data:; `+
+		`connect-src *; `+
+		`frame-ancestors 'none'`,
+		ContentSecurityPolicy(b))
 }
 
 func TestHelpers(t *testing.T) {
@@ -0,0 +1,87 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package idprouting decides which identity provider of a FederationDomain should be used by a user, based on the
+// username or email address that the user entered, or that the client sent in the login_hint param. This allows the
+// Supervisor to skip the IDP chooser page, which is sometimes called "home realm discovery".
+package idprouting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// LoginHintParamName is the OIDC authorization request param with a hint about the identifier that the user
+// might use to log in, e.g. their username or email address.
+// See https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest.
+const LoginHintParamName = "login_hint"
+
+// Rules are the routing rules of an identity provider. A login hint matches the rules when it is an email address
+// in one of the email domains, or when the whole login hint matches the username regular expression.
+type Rules struct {
+	emailDomains          []string
+	usernameRegex         string
+	compiledUsernameRegex *regexp.Regexp
+}
+
+// New validates and returns the Rules. Either of the emailDomains and the usernameRegex may be empty.
+// The email domains are compared case-insensitively. The usernameRegex uses the RE2 syntax of the regexp package
+// and must match the entire login hint.
+func New(emailDomains []string, usernameRegex string) (*Rules, error) {
+	r := &Rules{usernameRegex: usernameRegex}
+
+	for i, domain := range emailDomains {
+		if errs := validation.IsDNS1123Subdomain(strings.ToLower(domain)); len(errs) > 0 {
+			return nil, fmt.Errorf("emailDomains[%d] %q is not a valid domain name: %s", i, domain, strings.Join(errs, "; "))
+		}
+		r.emailDomains = append(r.emailDomains, strings.ToLower(domain))
+	}
+
+	if usernameRegex != "" {
+		compiled, err := regexp.Compile(`^(?:` + usernameRegex + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("usernameRegex is not a valid regular expression: %w", err)
+		}
+		r.compiledUsernameRegex = compiled
+	}
+
+	return r, nil
+}
+
+// EmailDomains returns the email domains, in lowercase. A nil Rules has none.
+func (r *Rules) EmailDomains() []string {
+	if r == nil {
+		return nil
+	}
+	return append([]string(nil), r.emailDomains...)
+}
+
+// UsernameRegex returns the username regular expression as it was configured. A nil Rules has none.
+func (r *Rules) UsernameRegex() string {
+	if r == nil {
+		return ""
+	}
+	return r.usernameRegex
+}
+
+// Matches returns true when the login hint matches the rules. Nothing matches a nil Rules, and an empty login hint
+// does not match any Rules.
+func (r *Rules) Matches(loginHint string) bool {
+	if r == nil || loginHint == "" {
+		return false
+	}
+
+	if at := strings.LastIndex(loginHint, "@"); at > 0 {
+		domain := strings.ToLower(loginHint[at+1:])
+		for _, emailDomain := range r.emailDomains {
+			if domain == emailDomain {
+				return true
+			}
+		}
+	}
+
+	return r.compiledUsernameRegex != nil && r.compiledUsernameRegex.MatchString(loginHint)
+}
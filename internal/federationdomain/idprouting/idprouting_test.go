@@ -0,0 +1,106 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package idprouting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		emailDomains  []string
+		usernameRegex string
+		wantErr       string
+	}{
+		{
+			name: "no rules",
+		},
+		{
+			name:          "valid rules",
+			emailDomains:  []string{"example.com", "Sub.Example.ORG"},
+			usernameRegex: `[a-z]+\.admin`,
+		},
+		{
+			name:         "email domain with an @",
+			emailDomains: []string{"example.com", "@example.org"},
+			wantErr:      `emailDomains[1] "@example.org" is not a valid domain name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:         "empty email domain",
+			emailDomains: []string{""},
+			wantErr:      `emailDomains[0] "" is not a valid domain name: a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`,
+		},
+		{
+			name:          "invalid regex",
+			usernameRegex: `admin(`,
+			wantErr:       "usernameRegex is not a valid regular expression: error parsing regexp: missing closing ): `^(?:admin()$`",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rules, err := New(tt.emailDomains, tt.usernameRegex)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, rules)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.usernameRegex, rules.UsernameRegex())
+			require.Len(t, rules.EmailDomains(), len(tt.emailDomains))
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+
+	rules, err := New([]string{"Example.com", "corp.example.org"}, `[a-z]+\.admin`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"example.com", "corp.example.org"}, rules.EmailDomains())
+
+	emailOnlyRules, err := New([]string{"example.com"}, "")
+	require.NoError(t, err)
+
+	var nilRules *Rules
+
+	tests := []struct {
+		name      string
+		rules     *Rules
+		loginHint string
+		want      bool
+	}{
+		{name: "email domain", rules: rules, loginHint: "pinny@example.com", want: true},
+		{name: "email domain is case-insensitive", rules: rules, loginHint: "pinny@EXAMPLE.com", want: true},
+		{name: "second email domain", rules: rules, loginHint: "pinny@corp.example.org", want: true},
+		{name: "subdomains do not match", rules: rules, loginHint: "pinny@other.example.com", want: false},
+		{name: "other email domain", rules: rules, loginHint: "pinny@example.net", want: false},
+		{name: "only the last @ counts", rules: rules, loginHint: "pinny@example.com@example.net", want: false},
+		{name: "no local part", rules: rules, loginHint: "@example.com", want: false},
+		{name: "username regex", rules: rules, loginHint: "pinny.admin", want: true},
+		{name: "username regex must match the whole login hint", rules: rules, loginHint: "pinny.admin2", want: false},
+		{name: "username regex is case-sensitive", rules: rules, loginHint: "Pinny.admin", want: false},
+		{name: "email domain without a username regex", rules: emailOnlyRules, loginHint: "pinny.admin", want: false},
+		{name: "empty login hint", rules: rules, loginHint: "", want: false},
+		{name: "nil rules", rules: nilRules, loginHint: "pinny@example.com", want: false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, tt.rules.Matches(tt.loginHint))
+		})
+	}
+
+	require.Nil(t, nilRules.EmailDomains())
+	require.Empty(t, nilRules.UsernameRegex())
+}
@@ -0,0 +1,175 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package locale translates the text of the web pages which the Supervisor shows to users during login.
+//
+// The translations are in the catalogs directory, with one JSON file per language, named after the BCP 47
+// language tag of the language. Each file maps message keys to the translated messages, which may contain
+// fmt verbs. To add a language, add a file with a translation of every message of en.json.
+package locale
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	// UILocalesParamName is the OIDC authorization request param with the user's preferred languages, as a space
+	// separated list of BCP 47 language tags, in order of preference.
+	// See https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest.
+	UILocalesParamName = "ui_locales"
+
+	acceptLanguageHeaderName = "Accept-Language"
+
+	catalogsDir = "catalogs"
+)
+
+//nolint:gochecknoglobals // This package uses globals to ensure that all parsing happens at init.
+var (
+	//go:embed catalogs/*.json
+	catalogsFS embed.FS
+
+	english = language.English
+
+	// The localizers of the supported languages, in the same order as the tags of the matcher.
+	localizers = loadCatalogs()
+	matcher    = newMatcher(localizers)
+)
+
+// Localizer translates messages into one language.
+type Localizer struct {
+	tag      language.Tag
+	messages map[string]string
+}
+
+type contextKey struct{}
+
+// loadCatalogs returns the Localizers of all catalogs, with English first.
+func loadCatalogs() []*Localizer {
+	entries, err := catalogsFS.ReadDir(catalogsDir)
+	if err != nil {
+		panic(err)
+	}
+
+	var loaded []*Localizer
+	for _, entry := range entries {
+		tag := language.MustParse(strings.TrimSuffix(entry.Name(), ".json"))
+
+		catalogJSON, err := catalogsFS.ReadFile(path.Join(catalogsDir, entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(catalogJSON, &messages); err != nil {
+			panic(fmt.Errorf("could not parse catalog %s: %w", entry.Name(), err))
+		}
+
+		l := &Localizer{tag: tag, messages: messages}
+		if tag == english {
+			loaded = append([]*Localizer{l}, loaded...)
+		} else {
+			loaded = append(loaded, l)
+		}
+	}
+	if len(loaded) == 0 || loaded[0].tag != english {
+		panic("missing catalog for English")
+	}
+
+	return loaded
+}
+
+// newMatcher returns a matcher for the languages of the localizers. The first language is its fallback.
+func newMatcher(localizers []*Localizer) language.Matcher {
+	tags := make([]language.Tag, 0, len(localizers))
+	for _, l := range localizers {
+		tags = append(tags, l.tag)
+	}
+	return language.NewMatcher(tags)
+}
+
+// Default returns the Localizer for English, which is used when none of the user's preferred languages are supported.
+func Default() *Localizer {
+	return localizers[0]
+}
+
+// Supported returns the Localizers for all supported languages, starting with the Default().
+func Supported() []*Localizer {
+	return append([]*Localizer(nil), localizers...)
+}
+
+// Negotiate returns the Localizer for the supported language which best matches the user's preferences. The languages
+// of the uiLocales, which is the value of the ui_locales param, are preferred over those of the acceptLanguage, which
+// is the value of the Accept-Language header. Both may be empty. Tags which cannot be parsed are ignored.
+func Negotiate(uiLocales string, acceptLanguage string) *Localizer {
+	var preferred []language.Tag
+	for _, s := range strings.Fields(uiLocales) {
+		if tag, err := language.Parse(s); err == nil {
+			preferred = append(preferred, tag)
+		}
+	}
+	if acceptLanguageTags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil {
+		preferred = append(preferred, acceptLanguageTags...)
+	}
+	if len(preferred) == 0 {
+		return Default()
+	}
+
+	_, index, confidence := matcher.Match(preferred...)
+	if confidence == language.No {
+		return Default()
+	}
+	return localizers[index]
+}
+
+// ForRequest returns the Localizer for the user of the request. The authParams are the params of the OIDC authorization
+// request, which may contain the ui_locales param. They may be nil.
+func ForRequest(r *http.Request, authParams url.Values) *Localizer {
+	return Negotiate(authParams.Get(UILocalesParamName), r.Header.Get(acceptLanguageHeaderName))
+}
+
+// NewContext returns a copy of the context which carries the Localizer.
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the Localizer of the context, or nil when the context does not carry one.
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
+
+// Lang returns the BCP 47 language tag of the Localizer's language, which is suitable for the lang attribute of HTML.
+// A nil Localizer is treated as the Default().
+func (l *Localizer) Lang() string {
+	if l == nil {
+		return english.String()
+	}
+	return l.tag.String()
+}
+
+// T returns the translation of the message with the given key, formatted with the args. A message which has not been
+// translated yet falls back to English. A nil Localizer is treated as the Default().
+func (l *Localizer) T(key string, args ...any) string {
+	if l == nil {
+		l = Default()
+	}
+	message, ok := l.messages[key]
+	if !ok {
+		message, ok = Default().messages[key]
+		if !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
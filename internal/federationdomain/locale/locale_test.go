@@ -0,0 +1,142 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package locale
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	t.Parallel()
+
+	fmtVerbs := regexp.MustCompile(`%[a-z]`)
+	english := Default()
+	require.Equal(t, "en", english.Lang())
+
+	for _, l := range Supported() {
+		l := l
+		t.Run(l.Lang(), func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, sortedKeys(english.messages), sortedKeys(l.messages),
+				"each catalog must have the same keys as the English catalog")
+			for key, message := range l.messages {
+				require.NotEmpty(t, message, "message %q is empty", key)
+				require.Equal(t, fmtVerbs.FindAllString(english.messages[key], -1), fmtVerbs.FindAllString(message, -1),
+					"message %q must have the same fmt verbs as the English message", key)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		uiLocales      string
+		acceptLanguage string
+		wantLang       string
+	}{
+		{
+			name:     "no preferences",
+			wantLang: "en",
+		},
+		{
+			name:           "Accept-Language",
+			acceptLanguage: "de-DE,de;q=0.9,en;q=0.8",
+			wantLang:       "de",
+		},
+		{
+			name:           "Accept-Language sorted by quality",
+			acceptLanguage: "en;q=0.5,fr;q=0.9",
+			wantLang:       "fr",
+		},
+		{
+			name:           "ui_locales is preferred over Accept-Language",
+			uiLocales:      "es-MX en",
+			acceptLanguage: "de",
+			wantLang:       "es",
+		},
+		{
+			name:           "unsupported ui_locales falls back to Accept-Language",
+			uiLocales:      "zz",
+			acceptLanguage: "fr-CA",
+			wantLang:       "fr",
+		},
+		{
+			name:           "unsupported languages fall back to English",
+			uiLocales:      "xx",
+			acceptLanguage: "zz",
+			wantLang:       "en",
+		},
+		{
+			name:           "invalid values are ignored",
+			uiLocales:      "!!! de",
+			acceptLanguage: "this is not valid;;;q=",
+			wantLang:       "de",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.wantLang, Negotiate(tt.uiLocales, tt.acceptLanguage).Lang())
+		})
+	}
+}
+
+func TestForRequest(t *testing.T) {
+	t.Parallel()
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	require.Equal(t, "fr", ForRequest(r, nil).Lang())
+	require.Equal(t, "de", ForRequest(r, url.Values{"ui_locales": []string{"de"}}).Lang())
+}
+
+func TestContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	require.Nil(t, FromContext(ctx))
+
+	german := Negotiate("de", "")
+	require.Same(t, german, FromContext(NewContext(ctx, german)))
+}
+
+func TestT(t *testing.T) {
+	t.Parallel()
+
+	german := Negotiate("de", "")
+	require.Equal(t, "Bei My IDP anmelden", german.T("login.heading", "My IDP"))
+	require.Equal(t, "Passwort", german.T("login.password"))
+	require.Equal(t, "no.such.key", german.T("no.such.key"))
+
+	partial := &Localizer{messages: map[string]string{"login.password": "Mot de passe"}}
+	require.Equal(t, "Mot de passe", partial.T("login.password"))
+	require.Equal(t, "Username", partial.T("login.username"), "untranslated messages should fall back to English")
+
+	var nilLocalizer *Localizer
+	require.Equal(t, "en", nilLocalizer.Lang())
+	require.Equal(t, "Log in to My IDP", nilLocalizer.T("login.heading", "My IDP"))
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package oidc contains common OIDC functionality needed by FederationDomains to implement
@@ -6,9 +6,12 @@
 package oidc
 
 import (
+	"context"
 	"crypto/subtle"
 	"fmt"
+	"html/template"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/felixge/httpsnoop"
@@ -17,10 +20,14 @@ import (
 	errorsx "github.com/pkg/errors"
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/clientauthentication"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/federationdomain/endpoints/clientcredentials"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/endpoints/tokenexchange"
 	"go.pinniped.dev/internal/federationdomain/formposthtml"
+	"go.pinniped.dev/internal/federationdomain/locale"
 	"go.pinniped.dev/internal/federationdomain/strategy"
 	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/httputil/httperr"
@@ -31,14 +38,16 @@ import (
 )
 
 const (
-	WellKnownEndpointPath     = "/.well-known/openid-configuration"
-	AuthorizationEndpointPath = "/oauth2/authorize"
-	TokenEndpointPath         = "/oauth2/token" //nolint:gosec // ignore lint warning that this is a credential
-	CallbackEndpointPath      = "/callback"
-	ChooseIDPEndpointPath     = "/choose_identity_provider"
-	JWKSEndpointPath          = "/jwks.json"
-	PinnipedIDPsPathV1Alpha1  = "/v1alpha1/pinniped_identity_providers"
-	PinnipedLoginPath         = "/login"
+	WellKnownEndpointPath       = "/.well-known/openid-configuration"
+	AuthorizationEndpointPath   = "/oauth2/authorize"
+	TokenEndpointPath           = "/oauth2/token" //nolint:gosec // ignore lint warning that this is a credential
+	PushedAuthorizeEndpointPath = "/oauth2/par"
+	CallbackEndpointPath        = "/callback"
+	ChooseIDPEndpointPath       = "/choose_identity_provider"
+	JWKSEndpointPath            = "/jwks.json"
+	PinnipedIDPsPathV1Alpha1    = "/v1alpha1/pinniped_identity_providers"
+	PinnipedLoginPath           = "/login"
+	ConsentEndpointPath         = "/consent"
 )
 
 const (
@@ -64,6 +73,10 @@ const (
 	// cookie contents.
 	CSRFCookieEncodingName = "csrf"
 
+	// PushedAuthorizeRequestURIPrefix is the prefix of the request_uri values which are returned by the pushed
+	// authorization request endpoint. It is the prefix which is suggested by RFC9126.
+	PushedAuthorizeRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
 	// CSRFCookieLifespan is the length of time that the CSRF cookie is valid. After this time, the
 	// Supervisor's authorization endpoint should give the browser a new CSRF cookie. We set it to
 	// a week so that it is unlikely to expire during a login.
@@ -119,6 +132,11 @@ func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
 		OIDCSessionStorageLifetime:              authorizationCodeLifespan + (1 * time.Minute),
 		AccessTokenSessionStorageLifetime:       refreshTokenLifespan + accessTokenLifespan,
 		RefreshTokenSessionStorageLifetime:      refreshTokenLifespan + accessTokenLifespan,
+		ClientAssertionJTIStorageLifetime:       1 * time.Hour,
+		ConsentRequestStorageLifetime:           30 * time.Minute,
+		ConsentGrantStorageLifetime:             30 * 24 * time.Hour,
+		SecondFactorRequestStorageLifetime:      10 * time.Minute,
+		PushedAuthorizeRequestLifespan:          5 * time.Minute,
 	}
 }
 
@@ -128,10 +146,20 @@ func FositeOauth2Helper(
 	hmacSecretOfLengthAtLeast32Func func() []byte,
 	jwksProvider jwks.DynamicJWKSProvider,
 	timeoutsConfiguration timeouts.Configuration,
+	tokenExchangeAudiences tokenexchange.AllowedAudiences,
+	pageBranding *branding.Branding,
 ) fosite.OAuth2Provider {
+	formPostTemplates := formposthtml.Templates(pageBranding)
+
 	oauthConfig := &fosite.Config{
 		IDTokenIssuer: issuer,
 
+		// The audience of the JWTs of clients which use the private_key_jwt client authentication method.
+		TokenURL: issuer + TokenEndpointPath,
+
+		// Fetches the JSON Web Key Sets of clients which use the private_key_jwt client authentication method.
+		JWKSFetcherStrategy: jwksFetcherStrategy(),
+
 		AuthorizeCodeLifespan: timeoutsConfiguration.AuthorizeCodeLifespan,
 		IDTokenLifespan:       timeoutsConfiguration.IDTokenLifespan,
 		AccessTokenLifespan:   timeoutsConfiguration.AccessTokenLifespan,
@@ -154,10 +182,15 @@ func FositeOauth2Helper(
 		RedirectSecureChecker: fosite.IsRedirectURISecureStrict,
 
 		// html template for rendering the authorization response when the request has response_mode=form_post
-		FormPostHTMLTemplate: formposthtml.Template(),
+		// which is rendered in English unless the context of the request has another Localizer (see localizedConfig)
+		FormPostHTMLTemplate: formPostTemplates.Template(nil),
 
 		// defaults to using BCrypt when nil
 		ClientSecretsHasher: nil,
+
+		// The request_uri returned by the pushed authorization request endpoint, and how long it is valid.
+		PushedAuthorizeRequestURIPrefix: PushedAuthorizeRequestURIPrefix,
+		PushedAuthorizeContextLifespan:  timeoutsConfiguration.PushedAuthorizeRequestLifespan,
 	}
 
 	oAuth2Provider := compose.Compose(
@@ -173,12 +206,47 @@ func FositeOauth2Helper(
 		compose.OpenIDConnectExplicitFactory,
 		compose.OpenIDConnectRefreshFactory,
 		compose.OAuth2PKCEFactory,
-		tokenexchange.HandlerFactory, // handle the "urn:ietf:params:oauth:grant-type:token-exchange" grant type
+		compose.PushedAuthorizeHandlerFactory,                // handle pushed authorization requests (RFC9126)
+		tokenexchange.HandlerFactory(tokenExchangeAudiences), // handle the "urn:ietf:params:oauth:grant-type:token-exchange" grant type
+		clientcredentials.HandlerFactory,                     // handle the "client_credentials" grant type
+	)
+
+	// Add support for the tls_client_auth client authentication method, which needs the TLS connection of the request.
+	oauthConfig.ClientAuthenticationStrategy = clientauthentication.NewStrategy(
+		oauthStore.(fosite.ClientManager),
+		oAuth2Provider.(*fosite.Fosite).DefaultClientAuthenticationStrategy,
 	)
 
+	// Render the response_mode=form_post page in the language of the user.
+	oAuth2Provider.(*fosite.Fosite).Config = &localizedConfig{Config: oauthConfig, formPostTemplates: formPostTemplates}
+
 	return oAuth2Provider
 }
 
+// localizedConfig is a fosite.Config which renders the response_mode=form_post page in the language of the
+// Localizer of the context. See LocalizedContext.
+type localizedConfig struct {
+	*fosite.Config
+	formPostTemplates *formposthtml.LocalizedTemplates
+}
+
+func (c *localizedConfig) GetFormPostHTMLTemplate(ctx context.Context) *template.Template {
+	return c.formPostTemplates.Template(locale.FromContext(ctx))
+}
+
+// LocalizedContext returns the context of the request with the Localizer for the user's language, which is
+// chosen by the ui_locales param of the authorization request or by the Accept-Language header of the request.
+// Fosite renders the response_mode=form_post page in that language when it is given this context.
+func LocalizedContext(r *http.Request, authorizeRequester fosite.AuthorizeRequester) context.Context {
+	return locale.NewContext(r.Context(), locale.ForRequest(r, authorizeRequester.GetRequestForm()))
+}
+
+// jwksFetcherStrategy is shared by all FederationDomains, because each fetcher starts its own cache,
+// which would otherwise be leaked whenever the FederationDomains are reconfigured.
+var jwksFetcherStrategy = sync.OnceValue(func() fosite.JWKSFetcherStrategy { //nolint:gochecknoglobals
+	return fosite.NewDefaultJWKSFetcherStrategy()
+})
+
 // FositeErrorForLog generates a list of information about the provided Fosite error that can be
 // passed to a plog function (e.g., plog.Info()).
 //
@@ -223,7 +291,7 @@ func ScopeWasRequested(authorizeRequester fosite.AuthorizeRequester, scopeName s
 }
 
 func ReadStateParamAndValidateCSRFCookie(r *http.Request, cookieDecoder Decoder, stateDecoder Decoder) (string, *UpstreamStateParamData, error) {
-	csrfValue, err := readCSRFCookie(r, cookieDecoder)
+	csrfValue, err := ReadCSRFCookie(r, cookieDecoder)
 	if err != nil {
 		return "", nil, err
 	}
@@ -241,7 +309,8 @@ func ReadStateParamAndValidateCSRFCookie(r *http.Request, cookieDecoder Decoder,
 	return encodedState, decodedState, nil
 }
 
-func readCSRFCookie(r *http.Request, cookieDecoder Decoder) (csrftoken.CSRFToken, error) {
+// ReadCSRFCookie reads and decodes the CSRF cookie which was set by the authorization endpoint.
+func ReadCSRFCookie(r *http.Request, cookieDecoder Decoder) (csrftoken.CSRFToken, error) {
 	receivedCSRFCookie, err := r.Cookie(CSRFCookieName)
 	if err != nil {
 		// Error means that the cookie was not found
@@ -306,7 +375,7 @@ func WriteAuthorizeError(r *http.Request, w http.ResponseWriter, oauthHelper fos
 		w = rewriteStatusSeeOtherToStatusFoundForBrowserless(w)
 	}
 	// Return an error according to OIDC spec 3.1.2.6 (second paragraph).
-	oauthHelper.WriteAuthorizeError(r.Context(), w, authorizeRequester, err)
+	oauthHelper.WriteAuthorizeError(LocalizedContext(r, authorizeRequester), w, authorizeRequester, err)
 }
 
 // PerformAuthcodeRedirect successfully completes a downstream login by creating a session and
@@ -329,7 +398,7 @@ func PerformAuthcodeRedirect(
 	if isBrowserless {
 		w = rewriteStatusSeeOtherToStatusFoundForBrowserless(w)
 	}
-	oauthHelper.WriteAuthorizeResponse(r.Context(), w, authorizeRequester, authorizeResponder)
+	oauthHelper.WriteAuthorizeResponse(LocalizedContext(r, authorizeRequester), w, authorizeRequester, authorizeResponder)
 }
 
 func rewriteStatusSeeOtherToStatusFoundForBrowserless(w http.ResponseWriter) http.ResponseWriter {
@@ -1,12 +1,16 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcclientvalidator
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/go-jose/go-jose/v3"
 	"golang.org/x/crypto/bcrypt"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,17 +23,25 @@ import (
 const (
 	DefaultMinBcryptCost = 12
 
-	clientSecretExists     = "ClientSecretExists"
-	allowedGrantTypesValid = "AllowedGrantTypesValid"
-	allowedScopesValid     = "AllowedScopesValid"
+	clientSecretExists        = "ClientSecretExists"
+	allowedGrantTypesValid    = "AllowedGrantTypesValid"
+	allowedScopesValid        = "AllowedScopesValid"
+	clientAuthenticationValid = "ClientAuthenticationValid"
 
-	reasonSuccess                  = "Success"
-	reasonMissingRequiredValue     = "MissingRequiredValue"
-	reasonNoClientSecretFound      = "NoClientSecretFound"
-	reasonInvalidClientSecretFound = "InvalidClientSecretFound"
+	reasonSuccess                     = "Success"
+	reasonMissingRequiredValue        = "MissingRequiredValue"
+	reasonNoClientSecretFound         = "NoClientSecretFound"
+	reasonInvalidClientSecretFound    = "InvalidClientSecretFound"
+	reasonClientSecretNotRequired     = "ClientSecretNotRequired"
+	reasonInvalidClientAuthentication = "InvalidClientAuthentication"
 
-	allowedGrantTypesFieldName = "allowedGrantTypes"
-	allowedScopesFieldName     = "allowedScopes"
+	allowedGrantTypesFieldName    = "allowedGrantTypes"
+	allowedScopesFieldName        = "allowedScopes"
+	serviceIdentityFieldName      = "serviceIdentity"
+	clientAuthMethodFieldName     = "clientAuthentication.method"
+	clientAuthPrivateKeyJWTName   = "clientAuthentication.privateKeyJWT"
+	clientAuthTLSClientAuthName   = "clientAuthentication.tlsClientAuth"
+	tlsClientAuthSubjectFieldList = "subjectDN, sanDNS, sanURI, or sanEmail"
 )
 
 // Validate validates the OIDCClient and its corresponding client secret storage Secret.
@@ -38,11 +50,24 @@ const (
 // along with a slice of conditions containing more details, and the list of client secrets in the
 // case that the client was valid.
 func Validate(oidcClient *v1alpha1.OIDCClient, secret *corev1.Secret, minBcryptCost int) (bool, []*metav1.Condition, []string) {
-	conds := make([]*metav1.Condition, 0, 3)
+	conds := make([]*metav1.Condition, 0, 4)
 
-	conds, clientSecrets := validateSecret(secret, conds, minBcryptCost)
+	var clientSecrets []string
+	if method := ClientAuthenticationMethod(oidcClient); method == v1alpha1.ClientAuthenticationMethodClientSecretBasic {
+		conds, clientSecrets = validateSecret(secret, conds, minBcryptCost)
+	} else {
+		// Valid: the client does not authenticate using client secrets, so it does not need any.
+		conds = append(conds, &metav1.Condition{
+			Type:    clientSecretExists,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonClientSecretNotRequired,
+			Message: fmt.Sprintf("no client secret is required when %q is %q", clientAuthMethodFieldName, method),
+		})
+		clientSecrets = []string{}
+	}
 	conds = validateAllowedGrantTypes(oidcClient, conds)
 	conds = validateAllowedScopes(oidcClient, conds)
+	conds = validateClientAuthentication(oidcClient, conds)
 
 	valid := true
 	for _, cond := range conds {
@@ -54,6 +79,107 @@ func Validate(oidcClient *v1alpha1.OIDCClient, secret *corev1.Secret, minBcryptC
 	return valid, conds, clientSecrets
 }
 
+// ClientAuthenticationMethod returns the client authentication method of the OIDCClient, including its default.
+func ClientAuthenticationMethod(oidcClient *v1alpha1.OIDCClient) v1alpha1.ClientAuthenticationMethod {
+	if oidcClient.Spec.ClientAuthentication == nil || oidcClient.Spec.ClientAuthentication.Method == "" {
+		return v1alpha1.ClientAuthenticationMethodClientSecretBasic
+	}
+	return oidcClient.Spec.ClientAuthentication.Method
+}
+
+// ParseJWKS parses the inline JSON Web Key Set of the private_key_jwt client authentication method.
+func ParseJWKS(jwks string) (*jose.JSONWebKeySet, error) {
+	keySet := &jose.JSONWebKeySet{}
+	if err := json.Unmarshal([]byte(jwks), keySet); err != nil {
+		return nil, err
+	}
+	for _, key := range keySet.Keys {
+		if key.Use == "sig" && key.IsPublic() {
+			return keySet, nil
+		}
+	}
+	return nil, fmt.Errorf(`must contain at least one public key with "use" set to "sig"`)
+}
+
+// ParseCertificateAuthorityData parses the certificate authorities of the tls_client_auth client authentication method.
+func ParseCertificateAuthorityData(certificateAuthorityData string) (*x509.CertPool, error) {
+	bundle, err := base64.StdEncoding.DecodeString(certificateAuthorityData)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found")
+	}
+	return pool, nil
+}
+
+// validateClientAuthentication checks if clientAuthentication is valid on the OIDCClient.
+func validateClientAuthentication(oidcClient *v1alpha1.OIDCClient, conditions []*metav1.Condition) []*metav1.Condition {
+	m := make([]string, 0, 2)
+
+	method := ClientAuthenticationMethod(oidcClient)
+	var privateKeyJWT *v1alpha1.OIDCClientPrivateKeyJWT
+	var tlsClientAuth *v1alpha1.OIDCClientTLSClientAuth
+	if oidcClient.Spec.ClientAuthentication != nil {
+		privateKeyJWT = oidcClient.Spec.ClientAuthentication.PrivateKeyJWT
+		tlsClientAuth = oidcClient.Spec.ClientAuthentication.TLSClientAuth
+	}
+
+	switch {
+	case method == v1alpha1.ClientAuthenticationMethodPrivateKeyJWT && privateKeyJWT == nil:
+		m = append(m, fmt.Sprintf("%q must be set when %q is %q", clientAuthPrivateKeyJWTName, clientAuthMethodFieldName, method))
+	case method != v1alpha1.ClientAuthenticationMethodPrivateKeyJWT && privateKeyJWT != nil:
+		m = append(m, fmt.Sprintf("%q must only be set when %q is %q",
+			clientAuthPrivateKeyJWTName, clientAuthMethodFieldName, v1alpha1.ClientAuthenticationMethodPrivateKeyJWT))
+	case privateKeyJWT != nil && (privateKeyJWT.JWKS == "") == (privateKeyJWT.JWKSURL == ""):
+		m = append(m, fmt.Sprintf("exactly one of jwks or jwksURL must be set in %q", clientAuthPrivateKeyJWTName))
+	case privateKeyJWT != nil && privateKeyJWT.JWKS != "":
+		if _, err := ParseJWKS(privateKeyJWT.JWKS); err != nil {
+			m = append(m, fmt.Sprintf("%q has invalid jwks: %s", clientAuthPrivateKeyJWTName, err.Error()))
+		}
+	}
+
+	switch {
+	case method == v1alpha1.ClientAuthenticationMethodTLSClientAuth && tlsClientAuth == nil:
+		m = append(m, fmt.Sprintf("%q must be set when %q is %q", clientAuthTLSClientAuthName, clientAuthMethodFieldName, method))
+	case method != v1alpha1.ClientAuthenticationMethodTLSClientAuth && tlsClientAuth != nil:
+		m = append(m, fmt.Sprintf("%q must only be set when %q is %q",
+			clientAuthTLSClientAuthName, clientAuthMethodFieldName, v1alpha1.ClientAuthenticationMethodTLSClientAuth))
+	case tlsClientAuth != nil:
+		if _, err := ParseCertificateAuthorityData(tlsClientAuth.CertificateAuthorityData); err != nil {
+			m = append(m, fmt.Sprintf("%q has invalid certificateAuthorityData: %s", clientAuthTLSClientAuthName, err.Error()))
+		}
+		subjects := 0
+		for _, subject := range []string{tlsClientAuth.SubjectDN, tlsClientAuth.SANDNS, tlsClientAuth.SANURI, tlsClientAuth.SANEmail} {
+			if subject != "" {
+				subjects++
+			}
+		}
+		if subjects != 1 {
+			m = append(m, fmt.Sprintf("exactly one of %s must be set in %q", tlsClientAuthSubjectFieldList, clientAuthTLSClientAuthName))
+		}
+	}
+
+	if len(m) == 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    clientAuthenticationValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonSuccess,
+			Message: fmt.Sprintf("client authentication method %q is valid", method),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    clientAuthenticationValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidClientAuthentication,
+			Message: strings.Join(m, "; "),
+		})
+	}
+
+	return conditions
+}
+
 // validateAllowedScopes checks if allowedScopes is valid on the OIDCClient.
 func validateAllowedScopes(oidcClient *v1alpha1.OIDCClient, conditions []*metav1.Condition) []*metav1.Condition {
 	m := make([]string, 0, 4)
@@ -110,6 +236,14 @@ func validateAllowedGrantTypes(oidcClient *v1alpha1.OIDCClient, conditions []*me
 		m = append(m, fmt.Sprintf("%q must be included in %q when %q is included in %q",
 			oidcapi.GrantTypeTokenExchange, allowedGrantTypesFieldName, oidcapi.ScopeRequestAudience, allowedScopesFieldName))
 	}
+	if allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeClientCredentials) && oidcClient.Spec.ServiceIdentity == nil {
+		m = append(m, fmt.Sprintf("%q must be set when %q is included in %q",
+			serviceIdentityFieldName, oidcapi.GrantTypeClientCredentials, allowedGrantTypesFieldName))
+	}
+	if oidcClient.Spec.ServiceIdentity != nil && !allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeClientCredentials) {
+		m = append(m, fmt.Sprintf("%q must be included in %q when %q is set",
+			oidcapi.GrantTypeClientCredentials, allowedGrantTypesFieldName, serviceIdentityFieldName))
+	}
 
 	if len(m) == 0 {
 		conditions = append(conditions, &metav1.Condition{
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resolvedprovider
@@ -6,10 +6,12 @@ package resolvedprovider
 import (
 	"context"
 	"net/http"
+	"net/url"
 
 	"github.com/ory/fosite"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/psession"
@@ -33,6 +35,11 @@ type Identity struct {
 	// downstream group memberships).
 	UpstreamGroups []string
 
+	// Additional claims (for OIDC) or attributes (for LDAP and Active Directory) from the upstream identity provider,
+	// which are made available to identity transformation expressions. These are saved in the user's session and
+	// reused when identity transformations are reapplied during refreshes. nil means that there are none.
+	UpstreamClaims map[string]interface{}
+
 	// The downstream subject determined for this user in an identity provider-specific way.
 	// Must not be empty.
 	DownstreamSubject string
@@ -53,6 +60,13 @@ type IdentityLoginExtras struct {
 
 	// Login warnings to show the user after they exchange their downstream authcode, if any.
 	Warnings []string
+
+	// RequireTOTP is true when the user must also present a TOTP one-time password before the login may finish.
+	RequireTOTP bool
+
+	// RequireWebAuthn is true when the user must also present a WebAuthn security key or passkey before the login
+	// may finish, which is only possible when logging in using a web browser.
+	RequireWebAuthn bool
 }
 
 // RefreshedIdentity represents the parts of an identity that an identity provider may update
@@ -85,10 +99,13 @@ type RefreshedIdentity struct {
 // the information needed to create the PKCE and nonce parameters for the upstream authorization request. If the
 // upstream authorization request does not allow PKCE, then implementations of
 // FederationDomainResolvedIdentityProvider.UpstreamAuthorizeRedirectURL may choose to ignore that struct field.
+// The AuthParams are the params of the downstream authorization request, which implementations may choose to copy
+// into the upstream authorization request.
 type UpstreamAuthorizeRequestState struct {
 	EncodedStateParam string
 	PKCE              pkce.Code
 	Nonce             nonce.Nonce
+	AuthParams        url.Values
 }
 
 type FederationDomainResolvedIdentityProvider interface {
@@ -113,6 +130,10 @@ type FederationDomainResolvedIdentityProvider interface {
 	// FederationDomain for this identity provider.
 	GetTransforms() *idtransform.TransformationPipeline
 
+	// GetRouting returns the routing rules configured on the FederationDomain for this identity provider,
+	// or nil when there are none.
+	GetRouting() *idprouting.Rules
+
 	// CloneIDPSpecificSessionDataFromSession should reach into the provided session and return a clone
 	// of the field which is specific to the upstream identity provider type. If the session's field is
 	// nil, then return nil.
@@ -1,10 +1,11 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resolvedldap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -15,6 +16,7 @@ import (
 	"go.pinniped.dev/internal/authenticators"
 	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/httputil/httperr"
@@ -33,6 +35,9 @@ type FederationDomainResolvedLDAPIdentityProvider struct {
 	Provider            upstreamprovider.UpstreamLDAPIdentityProviderI
 	SessionProviderType psession.ProviderType
 	Transforms          *idtransform.TransformationPipeline
+	RequireTOTP         bool
+	RequireWebAuthn     bool
+	Routing             *idprouting.Rules
 }
 
 var _ resolvedprovider.FederationDomainResolvedIdentityProvider = (*FederationDomainResolvedLDAPIdentityProvider)(nil)
@@ -64,6 +69,10 @@ func (p *FederationDomainResolvedLDAPIdentityProvider) GetTransforms() *idtransf
 	return p.Transforms
 }
 
+func (p *FederationDomainResolvedLDAPIdentityProvider) GetRouting() *idprouting.Rules {
+	return p.Routing
+}
+
 func (p *FederationDomainResolvedLDAPIdentityProvider) CloneIDPSpecificSessionDataFromSession(session *psession.CustomSessionData) interface{} {
 	switch p.GetSessionProviderType() {
 	case psession.ProviderTypeLDAP:
@@ -119,6 +128,34 @@ var (
 		HintField:        "Username/password not accepted by LDAP provider.",
 		CodeField:        http.StatusForbidden,
 	}
+
+	// ErrAccessDeniedDueToPasswordExpired is returned by Login when the LDAP auth failed because the user's password
+	// has expired. Like ErrAccessDeniedDueToUsernamePasswordNotAccepted, compare to this error using "==".
+	ErrAccessDeniedDueToPasswordExpired = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "Password has expired according to LDAP provider.",
+		CodeField:        http.StatusForbidden,
+	}
+
+	// ErrAccessDeniedDueToPasswordMustChange is returned by Login when the LDAP auth failed because the user must
+	// change their password before logging in, e.g. after an administrator reset it. Compare to this error using "==".
+	ErrAccessDeniedDueToPasswordMustChange = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "Password must be changed according to LDAP provider.",
+		CodeField:        http.StatusForbidden,
+	}
+
+	// ErrAccessDeniedDueToAccountLocked is returned by Login when the LDAP auth failed because the user's account
+	// is locked. This is only returned after the password was verified, so that it does not reveal to anyone who
+	// does not know the password whether an account is locked. Compare to this error using "==".
+	ErrAccessDeniedDueToAccountLocked = &fosite.RFC6749Error{
+		ErrorField:       "access_denied",
+		DescriptionField: "The resource owner or authorization server denied the request.",
+		HintField:        "Account is locked according to LDAP provider.",
+		CodeField:        http.StatusForbidden,
+	}
 )
 
 func (p *FederationDomainResolvedLDAPIdentityProvider) Login(
@@ -127,6 +164,17 @@ func (p *FederationDomainResolvedLDAPIdentityProvider) Login(
 	submittedPassword string,
 ) (*resolvedprovider.Identity, *resolvedprovider.IdentityLoginExtras, error) {
 	authenticateResponse, authenticated, err := p.Provider.AuthenticateUser(ctx, submittedUsername, submittedPassword)
+	switch {
+	case errors.Is(err, authenticators.ErrPasswordExpired):
+		plog.DebugErr("upstream LDAP authentication prevented by password policy", err, "upstreamName", p.Provider.GetName())
+		return nil, nil, ErrAccessDeniedDueToPasswordExpired
+	case errors.Is(err, authenticators.ErrPasswordMustChange):
+		plog.DebugErr("upstream LDAP authentication prevented by password policy", err, "upstreamName", p.Provider.GetName())
+		return nil, nil, ErrAccessDeniedDueToPasswordMustChange
+	case errors.Is(err, authenticators.ErrAccountLocked):
+		plog.DebugErr("upstream LDAP authentication prevented by password policy", err, "upstreamName", p.Provider.GetName())
+		return nil, nil, ErrAccessDeniedDueToAccountLocked
+	}
 	if err != nil {
 		plog.WarningErr("unexpected error during upstream LDAP authentication", err, "upstreamName", p.Provider.GetName())
 		return nil, nil, ErrUnexpectedUpstreamLDAPError.WithWrap(err)
@@ -160,16 +208,32 @@ func (p *FederationDomainResolvedLDAPIdentityProvider) Login(
 	return &resolvedprovider.Identity{
 			UpstreamUsername:       upstreamUsername,
 			UpstreamGroups:         upstreamGroups,
+			UpstreamClaims:         upstreamClaimsFromLDAPAttributes(authenticateResponse.UpstreamAttributes),
 			DownstreamSubject:      subject,
 			IDPSpecificSessionData: sessionData,
 		},
 		&resolvedprovider.IdentityLoginExtras{
 			DownstreamAdditionalClaims: nil,
-			Warnings:                   nil,
+			Warnings:                   authenticateResponse.Warnings,
+			RequireTOTP:                p.RequireTOTP,
+			RequireWebAuthn:            p.RequireWebAuthn,
 		},
 		nil
 }
 
+// upstreamClaimsFromLDAPAttributes makes the additional LDAP attributes available to identity transformation
+// expressions. LDAP attributes may have multiple values, so each attribute's value is always a list of strings.
+func upstreamClaimsFromLDAPAttributes(attributes map[string][]string) map[string]interface{} {
+	if len(attributes) == 0 {
+		return nil
+	}
+	claims := make(map[string]interface{}, len(attributes))
+	for name, values := range attributes {
+		claims[name] = values
+	}
+	return claims
+}
+
 func (p *FederationDomainResolvedLDAPIdentityProvider) LoginFromCallback(
 	_ctx context.Context,
 	_authCode string,
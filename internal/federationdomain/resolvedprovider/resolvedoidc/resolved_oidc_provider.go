@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resolvedoidc
@@ -21,6 +21,7 @@ import (
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/httputil/httperr"
@@ -54,6 +55,7 @@ type FederationDomainResolvedOIDCIdentityProvider struct {
 	Provider            upstreamprovider.UpstreamOIDCIdentityProviderI
 	SessionProviderType psession.ProviderType
 	Transforms          *idtransform.TransformationPipeline
+	Routing             *idprouting.Rules
 }
 
 var _ resolvedprovider.FederationDomainResolvedIdentityProvider = (*FederationDomainResolvedOIDCIdentityProvider)(nil)
@@ -86,6 +88,10 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) GetTransforms() *idtransf
 	return p.Transforms
 }
 
+func (p *FederationDomainResolvedOIDCIdentityProvider) GetRouting() *idprouting.Rules {
+	return p.Routing
+}
+
 func (p *FederationDomainResolvedOIDCIdentityProvider) CloneIDPSpecificSessionDataFromSession(session *psession.CustomSessionData) interface{} {
 	if session.OIDC == nil {
 		return nil
@@ -117,6 +123,14 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) UpstreamAuthorizeRedirect
 		authCodeOptions = append(authCodeOptions, oauth2.SetAuthURLParam(key, val))
 	}
 
+	// Copy the allowed params of the downstream authorization request, e.g. the login_hint.
+	// The watcher has already ensured that these do not overlap with the additional authcode params.
+	for _, name := range p.Provider.GetPassthroughAuthcodeParams() {
+		if val := state.AuthParams.Get(name); val != "" {
+			authCodeOptions = append(authCodeOptions, oauth2.SetAuthURLParam(name, val))
+		}
+	}
+
 	redirectURL := upstreamOAuthConfig.AuthCodeURL(
 		state.EncodedStateParam,
 		authCodeOptions...,
@@ -166,6 +180,7 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) Login(
 	return &resolvedprovider.Identity{
 			UpstreamUsername:       upstreamUsername,
 			UpstreamGroups:         upstreamGroups,
+			UpstreamClaims:         upstreamClaimsForTransformationsFromUpstreamIDToken(p.Provider, token.IDToken.Claims),
 			DownstreamSubject:      subject,
 			IDPSpecificSessionData: oidcSessionData,
 		},
@@ -212,6 +227,7 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) LoginFromCallback(
 	return &resolvedprovider.Identity{
 			UpstreamUsername:       upstreamUsername,
 			UpstreamGroups:         upstreamGroups,
+			UpstreamClaims:         upstreamClaimsForTransformationsFromUpstreamIDToken(p.Provider, token.IDToken.Claims),
 			DownstreamSubject:      subject,
 			IDPSpecificSessionData: oidcSessionData,
 		},
@@ -462,6 +478,25 @@ func mapAdditionalClaimsFromUpstreamIDToken(
 	return mapped
 }
 
+// upstreamClaimsForTransformationsFromUpstreamIDToken returns the upstream claims which should be made available to
+// identity transformation expressions, if any. Claims which are not present in the upstream token are skipped.
+func upstreamClaimsForTransformationsFromUpstreamIDToken(
+	upstreamIDPConfig upstreamprovider.UpstreamOIDCIdentityProviderI,
+	idTokenClaims map[string]interface{},
+) map[string]interface{} {
+	claimNames := upstreamIDPConfig.GetAdditionalClaimsForTransformations()
+	if len(claimNames) == 0 {
+		return nil
+	}
+	claims := make(map[string]interface{}, len(claimNames))
+	for _, claimName := range claimNames {
+		if claimValue, ok := idTokenClaims[claimName]; ok {
+			claims[claimName] = claimValue
+		}
+	}
+	return claims
+}
+
 func getDownstreamSubjectAndUpstreamUsernameFromUpstreamIDToken(
 	upstreamIDPConfig upstreamprovider.UpstreamOIDCIdentityProviderI,
 	idTokenClaims map[string]interface{},
@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resolvedoidc
@@ -70,3 +70,60 @@ func TestMapAdditionalClaimsFromUpstreamIDToken(t *testing.T) {
 		})
 	}
 }
+
+func TestUpstreamClaimsForTransformationsFromUpstreamIDToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		claimNames     []string
+		upstreamClaims map[string]interface{}
+		wantClaims     map[string]interface{}
+	}{
+		{
+			name: "happy path",
+			claimNames: []string{
+				"email_verified", "acr",
+			},
+			upstreamClaims: map[string]interface{}{
+				"sub":            "some-subject",
+				"email_verified": true,
+				"acr":            "phr",
+			},
+			wantClaims: map[string]interface{}{
+				"email_verified": true,
+				"acr":            "phr",
+			},
+		},
+		{
+			name: "missing",
+			claimNames: []string{
+				"email_verified",
+			},
+			upstreamClaims: map[string]interface{}{
+				"sub": "some-subject",
+			},
+			wantClaims: map[string]interface{}{},
+		},
+		{
+			name: "none configured",
+			upstreamClaims: map[string]interface{}{
+				"sub":            "some-subject",
+				"email_verified": true,
+			},
+			wantClaims: nil,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			idp := oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+				WithAdditionalClaimsForTransformations(test.claimNames).
+				Build()
+			actual := upstreamClaimsForTransformationsFromUpstreamIDToken(idp, test.upstreamClaims)
+
+			require.Equal(t, test.wantClaims, actual)
+		})
+	}
+}
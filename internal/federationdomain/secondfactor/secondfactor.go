@@ -0,0 +1,231 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secondfactor checks the second authentication factor which users must present in addition to their
+// password, when it is required by the FederationDomain for their identity provider. The second factor is either
+// a TOTP one-time password from an authenticator app, or a WebAuthn security key or passkey.
+package secondfactor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/federationdomain/webauthn"
+	"go.pinniped.dev/internal/fositestorage/secondfactorrequest"
+	"go.pinniped.dev/internal/fositestorage/totpenrollment"
+	"go.pinniped.dev/internal/fositestorage/webauthnenrollment"
+)
+
+const (
+	// ErrNotEnrolled is returned when the user has not enrolled an authenticator app yet.
+	ErrNotEnrolled = constable.Error("user has not enrolled an authenticator app")
+
+	// ErrIncorrectPassword is returned when the one-time password was not accepted.
+	ErrIncorrectPassword = constable.Error("incorrect one-time password")
+
+	// ErrSecurityKeyNotAccepted is returned when the response of the user's security key or passkey was not accepted.
+	ErrSecurityKeyNotAccepted = webauthn.ErrNotAccepted
+
+	// ErrTooManyAttempts is returned when the user entered too many incorrect one-time passwords recently,
+	// so no one-time password is checked until their lockout ends.
+	ErrTooManyAttempts = constable.Error("too many incorrect one-time passwords")
+
+	// MaxFailedAttempts is how many incorrect one-time passwords a user may enter on the login page before
+	// they must start over by entering their password again. It is also how many incorrect one-time passwords
+	// a user may enter in a row, across all logins, before they are locked out for a while.
+	MaxFailedAttempts = 5
+
+	// initialLockout is how long a user is locked out after their first MaxFailedAttempts incorrect one-time passwords.
+	initialLockout = time.Minute
+	// maxLockout is the longest that a user who keeps entering incorrect one-time passwords is locked out.
+	maxLockout = time.Hour
+)
+
+// Storage holds the TOTP and WebAuthn enrollments of users and the authorization requests which are waiting for
+// users to present their second factor on the login page.
+type Storage struct {
+	Enrollments         totpenrollment.Storage
+	WebAuthnEnrollments webauthnenrollment.Storage
+	Requests            secondfactorrequest.Storage
+	clock               func() time.Time
+}
+
+func NewStorage(secrets corev1client.SecretInterface, timeoutsConfiguration timeouts.Configuration) *Storage {
+	return &Storage{
+		Enrollments:         totpenrollment.New(secrets, time.Now),
+		WebAuthnEnrollments: webauthnenrollment.New(secrets, time.Now),
+		Requests:            secondfactorrequest.New(secrets, time.Now, timeoutsConfiguration.SecondFactorRequestStorageLifetime),
+		clock:               time.Now,
+	}
+}
+
+// IsEnrolled returns true when the user has enrolled an authenticator app.
+func (s *Storage) IsEnrolled(ctx context.Context, subject string) (bool, error) {
+	enrollment, _, err := s.Enrollments.GetEnrollment(ctx, subject)
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil, nil
+}
+
+// VerifyTOTP checks the one-time password against the authenticator app which the user has enrolled.
+// It returns ErrNotEnrolled when the user has not enrolled an authenticator app, and ErrIncorrectPassword
+// when the one-time password was not accepted. Each one-time password is only accepted once.
+//
+// Incorrect one-time passwords are counted per user, no matter which login they came from. After every
+// MaxFailedAttempts incorrect one-time passwords in a row, the user is locked out for a while, which doubles
+// each time up to maxLockout. ErrTooManyAttempts is returned during a lockout, without checking the password.
+func (s *Storage) VerifyTOTP(ctx context.Context, subject string, password string) error {
+	enrollment, resourceVersion, err := s.Enrollments.GetEnrollment(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return ErrNotEnrolled
+	}
+
+	now := s.clock()
+	if now.Before(enrollment.LockedUntil) {
+		return ErrTooManyAttempts
+	}
+
+	timeStep, valid, err := totp.Validate(enrollment.Secret, password, now, enrollment.LastUsedTimeStep)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		enrollment.FailedAttempts++
+		if enrollment.FailedAttempts%MaxFailedAttempts == 0 {
+			lockouts := enrollment.FailedAttempts / MaxFailedAttempts
+			lockout := maxLockout
+			if lockouts <= 6 { // avoid overflowing the shift
+				lockout = min(initialLockout<<(lockouts-1), maxLockout)
+			}
+			enrollment.LockedUntil = now.Add(lockout)
+		}
+		// The failed attempt must be recorded before telling the user that the password was incorrect. Otherwise,
+		// concurrent guesses could read the same enrollment and avoid being counted.
+		if err := s.Enrollments.UpdateEnrollment(ctx, enrollment, resourceVersion); err != nil {
+			return err
+		}
+		return ErrIncorrectPassword
+	}
+
+	// Remember the time step, so the same one-time password cannot be used again. The update fails when another
+	// login has updated the enrollment since it was read, so two concurrent logins cannot use the same password.
+	enrollment.LastUsedTimeStep = timeStep
+	enrollment.FailedAttempts = 0
+	enrollment.LockedUntil = time.Time{}
+	if err := s.Enrollments.UpdateEnrollment(ctx, enrollment, resourceVersion); err != nil {
+		return err
+	}
+	return nil
+}
+
+// EnrollTOTP enrolls the user's authenticator app, after checking that the app generated the one-time password
+// from the given secret. It returns ErrIncorrectPassword when the one-time password was not accepted.
+func (s *Storage) EnrollTOTP(ctx context.Context, subject string, secret string, password string) error {
+	timeStep, valid, err := totp.Validate(secret, password, s.clock(), 0)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrIncorrectPassword
+	}
+
+	return s.Enrollments.CreateEnrollment(ctx, &totpenrollment.Enrollment{
+		Subject:          subject,
+		Secret:           secret,
+		LastUsedTimeStep: timeStep,
+	})
+}
+
+// WebAuthnCredentialID returns the ID of the credential of the security key or passkey which the user has registered,
+// or nil when the user has not registered one.
+func (s *Storage) WebAuthnCredentialID(ctx context.Context, subject string) ([]byte, error) {
+	enrollment, _, err := s.WebAuthnEnrollments.GetEnrollment(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, nil
+	}
+	return enrollment.CredentialID, nil
+}
+
+// VerifyWebAuthn checks the response of the user's security key or passkey to the challenge. It returns ErrNotEnrolled
+// when the user has not registered a security key or passkey, and an error which wraps ErrSecurityKeyNotAccepted when
+// the response was not accepted.
+func (s *Storage) VerifyWebAuthn(
+	ctx context.Context,
+	rp *webauthn.RelyingParty,
+	subject string,
+	challenge string,
+	response *webauthn.AssertionResponse,
+) error {
+	enrollment, resourceVersion, err := s.WebAuthnEnrollments.GetEnrollment(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if enrollment == nil {
+		return ErrNotEnrolled
+	}
+
+	signCount, err := rp.VerifyAssertion(challenge, &webauthn.Credential{
+		ID:        enrollment.CredentialID,
+		PublicKey: enrollment.PublicKey,
+		SignCount: enrollment.SignCount,
+	}, response)
+	if err != nil {
+		return err
+	}
+
+	// Remember the signature counter, so a cloned authenticator can be detected. The update fails when another
+	// login has updated the enrollment since it was read, so two concurrent logins cannot report the same counter.
+	enrollment.SignCount = signCount
+	return s.WebAuthnEnrollments.UpdateEnrollment(ctx, enrollment, resourceVersion)
+}
+
+// EnrollWebAuthn registers the user's security key or passkey, after checking its response to the challenge.
+// It returns an error which wraps ErrSecurityKeyNotAccepted when the response was not accepted.
+func (s *Storage) EnrollWebAuthn(
+	ctx context.Context,
+	rp *webauthn.RelyingParty,
+	subject string,
+	challenge string,
+	response *webauthn.RegistrationResponse,
+) error {
+	credential, err := rp.VerifyRegistration(challenge, response)
+	if err != nil {
+		return err
+	}
+
+	return s.WebAuthnEnrollments.CreateEnrollment(ctx, &webauthnenrollment.Enrollment{
+		Subject:      subject,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		SignCount:    credential.SignCount,
+	})
+}
+
+// GenerateRequestID generates the random ID of a new second factor request.
+func GenerateRequestID() (string, error) {
+	return generateRequestIDFromReader(rand.Reader)
+}
+
+func generateRequestIDFromReader(entropySource io.Reader) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(entropySource, buf); err != nil {
+		return "", fmt.Errorf("could not generate second factor request ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
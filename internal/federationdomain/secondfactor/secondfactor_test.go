@@ -0,0 +1,221 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package secondfactor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/totp"
+	"go.pinniped.dev/internal/federationdomain/webauthn"
+	"go.pinniped.dev/internal/fositestorage/totpenrollment"
+	"go.pinniped.dev/internal/testutil/webauthntestutil"
+)
+
+const (
+	testSubject = "ldaps://ldap.example.com?base=ou%3Dusers&idpName=my-ldap&sub=some-uid"
+	testSecret  = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+)
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestEnrollAndVerifyTOTP(t *testing.T) {
+	ctx := context.Background()
+	storage := makeTestSubject()
+
+	codeAt := func(t *testing.T, offset time.Duration) string {
+		code, err := totp.Code(testSecret, totp.TimeStep(fakeNow.Add(offset)))
+		require.NoError(t, err)
+		return code
+	}
+
+	enrolled, err := storage.IsEnrolled(ctx, testSubject)
+	require.NoError(t, err)
+	require.False(t, enrolled)
+
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, 0)), ErrNotEnrolled)
+
+	// Enrolling requires a correct one-time password.
+	require.ErrorIs(t, storage.EnrollTOTP(ctx, testSubject, testSecret, "000000"), ErrIncorrectPassword)
+	enrolled, err = storage.IsEnrolled(ctx, testSubject)
+	require.NoError(t, err)
+	require.False(t, enrolled)
+
+	require.NoError(t, storage.EnrollTOTP(ctx, testSubject, testSecret, codeAt(t, 0)))
+	enrolled, err = storage.IsEnrolled(ctx, testSubject)
+	require.NoError(t, err)
+	require.True(t, enrolled)
+
+	enrollment, _, err := storage.Enrollments.GetEnrollment(ctx, testSubject)
+	require.NoError(t, err)
+	require.Equal(t, &totpenrollment.Enrollment{
+		Subject:          testSubject,
+		Secret:           testSecret,
+		LastUsedTimeStep: totp.TimeStep(fakeNow),
+		Version:          "1",
+	}, enrollment)
+
+	// The password which was used to enroll cannot be used again.
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, 0)), ErrIncorrectPassword)
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, "000000"), ErrIncorrectPassword)
+
+	// The password of the next time step is accepted once.
+	require.NoError(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, totp.Period)))
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, totp.Period)), ErrIncorrectPassword)
+
+	enrollment, _, err = storage.Enrollments.GetEnrollment(ctx, testSubject)
+	require.NoError(t, err)
+	require.Equal(t, totp.TimeStep(fakeNow.Add(totp.Period)), enrollment.LastUsedTimeStep)
+
+	// Other users are not enrolled.
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, "some-other-subject", codeAt(t, 0)), ErrNotEnrolled)
+}
+
+func TestVerifyTOTPLockout(t *testing.T) {
+	ctx := context.Background()
+	storage := makeTestSubject()
+	now := fakeNow
+	storage.clock = func() time.Time { return now }
+
+	codeAt := func(t *testing.T, at time.Time) string {
+		code, err := totp.Code(testSecret, totp.TimeStep(at))
+		require.NoError(t, err)
+		return code
+	}
+
+	require.NoError(t, storage.EnrollTOTP(ctx, testSubject, testSecret, codeAt(t, now)))
+
+	failAttempts := func(t *testing.T, count int) {
+		for i := 0; i < count; i++ {
+			require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, "000000"), ErrIncorrectPassword)
+		}
+	}
+
+	requireEnrollment := func(t *testing.T, wantFailedAttempts int, wantLockedUntil time.Time) {
+		enrollment, _, err := storage.Enrollments.GetEnrollment(ctx, testSubject)
+		require.NoError(t, err)
+		require.Equal(t, wantFailedAttempts, enrollment.FailedAttempts)
+		require.True(t, wantLockedUntil.Equal(enrollment.LockedUntil),
+			"wanted locked until %s but got %s", wantLockedUntil, enrollment.LockedUntil)
+	}
+
+	// Failed attempts are counted, but the user is not locked out yet.
+	failAttempts(t, MaxFailedAttempts-1)
+	requireEnrollment(t, MaxFailedAttempts-1, time.Time{})
+
+	// The next failed attempt locks out the user, even when the next one-time password is correct.
+	failAttempts(t, 1)
+	requireEnrollment(t, MaxFailedAttempts, fakeNow.Add(time.Minute))
+	now = now.Add(totp.Period)
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, now)), ErrTooManyAttempts)
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, "000000"), ErrTooManyAttempts)
+	requireEnrollment(t, MaxFailedAttempts, fakeNow.Add(time.Minute))
+
+	// After the lockout, the user may try again, and the next lockout is twice as long.
+	now = fakeNow.Add(time.Minute)
+	failAttempts(t, MaxFailedAttempts)
+	requireEnrollment(t, 2*MaxFailedAttempts, now.Add(2*time.Minute))
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, now)), ErrTooManyAttempts)
+
+	// Lockouts never last longer than an hour.
+	for i := 0; i < 10; i++ {
+		now = now.Add(time.Hour)
+		failAttempts(t, MaxFailedAttempts)
+	}
+	requireEnrollment(t, 12*MaxFailedAttempts, now.Add(time.Hour))
+
+	// A correct one-time password after the lockout resets the failed attempts.
+	now = now.Add(time.Hour)
+	failAttempts(t, 2)
+	require.NoError(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, now)))
+	requireEnrollment(t, 0, time.Time{})
+
+	// Locking out one user does not lock out other users.
+	require.NoError(t, storage.EnrollTOTP(ctx, "some-other-subject", testSecret, codeAt(t, now)))
+	failAttempts(t, MaxFailedAttempts)
+	now = now.Add(totp.Period)
+	require.ErrorIs(t, storage.VerifyTOTP(ctx, testSubject, codeAt(t, now)), ErrTooManyAttempts)
+	require.NoError(t, storage.VerifyTOTP(ctx, "some-other-subject", codeAt(t, now)))
+}
+
+func TestEnrollAndVerifyWebAuthn(t *testing.T) {
+	ctx := context.Background()
+	storage := makeTestSubject()
+	rp := &webauthn.RelyingParty{ID: "issuer.example.com", Origin: "https://issuer.example.com"}
+	authenticator := webauthntestutil.NewAuthenticator(t)
+
+	assert := func(challenge string) *webauthn.AssertionResponse {
+		clientDataJSON, authData, signature := authenticator.Assert(t, rp.Origin, rp.ID, challenge)
+		return &webauthn.AssertionResponse{
+			CredentialID:      authenticator.CredentialID,
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+		}
+	}
+
+	credentialID, err := storage.WebAuthnCredentialID(ctx, testSubject)
+	require.NoError(t, err)
+	require.Nil(t, credentialID)
+
+	require.ErrorIs(t, storage.VerifyWebAuthn(ctx, rp, testSubject, "some-challenge", assert("some-challenge")), ErrNotEnrolled)
+
+	// Registering requires a response to the same challenge.
+	clientDataJSON, attestationObject := authenticator.Register(t, rp.Origin, rp.ID, "registration-challenge")
+	registration := &webauthn.RegistrationResponse{ClientDataJSON: clientDataJSON, AttestationObject: attestationObject}
+	require.ErrorIs(t, storage.EnrollWebAuthn(ctx, rp, testSubject, "other-challenge", registration), ErrSecurityKeyNotAccepted)
+	credentialID, err = storage.WebAuthnCredentialID(ctx, testSubject)
+	require.NoError(t, err)
+	require.Nil(t, credentialID)
+
+	require.NoError(t, storage.EnrollWebAuthn(ctx, rp, testSubject, "registration-challenge", registration))
+	credentialID, err = storage.WebAuthnCredentialID(ctx, testSubject)
+	require.NoError(t, err)
+	require.Equal(t, authenticator.CredentialID, credentialID)
+
+	// The signature counter is remembered, so the same response cannot be used twice.
+	response := assert("login-challenge")
+	require.ErrorIs(t, storage.VerifyWebAuthn(ctx, rp, testSubject, "other-challenge", response), ErrSecurityKeyNotAccepted)
+	require.NoError(t, storage.VerifyWebAuthn(ctx, rp, testSubject, "login-challenge", response))
+	err = storage.VerifyWebAuthn(ctx, rp, testSubject, "login-challenge", response)
+	require.EqualError(t, err, "security key response not accepted: signature counter 2 is not greater than 2")
+
+	enrollment, _, err := storage.WebAuthnEnrollments.GetEnrollment(ctx, testSubject)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), enrollment.SignCount)
+
+	// Users who have registered a security key are not enrolled in TOTP, and the other way around.
+	enrolled, err := storage.IsEnrolled(ctx, testSubject)
+	require.NoError(t, err)
+	require.False(t, enrolled)
+}
+
+func TestEnrollTOTPWithInvalidSecret(t *testing.T) {
+	storage := makeTestSubject()
+	err := storage.EnrollTOTP(context.Background(), testSubject, "not base32!", "123456")
+	require.EqualError(t, err, "could not decode TOTP secret: illegal base32 data at input byte 3")
+}
+
+func TestGenerateRequestID(t *testing.T) {
+	id, err := GenerateRequestID()
+	require.NoError(t, err)
+	require.Len(t, id, 64)
+
+	var empty bytes.Buffer
+	id, err = generateRequestIDFromReader(&empty)
+	require.EqualError(t, err, "could not generate second factor request ID: EOF")
+	require.Empty(t, id)
+}
+
+func makeTestSubject() *Storage {
+	storage := NewStorage(fake.NewSimpleClientset().CoreV1().Secrets("test-ns"), oidc.DefaultOIDCTimeoutsConfiguration())
+	storage.clock = func() time.Time { return fakeNow }
+	return storage
+}
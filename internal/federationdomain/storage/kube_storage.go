@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package storage
@@ -18,8 +18,10 @@ import (
 	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizerequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
@@ -32,6 +34,8 @@ type KubeStorage struct {
 	oidcStorage              openid.OpenIDConnectRequestStorage
 	accessTokenStorage       accesstoken.RevocationStorage
 	refreshTokenStorage      refreshtoken.RevocationStorage
+	clientAssertionStorage   clientassertion.Storage
+	parStorage               fosite.PARStorage
 }
 
 var _ fositestoragei.AllFositeStorage = &KubeStorage{}
@@ -50,6 +54,8 @@ func NewKubeStorage(
 		oidcStorage:              openidconnect.New(secrets, nowFunc, timeoutsConfiguration.OIDCSessionStorageLifetime),
 		accessTokenStorage:       accesstoken.New(secrets, nowFunc, timeoutsConfiguration.AccessTokenSessionStorageLifetime),
 		refreshTokenStorage:      refreshtoken.New(secrets, nowFunc, timeoutsConfiguration.RefreshTokenSessionStorageLifetime),
+		clientAssertionStorage:   clientassertion.New(secrets, nowFunc, timeoutsConfiguration.ClientAssertionJTIStorageLifetime),
+		parStorage:               pushedauthorizerequest.New(secrets, nowFunc, timeoutsConfiguration.PushedAuthorizeRequestLifespan),
 	}
 }
 
@@ -197,10 +203,42 @@ func (k KubeStorage) GetClient(ctx context.Context, id string) (fosite.Client, e
 	return k.clientManager.GetClient(ctx, id)
 }
 
+//
+// Client assertion JWTs:
+//
+// These are keyed by a hash of the ID of the JWT.
+//
+// Fosite will check and create these in the token endpoint when a client authenticates with the private_key_jwt
+// method, to make sure that each JWT is only used once. They are never deleted, but they will be garbage collected
+// after the JWT has expired.
+//
+
 func (k KubeStorage) ClientAssertionJWTValid(ctx context.Context, jti string) error {
-	return k.clientManager.ClientAssertionJWTValid(ctx, jti)
+	return k.clientAssertionStorage.ClientAssertionJWTValid(ctx, jti)
 }
 
 func (k KubeStorage) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
-	return k.clientManager.SetClientAssertionJWT(ctx, jti, exp)
+	return k.clientAssertionStorage.SetClientAssertionJWT(ctx, jti, exp)
+}
+
+//
+// Pushed authorization requests:
+//
+// These are keyed by a hash of the request_uri which is returned to the client.
+//
+// Fosite will create these in the pushed authorization request endpoint. The authorization endpoint reads and deletes
+// them when the user's browser brings the request_uri to the authorization endpoint, so each request_uri can only be
+// used once. If the browser never arrives, then they will be garbage collected after they expire.
+//
+
+func (k KubeStorage) CreatePARSession(ctx context.Context, requestURI string, request fosite.AuthorizeRequester) error {
+	return k.parStorage.CreatePARSession(ctx, requestURI, request)
+}
+
+func (k KubeStorage) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	return k.parStorage.GetPARSession(ctx, requestURI)
+}
+
+func (k KubeStorage) DeletePARSession(ctx context.Context, requestURI string) error {
+	return k.parStorage.DeletePARSession(ctx, requestURI)
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package storage
@@ -106,3 +106,15 @@ func (NullStorage) GetAuthorizeCodeSession(_ context.Context, _ string, _ fosite
 func (NullStorage) InvalidateAuthorizeCodeSession(_ context.Context, _ string) (err error) {
 	return errNullStorageNotImplemented
 }
+
+func (NullStorage) CreatePARSession(_ context.Context, _ string, _ fosite.AuthorizeRequester) error {
+	return errNullStorageNotImplemented
+}
+
+func (NullStorage) GetPARSession(_ context.Context, _ string) (fosite.AuthorizeRequester, error) {
+	return nil, errNullStorageNotImplemented
+}
+
+func (NullStorage) DeletePARSession(_ context.Context, _ string) error {
+	return errNullStorageNotImplemented
+}
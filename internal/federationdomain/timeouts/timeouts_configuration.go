@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package timeouts
@@ -71,4 +71,29 @@ type Configuration struct {
 	// when the token does not exist. If this is desirable, then the RefreshTokenSessionStorageLifetime can be made
 	// to be significantly larger than RefreshTokenLifespan, at the cost of slower cleanup.
 	RefreshTokenSessionStorageLifetime time.Duration
+
+	// ClientAssertionJTIStorageLifetime is the length of time after which the ID of a client assertion JWT, which was
+	// used by a client to authenticate with the private_key_jwt method, is allowed to be garbage collected from storage.
+	// The ID must be remembered until the JWT expires to prevent it from being used again. Therefore, client assertions
+	// which would expire after this length of time are rejected.
+	ClientAssertionJTIStorageLifetime time.Duration
+
+	// ConsentRequestStorageLifetime is the length of time after which an authorization request which is waiting
+	// for the user to approve it on the consent page is allowed to be garbage collected from storage.
+	// The user must approve or deny the request before this length of time has passed.
+	ConsentRequestStorageLifetime time.Duration
+
+	// ConsentGrantStorageLifetime is the length of time for which the supervisor remembers the scopes which a user
+	// has approved for a client on the consent page. After this length of time, the user will be asked again.
+	ConsentGrantStorageLifetime time.Duration
+
+	// SecondFactorRequestStorageLifetime is the length of time after which an authorization request which is waiting
+	// for the user to present their second authentication factor on the login page is allowed to be garbage collected
+	// from storage. The user must present their second factor before this length of time has passed.
+	SecondFactorRequestStorageLifetime time.Duration
+
+	// PushedAuthorizeRequestLifespan is how long the request_uri which is returned by the pushed authorization request
+	// endpoint is valid. The client must send the user to the authorization endpoint with the request_uri before
+	// it expires. It is also the length of time after which the pushed params are allowed to be garbage collected.
+	PushedAuthorizeRequestLifespan time.Duration
 }
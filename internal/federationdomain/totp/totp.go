@@ -0,0 +1,130 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package totp implements the time-based one-time passwords of RFC 6238, which users generate with
+// authenticator apps to present a second authentication factor.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 uses HMAC-SHA1 by default, and it is the only algorithm supported by all authenticator apps
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Period is the length of each time step.
+	Period = 30 * time.Second
+
+	// Digits is the number of digits of each one-time password.
+	Digits = 6
+
+	// digitsModulus is 10 to the power of Digits.
+	digitsModulus = 1_000_000
+
+	// secretLength is the number of random bytes of each secret, as recommended by RFC 4226.
+	secretLength = 20
+
+	// allowedSkew is how many time steps before and after the current time step are also accepted,
+	// to tolerate the clock of the user's authenticator app being slightly off, as recommended by RFC 6238.
+	allowedSkew = 1
+)
+
+//nolint:gochecknoglobals // This is effectively a constant.
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret generates a new random secret, encoded in base32 as expected by authenticator apps.
+func GenerateSecret() (string, error) { return generateSecret(rand.Reader) }
+
+func generateSecret(entropySource io.Reader) (string, error) {
+	buf := make([]byte, secretLength)
+	if _, err := io.ReadFull(entropySource, buf); err != nil {
+		return "", fmt.Errorf("could not generate TOTP secret: %w", err)
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// TimeStep returns the time step which contains the given time.
+func TimeStep(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the one-time password of the secret for the given time step.
+func Code(secret string, timeStep int64) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, timeStep), nil
+}
+
+// Validate checks the one-time password against the secret, accepting the passwords of the time step of now and
+// of its adjacent time steps. Passwords of time steps which are not after lastUsedTimeStep are rejected, so each
+// password can only be used once. On success, it returns the time step of the password, which should be remembered
+// as the new lastUsedTimeStep.
+func Validate(secret string, password string, now time.Time, lastUsedTimeStep int64) (int64, bool, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	password = strings.TrimSpace(password)
+	if len(password) != Digits {
+		return 0, false, nil
+	}
+
+	currentTimeStep := TimeStep(now)
+	for timeStep := currentTimeStep - allowedSkew; timeStep <= currentTimeStep+allowedSkew; timeStep++ {
+		if timeStep <= lastUsedTimeStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(code(key, timeStep)), []byte(password)) == 1 {
+			return timeStep, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// KeyURI returns the otpauth:// URI of the secret, which authenticator apps accept to enroll it.
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format.
+func KeyURI(issuer string, accountName string, secret string) string {
+	query := url.Values{
+		"secret":    []string{secret},
+		"issuer":    []string{issuer},
+		"algorithm": []string{"SHA1"},
+		"digits":    []string{strconv.Itoa(Digits)},
+		"period":    []string{strconv.Itoa(int(Period / time.Second))},
+	}
+	return "otpauth://totp/" + url.PathEscape(issuer) + ":" + url.PathEscape(accountName) + "?" + query.Encode()
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// code implements the HOTP algorithm of RFC 4226, using the time step as the counter.
+func code(key []byte, timeStep int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(timeStep))
+
+	mac := hmac.New(sha1.New, key)
+	_, _ = mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	truncated := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, truncated%digitsModulus)
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package totp
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// The ASCII secret "12345678901234567890" of the test vectors of RFC 6238, encoded in base32.
+const rfcTestSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The test vectors for SHA1 from Appendix B of RFC 6238, truncated to 6 digits.
+	tests := []struct {
+		unixTime int64
+		want     string
+	}{
+		{unixTime: 59, want: "287082"},
+		{unixTime: 1111111109, want: "081804"},
+		{unixTime: 1111111111, want: "050471"},
+		{unixTime: 1234567890, want: "005924"},
+		{unixTime: 2000000000, want: "279037"},
+		{unixTime: 20000000000, want: "353130"},
+	}
+	for _, test := range tests {
+		got, err := Code(rfcTestSecret, TimeStep(time.Unix(test.unixTime, 0)))
+		require.NoError(t, err)
+		require.Equal(t, test.want, got, "unix time %d", test.unixTime)
+	}
+
+	_, err := Code("not base32!", 1)
+	require.EqualError(t, err, "could not decode TOTP secret: illegal base32 data at input byte 3")
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	currentTimeStep := TimeStep(now)
+
+	codeAt := func(timeStep int64) string {
+		c, err := Code(rfcTestSecret, timeStep)
+		require.NoError(t, err)
+		return c
+	}
+
+	tests := []struct {
+		name             string
+		secret           string
+		password         string
+		lastUsedTimeStep int64
+		wantTimeStep     int64
+		wantValid        bool
+		wantErr          string
+	}{
+		{
+			name:         "password of the current time step",
+			secret:       rfcTestSecret,
+			password:     codeAt(currentTimeStep),
+			wantTimeStep: currentTimeStep,
+			wantValid:    true,
+		},
+		{
+			name:         "password of the previous time step",
+			secret:       rfcTestSecret,
+			password:     codeAt(currentTimeStep - 1),
+			wantTimeStep: currentTimeStep - 1,
+			wantValid:    true,
+		},
+		{
+			name:         "password of the next time step",
+			secret:       rfcTestSecret,
+			password:     codeAt(currentTimeStep + 1),
+			wantTimeStep: currentTimeStep + 1,
+			wantValid:    true,
+		},
+		{
+			name:         "password with surrounding whitespace",
+			secret:       rfcTestSecret,
+			password:     " " + codeAt(currentTimeStep) + "\n",
+			wantTimeStep: currentTimeStep,
+			wantValid:    true,
+		},
+		{
+			name:         "lowercase secret",
+			secret:       strings.ToLower(rfcTestSecret),
+			password:     codeAt(currentTimeStep),
+			wantTimeStep: currentTimeStep,
+			wantValid:    true,
+		},
+		{
+			name:     "password of a time step which is too old",
+			secret:   rfcTestSecret,
+			password: codeAt(currentTimeStep - 2),
+		},
+		{
+			name:     "password of a time step which is too new",
+			secret:   rfcTestSecret,
+			password: codeAt(currentTimeStep + 2),
+		},
+		{
+			name:             "password which was already used",
+			secret:           rfcTestSecret,
+			password:         codeAt(currentTimeStep),
+			lastUsedTimeStep: currentTimeStep,
+		},
+		{
+			name:             "password which is newer than the last used password",
+			secret:           rfcTestSecret,
+			password:         codeAt(currentTimeStep + 1),
+			lastUsedTimeStep: currentTimeStep,
+			wantTimeStep:     currentTimeStep + 1,
+			wantValid:        true,
+		},
+		{
+			name:     "wrong password",
+			secret:   rfcTestSecret,
+			password: "123456",
+		},
+		{
+			name:     "password with the wrong number of digits",
+			secret:   rfcTestSecret,
+			password: "1234567",
+		},
+		{
+			name:     "empty password",
+			secret:   rfcTestSecret,
+			password: "",
+		},
+		{
+			name:     "invalid secret",
+			secret:   "not base32!",
+			password: "123456",
+			wantErr:  "could not decode TOTP secret: illegal base32 data at input byte 3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			timeStep, valid, err := Validate(test.secret, test.password, now, test.lastUsedTimeStep)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantValid, valid)
+			require.Equal(t, test.wantTimeStep, timeStep)
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, 32)
+	_, err = Code(secret, 1)
+	require.NoError(t, err)
+
+	secret, err = generateSecret(bytes.NewReader([]byte("12345678901234567890")))
+	require.NoError(t, err)
+	require.Equal(t, rfcTestSecret, secret)
+
+	var empty bytes.Buffer
+	secret, err = generateSecret(&empty)
+	require.EqualError(t, err, "could not generate TOTP secret: EOF")
+	require.Empty(t, secret)
+}
+
+func TestKeyURI(t *testing.T) {
+	require.Equal(t,
+		"otpauth://totp/issuer.example.com%2Fpath:pinny@example.com"+
+			"?algorithm=SHA1&digits=6&issuer=issuer.example.com%2Fpath&period=30&secret="+rfcTestSecret,
+		KeyURI("issuer.example.com/path", "pinny@example.com", rfcTestSecret),
+	)
+}
@@ -0,0 +1,26 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transformpipeline builds identity transformation pipelines from the configuration of a FederationDomain.
+package transformpipeline
+
+import (
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+// NewAccessPolicy compiles the access policy of an identity provider of a FederationDomain. It returns an error
+// which describes every invalid part of the policy.
+func NewAccessPolicy(accessPolicy *configv1alpha1.FederationDomainAccessPolicy) (*idtransform.AccessPolicy, error) {
+	rejectionMessage := accessPolicy.RejectionMessage
+	if len(rejectionMessage) == 0 {
+		rejectionMessage = celtransformer.DefaultPolicyRejectedAuthMessage
+	}
+	return idtransform.NewAccessPolicy(&idtransform.AccessPolicySource{
+		AllowedGroups:                 accessPolicy.AllowedGroups,
+		DeniedGroups:                  accessPolicy.DeniedGroups,
+		AllowedUsernamePatterns:       accessPolicy.AllowedUsernamePatterns,
+		RejectedAuthenticationMessage: rejectionMessage,
+	})
+}
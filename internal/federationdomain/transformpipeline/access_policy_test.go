@@ -0,0 +1,74 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transformpipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+)
+
+func TestNewAccessPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		accessPolicy *configv1alpha1.FederationDomainAccessPolicy
+		username     string
+		groups       []string
+
+		wantAllowed          bool
+		wantRejectionMessage string
+		wantErr              string
+	}{
+		{
+			name:         "allowed",
+			accessPolicy: &configv1alpha1.FederationDomainAccessPolicy{AllowedGroups: []string{"admins"}},
+			username:     "ryan",
+			groups:       []string{"admins"},
+			wantAllowed:  true,
+		},
+		{
+			name:                 "rejected with the default message",
+			accessPolicy:         &configv1alpha1.FederationDomainAccessPolicy{DeniedGroups: []string{"contractors"}},
+			username:             "ryan",
+			groups:               []string{"contractors"},
+			wantRejectionMessage: celtransformer.DefaultPolicyRejectedAuthMessage,
+		},
+		{
+			name: "rejected with a custom message",
+			accessPolicy: &configv1alpha1.FederationDomainAccessPolicy{
+				AllowedUsernamePatterns: []string{"admin-.*"},
+				RejectionMessage:        "only admins may log in",
+			},
+			username:             "ryan",
+			wantRejectionMessage: "only admins may log in",
+		},
+		{
+			name:         "invalid username pattern",
+			accessPolicy: &configv1alpha1.FederationDomainAccessPolicy{AllowedUsernamePatterns: []string{"("}},
+			wantErr:      "allowedUsernamePatterns[0] \"(\" is invalid: error parsing regexp: missing closing ): `^(?:()$`",
+		},
+	}
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			accessPolicy, err := NewAccessPolicy(test.accessPolicy)
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, accessPolicy)
+				return
+			}
+			require.NoError(t, err)
+
+			result, err := accessPolicy.Evaluate(context.Background(), test.username, test.groups, nil)
+			require.NoError(t, err)
+			require.Equal(t, test.wantAllowed, result.AuthenticationAllowed)
+			require.Equal(t, test.wantRejectionMessage, result.RejectedAuthenticationMessage)
+		})
+	}
+}
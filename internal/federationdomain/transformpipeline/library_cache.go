@@ -0,0 +1,185 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transformpipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	configlisters "go.pinniped.dev/generated/latest/client/supervisor/listers/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+// LibraryCache compiles IdentityTransformationLibraries and remembers the results, so that each version of a library
+// is compiled and has its examples evaluated only once, no matter how many FederationDomains use it. It is shared by
+// everything in the Supervisor which needs the compiled libraries, and is safe to use from multiple goroutines.
+type LibraryCache struct {
+	lock           sync.Mutex
+	celTransformer *celtransformer.CELTransformer
+	libraries      map[string]*CompiledLibrary
+}
+
+// CompiledLibrary is the result of compiling one version of an IdentityTransformationLibrary.
+type CompiledLibrary struct {
+	uid        types.UID
+	generation int64
+
+	// pipeline is nil when any of the expressions was invalid.
+	pipeline *idtransform.TransformationPipeline
+
+	// errorsForExpressions and errorsForExamples are validation messages, which are empty when there were no errors.
+	errorsForExpressions string
+	errorsForExamples    string
+}
+
+// IsValid returns true when the library's expressions compiled and its examples passed,
+// in which case the pipeline may be used.
+func (l *CompiledLibrary) IsValid() bool {
+	return l.pipeline != nil && l.errorsForExpressions == "" && l.errorsForExamples == ""
+}
+
+// Pipeline returns the compiled transformations of the library, or nil when any of its expressions was invalid.
+func (l *CompiledLibrary) Pipeline() *idtransform.TransformationPipeline {
+	return l.pipeline
+}
+
+// ErrorsForExpressions returns the validation messages for the library's expressions, or an empty string.
+func (l *CompiledLibrary) ErrorsForExpressions() string {
+	return l.errorsForExpressions
+}
+
+// ErrorsForExamples returns the validation messages for the library's examples, or an empty string.
+func (l *CompiledLibrary) ErrorsForExamples() string {
+	return l.errorsForExamples
+}
+
+// NewLibraryCache creates an empty LibraryCache.
+func NewLibraryCache() *LibraryCache {
+	return &LibraryCache{
+		libraries: map[string]*CompiledLibrary{},
+	}
+}
+
+// Compile returns the compiled form of the given library, reusing the previous result when this generation of the
+// library was already compiled. Invalid expressions and failed examples are not errors. They are described by the
+// returned result. Only unexpected problems are returned as errors.
+func (c *LibraryCache) Compile(
+	ctx context.Context,
+	library *configv1alpha1.IdentityTransformationLibrary,
+) (*CompiledLibrary, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := library.Name
+	if previous, ok := c.libraries[key]; ok && previous.uid == library.UID && previous.generation == library.Generation {
+		return previous, nil
+	}
+
+	if c.celTransformer == nil {
+		var err error
+		c.celTransformer, err = celtransformer.NewCELTransformer(MaxExpressionRuntime)
+		if err != nil {
+			return nil, err // shouldn't really happen
+		}
+	}
+
+	paths := &Paths{
+		ConstantsType:   "spec.constants",
+		ExpressionsType: "spec.expressions",
+		Expressions:     "spec.expressions",
+		Examples:        ".spec.examples",
+	}
+
+	consts, err := MakeConstants(library.Spec.Constants, paths)
+	if err != nil {
+		return nil, fmt.Errorf("IdentityTransformationLibrary %s: %w", key, err)
+	}
+
+	pipeline := idtransform.NewTransformationPipeline()
+	errorsForExpressions, err := CompileExpressions(c.celTransformer, library.Spec.Expressions, consts, pipeline, paths)
+	if err != nil {
+		return nil, fmt.Errorf("IdentityTransformationLibrary %s: %w", key, err)
+	}
+	if len(errorsForExpressions) > 0 {
+		pipeline = nil
+	}
+
+	_, errorsForExamples := EvaluateExamples(ctx, library.Spec.Examples, pipeline, paths)
+
+	compiled := &CompiledLibrary{
+		uid:                  library.UID,
+		generation:           library.Generation,
+		pipeline:             pipeline,
+		errorsForExpressions: strings.Join(errorsForExpressions, "\n\n"),
+		errorsForExamples:    errorsForExamples,
+	}
+	c.libraries[key] = compiled
+	return compiled, nil
+}
+
+// ForgetAllExcept removes the compiled forms of any libraries which are not in the given list,
+// e.g. because they were deleted.
+func (c *LibraryCache) ForgetAllExcept(libraries []*configv1alpha1.IdentityTransformationLibrary) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	keep := make(map[string]*CompiledLibrary, len(libraries))
+	for _, library := range libraries {
+		if compiled, ok := c.libraries[library.Name]; ok {
+			keep[library.Name] = compiled
+		}
+	}
+	c.libraries = keep
+}
+
+// AppendLibraries finds the named libraries and appends their compiled transformations to the pipeline, in the order
+// given. It returns the libraries which were appended, and a validation message for each library which was not found
+// or is not valid. The librariesPath is the location of the list of names in the spec of a resource, for use in the
+// validation messages.
+func (c *LibraryCache) AppendLibraries(
+	ctx context.Context,
+	lister configlisters.IdentityTransformationLibraryLister,
+	libraryNames []string,
+	librariesPath string,
+	pipeline *idtransform.TransformationPipeline,
+) ([]*configv1alpha1.IdentityTransformationLibrary, []string, error) {
+	appended := []*configv1alpha1.IdentityTransformationLibrary{}
+	validationErrors := []string{}
+
+	for libIndex, libName := range libraryNames {
+		library, err := lister.Get(libName)
+		if errors.IsNotFound(err) {
+			validationErrors = append(validationErrors,
+				fmt.Sprintf("%s[%d] refers to an IdentityTransformationLibrary named %q which was not found",
+					librariesPath, libIndex, libName))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err // unexpected error from the informer
+		}
+
+		compiledLibrary, err := c.Compile(ctx, library)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !compiledLibrary.IsValid() {
+			validationErrors = append(validationErrors,
+				fmt.Sprintf("%s[%d] refers to an IdentityTransformationLibrary named %q which is not valid: see its status for details",
+					librariesPath, libIndex, libName))
+			continue
+		}
+
+		pipeline.AppendTransformationPipeline(compiledLibrary.Pipeline())
+		appended = append(appended, library)
+	}
+
+	return appended, validationErrors, nil
+}
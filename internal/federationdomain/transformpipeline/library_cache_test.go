@@ -0,0 +1,109 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transformpipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	configlisters "go.pinniped.dev/generated/latest/client/supervisor/listers/config/v1alpha1"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+func TestLibraryCache(t *testing.T) {
+	library := &configv1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-library", UID: "uid-1", Generation: 1},
+		Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+			Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+				{Type: "username/v1", Expression: `"pre:" + username`},
+			},
+		},
+	}
+
+	libraryCache := NewLibraryCache()
+
+	compiled, err := libraryCache.Compile(context.Background(), library)
+	require.NoError(t, err)
+	require.True(t, compiled.IsValid())
+	result, err := compiled.Pipeline().Evaluate(context.Background(), "ryan", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "pre:ryan", result.Username)
+
+	// The same generation of the same library is only compiled once.
+	compiledAgain, err := libraryCache.Compile(context.Background(), library.DeepCopy())
+	require.NoError(t, err)
+	require.Same(t, compiled, compiledAgain)
+
+	// A new generation is compiled again.
+	newGeneration := library.DeepCopy()
+	newGeneration.Generation = 2
+	newGeneration.Spec.Expressions[0].Expression = `this is not valid`
+	compiledNewGeneration, err := libraryCache.Compile(context.Background(), newGeneration)
+	require.NoError(t, err)
+	require.NotSame(t, compiled, compiledNewGeneration)
+	require.False(t, compiledNewGeneration.IsValid())
+	require.Nil(t, compiledNewGeneration.Pipeline())
+
+	// A library which was deleted and recreated with the same name is compiled again.
+	recreated := library.DeepCopy()
+	recreated.UID = "uid-2"
+	compiledRecreated, err := libraryCache.Compile(context.Background(), recreated)
+	require.NoError(t, err)
+	require.NotSame(t, compiled, compiledRecreated)
+	require.True(t, compiledRecreated.IsValid())
+
+	// Deleted libraries are forgotten.
+	libraryCache.ForgetAllExcept(nil)
+	require.Empty(t, libraryCache.libraries)
+
+	// An illegal constant type is an unexpected error, since the CRD validates it.
+	illegalConstant := library.DeepCopy()
+	illegalConstant.Spec.Constants = []configv1alpha1.FederationDomainTransformsConstant{{Name: "foo", Type: "this is illegal"}}
+	_, err = libraryCache.Compile(context.Background(), illegalConstant)
+	require.EqualError(t, err, `IdentityTransformationLibrary some-library: one of spec.constants[].type is invalid: "this is illegal"`)
+}
+
+func TestAppendLibraries(t *testing.T) {
+	validLibrary := &configv1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "valid-library", UID: "uid-1", Generation: 1},
+		Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+			Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+				{Type: "username/v1", Expression: `"pre:" + username`},
+			},
+		},
+	}
+	invalidLibrary := &configv1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-library", UID: "uid-2", Generation: 1},
+		Spec: configv1alpha1.IdentityTransformationLibrarySpec{
+			Expressions: []configv1alpha1.FederationDomainTransformsExpression{
+				{Type: "username/v1", Expression: `42`},
+			},
+		},
+	}
+
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.NoError(t, indexer.Add(validLibrary))
+	require.NoError(t, indexer.Add(invalidLibrary))
+	lister := configlisters.NewIdentityTransformationLibraryLister(indexer)
+
+	pipeline := idtransform.NewTransformationPipeline()
+	appended, validationErrors, err := NewLibraryCache().AppendLibraries(context.Background(), lister,
+		[]string{"missing-library", "valid-library", "invalid-library", "valid-library"}, "spec.libraries", pipeline)
+	require.NoError(t, err)
+
+	require.Equal(t, []*configv1alpha1.IdentityTransformationLibrary{validLibrary, validLibrary}, appended)
+	require.Equal(t, []string{
+		`spec.libraries[0] refers to an IdentityTransformationLibrary named "missing-library" which was not found`,
+		`spec.libraries[2] refers to an IdentityTransformationLibrary named "invalid-library" which is not valid: see its status for details`,
+	}, validationErrors)
+
+	result, err := pipeline.Evaluate(context.Background(), "ryan", nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "pre:pre:ryan", result.Username)
+}
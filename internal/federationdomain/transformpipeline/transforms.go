@@ -0,0 +1,203 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package transformpipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+// MaxExpressionRuntime is the longest time that a single expression may run.
+const MaxExpressionRuntime = 5 * time.Second
+
+// Paths describes where a list of transforms lives in the spec of a resource, for use in error messages.
+type Paths struct {
+	ConstantsType   string
+	ExpressionsType string
+	Expressions     string
+	Examples        string
+}
+
+// MakeConstants reads the declared constants, so they can be made available to the expressions.
+func MakeConstants(
+	constants []configv1alpha1.FederationDomainTransformsConstant,
+	paths *Paths,
+) (*celtransformer.TransformationConstants, error) {
+	consts := &celtransformer.TransformationConstants{
+		StringConstants:     map[string]string{},
+		StringListConstants: map[string][]string{},
+	}
+
+	// Read all the declared constants.
+	for _, constant := range constants {
+		// The CRD requires the name field, and validates that it has at least one character,
+		// and validates that the names are unique within the list.
+		switch constant.Type {
+		case "string":
+			consts.StringConstants[constant.Name] = constant.StringValue
+		case "stringList":
+			consts.StringListConstants[constant.Name] = constant.StringListValue
+		default:
+			// This shouldn't really happen since the CRD validates it, but handle it as an error.
+			return nil, fmt.Errorf("one of %s[].type is invalid: %q", paths.ConstantsType, constant.Type)
+		}
+	}
+
+	return consts, nil
+}
+
+// CompileExpressions compiles the expressions and appends them to the pipeline. It returns a validation
+// message for each expression which did not compile, in which case the pipeline should not be used.
+func CompileExpressions(
+	celTransformer *celtransformer.CELTransformer,
+	expressions []configv1alpha1.FederationDomainTransformsExpression,
+	consts *celtransformer.TransformationConstants,
+	pipeline *idtransform.TransformationPipeline,
+	paths *Paths,
+) ([]string, error) {
+	expressionsCompileErrors := []string{}
+
+	for exprIndex, expr := range expressions {
+		var rawTransform celtransformer.CELTransformation
+		switch expr.Type {
+		case "username/v1":
+			rawTransform = &celtransformer.UsernameTransformation{Expression: expr.Expression}
+		case "groups/v1":
+			rawTransform = &celtransformer.GroupsTransformation{Expression: expr.Expression}
+		case "policy/v1":
+			rawTransform = &celtransformer.AllowAuthenticationPolicy{
+				Expression:                    expr.Expression,
+				RejectedAuthenticationMessage: expr.Message,
+			}
+		default:
+			// This shouldn't really happen since the CRD validates it, but handle it as an error.
+			return nil, fmt.Errorf("one of %s[].type is invalid: %q", paths.ExpressionsType, expr.Type)
+		}
+
+		compiledTransform, err := celTransformer.CompileTransformation(rawTransform, consts)
+		if err != nil {
+			expressionsCompileErrors = append(expressionsCompileErrors,
+				fmt.Sprintf("%s[%d].expression was invalid:\n%s", paths.Expressions, exprIndex, err.Error()))
+			continue
+		}
+
+		pipeline.AppendTransformation(compiledTransform)
+	}
+
+	return expressionsCompileErrors, nil
+}
+
+// EvaluateExamples runs the examples against the pipeline. A nil pipeline means that some expression was
+// invalid, in which case the examples cannot be run.
+func EvaluateExamples(
+	ctx context.Context,
+	examples []configv1alpha1.FederationDomainTransformsExample,
+	pipeline *idtransform.TransformationPipeline,
+	paths *Paths,
+) (bool, string) {
+	errorFmt := paths.Examples + "[%d] example failed:\nexpected: %s\nactual:   %s"
+	examplesErrors := []string{}
+
+	if pipeline == nil {
+		// Unable to evaluate the conditions where the pipeline of expressions was invalid.
+		return false, fmt.Sprintf(
+			"unable to check if the examples specified by %s[] had errors because an expression was invalid",
+			paths.Examples)
+	}
+
+	// Run all the provided transform examples. If any fail, put errors on the status.
+	for exIndex, e := range examples {
+		var upstreamClaims idtransform.UpstreamClaims
+		if e.UpstreamClaims != nil && len(e.UpstreamClaims.Raw) > 0 {
+			if err := json.Unmarshal(e.UpstreamClaims.Raw, &upstreamClaims); err != nil {
+				examplesErrors = append(examplesErrors, fmt.Sprintf("%s[%d].upstreamClaims could not be read as a JSON object: %s",
+					paths.Examples, exIndex, err.Error()))
+				continue
+			}
+		}
+
+		result, err := pipeline.Evaluate(ctx, e.Username, e.Groups, upstreamClaims)
+		if err != nil {
+			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, exIndex,
+				"no transformation errors",
+				fmt.Sprintf("transformations resulted in an unexpected error %q", err.Error())))
+			continue
+		}
+		resultWasAuthRejected := !result.AuthenticationAllowed
+
+		if e.Expects.Rejected && !resultWasAuthRejected {
+			examplesErrors = append(examplesErrors,
+				fmt.Sprintf(errorFmt, exIndex, "authentication to be rejected", "authentication was not rejected"))
+			continue
+		}
+
+		if !e.Expects.Rejected && resultWasAuthRejected {
+			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, exIndex,
+				"authentication not to be rejected",
+				fmt.Sprintf("authentication was rejected with message %q", result.RejectedAuthenticationMessage)))
+			continue
+		}
+
+		expectedRejectionMessage := e.Expects.Message
+		if len(expectedRejectionMessage) == 0 {
+			expectedRejectionMessage = celtransformer.DefaultPolicyRejectedAuthMessage
+		}
+		if e.Expects.Rejected && resultWasAuthRejected && expectedRejectionMessage != result.RejectedAuthenticationMessage {
+			examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, exIndex,
+				fmt.Sprintf("authentication rejection message %q", expectedRejectionMessage),
+				fmt.Sprintf("authentication rejection message %q", result.RejectedAuthenticationMessage)))
+			continue
+		}
+
+		if result.AuthenticationAllowed {
+			// In the case where the user expected the auth to be allowed and it was allowed, then compare
+			// the expected username and group names to the actual username and group names.
+			if e.Expects.Username != result.Username {
+				examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, exIndex,
+					fmt.Sprintf("username %q", e.Expects.Username),
+					fmt.Sprintf("username %q", result.Username)))
+			}
+			expectedGroups := e.Expects.Groups
+			if expectedGroups == nil {
+				expectedGroups = []string{}
+			}
+			if !stringSetsEqual(expectedGroups, result.Groups) {
+				examplesErrors = append(examplesErrors, fmt.Sprintf(errorFmt, exIndex,
+					fmt.Sprintf("groups [%s]", strings.Join(sortAndQuote(expectedGroups), ", ")),
+					fmt.Sprintf("groups [%s]", strings.Join(sortAndQuote(result.Groups), ", "))))
+			}
+		}
+	}
+
+	if len(examplesErrors) > 0 {
+		return false, strings.Join(examplesErrors, "\n\n")
+	}
+
+	return true, ""
+}
+
+func stringSetsEqual(a []string, b []string) bool {
+	aSet := sets.New(a...)
+	bSet := sets.New(b...)
+	return aSet.Equal(bSet)
+}
+
+func sortAndQuote(strs []string) []string {
+	quoted := make([]string, 0, len(strs))
+	for _, s := range strs {
+		quoted = append(quoted, fmt.Sprintf("%q", s))
+	}
+	sort.Strings(quoted)
+	return quoted
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package upstreamprovider
@@ -77,9 +77,17 @@ type UpstreamOIDCIdentityProviderI interface {
 	// GetAdditionalAuthcodeParams returns additional params to be sent on authcode requests.
 	GetAdditionalAuthcodeParams() map[string]string
 
+	// GetPassthroughAuthcodeParams returns the names of the params of the downstream authorization request which
+	// should be copied into authcode requests.
+	GetPassthroughAuthcodeParams() []string
+
 	// GetAdditionalClaimMappings returns additional claims to be mapped from the upstream ID token.
 	GetAdditionalClaimMappings() map[string]string
 
+	// GetAdditionalClaimsForTransformations returns the names of the upstream claims which should be made available
+	// to identity transformation expressions.
+	GetAdditionalClaimsForTransformations() []string
+
 	// PasswordCredentialsGrantAndValidateTokens performs upstream OIDC resource owner password credentials grant and
 	// token validation. Returns the validated raw tokens as well as the parsed claims of the ID token.
 	PasswordCredentialsGrantAndValidateTokens(ctx context.Context, username, password string) (*oidctypes.Token, error)
@@ -0,0 +1,147 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	errCBORUnexpectedEnd = constable.Error("unexpected end of CBOR data")
+
+	// maxCBORDepth limits the nesting of arrays and maps, which is very shallow in the responses of authenticators.
+	maxCBORDepth = 8
+)
+
+// decodeCBOR decodes the first CBOR data item (RFC 8949) of data, and returns it along with the bytes which follow it.
+// It only supports what authenticators use in their responses: integers, byte strings, text strings, arrays and maps
+// of definite length, booleans and null. Integers are decoded as int64, byte strings as []byte, text strings as string,
+// arrays as []any and maps as map[any]any, whose keys are either int64 or string.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("CBOR data is nested more than %d levels deep", maxCBORDepth)
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORUnexpectedEnd
+	}
+
+	majorType, additionalInfo, data := data[0]>>5, data[0]&0x1f, data[1:]
+
+	if majorType == 7 {
+		switch additionalInfo {
+		case 20:
+			return false, data, nil
+		case 21:
+			return true, data, nil
+		case 22:
+			return nil, data, nil
+		default:
+			return nil, nil, fmt.Errorf("unsupported CBOR simple value or float %d", additionalInfo)
+		}
+	}
+
+	argument, data, err := readCBORArgument(additionalInfo, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch majorType {
+	case 0: // unsigned integer
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("CBOR integer %d is too large", argument)
+		}
+		return int64(argument), data, nil
+	case 1: // negative integer
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("CBOR integer -1-%d is too small", argument)
+		}
+		return -1 - int64(argument), data, nil
+	case 2, 3: // byte string, text string
+		if argument > uint64(len(data)) {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		if majorType == 2 {
+			return data[:argument:argument], data[argument:], nil
+		}
+		return string(data[:argument]), data[argument:], nil
+	case 4: // array
+		// Each item takes at least one byte, so this avoids allocating huge arrays for invalid lengths.
+		if argument > uint64(len(data)) {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		items := make([]any, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			var item any
+			item, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, data, nil
+	case 5: // map
+		if argument > uint64(len(data)) {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		items := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value any
+			key, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("unsupported CBOR map key of type %T", key)
+			}
+			if _, ok := items[key]; ok {
+				return nil, nil, fmt.Errorf("duplicate CBOR map key %v", key)
+			}
+			value, data, err = decodeCBORItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items[key] = value
+		}
+		return items, data, nil
+	default: // 6 is a tag, which authenticators do not use
+		return nil, nil, fmt.Errorf("unsupported CBOR major type %d", majorType)
+	}
+}
+
+// readCBORArgument reads the argument of a data item, which is its value, length or count, depending on its major type.
+func readCBORArgument(additionalInfo byte, data []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case additionalInfo < 24:
+		return uint64(additionalInfo), data, nil
+	case additionalInfo == 24:
+		size = 1
+	case additionalInfo == 25:
+		size = 2
+	case additionalInfo == 26:
+		size = 4
+	case additionalInfo == 27:
+		size = 8
+	default:
+		// 31 is the indefinite length, which authenticators do not use, and the others are reserved.
+		return 0, nil, fmt.Errorf("unsupported CBOR additional information %d", additionalInfo)
+	}
+
+	if len(data) < size {
+		return 0, nil, errCBORUnexpectedEnd
+	}
+	var buf [8]byte
+	copy(buf[8-size:], data[:size])
+	return binary.BigEndian.Uint64(buf[:]), data[size:], nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name      string
+		hex       string
+		want      any
+		wantRest  string
+		wantError string
+	}{
+		// Most of these examples are from Appendix A of RFC 8949.
+		{name: "zero", hex: "00", want: int64(0)},
+		{name: "small unsigned integer", hex: "17", want: int64(23)},
+		{name: "one byte unsigned integer", hex: "1818", want: int64(24)},
+		{name: "two byte unsigned integer", hex: "1903e8", want: int64(1000)},
+		{name: "four byte unsigned integer", hex: "1a000f4240", want: int64(1000000)},
+		{name: "eight byte unsigned integer", hex: "1b000000e8d4a51000", want: int64(1000000000000)},
+		{name: "unsigned integer which is too large", hex: "1bffffffffffffffff", wantError: "CBOR integer 18446744073709551615 is too large"},
+		{name: "negative integer", hex: "20", want: int64(-1)},
+		{name: "two byte negative integer", hex: "3903e7", want: int64(-1000)},
+		{name: "negative integer which is too small", hex: "3bffffffffffffffff", wantError: "CBOR integer -1-18446744073709551615 is too small"},
+		{name: "empty byte string", hex: "40", want: []byte{}},
+		{name: "byte string", hex: "4401020304", want: []byte{1, 2, 3, 4}},
+		{name: "text string", hex: "6449455446", want: "IETF"},
+		{name: "array", hex: "83010203", want: []any{int64(1), int64(2), int64(3)}},
+		{name: "nested array", hex: "8301820203820405", want: []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{name: "map", hex: "a201020304", want: map[any]any{int64(1): int64(2), int64(3): int64(4)}},
+		{name: "map with text keys", hex: "a26161016162820203", want: map[any]any{"a": int64(1), "b": []any{int64(2), int64(3)}}},
+		{name: "false", hex: "f4", want: false},
+		{name: "true", hex: "f5", want: true},
+		{name: "null", hex: "f6", want: nil},
+		{name: "remaining bytes are returned", hex: "0102", want: int64(1), wantRest: "02"},
+		{name: "empty", hex: "", wantError: "unexpected end of CBOR data"},
+		{name: "truncated argument", hex: "19ff", wantError: "unexpected end of CBOR data"},
+		{name: "truncated byte string", hex: "4401", wantError: "unexpected end of CBOR data"},
+		{name: "truncated array", hex: "8301", wantError: "unexpected end of CBOR data"},
+		{name: "array longer than the data", hex: "9bffffffffffffffff", wantError: "unexpected end of CBOR data"},
+		{name: "map longer than the data", hex: "bbffffffffffffffff", wantError: "unexpected end of CBOR data"},
+		{name: "indefinite length", hex: "5f42010243030405ff", wantError: "unsupported CBOR additional information 31"},
+		{name: "tag", hex: "c11a514b67b0", wantError: "unsupported CBOR major type 6"},
+		{name: "float", hex: "f93c00", wantError: "unsupported CBOR simple value or float 25"},
+		{name: "map with a byte string key", hex: "a14101f6", wantError: "unsupported CBOR map key of type []uint8"},
+		{name: "map with a duplicate key", hex: "a201020103", wantError: "duplicate CBOR map key 1"},
+		{name: "nested too deeply", hex: "818181818181818181818100", wantError: "CBOR data is nested more than 8 levels deep"},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := hex.DecodeString(tt.hex)
+			require.NoError(t, err)
+
+			got, rest, err := decodeCBOR(data)
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantRest, hex.EncodeToString(rest))
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+)
+
+// These are the COSE algorithms (RFC 9053) of the credential public keys which are supported.
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+// These are the labels and values of the COSE_Key parameters (RFC 9052 and RFC 9053) which are used by authenticators.
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurve = -1
+	coseX     = -2
+	coseY     = -3
+
+	coseRSAModulus  = -1
+	coseRSAExponent = -2
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	// minRSAKeyBits is the smallest RSA key which is accepted.
+	minRSAKeyBits = 2048
+)
+
+// SupportedAlgorithms returns the COSE algorithms of the credential public keys which are supported,
+// in order of preference.
+func SupportedAlgorithms() []int {
+	return []int{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+}
+
+// publicKey is the parsed public key of a credential.
+type publicKey struct {
+	algorithm int64
+	key       crypto.PublicKey
+}
+
+// parsePublicKey parses the COSE_Key of a credential, and returns it along with the bytes which follow it.
+func parsePublicKey(coseKey []byte) (*publicKey, []byte, error) {
+	decoded, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not decode credential public key: %w", err)
+	}
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, nil, fmt.Errorf("credential public key is not a map")
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, _ := params[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == AlgorithmES256:
+		key, err := parseP256PublicKey(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &publicKey{algorithm: algorithm, key: key}, rest, nil
+	case keyType == coseKeyTypeOKP && algorithm == AlgorithmEdDSA:
+		curve, _ := params[int64(coseCurve)].(int64)
+		x, _ := params[int64(coseX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, nil, fmt.Errorf("credential public key is not a valid Ed25519 key")
+		}
+		return &publicKey{algorithm: algorithm, key: ed25519.PublicKey(x)}, rest, nil
+	case keyType == coseKeyTypeRSA && algorithm == AlgorithmRS256:
+		key, err := parseRSAPublicKey(params)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &publicKey{algorithm: algorithm, key: key}, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("credential public key has unsupported key type %d and algorithm %d", keyType, algorithm)
+	}
+}
+
+func parseP256PublicKey(params map[any]any) (*ecdsa.PublicKey, error) {
+	curve, _ := params[int64(coseCurve)].(int64)
+	x, _ := params[int64(coseX)].([]byte)
+	y, _ := params[int64(coseY)].([]byte)
+	if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+		return nil, fmt.Errorf("credential public key is not a valid P-256 key")
+	}
+
+	// Check that the point is on the curve, which crypto/ecdsa does not check by itself.
+	uncompressed := append(append([]byte{4}, x...), y...)
+	if _, err := ecdh.P256().NewPublicKey(uncompressed); err != nil {
+		return nil, fmt.Errorf("credential public key is not a valid P-256 key")
+	}
+
+	return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func parseRSAPublicKey(params map[any]any) (*rsa.PublicKey, error) {
+	modulus, _ := params[int64(coseRSAModulus)].([]byte)
+	exponent, _ := params[int64(coseRSAExponent)].([]byte)
+	if len(exponent) == 0 || len(exponent) > 4 {
+		return nil, fmt.Errorf("credential public key is not a valid RSA key")
+	}
+
+	key := &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(new(big.Int).SetBytes(exponent).Int64())}
+	if key.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("credential public key is an RSA key smaller than %d bits", minRSAKeyBits)
+	}
+	if key.E < 3 || key.E%2 == 0 {
+		return nil, fmt.Errorf("credential public key is not a valid RSA key")
+	}
+	return key, nil
+}
+
+// verify checks the signature of the data.
+func (k *publicKey) verify(data []byte, signature []byte) bool {
+	switch key := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(key, digest[:], signature)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) == nil
+	default:
+		return false
+	}
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/testutil/webauthntestutil"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	data := []byte("some signed data")
+	digest := sha256.Sum256(data)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecSignature, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	require.NoError(t, err)
+	x, y := make([]byte, 32), make([]byte, 32)
+	ecKey.X.FillBytes(x)
+	ecKey.Y.FillBytes(y)
+
+	edPublicKey, edPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edSignature := ed25519.Sign(edPrivateKey, data)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaSignature, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	rsaModulus := rsaKey.N.Bytes()
+	rsaExponent := big.NewInt(int64(rsaKey.E)).Bytes()
+
+	smallRSAKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		coseKey       map[any]any
+		signature     []byte
+		wantAlgorithm int64
+		wantError     string
+	}{
+		{
+			name:          "ES256",
+			coseKey:       map[any]any{1: 2, 3: -7, -1: 1, -2: x, -3: y},
+			signature:     ecSignature,
+			wantAlgorithm: AlgorithmES256,
+		},
+		{
+			name:          "EdDSA",
+			coseKey:       map[any]any{1: 1, 3: -8, -1: 6, -2: []byte(edPublicKey)},
+			signature:     edSignature,
+			wantAlgorithm: AlgorithmEdDSA,
+		},
+		{
+			name:          "RS256",
+			coseKey:       map[any]any{1: 3, 3: -257, -1: rsaModulus, -2: rsaExponent},
+			signature:     rsaSignature,
+			wantAlgorithm: AlgorithmRS256,
+		},
+		{
+			name:      "ES256 with a different curve",
+			coseKey:   map[any]any{1: 2, 3: -7, -1: 2, -2: x, -3: y},
+			wantError: "credential public key is not a valid P-256 key",
+		},
+		{
+			name:      "ES256 with a point which is not on the curve",
+			coseKey:   map[any]any{1: 2, 3: -7, -1: 1, -2: x, -3: x},
+			wantError: "credential public key is not a valid P-256 key",
+		},
+		{
+			name:      "EdDSA with a different curve",
+			coseKey:   map[any]any{1: 1, 3: -8, -1: 7, -2: []byte(edPublicKey)},
+			wantError: "credential public key is not a valid Ed25519 key",
+		},
+		{
+			name:      "RS256 with a small key",
+			coseKey:   map[any]any{1: 3, 3: -257, -1: smallRSAKey.N.Bytes(), -2: rsaExponent},
+			wantError: "credential public key is an RSA key smaller than 2048 bits",
+		},
+		{
+			name:      "RS256 with an even exponent",
+			coseKey:   map[any]any{1: 3, 3: -257, -1: rsaModulus, -2: []byte{4}},
+			wantError: "credential public key is not a valid RSA key",
+		},
+		{
+			name:      "RS256 without an exponent",
+			coseKey:   map[any]any{1: 3, 3: -257, -1: rsaModulus},
+			wantError: "credential public key is not a valid RSA key",
+		},
+		{
+			name:      "unsupported algorithm",
+			coseKey:   map[any]any{1: 2, 3: -35, -1: 2, -2: x, -3: y},
+			wantError: "credential public key has unsupported key type 2 and algorithm -35",
+		},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			key, rest, err := parsePublicKey(append(webauthntestutil.EncodeCBOR(tt.coseKey), 1, 2, 3))
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				require.Nil(t, key)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []byte{1, 2, 3}, rest)
+			require.Equal(t, tt.wantAlgorithm, key.algorithm)
+			require.True(t, key.verify(data, tt.signature))
+			require.False(t, key.verify([]byte("other data"), tt.signature))
+		})
+	}
+
+	_, _, err = parsePublicKey(webauthntestutil.EncodeCBOR([]any{1, 2}))
+	require.EqualError(t, err, "credential public key is not a map")
+
+	_, _, err = parsePublicKey(nil)
+	require.EqualError(t, err, "could not decode credential public key: unexpected end of CBOR data")
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webauthn verifies the responses of the security keys and passkeys which users register and use as their
+// second authentication factor, as described by the W3C Web Authentication specification
+// (https://www.w3.org/TR/webauthn-2/). It only supports what the Supervisor's login page needs. In particular,
+// attestation is not requested, so the make and model of the authenticator are not verified.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	// ErrNotAccepted is returned when the response of the authenticator was not accepted.
+	ErrNotAccepted = constable.Error("security key response not accepted")
+
+	// challengeLength is the number of random bytes of each challenge, which should be at least 16.
+	challengeLength = 32
+
+	// maxCredentialIDLength is the longest credential ID which authenticators may return.
+	maxCredentialIDLength = 1023
+
+	// These are the bits of the flags of the authenticator data.
+	flagUserPresent            = 0x01
+	flagAttestedCredentialData = 0x40
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+// RelyingParty is the web site which users log in to, whose origin and ID are checked by authenticators.
+type RelyingParty struct {
+	// ID is the host name of the web site. Credentials are scoped to it.
+	ID string
+
+	// Origin is the scheme, host and port of the web site, as reported by the browser.
+	Origin string
+}
+
+// NewRelyingParty returns the relying party of the web site with the given URL.
+func NewRelyingParty(siteURL string) (*RelyingParty, error) {
+	parsedURL, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse URL of relying party: %w", err)
+	}
+	if parsedURL.Scheme == "" || parsedURL.Hostname() == "" {
+		return nil, fmt.Errorf("URL of relying party must have a scheme and a host: %q", siteURL)
+	}
+	return &RelyingParty{
+		ID:     parsedURL.Hostname(),
+		Origin: parsedURL.Scheme + "://" + parsedURL.Host,
+	}, nil
+}
+
+// Credential is a credential which a user has registered with their authenticator.
+type Credential struct {
+	// ID identifies the credential to the authenticator.
+	ID []byte
+
+	// PublicKey is the COSE_Key of the credential's public key.
+	PublicKey []byte
+
+	// SignCount is the signature counter which the authenticator reported most recently. Authenticators which
+	// do not have a signature counter always report zero.
+	SignCount uint32
+}
+
+// RegistrationResponse is the response of navigator.credentials.create(), which registers a new credential.
+type RegistrationResponse struct {
+	ClientDataJSON    []byte
+	AttestationObject []byte
+}
+
+// AssertionResponse is the response of navigator.credentials.get(), which proves that the user has the credential.
+type AssertionResponse struct {
+	CredentialID      []byte
+	ClientDataJSON    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+}
+
+// GenerateChallenge generates a new random challenge, encoded in base64url as it appears in the client data.
+func GenerateChallenge() (string, error) { return generateChallenge(rand.Reader) }
+
+func generateChallenge(entropySource io.Reader) (string, error) {
+	buf := make([]byte, challengeLength)
+	if _, err := io.ReadFull(entropySource, buf); err != nil {
+		return "", fmt.Errorf("could not generate WebAuthn challenge: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// VerifyRegistration checks the response of navigator.credentials.create() for the given challenge,
+// and returns the credential which the authenticator created. All reasons for not accepting the
+// response wrap ErrNotAccepted.
+func (rp *RelyingParty) VerifyRegistration(challenge string, response *RegistrationResponse) (*Credential, error) {
+	if err := rp.verifyClientData(response.ClientDataJSON, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	decoded, rest, err := decodeCBOR(response.AttestationObject)
+	if err != nil {
+		return nil, notAccepted("could not decode attestation object: %v", err)
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok || len(rest) != 0 {
+		return nil, notAccepted("attestation object is not a map")
+	}
+	// The attestation statement is not verified, because attestation is not requested.
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, notAccepted("attestation object does not have authenticator data")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.credentialPublicKey == nil {
+		return nil, notAccepted("authenticator data does not have attested credential data")
+	}
+
+	return &Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.credentialPublicKey,
+		SignCount: authData.signCount,
+	}, nil
+}
+
+// VerifyAssertion checks the response of navigator.credentials.get() for the given challenge and credential,
+// and returns the new signature counter of the credential, which should be remembered for the next assertion.
+// All reasons for not accepting the response wrap ErrNotAccepted.
+func (rp *RelyingParty) VerifyAssertion(challenge string, credential *Credential, response *AssertionResponse) (uint32, error) {
+	if !bytes.Equal(response.CredentialID, credential.ID) {
+		return 0, notAccepted("credential was not registered")
+	}
+
+	if err := rp.verifyClientData(response.ClientDataJSON, clientDataTypeGet, challenge); err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.verifyAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, _, err := parsePublicKey(credential.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	// The signature is of the authenticator data followed by the hash of the client data.
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	signed := append(append([]byte{}, response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signed, response.Signature) {
+		return 0, notAccepted("signature is not valid")
+	}
+
+	// Signature counters only increase, unless the authenticator does not have one, so a counter which did not
+	// increase means that the credential might have been cloned.
+	if (authData.signCount != 0 || credential.SignCount != 0) && authData.signCount <= credential.SignCount {
+		return 0, notAccepted("signature counter %d is not greater than %d", authData.signCount, credential.SignCount)
+	}
+
+	return authData.signCount, nil
+}
+
+// verifyClientData checks the client data which the browser collected and the authenticator signed.
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, wantType string, wantChallenge string) error {
+	var clientData struct {
+		Type        string `json:"type"`
+		Challenge   string `json:"challenge"`
+		Origin      string `json:"origin"`
+		CrossOrigin bool   `json:"crossOrigin"`
+	}
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return notAccepted("could not decode client data: %v", err)
+	}
+
+	switch {
+	case clientData.Type != wantType:
+		return notAccepted("client data has type %q instead of %q", clientData.Type, wantType)
+	case wantChallenge == "" || subtle.ConstantTimeCompare([]byte(clientData.Challenge), []byte(wantChallenge)) != 1:
+		return notAccepted("client data does not have the expected challenge")
+	case clientData.Origin != rp.Origin:
+		return notAccepted("client data has origin %q instead of %q", clientData.Origin, rp.Origin)
+	case clientData.CrossOrigin:
+		return notAccepted("client data is from a cross-origin iframe")
+	}
+	return nil
+}
+
+type authenticatorData struct {
+	signCount uint32
+
+	// credentialID and credentialPublicKey are only present in the authenticator data of registrations.
+	credentialID        []byte
+	credentialPublicKey []byte
+}
+
+// verifyAuthenticatorData parses the authenticator data, and checks that it is scoped to the relying party and
+// that the user was present.
+func (rp *RelyingParty) verifyAuthenticatorData(data []byte) (*authenticatorData, error) {
+	// The authenticator data starts with the SHA-256 hash of the relying party ID, one byte of flags,
+	// and the four byte signature counter.
+	if len(data) < sha256.Size+1+4 {
+		return nil, notAccepted("authenticator data is too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(data[:sha256.Size], rpIDHash[:]) != 1 {
+		return nil, notAccepted("authenticator data is not for relying party %q", rp.ID)
+	}
+	flags := data[sha256.Size]
+	if flags&flagUserPresent == 0 {
+		return nil, notAccepted("authenticator data does not have the user present flag")
+	}
+	parsed := &authenticatorData{signCount: binary.BigEndian.Uint32(data[sha256.Size+1:])}
+
+	if flags&flagAttestedCredentialData == 0 {
+		return parsed, nil
+	}
+
+	// The attested credential data has the 16 byte AAGUID of the authenticator, the two byte length of the
+	// credential ID, the credential ID and the COSE_Key of the credential's public key. It may be followed by
+	// extensions, which are ignored.
+	rest := data[sha256.Size+1+4:]
+	if len(rest) < 16+2 {
+		return nil, notAccepted("attested credential data is too short")
+	}
+	credentialIDLength := int(binary.BigEndian.Uint16(rest[16:]))
+	rest = rest[16+2:]
+	if credentialIDLength == 0 || credentialIDLength > maxCredentialIDLength || credentialIDLength > len(rest) {
+		return nil, notAccepted("attested credential data has an invalid credential ID length %d", credentialIDLength)
+	}
+	parsed.credentialID, rest = rest[:credentialIDLength:credentialIDLength], rest[credentialIDLength:]
+
+	_, afterKey, err := parsePublicKey(rest)
+	if err != nil {
+		return nil, notAccepted("%v", err)
+	}
+	parsed.credentialPublicKey = rest[: len(rest)-len(afterKey) : len(rest)-len(afterKey)]
+
+	return parsed, nil
+}
+
+func notAccepted(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrNotAccepted, fmt.Sprintf(format, args...))
+}
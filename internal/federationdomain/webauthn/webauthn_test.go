@@ -0,0 +1,300 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/testutil/webauthntestutil"
+)
+
+const (
+	testOrigin    = "https://issuer.example.com:8443"
+	testRPID      = "issuer.example.com"
+	testChallenge = "some-challenge"
+)
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := NewRelyingParty("https://issuer.example.com:8443/some/path")
+	require.NoError(t, err)
+	require.Equal(t, &RelyingParty{ID: testRPID, Origin: testOrigin}, rp)
+
+	rp, err = NewRelyingParty("https://issuer.example.com")
+	require.NoError(t, err)
+	require.Equal(t, &RelyingParty{ID: "issuer.example.com", Origin: "https://issuer.example.com"}, rp)
+
+	_, err = NewRelyingParty("/no/host")
+	require.EqualError(t, err, `URL of relying party must have a scheme and a host: "/no/host"`)
+
+	_, err = NewRelyingParty("https://bad\x7furl")
+	require.ErrorContains(t, err, "could not parse URL of relying party")
+}
+
+func TestGenerateChallenge(t *testing.T) {
+	challenge, err := GenerateChallenge()
+	require.NoError(t, err)
+	require.Len(t, challenge, 43)
+
+	var empty bytes.Buffer
+	challenge, err = generateChallenge(&empty)
+	require.EqualError(t, err, "could not generate WebAuthn challenge: EOF")
+	require.Empty(t, challenge)
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	rp := &RelyingParty{ID: testRPID, Origin: testOrigin}
+	authenticator := webauthntestutil.NewAuthenticator(t)
+
+	clientDataJSON, attestationObject := authenticator.Register(t, testOrigin, testRPID, testChallenge)
+	credential, err := rp.VerifyRegistration(testChallenge, &RegistrationResponse{ClientDataJSON: clientDataJSON, AttestationObject: attestationObject})
+	require.NoError(t, err)
+	require.Equal(t, &Credential{ID: authenticator.CredentialID, PublicKey: authenticator.COSEKey(), SignCount: 0}, credential)
+
+	// An empty challenge is never accepted, even when the client data has an empty challenge too.
+	_, err = rp.VerifyRegistration("", &RegistrationResponse{
+		ClientDataJSON:    webauthntestutil.ClientDataJSON(t, "webauthn.create", "", testOrigin),
+		AttestationObject: attestationObject,
+	})
+	require.EqualError(t, err, "security key response not accepted: client data does not have the expected challenge")
+
+	attestationWithAuthData := func(authData []byte) []byte {
+		return webauthntestutil.EncodeCBOR(map[any]any{"fmt": "none", "attStmt": map[any]any{}, "authData": authData})
+	}
+
+	tests := []struct {
+		name              string
+		clientDataJSON    []byte
+		attestationObject []byte
+		wantError         string
+	}{
+		{
+			name:              "wrong client data type",
+			clientDataJSON:    webauthntestutil.ClientDataJSON(t, "webauthn.get", testChallenge, testOrigin),
+			attestationObject: attestationObject,
+			wantError:         `security key response not accepted: client data has type "webauthn.get" instead of "webauthn.create"`,
+		},
+		{
+			name:              "wrong challenge",
+			clientDataJSON:    webauthntestutil.ClientDataJSON(t, "webauthn.create", "other-challenge", testOrigin),
+			attestationObject: attestationObject,
+			wantError:         "security key response not accepted: client data does not have the expected challenge",
+		},
+		{
+			name:              "wrong origin",
+			clientDataJSON:    webauthntestutil.ClientDataJSON(t, "webauthn.create", testChallenge, "https://evil.example.com"),
+			attestationObject: attestationObject,
+			wantError:         `security key response not accepted: client data has origin "https://evil.example.com" instead of "https://issuer.example.com:8443"`,
+		},
+		{
+			name:              "cross origin",
+			clientDataJSON:    []byte(`{"type":"webauthn.create","challenge":"some-challenge","origin":"https://issuer.example.com:8443","crossOrigin":true}`),
+			attestationObject: attestationObject,
+			wantError:         "security key response not accepted: client data is from a cross-origin iframe",
+		},
+		{
+			name:              "invalid client data",
+			clientDataJSON:    []byte(`not json`),
+			attestationObject: attestationObject,
+			wantError:         "security key response not accepted: could not decode client data: invalid character 'o' in literal null (expecting 'u')",
+		},
+		{
+			name:              "invalid attestation object",
+			clientDataJSON:    clientDataJSON,
+			attestationObject: []byte{0xa1},
+			wantError:         "security key response not accepted: could not decode attestation object: unexpected end of CBOR data",
+		},
+		{
+			name:              "attestation object is not a map",
+			clientDataJSON:    clientDataJSON,
+			attestationObject: webauthntestutil.EncodeCBOR("not a map"),
+			wantError:         "security key response not accepted: attestation object is not a map",
+		},
+		{
+			name:              "attestation object without authenticator data",
+			clientDataJSON:    clientDataJSON,
+			attestationObject: webauthntestutil.EncodeCBOR(map[any]any{"fmt": "none"}),
+			wantError:         "security key response not accepted: attestation object does not have authenticator data",
+		},
+		{
+			name:              "authenticator data is too short",
+			clientDataJSON:    clientDataJSON,
+			attestationObject: attestationWithAuthData([]byte{1, 2, 3}),
+			wantError:         "security key response not accepted: authenticator data is too short",
+		},
+		{
+			name:           "authenticator data for a different relying party",
+			clientDataJSON: clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData("evil.example.com",
+				webauthntestutil.FlagUserPresent|webauthntestutil.FlagAttestedCredentialData, 0, authenticator.AttestedCredentialData())),
+			wantError: `security key response not accepted: authenticator data is not for relying party "issuer.example.com"`,
+		},
+		{
+			name:           "user was not present",
+			clientDataJSON: clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData(testRPID,
+				webauthntestutil.FlagAttestedCredentialData, 0, authenticator.AttestedCredentialData())),
+			wantError: "security key response not accepted: authenticator data does not have the user present flag",
+		},
+		{
+			name:              "no attested credential data",
+			clientDataJSON:    clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData(testRPID, webauthntestutil.FlagUserPresent, 0, nil)),
+			wantError:         "security key response not accepted: authenticator data does not have attested credential data",
+		},
+		{
+			name:           "attested credential data is too short",
+			clientDataJSON: clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData(testRPID,
+				webauthntestutil.FlagUserPresent|webauthntestutil.FlagAttestedCredentialData, 0, make([]byte, 17))),
+			wantError: "security key response not accepted: attested credential data is too short",
+		},
+		{
+			name:           "credential ID is longer than the data",
+			clientDataJSON: clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData(testRPID,
+				webauthntestutil.FlagUserPresent|webauthntestutil.FlagAttestedCredentialData, 0, append(make([]byte, 16), 0, 5, 1))),
+			wantError: "security key response not accepted: attested credential data has an invalid credential ID length 5",
+		},
+		{
+			name:           "unsupported credential public key",
+			clientDataJSON: clientDataJSON,
+			attestationObject: attestationWithAuthData(webauthntestutil.AuthenticatorData(testRPID,
+				webauthntestutil.FlagUserPresent|webauthntestutil.FlagAttestedCredentialData, 0,
+				append(append(make([]byte, 16), 0, 1, 1), webauthntestutil.EncodeCBOR(map[any]any{1: 2, 3: -36})...))),
+			wantError: "security key response not accepted: credential public key has unsupported key type 2 and algorithm -36",
+		},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			credential, err := rp.VerifyRegistration(testChallenge, &RegistrationResponse{ClientDataJSON: tt.clientDataJSON, AttestationObject: tt.attestationObject})
+			require.EqualError(t, err, tt.wantError)
+			require.ErrorIs(t, err, ErrNotAccepted)
+			require.Nil(t, credential)
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	rp := &RelyingParty{ID: testRPID, Origin: testOrigin}
+	authenticator := webauthntestutil.NewAuthenticator(t)
+	clientDataJSON, attestationObject := authenticator.Register(t, testOrigin, testRPID, "registration-challenge")
+	credential, err := rp.VerifyRegistration("registration-challenge", &RegistrationResponse{ClientDataJSON: clientDataJSON, AttestationObject: attestationObject})
+	require.NoError(t, err)
+
+	// The signature counter is returned, and must increase with each assertion.
+	clientDataJSON, authData, signature := authenticator.Assert(t, testOrigin, testRPID, testChallenge)
+	signCount, err := rp.VerifyAssertion(testChallenge, credential, &AssertionResponse{
+		CredentialID: authenticator.CredentialID, ClientDataJSON: clientDataJSON, AuthenticatorData: authData, Signature: signature,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), signCount)
+
+	credential.SignCount = signCount
+	_, err = rp.VerifyAssertion(testChallenge, credential, &AssertionResponse{
+		CredentialID: authenticator.CredentialID, ClientDataJSON: clientDataJSON, AuthenticatorData: authData, Signature: signature,
+	})
+	require.EqualError(t, err, "security key response not accepted: signature counter 1 is not greater than 1")
+
+	clientDataJSON, authData, signature = authenticator.Assert(t, testOrigin, testRPID, testChallenge)
+	signCount, err = rp.VerifyAssertion(testChallenge, credential, &AssertionResponse{
+		CredentialID: authenticator.CredentialID, ClientDataJSON: clientDataJSON, AuthenticatorData: authData, Signature: signature,
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), signCount)
+
+	// Authenticators which do not have a signature counter always report zero.
+	credential.SignCount = 0
+	authenticator.NoSignCount = true
+	for i := 0; i < 2; i++ {
+		clientDataJSON, authData, signature = authenticator.Assert(t, testOrigin, testRPID, testChallenge)
+		signCount, err = rp.VerifyAssertion(testChallenge, credential, &AssertionResponse{
+			CredentialID: authenticator.CredentialID, ClientDataJSON: clientDataJSON, AuthenticatorData: authData, Signature: signature,
+		})
+		require.NoError(t, err)
+		require.Equal(t, uint32(0), signCount)
+	}
+
+	clientDataJSON, authData, signature = authenticator.Assert(t, testOrigin, testRPID, testChallenge)
+	otherAuthenticator := webauthntestutil.NewAuthenticator(t)
+
+	tests := []struct {
+		name           string
+		credentialID   []byte
+		clientDataJSON []byte
+		authData       []byte
+		signature      []byte
+		wantError      string
+	}{
+		{
+			name:           "credential was not registered",
+			credentialID:   otherAuthenticator.CredentialID,
+			clientDataJSON: clientDataJSON,
+			authData:       authData,
+			signature:      signature,
+			wantError:      "security key response not accepted: credential was not registered",
+		},
+		{
+			name:           "wrong client data type",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: webauthntestutil.ClientDataJSON(t, "webauthn.create", testChallenge, testOrigin),
+			authData:       authData,
+			signature:      signature,
+			wantError:      `security key response not accepted: client data has type "webauthn.create" instead of "webauthn.get"`,
+		},
+		{
+			name:           "wrong challenge",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: webauthntestutil.ClientDataJSON(t, "webauthn.get", "other-challenge", testOrigin),
+			authData:       authData,
+			signature:      signature,
+			wantError:      "security key response not accepted: client data does not have the expected challenge",
+		},
+		{
+			name:           "authenticator data for a different relying party",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: clientDataJSON,
+			authData:       webauthntestutil.AuthenticatorData("evil.example.com", webauthntestutil.FlagUserPresent, 0, nil),
+			signature:      signature,
+			wantError:      `security key response not accepted: authenticator data is not for relying party "issuer.example.com"`,
+		},
+		{
+			name:           "signature of different authenticator data",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: clientDataJSON,
+			authData:       webauthntestutil.AuthenticatorData(testRPID, webauthntestutil.FlagUserPresent, 100, nil),
+			signature:      signature,
+			wantError:      "security key response not accepted: signature is not valid",
+		},
+		{
+			name:           "signature of a different authenticator",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: clientDataJSON,
+			authData:       authData,
+			signature:      otherAuthenticator.Sign(t, authData, clientDataJSON),
+			wantError:      "security key response not accepted: signature is not valid",
+		},
+		{
+			name:           "no signature",
+			credentialID:   authenticator.CredentialID,
+			clientDataJSON: clientDataJSON,
+			authData:       authData,
+			wantError:      "security key response not accepted: signature is not valid",
+		},
+	}
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			signCount, err := rp.VerifyAssertion(testChallenge, credential, &AssertionResponse{
+				CredentialID: tt.credentialID, ClientDataJSON: tt.clientDataJSON, AuthenticatorData: tt.authData, Signature: tt.signature,
+			})
+			require.EqualError(t, err, tt.wantError)
+			require.ErrorIs(t, err, ErrNotAccepted)
+			require.Zero(t, signCount)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package authorizationcode
@@ -34,7 +34,10 @@ const (
 	// Version 4 is when fosite added json tags to their openid.DefaultSession struct.
 	// Version 5 is when we added the UpstreamUsername and UpstreamGroups fields to psession.CustomSessionData.
 	// Version 6 is when we upgraded fosite in Dec 2023.
-	authorizeCodeStorageVersion = "6"
+	// Version 7 is when we added the UpstreamClaims field to psession.CustomSessionData.
+	// Version 8 is when we added the AdditionalClaimsFromTransforms field to psession.CustomSessionData.
+	// Version 9 is when we added the DownstreamGroups field to psession.CustomSessionData.
+	authorizeCodeStorageVersion = "9"
 )
 
 var _ oauth2.AuthorizeCodeStorage = &authorizeCodeStorage{}
@@ -258,128 +261,161 @@ const ExpectedAuthorizeCodeSessionJSONFromFuzzing = `{
 			"token_endpoint_auth_signing_alg": "ưƓǴ罷ǹ~]ea胠Ĺĩv絹b垇I"
 		},
 		"scopes": [
-			"ĩǀŻQ'k頂箨J-a",
-			"ɓ啶#昏Q遐*\\髎bŸ1慂U"
+			"妶ǵ!ȁu狍ɶȳsčɦƦ诱"
 		],
 		"grantedScopes": [
-			"ƼĮǡ鑻Z¥篚h°ʣ£ǖ%\"砬ʍ"
+			"攬林Ñz焁糳¿o\u003eQ鱙翑ȲŻ",
+			"锰劝旣樎Ȱ鍌#ȳńƩŴȭ"
 		],
 		"form": {
-			"¡": [
-				"Ła卦牟懧¥ɂĵ",
-				"ɎǛƍdÚ慂+槰蚪i齥篗裢?霃谥vƘ:",
-				"/濔Aʉ\u003cS獾蔀OƭUǦ"
+			"N檇雨缠蕖¤'+ʣ": [
+				"\u0026ɽ艄ʬʏ"
 			],
-			"民撲ʓeŘ嬀j¤囡莒汗狲N\u003cCq": [
-				"5ȏ樛ȧ.mĔ櫓Ǩ療騃Ǐ}ɟ",
-				"潠[ĝU噤'",
-				"ŁȗɉY妶ǵ!ȁ"
+			"TFǊĆw宵ɚeY48珎²Lcé": [
+				"觢Û±"
 			],
-			"褰ʎɰ癟VĎĢ婄磫绒u妔隤ʑƍš駎竪": [
-				"鱙翑ȲŻ麤ã桒嘞\\摗Ǘū稖咾鎅ǸÖ"
+			"鲶H股ƲLŋZ-{5£踉": [
+				"5^驜Ŗ~ů崧軒q腟u尿",
+				"ğ"
 			]
 		},
 		"session": {
 			"fosite": {
 				"id_token_claims": {
-					"jti": "褗6巽ēđų蓼tùZ蛆鬣a\"ÙǞ0觢",
-					"iss": "j¦鲶H股ƲLŋZ-{",
-					"sub": "ehpƧ蓟",
+					"jti": "ǫ\\aȊ4ț髄AlȒ",
+					"iss": "_袻vÓG-壧丵礴鋈k蟵pAɂʅ噪",
+					"sub": "\u0026PƢ曰l騌蘙螤\\阏Đ镴Ƥm蔻ǭ\\鿞Č",
 					"aud": [
-						"驜Ŗ~ů崧軒q腟u尿宲!"
+						"騒濒鑳绪HrǓ\\BRë_g\"ʎ啴SƇ",
+						"Č{Ȩʦ4撎胬龯,t猟"
 					],
-					"nonce": "ǎ^嫯R忑隯ƗƋ*L\u0026",
-					"exp": "1989-06-02T14:40:29.613836765Z",
-					"iat": "2052-03-26T02:39:27.882495556Z",
-					"rat": "2038-04-06T10:46:24.698586972Z",
-					"auth_time": "2003-01-05T11:30:18.206004879Z",
-					"at_hash": "ğǫ\\aȊ4ț髄Al",
-					"acr": "曓蓳n匟鯘磹*金爃鶴滱ůĮǐ_c3#",
+					"nonce": "郂üţ",
+					"exp": "2056-02-18T11:02:06.41772941Z",
+					"iat": "2019-10-31T12:28:35.603806848Z",
+					"rat": "2090-12-04T13:45:43.757115889Z",
+					"auth_time": "1982-04-18T09:24:15.317737386Z",
+					"at_hash": "Ǫ飘ȱF?",
+					"acr": "ğ~劰û橸",
 					"amr": [
-						"装ƹýĸŴB岺Ð嫹Sx镯荫őł疂ư墫"
+						"旎Ȳ濡胉室癑勦e骲v0H晦XŘO溪"
 					],
-					"c_hash": "\u0026鶡",
+					"c_hash": "屃ȹ碼Ǫ曞耕ȣ甽4Ǟ",
 					"ext": {
-						"rǓ\\BRë_g\"ʎ啴SƇMǃļū": {
-							"4撎胬龯,t猟i\u0026\u0026Q@ǤǟǗ": [
-								1239190737
+						"Bd謺錳4帳ŅǃĊdŘ鸨EJ毕": 3703211980,
+						"řĬń戹%c%稒趘ɆƊ#XɗD愌铵ĸY": {
+							",Ǘ饮欥": [
+								367102170
 							],
-							"飘ȱF?Ƈ畋": {
-								"劰û橸ɽ銐ƭ?}HƟ玈鳚": null,
-								"骲v0H晦XŘO溪V蔓Ȍ+~ē埅Ȝ": {
-									"4Ǟ": false
-								}
+							"膘)渽圭": {
+								".醋fʜ3": {
+									"ɦüHêQ仏1őƖ2Ė暮唍ǞʜƢú4": false
+								},
+								"a|载ǰɱ汶C]ɲ'=ĸ闒NȢ": null
 							}
-						},
-						"鑳绪": 2738428764
+						}
 					}
 				},
 				"headers": {
 					"extra": {
-						"d謺錳4帳ŅǃĊ": 663773398,
-						"Ř鸨EJ": {
-							"Ǽǟ迍阊v\"豑觳翢砜": [
-								995342744
-							],
-							"ȏl鐉诳DT=3骜Ǹ": {
-								"厷ɁOƪ穋嶿鳈恱va|载ǰɱ汶C]ɲ": null,
-								"荤Ý呐ʣ®ǅȪǣǎǔ爣縗ɦü": {
-									"H :靥湤庤毩fɤȆʪ融ƆuŤn": true
-								}
-							}
+						"+韁臯氃妪婝rȤ\"h丬鎒ơ娻}ɼƟȥE": 1475316937,
+						"嫌ɶȤ\u0026¥潝邎": {
+							"檄¬mrŉ2": {
+								".悃UƎȣ掘ʃƸ澺": {
+									"©Ź榨Q|ôɵt毇妬\u003e6鉢緋u": false
+								},
+								"餧Ĭ倏4ĵ嶼仒篻ɥ闣ʬ橳(ý綃ʃʚƟ": null
+							},
+							"莛8嘶×姮c恭企Ź邖ɐ": [
+								2398748717
+							]
 						}
 					}
 				},
 				"expires_at": {
-					"韁臯氃妪婝rȤ\"h丬鎒ơ娻}ɼƟ": "1970-04-27T04:31:30.902468229Z"
+					"ʚ£:設虝27就伒犘c钡ɏȫ齁š%O": "2067-07-23T06:07:32.637553013Z",
+					"概÷驣7Ʀ澉": "1979-11-11T20:59:28.367928651Z"
 				},
-				"username": "髉龳ǽÙ",
-				"subject": "\u0026¥潝邎Ȗ莅ŝǔ盕戙鵮碡ʯiŬŽ"
+				"username": "]ȗ韚ʫ繕ȫ碰+ʫ怓曥",
+				"subject": "帴ʘ赱ŕ瑹xȢ~1Įx欼笝?ú"
 			},
 			"custom": {
-				"username": "Ĝ眧Ĭ",
-				"upstreamUsername": "ŉ2ƋŢ觛ǂ焺nŐǛ",
+				"username": "妼É4İ\u003e×1飞O+î艔垎0OƉ",
+				"upstreamUsername": "%Ä摱ìÓȐĨf跞@)¿,ɭS隑i",
 				"upstreamGroups": [
-					"闣ʬ橳(ý綃ʃʚƟ覣k眐4Ĉt",
-					"ʃƸ澺淗a紽ǒ|鰽ŋ猊Ia瓕巈環_ɑ"
+					"艱iYn面@yȝƋ鬯犦獢9c5¤.岵",
+					"浛a齙\\蹼偦歛"
+				],
+				"upstreamClaims": {
+					" 皦pSǬŝ社Vƅȭǝ*擦28ǅ": 773536588,
+					"甍 ć\u003cʘ筫": {
+						"$+溪ŸȢŒų崓ļ憽": {
+							"囤1+,Ȳ齠@ɍB鳛Nč乿ƔǴę鏶": {
+								"ĩŦʀ宍D挟": false
+							},
+							"姧骦:駝重EȫʆɵʮGɃ": null
+						},
+						"蛖a³2ʫ承dʬ)ġ,TÀqy_": [
+							3252436752
+						]
+					}
+				},
+				"additionalClaimsFromTransforms": {
+					"H6b璡Ȟ2\\袓,5JƊ": 1090096263,
+					"x荃墎]ac[": {
+						"â融貵捠ŉ": [
+							3443095212
+						],
+						"緃責cpbɋ抿*泡hUɨ": {
+							"籌": null,
+							"鹠NƤ鷒": {
+								"Ķěå": false
+							}
+						}
+					}
+				},
+				"downstreamGroups": [
+					"ƍ逤ŔfȀ箬+橇肅aā鲴ļt}",
+					"T1峱ĊYů7ɼȣʒM弰(ǙȞ崂硠C",
+					"者ƪɗǋ憵芧Ǡt狥[N莈此ŵG"
 				],
-				"providerUID": "ƴŤȱʀļÂ?墖",
-				"providerName": "7就伒犘c钡",
-				"providerType": "k|鬌R蜚蠣麹概÷驣7Ʀ澉1æɽ誮",
+				"providerUID": "ǐɤ椟ȮɄ",
+				"providerName": "貧ɔǟC½ư3f",
+				"providerType": "浽Ȕ鑇Å睰ǎƳƺɸC/İ",
 				"warnings": [
-					"鷞aŚB碠k9帴ʘ赱",
-					"ď逳鞪?3)藵睋邔\u0026Ű惫蜀Ģ¡圔"
+					"¨|Y弴hǇ觃趿Ȝa榏熷戒篓Ĳƺ燅ňƳ"
 				],
 				"oidc": {
-					"upstreamRefreshToken": "墀jMʥ",
-					"upstreamAccessToken": "+î艔垎0",
-					"upstreamSubject": "ĝ",
-					"upstreamIssuer": "ǢIȽ"
+					"upstreamRefreshToken": "g塡ÑW",
+					"upstreamAccessToken": "mYʫQÁ嫧ɍ",
+					"upstreamSubject": "姘瞷",
+					"upstreamIssuer": "%WqCdēr"
 				},
 				"ldap": {
-					"userDN": "士b",
+					"userDN": "¼鶕f竍ʛle梦q环mN穴əz騹",
 					"extraRefreshAttributes": {
-						"O灞浛a齙\\蹼偦歛ơ 皦pSǬŝ": "ǅķ?吭匞饫Ƽĝ\"zvư",
-						"f跞@)¿,ɭS隑ip偶宾儮猷": "面@yȝƋ鬯犦獢9c5¤"
+						"囄¢": "ù弼ĉ簺aE",
+						"鴫欥Ɓ象5柩Ȍ[Ʃ郌韣Ǣ27Ȅ5µTɠ": "ĺĴ鑵SŮ弉p阚ÉI\u0026茛Ʊ螥殺ȟ"
 					}
 				},
 				"activedirectory": {
-					"userDN": "置b",
+					"userDN": "LO",
 					"extraRefreshAttributes": {
-						"MN\u0026錝D肁Ŷɽ蔒PR}Ųʓl{鼐": "$+溪ŸȢŒų崓ļ憽",
-						"ĩŦʀ宍D挟": "q萮左/篣AÚƄŕ~čfVLPC諡}",
-						"姧骦:駝重EȫʆɵʮGɃ": "囤1+,Ȳ齠@ɍB鳛Nč乿ƔǴę鏶"
+						"4": "E挔窈秚pÔƐKƗnȤ嬅fɦ狍Ǿ",
+						"eE諠ʣ靰aȢ¦B": "兿ǆɦĤÊ丙;L梙»腘N]衖薎m"
 					}
 				}
 			}
 		},
 		"requestedAudience": [
-			"ň"
+			"熄",
+			"酆ú",
+			"ODƊ淓ƙ"
 		],
 		"grantedAudience": [
-			"â融貵捠ŉ",
-			"d鞕ȸ腿tʏƲ%}ſ¯Ɣ 籌Tǘ乚Ȥ2"
+			"瑞\"嗵",
+			"wí讃仄-?埩",
+			"塯櫷ā嫁U貔ɝN昣鞵qÂƹO~崈5%#"
 		]
 	},
-	"version": "6"
+	"version": "9"
 }`
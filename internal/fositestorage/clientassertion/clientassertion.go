@@ -0,0 +1,102 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clientassertion stores the IDs of the JWTs which clients have used to authenticate
+// with the private_key_jwt client authentication method, so that each JWT can only be used once.
+package clientassertion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "client-assertion"
+
+	ErrInvalidClientAssertionVersion = constable.Error("client assertion data has wrong version")
+
+	// Version 1 was the initial release of storage.
+	clientAssertionStorageVersion = "1"
+)
+
+// Storage remembers the IDs of the client assertion JWTs which were already used.
+type Storage interface {
+	// ClientAssertionJWTValid returns an error if the JTI is known or the storage check failed,
+	// and nil if the JTI is not known.
+	ClientAssertionJWTValid(ctx context.Context, jti string) error
+
+	// SetClientAssertionJWT marks a JTI as known until the given expiry time.
+	SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error
+}
+
+type clientAssertionStorage struct {
+	storage  crud.Storage
+	clock    func() time.Time
+	lifetime time.Duration
+}
+
+type session struct {
+	Expiry  time.Time `json:"expiry"`
+	Version string    `json:"version"`
+}
+
+// New returns a Storage which stores each JTI for the given lifetime. Client assertions which expire after
+// that lifetime are rejected, because they could be used again after their JTI was garbage collected.
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) Storage {
+	return &clientAssertionStorage{
+		storage:  crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime),
+		clock:    clock,
+		lifetime: sessionStorageLifetime,
+	}
+}
+
+func (c *clientAssertionStorage) ClientAssertionJWTValid(ctx context.Context, jti string) error {
+	session := &session{}
+	_, err := c.storage.Get(ctx, signature(jti), session)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get client assertion: %w", err)
+	}
+
+	if version := session.Version; version != clientAssertionStorageVersion {
+		return fmt.Errorf("%w: client assertion has version %s instead of %s",
+			ErrInvalidClientAssertionVersion, version, clientAssertionStorageVersion)
+	}
+
+	// Even when the stored JTI has expired, it is still known until it is garbage collected.
+	return fosite.ErrJTIKnown
+}
+
+func (c *clientAssertionStorage) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
+	if exp.After(c.clock().Add(c.lifetime)) {
+		return fosite.ErrInvalidClient.WithHintf("Claim 'exp' from 'client_assertion' must not be more than %s in the future.", c.lifetime)
+	}
+
+	_, err := c.storage.Create(ctx, signature(jti), &session{Expiry: exp.UTC(), Version: clientAssertionStorageVersion}, nil, nil)
+	if errors.IsAlreadyExists(err) {
+		// Another request used the same JTI after this request checked it.
+		return fosite.ErrJTIKnown.WithHint("Claim 'jti' from 'client_assertion' MUST only be used once.")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create client assertion: %w", err)
+	}
+	return nil
+}
+
+// signature hashes the JTI, which is chosen by the client and may have any length, into a valid storage key.
+func signature(jti string) string {
+	hash := sha256.Sum256([]byte(jti))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
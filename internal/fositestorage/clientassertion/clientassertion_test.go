@@ -0,0 +1,128 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientassertion
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = time.Hour
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestClientAssertionStorage(t *testing.T) {
+	secretsGVR := schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "secrets",
+	}
+
+	const secretName = "pinniped-storage-client-assertion-lsunzjls3sazqgwsvfcf2ngbwgoo7j2us6x3svct3vpojc5mty7q"
+
+	wantActions := []coretesting.Action{
+		coretesting.NewGetAction(secretsGVR, namespace, secretName),
+		coretesting.NewCreateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            secretName,
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type": "client-assertion",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"expiry":"2030-01-01T00:05:00Z","version":"1"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/client-assertion",
+		}),
+		coretesting.NewGetAction(secretsGVR, namespace, secretName),
+		coretesting.NewCreateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            secretName,
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type": "client-assertion",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"expiry":"2030-01-01T00:10:00Z","version":"1"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/client-assertion",
+		}),
+	}
+
+	ctx, client, storage := makeTestSubject()
+
+	require.NoError(t, storage.ClientAssertionJWTValid(ctx, "some-jti"))
+	require.NoError(t, storage.SetClientAssertionJWT(ctx, "some-jti", fakeNow.Add(5*time.Minute)))
+
+	err := storage.ClientAssertionJWTValid(ctx, "some-jti")
+	require.ErrorIs(t, err, fosite.ErrJTIKnown)
+
+	err = storage.SetClientAssertionJWT(ctx, "some-jti", fakeNow.Add(10*time.Minute))
+	require.ErrorIs(t, err, fosite.ErrJTIKnown)
+	require.Equal(t, "Claim 'jti' from 'client_assertion' MUST only be used once.", fosite.ErrorToRFC6749Error(err).HintField)
+
+	require.Equal(t, wantActions, client.Actions())
+}
+
+func TestClientAssertionStorageRejectsLongLivedAssertions(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	err := storage.SetClientAssertionJWT(ctx, "some-jti", fakeNow.Add(lifetime+time.Second))
+	require.ErrorIs(t, err, fosite.ErrInvalidClient)
+	require.Equal(t, "Claim 'exp' from 'client_assertion' must not be more than 1h0m0s in the future.", fosite.ErrorToRFC6749Error(err).HintField)
+
+	require.NoError(t, storage.SetClientAssertionJWT(ctx, "some-other-jti", fakeNow.Add(lifetime)))
+	require.Len(t, client.Actions(), 1)
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-client-assertion-lsunzjls3sazqgwsvfcf2ngbwgoo7j2us6x3svct3vpojc5mty7q",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "client-assertion",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"expiry":"2030-01-01T00:05:00Z","version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/client-assertion",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	err := storage.ClientAssertionJWTValid(ctx, "some-jti")
+	require.EqualError(t, err, "client assertion data has wrong version: client assertion has version not-the-right-version instead of 1")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetime)
+}
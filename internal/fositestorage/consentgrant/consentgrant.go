@@ -0,0 +1,116 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package consentgrant remembers which scopes each user has approved for each client on the consent page.
+package consentgrant
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "consent-grant"
+
+	ErrInvalidConsentGrantVersion = constable.Error("consent grant data has wrong version")
+
+	// Version 1 was the initial release of storage.
+	consentGrantStorageVersion = "1"
+)
+
+// Storage remembers the scopes which users have approved.
+type Storage interface {
+	// GetApprovedScopes returns the scopes which the user has approved for the client, or nil when the user
+	// has not approved any scopes for the client or when the approval has expired.
+	GetApprovedScopes(ctx context.Context, subject string, clientID string) ([]string, error)
+
+	// ApproveScopes remembers that the user has approved the scopes for the client, in addition to any
+	// scopes which were already approved.
+	ApproveScopes(ctx context.Context, subject string, clientID string, scopes []string) error
+}
+
+type consentGrantStorage struct {
+	storage crud.Storage
+}
+
+type session struct {
+	Subject  string   `json:"subject"`
+	ClientID string   `json:"clientID"`
+	Scopes   []string `json:"scopes"`
+	Version  string   `json:"version"`
+}
+
+// New returns a Storage which remembers each approval for the given lifetime. Approving more scopes for the same
+// client does not extend the lifetime of the earlier approval.
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) Storage {
+	return &consentGrantStorage{storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime)}
+}
+
+func (c *consentGrantStorage) GetApprovedScopes(ctx context.Context, subject string, clientID string) ([]string, error) {
+	grant, _, err := c.get(ctx, subject, clientID)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return grant.Scopes, nil
+}
+
+func (c *consentGrantStorage) ApproveScopes(ctx context.Context, subject string, clientID string, scopes []string) error {
+	grant, resourceVersion, err := c.get(ctx, subject, clientID)
+	if errors.IsNotFound(err) {
+		_, err = c.storage.Create(ctx, signature(subject, clientID), &session{
+			Subject:  subject,
+			ClientID: clientID,
+			Scopes:   sets.List(sets.New(scopes...)),
+			Version:  consentGrantStorageVersion,
+		}, nil, nil)
+		if err != nil {
+			return fmt.Errorf("failed to create consent grant: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	grant.Scopes = sets.List(sets.New(grant.Scopes...).Insert(scopes...))
+	if _, err := c.storage.Update(ctx, signature(subject, clientID), resourceVersion, grant); err != nil {
+		return fmt.Errorf("failed to update consent grant: %w", err)
+	}
+	return nil
+}
+
+func (c *consentGrantStorage) get(ctx context.Context, subject string, clientID string) (*session, string, error) {
+	grant := &session{}
+	resourceVersion, err := c.storage.Get(ctx, signature(subject, clientID), grant)
+	if errors.IsNotFound(err) {
+		return nil, "", err
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get consent grant: %w", err)
+	}
+
+	if version := grant.Version; version != consentGrantStorageVersion {
+		return nil, "", fmt.Errorf("%w: consent grant has version %s instead of %s",
+			ErrInvalidConsentGrantVersion, version, consentGrantStorageVersion)
+	}
+	return grant, resourceVersion, nil
+}
+
+// signature hashes the downstream subject of the user and the client ID into a valid storage key.
+func signature(subject string, clientID string) string {
+	hash := sha256.Sum256([]byte(clientID + "\x00" + subject))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
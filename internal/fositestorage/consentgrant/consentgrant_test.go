@@ -0,0 +1,95 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consentgrant
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = 30 * 24 * time.Hour
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestConsentGrantStorage(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	scopes, err := storage.GetApprovedScopes(ctx, "some-subject", "some-client")
+	require.NoError(t, err)
+	require.Nil(t, scopes)
+
+	require.NoError(t, storage.ApproveScopes(ctx, "some-subject", "some-client", []string{"openid", "groups", "openid"}))
+
+	scopes, err = storage.GetApprovedScopes(ctx, "some-subject", "some-client")
+	require.NoError(t, err)
+	require.Equal(t, []string{"groups", "openid"}, scopes)
+
+	// Approving more scopes adds them to the scopes which were already approved.
+	require.NoError(t, storage.ApproveScopes(ctx, "some-subject", "some-client", []string{"openid", "offline_access"}))
+
+	scopes, err = storage.GetApprovedScopes(ctx, "some-subject", "some-client")
+	require.NoError(t, err)
+	require.Equal(t, []string{"groups", "offline_access", "openid"}, scopes)
+
+	// Approvals are remembered separately for each user and each client.
+	scopes, err = storage.GetApprovedScopes(ctx, "some-other-subject", "some-client")
+	require.NoError(t, err)
+	require.Nil(t, scopes)
+	scopes, err = storage.GetApprovedScopes(ctx, "some-subject", "some-other-client")
+	require.NoError(t, err)
+	require.Nil(t, scopes)
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	secret := secrets.Items[0]
+	require.Equal(t, "pinniped-storage-consent-grant-o62vrziz665lbe3rbgvzmcakrbypnttwedf42oztbgr6tsozmnga", secret.Name)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "consent-grant"}, secret.Labels)
+	// Approving more scopes did not extend the lifetime of the earlier approval.
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString}, secret.Annotations)
+	require.JSONEq(t,
+		`{"subject":"some-subject","clientID":"some-client","scopes":["groups","offline_access","openid"],"version":"1"}`,
+		string(secret.Data["pinniped-storage-data"]))
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-consent-grant-o62vrziz665lbe3rbgvzmcakrbypnttwedf42oztbgr6tsozmnga",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "consent-grant",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"subject":"some-subject","clientID":"some-client","scopes":["openid"],"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/consent-grant",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, err := storage.GetApprovedScopes(ctx, "some-subject", "some-client")
+	require.EqualError(t, err, "consent grant data has wrong version: consent grant has version not-the-right-version instead of 1")
+
+	err = storage.ApproveScopes(ctx, "some-subject", "some-client", []string{"openid"})
+	require.EqualError(t, err, "consent grant data has wrong version: consent grant has version not-the-right-version instead of 1")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetime)
+}
@@ -0,0 +1,149 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package consentrequest stores the authorization requests which are waiting for users to approve their scopes
+// on the consent page.
+package consentrequest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	TypeLabelValue = "consent-request"
+
+	ErrInvalidConsentRequestVersion = constable.Error("consent request data has wrong version")
+	ErrInvalidConsentRequestData    = constable.Error("consent request data must not be nil")
+
+	// Version 1 was the initial release of storage.
+	consentRequestStorageVersion = "1"
+)
+
+// ConsentRequest is an authorization request which is waiting for the user to approve its scopes. The user has
+// already logged in, so it holds the user's downstream session, which may include upstream tokens.
+type ConsentRequest struct {
+	// AuthParams are the params of the original authorization request.
+	AuthParams string `json:"authParams"`
+
+	// CSRFToken is the value of the CSRF cookie of the browser which was used to log in. Only that browser
+	// may approve or deny the request.
+	CSRFToken csrftoken.CSRFToken `json:"csrfToken"`
+
+	// Session is the downstream session which will be used to issue the authcode when the user approves the request.
+	Session *psession.PinnipedSession `json:"session"`
+
+	// Denied means that the user already denied the request. Denied requests are kept until they are garbage
+	// collected, so the garbage collector can revoke any upstream tokens which are held by the session.
+	Denied bool `json:"denied"`
+
+	// Version is the version of the storage format.
+	Version string `json:"version"`
+}
+
+// Storage stores consent requests.
+type Storage interface {
+	CreateConsentRequest(ctx context.Context, id string, request *ConsentRequest) error
+	GetConsentRequest(ctx context.Context, id string) (*ConsentRequest, error)
+	DenyConsentRequest(ctx context.Context, id string) error
+	DeleteConsentRequest(ctx context.Context, id string) error
+}
+
+type consentRequestStorage struct {
+	storage crud.Storage
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) Storage {
+	return &consentRequestStorage{storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime)}
+}
+
+// ReadFromSecret reads the contents of a Secret as a ConsentRequest.
+func ReadFromSecret(secret *corev1.Secret) (*ConsentRequest, error) {
+	request := &ConsentRequest{}
+	if err := crud.FromSecret(TypeLabelValue, secret, request); err != nil {
+		return nil, err
+	}
+	if err := validate(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (c *consentRequestStorage) CreateConsentRequest(ctx context.Context, id string, request *ConsentRequest) error {
+	if request == nil || request.Session == nil {
+		return ErrInvalidConsentRequestData
+	}
+
+	toStore := *request
+	toStore.Version = consentRequestStorageVersion
+
+	_, err := c.storage.Create(ctx, signature(id), &toStore, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create consent request: %w", err)
+	}
+	return nil
+}
+
+func (c *consentRequestStorage) GetConsentRequest(ctx context.Context, id string) (*ConsentRequest, error) {
+	request, _, err := c.get(ctx, id)
+	return request, err
+}
+
+func (c *consentRequestStorage) DenyConsentRequest(ctx context.Context, id string) error {
+	request, resourceVersion, err := c.get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	request.Denied = true
+
+	// Update uses the resource version, so only one concurrent request can deny the consent request.
+	if _, err := c.storage.Update(ctx, signature(id), resourceVersion, request); err != nil {
+		return fmt.Errorf("failed to update consent request: %w", err)
+	}
+	return nil
+}
+
+func (c *consentRequestStorage) DeleteConsentRequest(ctx context.Context, id string) error {
+	return c.storage.Delete(ctx, signature(id))
+}
+
+func (c *consentRequestStorage) get(ctx context.Context, id string) (*ConsentRequest, string, error) {
+	request := &ConsentRequest{}
+	resourceVersion, err := c.storage.Get(ctx, signature(id), request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get consent request: %w", err)
+	}
+	if err := validate(request); err != nil {
+		return nil, "", err
+	}
+	return request, resourceVersion, nil
+}
+
+func validate(request *ConsentRequest) error {
+	if version := request.Version; version != consentRequestStorageVersion {
+		return fmt.Errorf("%w: consent request has version %s instead of %s",
+			ErrInvalidConsentRequestVersion, version, consentRequestStorageVersion)
+	}
+	if request.Session == nil || request.Session.Custom == nil {
+		return ErrInvalidConsentRequestData
+	}
+	return nil
+}
+
+// signature hashes the request ID, which is also sent to the browser, so it is not used as the storage key directly.
+func signature(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package consentrequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/psession"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = 30 * time.Minute
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestConsentRequestStorage(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	request := &ConsentRequest{
+		AuthParams: "client_id=some-client",
+		CSRFToken:  "some-csrf-token",
+		Session: &psession.PinnipedSession{
+			Custom: &psession.CustomSessionData{Username: "some-username"},
+		},
+	}
+	require.NoError(t, storage.CreateConsentRequest(ctx, "some-id", request))
+	require.Empty(t, request.Version, "the caller's request should not be modified")
+
+	got, err := storage.GetConsentRequest(ctx, "some-id")
+	require.NoError(t, err)
+	require.Equal(t, "client_id=some-client", got.AuthParams)
+	require.Equal(t, "some-csrf-token", string(got.CSRFToken))
+	require.Equal(t, "some-username", got.Session.Custom.Username)
+	require.False(t, got.Denied)
+	require.Equal(t, "1", got.Version)
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, "pinniped-storage-consent-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "consent-request"}, secret.Labels)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString}, secret.Annotations)
+
+	readFromSecret, err := ReadFromSecret(secret)
+	require.NoError(t, err)
+	require.Equal(t, got, readFromSecret)
+
+	require.NoError(t, storage.DenyConsentRequest(ctx, "some-id"))
+
+	got, err = storage.GetConsentRequest(ctx, "some-id")
+	require.NoError(t, err)
+	require.True(t, got.Denied)
+
+	require.NoError(t, storage.DeleteConsentRequest(ctx, "some-id"))
+
+	_, err = storage.GetConsentRequest(ctx, "some-id")
+	require.True(t, errors.IsNotFound(err))
+	require.EqualError(t, err, `failed to get consent request: failed to get consent-request for signature mq67vT-P5lG4foNjBFWnCkOWTGmRB3u0UL8aPtLSXMw: secrets "pinniped-storage-consent-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga" not found`)
+
+	err = storage.DenyConsentRequest(ctx, "some-id")
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestCreateWithNilSession(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	require.ErrorIs(t, storage.CreateConsentRequest(ctx, "some-id", nil), ErrInvalidConsentRequestData)
+	require.ErrorIs(t, storage.CreateConsentRequest(ctx, "some-id", &ConsentRequest{}), ErrInvalidConsentRequestData)
+	require.Empty(t, client.Actions())
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-consent-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "consent-request",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"authParams":"","csrfToken":"","session":{"custom":{}},"denied":false,"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/consent-request",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, err := storage.GetConsentRequest(ctx, "some-id")
+	require.EqualError(t, err, "consent request data has wrong version: consent request has version not-the-right-version instead of 1")
+
+	_, err = ReadFromSecret(secret)
+	require.EqualError(t, err, "consent request data has wrong version: consent request has version not-the-right-version instead of 1")
+}
+
+func TestReadFromSecretWithoutSession(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-consent-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "consent-request",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"authParams":"","csrfToken":"","denied":false,"version":"1"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/consent-request",
+	}
+
+	_, err := ReadFromSecret(secret)
+	require.ErrorIs(t, err, ErrInvalidConsentRequestData)
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetime)
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ldapgroupcache remembers the group memberships which were found by the group searches of LDAP and
+// Active Directory identity providers, so that they can be shared by all Supervisor pods until they expire.
+package ldapgroupcache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/upstreamldap"
+)
+
+const (
+	TypeLabelValue = "ldap-group-cache"
+
+	// Version 1 was the initial release of storage.
+	groupCacheStorageVersion = "1"
+)
+
+// Cache is an upstreamldap.GroupCache which remembers each entry both in memory and in a Secret. The Secret allows
+// other Supervisor pods to use the entry, and the copy in memory avoids reading the Secret again on this pod.
+type Cache struct {
+	secrets corev1client.SecretInterface
+	clock   func() time.Time
+
+	lock    sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	groups    []string
+	expiresAt time.Time
+}
+
+type session struct {
+	Groups    []string  `json:"groups"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Version   string    `json:"version"`
+}
+
+var _ upstreamldap.GroupCache = (*Cache)(nil)
+
+// New returns an empty Cache which stores its entries in the given Secrets.
+func New(secrets corev1client.SecretInterface, clock func() time.Time) *Cache {
+	return &Cache{secrets: secrets, clock: clock, entries: map[string]*entry{}}
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]string, bool) {
+	now := c.clock()
+
+	c.lock.Lock()
+	e, found := c.entries[key]
+	c.lock.Unlock()
+	if found && now.Before(e.expiresAt) {
+		return e.groups, true
+	}
+
+	stored := &session{}
+	_, err := c.storage(0).Get(ctx, key, stored)
+	if errors.IsNotFound(err) {
+		return nil, false
+	}
+	if err != nil {
+		plog.WarningErr("failed to get LDAP group cache entry", err)
+		return nil, false
+	}
+	if stored.Version != groupCacheStorageVersion || !now.Before(stored.ExpiresAt) {
+		return nil, false
+	}
+
+	c.remember(key, stored.Groups, stored.ExpiresAt)
+	return stored.Groups, true
+}
+
+func (c *Cache) Put(ctx context.Context, key string, groups []string, ttl time.Duration) {
+	expiresAt := c.clock().Add(ttl)
+	c.remember(key, groups, expiresAt)
+
+	storage := c.storage(ttl)
+	stored := &session{Groups: groups, ExpiresAt: expiresAt, Version: groupCacheStorageVersion}
+	_, err := storage.Create(ctx, key, stored, nil, nil)
+	if errors.IsAlreadyExists(err) {
+		// Either the previous entry expired but was not garbage collected yet, or another pod just created
+		// an entry for the same key. Either way, replace it so that it will be garbage collected at the right time.
+		if err = storage.Delete(ctx, key); err == nil || errors.IsNotFound(err) {
+			_, err = storage.Create(ctx, key, stored, nil, nil)
+		}
+	}
+	if err != nil {
+		plog.WarningErr("failed to store LDAP group cache entry", err)
+	}
+}
+
+func (c *Cache) storage(lifetime time.Duration) crud.Storage {
+	return crud.New(TypeLabelValue, c.secrets, c.clock, lifetime)
+}
+
+// remember keeps an entry in memory, and forgets any other entries which have expired.
+func (c *Cache) remember(key string, groups []string, expiresAt time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := c.clock()
+	for k, e := range c.entries {
+		if !now.Before(e.expiresAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = &entry{groups: groups, expiresAt: expiresAt}
+}
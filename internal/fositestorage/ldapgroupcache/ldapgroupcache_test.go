@@ -0,0 +1,95 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ldapgroupcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	fakeClock := clocktesting.NewFakeClock(fakeNow)
+	cache := New(client.CoreV1().Secrets(namespace), fakeClock.Now)
+
+	groups, found := cache.Get(ctx, "some-key")
+	require.False(t, found)
+	require.Nil(t, groups)
+
+	cache.Put(ctx, "some-key", []string{"group1", "group2"}, 5*time.Minute)
+
+	groups, found = cache.Get(ctx, "some-key")
+	require.True(t, found)
+	require.Equal(t, []string{"group1", "group2"}, groups)
+
+	// Entries are remembered separately for each key.
+	_, found = cache.Get(ctx, "some-other-key")
+	require.False(t, found)
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	secret := secrets.Items[0]
+	require.Equal(t, "pinniped-storage-ldap-group-cache-wkez56shwi", secret.Name)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "ldap-group-cache"}, secret.Labels)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": "2030-01-01T00:05:00Z"}, secret.Annotations)
+	require.JSONEq(t,
+		`{"groups":["group1","group2"],"expiresAt":"2030-01-01T00:05:00Z","version":"1"}`,
+		string(secret.Data["pinniped-storage-data"]))
+
+	// Entries are shared with other caches, e.g. on other pods, through storage.
+	otherCache := New(client.CoreV1().Secrets(namespace), fakeClock.Now)
+	groups, found = otherCache.Get(ctx, "some-key")
+	require.True(t, found)
+	require.Equal(t, []string{"group1", "group2"}, groups)
+
+	// Entries expire after their TTL, even when they were not garbage collected yet.
+	fakeClock.Step(5 * time.Minute)
+	_, found = cache.Get(ctx, "some-key")
+	require.False(t, found)
+	_, found = otherCache.Get(ctx, "some-key")
+	require.False(t, found)
+
+	// Putting an entry again replaces the expired entry in storage.
+	cache.Put(ctx, "some-key", []string{"group3"}, time.Minute)
+	groups, found = New(client.CoreV1().Secrets(namespace), fakeClock.Now).Get(ctx, "some-key")
+	require.True(t, found)
+	require.Equal(t, []string{"group3"}, groups)
+
+	secrets, err = client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": "2030-01-01T00:06:00Z"}, secrets.Items[0].Annotations)
+}
+
+func TestCacheWhenStorageIsMissingTheEntry(t *testing.T) {
+	ctx := context.Background()
+	fakeClock := clocktesting.NewFakeClock(fakeNow)
+
+	// Delete the entry from storage after it was remembered in memory, as if it was garbage collected.
+	client := fake.NewSimpleClientset()
+	cache := New(client.CoreV1().Secrets(namespace), fakeClock.Now)
+	cache.Put(ctx, "some-key", []string{"group1"}, time.Minute)
+	require.NoError(t, client.CoreV1().Secrets(namespace).Delete(ctx, "pinniped-storage-ldap-group-cache-wkez56shwi", metav1.DeleteOptions{}))
+
+	// The copy in memory is still used.
+	groups, found := cache.Get(ctx, "some-key")
+	require.True(t, found)
+	require.Equal(t, []string{"group1"}, groups)
+
+	// Other caches do not find it.
+	_, found = New(client.CoreV1().Secrets(namespace), fakeClock.Now).Get(ctx, "some-key")
+	require.False(t, found)
+}
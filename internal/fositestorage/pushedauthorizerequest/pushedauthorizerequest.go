@@ -0,0 +1,109 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pushedauthorizerequest stores the params of the authorization requests which clients have pushed to the
+// pushed authorization request endpoint (RFC9126), until the user's browser brings the request_uri to the
+// authorization endpoint.
+package pushedauthorizerequest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/ory/fosite"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "pushed-authorize-request"
+
+	ErrInvalidPushedAuthorizeRequestVersion = constable.Error("pushed authorization request data has wrong version")
+	ErrPushedAuthorizeRequestExpired        = constable.Error("pushed authorization request has expired")
+
+	// Version 1 was the initial release of storage.
+	pushedAuthorizeRequestStorageVersion = "1"
+)
+
+type pushedAuthorizeRequestStorage struct {
+	storage crud.Storage
+	clock   func() time.Time
+}
+
+var _ fosite.PARStorage = &pushedAuthorizeRequestStorage{}
+
+type session struct {
+	ClientID    string     `json:"clientID"`
+	Form        url.Values `json:"form"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	Version     string     `json:"version"`
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) fosite.PARStorage {
+	return &pushedAuthorizeRequestStorage{
+		storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime),
+		clock:   clock,
+	}
+}
+
+// CreatePARSession stores the params of the pushed authorization request. Only the params are stored, because the
+// authorization endpoint validates them again as if the client had sent them to the authorization endpoint directly.
+func (p *pushedAuthorizeRequestStorage) CreatePARSession(ctx context.Context, requestURI string, request fosite.AuthorizeRequester) error {
+	expiresAt := time.Time{}
+	if request.GetSession() != nil {
+		expiresAt = request.GetSession().GetExpiresAt(fosite.PushedAuthorizeRequestContext)
+	}
+
+	_, err := p.storage.Create(ctx, signature(requestURI), &session{
+		ClientID:    request.GetClient().GetID(),
+		Form:        request.GetRequestForm(),
+		RequestedAt: request.GetRequestedAt(),
+		ExpiresAt:   expiresAt,
+		Version:     pushedAuthorizeRequestStorageVersion,
+	}, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create pushed authorization request: %w", err)
+	}
+	return nil
+}
+
+// GetPARSession returns an authorization request which holds the pushed params and the ID of the client which
+// pushed them. The client is not looked up again here, so it only has an ID.
+func (p *pushedAuthorizeRequestStorage) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	session := &session{}
+	if _, err := p.storage.Get(ctx, signature(requestURI), session); err != nil {
+		return nil, fmt.Errorf("failed to get pushed authorization request: %w", err)
+	}
+
+	if version := session.Version; version != pushedAuthorizeRequestStorageVersion {
+		return nil, fmt.Errorf("%w: pushed authorization request has version %s instead of %s",
+			ErrInvalidPushedAuthorizeRequestVersion, version, pushedAuthorizeRequestStorageVersion)
+	}
+
+	if !session.ExpiresAt.IsZero() && p.clock().After(session.ExpiresAt) {
+		return nil, ErrPushedAuthorizeRequestExpired
+	}
+
+	request := fosite.NewAuthorizeRequest()
+	request.Client = &fosite.DefaultClient{ID: session.ClientID}
+	request.Form = session.Form
+	request.RequestedAt = session.RequestedAt
+	return request, nil
+}
+
+func (p *pushedAuthorizeRequestStorage) DeletePARSession(ctx context.Context, requestURI string) error {
+	return p.storage.Delete(ctx, signature(requestURI))
+}
+
+// signature hashes the request_uri, which is also sent to the browser, so it is not used as the storage key directly.
+func signature(requestURI string) string {
+	hash := sha256.Sum256([]byte(requestURI))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,103 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pushedauthorizerequest
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	namespace  = "test-ns"
+	requestURI = "urn:ietf:params:oauth:request_uri:some-request-uri"
+	secretName = "pinniped-storage-pushed-authorize-request-v6ijpl6btoeius5r53arfemdoe2odyz2fokkq34vvj7w2meuhq7q"
+)
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = 5 * time.Minute
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestPushedAuthorizeRequestStorage(t *testing.T) {
+	ctx, client, fakeClock, storage := makeTestSubject()
+
+	form := url.Values{
+		"client_id":    []string{"some-client"},
+		"redirect_uri": []string{"https://example.com/callback"},
+		"scope":        []string{"openid username"},
+	}
+	require.NoError(t, storage.CreatePARSession(ctx, requestURI, newAuthorizeRequest(form, fakeNow.Add(lifetime))))
+
+	got, err := storage.GetPARSession(ctx, requestURI)
+	require.NoError(t, err)
+	require.Equal(t, "some-client", got.GetClient().GetID())
+	require.Equal(t, form, got.GetRequestForm())
+	require.Equal(t, fakeNow, got.GetRequestedAt())
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "pushed-authorize-request"}, secret.Labels)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString}, secret.Annotations)
+
+	fakeClock.Step(lifetime + time.Second)
+	_, err = storage.GetPARSession(ctx, requestURI)
+	require.ErrorIs(t, err, ErrPushedAuthorizeRequestExpired)
+
+	require.NoError(t, storage.DeletePARSession(ctx, requestURI))
+
+	_, err = storage.GetPARSession(ctx, requestURI)
+	require.True(t, errors.IsNotFound(err))
+	require.EqualError(t, err, `failed to get pushed authorization request: failed to get pushed-authorize-request for signature r5CXr8GbiIpLse7BEpGDcTTh4zorlKhvlap_bTCUPD8: secrets "`+secretName+`" not found`)
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, _, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "pushed-authorize-request",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"clientID":"some-client","form":{},"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/pushed-authorize-request",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, err := storage.GetPARSession(ctx, requestURI)
+	require.EqualError(t, err, "pushed authorization request data has wrong version: pushed authorization request has version not-the-right-version instead of 1")
+}
+
+func newAuthorizeRequest(form url.Values, expiresAt time.Time) fosite.AuthorizeRequester {
+	request := fosite.NewAuthorizeRequest()
+	request.Client = &fosite.DefaultClient{ID: "some-client"}
+	request.Form = form
+	request.RequestedAt = fakeNow
+	request.Session = psession.NewPinnipedSession()
+	request.Session.SetExpiresAt(fosite.PushedAuthorizeRequestContext, expiresAt)
+	return request
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, *clocktesting.FakeClock, fosite.PARStorage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	fakeClock := clocktesting.NewFakeClock(fakeNow)
+	return context.Background(), client, fakeClock, New(secrets, fakeClock.Now, lifetime)
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package refreshtoken
@@ -33,7 +33,10 @@ const (
 	// Version 4 is when fosite added json tags to their openid.DefaultSession struct.
 	// Version 5 is when we added the UpstreamUsername and UpstreamGroups fields to psession.CustomSessionData.
 	// Version 6 is when we upgraded fosite in Dec 2023.
-	refreshTokenStorageVersion = "6"
+	// Version 7 is when we added the UpstreamClaims field to psession.CustomSessionData.
+	// Version 8 is when we added the AdditionalClaimsFromTransforms field to psession.CustomSessionData.
+	// Version 9 is when we added the DownstreamGroups field to psession.CustomSessionData.
+	refreshTokenStorageVersion = "9"
 )
 
 type RevocationStorage interface {
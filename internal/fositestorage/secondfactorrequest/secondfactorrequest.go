@@ -0,0 +1,144 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secondfactorrequest stores the authorization requests of users who have entered their password on the
+// login page, and who are now being asked for their second authentication factor.
+package secondfactorrequest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	TypeLabelValue = "second-factor-request"
+
+	ErrInvalidSecondFactorRequestVersion = constable.Error("second factor request data has wrong version")
+	ErrInvalidSecondFactorRequestData    = constable.Error("second factor request data must not be nil")
+
+	// Version 1 was the initial release of storage. The WebAuthn fields were added later without changing the
+	// version, because requests which do not have them are TOTP requests, like before.
+	secondFactorRequestStorageVersion = "1"
+)
+
+// SecondFactorRequest is an authorization request which is waiting for the user to present their second
+// authentication factor. The user's password was already accepted, so it holds the user's downstream session.
+type SecondFactorRequest struct {
+	// AuthParams are the params of the original authorization request.
+	AuthParams string `json:"authParams"`
+
+	// CSRFToken is the value of the CSRF cookie of the browser which was used to log in. Only that browser
+	// may present the second factor.
+	CSRFToken csrftoken.CSRFToken `json:"csrfToken"`
+
+	// Session is the downstream session which will be used to issue the authcode after the user presented
+	// their second factor.
+	Session *psession.PinnipedSession `json:"session"`
+
+	// PendingTOTPSecret is the newly generated TOTP secret which the user is asked to enroll in their authenticator
+	// app, when the user has not enrolled one yet. It is empty when the user has already enrolled.
+	PendingTOTPSecret string `json:"pendingTOTPSecret,omitempty"`
+
+	// WebAuthnChallenge is the challenge which the user's security key or passkey must sign, when the
+	// FederationDomain requires WebAuthn. It is empty when the FederationDomain requires TOTP.
+	WebAuthnChallenge string `json:"webAuthnChallenge,omitempty"`
+
+	// PendingWebAuthnRegistration is true when the FederationDomain requires WebAuthn and the user has not
+	// registered a security key or passkey yet, so the user is asked to register one.
+	PendingWebAuthnRegistration bool `json:"pendingWebAuthnRegistration,omitempty"`
+
+	// FailedAttempts is the number of incorrect one-time passwords or security key responses which were
+	// submitted for this request.
+	FailedAttempts int `json:"failedAttempts"`
+
+	// Version is the version of the storage format.
+	Version string `json:"version"`
+}
+
+// Storage stores second factor requests.
+type Storage interface {
+	CreateSecondFactorRequest(ctx context.Context, id string, request *SecondFactorRequest) error
+	GetSecondFactorRequest(ctx context.Context, id string) (*SecondFactorRequest, error)
+	RecordFailedAttempt(ctx context.Context, id string) (int, error)
+	DeleteSecondFactorRequest(ctx context.Context, id string) error
+}
+
+type secondFactorRequestStorage struct {
+	storage crud.Storage
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime time.Duration) Storage {
+	return &secondFactorRequestStorage{storage: crud.New(TypeLabelValue, secrets, clock, sessionStorageLifetime)}
+}
+
+func (s *secondFactorRequestStorage) CreateSecondFactorRequest(ctx context.Context, id string, request *SecondFactorRequest) error {
+	if request == nil || request.Session == nil {
+		return ErrInvalidSecondFactorRequestData
+	}
+
+	toStore := *request
+	toStore.Version = secondFactorRequestStorageVersion
+
+	_, err := s.storage.Create(ctx, signature(id), &toStore, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create second factor request: %w", err)
+	}
+	return nil
+}
+
+func (s *secondFactorRequestStorage) GetSecondFactorRequest(ctx context.Context, id string) (*SecondFactorRequest, error) {
+	request, _, err := s.get(ctx, id)
+	return request, err
+}
+
+// RecordFailedAttempt counts an incorrect one-time password for the request, and returns the new count.
+func (s *secondFactorRequestStorage) RecordFailedAttempt(ctx context.Context, id string) (int, error) {
+	request, resourceVersion, err := s.get(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	request.FailedAttempts++
+
+	// Update uses the resource version, so concurrent attempts cannot overwrite each other's count.
+	if _, err := s.storage.Update(ctx, signature(id), resourceVersion, request); err != nil {
+		return 0, fmt.Errorf("failed to update second factor request: %w", err)
+	}
+	return request.FailedAttempts, nil
+}
+
+func (s *secondFactorRequestStorage) DeleteSecondFactorRequest(ctx context.Context, id string) error {
+	return s.storage.Delete(ctx, signature(id))
+}
+
+func (s *secondFactorRequestStorage) get(ctx context.Context, id string) (*SecondFactorRequest, string, error) {
+	request := &SecondFactorRequest{}
+	resourceVersion, err := s.storage.Get(ctx, signature(id), request)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get second factor request: %w", err)
+	}
+	if version := request.Version; version != secondFactorRequestStorageVersion {
+		return nil, "", fmt.Errorf("%w: second factor request has version %s instead of %s",
+			ErrInvalidSecondFactorRequestVersion, version, secondFactorRequestStorageVersion)
+	}
+	if request.Session == nil || request.Session.Custom == nil {
+		return nil, "", ErrInvalidSecondFactorRequestData
+	}
+	return request, resourceVersion, nil
+}
+
+// signature hashes the request ID, which is also sent to the browser, so it is not used as the storage key directly.
+func signature(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
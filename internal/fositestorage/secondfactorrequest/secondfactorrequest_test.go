@@ -0,0 +1,129 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package secondfactorrequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/psession"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+var lifetime = 10 * time.Minute
+var fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+
+func TestSecondFactorRequestStorage(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	request := &SecondFactorRequest{
+		AuthParams: "client_id=some-client",
+		CSRFToken:  "some-csrf-token",
+		Session: &psession.PinnipedSession{
+			Custom: &psession.CustomSessionData{Username: "some-username"},
+		},
+		PendingTOTPSecret: "some-secret",
+	}
+	require.NoError(t, storage.CreateSecondFactorRequest(ctx, "some-id", request))
+	require.Empty(t, request.Version, "the caller's request should not be modified")
+
+	got, err := storage.GetSecondFactorRequest(ctx, "some-id")
+	require.NoError(t, err)
+	require.Equal(t, "client_id=some-client", got.AuthParams)
+	require.Equal(t, "some-csrf-token", string(got.CSRFToken))
+	require.Equal(t, "some-username", got.Session.Custom.Username)
+	require.Equal(t, "some-secret", got.PendingTOTPSecret)
+	require.Zero(t, got.FailedAttempts)
+	require.Equal(t, "1", got.Version)
+
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, "pinniped-storage-second-factor-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "second-factor-request"}, secret.Labels)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString}, secret.Annotations)
+
+	failedAttempts, err := storage.RecordFailedAttempt(ctx, "some-id")
+	require.NoError(t, err)
+	require.Equal(t, 1, failedAttempts)
+	failedAttempts, err = storage.RecordFailedAttempt(ctx, "some-id")
+	require.NoError(t, err)
+	require.Equal(t, 2, failedAttempts)
+
+	got, err = storage.GetSecondFactorRequest(ctx, "some-id")
+	require.NoError(t, err)
+	require.Equal(t, 2, got.FailedAttempts)
+
+	require.NoError(t, storage.DeleteSecondFactorRequest(ctx, "some-id"))
+
+	_, err = storage.GetSecondFactorRequest(ctx, "some-id")
+	require.True(t, errors.IsNotFound(err))
+	require.EqualError(t, err, `failed to get second factor request: failed to get second-factor-request for signature mq67vT-P5lG4foNjBFWnCkOWTGmRB3u0UL8aPtLSXMw: secrets "pinniped-storage-second-factor-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga" not found`)
+
+	_, err = storage.RecordFailedAttempt(ctx, "some-id")
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestWebAuthnSecondFactorRequestStorage(t *testing.T) {
+	ctx, _, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateSecondFactorRequest(ctx, "some-id", &SecondFactorRequest{
+		AuthParams:                  "client_id=some-client",
+		CSRFToken:                   "some-csrf-token",
+		Session:                     &psession.PinnipedSession{Custom: &psession.CustomSessionData{Username: "some-username"}},
+		WebAuthnChallenge:           "some-challenge",
+		PendingWebAuthnRegistration: true,
+	}))
+
+	got, err := storage.GetSecondFactorRequest(ctx, "some-id")
+	require.NoError(t, err)
+	require.Empty(t, got.PendingTOTPSecret)
+	require.Equal(t, "some-challenge", got.WebAuthnChallenge)
+	require.True(t, got.PendingWebAuthnRegistration)
+}
+
+func TestCreateWithNilSession(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	require.ErrorIs(t, storage.CreateSecondFactorRequest(ctx, "some-id", nil), ErrInvalidSecondFactorRequestData)
+	require.ErrorIs(t, storage.CreateSecondFactorRequest(ctx, "some-id", &SecondFactorRequest{}), ErrInvalidSecondFactorRequestData)
+	require.Empty(t, client.Actions())
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-second-factor-request-tkxlxpj7r7tfdod6qnrqivnhbjbzmtdjsedxxncqx4nd5uwsltga",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "second-factor-request",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"authParams":"","csrfToken":"","session":{"custom":{}},"failedAttempts":0,"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/second-factor-request",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, err := storage.GetSecondFactorRequest(ctx, "some-id")
+	require.EqualError(t, err, "second factor request data has wrong version: second factor request has version not-the-right-version instead of 1")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetime)
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package totpenrollment stores the TOTP secrets of the authenticator apps which users have enrolled as their
+// second authentication factor.
+package totpenrollment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "totp-enrollment"
+
+	ErrInvalidTOTPEnrollmentVersion = constable.Error("totp enrollment data has wrong version")
+	ErrInvalidTOTPEnrollmentData    = constable.Error("totp enrollment data must have a secret")
+
+	// Version 1 was the initial release of storage. The failed attempt fields were added later without changing
+	// the version, because enrollments which do not have them simply have no recent failed attempts.
+	totpEnrollmentStorageVersion = "1"
+)
+
+// Enrollment is the authenticator app which a user has enrolled.
+type Enrollment struct {
+	// Subject is the downstream subject of the user.
+	Subject string `json:"subject"`
+
+	// Secret is the base32 encoded TOTP secret which is shared with the user's authenticator app.
+	Secret string `json:"secret"`
+
+	// LastUsedTimeStep is the time step of the most recent one-time password which was accepted,
+	// so that each one-time password can only be used once.
+	LastUsedTimeStep int64 `json:"lastUsedTimeStep"`
+
+	// FailedAttempts is how many incorrect one-time passwords were entered since the last one which was accepted.
+	FailedAttempts int `json:"failedAttempts,omitempty"`
+
+	// LockedUntil is when the user may try another one-time password, after entering too many incorrect ones.
+	LockedUntil time.Time `json:"lockedUntil"`
+
+	// Version is the version of the storage format.
+	Version string `json:"version"`
+}
+
+// Storage stores the enrollments of users. Enrollments never expire, so they are not garbage collected.
+// Deleting the Secret of an enrollment forces the user to enroll again during their next login.
+type Storage interface {
+	// GetEnrollment returns the enrollment of the user and its resource version, or nil when the user has not enrolled.
+	GetEnrollment(ctx context.Context, subject string) (*Enrollment, string, error)
+
+	// CreateEnrollment stores a new enrollment. It fails when the user has already enrolled.
+	CreateEnrollment(ctx context.Context, enrollment *Enrollment) error
+
+	// UpdateEnrollment updates an enrollment. It fails when the enrollment was updated since it was read, so
+	// concurrent logins cannot both use the same one-time password.
+	UpdateEnrollment(ctx context.Context, enrollment *Enrollment, resourceVersion string) error
+}
+
+type totpEnrollmentStorage struct {
+	storage crud.Storage
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time) Storage {
+	// A lifetime of zero means that enrollments are never garbage collected.
+	return &totpEnrollmentStorage{storage: crud.New(TypeLabelValue, secrets, clock, 0)}
+}
+
+func (t *totpEnrollmentStorage) GetEnrollment(ctx context.Context, subject string) (*Enrollment, string, error) {
+	enrollment := &Enrollment{}
+	resourceVersion, err := t.storage.Get(ctx, signature(subject), enrollment)
+	if errors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get totp enrollment: %w", err)
+	}
+
+	if version := enrollment.Version; version != totpEnrollmentStorageVersion {
+		return nil, "", fmt.Errorf("%w: totp enrollment has version %s instead of %s",
+			ErrInvalidTOTPEnrollmentVersion, version, totpEnrollmentStorageVersion)
+	}
+	if enrollment.Secret == "" {
+		return nil, "", ErrInvalidTOTPEnrollmentData
+	}
+	return enrollment, resourceVersion, nil
+}
+
+func (t *totpEnrollmentStorage) CreateEnrollment(ctx context.Context, enrollment *Enrollment) error {
+	if enrollment == nil || enrollment.Secret == "" {
+		return ErrInvalidTOTPEnrollmentData
+	}
+
+	toStore := *enrollment
+	toStore.Version = totpEnrollmentStorageVersion
+
+	if _, err := t.storage.Create(ctx, signature(enrollment.Subject), &toStore, nil, nil); err != nil {
+		return fmt.Errorf("failed to create totp enrollment: %w", err)
+	}
+	return nil
+}
+
+func (t *totpEnrollmentStorage) UpdateEnrollment(ctx context.Context, enrollment *Enrollment, resourceVersion string) error {
+	if enrollment == nil || enrollment.Secret == "" {
+		return ErrInvalidTOTPEnrollmentData
+	}
+
+	toStore := *enrollment
+	toStore.Version = totpEnrollmentStorageVersion
+
+	if _, err := t.storage.Update(ctx, signature(enrollment.Subject), resourceVersion, &toStore); err != nil {
+		return fmt.Errorf("failed to update totp enrollment: %w", err)
+	}
+	return nil
+}
+
+// signature hashes the downstream subject of the user into a valid storage key.
+func signature(subject string) string {
+	hash := sha256.Sum256([]byte(subject))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
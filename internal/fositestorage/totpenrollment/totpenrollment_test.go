@@ -0,0 +1,130 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package totpenrollment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestTOTPEnrollmentStorage(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	enrollment, resourceVersion, err := storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Nil(t, enrollment)
+	require.Empty(t, resourceVersion)
+
+	require.NoError(t, storage.CreateEnrollment(ctx, &Enrollment{Subject: "some-subject", Secret: "some-secret", LastUsedTimeStep: 42}))
+
+	// The same user cannot enroll twice.
+	err = storage.CreateEnrollment(ctx, &Enrollment{Subject: "some-subject", Secret: "some-other-secret"})
+	require.EqualError(t, err, `failed to create totp enrollment: failed to create totp-enrollment for signature `+
+		`bIxIBe8XfWCMYe8-6I0Z_rLyNMcrRfbUedc-PGQ4W6U: secrets "pinniped-storage-totp-enrollment-nsgeqbppc56wbddb547ordiz72zpenghfnc7nvdz247dyzbylosq" already exists`)
+
+	enrollment, resourceVersion, err = storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Equal(t, &Enrollment{Subject: "some-subject", Secret: "some-secret", LastUsedTimeStep: 42, Version: "1"}, enrollment)
+
+	enrollment.LastUsedTimeStep = 43
+	enrollment.FailedAttempts = 5
+	enrollment.LockedUntil = fakeNow.Add(time.Minute)
+	require.NoError(t, storage.UpdateEnrollment(ctx, enrollment, resourceVersion))
+
+	enrollment, _, err = storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Equal(t, int64(43), enrollment.LastUsedTimeStep)
+	require.Equal(t, 5, enrollment.FailedAttempts)
+	require.Equal(t, fakeNow.Add(time.Minute), enrollment.LockedUntil)
+
+	// Enrollments are stored separately for each user.
+	enrollment, _, err = storage.GetEnrollment(ctx, "some-other-subject")
+	require.NoError(t, err)
+	require.Nil(t, enrollment)
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	secret := secrets.Items[0]
+	require.Equal(t, "pinniped-storage-totp-enrollment-nsgeqbppc56wbddb547ordiz72zpenghfnc7nvdz247dyzbylosq", secret.Name)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "totp-enrollment"}, secret.Labels)
+	// Enrollments are never garbage collected.
+	require.Empty(t, secret.Annotations)
+	require.JSONEq(t,
+		`{"subject":"some-subject","secret":"some-secret","lastUsedTimeStep":43,"failedAttempts":5,"lockedUntil":"2030-01-01T00:01:00Z","version":"1"}`,
+		string(secret.Data["pinniped-storage-data"]))
+}
+
+func TestInvalidEnrollments(t *testing.T) {
+	ctx, _, storage := makeTestSubject()
+
+	require.ErrorIs(t, storage.CreateEnrollment(ctx, nil), ErrInvalidTOTPEnrollmentData)
+	require.ErrorIs(t, storage.CreateEnrollment(ctx, &Enrollment{Subject: "some-subject"}), ErrInvalidTOTPEnrollmentData)
+	require.ErrorIs(t, storage.UpdateEnrollment(ctx, &Enrollment{Subject: "some-subject"}, "1"), ErrInvalidTOTPEnrollmentData)
+}
+
+func TestEnrollmentWithoutFailedAttempts(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	// Enrollments which were stored before failed attempts were recorded have no recent failed attempts.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-totp-enrollment-nsgeqbppc56wbddb547ordiz72zpenghfnc7nvdz247dyzbylosq",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "totp-enrollment",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"subject":"some-subject","secret":"some-secret","lastUsedTimeStep":42,"version":"1"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/totp-enrollment",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	enrollment, _, err := storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Equal(t, &Enrollment{Subject: "some-subject", Secret: "some-secret", LastUsedTimeStep: 42, Version: "1"}, enrollment)
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pinniped-storage-totp-enrollment-nsgeqbppc56wbddb547ordiz72zpenghfnc7nvdz247dyzbylosq",
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "totp-enrollment",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"subject":"some-subject","secret":"some-secret","version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/totp-enrollment",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, _, err := storage.GetEnrollment(ctx, "some-subject")
+	require.EqualError(t, err, "totp enrollment data has wrong version: totp enrollment has version not-the-right-version instead of 1")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now)
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webauthnenrollment stores the credentials of the security keys and passkeys which users have registered
+// as their second authentication factor.
+package webauthnenrollment
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "webauthn-enrollment"
+
+	ErrInvalidWebAuthnEnrollmentVersion = constable.Error("webauthn enrollment data has wrong version")
+	ErrInvalidWebAuthnEnrollmentData    = constable.Error("webauthn enrollment data must have a credential ID and a public key")
+
+	// Version 1 was the initial release of storage.
+	webAuthnEnrollmentStorageVersion = "1"
+)
+
+// Enrollment is the credential of the security key or passkey which a user has registered.
+type Enrollment struct {
+	// Subject is the downstream subject of the user.
+	Subject string `json:"subject"`
+
+	// CredentialID identifies the credential to the user's authenticator.
+	CredentialID []byte `json:"credentialID"`
+
+	// PublicKey is the COSE_Key of the credential's public key.
+	PublicKey []byte `json:"publicKey"`
+
+	// SignCount is the signature counter which the authenticator reported most recently, so that
+	// cloned authenticators can be detected.
+	SignCount uint32 `json:"signCount"`
+
+	// Version is the version of the storage format.
+	Version string `json:"version"`
+}
+
+// Storage stores the enrollments of users. Enrollments never expire, so they are not garbage collected.
+// Deleting the Secret of an enrollment forces the user to register again during their next login.
+type Storage interface {
+	// GetEnrollment returns the enrollment of the user and its resource version, or nil when the user has not enrolled.
+	GetEnrollment(ctx context.Context, subject string) (*Enrollment, string, error)
+
+	// CreateEnrollment stores a new enrollment. It fails when the user has already enrolled.
+	CreateEnrollment(ctx context.Context, enrollment *Enrollment) error
+
+	// UpdateEnrollment updates an enrollment. It fails when the enrollment was updated since it was read, so
+	// concurrent logins cannot both report the same signature counter.
+	UpdateEnrollment(ctx context.Context, enrollment *Enrollment, resourceVersion string) error
+}
+
+type webAuthnEnrollmentStorage struct {
+	storage crud.Storage
+}
+
+func New(secrets corev1client.SecretInterface, clock func() time.Time) Storage {
+	// A lifetime of zero means that enrollments are never garbage collected.
+	return &webAuthnEnrollmentStorage{storage: crud.New(TypeLabelValue, secrets, clock, 0)}
+}
+
+func (w *webAuthnEnrollmentStorage) GetEnrollment(ctx context.Context, subject string) (*Enrollment, string, error) {
+	enrollment := &Enrollment{}
+	resourceVersion, err := w.storage.Get(ctx, signature(subject), enrollment)
+	if errors.IsNotFound(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get webauthn enrollment: %w", err)
+	}
+
+	if version := enrollment.Version; version != webAuthnEnrollmentStorageVersion {
+		return nil, "", fmt.Errorf("%w: webauthn enrollment has version %s instead of %s",
+			ErrInvalidWebAuthnEnrollmentVersion, version, webAuthnEnrollmentStorageVersion)
+	}
+	if !isValid(enrollment) {
+		return nil, "", ErrInvalidWebAuthnEnrollmentData
+	}
+	return enrollment, resourceVersion, nil
+}
+
+func (w *webAuthnEnrollmentStorage) CreateEnrollment(ctx context.Context, enrollment *Enrollment) error {
+	if !isValid(enrollment) {
+		return ErrInvalidWebAuthnEnrollmentData
+	}
+
+	toStore := *enrollment
+	toStore.Version = webAuthnEnrollmentStorageVersion
+
+	if _, err := w.storage.Create(ctx, signature(enrollment.Subject), &toStore, nil, nil); err != nil {
+		return fmt.Errorf("failed to create webauthn enrollment: %w", err)
+	}
+	return nil
+}
+
+func (w *webAuthnEnrollmentStorage) UpdateEnrollment(ctx context.Context, enrollment *Enrollment, resourceVersion string) error {
+	if !isValid(enrollment) {
+		return ErrInvalidWebAuthnEnrollmentData
+	}
+
+	toStore := *enrollment
+	toStore.Version = webAuthnEnrollmentStorageVersion
+
+	if _, err := w.storage.Update(ctx, signature(enrollment.Subject), resourceVersion, &toStore); err != nil {
+		return fmt.Errorf("failed to update webauthn enrollment: %w", err)
+	}
+	return nil
+}
+
+func isValid(enrollment *Enrollment) bool {
+	return enrollment != nil && len(enrollment.CredentialID) != 0 && len(enrollment.PublicKey) != 0
+}
+
+// signature hashes the downstream subject of the user into a valid storage key.
+func signature(subject string) string {
+	hash := sha256.Sum256([]byte(subject))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
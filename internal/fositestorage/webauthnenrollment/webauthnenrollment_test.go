@@ -0,0 +1,122 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthnenrollment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const (
+	namespace  = "test-ns"
+	secretName = "pinniped-storage-webauthn-enrollment-nsgeqbppc56wbddb547ordiz72zpenghfnc7nvdz247dyzbylosq"
+)
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestWebAuthnEnrollmentStorage(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	enrollment, resourceVersion, err := storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Nil(t, enrollment)
+	require.Empty(t, resourceVersion)
+
+	require.NoError(t, storage.CreateEnrollment(ctx, &Enrollment{
+		Subject:      "some-subject",
+		CredentialID: []byte("some-credential-id"),
+		PublicKey:    []byte("some-public-key"),
+		SignCount:    42,
+	}))
+
+	// The same user cannot enroll twice.
+	err = storage.CreateEnrollment(ctx, &Enrollment{
+		Subject:      "some-subject",
+		CredentialID: []byte("some-other-credential-id"),
+		PublicKey:    []byte("some-other-public-key"),
+	})
+	require.EqualError(t, err, `failed to create webauthn enrollment: failed to create webauthn-enrollment for signature `+
+		`bIxIBe8XfWCMYe8-6I0Z_rLyNMcrRfbUedc-PGQ4W6U: secrets "`+secretName+`" already exists`)
+
+	enrollment, resourceVersion, err = storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Equal(t, &Enrollment{
+		Subject:      "some-subject",
+		CredentialID: []byte("some-credential-id"),
+		PublicKey:    []byte("some-public-key"),
+		SignCount:    42,
+		Version:      "1",
+	}, enrollment)
+
+	enrollment.SignCount = 43
+	require.NoError(t, storage.UpdateEnrollment(ctx, enrollment, resourceVersion))
+
+	enrollment, _, err = storage.GetEnrollment(ctx, "some-subject")
+	require.NoError(t, err)
+	require.Equal(t, uint32(43), enrollment.SignCount)
+
+	// Enrollments are stored separately for each user.
+	enrollment, _, err = storage.GetEnrollment(ctx, "some-other-subject")
+	require.NoError(t, err)
+	require.Nil(t, enrollment)
+
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 1)
+	secret := secrets.Items[0]
+	require.Equal(t, secretName, secret.Name)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "webauthn-enrollment"}, secret.Labels)
+	// Enrollments are never garbage collected.
+	require.Empty(t, secret.Annotations)
+	require.JSONEq(t,
+		`{"subject":"some-subject","credentialID":"c29tZS1jcmVkZW50aWFsLWlk","publicKey":"c29tZS1wdWJsaWMta2V5","signCount":43,"version":"1"}`,
+		string(secret.Data["pinniped-storage-data"]))
+}
+
+func TestInvalidEnrollments(t *testing.T) {
+	ctx, _, storage := makeTestSubject()
+
+	require.ErrorIs(t, storage.CreateEnrollment(ctx, nil), ErrInvalidWebAuthnEnrollmentData)
+	require.ErrorIs(t, storage.CreateEnrollment(ctx, &Enrollment{Subject: "some-subject", PublicKey: []byte("some-public-key")}),
+		ErrInvalidWebAuthnEnrollmentData)
+	require.ErrorIs(t, storage.CreateEnrollment(ctx, &Enrollment{Subject: "some-subject", CredentialID: []byte("some-credential-id")}),
+		ErrInvalidWebAuthnEnrollmentData)
+	require.ErrorIs(t, storage.UpdateEnrollment(ctx, &Enrollment{Subject: "some-subject"}, "1"), ErrInvalidWebAuthnEnrollmentData)
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, client, storage := makeTestSubject()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "webauthn-enrollment",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"subject":"some-subject","credentialID":"YQ==","publicKey":"Yg==","version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/webauthn-enrollment",
+	}
+	require.NoError(t, client.Tracker().Add(secret))
+
+	_, _, err := storage.GetEnrollment(ctx, "some-subject")
+	require.EqualError(t, err, "webauthn enrollment data has wrong version: webauthn enrollment has version not-the-right-version instead of 1")
+}
+
+func makeTestSubject() (context.Context, *fake.Clientset, Storage) {
+	client := fake.NewSimpleClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(), client, New(secrets, clocktesting.NewFakeClock(fakeNow).Now)
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package groupsuffix
@@ -9,6 +9,7 @@ import (
 	identityv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/identity/v1alpha1"
 	loginv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/login/v1alpha1"
 	clientsecretv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/clientsecret/v1alpha1"
+	transformationv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/transformation/v1alpha1"
 )
 
 type GroupData schema.GroupVersion
@@ -34,15 +35,19 @@ func ConciergeAggregatedGroups(apiGroupSuffix string) (login, identity GroupData
 		}
 }
 
-func SupervisorAggregatedGroups(apiGroupSuffix string) (clientSecret GroupData) {
+func SupervisorAggregatedGroups(apiGroupSuffix string) (clientSecret, transformation GroupData) {
 	clientSecretVirtualSupervisorAPIGroup, ok1 := Replace(clientsecretv1alpha1.GroupName, apiGroupSuffix)
+	transformationVirtualSupervisorAPIGroup, ok2 := Replace(transformationv1alpha1.GroupName, apiGroupSuffix)
 
-	if !ok1 {
+	if valid := ok1 && ok2; !valid {
 		panic("static group input is invalid")
 	}
 
 	return GroupData{
-		Group:   clientSecretVirtualSupervisorAPIGroup,
-		Version: clientsecretv1alpha1.SchemeGroupVersion.Version,
-	}
+			Group:   clientSecretVirtualSupervisorAPIGroup,
+			Version: clientsecretv1alpha1.SchemeGroupVersion.Version,
+		}, GroupData{
+			Group:   transformationVirtualSupervisorAPIGroup,
+			Version: transformationv1alpha1.SchemeGroupVersion.Version,
+		}
 }
@@ -0,0 +1,97 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package idtransform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// AccessPolicySource is the configuration of an AccessPolicy.
+type AccessPolicySource struct {
+	AllowedGroups                 []string
+	DeniedGroups                  []string
+	AllowedUsernamePatterns       []string
+	RejectedAuthenticationMessage string
+}
+
+// AccessPolicy is an IdentityTransformation which does not change the username or group names, and only decides
+// whether the authentication is allowed based on allow and deny lists.
+type AccessPolicy struct {
+	source                  *AccessPolicySource
+	allowedGroups           sets.Set[string]
+	deniedGroups            sets.Set[string]
+	allowedUsernamePatterns []*regexp.Regexp
+}
+
+var _ IdentityTransformation = (*AccessPolicy)(nil)
+
+// NewAccessPolicy compiles the username patterns of the given source. It returns an error which describes every
+// invalid pattern.
+func NewAccessPolicy(source *AccessPolicySource) (*AccessPolicy, error) {
+	var errs []error
+	patterns := make([]*regexp.Regexp, 0, len(source.AllowedUsernamePatterns))
+	for i, pattern := range source.AllowedUsernamePatterns {
+		// Anchor the pattern so that it must match the entire username.
+		compiled, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("allowedUsernamePatterns[%d] %q is invalid: %w", i, pattern, err))
+			continue
+		}
+		patterns = append(patterns, compiled)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return &AccessPolicy{
+		source:                  source,
+		allowedGroups:           sets.New(source.AllowedGroups...),
+		deniedGroups:            sets.New(source.DeniedGroups...),
+		allowedUsernamePatterns: patterns,
+	}, nil
+}
+
+func (p *AccessPolicy) Evaluate(_ctx context.Context, username string, groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
+	result := &TransformationResult{
+		Username:              username,
+		Groups:                groups,
+		AuthenticationAllowed: p.allows(username, groups),
+	}
+	if !result.AuthenticationAllowed {
+		result.RejectedAuthenticationMessage = p.source.RejectedAuthenticationMessage
+	}
+	return result, nil
+}
+
+func (p *AccessPolicy) allows(username string, groups []string) bool {
+	if p.deniedGroups.HasAny(groups...) {
+		return false
+	}
+
+	if p.allowedGroups.Len() == 0 && len(p.allowedUsernamePatterns) == 0 {
+		// There are no allow lists, so everyone who was not denied is allowed.
+		return true
+	}
+
+	if p.allowedGroups.HasAny(groups...) {
+		return true
+	}
+
+	for _, pattern := range p.allowedUsernamePatterns {
+		if pattern.MatchString(username) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *AccessPolicy) Source() interface{} {
+	return p.source
+}
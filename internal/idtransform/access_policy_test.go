@@ -0,0 +1,140 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package idtransform
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   *AccessPolicySource
+		username string
+		groups   []string
+
+		wantAllowed bool
+	}{
+		{
+			name:        "empty policy allows everyone",
+			source:      &AccessPolicySource{},
+			username:    "ryan",
+			groups:      []string{"a"},
+			wantAllowed: true,
+		},
+		{
+			name:        "only denied groups allows users who are not members",
+			source:      &AccessPolicySource{DeniedGroups: []string{"contractors"}},
+			username:    "ryan",
+			groups:      []string{"admins"},
+			wantAllowed: true,
+		},
+		{
+			name:        "only denied groups rejects members",
+			source:      &AccessPolicySource{DeniedGroups: []string{"contractors"}},
+			username:    "ryan",
+			groups:      []string{"admins", "contractors"},
+			wantAllowed: false,
+		},
+		{
+			name:        "allowed groups allows members",
+			source:      &AccessPolicySource{AllowedGroups: []string{"admins", "devs"}},
+			username:    "ryan",
+			groups:      []string{"devs"},
+			wantAllowed: true,
+		},
+		{
+			name:        "allowed groups rejects users who are not members",
+			source:      &AccessPolicySource{AllowedGroups: []string{"admins", "devs"}},
+			username:    "ryan",
+			groups:      []string{"sales"},
+			wantAllowed: false,
+		},
+		{
+			name:        "allowed groups rejects users who have no groups",
+			source:      &AccessPolicySource{AllowedGroups: []string{"admins"}},
+			username:    "ryan",
+			groups:      []string{},
+			wantAllowed: false,
+		},
+		{
+			name:        "allowed username pattern allows matching users",
+			source:      &AccessPolicySource{AllowedUsernamePatterns: []string{`.*@example\.com`}},
+			username:    "ryan@example.com",
+			wantAllowed: true,
+		},
+		{
+			name:        "allowed username pattern must match the whole username",
+			source:      &AccessPolicySource{AllowedUsernamePatterns: []string{`ryan`, `.*@example\.com`}},
+			username:    "ryan@example.com.evil.com",
+			wantAllowed: false,
+		},
+		{
+			name:        "allowed username patterns are alternatives to each other",
+			source:      &AccessPolicySource{AllowedUsernamePatterns: []string{`ryan|josh`, `.*@example\.com`}},
+			username:    "josh",
+			wantAllowed: true,
+		},
+		{
+			name: "a user who is not a member of the allowed groups is allowed by the allowed username patterns",
+			source: &AccessPolicySource{
+				AllowedGroups:           []string{"admins"},
+				AllowedUsernamePatterns: []string{`ryan`},
+			},
+			username:    "ryan",
+			groups:      []string{"devs"},
+			wantAllowed: true,
+		},
+		{
+			name: "a user who does not match the allowed username patterns is allowed by the allowed groups",
+			source: &AccessPolicySource{
+				AllowedGroups:           []string{"admins"},
+				AllowedUsernamePatterns: []string{`ryan`},
+			},
+			username:    "josh",
+			groups:      []string{"admins"},
+			wantAllowed: true,
+		},
+		{
+			name: "denied groups take precedence over allowed groups and allowed username patterns",
+			source: &AccessPolicySource{
+				AllowedGroups:           []string{"admins"},
+				DeniedGroups:            []string{"suspended"},
+				AllowedUsernamePatterns: []string{`ryan`},
+			},
+			username:    "ryan",
+			groups:      []string{"admins", "suspended"},
+			wantAllowed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.source.RejectedAuthenticationMessage = "some rejection message"
+			policy, err := NewAccessPolicy(tt.source)
+			require.NoError(t, err)
+			require.Same(t, tt.source, policy.Source())
+
+			result, err := policy.Evaluate(context.Background(), tt.username, tt.groups, nil)
+			require.NoError(t, err)
+			require.Equal(t, tt.username, result.Username)
+			require.Equal(t, tt.groups, result.Groups)
+			require.Equal(t, tt.wantAllowed, result.AuthenticationAllowed)
+			if tt.wantAllowed {
+				require.Empty(t, result.RejectedAuthenticationMessage)
+			} else {
+				require.Equal(t, "some rejection message", result.RejectedAuthenticationMessage)
+			}
+		})
+	}
+}
+
+func TestNewAccessPolicyInvalidPatterns(t *testing.T) {
+	_, err := NewAccessPolicy(&AccessPolicySource{AllowedUsernamePatterns: []string{`ok`, `(`, `also ok`, `[`}})
+	require.EqualError(t, err,
+		"allowedUsernamePatterns[1] \"(\" is invalid: error parsing regexp: missing closing ): `^(?:()$`\n"+
+			"allowedUsernamePatterns[3] \"[\" is invalid: error parsing regexp: missing closing ]: `[)$`")
+}
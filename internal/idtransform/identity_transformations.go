@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package idtransform defines upstream-to-downstream identity transformations which could be
@@ -20,21 +20,44 @@ type TransformationResult struct {
 	Groups                        []string // the new group names for an allowed auth
 	AuthenticationAllowed         bool     // when false, disallow this authentication attempt
 	RejectedAuthenticationMessage string   // should be set when AuthenticationAllowed is false
+
+	// AdditionalClaims are the custom claims for downstream ID tokens which were computed by the pipeline for an
+	// allowed auth, or nil when the pipeline has no additional claims. Individual transformations do not set this.
+	AdditionalClaims map[string]interface{}
 }
 
+// UpstreamClaims are the claims (for OIDC) or attributes (for LDAP) of the user from the upstream identity provider.
+// They are made available to transformations as read-only input, and are not changed by transformations.
+type UpstreamClaims map[string]interface{}
+
 // IdentityTransformation is an individual identity transformation which can be evaluated.
 type IdentityTransformation interface {
-	Evaluate(ctx context.Context, username string, groups []string) (*TransformationResult, error)
+	Evaluate(ctx context.Context, username string, groups []string, upstreamClaims UpstreamClaims) (*TransformationResult, error)
 
 	// Source returns some representation of the original source code of the transformation, which is
 	// useful for tests to be able to check that a compiled transformation came from the right source.
 	Source() interface{}
 }
 
+// AdditionalClaim computes the value of a custom claim for downstream ID tokens from a transformed identity.
+type AdditionalClaim interface {
+	// Name returns the name of the claim.
+	Name() string
+
+	// Evaluate returns the value of the claim, which must be a value that can be represented in JSON.
+	// A nil value means that the claim should be omitted.
+	Evaluate(ctx context.Context, username string, groups []string, upstreamClaims UpstreamClaims) (interface{}, error)
+
+	// Source returns some representation of the original source code of the claim, which is
+	// useful for tests to be able to check that a compiled claim came from the right source.
+	Source() interface{}
+}
+
 // TransformationPipeline is a list of identity transforms, which can be evaluated in order against some given input
-// values.
+// values, followed by a list of additional claims which are computed from the result.
 type TransformationPipeline struct {
-	transforms []IdentityTransformation
+	transforms       []IdentityTransformation
+	additionalClaims []AdditionalClaim
 }
 
 // NewTransformationPipeline creates an empty TransformationPipeline.
@@ -49,11 +72,52 @@ func (p *TransformationPipeline) AppendTransformation(t IdentityTransformation)
 	p.transforms = append(p.transforms, t)
 }
 
+// AppendTransformationPipeline adds all the transformations of another pipeline to the end of the list of
+// transformations for this pipeline. The other pipeline is not changed. This has the same thread-safety
+// considerations as AppendTransformation.
+func (p *TransformationPipeline) AppendTransformationPipeline(other *TransformationPipeline) {
+	p.transforms = append(p.transforms, other.transforms...)
+}
+
+// AppendAdditionalClaim adds a claim to the end of the list of additional claims for this pipeline. This has the
+// same thread-safety considerations as AppendTransformation.
+func (p *TransformationPipeline) AppendAdditionalClaim(c AdditionalClaim) {
+	p.additionalClaims = append(p.additionalClaims, c)
+}
+
 // Evaluate runs the transformation pipeline for a given input identity. It returns a potentially transformed or
 // rejected identity, or an error. If any transformation in the list rejects the authentication, then the list is
-// short-circuited but no error is returned. Only unexpected errors are returned as errors. This is safe to call
-// from multiple goroutines.
-func (p *TransformationPipeline) Evaluate(ctx context.Context, username string, groups []string) (*TransformationResult, error) {
+// short-circuited but no error is returned. Only unexpected errors are returned as errors. The upstreamClaims are
+// passed unchanged to every transformation, and may be nil when there are none. This is safe to call from multiple
+// goroutines.
+func (p *TransformationPipeline) Evaluate(ctx context.Context, username string, groups []string, upstreamClaims UpstreamClaims) (*TransformationResult, error) {
+	return p.evaluate(ctx, username, groups, upstreamClaims, func(TransformationTraceStep) {})
+}
+
+// TransformationTraceStep is the outcome of evaluating one transformation of a pipeline.
+type TransformationTraceStep struct {
+	Index  int                   // the index of the transformation in the pipeline
+	Result *TransformationResult // the result of the transformation, or nil when it returned an error
+	Err    error                 // the error caused by the transformation, if any
+}
+
+// EvaluateWithTrace is the same as Evaluate, but also returns the outcome of each transformation which was evaluated,
+// in the order that they were evaluated. This is useful to explain the result of a pipeline.
+func (p *TransformationPipeline) EvaluateWithTrace(ctx context.Context, username string, groups []string, upstreamClaims UpstreamClaims) (*TransformationResult, []TransformationTraceStep, error) {
+	trace := []TransformationTraceStep{}
+	result, err := p.evaluate(ctx, username, groups, upstreamClaims, func(step TransformationTraceStep) {
+		trace = append(trace, step)
+	})
+	return result, trace, err
+}
+
+func (p *TransformationPipeline) evaluate(
+	ctx context.Context,
+	username string,
+	groups []string,
+	upstreamClaims UpstreamClaims,
+	recordStep func(TransformationTraceStep),
+) (*TransformationResult, error) {
 	if groups == nil {
 		groups = []string{}
 	}
@@ -65,38 +129,84 @@ func (p *TransformationPipeline) Evaluate(ctx context.Context, username string,
 	}
 
 	for i, transform := range p.transforms {
-		var err error
-		accumulatedResult, err = transform.Evaluate(ctx, accumulatedResult.Username, accumulatedResult.Groups)
+		result, err := transform.Evaluate(ctx, accumulatedResult.Username, accumulatedResult.Groups, upstreamClaims)
 		if err != nil {
 			// There was an unexpected error evaluating a transformation.
+			recordStep(TransformationTraceStep{Index: i, Err: err})
 			return nil, fmt.Errorf("identity transformation at index %d: %w", i, err)
 		}
+		accumulatedResult = result
 		if !accumulatedResult.AuthenticationAllowed {
 			// Auth has been rejected by a policy. Stop evaluating the rest of the transformations.
+			recordStep(TransformationTraceStep{Index: i, Result: copyResult(result)})
 			return accumulatedResult, nil
 		}
 		if strings.TrimSpace(accumulatedResult.Username) == "" {
-			return nil, fmt.Errorf("identity transformation returned an empty username, which is not allowed")
+			err = fmt.Errorf("identity transformation returned an empty username, which is not allowed")
+			recordStep(TransformationTraceStep{Index: i, Result: copyResult(result), Err: err})
+			return nil, err
 		}
 		if accumulatedResult.Groups == nil {
-			return nil, fmt.Errorf("identity transformation returned a null list of groups, which is not allowed")
+			err = fmt.Errorf("identity transformation returned a null list of groups, which is not allowed")
+			recordStep(TransformationTraceStep{Index: i, Result: copyResult(result), Err: err})
+			return nil, err
 		}
+		recordStep(TransformationTraceStep{Index: i, Result: copyResult(result)})
 	}
 
 	accumulatedResult.Groups = sortAndUniq(accumulatedResult.Groups)
 
+	// The additional claims are computed from the final username and groups.
+	additionalClaims, err := p.evaluateAdditionalClaims(ctx, accumulatedResult.Username, accumulatedResult.Groups, upstreamClaims)
+	if err != nil {
+		return nil, err
+	}
+	accumulatedResult.AdditionalClaims = additionalClaims
+
 	// There were no unexpected errors and no policy which rejected auth.
 	return accumulatedResult, nil
 }
 
+func (p *TransformationPipeline) evaluateAdditionalClaims(
+	ctx context.Context,
+	username string,
+	groups []string,
+	upstreamClaims UpstreamClaims,
+) (map[string]interface{}, error) {
+	if len(p.additionalClaims) == 0 {
+		return nil, nil
+	}
+	claims := make(map[string]interface{}, len(p.additionalClaims))
+	for _, claim := range p.additionalClaims {
+		value, err := claim.Evaluate(ctx, username, groups, upstreamClaims)
+		if err != nil {
+			return nil, fmt.Errorf("additional claim %q: %w", claim.Name(), err)
+		}
+		if value != nil {
+			claims[claim.Name()] = value
+		}
+	}
+	return claims, nil
+}
+
 func (p *TransformationPipeline) Source() []interface{} {
 	result := []interface{}{}
 	for _, transform := range p.transforms {
 		result = append(result, transform.Source())
 	}
+	for _, claim := range p.additionalClaims {
+		result = append(result, claim.Source())
+	}
 	return result
 }
 
+// copyResult returns a copy of the result for a trace, so that the trace is not changed when the result which is
+// returned by the pipeline is changed.
+func copyResult(r *TransformationResult) *TransformationResult {
+	c := *r
+	return &c
+}
+
 func sortAndUniq(s []string) []string {
 	unique := sets.New(s...).UnsortedList()
 	sort.Strings(unique)
@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package idtransform
@@ -13,7 +13,7 @@ import (
 
 type fakeNoopTransformer struct{}
 
-func (a fakeNoopTransformer) Evaluate(_ctx context.Context, username string, groups []string) (*TransformationResult, error) {
+func (a fakeNoopTransformer) Evaluate(_ctx context.Context, username string, groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	return &TransformationResult{
 		Username:                      username,
 		Groups:                        groups,
@@ -28,7 +28,7 @@ func (a fakeNoopTransformer) Source() interface{} {
 
 type fakeNilGroupTransformer struct{}
 
-func (a fakeNilGroupTransformer) Evaluate(_ctx context.Context, username string, _groups []string) (*TransformationResult, error) {
+func (a fakeNilGroupTransformer) Evaluate(_ctx context.Context, username string, _groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	return &TransformationResult{
 		Username:                      username,
 		Groups:                        nil,
@@ -43,7 +43,7 @@ func (a fakeNilGroupTransformer) Source() interface{} {
 
 type fakeAppendStringTransformer struct{}
 
-func (a fakeAppendStringTransformer) Evaluate(_ctx context.Context, username string, groups []string) (*TransformationResult, error) {
+func (a fakeAppendStringTransformer) Evaluate(_ctx context.Context, username string, groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	newGroups := []string{}
 	for _, group := range groups {
 		newGroups = append(newGroups, group+":transformed")
@@ -62,7 +62,7 @@ func (a fakeAppendStringTransformer) Source() interface{} {
 
 type fakeDeleteUsernameAndGroupsTransformer struct{}
 
-func (a fakeDeleteUsernameAndGroupsTransformer) Evaluate(_ctx context.Context, _username string, _groups []string) (*TransformationResult, error) {
+func (a fakeDeleteUsernameAndGroupsTransformer) Evaluate(_ctx context.Context, _username string, _groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	return &TransformationResult{
 		Username:                      "",
 		Groups:                        []string{},
@@ -77,7 +77,7 @@ func (a fakeDeleteUsernameAndGroupsTransformer) Source() interface{} {
 
 type fakeAuthenticationDisallowedTransformer struct{}
 
-func (a fakeAuthenticationDisallowedTransformer) Evaluate(_ctx context.Context, username string, groups []string) (*TransformationResult, error) {
+func (a fakeAuthenticationDisallowedTransformer) Evaluate(_ctx context.Context, username string, groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	newGroups := []string{}
 	for _, group := range groups {
 		newGroups = append(newGroups, group+":disallowed")
@@ -96,7 +96,7 @@ func (a fakeAuthenticationDisallowedTransformer) Source() interface{} {
 
 type fakeErrorTransformer struct{}
 
-func (a fakeErrorTransformer) Evaluate(_ctx context.Context, _username string, _groups []string) (*TransformationResult, error) {
+func (a fakeErrorTransformer) Evaluate(_ctx context.Context, _username string, _groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	return &TransformationResult{}, errors.New("unexpected catastrophic error")
 }
 
@@ -108,7 +108,7 @@ type fakeTransformerWithSource struct {
 	source string
 }
 
-func (a fakeTransformerWithSource) Evaluate(_ctx context.Context, _username string, _groups []string) (*TransformationResult, error) {
+func (a fakeTransformerWithSource) Evaluate(_ctx context.Context, _username string, _groups []string, _upstreamClaims UpstreamClaims) (*TransformationResult, error) {
 	return nil, nil // not needed for this test
 }
 
@@ -116,6 +116,27 @@ func (a fakeTransformerWithSource) Source() interface{} {
 	return a.source
 }
 
+type fakeAdditionalClaim struct {
+	name  string
+	value interface{}
+	err   error
+}
+
+func (a fakeAdditionalClaim) Name() string {
+	return a.name
+}
+
+func (a fakeAdditionalClaim) Evaluate(_ctx context.Context, username string, groups []string, upstreamClaims UpstreamClaims) (interface{}, error) {
+	if a.value == "echo" {
+		return map[string]interface{}{"username": username, "groups": groups, "upstreamClaims": upstreamClaims}, nil
+	}
+	return a.value, a.err
+}
+
+func (a fakeAdditionalClaim) Source() interface{} {
+	return a.name
+}
+
 func TestTransformationPipelineEvaluation(t *testing.T) {
 	tests := []struct {
 		name                               string
@@ -307,7 +328,7 @@ func TestTransformationPipelineEvaluation(t *testing.T) {
 				pipeline.AppendTransformation(transform)
 			}
 
-			result, err := pipeline.Evaluate(context.Background(), tt.username, tt.groups)
+			result, err := pipeline.Evaluate(context.Background(), tt.username, tt.groups, nil)
 
 			if tt.wantError != "" {
 				require.EqualError(t, err, tt.wantError)
@@ -338,3 +359,209 @@ func TestTransformationSource(t *testing.T) {
 	require.Equal(t, []interface{}{"foo", "bar", "baz"}, pipeline.Source())
 	require.NotEqual(t, []interface{}{"foo", "something-else", "baz"}, pipeline.Source())
 }
+
+func TestAppendTransformationPipeline(t *testing.T) {
+	library := NewTransformationPipeline()
+	library.AppendTransformation(&fakeTransformerWithSource{source: "foo"})
+	library.AppendTransformation(&fakeTransformerWithSource{source: "bar"})
+
+	pipeline := NewTransformationPipeline()
+	pipeline.AppendTransformationPipeline(library)
+	pipeline.AppendTransformation(&fakeTransformerWithSource{source: "baz"})
+
+	require.Equal(t, []interface{}{"foo", "bar", "baz"}, pipeline.Source())
+	require.Equal(t, []interface{}{"foo", "bar"}, library.Source(), "the appended pipeline should not be changed")
+}
+
+func TestTransformationPipelineEvaluateWithTrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms []IdentityTransformation
+		wantResult *TransformationResult
+		wantTrace  []TransformationTraceStep
+		wantError  string
+	}{
+		{
+			name:       "empty pipeline",
+			transforms: []IdentityTransformation{},
+			wantResult: &TransformationResult{Username: "foo", Groups: []string{"bar"}, AuthenticationAllowed: true},
+			wantTrace:  []TransformationTraceStep{},
+		},
+		{
+			name: "every transformation is traced",
+			transforms: []IdentityTransformation{
+				&fakeAppendStringTransformer{},
+				&fakeAppendStringTransformer{},
+			},
+			wantResult: &TransformationResult{
+				Username: "foo:transformed:transformed", Groups: []string{"bar:transformed:transformed"},
+				AuthenticationAllowed: true, RejectedAuthenticationMessage: "none",
+			},
+			wantTrace: []TransformationTraceStep{
+				{Index: 0, Result: &TransformationResult{
+					Username: "foo:transformed", Groups: []string{"bar:transformed"},
+					AuthenticationAllowed: true, RejectedAuthenticationMessage: "none",
+				}},
+				{Index: 1, Result: &TransformationResult{
+					Username: "foo:transformed:transformed", Groups: []string{"bar:transformed:transformed"},
+					AuthenticationAllowed: true, RejectedAuthenticationMessage: "none",
+				}},
+			},
+		},
+		{
+			name: "a rejection stops the trace",
+			transforms: []IdentityTransformation{
+				&fakeAuthenticationDisallowedTransformer{},
+				&fakeAppendStringTransformer{},
+			},
+			wantResult: &TransformationResult{
+				Username: "foo:disallowed", Groups: []string{"bar:disallowed"},
+				AuthenticationAllowed: false, RejectedAuthenticationMessage: "no authentication is allowed",
+			},
+			wantTrace: []TransformationTraceStep{
+				{Index: 0, Result: &TransformationResult{
+					Username: "foo:disallowed", Groups: []string{"bar:disallowed"},
+					AuthenticationAllowed: false, RejectedAuthenticationMessage: "no authentication is allowed",
+				}},
+			},
+		},
+		{
+			name: "an error is traced and stops the trace",
+			transforms: []IdentityTransformation{
+				&fakeNoopTransformer{},
+				&fakeErrorTransformer{},
+				&fakeNoopTransformer{},
+			},
+			wantTrace: []TransformationTraceStep{
+				{Index: 0, Result: &TransformationResult{
+					Username: "foo", Groups: []string{"bar"},
+					AuthenticationAllowed: true, RejectedAuthenticationMessage: "none",
+				}},
+				{Index: 1, Err: errors.New("unexpected catastrophic error")},
+			},
+			wantError: "identity transformation at index 1: unexpected catastrophic error",
+		},
+		{
+			name: "an invalid result is traced along with its error",
+			transforms: []IdentityTransformation{
+				&fakeDeleteUsernameAndGroupsTransformer{},
+			},
+			wantTrace: []TransformationTraceStep{
+				{
+					Index: 0,
+					Result: &TransformationResult{
+						Username: "", Groups: []string{},
+						AuthenticationAllowed: true, RejectedAuthenticationMessage: "none",
+					},
+					Err: errors.New("identity transformation returned an empty username, which is not allowed"),
+				},
+			},
+			wantError: "identity transformation returned an empty username, which is not allowed",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			pipeline := NewTransformationPipeline()
+			for _, transform := range tt.transforms {
+				pipeline.AppendTransformation(transform)
+			}
+
+			result, trace, err := pipeline.EvaluateWithTrace(context.Background(), "foo", []string{"bar"}, nil)
+
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				require.Nil(t, result)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantResult, result)
+			}
+			require.Equal(t, tt.wantTrace, trace)
+		})
+	}
+}
+
+func TestTransformationPipelineAdditionalClaims(t *testing.T) {
+	tests := []struct {
+		name                 string
+		transforms           []IdentityTransformation
+		additionalClaims     []AdditionalClaim
+		wantAdditionalClaims map[string]interface{}
+		wantAllowed          bool
+		wantErr              string
+	}{
+		{
+			name:                 "no additional claims",
+			transforms:           []IdentityTransformation{&fakeNoopTransformer{}},
+			wantAdditionalClaims: nil,
+			wantAllowed:          true,
+		},
+		{
+			name:       "additional claims are computed from the final transformed identity",
+			transforms: []IdentityTransformation{&fakeAppendStringTransformer{}},
+			additionalClaims: []AdditionalClaim{
+				&fakeAdditionalClaim{name: "a", value: "echo"},
+				&fakeAdditionalClaim{name: "b", value: true},
+				&fakeAdditionalClaim{name: "c", value: nil},
+			},
+			wantAdditionalClaims: map[string]interface{}{
+				"a": map[string]interface{}{
+					"username":       "foo:transformed",
+					"groups":         []string{"bar:transformed", "foo:transformed"},
+					"upstreamClaims": UpstreamClaims{"x": "y"},
+				},
+				"b": true,
+			},
+			wantAllowed: true,
+		},
+		{
+			name:       "additional claims are not computed when auth is rejected",
+			transforms: []IdentityTransformation{&fakeAuthenticationDisallowedTransformer{}},
+			additionalClaims: []AdditionalClaim{
+				&fakeAdditionalClaim{name: "a", err: errors.New("should not be evaluated")},
+			},
+			wantAllowed: false,
+		},
+		{
+			name: "an additional claim returns an error",
+			additionalClaims: []AdditionalClaim{
+				&fakeAdditionalClaim{name: "a", value: "ok"},
+				&fakeAdditionalClaim{name: "b", err: errors.New("claim error")},
+			},
+			wantErr: `additional claim "b": claim error`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pipeline := NewTransformationPipeline()
+			for _, transform := range tt.transforms {
+				pipeline.AppendTransformation(transform)
+			}
+			for _, claim := range tt.additionalClaims {
+				pipeline.AppendAdditionalClaim(claim)
+			}
+
+			result, err := pipeline.Evaluate(context.Background(), "foo", []string{"foo", "bar"}, UpstreamClaims{"x": "y"})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, result)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAllowed, result.AuthenticationAllowed)
+			require.Equal(t, tt.wantAdditionalClaims, result.AdditionalClaims)
+		})
+	}
+}
+
+func TestAdditionalClaimsSource(t *testing.T) {
+	pipeline := NewTransformationPipeline()
+	pipeline.AppendAdditionalClaim(&fakeAdditionalClaim{name: "claim"})
+	pipeline.AppendTransformation(&fakeTransformerWithSource{source: "foo"})
+
+	require.Equal(t, []interface{}{"foo", "claim"}, pipeline.Source())
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 //
 
@@ -107,6 +107,20 @@ func (mr *MockUpstreamOIDCIdentityProviderIMockRecorder) GetAdditionalClaimMappi
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdditionalClaimMappings", reflect.TypeOf((*MockUpstreamOIDCIdentityProviderI)(nil).GetAdditionalClaimMappings))
 }
 
+// GetAdditionalClaimsForTransformations mocks base method.
+func (m *MockUpstreamOIDCIdentityProviderI) GetAdditionalClaimsForTransformations() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdditionalClaimsForTransformations")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetAdditionalClaimsForTransformations indicates an expected call of GetAdditionalClaimsForTransformations.
+func (mr *MockUpstreamOIDCIdentityProviderIMockRecorder) GetAdditionalClaimsForTransformations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdditionalClaimsForTransformations", reflect.TypeOf((*MockUpstreamOIDCIdentityProviderI)(nil).GetAdditionalClaimsForTransformations))
+}
+
 // GetAuthorizationURL mocks base method.
 func (m *MockUpstreamOIDCIdentityProviderI) GetAuthorizationURL() *url.URL {
 	m.ctrl.T.Helper()
@@ -163,6 +177,20 @@ func (mr *MockUpstreamOIDCIdentityProviderIMockRecorder) GetName() *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetName", reflect.TypeOf((*MockUpstreamOIDCIdentityProviderI)(nil).GetName))
 }
 
+// GetPassthroughAuthcodeParams mocks base method.
+func (m *MockUpstreamOIDCIdentityProviderI) GetPassthroughAuthcodeParams() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPassthroughAuthcodeParams")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetPassthroughAuthcodeParams indicates an expected call of GetPassthroughAuthcodeParams.
+func (mr *MockUpstreamOIDCIdentityProviderIMockRecorder) GetPassthroughAuthcodeParams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPassthroughAuthcodeParams", reflect.TypeOf((*MockUpstreamOIDCIdentityProviderI)(nil).GetPassthroughAuthcodeParams))
+}
+
 // GetResourceUID mocks base method.
 func (m *MockUpstreamOIDCIdentityProviderI) GetResourceUID() types.UID {
 	m.ctrl.T.Helper()
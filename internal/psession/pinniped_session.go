@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package psession
@@ -45,6 +45,24 @@ type CustomSessionData struct {
 	// refresh, and when the LDAP search was configured to skip group refreshes.
 	UpstreamGroups []string `json:"upstreamGroups"`
 
+	// UpstreamClaims is the set of additional claims (for OIDC providers) or attributes (for LDAP and Active
+	// Directory providers) from the upstream identity provider during the user's initial login, which are made
+	// available to identity transformation expressions. We store this so that identity transformations can be
+	// reapplied consistently during refresh flows, even though the upstream may not return them again during refresh.
+	UpstreamClaims map[string]interface{} `json:"upstreamClaims,omitempty"`
+
+	// AdditionalClaimsFromTransforms is the set of custom claims which were computed by the additional claims of the
+	// FederationDomain's identity transformations during the user's initial login or most recent refresh, and which
+	// were added to the additionalClaims claim of the downstream ID tokens. We store this so that these claims can
+	// be replaced by their recomputed values during refresh flows, without removing any other additional claims.
+	AdditionalClaimsFromTransforms map[string]interface{} `json:"additionalClaimsFromTransforms,omitempty"`
+
+	// DownstreamGroups is the groups list determined by the FederationDomain's identity transformations during the
+	// user's initial login or most recent refresh. Unlike the groups claim of the downstream ID tokens, it is stored
+	// even when the groups scope was not granted, so that policies which depend on the user's groups, such as the
+	// conditions of the audiences allowed during token exchange, are always evaluated against the user's real groups.
+	DownstreamGroups []string `json:"downstreamGroups,omitempty"`
+
 	// The Kubernetes resource UID of the identity provider CRD for the upstream IDP used to start this session.
 	// This should be validated again upon downstream refresh to make sure that we are not refreshing against
 	// a different identity provider CRD which just happens to have the same name.
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package credentialrequest provides REST functionality for the CredentialRequest resource.
@@ -7,8 +7,11 @@ package credentialrequest
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"time"
 
+	"github.com/go-jose/go-jose/v3/jwt"
+
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -21,6 +24,8 @@ import (
 	"k8s.io/utils/trace"
 
 	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/authenticationinfo"
 	"go.pinniped.dev/internal/clientcertissuer"
 )
 
@@ -113,9 +118,16 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 		return failureResponse(), nil
 	}
 
+	// embed how the user authenticated into the cert so that the WhoAmIRequest API can report it later
+	authenticationInfoExtension, err := newAuthenticationInfo(credentialRequest).ClientCertExtension()
+	if err != nil {
+		traceFailureWithError(t, "authentication info", err)
+		return failureResponse(), nil
+	}
+
 	// this timestamp should be returned from IssueClientCertPEM but this is a safe approximation
 	expires := metav1.NewTime(time.Now().UTC().Add(clientCertificateTTL))
-	certPEM, keyPEM, err := r.issuer.IssueClientCertPEM(userInfo.GetName(), userInfo.GetGroups(), clientCertificateTTL)
+	certPEM, keyPEM, err := r.issuer.IssueClientCertPEM(userInfo.GetName(), userInfo.GetGroups(), clientCertificateTTL, authenticationInfoExtension)
 	if err != nil {
 		traceFailureWithError(t, "cert issuer", err)
 		return failureResponse(), nil
@@ -178,6 +190,39 @@ func validateRequest(ctx context.Context, obj runtime.Object, createValidation r
 	return credentialRequest, nil
 }
 
+// newAuthenticationInfo describes how the user of an authenticated TokenCredentialRequest authenticated.
+func newAuthenticationInfo(credentialRequest *loginapi.TokenCredentialRequest) *authenticationinfo.Info {
+	authenticator := credentialRequest.Spec.Authenticator
+	info := &authenticationinfo.Info{Authenticator: &authenticator}
+
+	// The token was already validated by the authenticator, so it is safe to read its claims without verifying them.
+	// Only JWT authenticators validate the signature of the token, so do not trust the claims of other tokens.
+	if authenticator.Kind != "JWTAuthenticator" {
+		return info
+	}
+	token, err := jwt.ParseSigned(credentialRequest.Spec.Token)
+	if err != nil {
+		return info
+	}
+	var claims jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return info
+	}
+
+	// The subject of the ID tokens issued by the Supervisor includes the name of the upstream identity provider,
+	// which also distinguishes them from the ID tokens issued by other OIDC providers.
+	subject, err := url.Parse(claims.Subject)
+	if err != nil {
+		return info
+	}
+	if idpName := subject.Query().Get(oidcapi.IDTokenSubClaimIDPNameQueryParam); len(idpName) != 0 {
+		info.SupervisorIssuer = claims.Issuer
+		info.UpstreamIdentityProviderName = idpName
+	}
+
+	return info
+}
+
 func isUserInfoValid(userInfo user.Info) bool {
 	switch {
 	case userInfo == nil, // must be non-nil
@@ -1,19 +1,26 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package credentialrequest
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509/pkix"
 	"errors"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
 	"github.com/go-logr/logr"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -25,6 +32,7 @@ import (
 	"k8s.io/utils/ptr"
 
 	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
+	"go.pinniped.dev/internal/authenticationinfo"
 	"go.pinniped.dev/internal/clientcertissuer"
 	"go.pinniped.dev/internal/mocks/credentialrequestmocks"
 	"go.pinniped.dev/internal/mocks/issuermocks"
@@ -96,6 +104,7 @@ func TestCreate(t *testing.T) {
 				"test-user",
 				[]string{"test-group-1", "test-group-2"},
 				5*time.Minute,
+				authenticationInfoExtension(t, &authenticationinfo.Info{Authenticator: &corev1.TypedLocalObjectReference{}}),
 			).Return([]byte("test-cert"), []byte("test-key"), nil)
 
 			storage := NewREST(requestAuthenticator, clientCertIssuer, schema.GroupResource{})
@@ -134,7 +143,7 @@ func TestCreate(t *testing.T) {
 
 			clientCertIssuer := issuermocks.NewMockClientCertIssuer(ctrl)
 			clientCertIssuer.EXPECT().
-				IssueClientCertPEM(gomock.Any(), gomock.Any(), gomock.Any()).
+				IssueClientCertPEM(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 				Return(nil, nil, fmt.Errorf("some certificate authority error"))
 
 			storage := NewREST(requestAuthenticator, clientCertIssuer, schema.GroupResource{})
@@ -144,6 +153,74 @@ func TestCreate(t *testing.T) {
 			requireOneLogStatement(r, logger, `"failure" failureType:cert issuer,msg:some certificate authority error`)
 		})
 
+		when("the token was authenticated", func() {
+			var (
+				supervisorIDToken string
+				otherIDToken      string
+			)
+
+			it.Before(func() {
+				supervisorIDToken = signedTestJWT(t, jwt.Claims{
+					Issuer:  "https://supervisor.example.com/some/path",
+					Subject: "https://upstream.example.com?idpName=some+idp&sub=some-subject",
+				})
+				otherIDToken = signedTestJWT(t, jwt.Claims{
+					Issuer:  "https://other-issuer.example.com",
+					Subject: "some-subject",
+				})
+			})
+
+			issueWithAuthenticationInfo := func(req *loginapi.TokenCredentialRequest, wantInfo *authenticationinfo.Info) runtime.Object {
+				requestAuthenticator := credentialrequestmocks.NewMockTokenCredentialRequestAuthenticator(ctrl)
+				requestAuthenticator.EXPECT().AuthenticateTokenCredentialRequest(gomock.Any(), req).
+					Return(&user.DefaultInfo{Name: "test-user"}, nil)
+
+				clientCertIssuer := issuermocks.NewMockClientCertIssuer(ctrl)
+				clientCertIssuer.EXPECT().
+					IssueClientCertPEM("test-user", nil, 5*time.Minute, authenticationInfoExtension(t, wantInfo)).
+					Return([]byte("test-cert"), []byte("test-key"), nil)
+
+				response, err := callCreate(context.Background(), NewREST(requestAuthenticator, clientCertIssuer, schema.GroupResource{}), req)
+				r.NoError(err)
+				return response
+			}
+
+			it("CreateEmbedsTheSupervisorDetailsIntoTheCertWhenAJWTAuthenticatorAuthenticatesASupervisorIDToken", func() {
+				authenticator := corev1.TypedLocalObjectReference{
+					APIGroup: ptr.To("authentication.concierge.pinniped.dev"),
+					Kind:     "JWTAuthenticator",
+					Name:     "some-jwt-authenticator",
+				}
+				response := issueWithAuthenticationInfo(
+					credentialRequest(loginapi.TokenCredentialRequestSpec{Token: supervisorIDToken, Authenticator: authenticator}),
+					&authenticationinfo.Info{
+						Authenticator:                &authenticator,
+						SupervisorIssuer:             "https://supervisor.example.com/some/path",
+						UpstreamIdentityProviderName: "some idp",
+					},
+				)
+				r.NotNil(response.(*loginapi.TokenCredentialRequest).Status.Credential)
+			})
+
+			it("CreateDoesNotEmbedSupervisorDetailsIntoTheCertForOtherIDTokens", func() {
+				authenticator := corev1.TypedLocalObjectReference{Kind: "JWTAuthenticator", Name: "some-jwt-authenticator"}
+				response := issueWithAuthenticationInfo(
+					credentialRequest(loginapi.TokenCredentialRequestSpec{Token: otherIDToken, Authenticator: authenticator}),
+					&authenticationinfo.Info{Authenticator: &authenticator},
+				)
+				r.NotNil(response.(*loginapi.TokenCredentialRequest).Status.Credential)
+			})
+
+			it("CreateDoesNotTrustTheClaimsOfTokensWhichWereAuthenticatedByAWebhook", func() {
+				authenticator := corev1.TypedLocalObjectReference{Kind: "WebhookAuthenticator", Name: "some-webhook-authenticator"}
+				response := issueWithAuthenticationInfo(
+					credentialRequest(loginapi.TokenCredentialRequestSpec{Token: supervisorIDToken, Authenticator: authenticator}),
+					&authenticationinfo.Info{Authenticator: &authenticator},
+				)
+				r.NotNil(response.(*loginapi.TokenCredentialRequest).Status.Credential)
+			})
+		})
+
 		it("CreateSucceedsWithAnUnauthenticatedStatusWhenGivenATokenAndTheWebhookReturnsNilUser", func() {
 			req := validCredentialRequest()
 
@@ -392,10 +469,28 @@ func requireSuccessfulResponseWithAuthenticationFailureMessage(t *testing.T, err
 	})
 }
 
+func authenticationInfoExtension(t *testing.T, info *authenticationinfo.Info) pkix.Extension {
+	t.Helper()
+	extension, err := info.ClientCertExtension()
+	require.NoError(t, err)
+	return extension
+}
+
+func signedTestJWT(t *testing.T, claims jwt.Claims) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: key}, nil)
+	require.NoError(t, err)
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
 func successfulIssuer(ctrl *gomock.Controller) clientcertissuer.ClientCertIssuer {
 	clientCertIssuer := issuermocks.NewMockClientCertIssuer(ctrl)
 	clientCertIssuer.EXPECT().
-		IssueClientCertPEM(gomock.Any(), gomock.Any(), gomock.Any()).
+		IssueClientCertPEM(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 		Return([]byte("test-cert"), []byte("test-key"), nil)
 	return clientCertIssuer
 }
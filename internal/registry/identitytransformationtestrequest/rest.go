@@ -0,0 +1,490 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package identitytransformationtestrequest provides REST functionality for the IdentityTransformationTestRequest resource.
+package identitytransformationtestrequest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/utils/trace"
+
+	configv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	transformationapi "go.pinniped.dev/generated/latest/apis/supervisor/transformation"
+	configv1alpha1clientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
+	configlisters "go.pinniped.dev/generated/latest/client/supervisor/listers/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+// accessPolicyType is used as the type of the trace step for the access policy of an identity provider.
+const accessPolicyType = "accessPolicy"
+
+type timeNowFunc func() metav1.Time
+
+func NewREST(
+	resource schema.GroupResource,
+	federationDomainsClient configv1alpha1clientset.FederationDomainInterface,
+	identityTransformationLibraries configlisters.IdentityTransformationLibraryLister,
+	identityTransformationLibraryCache *transformpipeline.LibraryCache,
+	celTransformer *celtransformer.CELTransformer,
+	namespace string,
+	timeNowFunc timeNowFunc,
+) *REST {
+	return &REST{
+		federationDomainsClient:            federationDomainsClient,
+		identityTransformationLibraries:    identityTransformationLibraries,
+		identityTransformationLibraryCache: identityTransformationLibraryCache,
+		celTransformer:                     celTransformer,
+		namespace:                          namespace,
+		tableConvertor:                     rest.NewDefaultTableConvertor(resource),
+		timeNowFunc:                        timeNowFunc,
+	}
+}
+
+type REST struct {
+	federationDomainsClient            configv1alpha1clientset.FederationDomainInterface
+	identityTransformationLibraries    configlisters.IdentityTransformationLibraryLister
+	identityTransformationLibraryCache *transformpipeline.LibraryCache
+	celTransformer                     *celtransformer.CELTransformer
+	namespace                          string
+	tableConvertor                     rest.TableConvertor
+	timeNowFunc                        timeNowFunc
+}
+
+// Assert that our *REST implements all the optional interfaces that we expect it to implement.
+var _ interface {
+	rest.Creater
+	rest.NamespaceScopedStrategy
+	rest.Scoper
+	rest.Storage
+	rest.CategoriesProvider
+	rest.SingularNameProvider
+	rest.Lister
+	rest.TableConvertor
+} = (*REST)(nil)
+
+func (*REST) New() runtime.Object {
+	return &transformationapi.IdentityTransformationTestRequest{}
+}
+
+func (*REST) Destroy() {}
+
+func (*REST) NewList() runtime.Object {
+	return &transformationapi.IdentityTransformationTestRequestList{}
+}
+
+// List implements the list verb. Support the list verb to support `kubectl get pinniped`, to make sure all resources
+// are in the pinniped category, and avoid kubectl errors when kubectl lists.
+func (*REST) List(_ context.Context, _ *metainternalversion.ListOptions) (runtime.Object, error) {
+	return &transformationapi.IdentityTransformationTestRequestList{
+		ListMeta: metav1.ListMeta{
+			ResourceVersion: "0", // this resource version means "from the API server cache"
+		},
+		Items: []transformationapi.IdentityTransformationTestRequest{}, // avoid sending nil items list
+	}, nil
+}
+
+func (r *REST) ConvertToTable(ctx context.Context, obj runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return r.tableConvertor.ConvertToTable(ctx, obj, tableOptions)
+}
+
+func (*REST) NamespaceScoped() bool {
+	return true
+}
+
+func (*REST) Categories() []string {
+	return []string{"pinniped"}
+}
+
+func (*REST) GetSingularName() string {
+	return "identitytransformationtestrequest"
+}
+
+func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+	t := trace.FromContext(ctx).Nest("create",
+		trace.Field{Key: "kind", Value: "IdentityTransformationTestRequest"},
+		trace.Field{Key: "metadata.name", Value: name(obj)},
+	)
+	defer t.Log()
+
+	// Validate the create request before honoring it.
+	// This function is provided from kube kube-api server calling validating admission webhooks if there are any registered.
+	req, upstreamClaims, err := r.validateRequest(ctx, obj, createValidation, options, t)
+	if err != nil {
+		return nil, err
+	}
+	t.Step("validateRequest")
+
+	// Find the specified FederationDomain.
+	federationDomain, err := r.federationDomainsClient.Get(ctx, req.Spec.FederationDomainName, metav1.GetOptions{})
+	if err != nil {
+		traceFailureWithError(t, "federationDomainsClient.Get", err)
+		if apierrors.IsNotFound(err) {
+			errs := field.ErrorList{field.NotFound(field.NewPath("spec", "federationDomainName"), req.Spec.FederationDomainName)}
+			return nil, apierrors.NewInvalid(kindFromContext(ctx), req.Name, errs)
+		}
+		return nil, apierrors.NewInternalError(fmt.Errorf("getting FederationDomain %q failed", req.Spec.FederationDomainName))
+	}
+	t.Step("federationDomainsClient.Get")
+
+	// Find the specified identity provider of the FederationDomain.
+	var idp *configv1alpha1.FederationDomainIdentityProvider
+	for i := range federationDomain.Spec.IdentityProviders {
+		if federationDomain.Spec.IdentityProviders[i].DisplayName == req.Spec.IdentityProviderDisplayName {
+			idp = &federationDomain.Spec.IdentityProviders[i]
+			break
+		}
+	}
+	if idp == nil {
+		msg := fmt.Sprintf("FederationDomain %q has no identity provider with this displayName", federationDomain.Name)
+		traceFailure(t, "find identity provider", msg)
+		errs := field.ErrorList{field.Invalid(field.NewPath("spec", "identityProviderDisplayName"), req.Spec.IdentityProviderDisplayName, msg)}
+		return nil, apierrors.NewInvalid(kindFromContext(ctx), req.Name, errs)
+	}
+
+	// Use the candidate transforms from the request when there are some, or else the saved transforms.
+	transforms := req.Spec.Transforms
+	if transforms == nil {
+		transforms = transformsFromIdentityProvider(idp)
+	}
+
+	status := transformationapi.IdentityTransformationTestRequestStatus{}
+
+	pipeline, sources, compileErrors, err := r.makeTransformationPipeline(ctx, transforms, idp.AccessPolicy)
+	if err != nil {
+		traceFailureWithError(t, "makeTransformationPipeline", err)
+		return nil, apierrors.NewInternalError(fmt.Errorf("preparing the transforms failed"))
+	}
+	t.Step("makeTransformationPipeline")
+
+	if len(compileErrors) > 0 {
+		// The transforms cannot be evaluated, so report why instead.
+		status.Error = strings.Join(compileErrors, "\n\n")
+	} else {
+		result, steps, err := pipeline.EvaluateWithTrace(ctx, req.Spec.Username, req.Spec.Groups, upstreamClaims)
+		t.Step("pipeline.EvaluateWithTrace")
+
+		status.Trace = make([]transformationapi.IdentityTransformationTestRequestTraceStep, 0, len(steps))
+		for _, step := range steps {
+			status.Trace = append(status.Trace, makeTraceStep(sources[step.Index], step))
+		}
+
+		switch {
+		case err != nil:
+			status.Error = err.Error()
+		case !result.AuthenticationAllowed:
+			status.Rejected = true
+			status.Message = result.RejectedAuthenticationMessage
+		default:
+			status.Username = result.Username
+			status.Groups = result.Groups
+		}
+	}
+
+	return &transformationapi.IdentityTransformationTestRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              req.Name,
+			Namespace:         req.Namespace,
+			CreationTimestamp: r.timeNowFunc(),
+		},
+		Spec:   req.Spec,
+		Status: status,
+	}, nil
+}
+
+// transformSource describes where a transformation of a pipeline came from, for use in the trace.
+type transformSource struct {
+	source         string
+	transformType  string
+	expressionText string
+}
+
+// makeTransformationPipeline compiles the transforms and the access policy into a pipeline, using the same helpers
+// and the same compiled libraries as the FederationDomain controller, so the result matches what the Supervisor runs
+// during an authentication. It also returns the source of each transformation of the pipeline, by index. When anything
+// does not compile, it returns a message for each problem instead of a pipeline.
+func (r *REST) makeTransformationPipeline(
+	ctx context.Context,
+	transforms *transformationapi.IdentityTransformationTestRequestTransforms,
+	accessPolicy *configv1alpha1.FederationDomainAccessPolicy,
+) (*idtransform.TransformationPipeline, []transformSource, []string, error) {
+	pipeline := idtransform.NewTransformationPipeline()
+	sources := []transformSource{}
+	paths := &transformpipeline.Paths{
+		ConstantsType:   "transforms.constants",
+		ExpressionsType: "transforms.expressions",
+		Expressions:     "transforms.expressions",
+		Examples:        "transforms.examples",
+	}
+
+	// The transformations of the libraries come first, in the order that the libraries were listed.
+	libraries, compileErrors, err := r.identityTransformationLibraryCache.AppendLibraries(ctx,
+		r.identityTransformationLibraries, transforms.Libraries, "transforms.libraries", pipeline)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	appendedIndex := 0
+	for libIndex, libName := range transforms.Libraries {
+		if appendedIndex >= len(libraries) || libraries[appendedIndex].Name != libName {
+			continue // this library was not appended, so it has no transformations in the pipeline
+		}
+		for exprIndex, expr := range libraries[appendedIndex].Spec.Expressions {
+			sources = append(sources, transformSource{
+				source:         fmt.Sprintf("transforms.libraries[%d](%s).expressions[%d]", libIndex, libName, exprIndex),
+				transformType:  expr.Type,
+				expressionText: expr.Expression,
+			})
+		}
+		appendedIndex++
+	}
+
+	// Compile all the expressions and add them to the pipeline.
+	consts, err := transformpipeline.MakeConstants(convertToConfigConstants(transforms.Constants), paths)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	expressions := convertToConfigExpressions(transforms.Expressions)
+	expressionsCompileErrors, err := transformpipeline.CompileExpressions(r.celTransformer, expressions, consts, pipeline, paths)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	compileErrors = append(compileErrors, expressionsCompileErrors...)
+	for exprIndex, expr := range expressions {
+		sources = append(sources, transformSource{
+			source:         fmt.Sprintf("%s[%d]", paths.Expressions, exprIndex),
+			transformType:  expr.Type,
+			expressionText: expr.Expression,
+		})
+	}
+
+	// The access policy is evaluated after all the expressions, so it sees the transformed username and groups.
+	if accessPolicy != nil {
+		compiledAccessPolicy, err := transformpipeline.NewAccessPolicy(accessPolicy)
+		if err != nil {
+			compileErrors = append(compileErrors, fmt.Sprintf("accessPolicy of the identity provider was invalid:\n%s", err.Error()))
+		} else {
+			pipeline.AppendTransformation(compiledAccessPolicy)
+			sources = append(sources, transformSource{source: accessPolicyType, transformType: accessPolicyType})
+		}
+	}
+
+	if len(compileErrors) > 0 {
+		return nil, nil, compileErrors, nil
+	}
+
+	return pipeline, sources, nil, nil
+}
+
+func makeTraceStep(source transformSource, step idtransform.TransformationTraceStep) transformationapi.IdentityTransformationTestRequestTraceStep {
+	traceStep := transformationapi.IdentityTransformationTestRequestTraceStep{
+		Source:     source.source,
+		Type:       source.transformType,
+		Expression: source.expressionText,
+	}
+	if step.Result != nil {
+		traceStep.Username = step.Result.Username
+		traceStep.Groups = step.Result.Groups
+		if !step.Result.AuthenticationAllowed {
+			traceStep.Rejected = true
+			traceStep.Message = step.Result.RejectedAuthenticationMessage
+		}
+	}
+	if step.Err != nil {
+		traceStep.Error = step.Err.Error()
+	}
+	return traceStep
+}
+
+func transformsFromIdentityProvider(idp *configv1alpha1.FederationDomainIdentityProvider) *transformationapi.IdentityTransformationTestRequestTransforms {
+	return &transformationapi.IdentityTransformationTestRequestTransforms{
+		Constants:   convertConstants(idp.Transforms.Constants),
+		Expressions: convertExpressions(idp.Transforms.Expressions),
+		Libraries:   idp.Transforms.Libraries,
+	}
+}
+
+func convertConstants(constants []configv1alpha1.FederationDomainTransformsConstant) []transformationapi.TransformsConstant {
+	converted := make([]transformationapi.TransformsConstant, 0, len(constants))
+	for _, c := range constants {
+		converted = append(converted, transformationapi.TransformsConstant{
+			Name:            c.Name,
+			Type:            c.Type,
+			StringValue:     c.StringValue,
+			StringListValue: c.StringListValue,
+		})
+	}
+	return converted
+}
+
+func convertExpressions(expressions []configv1alpha1.FederationDomainTransformsExpression) []transformationapi.TransformsExpression {
+	converted := make([]transformationapi.TransformsExpression, 0, len(expressions))
+	for _, e := range expressions {
+		converted = append(converted, transformationapi.TransformsExpression{
+			Type:       e.Type,
+			Expression: e.Expression,
+			Message:    e.Message,
+		})
+	}
+	return converted
+}
+
+func convertToConfigConstants(constants []transformationapi.TransformsConstant) []configv1alpha1.FederationDomainTransformsConstant {
+	converted := make([]configv1alpha1.FederationDomainTransformsConstant, 0, len(constants))
+	for _, c := range constants {
+		converted = append(converted, configv1alpha1.FederationDomainTransformsConstant{
+			Name:            c.Name,
+			Type:            c.Type,
+			StringValue:     c.StringValue,
+			StringListValue: c.StringListValue,
+		})
+	}
+	return converted
+}
+
+func convertToConfigExpressions(expressions []transformationapi.TransformsExpression) []configv1alpha1.FederationDomainTransformsExpression {
+	converted := make([]configv1alpha1.FederationDomainTransformsExpression, 0, len(expressions))
+	for _, e := range expressions {
+		converted = append(converted, configv1alpha1.FederationDomainTransformsExpression{
+			Type:       e.Type,
+			Expression: e.Expression,
+			Message:    e.Message,
+		})
+	}
+	return converted
+}
+
+func (r *REST) validateRequest(
+	ctx context.Context,
+	obj runtime.Object,
+	createValidation rest.ValidateObjectFunc,
+	options *metav1.CreateOptions,
+	tracer *trace.Trace,
+) (*transformationapi.IdentityTransformationTestRequest, idtransform.UpstreamClaims, error) {
+	testRequest, ok := obj.(*transformationapi.IdentityTransformationTestRequest)
+	if !ok {
+		traceValidationFailure(tracer, "not an IdentityTransformationTestRequest")
+		return nil, nil, apierrors.NewBadRequest(fmt.Sprintf("not an IdentityTransformationTestRequest: %#v", obj))
+	}
+
+	// Ensure namespace on the object is correct, or error if a conflicting namespace was set in the object.
+	requestNamespace, ok := genericapirequest.NamespaceFrom(ctx)
+	if !ok {
+		msg := "no namespace information found in request context"
+		traceValidationFailure(tracer, msg)
+		return nil, nil, apierrors.NewInternalError(fmt.Errorf(msg))
+	}
+	if err := rest.EnsureObjectNamespaceMatchesRequestNamespace(requestNamespace, testRequest); err != nil {
+		traceValidationFailure(tracer, err.Error())
+		return nil, nil, err
+	}
+	// FederationDomains only exist in the supervisor's namespace.
+	if requestNamespace != r.namespace {
+		msg := fmt.Sprintf("namespace must be %s on IdentityTransformationTestRequest, was %s", r.namespace, requestNamespace)
+		traceValidationFailure(tracer, msg)
+		return nil, nil, apierrors.NewBadRequest(msg)
+	}
+
+	var errs field.ErrorList
+	specPath := field.NewPath("spec")
+	if len(testRequest.Spec.FederationDomainName) == 0 {
+		errs = append(errs, field.Required(specPath.Child("federationDomainName"), ""))
+	}
+	if len(testRequest.Spec.IdentityProviderDisplayName) == 0 {
+		errs = append(errs, field.Required(specPath.Child("identityProviderDisplayName"), ""))
+	}
+	if len(strings.TrimSpace(testRequest.Spec.Username)) == 0 {
+		errs = append(errs, field.Required(specPath.Child("username"), ""))
+	}
+	if testRequest.Spec.Transforms != nil {
+		transformsPath := specPath.Child("transforms")
+		for i, constant := range testRequest.Spec.Transforms.Constants {
+			if constant.Type != "string" && constant.Type != "stringList" {
+				errs = append(errs, field.NotSupported(transformsPath.Child("constants").Index(i).Child("type"),
+					constant.Type, []string{"string", "stringList"}))
+			}
+		}
+		for i, expr := range testRequest.Spec.Transforms.Expressions {
+			if expr.Type != "username/v1" && expr.Type != "groups/v1" && expr.Type != "policy/v1" {
+				errs = append(errs, field.NotSupported(transformsPath.Child("expressions").Index(i).Child("type"),
+					expr.Type, []string{"username/v1", "groups/v1", "policy/v1"}))
+			}
+		}
+	}
+	var upstreamClaims idtransform.UpstreamClaims
+	if testRequest.Spec.UpstreamClaims != nil && len(testRequest.Spec.UpstreamClaims.Raw) > 0 {
+		if err := json.Unmarshal(testRequest.Spec.UpstreamClaims.Raw, &upstreamClaims); err != nil {
+			errs = append(errs, field.Invalid(specPath.Child("upstreamClaims"), string(testRequest.Spec.UpstreamClaims.Raw),
+				fmt.Sprintf("could not be read as a JSON object: %s", err.Error())))
+		}
+	}
+	if len(errs) > 0 {
+		traceValidationFailure(tracer, errs.ToAggregate().Error())
+		return nil, nil, apierrors.NewInvalid(kindFromContext(ctx), testRequest.Name, errs)
+	}
+
+	// just a sanity check, not sure how to honor a dry run on a virtual API
+	if options != nil {
+		if len(options.DryRun) != 0 {
+			traceValidationFailure(tracer, "dryRun not supported")
+			errs := field.ErrorList{field.NotSupported(field.NewPath("dryRun"), options.DryRun, []string(nil))}
+			return nil, nil, apierrors.NewInvalid(kindFromContext(ctx), testRequest.Name, errs)
+		}
+	}
+
+	if createValidation != nil {
+		if err := createValidation(ctx, obj.DeepCopyObject()); err != nil {
+			traceFailureWithError(tracer, "validation webhook", err)
+			return nil, nil, err
+		}
+	}
+
+	return testRequest, upstreamClaims, nil
+}
+
+func traceFailure(t *trace.Trace, failureType string, msg string) {
+	t.Step("failure",
+		trace.Field{Key: "failureType", Value: failureType},
+		trace.Field{Key: "msg", Value: msg},
+	)
+}
+
+func traceValidationFailure(t *trace.Trace, msg string) {
+	traceFailure(t, "request validation", msg)
+}
+
+func traceFailureWithError(t *trace.Trace, failureType string, err error) {
+	t.Step("failure",
+		trace.Field{Key: "failureType", Value: failureType},
+		trace.Field{Key: "msg", Value: err.Error()},
+	)
+}
+
+func name(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return "<unknown>"
+	}
+	return accessor.GetName()
+}
+
+func kindFromContext(ctx context.Context) schema.GroupKind {
+	if info, ok := genericapirequest.RequestInfoFrom(ctx); ok {
+		return schema.GroupKind{Group: info.APIGroup, Kind: "IdentityTransformationTestRequest"}
+	}
+	// this should never happen in practice
+	return transformationapi.Kind("IdentityTransformationTestRequest")
+}
@@ -0,0 +1,453 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package identitytransformationtestrequest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	transformationapi "go.pinniped.dev/generated/latest/apis/supervisor/transformation"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
+)
+
+func TestNew(t *testing.T) {
+	r := NewREST(
+		schema.GroupResource{Group: "bears", Resource: "panda"},
+		nil,
+		nil,
+		nil,
+		nil,
+		"foobar",
+		nil,
+	)
+
+	require.NotNil(t, r)
+	require.True(t, r.NamespaceScoped())
+	require.Equal(t, []string{"pinniped"}, r.Categories())
+	require.Equal(t, "identitytransformationtestrequest", r.GetSingularName())
+
+	require.IsType(t, &transformationapi.IdentityTransformationTestRequest{}, r.New())
+	require.IsType(t, &transformationapi.IdentityTransformationTestRequestList{}, r.NewList())
+
+	ctx := context.Background()
+
+	// check the simple invariants of our no-op list
+	list, err := r.List(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, list)
+	require.IsType(t, &transformationapi.IdentityTransformationTestRequestList{}, list)
+	require.Equal(t, "0", list.(*transformationapi.IdentityTransformationTestRequestList).ResourceVersion)
+	require.NotNil(t, list.(*transformationapi.IdentityTransformationTestRequestList).Items)
+	require.Len(t, list.(*transformationapi.IdentityTransformationTestRequestList).Items, 0)
+
+	// make sure we can turn lists into tables if needed
+	table, err := r.ConvertToTable(ctx, list, nil)
+	require.NoError(t, err)
+	require.NotNil(t, table)
+	require.Equal(t, "0", table.ResourceVersion)
+	require.Nil(t, table.Rows)
+}
+
+func TestCreate(t *testing.T) {
+	namespace := "some-namespace"
+	namespacedContext := genericapirequest.WithNamespace(
+		genericapirequest.WithRequestInfo(
+			genericapirequest.NewContext(),
+			&genericapirequest.RequestInfo{
+				APIGroup:   "transformation.supervisor.pinniped.dev",
+				APIVersion: "v1alpha1",
+				Resource:   "identitytransformationtestrequests",
+			},
+		),
+		namespace,
+	)
+
+	fakeNow := metav1.Now()
+	fakeTimeNowFunc := func() metav1.Time { return fakeNow }
+
+	federationDomain := &v1alpha1.FederationDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-federation-domain", Namespace: namespace},
+		Spec: v1alpha1.FederationDomainSpec{
+			Issuer: "https://issuer.example.com",
+			IdentityProviders: []v1alpha1.FederationDomainIdentityProvider{
+				{
+					DisplayName: "other-idp",
+				},
+				{
+					DisplayName: "some-idp",
+					Transforms: v1alpha1.FederationDomainTransforms{
+						Constants: []v1alpha1.FederationDomainTransformsConstant{
+							{Name: "prefix", Type: "string", StringValue: "idp:"},
+						},
+						Expressions: []v1alpha1.FederationDomainTransformsExpression{
+							{Type: "username/v1", Expression: `strConst.prefix + username`},
+							{Type: "policy/v1", Expression: `username != "idp:blocked"`, Message: "blocked by policy"},
+						},
+						Libraries: []string{"some-library"},
+					},
+					AccessPolicy: &v1alpha1.FederationDomainAccessPolicy{
+						DeniedGroups: []string{"denied"},
+					},
+				},
+			},
+		},
+	}
+
+	library := &v1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-library"},
+		Spec: v1alpha1.IdentityTransformationLibrarySpec{
+			Constants: []v1alpha1.FederationDomainTransformsConstant{
+				{Name: "suffix", Type: "string", StringValue: ":lib"},
+			},
+			Expressions: []v1alpha1.FederationDomainTransformsExpression{
+				{Type: "groups/v1", Expression: `groups.map(g, g + strConst.suffix)`},
+			},
+		},
+	}
+
+	invalidLibrary := &v1alpha1.IdentityTransformationLibrary{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid-library"},
+		Spec: v1alpha1.IdentityTransformationLibrarySpec{
+			Expressions: []v1alpha1.FederationDomainTransformsExpression{
+				{Type: "username/v1", Expression: `42`},
+			},
+		},
+	}
+
+	makeRequest := func(editSpec func(spec *transformationapi.IdentityTransformationTestRequestSpec)) *transformationapi.IdentityTransformationTestRequest {
+		req := &transformationapi.IdentityTransformationTestRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "some-test", Namespace: namespace},
+			Spec: transformationapi.IdentityTransformationTestRequestSpec{
+				FederationDomainName:        "some-federation-domain",
+				IdentityProviderDisplayName: "some-idp",
+				Username:                    "ryan",
+				Groups:                      []string{"b", "a"},
+			},
+		}
+		if editSpec != nil {
+			editSpec(&req.Spec)
+		}
+		return req
+	}
+
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		obj        runtime.Object
+		options    *metav1.CreateOptions
+		wantStatus *transformationapi.IdentityTransformationTestRequestStatus
+		wantErr    string
+		wantErrFn  func(error) bool
+	}{
+		{
+			name:      "wrong type of request object provided",
+			ctx:       namespacedContext,
+			obj:       &metav1.Status{},
+			wantErr:   "not an IdentityTransformationTestRequest: ",
+			wantErrFn: apierrors.IsBadRequest,
+		},
+		{
+			name:      "no namespace in the request context",
+			ctx:       context.Background(),
+			obj:       makeRequest(nil),
+			wantErr:   "Internal error occurred: no namespace information found in request context",
+			wantErrFn: apierrors.IsInternalError,
+		},
+		{
+			name:      "request is not in the supervisor's namespace",
+			ctx:       genericapirequest.WithNamespace(genericapirequest.NewContext(), "other-namespace"),
+			obj:       &transformationapi.IdentityTransformationTestRequest{ObjectMeta: metav1.ObjectMeta{Namespace: "other-namespace"}},
+			wantErr:   "namespace must be some-namespace on IdentityTransformationTestRequest, was other-namespace",
+			wantErrFn: apierrors.IsBadRequest,
+		},
+		{
+			name: "required fields are missing",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				*spec = transformationapi.IdentityTransformationTestRequestSpec{Username: "  "}
+			}),
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`[spec.federationDomainName: Required value, spec.identityProviderDisplayName: Required value, spec.username: Required value]`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name: "upstream claims are not a JSON object",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.UpstreamClaims = &runtime.RawExtension{Raw: []byte(`["a"]`)}
+			}),
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`spec.upstreamClaims: Invalid value: "[\"a\"]": could not be read as a JSON object: ` +
+				`json: cannot unmarshal array into Go value of type idtransform.UpstreamClaims`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name:    "dry run is not supported",
+			ctx:     namespacedContext,
+			obj:     makeRequest(nil),
+			options: &metav1.CreateOptions{DryRun: []string{"All"}},
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`dryRun: Unsupported value: []string{"All"}`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name: "federation domain is not found",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.FederationDomainName = "other-federation-domain"
+			}),
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`spec.federationDomainName: Not found: "other-federation-domain"`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name: "identity provider is not found",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.IdentityProviderDisplayName = "missing-idp"
+			}),
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`spec.identityProviderDisplayName: Invalid value: "missing-idp": ` +
+				`FederationDomain "some-federation-domain" has no identity provider with this displayName`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name: "identity provider without transforms or access policy",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.IdentityProviderDisplayName = "other-idp"
+			}),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Username: "ryan",
+				Groups:   []string{"a", "b"},
+				Trace:    []transformationapi.IdentityTransformationTestRequestTraceStep{},
+			},
+		},
+		{
+			name: "saved transforms allow the authentication",
+			ctx:  namespacedContext,
+			obj:  makeRequest(nil),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Username: "idp:ryan",
+				Groups:   []string{"a:lib", "b:lib"},
+				Trace: []transformationapi.IdentityTransformationTestRequestTraceStep{
+					{
+						Source:     "transforms.libraries[0](some-library).expressions[0]",
+						Type:       "groups/v1",
+						Expression: `groups.map(g, g + strConst.suffix)`,
+						Username:   "ryan",
+						Groups:     []string{"b:lib", "a:lib"},
+					},
+					{
+						Source:     "transforms.expressions[0]",
+						Type:       "username/v1",
+						Expression: `strConst.prefix + username`,
+						Username:   "idp:ryan",
+						Groups:     []string{"b:lib", "a:lib"},
+					},
+					{
+						Source:     "transforms.expressions[1]",
+						Type:       "policy/v1",
+						Expression: `username != "idp:blocked"`,
+						Username:   "idp:ryan",
+						Groups:     []string{"b:lib", "a:lib"},
+					},
+					{
+						Source:   "accessPolicy",
+						Type:     "accessPolicy",
+						Username: "idp:ryan",
+						Groups:   []string{"b:lib", "a:lib"},
+					},
+				},
+			},
+		},
+		{
+			name: "saved transforms reject the authentication",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.Username = "blocked"
+			}),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Rejected: true,
+				Message:  "blocked by policy",
+				Trace: []transformationapi.IdentityTransformationTestRequestTraceStep{
+					{
+						Source:     "transforms.libraries[0](some-library).expressions[0]",
+						Type:       "groups/v1",
+						Expression: `groups.map(g, g + strConst.suffix)`,
+						Username:   "blocked",
+						Groups:     []string{"b:lib", "a:lib"},
+					},
+					{
+						Source:     "transforms.expressions[0]",
+						Type:       "username/v1",
+						Expression: `strConst.prefix + username`,
+						Username:   "idp:blocked",
+						Groups:     []string{"b:lib", "a:lib"},
+					},
+					{
+						Source:     "transforms.expressions[1]",
+						Type:       "policy/v1",
+						Expression: `username != "idp:blocked"`,
+						Username:   "idp:blocked",
+						Groups:     []string{"b:lib", "a:lib"},
+						Rejected:   true,
+						Message:    "blocked by policy",
+					},
+				},
+			},
+		},
+		{
+			name: "candidate transforms are used instead of the saved transforms, and the access policy still applies",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.UpstreamClaims = &runtime.RawExtension{Raw: []byte(`{"dept":"denied"}`)}
+				spec.Transforms = &transformationapi.IdentityTransformationTestRequestTransforms{
+					Expressions: []transformationapi.TransformsExpression{
+						{Type: "groups/v1", Expression: `groups + [upstreamClaims.dept]`},
+					},
+				}
+			}),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Rejected: true,
+				Message:  celtransformer.DefaultPolicyRejectedAuthMessage,
+				Trace: []transformationapi.IdentityTransformationTestRequestTraceStep{
+					{
+						Source:     "transforms.expressions[0]",
+						Type:       "groups/v1",
+						Expression: `groups + [upstreamClaims.dept]`,
+						Username:   "ryan",
+						Groups:     []string{"b", "a", "denied"},
+					},
+					{
+						Source:   "accessPolicy",
+						Type:     "accessPolicy",
+						Username: "ryan",
+						Groups:   []string{"b", "a", "denied"},
+						Rejected: true,
+						Message:  celtransformer.DefaultPolicyRejectedAuthMessage,
+					},
+				},
+			},
+		},
+		{
+			name: "candidate transforms which do not compile",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.Transforms = &transformationapi.IdentityTransformationTestRequestTransforms{
+					Libraries: []string{"missing-library", "invalid-library"},
+					Expressions: []transformationapi.TransformsExpression{
+						{Type: "username/v1", Expression: `username`},
+						{Type: "username/v1", Expression: `42`},
+					},
+				}
+			}),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Error: `transforms.libraries[0] refers to an IdentityTransformationLibrary named "missing-library" which was not found` +
+					"\n\n" + `transforms.libraries[1] refers to an IdentityTransformationLibrary named "invalid-library" which is not valid: see its status for details` +
+					"\n\n" + `transforms.expressions[1].expression was invalid:` + "\n" +
+					`CEL expression should return type "string" but returns type "int"`,
+			},
+		},
+		{
+			name: "candidate transforms have unsupported types",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.Transforms = &transformationapi.IdentityTransformationTestRequestTransforms{
+					Constants: []transformationapi.TransformsConstant{
+						{Name: "foo", Type: "int"},
+					},
+					Expressions: []transformationapi.TransformsExpression{
+						{Type: "username/v2", Expression: `username`},
+					},
+				}
+			}),
+			wantErr: `IdentityTransformationTestRequest.transformation.supervisor.pinniped.dev "some-test" is invalid: ` +
+				`[spec.transforms.constants[0].type: Unsupported value: "int": supported values: "string", "stringList", ` +
+				`spec.transforms.expressions[0].type: Unsupported value: "username/v2": supported values: "username/v1", "groups/v1", "policy/v1"]`,
+			wantErrFn: apierrors.IsInvalid,
+		},
+		{
+			name: "candidate transforms which fail during evaluation",
+			ctx:  namespacedContext,
+			obj: makeRequest(func(spec *transformationapi.IdentityTransformationTestRequestSpec) {
+				spec.UpstreamClaims = &runtime.RawExtension{Raw: []byte(`{"admin":true}`)}
+				spec.Transforms = &transformationapi.IdentityTransformationTestRequestTransforms{
+					Expressions: []transformationapi.TransformsExpression{
+						{Type: "username/v1", Expression: `upstreamClaims.admin`},
+					},
+				}
+			}),
+			wantStatus: &transformationapi.IdentityTransformationTestRequestStatus{
+				Error: "identity transformation at index 0: could not convert expression result to string: " +
+					"type conversion error from bool to 'string'",
+				Trace: []transformationapi.IdentityTransformationTestRequestTraceStep{
+					{
+						Source:     "transforms.expressions[0]",
+						Type:       "username/v1",
+						Expression: `upstreamClaims.admin`,
+						Error:      "could not convert expression result to string: type conversion error from bool to 'string'",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			supervisorClient := supervisorfake.NewSimpleClientset(federationDomain)
+			libraryInformer := supervisorinformers.NewSharedInformerFactory(supervisorClient, 0).
+				Config().V1alpha1().IdentityTransformationLibraries()
+			require.NoError(t, libraryInformer.Informer().GetIndexer().Add(library))
+			require.NoError(t, libraryInformer.Informer().GetIndexer().Add(invalidLibrary))
+
+			celTransformer, err := celtransformer.NewCELTransformer(transformpipeline.MaxExpressionRuntime)
+			require.NoError(t, err)
+
+			r := NewREST(
+				schema.GroupResource{Group: "bears", Resource: "panda"},
+				supervisorClient.ConfigV1alpha1().FederationDomains(namespace),
+				libraryInformer.Lister(),
+				transformpipeline.NewLibraryCache(),
+				celTransformer,
+				namespace,
+				fakeTimeNowFunc,
+			)
+
+			got, err := r.Create(tt.ctx, tt.obj, nil, tt.options)
+
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				require.True(t, tt.wantErrFn(err), "unexpected type of error: %v", err)
+				require.Nil(t, got)
+				return
+			}
+
+			require.NoError(t, err)
+			req := tt.obj.(*transformationapi.IdentityTransformationTestRequest)
+			require.Equal(t, &transformationapi.IdentityTransformationTestRequest{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              req.Name,
+					Namespace:         req.Namespace,
+					CreationTimestamp: fakeNow,
+				},
+				Spec:   req.Spec,
+				Status: *tt.wantStatus,
+			}, got)
+		})
+	}
+}
@@ -1,6 +1,12 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
+// Package whoamirequest provides REST functionality for the WhoAmIRequest resource.
+//
+// The authentication details in the response come from the authenticationinfo.ExtraKey user extra. That extra
+// is only trustworthy when it was set by the impersonation proxy, which does not allow its users to set it.
+// Anyone who may impersonate user extras can also set it directly against the Kubernetes API server, so an
+// unparseable value is ignored rather than failing the request.
 package whoamirequest
 
 import (
@@ -19,6 +25,8 @@ import (
 
 	identityapi "go.pinniped.dev/generated/latest/apis/concierge/identity"
 	identityapivalidation "go.pinniped.dev/generated/latest/apis/concierge/identity/validation"
+	"go.pinniped.dev/internal/authenticationinfo"
+	"go.pinniped.dev/internal/plog"
 )
 
 func NewREST(resource schema.GroupResource) *REST {
@@ -112,6 +120,12 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 
 	auds, _ := authenticator.AudiencesFrom(ctx)
 
+	// only the impersonation proxy knows how the user authenticated, see the authenticationinfo package
+	info, err := authenticationinfo.FromExtra(userInfo.GetExtra())
+	if err != nil {
+		plog.Warning("ignoring authentication info which could not be parsed", "username", userInfo.GetName(), "error", err.Error())
+	}
+
 	out := &identityapi.WhoAmIRequest{
 		Status: identityapi.WhoAmIRequestStatus{
 			KubernetesUserInfo: identityapi.KubernetesUserInfo{
@@ -124,7 +138,20 @@ func (r *REST) Create(ctx context.Context, obj runtime.Object, createValidation
 			},
 		},
 	}
+	if info != nil {
+		out.Status.AuthenticationInfo = &identityapi.AuthenticationInfo{
+			ImpersonationProxy:           true,
+			Authenticator:                info.Authenticator,
+			CredentialExpiration:         info.CredentialExpiration,
+			SupervisorIssuer:             info.SupervisorIssuer,
+			UpstreamIdentityProviderName: info.UpstreamIdentityProviderName,
+		}
+	}
 	for k, v := range userInfo.GetExtra() {
+		if k == authenticationinfo.ExtraKey {
+			continue // already reported above
+		}
+
 		if out.Status.KubernetesUserInfo.User.Extra == nil {
 			out.Status.KubernetesUserInfo.User.Extra = map[string]identityapi.ExtraValue{}
 		}
@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package whoamirequest
@@ -7,8 +7,10 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -16,6 +18,7 @@ import (
 	"k8s.io/apiserver/pkg/authentication/user"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
 	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/utils/ptr"
 
 	identityapi "go.pinniped.dev/generated/latest/apis/concierge/identity"
 )
@@ -191,6 +194,128 @@ func TestCreate(t *testing.T) {
 			},
 			wantErr: ``,
 		},
+		{
+			name: "with authentication info from the impersonation proxy",
+			args: args{
+				ctx: genericapirequest.WithUser(genericapirequest.NewContext(), &user.DefaultInfo{
+					Name: "panda",
+					Extra: map[string][]string{
+						"needs": {"sleep"},
+						"authentication-info.impersonation-proxy.concierge.pinniped.dev": {
+							`{"authenticator":{"apiGroup":"authentication.concierge.pinniped.dev","kind":"JWTAuthenticator","name":"some-jwt-authenticator"},` +
+								`"supervisorIssuer":"https://supervisor.example.com","upstreamIdentityProviderName":"some-idp",` +
+								`"credentialExpiration":"2026-04-13T09:57:00Z"}`,
+						},
+					},
+				}),
+				obj:              &identityapi.WhoAmIRequest{},
+				createValidation: nil,
+				options:          nil,
+			},
+			want: &identityapi.WhoAmIRequest{
+				Status: identityapi.WhoAmIRequestStatus{
+					KubernetesUserInfo: identityapi.KubernetesUserInfo{
+						User: identityapi.UserInfo{
+							Username: "panda",
+							Extra: map[string]identityapi.ExtraValue{
+								"needs": {"sleep"},
+							},
+						},
+					},
+					AuthenticationInfo: &identityapi.AuthenticationInfo{
+						ImpersonationProxy: true,
+						Authenticator: &corev1.TypedLocalObjectReference{
+							APIGroup: ptr.To("authentication.concierge.pinniped.dev"),
+							Kind:     "JWTAuthenticator",
+							Name:     "some-jwt-authenticator",
+						},
+						CredentialExpiration:         ptr.To(metav1.NewTime(time.Date(2026, 4, 13, 9, 57, 0, 0, time.UTC).Local())),
+						SupervisorIssuer:             "https://supervisor.example.com",
+						UpstreamIdentityProviderName: "some-idp",
+					},
+				},
+			},
+			wantErr: ``,
+		},
+		{
+			name: "with empty authentication info from the impersonation proxy",
+			args: args{
+				ctx: genericapirequest.WithUser(genericapirequest.NewContext(), &user.DefaultInfo{
+					Name: "panda",
+					Extra: map[string][]string{
+						"authentication-info.impersonation-proxy.concierge.pinniped.dev": {"{}"},
+					},
+				}),
+				obj:              &identityapi.WhoAmIRequest{},
+				createValidation: nil,
+				options:          nil,
+			},
+			want: &identityapi.WhoAmIRequest{
+				Status: identityapi.WhoAmIRequestStatus{
+					KubernetesUserInfo: identityapi.KubernetesUserInfo{
+						User: identityapi.UserInfo{
+							Username: "panda",
+						},
+					},
+					AuthenticationInfo: &identityapi.AuthenticationInfo{
+						ImpersonationProxy: true,
+					},
+				},
+			},
+			wantErr: ``,
+		},
+		{
+			name: "with invalid authentication info",
+			args: args{
+				ctx: genericapirequest.WithUser(genericapirequest.NewContext(), &user.DefaultInfo{
+					Name: "panda",
+					Extra: map[string][]string{
+						"authentication-info.impersonation-proxy.concierge.pinniped.dev": {"{}", "{}"},
+					},
+				}),
+				obj:              &identityapi.WhoAmIRequest{},
+				createValidation: nil,
+				options:          nil,
+			},
+			want: &identityapi.WhoAmIRequest{
+				Status: identityapi.WhoAmIRequestStatus{
+					KubernetesUserInfo: identityapi.KubernetesUserInfo{
+						User: identityapi.UserInfo{
+							Username: "panda",
+						},
+					},
+				},
+			},
+			wantErr: ``,
+		},
+		{
+			name: "with authentication info which cannot be decoded",
+			args: args{
+				ctx: genericapirequest.WithUser(genericapirequest.NewContext(), &user.DefaultInfo{
+					Name: "panda",
+					Extra: map[string][]string{
+						"needs": {"sleep"},
+						"authentication-info.impersonation-proxy.concierge.pinniped.dev": {"not json"},
+					},
+				}),
+				obj:              &identityapi.WhoAmIRequest{},
+				createValidation: nil,
+				options:          nil,
+			},
+			want: &identityapi.WhoAmIRequest{
+				Status: identityapi.WhoAmIRequestStatus{
+					KubernetesUserInfo: identityapi.KubernetesUserInfo{
+						User: identityapi.UserInfo{
+							Username: "panda",
+							Extra: map[string]identityapi.ExtraValue{
+								"needs": {"sleep"},
+							},
+						},
+					},
+				},
+			},
+			wantErr: ``,
+		},
 	}
 	for _, tt := range tests {
 		tt := tt
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package apiserver
@@ -19,10 +19,14 @@ import (
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	configv1alpha1clientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
+	configlisters "go.pinniped.dev/generated/latest/client/supervisor/listers/config/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/controllerinit"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/pversion"
 	"go.pinniped.dev/internal/registry/clientsecretrequest"
+	"go.pinniped.dev/internal/registry/identitytransformationtestrequest"
 )
 
 type Config struct {
@@ -31,13 +35,17 @@ type Config struct {
 }
 
 type ExtraConfig struct {
-	BuildControllersPostStartHook      controllerinit.RunnerBuilder
-	Scheme                             *runtime.Scheme
-	NegotiatedSerializer               runtime.NegotiatedSerializer
-	ClientSecretSupervisorGroupVersion schema.GroupVersion
-	Secrets                            corev1client.SecretInterface
-	OIDCClients                        configv1alpha1clientset.OIDCClientInterface
-	Namespace                          string
+	BuildControllersPostStartHook        controllerinit.RunnerBuilder
+	Scheme                               *runtime.Scheme
+	NegotiatedSerializer                 runtime.NegotiatedSerializer
+	ClientSecretSupervisorGroupVersion   schema.GroupVersion
+	TransformationSupervisorGroupVersion schema.GroupVersion
+	Secrets                              corev1client.SecretInterface
+	OIDCClients                          configv1alpha1clientset.OIDCClientInterface
+	FederationDomains                    configv1alpha1clientset.FederationDomainInterface
+	IdentityTransformationLibraries      configlisters.IdentityTransformationLibraryLister
+	IdentityTransformationLibraryCache   *transformpipeline.LibraryCache
+	Namespace                            string
 }
 
 type PinnipedServer struct {
@@ -78,6 +86,11 @@ func (c completedConfig) New() (*PinnipedServer, error) {
 		GenericAPIServer: genericServer,
 	}
 
+	celTransformer, err := celtransformer.NewCELTransformer(transformpipeline.MaxExpressionRuntime)
+	if err != nil {
+		return nil, fmt.Errorf("could not create CEL transformer: %w", err)
+	}
+
 	var errs []error //nolint:prealloc
 	for _, f := range []func() (schema.GroupVersionResource, rest.Storage){
 		func() (schema.GroupVersionResource, rest.Storage) {
@@ -94,6 +107,19 @@ func (c completedConfig) New() (*PinnipedServer, error) {
 			)
 			return clientSecretReqGVR, clientSecretReqStorage
 		},
+		func() (schema.GroupVersionResource, rest.Storage) {
+			transformationTestReqGVR := c.ExtraConfig.TransformationSupervisorGroupVersion.WithResource("identitytransformationtestrequests")
+			transformationTestReqStorage := identitytransformationtestrequest.NewREST(
+				transformationTestReqGVR.GroupResource(),
+				c.ExtraConfig.FederationDomains,
+				c.ExtraConfig.IdentityTransformationLibraries,
+				c.ExtraConfig.IdentityTransformationLibraryCache,
+				celTransformer,
+				c.ExtraConfig.Namespace,
+				metav1.Now,
+			)
+			return transformationTestReqGVR, transformationTestReqStorage
+		},
 	} {
 		gvr, storage := f()
 		errs = append(errs,
@@ -1,4 +1,4 @@
-// Copyright 2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package scheme contains code to construct a proper runtime.Scheme for the Supervisor aggregated API.
@@ -9,6 +9,8 @@ import (
 
 	clientsecretapi "go.pinniped.dev/generated/latest/apis/supervisor/clientsecret"
 	clientsecretv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/clientsecret/v1alpha1"
+	transformationapi "go.pinniped.dev/generated/latest/apis/supervisor/transformation"
+	transformationv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/transformation/v1alpha1"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -20,7 +22,7 @@ import (
 
 // New returns a runtime.Scheme for use by the Supervisor aggregated API running with the provided
 // apiGroupSuffix.
-func New(apiGroupSuffix string) (_ *runtime.Scheme, oauth schema.GroupVersion) {
+func New(apiGroupSuffix string) (_ *runtime.Scheme, clientSecret, transformation schema.GroupVersion) {
 	// standard set up of the server side scheme
 	scheme := runtime.NewScheme()
 
@@ -32,19 +34,24 @@ func New(apiGroupSuffix string) (_ *runtime.Scheme, oauth schema.GroupVersion) {
 		schemeBuilder := runtime.NewSchemeBuilder(
 			clientsecretv1alpha1.AddToScheme,
 			clientsecretapi.AddToScheme,
+			transformationv1alpha1.AddToScheme,
+			transformationapi.AddToScheme,
 		)
 		utilruntime.Must(schemeBuilder.AddToScheme(scheme))
-		return scheme, clientsecretv1alpha1.SchemeGroupVersion
+		return scheme, clientsecretv1alpha1.SchemeGroupVersion, transformationv1alpha1.SchemeGroupVersion
 	}
 
-	clientSecretSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(apiGroupSuffix)
+	clientSecretSupervisorGroupData, transformationSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(apiGroupSuffix)
 
 	addToSchemeAtNewGroup(scheme, clientsecretv1alpha1.GroupName, clientSecretSupervisorGroupData.Group, clientsecretv1alpha1.AddToScheme, clientsecretapi.AddToScheme)
+	addToSchemeAtNewGroup(scheme, transformationv1alpha1.GroupName, transformationSupervisorGroupData.Group, transformationv1alpha1.AddToScheme, transformationapi.AddToScheme)
 
 	// manually register conversions and defaulting into the correct scheme since we cannot directly call AddToScheme
 	schemeBuilder := runtime.NewSchemeBuilder(
 		clientsecretv1alpha1.RegisterConversions,
 		clientsecretv1alpha1.RegisterDefaults,
+		transformationv1alpha1.RegisterConversions,
+		transformationv1alpha1.RegisterDefaults,
 	)
 	utilruntime.Must(schemeBuilder.AddToScheme(scheme))
 
@@ -55,8 +62,9 @@ func New(apiGroupSuffix string) (_ *runtime.Scheme, oauth schema.GroupVersion) {
 	// *clientsecretv1alpha1.OIDCClientSecretRequest.  This will do nothing when there is no
 	// defaulting func registered, but it will almost certainly panic if one is added.
 	scheme.Default((*clientsecretv1alpha1.OIDCClientSecretRequest)(nil))
+	scheme.Default((*transformationv1alpha1.IdentityTransformationTestRequest)(nil))
 
-	return scheme, schema.GroupVersion(clientSecretSupervisorGroupData)
+	return scheme, schema.GroupVersion(clientSecretSupervisorGroupData), schema.GroupVersion(transformationSupervisorGroupData)
 }
 
 func addToSchemeAtNewGroup(scheme *runtime.Scheme, oldGroup, newGroup string, funcs ...func(*runtime.Scheme) error) {
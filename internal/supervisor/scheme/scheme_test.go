@@ -1,4 +1,4 @@
-// Copyright 2022 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package scheme
@@ -14,6 +14,8 @@ import (
 
 	clientsecretapi "go.pinniped.dev/generated/latest/apis/supervisor/clientsecret"
 	clientsecretv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/clientsecret/v1alpha1"
+	transformationapi "go.pinniped.dev/generated/latest/apis/supervisor/transformation"
+	transformationv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/transformation/v1alpha1"
 )
 
 func TestNew(t *testing.T) {
@@ -26,6 +28,14 @@ func TestNew(t *testing.T) {
 		Group:   "clientsecret.supervisor.pinniped.dev",
 		Version: runtime.APIVersionInternal,
 	}
+	regularTransformationGV := schema.GroupVersion{
+		Group:   "transformation.supervisor.pinniped.dev",
+		Version: "v1alpha1",
+	}
+	regularTransformationGVInternal := schema.GroupVersion{
+		Group:   "transformation.supervisor.pinniped.dev",
+		Version: runtime.APIVersionInternal,
+	}
 
 	// the canonical other group
 	otherClientSecretGV := schema.GroupVersion{
@@ -36,6 +46,14 @@ func TestNew(t *testing.T) {
 		Group:   "clientsecret.supervisor.walrus.tld",
 		Version: runtime.APIVersionInternal,
 	}
+	otherTransformationGV := schema.GroupVersion{
+		Group:   "transformation.supervisor.walrus.tld",
+		Version: "v1alpha1",
+	}
+	otherTransformationGVInternal := schema.GroupVersion{
+		Group:   "transformation.supervisor.walrus.tld",
+		Version: runtime.APIVersionInternal,
+	}
 
 	// kube's core internal
 	internalGV := schema.GroupVersion{
@@ -44,10 +62,11 @@ func TestNew(t *testing.T) {
 	}
 
 	tests := []struct {
-		name                         string
-		apiGroupSuffix               string
-		want                         map[schema.GroupVersionKind]reflect.Type
-		wantClientSecretGroupVersion schema.GroupVersion
+		name                           string
+		apiGroupSuffix                 string
+		want                           map[schema.GroupVersionKind]reflect.Type
+		wantClientSecretGroupVersion   schema.GroupVersion
+		wantTransformationGroupVersion schema.GroupVersion
 	}{
 		{
 			name:           "regular api group",
@@ -71,6 +90,22 @@ func TestNew(t *testing.T) {
 
 				regularClientSecretGVInternal.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
 
+				regularTransformationGV.WithKind("IdentityTransformationTestRequest"):     reflect.TypeOf(&transformationv1alpha1.IdentityTransformationTestRequest{}).Elem(),
+				regularTransformationGV.WithKind("IdentityTransformationTestRequestList"): reflect.TypeOf(&transformationv1alpha1.IdentityTransformationTestRequestList{}).Elem(),
+
+				regularTransformationGVInternal.WithKind("IdentityTransformationTestRequest"):     reflect.TypeOf(&transformationapi.IdentityTransformationTestRequest{}).Elem(),
+				regularTransformationGVInternal.WithKind("IdentityTransformationTestRequestList"): reflect.TypeOf(&transformationapi.IdentityTransformationTestRequestList{}).Elem(),
+
+				regularTransformationGV.WithKind("CreateOptions"): reflect.TypeOf(&metav1.CreateOptions{}).Elem(),
+				regularTransformationGV.WithKind("DeleteOptions"): reflect.TypeOf(&metav1.DeleteOptions{}).Elem(),
+				regularTransformationGV.WithKind("GetOptions"):    reflect.TypeOf(&metav1.GetOptions{}).Elem(),
+				regularTransformationGV.WithKind("ListOptions"):   reflect.TypeOf(&metav1.ListOptions{}).Elem(),
+				regularTransformationGV.WithKind("PatchOptions"):  reflect.TypeOf(&metav1.PatchOptions{}).Elem(),
+				regularTransformationGV.WithKind("UpdateOptions"): reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
+				regularTransformationGV.WithKind("WatchEvent"):    reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
+
+				regularTransformationGVInternal.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
+
 				// the types below this line do not really matter to us because they are in the core group
 
 				internalGV.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
@@ -88,7 +123,8 @@ func TestNew(t *testing.T) {
 				metav1.Unversioned.WithKind("UpdateOptions"):   reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
 				metav1.Unversioned.WithKind("WatchEvent"):      reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
 			},
-			wantClientSecretGroupVersion: regularClientSecretGV,
+			wantClientSecretGroupVersion:   regularClientSecretGV,
+			wantTransformationGroupVersion: regularTransformationGV,
 		},
 		{
 			name:           "other api group",
@@ -112,6 +148,22 @@ func TestNew(t *testing.T) {
 
 				otherClientSecretGVInternal.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
 
+				otherTransformationGV.WithKind("IdentityTransformationTestRequest"):     reflect.TypeOf(&transformationv1alpha1.IdentityTransformationTestRequest{}).Elem(),
+				otherTransformationGV.WithKind("IdentityTransformationTestRequestList"): reflect.TypeOf(&transformationv1alpha1.IdentityTransformationTestRequestList{}).Elem(),
+
+				otherTransformationGVInternal.WithKind("IdentityTransformationTestRequest"):     reflect.TypeOf(&transformationapi.IdentityTransformationTestRequest{}).Elem(),
+				otherTransformationGVInternal.WithKind("IdentityTransformationTestRequestList"): reflect.TypeOf(&transformationapi.IdentityTransformationTestRequestList{}).Elem(),
+
+				otherTransformationGV.WithKind("CreateOptions"): reflect.TypeOf(&metav1.CreateOptions{}).Elem(),
+				otherTransformationGV.WithKind("DeleteOptions"): reflect.TypeOf(&metav1.DeleteOptions{}).Elem(),
+				otherTransformationGV.WithKind("GetOptions"):    reflect.TypeOf(&metav1.GetOptions{}).Elem(),
+				otherTransformationGV.WithKind("ListOptions"):   reflect.TypeOf(&metav1.ListOptions{}).Elem(),
+				otherTransformationGV.WithKind("PatchOptions"):  reflect.TypeOf(&metav1.PatchOptions{}).Elem(),
+				otherTransformationGV.WithKind("UpdateOptions"): reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
+				otherTransformationGV.WithKind("WatchEvent"):    reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
+
+				otherTransformationGVInternal.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
+
 				// the types below this line do not really matter to us because they are in the core group
 
 				internalGV.WithKind("WatchEvent"): reflect.TypeOf(&metav1.InternalEvent{}).Elem(),
@@ -129,15 +181,17 @@ func TestNew(t *testing.T) {
 				metav1.Unversioned.WithKind("UpdateOptions"):   reflect.TypeOf(&metav1.UpdateOptions{}).Elem(),
 				metav1.Unversioned.WithKind("WatchEvent"):      reflect.TypeOf(&metav1.WatchEvent{}).Elem(),
 			},
-			wantClientSecretGroupVersion: otherClientSecretGV,
+			wantClientSecretGroupVersion:   otherClientSecretGV,
+			wantTransformationGroupVersion: otherTransformationGV,
 		},
 	}
 	for _, tt := range tests {
 		tt := tt
 		t.Run(tt.name, func(t *testing.T) {
-			scheme, clientSecretGV := New(tt.apiGroupSuffix)
+			scheme, clientSecretGV, transformationGV := New(tt.apiGroupSuffix)
 			require.Equal(t, tt.want, scheme.AllKnownTypes())
 			require.Equal(t, tt.wantClientSecretGroupVersion, clientSecretGV)
+			require.Equal(t, tt.wantTransformationGroupVersion, transformationGV)
 		})
 	}
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package server defines the entrypoint for the Pinniped Supervisor server.
@@ -42,6 +42,7 @@ import (
 	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
 	"go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
 	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	configlisters "go.pinniped.dev/generated/latest/client/supervisor/listers/config/v1alpha1"
 	supervisoropenapi "go.pinniped.dev/generated/latest/client/supervisor/openapi"
 	"go.pinniped.dev/internal/apiserviceref"
 	"go.pinniped.dev/internal/config/featuregates"
@@ -64,6 +65,8 @@ import (
 	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/endpointsmanager"
+	"go.pinniped.dev/internal/federationdomain/transformpipeline"
+	"go.pinniped.dev/internal/fositestorage/ldapgroupcache"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/kubeclient"
 	"go.pinniped.dev/internal/leaderelection"
@@ -72,6 +75,7 @@ import (
 	"go.pinniped.dev/internal/secret"
 	"go.pinniped.dev/internal/supervisor/apiserver"
 	supervisorscheme "go.pinniped.dev/internal/supervisor/scheme"
+	"go.pinniped.dev/internal/upstreamldap"
 )
 
 const (
@@ -136,6 +140,8 @@ func prepareControllers(
 	dynamicJWKSProvider jwks.DynamicJWKSProvider,
 	dynamicTLSCertProvider dynamictlscertprovider.DynamicTLSCertProvider,
 	dynamicUpstreamIDPProvider dynamicupstreamprovider.DynamicUpstreamIDPProvider,
+	ldapGroupCache upstreamldap.GroupCache,
+	identityTransformationLibraryCache *transformpipeline.LibraryCache,
 	dynamicServingCertProvider dynamiccert.Private,
 	secretCache *secret.Cache,
 	supervisorDeployment *appsv1.Deployment,
@@ -148,9 +154,10 @@ func prepareControllers(
 	podInfo *downward.PodInfo,
 ) controllerinit.RunnerBuilder {
 	const certificateName string = "pinniped-supervisor-api-tls-serving-certificate"
-	clientSecretSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
+	clientSecretSupervisorGroupData, transformationSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
 	federationDomainInformer := pinnipedInformers.Config().V1alpha1().FederationDomains()
 	oidcClientInformer := pinnipedInformers.Config().V1alpha1().OIDCClients()
+	identityTransformationLibraryInformer := pinnipedInformers.Config().V1alpha1().IdentityTransformationLibraries()
 	secretInformer := kubeInformers.Core().V1().Secrets()
 
 	// Create controller manager.
@@ -176,6 +183,20 @@ func prepareControllers(
 				pinnipedInformers.IDP().V1alpha1().OIDCIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
+				identityTransformationLibraryInformer,
+				kubeInformers.Core().V1().ConfigMaps(),
+				identityTransformationLibraryCache,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
+		WithController(
+			supervisorconfig.NewIdentityTransformationLibraryWatcherController(
+				clock.RealClock{},
+				pinnipedClient,
+				identityTransformationLibraryInformer,
+				federationDomainInformer,
+				identityTransformationLibraryCache,
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -307,6 +328,7 @@ func prepareControllers(
 		WithController(
 			ldapupstreamwatcher.New(
 				dynamicUpstreamIDPProvider,
+				ldapGroupCache,
 				pinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				secretInformer,
@@ -316,6 +338,7 @@ func prepareControllers(
 		WithController(
 			activedirectoryupstreamwatcher.New(
 				dynamicUpstreamIDPProvider,
+				ldapGroupCache,
 				pinnipedClient,
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				secretInformer,
@@ -348,6 +371,17 @@ func prepareControllers(
 			),
 			singletonWorker,
 		).
+		WithController(
+			apicerts.NewAPIServiceUpdaterController(
+				podInfo.Namespace,
+				certificateName,
+				transformationSupervisorGroupData.APIServiceName(),
+				aggregatorClient,
+				secretInformer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
 		WithController(
 			apicerts.NewCertsObserverController(
 				podInfo.Namespace,
@@ -394,7 +428,7 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 	featuregates.DisableKubeFeatureGate(features.UnauthenticatedHTTP2DOSMitigation)
 
 	serverInstallationNamespace := podInfo.Namespace
-	clientSecretSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
+	clientSecretSupervisorGroupData, _ := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
 
 	apiServiceRef, err := apiserviceref.New(clientSecretSupervisorGroupData.APIServiceName())
 	if err != nil {
@@ -451,6 +485,12 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 	dynamicUpstreamIDPProvider := dynamicupstreamprovider.NewDynamicUpstreamIDPProvider()
 	secretCache := secret.Cache{}
 
+	// The compiled IdentityTransformationLibraries are shared by the controllers and by the aggregated API.
+	identityTransformationLibraryCache := transformpipeline.NewLibraryCache()
+
+	// The LDAP group cache is used while serving requests, so it must be allowed to write to kube storage on non-leaders.
+	ldapGroupCache := ldapgroupcache.New(clientWithoutLeaderElection.Kubernetes.CoreV1().Secrets(serverInstallationNamespace), time.Now)
+
 	// OIDC endpoints will be served by the endpoints manager, and any non-OIDC paths will fallback to the healthMux.
 	oidProvidersManager := endpointsmanager.NewManager(
 		healthMux,
@@ -461,9 +501,9 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		client.PinnipedSupervisor.ConfigV1alpha1().OIDCClients(serverInstallationNamespace),
 	)
 
-	// Get the "real" name of the client secret supervisor API group (i.e., the API group name with the
-	// injected suffix).
-	scheme, clientSecretGV := supervisorscheme.New(*cfg.APIGroupSuffix)
+	// Get the "real" names of the client secret and transformation supervisor API groups (i.e., the API group
+	// names with the injected suffix).
+	scheme, clientSecretGV, transformationGV := supervisorscheme.New(*cfg.APIGroupSuffix)
 
 	buildControllersFunc := prepareControllers(
 		cfg,
@@ -471,6 +511,8 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		dynamicJWKSProvider,
 		dynamicTLSCertProvider,
 		dynamicUpstreamIDPProvider,
+		ldapGroupCache,
+		identityTransformationLibraryCache,
 		dynamicServingCertProvider,
 		&secretCache,
 		supervisorDeployment,
@@ -493,8 +535,12 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		*cfg.AggregatedAPIServerPort,
 		scheme,
 		clientSecretGV,
+		transformationGV,
 		clientWithoutLeaderElection.Kubernetes.CoreV1().Secrets(serverInstallationNamespace),
 		client.PinnipedSupervisor.ConfigV1alpha1().OIDCClients(serverInstallationNamespace),
+		client.PinnipedSupervisor.ConfigV1alpha1().FederationDomains(serverInstallationNamespace),
+		pinnipedInformers.Config().V1alpha1().IdentityTransformationLibraries().Lister(),
+		identityTransformationLibraryCache,
 		serverInstallationNamespace,
 	)
 	if err != nil {
@@ -533,6 +579,11 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		}
 
 		c := ptls.Default(nil)
+		if e.RequestClientCertificates {
+			// Client certificates are optional, and they are verified by the token endpoint for the OIDCClients
+			// which use the tls_client_auth client authentication method, since each client has its own CA bundle.
+			c.ClientAuth = tls.RequestClientCert
+		}
 		c.GetCertificate = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
 			cert := dynamicTLSCertProvider.GetTLSCert(strings.ToLower(info.ServerName))
 			foundServerNameCert := cert != nil
@@ -603,8 +654,12 @@ func getAggregatedAPIServerConfig(
 	aggregatedAPIServerPort int64,
 	scheme *runtime.Scheme,
 	clientSecretSupervisorGroupVersion schema.GroupVersion,
+	transformationSupervisorGroupVersion schema.GroupVersion,
 	secrets corev1client.SecretInterface,
 	oidcClients v1alpha1.OIDCClientInterface,
+	federationDomains v1alpha1.FederationDomainInterface,
+	identityTransformationLibraries configlisters.IdentityTransformationLibraryLister,
+	identityTransformationLibraryCache *transformpipeline.LibraryCache,
 	serverInstallationNamespace string,
 ) (*apiserver.Config, error) {
 	codecs := serializer.NewCodecFactory(scheme)
@@ -614,7 +669,7 @@ func getAggregatedAPIServerConfig(
 
 	recommendedOptions := genericoptions.NewRecommendedOptions(
 		defaultEtcdPathPrefix,
-		codecs.LegacyCodec(clientSecretSupervisorGroupVersion),
+		codecs.LegacyCodec(clientSecretSupervisorGroupVersion, transformationSupervisorGroupVersion),
 	)
 	recommendedOptions.Etcd = nil // turn off etcd storage because we don't need it yet
 	recommendedOptions.SecureServing.ServerCert.GeneratedCert = dynamicCertProvider
@@ -660,13 +715,17 @@ func getAggregatedAPIServerConfig(
 	apiServerConfig := &apiserver.Config{
 		GenericConfig: serverConfig,
 		ExtraConfig: apiserver.ExtraConfig{
-			BuildControllersPostStartHook:      buildControllers,
-			Scheme:                             scheme,
-			NegotiatedSerializer:               codecs,
-			ClientSecretSupervisorGroupVersion: clientSecretSupervisorGroupVersion,
-			Secrets:                            secrets,
-			OIDCClients:                        oidcClients,
-			Namespace:                          serverInstallationNamespace,
+			BuildControllersPostStartHook:        buildControllers,
+			Scheme:                               scheme,
+			NegotiatedSerializer:                 codecs,
+			ClientSecretSupervisorGroupVersion:   clientSecretSupervisorGroupVersion,
+			TransformationSupervisorGroupVersion: transformationSupervisorGroupVersion,
+			Secrets:                              secrets,
+			OIDCClients:                          oidcClients,
+			FederationDomains:                    federationDomains,
+			IdentityTransformationLibraries:      identityTransformationLibraries,
+			IdentityTransformationLibraryCache:   identityTransformationLibraryCache,
+			Namespace:                            serverInstallationNamespace,
 		},
 	}
 	return apiServerConfig, nil
@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package testutil
@@ -85,6 +85,33 @@ func FullyCapableOIDCClientAndStorageSecret(
 	return OIDCClientAndStorageSecret(t, namespace, clientID, clientUID, allGrantTypes, allScopes, redirectURI, hashes, validateFunc)
 }
 
+// ServiceOIDCClientAndStorageSecret returns an OIDC client which is allowed to use all grant types and all scopes
+// that are supported by the Supervisor for dynamic clients, including the client_credentials grant using the specified
+// service identity, along with a corresponding client secret storage Secret.
+func ServiceOIDCClientAndStorageSecret(
+	t *testing.T,
+	namespace string,
+	clientID string,
+	clientUID string,
+	redirectURI string,
+	serviceIdentity *configv1alpha1.OIDCClientServiceIdentity,
+	hashes []string,
+	validateFunc OIDCClientValidatorFunc,
+) (*configv1alpha1.OIDCClient, *corev1.Secret) {
+	allGrantTypes := []configv1alpha1.GrantType{
+		"authorization_code", "urn:ietf:params:oauth:grant-type:token-exchange", "refresh_token", "client_credentials",
+	}
+
+	oidcClient := newOIDCClient(namespace, clientID, clientUID, redirectURI, allGrantTypes, allDynamicClientScopes())
+	oidcClient.Spec.ServiceIdentity = serviceIdentity
+	secret := OIDCClientSecretStorageSecretForUID(t, namespace, clientUID, hashes)
+
+	valid, conditions, _ := validateFunc(oidcClient, secret, bcrypt.MinCost)
+	require.True(t, valid, "Test's OIDCClient should have been valid. See conditions for errors: %s", conditions)
+
+	return oidcClient, secret
+}
+
 // OIDCClientAndStorageSecret returns an OIDC client which is allowed to use the specified grant types and scopes,
 // along with a corresponding client secret storage Secret. It also validates the client to make sure that the specified
 // combination of grant types and scopes is considered valid before returning the client.
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidctestutil
@@ -13,6 +13,7 @@ import (
 
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes/fake"
 	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -323,7 +324,9 @@ func castStoredAuthorizeRequest(t *testing.T, storedAuthorizeRequest fosite.Requ
 
 // FilterClientSecretCreateActions ignores any reads made to get a storage secret corresponding to an OIDCClient, since these
 // are normal actions when the request is using a dynamic client's client_id, and we don't need to make assertions
-// about these Secrets since they are not related to session storage.
+// about these Secrets since they are not related to session storage. It also ignores the reads and deletes of pushed
+// authorization requests, which are used up by the authorization endpoint before any session storage happens, and the
+// reads and updates of TOTP and WebAuthn enrollments, which are checked before any session storage happens.
 func FilterClientSecretCreateActions(actions []kubetesting.Action) []kubetesting.Action {
 	filtered := make([]kubetesting.Action, 0, len(actions))
 	for _, action := range actions {
@@ -332,6 +335,30 @@ func FilterClientSecretCreateActions(actions []kubetesting.Action) []kubetesting
 			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-oidc-client-secret-") {
 				continue // filter out OIDCClient's storage secret reads
 			}
+			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-pushed-authorize-request-") {
+				continue // filter out reads of pushed authorization requests
+			}
+			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-totp-enrollment-") {
+				continue // filter out reads of TOTP enrollments
+			}
+			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-webauthn-enrollment-") {
+				continue // filter out reads of WebAuthn enrollments
+			}
+		}
+		if action.Matches("update", "secrets") {
+			updateAction := action.(kubetesting.UpdateAction)
+			if strings.HasPrefix(updateAction.GetObject().(metav1.Object).GetName(), "pinniped-storage-totp-enrollment-") {
+				continue // filter out the update of a TOTP enrollment when its one-time password is used
+			}
+			if strings.HasPrefix(updateAction.GetObject().(metav1.Object).GetName(), "pinniped-storage-webauthn-enrollment-") {
+				continue // filter out the update of the signature counter of a WebAuthn enrollment
+			}
+		}
+		if action.Matches("delete", "secrets") {
+			deleteAction := action.(kubetesting.DeleteAction)
+			if strings.HasPrefix(deleteAction.GetName(), "pinniped-storage-pushed-authorize-request-") {
+				continue // filter out the deletion of a pushed authorization request when it is used
+			}
 		}
 		filtered = append(filtered, action) // otherwise include the action
 	}
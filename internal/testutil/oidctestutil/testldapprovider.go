@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidctestutil
@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 
 	"go.pinniped.dev/internal/authenticators"
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/idtransform"
 )
@@ -19,14 +20,17 @@ func NewTestUpstreamLDAPIdentityProviderBuilder() *TestUpstreamLDAPIdentityProvi
 }
 
 type TestUpstreamLDAPIdentityProviderBuilder struct {
-	name                           string
-	resourceUID                    types.UID
-	url                            *url.URL
-	authenticateFunc               func(ctx context.Context, username, password string) (*authenticators.Response, bool, error)
-	performRefreshErr              error
-	performRefreshGroups           []string
-	displayNameForFederationDomain string
-	transformsForFederationDomain  *idtransform.TransformationPipeline
+	name                               string
+	resourceUID                        types.UID
+	url                                *url.URL
+	authenticateFunc                   func(ctx context.Context, username, password string) (*authenticators.Response, bool, error)
+	performRefreshErr                  error
+	performRefreshGroups               []string
+	displayNameForFederationDomain     string
+	transformsForFederationDomain      *idtransform.TransformationPipeline
+	requireTOTPForFederationDomain     bool
+	requireWebAuthnForFederationDomain bool
+	routingForFederationDomain         *idprouting.Rules
 }
 
 func (t *TestUpstreamLDAPIdentityProviderBuilder) WithName(name string) *TestUpstreamLDAPIdentityProviderBuilder {
@@ -69,6 +73,21 @@ func (t *TestUpstreamLDAPIdentityProviderBuilder) WithTransformsForFederationDom
 	return t
 }
 
+func (t *TestUpstreamLDAPIdentityProviderBuilder) WithRequireTOTPForFederationDomain() *TestUpstreamLDAPIdentityProviderBuilder {
+	t.requireTOTPForFederationDomain = true
+	return t
+}
+
+func (t *TestUpstreamLDAPIdentityProviderBuilder) WithRequireWebAuthnForFederationDomain() *TestUpstreamLDAPIdentityProviderBuilder {
+	t.requireWebAuthnForFederationDomain = true
+	return t
+}
+
+func (t *TestUpstreamLDAPIdentityProviderBuilder) WithRoutingForFederationDomain(routing *idprouting.Rules) *TestUpstreamLDAPIdentityProviderBuilder {
+	t.routingForFederationDomain = routing
+	return t
+}
+
 func (t *TestUpstreamLDAPIdentityProviderBuilder) Build() *TestUpstreamLDAPIdentityProvider {
 	if t.displayNameForFederationDomain == "" {
 		// default it to the CR name
@@ -79,26 +98,32 @@ func (t *TestUpstreamLDAPIdentityProviderBuilder) Build() *TestUpstreamLDAPIdent
 		t.transformsForFederationDomain = idtransform.NewTransformationPipeline()
 	}
 	return &TestUpstreamLDAPIdentityProvider{
-		Name:                           t.name,
-		ResourceUID:                    t.resourceUID,
-		URL:                            t.url,
-		AuthenticateFunc:               t.authenticateFunc,
-		PerformRefreshErr:              t.performRefreshErr,
-		PerformRefreshGroups:           t.performRefreshGroups,
-		DisplayNameForFederationDomain: t.displayNameForFederationDomain,
-		TransformsForFederationDomain:  t.transformsForFederationDomain,
+		Name:                               t.name,
+		ResourceUID:                        t.resourceUID,
+		URL:                                t.url,
+		AuthenticateFunc:                   t.authenticateFunc,
+		PerformRefreshErr:                  t.performRefreshErr,
+		PerformRefreshGroups:               t.performRefreshGroups,
+		DisplayNameForFederationDomain:     t.displayNameForFederationDomain,
+		TransformsForFederationDomain:      t.transformsForFederationDomain,
+		RequireTOTPForFederationDomain:     t.requireTOTPForFederationDomain,
+		RequireWebAuthnForFederationDomain: t.requireWebAuthnForFederationDomain,
+		RoutingForFederationDomain:         t.routingForFederationDomain,
 	}
 }
 
 type TestUpstreamLDAPIdentityProvider struct {
-	Name                           string
-	ResourceUID                    types.UID
-	URL                            *url.URL
-	AuthenticateFunc               func(ctx context.Context, username, password string) (*authenticators.Response, bool, error)
-	PerformRefreshErr              error
-	PerformRefreshGroups           []string
-	DisplayNameForFederationDomain string
-	TransformsForFederationDomain  *idtransform.TransformationPipeline
+	Name                               string
+	ResourceUID                        types.UID
+	URL                                *url.URL
+	AuthenticateFunc                   func(ctx context.Context, username, password string) (*authenticators.Response, bool, error)
+	PerformRefreshErr                  error
+	PerformRefreshGroups               []string
+	DisplayNameForFederationDomain     string
+	TransformsForFederationDomain      *idtransform.TransformationPipeline
+	RequireTOTPForFederationDomain     bool
+	RequireWebAuthnForFederationDomain bool
+	RoutingForFederationDomain         *idprouting.Rules
 
 	// Fields for tracking actual calls make to mock functions.
 	performRefreshCallCount int
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidctestutil
@@ -11,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	"go.pinniped.dev/internal/federationdomain/idprouting"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -75,10 +76,13 @@ type TestUpstreamOIDCIdentityProvider struct {
 	GroupsClaim                    string
 	Scopes                         []string
 	AdditionalAuthcodeParams       map[string]string
+	PassthroughAuthcodeParams      []string
 	AdditionalClaimMappings        map[string]string
+	AdditionalClaimsForTransforms  []string
 	AllowPasswordGrant             bool
 	DisplayNameForFederationDomain string
 	TransformsForFederationDomain  *idtransform.TransformationPipeline
+	RoutingForFederationDomain     *idprouting.Rules
 
 	ExchangeAuthcodeAndValidateTokensFunc func(
 		ctx context.Context,
@@ -122,10 +126,18 @@ func (u *TestUpstreamOIDCIdentityProvider) GetAdditionalAuthcodeParams() map[str
 	return u.AdditionalAuthcodeParams
 }
 
+func (u *TestUpstreamOIDCIdentityProvider) GetPassthroughAuthcodeParams() []string {
+	return u.PassthroughAuthcodeParams
+}
+
 func (u *TestUpstreamOIDCIdentityProvider) GetAdditionalClaimMappings() map[string]string {
 	return u.AdditionalClaimMappings
 }
 
+func (u *TestUpstreamOIDCIdentityProvider) GetAdditionalClaimsForTransformations() []string {
+	return u.AdditionalClaimsForTransforms
+}
+
 func (u *TestUpstreamOIDCIdentityProvider) GetName() string {
 	return u.Name
 }
@@ -303,7 +315,9 @@ type TestUpstreamOIDCIdentityProviderBuilder struct {
 	authorizationURL                     url.URL
 	hasUserInfoURL                       bool
 	additionalAuthcodeParams             map[string]string
+	passthroughAuthcodeParams            []string
 	additionalClaimMappings              map[string]string
+	additionalClaimsForTransforms        []string
 	allowPasswordGrant                   bool
 	authcodeExchangeErr                  error
 	passwordGrantErr                     error
@@ -312,6 +326,7 @@ type TestUpstreamOIDCIdentityProviderBuilder struct {
 	validateTokenAndMergeWithUserInfoErr error
 	displayNameForFederationDomain       string
 	transformsForFederationDomain        *idtransform.TransformationPipeline
+	routingForFederationDomain           *idprouting.Rules
 }
 
 func (u *TestUpstreamOIDCIdentityProviderBuilder) WithName(value string) *TestUpstreamOIDCIdentityProviderBuilder {
@@ -392,11 +407,21 @@ func (u *TestUpstreamOIDCIdentityProviderBuilder) WithAdditionalAuthcodeParams(p
 	return u
 }
 
+func (u *TestUpstreamOIDCIdentityProviderBuilder) WithPassthroughAuthcodeParams(names []string) *TestUpstreamOIDCIdentityProviderBuilder {
+	u.passthroughAuthcodeParams = names
+	return u
+}
+
 func (u *TestUpstreamOIDCIdentityProviderBuilder) WithAdditionalClaimMappings(m map[string]string) *TestUpstreamOIDCIdentityProviderBuilder {
 	u.additionalClaimMappings = m
 	return u
 }
 
+func (u *TestUpstreamOIDCIdentityProviderBuilder) WithAdditionalClaimsForTransformations(claims []string) *TestUpstreamOIDCIdentityProviderBuilder {
+	u.additionalClaimsForTransforms = claims
+	return u
+}
+
 func (u *TestUpstreamOIDCIdentityProviderBuilder) WithRefreshToken(token string) *TestUpstreamOIDCIdentityProviderBuilder {
 	u.refreshToken = &oidctypes.RefreshToken{Token: token}
 	return u
@@ -471,6 +496,11 @@ func (u *TestUpstreamOIDCIdentityProviderBuilder) WithTransformsForFederationDom
 	return u
 }
 
+func (u *TestUpstreamOIDCIdentityProviderBuilder) WithRoutingForFederationDomain(routing *idprouting.Rules) *TestUpstreamOIDCIdentityProviderBuilder {
+	u.routingForFederationDomain = routing
+	return u
+}
+
 func (u *TestUpstreamOIDCIdentityProviderBuilder) Build() *TestUpstreamOIDCIdentityProvider {
 	if u.displayNameForFederationDomain == "" {
 		// default it to the CR name
@@ -492,9 +522,12 @@ func (u *TestUpstreamOIDCIdentityProviderBuilder) Build() *TestUpstreamOIDCIdent
 		AuthorizationURL:               u.authorizationURL,
 		UserInfoURL:                    u.hasUserInfoURL,
 		AdditionalAuthcodeParams:       u.additionalAuthcodeParams,
+		PassthroughAuthcodeParams:      u.passthroughAuthcodeParams,
 		AdditionalClaimMappings:        u.additionalClaimMappings,
+		AdditionalClaimsForTransforms:  u.additionalClaimsForTransforms,
 		DisplayNameForFederationDomain: u.displayNameForFederationDomain,
 		TransformsForFederationDomain:  u.transformsForFederationDomain,
+		RoutingForFederationDomain:     u.routingForFederationDomain,
 		ExchangeAuthcodeAndValidateTokensFunc: func(ctx context.Context, authcode string, pkceCodeVerifier oidcpkce.Code, expectedIDTokenNonce nonce.Nonce) (*oidctypes.Token, error) {
 			if u.authcodeExchangeErr != nil {
 				return nil, u.authcodeExchangeErr
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package testidplister
@@ -45,6 +45,7 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) GetIdentityProviders
 			Provider:            testIDP,
 			SessionProviderType: psession.ProviderTypeOIDC,
 			Transforms:          testIDP.TransformsForFederationDomain,
+			Routing:             testIDP.RoutingForFederationDomain,
 		}
 		fdIDPs[i] = fdIDP
 		i++
@@ -55,6 +56,9 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) GetIdentityProviders
 			Provider:            testIDP,
 			SessionProviderType: psession.ProviderTypeLDAP,
 			Transforms:          testIDP.TransformsForFederationDomain,
+			RequireTOTP:         testIDP.RequireTOTPForFederationDomain,
+			RequireWebAuthn:     testIDP.RequireWebAuthnForFederationDomain,
+			Routing:             testIDP.RoutingForFederationDomain,
 		}
 		fdIDPs[i] = fdIDP
 		i++
@@ -65,6 +69,9 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) GetIdentityProviders
 			Provider:            testIDP,
 			SessionProviderType: psession.ProviderTypeActiveDirectory,
 			Transforms:          testIDP.TransformsForFederationDomain,
+			RequireTOTP:         testIDP.RequireTOTPForFederationDomain,
+			RequireWebAuthn:     testIDP.RequireWebAuthnForFederationDomain,
+			Routing:             testIDP.RoutingForFederationDomain,
 		}
 		fdIDPs[i] = fdIDP
 		i++
@@ -87,6 +94,7 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByDis
 				Provider:            testIDP,
 				SessionProviderType: psession.ProviderTypeOIDC,
 				Transforms:          testIDP.TransformsForFederationDomain,
+				Routing:             testIDP.RoutingForFederationDomain,
 			}, nil
 		}
 	}
@@ -97,6 +105,9 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByDis
 				Provider:            testIDP,
 				SessionProviderType: psession.ProviderTypeLDAP,
 				Transforms:          testIDP.TransformsForFederationDomain,
+				RequireTOTP:         testIDP.RequireTOTPForFederationDomain,
+				RequireWebAuthn:     testIDP.RequireWebAuthnForFederationDomain,
+				Routing:             testIDP.RoutingForFederationDomain,
 			}, nil
 		}
 	}
@@ -107,12 +118,24 @@ func (t *TestFederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByDis
 				Provider:            testIDP,
 				SessionProviderType: psession.ProviderTypeActiveDirectory,
 				Transforms:          testIDP.TransformsForFederationDomain,
+				RequireTOTP:         testIDP.RequireTOTPForFederationDomain,
+				RequireWebAuthn:     testIDP.RequireWebAuthnForFederationDomain,
+				Routing:             testIDP.RoutingForFederationDomain,
 			}, nil
 		}
 	}
 	return nil, fmt.Errorf("did not find IDP with name %q", upstreamIDPDisplayName)
 }
 
+func (t *TestFederationDomainIdentityProvidersListerFinder) FindUpstreamIDPByLoginHint(loginHint string) (resolvedprovider.FederationDomainResolvedIdentityProvider, error) {
+	for _, fdIDP := range t.GetIdentityProviders() {
+		if fdIDP.GetRouting().Matches(loginHint) {
+			return fdIDP, nil
+		}
+	}
+	return nil, fmt.Errorf("did not find IDP for login hint %q", loginHint)
+}
+
 func (t *TestFederationDomainIdentityProvidersListerFinder) SetOIDCIdentityProviders(providers []*oidctestutil.TestUpstreamOIDCIdentityProvider) {
 	t.upstreamOIDCIdentityProviders = providers
 }
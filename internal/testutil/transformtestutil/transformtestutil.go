@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package transformtestutil
@@ -73,3 +73,24 @@ func NewPipeline(t *testing.T, transforms []celtransformer.CELTransformation) *i
 
 	return p
 }
+
+func NewPipelineWithAdditionalClaims(
+	t *testing.T,
+	transforms []celtransformer.CELTransformation,
+	additionalClaims []*celtransformer.AdditionalClaimExpression,
+) *idtransform.TransformationPipeline {
+	t.Helper()
+
+	transformer, err := celtransformer.NewCELTransformer(5 * time.Second)
+	require.NoError(t, err)
+
+	p := NewPipeline(t, transforms)
+
+	for _, additionalClaim := range additionalClaims {
+		compiledClaim, err := transformer.CompileAdditionalClaim(additionalClaim, nil)
+		require.NoError(t, err)
+		p.AppendAdditionalClaim(compiledClaim)
+	}
+
+	return p
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webauthntestutil implements a fake WebAuthn authenticator, which creates the same responses as a
+// browser would return from navigator.credentials.create() and navigator.credentials.get().
+package webauthntestutil
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	FlagUserPresent            = 0x01
+	FlagAttestedCredentialData = 0x40
+)
+
+// Authenticator is a fake authenticator with a single P-256 credential.
+type Authenticator struct {
+	CredentialID []byte
+	PrivateKey   *ecdsa.PrivateKey
+
+	// SignCount is the signature counter, which is incremented before each assertion.
+	SignCount uint32
+	// NoSignCount makes the authenticator always report a zero signature counter.
+	NoSignCount bool
+}
+
+func NewAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+
+	return &Authenticator{CredentialID: credentialID, PrivateKey: privateKey}
+}
+
+// Register returns the clientDataJSON and attestationObject of a new registration.
+func (a *Authenticator) Register(t *testing.T, origin string, rpID string, challenge string) ([]byte, []byte) {
+	t.Helper()
+
+	clientDataJSON := ClientDataJSON(t, "webauthn.create", challenge, origin)
+	authData := AuthenticatorData(rpID, FlagUserPresent|FlagAttestedCredentialData, 0, a.AttestedCredentialData())
+	attestationObject := EncodeCBOR(map[any]any{
+		"fmt":      "none",
+		"attStmt":  map[any]any{},
+		"authData": authData,
+	})
+	return clientDataJSON, attestationObject
+}
+
+// Assert returns the clientDataJSON, authenticatorData and signature of a new assertion.
+func (a *Authenticator) Assert(t *testing.T, origin string, rpID string, challenge string) ([]byte, []byte, []byte) {
+	t.Helper()
+
+	signCount := uint32(0)
+	if !a.NoSignCount {
+		a.SignCount++
+		signCount = a.SignCount
+	}
+	clientDataJSON := ClientDataJSON(t, "webauthn.get", challenge, origin)
+	authData := AuthenticatorData(rpID, FlagUserPresent, signCount, nil)
+	return clientDataJSON, authData, a.Sign(t, authData, clientDataJSON)
+}
+
+// Sign signs the authenticator data and the hash of the client data, like authenticators do for assertions.
+func (a *Authenticator) Sign(t *testing.T, authData []byte, clientDataJSON []byte) []byte {
+	t.Helper()
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.PrivateKey, digest[:])
+	require.NoError(t, err)
+	return signature
+}
+
+// AttestedCredentialData returns the attested credential data of the authenticator's credential.
+func (a *Authenticator) AttestedCredentialData() []byte {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 16)) // the AAGUID is all zeros when attestation is not requested
+	_ = binary.Write(&buf, binary.BigEndian, uint16(len(a.CredentialID)))
+	buf.Write(a.CredentialID)
+	buf.Write(a.COSEKey())
+	return buf.Bytes()
+}
+
+// COSEKey returns the COSE_Key of the authenticator's public key.
+func (a *Authenticator) COSEKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.PrivateKey.X.FillBytes(x)
+	a.PrivateKey.Y.FillBytes(y)
+	return EncodeCBOR(map[any]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: x,
+		-3: y,
+	})
+}
+
+// ClientDataJSON returns the client data which browsers collect.
+func ClientDataJSON(t *testing.T, clientDataType string, challenge string, origin string) []byte {
+	t.Helper()
+
+	clientDataJSON, err := json.Marshal(map[string]any{
+		"type":        clientDataType,
+		"challenge":   challenge,
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	require.NoError(t, err)
+	return clientDataJSON
+}
+
+// AuthenticatorData returns the authenticator data for the relying party.
+func AuthenticatorData(rpID string, flags byte, signCount uint32, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	var buf bytes.Buffer
+	buf.Write(rpIDHash[:])
+	buf.WriteByte(flags)
+	_ = binary.Write(&buf, binary.BigEndian, signCount)
+	buf.Write(attestedCredentialData)
+	return buf.Bytes()
+}
+
+// EncodeCBOR encodes ints, strings, byte strings, bools, nil, []any and map[any]any as CBOR. Maps are encoded
+// with their keys sorted by their encoding, like the canonical CBOR which authenticators use.
+func EncodeCBOR(v any) []byte {
+	var buf bytes.Buffer
+	encodeCBOR(&buf, v)
+	return buf.Bytes()
+}
+
+func encodeCBOR(buf *bytes.Buffer, v any) {
+	switch v := v.(type) {
+	case int:
+		if v >= 0 {
+			encodeCBORHead(buf, 0, uint64(v))
+		} else {
+			encodeCBORHead(buf, 1, uint64(-1-v))
+		}
+	case []byte:
+		encodeCBORHead(buf, 2, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		encodeCBORHead(buf, 3, uint64(len(v)))
+		buf.WriteString(v)
+	case []any:
+		encodeCBORHead(buf, 4, uint64(len(v)))
+		for _, item := range v {
+			encodeCBOR(buf, item)
+		}
+	case map[any]any:
+		type entry struct{ key, value []byte }
+		entries := make([]entry, 0, len(v))
+		for key, value := range v {
+			entries = append(entries, entry{key: EncodeCBOR(key), value: EncodeCBOR(value)})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if len(entries[i].key) != len(entries[j].key) {
+				return len(entries[i].key) < len(entries[j].key)
+			}
+			return bytes.Compare(entries[i].key, entries[j].key) < 0
+		})
+		encodeCBORHead(buf, 5, uint64(len(v)))
+		for _, e := range entries {
+			buf.Write(e.key)
+			buf.Write(e.value)
+		}
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case nil:
+		buf.WriteByte(0xf6)
+	default:
+		panic("unsupported type for CBOR encoding")
+	}
+}
+
+func encodeCBORHead(buf *bytes.Buffer, majorType byte, argument uint64) {
+	switch {
+	case argument < 24:
+		buf.WriteByte(majorType<<5 | byte(argument))
+	case argument <= 0xff:
+		buf.WriteByte(majorType<<5 | 24)
+		buf.WriteByte(byte(argument))
+	case argument <= 0xffff:
+		buf.WriteByte(majorType<<5 | 25)
+		_ = binary.Write(buf, binary.BigEndian, uint16(argument))
+	case argument <= 0xffffffff:
+		buf.WriteByte(majorType<<5 | 26)
+		_ = binary.Write(buf, binary.BigEndian, uint32(argument))
+	default:
+		buf.WriteByte(majorType<<5 | 27)
+		_ = binary.Write(buf, binary.BigEndian, argument)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"k8s.io/utils/clock"
+)
+
+const (
+	// defaultConnectionPoolSize is the most idle connections which a ConnectionPool keeps open.
+	defaultConnectionPoolSize = 10
+	// defaultConnectionPoolIdleTimeout is how long an idle connection is kept open. Many LDAP servers
+	// close idle connections after a few minutes, so this should be shorter than that.
+	defaultConnectionPoolIdleTimeout = 2 * time.Minute
+	// defaultConnectionPoolLivenessCheckAfter is how long a connection may be idle before it is checked with a
+	// cheap search before it is reused, since the server or a load balancer may drop idle connections without
+	// the client noticing.
+	defaultConnectionPoolLivenessCheckAfter = 15 * time.Second
+	// livenessCheckTimeLimitSeconds is the time limit of the search which checks that a connection is alive.
+	livenessCheckTimeLimitSeconds = 5
+)
+
+// ConnectionPool keeps a bounded number of idle connections which are already bound as the bind user, so that
+// user searches can reuse them instead of dialing and binding again. A ConnectionPool should be used by the
+// Providers of a single upstream. It is intentionally not used for binding as end users, since a connection which
+// was bound as an end user can no longer be used to search as the bind user. It is safe for concurrent use.
+type ConnectionPool struct {
+	size               int
+	idleTimeout        time.Duration
+	livenessCheckAfter time.Duration
+	clock              clock.PassiveClock
+
+	lock   sync.Mutex
+	idle   []*idleConn
+	closed bool
+}
+
+type idleConn struct {
+	conn Conn
+	// key identifies the settings which were used to dial and bind the connection.
+	key     string
+	idledAt time.Time
+}
+
+// NewConnectionPool creates an empty ConnectionPool.
+func NewConnectionPool() *ConnectionPool {
+	return newConnectionPool(defaultConnectionPoolSize, defaultConnectionPoolIdleTimeout,
+		defaultConnectionPoolLivenessCheckAfter, clock.RealClock{})
+}
+
+func newConnectionPool(size int, idleTimeout, livenessCheckAfter time.Duration, c clock.PassiveClock) *ConnectionPool {
+	return &ConnectionPool{size: size, idleTimeout: idleTimeout, livenessCheckAfter: livenessCheckAfter, clock: c}
+}
+
+// get returns the most recently used idle connection which was made using the settings identified by key, or nil
+// when there is none. Any idle connection which was made using other settings, which has been idle for too long,
+// or which is no longer alive is closed instead. Connections which have been idle for a while are checked with a
+// search of the root DSE before they are returned.
+func (cp *ConnectionPool) get(key string) Conn {
+	for {
+		idle := cp.takeIdle(key)
+		if idle == nil {
+			return nil
+		}
+
+		// Check outside the lock, since it talks to the server.
+		if cp.clock.Since(idle.idledAt) < cp.livenessCheckAfter || isAlive(idle.conn) {
+			return idle.conn
+		}
+		closeAndLogError(idle.conn, "removing dead connection from pool")
+	}
+}
+
+// takeIdle removes the most recently used idle connection which was made using the settings identified by key from
+// the pool and returns it, or returns nil when there is none. Other connections which cannot be used are closed.
+func (cp *ConnectionPool) takeIdle(key string) *idleConn {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	now := cp.clock.Now()
+	for len(cp.idle) > 0 {
+		last := cp.idle[len(cp.idle)-1]
+		cp.idle = cp.idle[:len(cp.idle)-1]
+
+		if last.key != key || now.Sub(last.idledAt) >= cp.idleTimeout || last.conn.IsClosing() {
+			closeAndLogError(last.conn, "removing connection from pool")
+			continue
+		}
+		return last
+	}
+	return nil
+}
+
+// isAlive reads the root DSE, which every LDAP server allows and which is cheap, to check that the connection
+// still works.
+func isAlive(conn Conn) bool {
+	_, err := conn.Search(ldap.NewSearchRequest(
+		"",
+		ldap.ScopeBaseObject,
+		ldap.NeverDerefAliases,
+		0,
+		livenessCheckTimeLimitSeconds,
+		false,
+		"(objectClass=*)",
+		[]string{"supportedLDAPVersion"},
+		nil,
+	))
+	return err == nil
+}
+
+// put returns a connection to the pool. When the connection is no longer alive, or when the pool is already
+// full or closed, then the connection is closed instead.
+func (cp *ConnectionPool) put(key string, conn Conn) {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	if cp.closed || len(cp.idle) >= cp.size || conn.IsClosing() {
+		closeAndLogError(conn, "returning connection to pool")
+		return
+	}
+	cp.idle = append(cp.idle, &idleConn{conn: conn, key: key, idledAt: cp.clock.Now()})
+}
+
+// Close closes all idle connections. Connections which are returned to the pool afterwards are also closed.
+func (cp *ConnectionPool) Close() {
+	cp.lock.Lock()
+	defer cp.lock.Unlock()
+
+	cp.closed = true
+	for _, idle := range cp.idle {
+		closeAndLogError(idle.conn, "closing connection pool")
+	}
+	cp.idle = nil
+}
+
+// connectionPoolKey identifies the settings which are used to dial and bind the pooled connections, so that
+// connections made using old settings are not reused after the settings of the upstream change.
+func (p *Provider) connectionPoolKey() string {
+	h := sha256.New()
+	for _, s := range append([]string{
+		string(p.c.ConnectionProtocol), p.c.BindUsername, p.c.BindPassword, string(p.c.CABundle),
+		string(p.c.BindClientCertificate), string(p.c.BindClientKey),
+	}, p.allHosts()...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
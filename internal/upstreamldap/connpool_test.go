@@ -0,0 +1,152 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/mocks/mockldapconn"
+)
+
+func TestConnectionPool(t *testing.T) {
+	const (
+		idleTimeout        = time.Minute
+		livenessCheckAfter = 10 * time.Second
+	)
+
+	newConn := func(ctrl *gomock.Controller) *mockldapconn.MockConn {
+		conn := mockldapconn.NewMockConn(ctrl)
+		conn.EXPECT().IsClosing().Return(false).AnyTimes()
+		return conn
+	}
+
+	t.Run("connections are reused most recently used first", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, clocktesting.NewFakePassiveClock(time.Now()))
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+
+		require.Nil(t, pool.get("key"))
+		pool.put("key", conn1)
+		pool.put("key", conn2)
+		require.Same(t, conn2, pool.get("key"))
+		require.Same(t, conn1, pool.get("key"))
+		require.Nil(t, pool.get("key"))
+	})
+
+	t.Run("connections beyond the size of the pool are closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		pool := newConnectionPool(1, idleTimeout, livenessCheckAfter, clocktesting.NewFakePassiveClock(time.Now()))
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+		conn2.EXPECT().Close().Times(1)
+
+		pool.put("key", conn1)
+		pool.put("key", conn2)
+		require.Same(t, conn1, pool.get("key"))
+	})
+
+	t.Run("connections which were made with other settings are closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, clocktesting.NewFakePassiveClock(time.Now()))
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+		conn2.EXPECT().Close().Times(1)
+
+		pool.put("key", conn1)
+		pool.put("old-key", conn2)
+		require.Same(t, conn1, pool.get("key"))
+	})
+
+	t.Run("connections which were idle for too long are closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, fakeClock)
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+		conn1.EXPECT().Close().Times(1)
+
+		pool.put("key", conn1)
+		fakeClock.SetTime(fakeClock.Now().Add(idleTimeout))
+		pool.put("key", conn2)
+		require.Same(t, conn2, pool.get("key"))
+		require.Nil(t, pool.get("key"))
+	})
+
+	t.Run("connections which were idle for a while are checked before they are reused", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, fakeClock)
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+		conn1.EXPECT().Search(gomock.Any()).Return(&ldap.SearchResult{}, nil).Times(1)
+		conn2.EXPECT().Search(gomock.Any()).Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("some network error"))).Times(1)
+		conn2.EXPECT().Close().Times(1)
+
+		pool.put("key", conn1)
+		pool.put("key", conn2)
+		fakeClock.SetTime(fakeClock.Now().Add(livenessCheckAfter))
+		require.Same(t, conn1, pool.get("key"))
+		require.Nil(t, pool.get("key"))
+	})
+
+	t.Run("connections which are no longer alive are closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, clocktesting.NewFakePassiveClock(time.Now()))
+
+		closedBeforePut := mockldapconn.NewMockConn(ctrl)
+		closedBeforePut.EXPECT().IsClosing().Return(true).Times(1)
+		closedBeforePut.EXPECT().Close().Times(1)
+		pool.put("key", closedBeforePut)
+
+		closedAfterPut := mockldapconn.NewMockConn(ctrl)
+		gomock.InOrder(
+			closedAfterPut.EXPECT().IsClosing().Return(false),
+			closedAfterPut.EXPECT().IsClosing().Return(true),
+		)
+		closedAfterPut.EXPECT().Close().Times(1)
+		pool.put("key", closedAfterPut)
+
+		require.Nil(t, pool.get("key"))
+	})
+
+	t.Run("closing the pool closes the idle connections and any connections which are returned later", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		pool := newConnectionPool(2, idleTimeout, livenessCheckAfter, clocktesting.NewFakePassiveClock(time.Now()))
+		conn1, conn2 := newConn(ctrl), newConn(ctrl)
+		conn1.EXPECT().Close().Times(1)
+		conn2.EXPECT().Close().Times(1)
+
+		pool.put("key", conn1)
+		pool.Close()
+		require.Nil(t, pool.get("key"))
+		pool.put("key", conn2)
+	})
+}
+
+func TestConnectionPoolKey(t *testing.T) {
+	config := ProviderConfig{
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+	}
+	key := New(config).connectionPoolKey()
+	require.Equal(t, key, New(config).connectionPoolKey())
+
+	for _, edit := range []func(c *ProviderConfig){
+		func(c *ProviderConfig) { c.Host = "other.example.com" },
+		func(c *ProviderConfig) { c.AdditionalHosts = []string{"other.example.com"} },
+		func(c *ProviderConfig) { c.ConnectionProtocol = StartTLS },
+		func(c *ProviderConfig) { c.BindUsername = "other-username" },
+		func(c *ProviderConfig) { c.BindPassword = "other-password" },
+		func(c *ProviderConfig) { c.CABundle = []byte("other-ca-bundle") },
+	} {
+		edited := config
+		edit(&edited)
+		require.NotEqual(t, key, New(edited).connectionPoolKey())
+	}
+}
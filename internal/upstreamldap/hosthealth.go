@@ -0,0 +1,161 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// hostInitialBackoff is how long a host is tried last after its first failure.
+	hostInitialBackoff = 10 * time.Second
+	// hostMaxBackoff is the longest that a host which keeps failing is tried last.
+	hostMaxBackoff = 5 * time.Minute
+)
+
+// HostHealth remembers which LDAP hosts recently could not be reached, so that dialing can try the other hosts
+// first. Each host which fails is backed off exponentially until it succeeds again. A single HostHealth can be
+// shared by many Providers, since it is keyed by host, and it is safe for concurrent use.
+type HostHealth struct {
+	clock clock.PassiveClock
+
+	lock  sync.Mutex
+	hosts map[string]*hostHealthState
+}
+
+type hostHealthState struct {
+	consecutiveFailures int
+	backoffUntil        time.Time
+}
+
+// NewHostHealth creates an empty HostHealth, in which every host is assumed to be healthy until it fails.
+func NewHostHealth() *HostHealth {
+	return newHostHealth(clock.RealClock{})
+}
+
+func newHostHealth(c clock.PassiveClock) *HostHealth {
+	return &HostHealth{clock: c, hosts: map[string]*hostHealthState{}}
+}
+
+// IsReachable returns true when the most recent attempt to connect to the host was successful.
+// When there was no attempt yet, then the host is not known to be reachable.
+func (h *HostHealth) IsReachable(host string) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	state, ok := h.hosts[host]
+	return ok && state.consecutiveFailures == 0
+}
+
+func (h *HostHealth) recordSuccess(host string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.hosts[host] = &hostHealthState{}
+}
+
+func (h *HostHealth) recordFailure(host string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	state, ok := h.hosts[host]
+	if !ok {
+		state = &hostHealthState{}
+		h.hosts[host] = state
+	}
+	state.consecutiveFailures++
+
+	backoff := hostMaxBackoff
+	// Avoid overflowing the duration when the host has been failing for a very long time.
+	if state.consecutiveFailures < 16 {
+		backoff = min(hostInitialBackoff<<(state.consecutiveFailures-1), hostMaxBackoff)
+	}
+	state.backoffUntil = h.clock.Now().Add(backoff)
+}
+
+// order returns the hosts in the order in which they should be dialed. Hosts which are not backed off keep their
+// original order, followed by the backed off hosts, ordered by which will be done backing off soonest.
+func (h *HostHealth) order(hosts []string) []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := h.clock.Now()
+	available := make([]string, 0, len(hosts))
+	backedOff := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if state, ok := h.hosts[host]; ok && now.Before(state.backoffUntil) {
+			backedOff = append(backedOff, host)
+			continue
+		}
+		available = append(available, host)
+	}
+
+	sort.SliceStable(backedOff, func(i, j int) bool {
+		return h.hosts[backedOff[i]].backoffUntil.Before(h.hosts[backedOff[j]].backoffUntil)
+	})
+
+	return append(available, backedOff...)
+}
+
+// SRVWeight is the priority and weight of a host from its DNS SRV record.
+type SRVWeight struct {
+	Priority uint16
+	Weight   uint16
+}
+
+// orderBySRVWeight returns the hosts with each run of consecutive hosts which have a weight and the same priority
+// shuffled by weight, using the selection algorithm of RFC 2782. The other hosts keep their positions.
+func orderBySRVWeight(hosts []string, weights map[string]SRVWeight) []string {
+	if len(weights) == 0 {
+		return hosts
+	}
+
+	ordered := append([]string{}, hosts...)
+	for start := 0; start < len(ordered); {
+		startWeight, ok := weights[ordered[start]]
+		if !ok {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(ordered) {
+			if w, ok := weights[ordered[end]]; !ok || w.Priority != startWeight.Priority {
+				break
+			}
+			end++
+		}
+		shuffleByWeight(ordered[start:end], weights)
+		start = end
+	}
+	return ordered
+}
+
+// shuffleByWeight works like the shuffling of SRV records in the net package, which chooses each next host
+// randomly with a probability proportional to its weight.
+func shuffleByWeight(hosts []string, weights map[string]SRVWeight) {
+	sum := 0
+	for _, host := range hosts {
+		sum += int(weights[host].Weight)
+	}
+	for sum > 0 && len(hosts) > 1 {
+		s := 0
+		n := rand.Intn(sum) //nolint:gosec // this is for load balancing, not for security
+		for i := range hosts {
+			s += int(weights[hosts[i]].Weight)
+			if s > n {
+				if i > 0 {
+					hosts[0], hosts[i] = hosts[i], hosts[0]
+				}
+				break
+			}
+		}
+		sum -= int(weights[hosts[0]].Weight)
+		hosts = hosts[1:]
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestHostHealth(t *testing.T) {
+	fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+	h := newHostHealth(fakeClock)
+	hosts := []string{"host1", "host2", "host3"}
+
+	// Unknown hosts keep their order.
+	require.Equal(t, hosts, h.order(hosts))
+	require.False(t, h.IsReachable("host1"))
+
+	h.recordSuccess("host1")
+	require.True(t, h.IsReachable("host1"))
+	require.Equal(t, hosts, h.order(hosts))
+
+	// Failed hosts are tried last, ordered by which is done backing off soonest.
+	h.recordFailure("host1")
+	fakeClock.SetTime(fakeClock.Now().Add(time.Second))
+	h.recordFailure("host2")
+	require.False(t, h.IsReachable("host1"))
+	require.Equal(t, []string{"host3", "host1", "host2"}, h.order(hosts))
+
+	// A host which fails again is backed off for longer.
+	h.recordFailure("host1")
+	require.Equal(t, []string{"host3", "host2", "host1"}, h.order(hosts))
+
+	// After the initial backoff, host2 is tried in its original order again, but host1 is still backed off.
+	fakeClock.SetTime(fakeClock.Now().Add(hostInitialBackoff))
+	require.Equal(t, []string{"host2", "host3", "host1"}, h.order(hosts))
+	require.False(t, h.IsReachable("host2"))
+
+	fakeClock.SetTime(fakeClock.Now().Add(hostInitialBackoff))
+	require.Equal(t, hosts, h.order(hosts))
+
+	// A success forgets the previous failures.
+	h.recordSuccess("host1")
+	h.recordFailure("host1")
+	fakeClock.SetTime(fakeClock.Now().Add(hostInitialBackoff))
+	require.Equal(t, hosts, h.order(hosts))
+}
+
+func TestHostHealthMaxBackoff(t *testing.T) {
+	fakeClock := clocktesting.NewFakePassiveClock(time.Now())
+	h := newHostHealth(fakeClock)
+
+	for i := 0; i < 100; i++ {
+		h.recordFailure("host1")
+	}
+	require.Equal(t, fakeClock.Now().Add(hostMaxBackoff), h.hosts["host1"].backoffUntil)
+	require.Equal(t, 100, h.hosts["host1"].consecutiveFailures)
+}
+
+func TestOrderBySRVWeight(t *testing.T) {
+	hosts := []string{"configured", "dc1", "dc2", "dc3", "dc4"}
+
+	// Without weights, the hosts keep their order.
+	require.Equal(t, hosts, orderBySRVWeight(hosts, nil))
+
+	weights := map[string]SRVWeight{
+		"dc1": {Priority: 0, Weight: 1},
+		"dc2": {Priority: 0, Weight: 3},
+		"dc3": {Priority: 1, Weight: 0},
+		"dc4": {Priority: 1, Weight: 10},
+	}
+	firstOfPriority0 := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		ordered := orderBySRVWeight(hosts, weights)
+		// Hosts without a weight keep their position, and the hosts are only shuffled within their priority.
+		require.Equal(t, "configured", ordered[0])
+		require.ElementsMatch(t, []string{"dc1", "dc2"}, ordered[1:3])
+		require.ElementsMatch(t, []string{"dc3", "dc4"}, ordered[3:5])
+		firstOfPriority0[ordered[1]]++
+	}
+	// The given hosts are not modified.
+	require.Equal(t, []string{"configured", "dc1", "dc2", "dc3", "dc4"}, hosts)
+	// Both hosts of priority 0 are chosen first sometimes, and the heavier one is chosen more often.
+	require.Greater(t, firstOfPriority0["dc1"], 0)
+	require.Greater(t, firstOfPriority0["dc2"], firstOfPriority0["dc1"])
+}
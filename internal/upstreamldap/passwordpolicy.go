@@ -0,0 +1,126 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamldap
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"go.pinniped.dev/internal/authenticators"
+)
+
+// passwordExpirationWarningPeriod is how long before their password expires that users start to be warned about it.
+const passwordExpirationWarningPeriod = 14 * 24 * time.Hour
+
+// activeDirectoryBindErrorDataPattern finds the sub-code in the diagnostic message of an Active Directory bind
+// error, e.g. "80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 532, v4563".
+var activeDirectoryBindErrorDataPattern = regexp.MustCompile(`\bdata ([0-9a-fA-F]+)\b`)
+
+// bindAsEndUser binds as the end user, asking the server to include the state of the user's password policy
+// in its response. When the password policy prevented the bind, the returned error wraps one of the password
+// policy errors of the authenticators package. Otherwise, it returns any warnings for the user.
+func bindAsEndUser(conn Conn, userDN string, password string) ([]string, error) {
+	result, err := conn.SimpleBind(&ldap.SimpleBindRequest{
+		Username: userDN,
+		Password: password,
+		Controls: []ldap.Control{ldap.NewControlBeheraPasswordPolicy()},
+	})
+
+	var passwordPolicy *ldap.ControlBeheraPasswordPolicy
+	if result != nil {
+		passwordPolicy, _ = ldap.FindControl(result.Controls, ldap.ControlTypeBeheraPasswordPolicy).(*ldap.ControlBeheraPasswordPolicy)
+	}
+
+	if policyErr := passwordPolicyError(passwordPolicy, err); policyErr != nil {
+		if err == nil {
+			// Some servers allow the bind, but then only allow the user to change their password.
+			return nil, policyErr
+		}
+		return nil, fmt.Errorf("%w: %w", policyErr, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return passwordPolicyWarnings(passwordPolicy), nil
+}
+
+// passwordPolicyError returns the password policy error which describes why the bind failed, or nil when the
+// password policy did not cause the failure. Servers report that an account is locked without checking the password,
+// so a locked account is only reported when the bind succeeded. Otherwise, it would tell anyone who tries to log in
+// whether an account exists and is locked, so it is treated like any other invalid credentials instead.
+func passwordPolicyError(passwordPolicy *ldap.ControlBeheraPasswordPolicy, bindErr error) error {
+	if passwordPolicy != nil {
+		switch passwordPolicy.Error {
+		case ldap.BeheraPasswordExpired:
+			return authenticators.ErrPasswordExpired
+		case ldap.BeheraAccountLocked:
+			if bindErr != nil {
+				return nil
+			}
+			return authenticators.ErrAccountLocked
+		case ldap.BeheraChangeAfterReset:
+			return authenticators.ErrPasswordMustChange
+		}
+	}
+
+	ldapErr := &ldap.Error{}
+	if !errors.As(bindErr, &ldapErr) || ldapErr.ResultCode != ldap.LDAPResultInvalidCredentials || ldapErr.Err == nil {
+		return nil
+	}
+	matches := activeDirectoryBindErrorDataPattern.FindStringSubmatch(ldapErr.Err.Error())
+	if matches == nil {
+		return nil
+	}
+	// See https://learn.microsoft.com/en-us/troubleshoot/windows-server/active-directory/common-active-directory-bind-errors.
+	// Active Directory only returns these sub-codes when the password was correct. It also returns sub-code 775 when
+	// the account is locked, but it does so even when the password was wrong, so that sub-code is not used.
+	switch matches[1] {
+	case "532":
+		return authenticators.ErrPasswordExpired
+	case "773":
+		return authenticators.ErrPasswordMustChange
+	default:
+		return nil
+	}
+}
+
+func isPasswordPolicyError(err error) bool {
+	return errors.Is(err, authenticators.ErrPasswordExpired) ||
+		errors.Is(err, authenticators.ErrPasswordMustChange) ||
+		errors.Is(err, authenticators.ErrAccountLocked)
+}
+
+// passwordPolicyWarnings returns warnings for the user when their password will expire soon, or when they
+// logged in using one of the remaining grace logins of their expired password.
+func passwordPolicyWarnings(passwordPolicy *ldap.ControlBeheraPasswordPolicy) []string {
+	if passwordPolicy == nil {
+		return nil
+	}
+	var warnings []string
+	if passwordPolicy.Expire > 0 && time.Duration(passwordPolicy.Expire)*time.Second <= passwordExpirationWarningPeriod {
+		warnings = append(warnings, fmt.Sprintf("Your password will expire in %s. Please change your password.",
+			describeTimeUntilPasswordExpiration(time.Duration(passwordPolicy.Expire)*time.Second)))
+	}
+	if passwordPolicy.Grace > 0 {
+		warnings = append(warnings, fmt.Sprintf("Your password has expired, and you may only log in %d more time(s) before you must change it.",
+			passwordPolicy.Grace))
+	}
+	return warnings
+}
+
+func describeTimeUntilPasswordExpiration(d time.Duration) string {
+	switch {
+	case d >= 48*time.Hour:
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	case d >= 2*time.Hour:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	default:
+		return "less than 2 hours"
+	}
+}
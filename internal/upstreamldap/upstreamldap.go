@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package upstreamldap implements an abstraction of upstream LDAP IDP interactions.
@@ -6,6 +6,7 @@ package upstreamldap
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -13,6 +14,9 @@ import (
 	"fmt"
 	"net"
 	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
 	"strings"
 	"time"
 
@@ -43,11 +47,17 @@ const (
 type Conn interface {
 	Bind(username, password string) error
 
+	SimpleBind(simpleBindRequest *ldap.SimpleBindRequest) (*ldap.SimpleBindResult, error)
+
+	ExternalBind() error
+
 	Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error)
 
 	SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error)
 
 	Close() error
+
+	IsClosing() bool
 }
 
 // Our Conn type is subset of the ldap.Client interface, which is implemented by ldap.Conn.
@@ -67,6 +77,16 @@ func (f LDAPDialerFunc) Dial(ctx context.Context, addr endpointaddr.HostPort) (C
 	return f(ctx, addr)
 }
 
+// GroupCache remembers the group memberships which were found by group searches, so that they do not need to be
+// searched for again until they expire. Failures to use the cache should be treated as cache misses.
+type GroupCache interface {
+	// Get returns the remembered groups for the key, or false when there are none or when they have expired.
+	Get(ctx context.Context, key string) ([]string, bool)
+
+	// Put remembers the groups for the key until the TTL has passed.
+	Put(ctx context.Context, key string, groups []string, ttl time.Duration)
+}
+
 type LDAPConnectionProtocol string
 
 const (
@@ -85,21 +105,53 @@ type ProviderConfig struct {
 	ResourceUID types.UID
 
 	// Host is the hostname or "hostname:port" of the LDAP server. When the port is not specified,
-	// the default LDAP port will be used.
+	// the default LDAP port will be used. The Host also identifies this provider in downstream subjects,
+	// so it is used by GetURL even when the connection is made to one of the AdditionalHosts.
 	Host string
 
+	// AdditionalHosts are the hostnames or "hostname:port" of other LDAP servers which serve the same directory,
+	// in the order in which they should be tried when the Host cannot be reached. Can be empty.
+	AdditionalHosts []string
+
+	// DiscoveredHostWeights are the DNS SRV priority and weight of those AdditionalHosts which were discovered using
+	// DNS SRV records, keyed by host. The discovered hosts should be listed in a stable order, so that the config does
+	// not change whenever they are discovered again. Each dial then shuffles the discovered hosts which have the same
+	// priority by their weight, to spread the connections over them. Can be nil.
+	DiscoveredHostWeights map[string]SRVWeight
+
+	// HostHealth remembers which hosts recently could not be reached, so that they can be tried last. It is
+	// intentionally a pointer, since it is shared state which should outlive any copy of this config.
+	// When nil, the hosts are always tried in order.
+	HostHealth *HostHealth
+
+	// ConnectionPool keeps idle connections which are bound as the bind user, so that they can be reused to
+	// search for users. It is intentionally a pointer, since it is shared state which should outlive any copy of
+	// this config. When nil, every search uses a new connection.
+	ConnectionPool *ConnectionPool
+
+	// GroupCache remembers the group memberships which were found during refreshes, for GroupSearch.RefreshCacheTTL.
+	// When nil, the group memberships are never remembered.
+	GroupCache GroupCache
+
 	// ConnectionProtocol determines how to establish the connection to the server. Either StartTLS or TLS.
 	ConnectionProtocol LDAPConnectionProtocol
 
 	// PEM-encoded CA cert bundle to trust when connecting to the LDAP server. Can be nil.
 	CABundle []byte
 
-	// BindUsername is the username to use when performing a bind with the upstream LDAP IDP.
+	// BindUsername is the username to use when performing a bind with the upstream LDAP IDP. When
+	// BindClientCertificate is set, it is only used in messages, and should describe the bind account.
 	BindUsername string
 
 	// BindPassword is the password to use when performing a bind with the upstream LDAP IDP.
 	BindPassword string
 
+	// BindClientCertificate and BindClientKey are the PEM-encoded client certificate and private key to present when
+	// connecting to the upstream LDAP IDP. When set, the bind uses the SASL EXTERNAL mechanism, so the upstream LDAP IDP
+	// authenticates the bind account using the client certificate instead of BindUsername and BindPassword.
+	BindClientCertificate []byte
+	BindClientKey         []byte
+
 	// UserSearch contains information about how to search for users in the upstream LDAP IDP.
 	UserSearch UserSearchConfig
 
@@ -136,6 +188,10 @@ type UserSearchConfig struct {
 	// UIDAttribute is the attribute in the LDAP entry from which the user's unique ID should be
 	// retrieved.
 	UIDAttribute string
+
+	// AdditionalAttributes are the names of other attributes in the LDAP entry which should be retrieved
+	// and made available to identity transformation expressions. Empty means to retrieve no other attributes.
+	AdditionalAttributes []string
 }
 
 // GroupSearchConfig contains information about how to search for group membership for users in the upstream LDAP IDP.
@@ -159,6 +215,11 @@ type GroupSearchConfig struct {
 	// (every 5 minutes). This can be done if group search is very slow or resource intensive for the LDAP
 	// server.
 	SkipGroupRefresh bool
+
+	// RefreshCacheTTL is how long the group memberships which were found during a refresh are remembered in the
+	// GroupCache, to be used by later refreshes of the same user instead of searching again. Zero means to search
+	// during every refresh.
+	RefreshCacheTTL time.Duration
 }
 
 type Provider struct {
@@ -189,18 +250,24 @@ func closeAndLogError(conn Conn, doingWhat string) {
 func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes upstreamprovider.RefreshAttributes, idpDisplayName string) ([]string, error) {
 	t := trace.FromContext(ctx).Nest("slow ldap refresh attempt", trace.Field{Key: "providerName", Value: p.GetName()})
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
-	userDN := storedRefreshAttributes.DN
 
-	conn, err := p.dial(ctx)
-	if err != nil {
-		return nil, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
-	}
-	defer closeAndLogError(conn, "refreshing connection")
+	var groups []string
+	err := p.withBindUserConn(ctx, "refreshing connection", func(conn Conn) error {
+		var err error
+		groups, err = p.performRefreshWithConn(ctx, t, conn, storedRefreshAttributes, idpDisplayName)
+		return err
+	})
+	return groups, err
+}
 
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
-	if err != nil {
-		return nil, fmt.Errorf(`error binding as %q before user search: %w`, p.c.BindUsername, err)
-	}
+func (p *Provider) performRefreshWithConn(
+	ctx context.Context,
+	t *trace.Trace,
+	conn Conn,
+	storedRefreshAttributes upstreamprovider.RefreshAttributes,
+	idpDisplayName string,
+) ([]string, error) {
+	userDN := storedRefreshAttributes.DN
 
 	searchResult, err := p.performUserRefreshSearch(conn, userDN)
 	if err != nil {
@@ -259,13 +326,65 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes u
 		}
 	}
 
-	mappedGroupNames, err := p.searchGroupsForUserMembership(conn, userDN, groupSearchUserAttributeForFilterValue)
+	mappedGroupNames, err := p.refreshGroupsForUserMembership(ctx, conn, userDN, groupSearchUserAttributeForFilterValue)
 	if err != nil {
 		return nil, err
 	}
 	return mappedGroupNames, nil
 }
 
+// refreshGroupsForUserMembership is like searchGroupsForUserMembership, except that it uses the group memberships
+// which are remembered in the GroupCache, when there is one.
+func (p *Provider) refreshGroupsForUserMembership(ctx context.Context, conn Conn, userDN string, groupSearchUserAttributeForFilterValue string) ([]string, error) {
+	if p.c.GroupCache == nil || p.c.GroupSearch.RefreshCacheTTL <= 0 {
+		return p.searchGroupsForUserMembership(conn, userDN, groupSearchUserAttributeForFilterValue)
+	}
+
+	key := p.groupCacheKey(userDN, groupSearchUserAttributeForFilterValue)
+	if groups, found := p.c.GroupCache.Get(ctx, key); found {
+		return groups, nil
+	}
+
+	groups, err := p.searchGroupsForUserMembership(conn, userDN, groupSearchUserAttributeForFilterValue)
+	if err != nil {
+		return nil, err
+	}
+	p.c.GroupCache.Put(ctx, key, groups, p.c.GroupSearch.RefreshCacheTTL)
+	return groups, nil
+}
+
+// groupCacheKey identifies the group search of a user, so that the group memberships which are remembered
+// are not used after the group search settings change, or for another user or upstream.
+func (p *Provider) groupCacheKey(userDN string, groupSearchUserAttributeForFilterValue string) string {
+	h := sha256.New()
+	for _, s := range append([]string{
+		string(p.c.ResourceUID),
+		p.c.GroupSearch.Base,
+		p.c.GroupSearch.Filter,
+		p.c.GroupSearch.UserAttributeForFilter,
+		p.c.GroupSearch.GroupNameAttribute,
+		userDN,
+		groupSearchUserAttributeForFilterValue,
+	}, p.groupAttributeParsingOverridesForCacheKey()...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// groupAttributeParsingOverridesForCacheKey describes each of the GroupAttributeParsingOverrides by its attribute
+// name and the name of its function. The cache can be shared by Supervisor pods, so it cannot use the addresses
+// of the functions.
+func (p *Provider) groupAttributeParsingOverridesForCacheKey() []string {
+	overrides := make([]string, 0, len(p.c.GroupAttributeParsingOverrides))
+	for attributeName, overrideFunc := range p.c.GroupAttributeParsingOverrides {
+		funcName := runtime.FuncForPC(reflect.ValueOf(overrideFunc).Pointer()).Name()
+		overrides = append(overrides, fmt.Sprintf("%s=%s", attributeName, funcName))
+	}
+	sort.Strings(overrides)
+	return overrides
+}
+
 func (p *Provider) performUserRefreshSearch(conn Conn, userDN string) (*ldap.SearchResult, error) {
 	search := p.refreshUserSearchRequest(userDN)
 
@@ -277,13 +396,139 @@ func (p *Provider) performUserRefreshSearch(conn Conn, userDN string) (*ldap.Sea
 	return searchResult, nil
 }
 
+// withBindUserConn calls f with a connection which is bound as the bind user, taken from the ConnectionPool when
+// there is an idle one. When f fails because of a network error, e.g. because a pooled connection went stale, then
+// f is retried once with a newly dialed connection. After an error the connection is closed instead of returned to
+// the ConnectionPool, since it might be broken.
+func (p *Provider) withBindUserConn(ctx context.Context, doingWhat string, f func(conn Conn) error) error {
+	conn, err := p.bindUserConn(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	err = f(conn)
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		closeAndLogError(conn, doingWhat)
+		plog.InfoErr("network error on LDAP connection, so retrying once with a new connection", err, "upstreamName", p.GetName())
+		conn, err = p.bindUserConn(ctx, false)
+		if err != nil {
+			return err
+		}
+		err = f(conn)
+	}
+
+	if err != nil {
+		closeAndLogError(conn, doingWhat)
+		return err
+	}
+	p.releaseBindUserConn(conn, doingWhat)
+	return nil
+}
+
+// bindUserConn returns an idle connection from the ConnectionPool which is already bound as the bind user when
+// usePool is true and there is one, or else dials a new connection and binds it as the bind user.
+func (p *Provider) bindUserConn(ctx context.Context, usePool bool) (Conn, error) {
+	if usePool && p.c.ConnectionPool != nil {
+		if conn := p.c.ConnectionPool.get(p.connectionPoolKey()); conn != nil {
+			return conn, nil
+		}
+	}
+
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf(`error dialing %s: %w`, p.hostsForErrorMessage(), err)
+	}
+
+	err = p.bindAsBindUser(conn)
+	if err != nil {
+		closeAndLogError(conn, "binding as bind user")
+		return nil, fmt.Errorf(`error binding as %q before user search: %w`, p.c.BindUsername, err)
+	}
+	return conn, nil
+}
+
+// releaseBindUserConn returns a connection which is still bound as the bind user to the ConnectionPool,
+// or closes it when there is no ConnectionPool.
+func (p *Provider) releaseBindUserConn(conn Conn, doingWhat string) {
+	if p.c.ConnectionPool == nil {
+		closeAndLogError(conn, doingWhat)
+		return
+	}
+	p.c.ConnectionPool.put(p.connectionPoolKey(), conn)
+}
+
+// allHosts returns the Host followed by the AdditionalHosts, without duplicates.
+func (p *Provider) allHosts() []string {
+	hosts := make([]string, 0, len(p.c.AdditionalHosts)+1)
+	seen := sets.New[string]()
+	for _, host := range append([]string{p.c.Host}, p.c.AdditionalHosts...) {
+		if !seen.Has(host) {
+			seen.Insert(host)
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+func (p *Provider) hostsForErrorMessage() string {
+	hosts := p.allHosts()
+	if len(hosts) == 1 {
+		return fmt.Sprintf("host %q", hosts[0])
+	}
+	return fmt.Sprintf("hosts %q", hosts)
+}
+
+// dial connects to the first host which can be reached. The hosts which recently could not be reached are tried last.
 func (p *Provider) dial(ctx context.Context) (Conn, error) {
-	tlsAddr, err := endpointaddr.Parse(p.c.Host, defaultLDAPSPort)
+	hosts := orderBySRVWeight(p.allHosts(), p.c.DiscoveredHostWeights)
+	if p.c.HostHealth != nil {
+		hosts = p.c.HostHealth.order(hosts)
+	}
+
+	if len(hosts) == 1 {
+		return p.dialHostAndRecordHealth(ctx, hosts[0])
+	}
+
+	var errs []error
+	for _, host := range hosts {
+		conn, err := p.dialHostAndRecordHealth(ctx, host)
+		if err == nil {
+			return conn, nil
+		}
+		plog.InfoErr("error dialing LDAP host, so trying the next host", err, "upstreamName", p.GetName(), "host", host)
+		errs = append(errs, fmt.Errorf("%s: %w", host, err))
+		if ctx.Err() != nil {
+			// No point in trying more hosts after the context is done.
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (p *Provider) dialHostAndRecordHealth(ctx context.Context, host string) (Conn, error) {
+	conn, err := p.dialHost(ctx, host)
+	p.recordHostHealth(host, err)
+	return conn, err
+}
+
+func (p *Provider) recordHostHealth(host string, err error) {
+	if p.c.HostHealth == nil {
+		return
+	}
+	if err != nil {
+		p.c.HostHealth.recordFailure(host)
+		return
+	}
+	p.c.HostHealth.recordSuccess(host)
+}
+
+func (p *Provider) dialHost(ctx context.Context, host string) (Conn, error) {
+	tlsAddr, err := endpointaddr.Parse(host, defaultLDAPSPort)
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
 
-	startTLSAddr, err := endpointaddr.Parse(p.c.Host, defaultLDAPPort)
+	startTLSAddr, err := endpointaddr.Parse(host, defaultLDAPPort)
 	if err != nil {
 		return nil, ldap.NewError(ldap.ErrorNetwork, err)
 	}
@@ -369,7 +614,15 @@ func (p *Provider) tlsConfig() (*tls.Config, error) {
 			return nil, fmt.Errorf("could not parse CA bundle")
 		}
 	}
-	return ptls.DefaultLDAP(rootCAs), nil
+	tlsConfig := ptls.DefaultLDAP(rootCAs)
+	if len(p.c.BindClientCertificate) > 0 {
+		clientCert, err := tls.X509KeyPair(p.c.BindClientCertificate, p.c.BindClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse bind client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+	return tlsConfig, nil
 }
 
 // GetName returns a name for this upstream provider.
@@ -402,11 +655,11 @@ func (p *Provider) TestConnection(ctx context.Context) error {
 
 	conn, err := p.dial(ctx)
 	if err != nil {
-		return fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
+		return fmt.Errorf(`error dialing %s: %w`, p.hostsForErrorMessage(), err)
 	}
 	defer closeAndLogError(conn, "testing connection")
 
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
+	err = p.bindAsBindUser(conn)
 	if err != nil {
 		return fmt.Errorf(`error binding as %q: %w`, p.c.BindUsername, err)
 	}
@@ -414,27 +667,77 @@ func (p *Provider) TestConnection(ctx context.Context) error {
 	return nil
 }
 
+// ProbeHosts dials and binds to each host, recording the results in the HostHealth when there is one. Every host is
+// probed each time, so that hosts which stopped working since they were last used are noticed. It returns the hosts
+// which are reachable and the hosts which are not, each in their configured order.
+func (p *Provider) ProbeHosts(ctx context.Context) ([]string, []string) {
+	var reachable, unreachable []string
+	for _, host := range p.allHosts() {
+		if err := p.probeHost(ctx, host); err != nil {
+			plog.InfoErr("error probing LDAP host", err, "upstreamName", p.GetName(), "host", host)
+			unreachable = append(unreachable, host)
+			continue
+		}
+		reachable = append(reachable, host)
+	}
+	return reachable, unreachable
+}
+
+func (p *Provider) probeHost(ctx context.Context, host string) error {
+	err := p.dialAndBindHost(ctx, host)
+	p.recordHostHealth(host, err)
+	return err
+}
+
+func (p *Provider) dialAndBindHost(ctx context.Context, host string) error {
+	conn, err := p.dialHost(ctx, host)
+	if err != nil {
+		return err
+	}
+	defer closeAndLogError(conn, "probing host")
+
+	return p.bindAsBindUser(conn)
+}
+
+// bindAsBindUser authenticates the connection as the bind account, using the client certificate of the
+// connection when there is one, or else using the bind username and password.
+func (p *Provider) bindAsBindUser(conn Conn) error {
+	if len(p.c.BindClientCertificate) > 0 {
+		return conn.ExternalBind()
+	}
+	return conn.Bind(p.c.BindUsername, p.c.BindPassword)
+}
+
 // DryRunAuthenticateUser provides a method for testing all of the Provider settings in a kind of dry run of
 // authentication for a given end user's username. It runs the same logic as AuthenticateUser except it does
 // not bind as that user, so it does not test their password. It returns the same values that a real call to
 // AuthenticateUser with the correct password would return.
 func (p *Provider) DryRunAuthenticateUser(ctx context.Context, username string) (*authenticators.Response, bool, error) {
-	endUserBindFunc := func(conn Conn, foundUserDN string) error {
+	endUserBindFunc := func(conn Conn, foundUserDN string) ([]string, error) {
 		// Act as if the end user bind always succeeds.
-		return nil
+		return nil, nil
 	}
 	return p.authenticateUserImpl(ctx, username, endUserBindFunc)
 }
 
 // AuthenticateUser authenticates an end user and returns their mapped username, groups, and UID. Implements authenticators.UserAuthenticator.
 func (p *Provider) AuthenticateUser(ctx context.Context, username, password string) (*authenticators.Response, bool, error) {
-	endUserBindFunc := func(conn Conn, foundUserDN string) error {
-		return conn.Bind(foundUserDN, password)
+	endUserBindFunc := func(conn Conn, foundUserDN string) ([]string, error) {
+		if p.c.ConnectionPool == nil {
+			return bindAsEndUser(conn, foundUserDN, password)
+		}
+		// The pooled connection must stay bound as the bind user, so bind as the end user on a separate connection.
+		userConn, err := p.dial(ctx)
+		if err != nil {
+			return nil, fmt.Errorf(`error dialing %s: %w`, p.hostsForErrorMessage(), err)
+		}
+		defer closeAndLogError(userConn, "binding as end user")
+		return bindAsEndUser(userConn, foundUserDN, password)
 	}
 	return p.authenticateUserImpl(ctx, username, endUserBindFunc)
 }
 
-func (p *Provider) authenticateUserImpl(ctx context.Context, username string, bindFunc func(conn Conn, foundUserDN string) error) (*authenticators.Response, bool, error) {
+func (p *Provider) authenticateUserImpl(ctx context.Context, username string, bindFunc func(conn Conn, foundUserDN string) ([]string, error)) (*authenticators.Response, bool, error) {
 	t := trace.FromContext(ctx).Nest("slow ldap authenticate user attempt", trace.Field{Key: "providerName", Value: p.GetName()})
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
 
@@ -450,20 +753,12 @@ func (p *Provider) authenticateUserImpl(ctx context.Context, username string, bi
 		return nil, false, nil
 	}
 
-	conn, err := p.dial(ctx)
-	if err != nil {
-		p.traceAuthFailure(t, err)
-		return nil, false, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
-	}
-	defer closeAndLogError(conn, "authenticating user")
-
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
-	if err != nil {
-		p.traceAuthFailure(t, err)
-		return nil, false, fmt.Errorf(`error binding as %q before user search: %w`, p.c.BindUsername, err)
-	}
-
-	response, err := p.searchAndBindUser(conn, username, bindFunc)
+	var response *authenticators.Response
+	err = p.withBindUserConn(ctx, "authenticating user", func(conn Conn) error {
+		var err error
+		response, err = p.searchAndBindUser(conn, username, bindFunc)
+		return err
+	})
 	if err != nil {
 		p.traceAuthFailure(t, err)
 		return nil, false, err
@@ -534,11 +829,11 @@ func (p *Provider) SearchForDefaultNamingContext(ctx context.Context) (string, e
 	conn, err := p.dial(ctx)
 	if err != nil {
 		p.traceSearchBaseDiscoveryFailure(t, err)
-		return "", fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
+		return "", fmt.Errorf(`error dialing %s: %w`, p.hostsForErrorMessage(), err)
 	}
 	defer closeAndLogError(conn, "searching for default naming context")
 
-	err = conn.Bind(p.c.BindUsername, p.c.BindPassword)
+	err = p.bindAsBindUser(conn)
 	if err != nil {
 		p.traceSearchBaseDiscoveryFailure(t, err)
 		return "", fmt.Errorf(`error binding as %q before querying for defaultNamingContext: %w`, p.c.BindUsername, err)
@@ -560,7 +855,7 @@ func (p *Provider) SearchForDefaultNamingContext(ctx context.Context) (string, e
 	return searchBase, nil
 }
 
-func (p *Provider) searchAndBindUser(conn Conn, username string, bindFunc func(conn Conn, foundUserDN string) error) (*authenticators.Response, error) {
+func (p *Provider) searchAndBindUser(conn Conn, username string, bindFunc func(conn Conn, foundUserDN string) ([]string, error)) (*authenticators.Response, error) {
 	searchResult, err := conn.Search(p.userSearchRequest(username))
 	if err != nil {
 		plog.All(`error searching for user`,
@@ -628,11 +923,26 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, bindFunc func(c
 		mappedRefreshAttributes[k] = mappedVal
 	}
 
+	var mappedAdditionalAttributes map[string][]string
+	if len(p.c.UserSearch.AdditionalAttributes) > 0 {
+		mappedAdditionalAttributes = make(map[string][]string, len(p.c.UserSearch.AdditionalAttributes))
+		for _, attributeName := range p.c.UserSearch.AdditionalAttributes {
+			// Attributes which are not present on the user's entry are left out, so that expressions can
+			// detect their absence.
+			if values := userEntry.GetAttributeValues(attributeName); len(values) > 0 {
+				mappedAdditionalAttributes[attributeName] = values
+			}
+		}
+	}
+
 	// Caution: Note that any other LDAP commands after this bind will be run as this user instead of as the configured BindUsername!
-	err = bindFunc(conn, userEntry.DN)
+	warnings, err := bindFunc(conn, userEntry.DN)
 	if err != nil {
 		plog.DebugErr("error binding for user (if this is not the expected dn for this username, please check the user search configuration)",
 			err, "upstreamName", p.GetName(), "username", username, "dn", userEntry.DN)
+		if isPasswordPolicyError(err) {
+			return nil, fmt.Errorf(`error binding for user %q against DN %q: %w`, username, userEntry.DN, err)
+		}
 		ldapErr := &ldap.Error{}
 		if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials {
 			return nil, nil
@@ -653,6 +963,8 @@ func (p *Provider) searchAndBindUser(conn Conn, username string, bindFunc func(c
 		},
 		DN:                     userEntry.DN,
 		ExtraRefreshAttributes: mappedRefreshAttributes,
+		UpstreamAttributes:     mappedAdditionalAttributes,
+		Warnings:               warnings,
 	}
 
 	return response, nil
@@ -723,7 +1035,7 @@ func (p *Provider) useGroupSearchUserAttributeForFilter() bool {
 }
 
 func (p *Provider) userSearchRequestedAttributes() []string {
-	attributes := make([]string, 0, len(p.c.RefreshAttributeChecks)+2)
+	attributes := make([]string, 0, len(p.c.RefreshAttributeChecks)+len(p.c.UserSearch.AdditionalAttributes)+2)
 	if p.c.UserSearch.UsernameAttribute != distinguishedNameAttributeName {
 		attributes = append(attributes, p.c.UserSearch.UsernameAttribute)
 	}
@@ -736,6 +1048,7 @@ func (p *Provider) userSearchRequestedAttributes() []string {
 	for k := range p.c.RefreshAttributeChecks {
 		attributes = append(attributes, k)
 	}
+	attributes = append(attributes, p.c.UserSearch.AdditionalAttributes...)
 	return attributes
 }
 
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package upstreamldap
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"testing"
 	"time"
 
@@ -66,6 +67,14 @@ var (
 	testGroupSearchFilterInterpolated = fmt.Sprintf(testGroupSearchFilterInterpolationSpec, testUserSearchResultDNValue, testUserSearchResultDNValue)
 )
 
+func expectedEndUserBindRequest(userDN string, password string) *ldap.SimpleBindRequest {
+	return &ldap.SimpleBindRequest{
+		Username: userDN,
+		Password: password,
+		Controls: []ldap.Control{ldap.NewControlBeheraPasswordPolicy()},
+	}
+}
+
 func TestEndUserAuthentication(t *testing.T) {
 	providerConfig := func(editFunc func(p *ProviderConfig)) *ProviderConfig {
 		config := &ProviderConfig{
@@ -201,7 +210,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -220,7 +229,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -239,7 +248,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -260,7 +269,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				r.User = &user.DefaultInfo{
@@ -283,7 +292,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				info := r.User.(*user.DefaultInfo)
@@ -316,7 +325,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				info := r.User.(*user.DefaultInfo)
@@ -349,7 +358,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				info := r.User.(*user.DefaultInfo)
@@ -373,7 +382,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				info := r.User.(*user.DefaultInfo)
@@ -397,7 +406,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				info := r.User.(*user.DefaultInfo)
@@ -438,7 +447,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -459,7 +468,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -480,7 +489,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -499,7 +508,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -520,7 +529,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -550,7 +559,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserDNWithSpecialChars, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				r.DN = testUserDNWithSpecialChars
@@ -583,7 +592,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserDNWithSpecialChars, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserDNWithSpecialChars, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				r.DN = testUserDNWithSpecialChars
@@ -625,7 +634,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: &authenticators.Response{
 				User: &user.DefaultInfo{
@@ -669,7 +678,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
 				r.ExtraRefreshAttributes = map[string]string{"some-attribute-to-check-during-refresh": "c29tZS1hdHRyaWJ1dGUtdmFsdWU"}
@@ -697,6 +706,44 @@ func TestEndUserAuthentication(t *testing.T) {
 			},
 			wantError: testutil.WantExactErrorString("found 0 values for attribute \"some-attribute-to-check-during-refresh\" while searching for user \"some-upstream-username\", but expected 1 result"),
 		},
+		{
+			name:     "requesting additional attributes for identity transformations",
+			username: testUpstreamUsername,
+			password: testUpstreamPassword,
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.UserSearch.AdditionalAttributes = []string{"title", "mail", "some-missing-attribute"}
+			}),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(func(r *ldap.SearchRequest) {
+					r.Attributes = append(r.Attributes, "title", "mail", "some-missing-attribute")
+				})).Return(&ldap.SearchResult{
+					Entries: []*ldap.Entry{
+						{
+							DN: testUserSearchResultDNValue,
+							Attributes: []*ldap.EntryAttribute{
+								ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+								ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+								ldap.NewEntryAttribute("title", []string{"Manager"}),
+								ldap.NewEntryAttribute("mail", []string{"user@example.com", "other@example.com"}),
+							},
+						},
+					},
+				}, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
+			},
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.UpstreamAttributes = map[string][]string{
+					"title": {"Manager"},
+					"mail":  {"user@example.com", "other@example.com"},
+				}
+			}),
+		},
 		{
 			name:     "when the UserAttributeForFilter is set to something other than dn",
 			username: testUpstreamUsername,
@@ -730,7 +777,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -824,7 +871,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -861,7 +908,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -899,7 +946,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
 			},
 			wantAuthResponse: expectedAuthResponse(nil),
 		},
@@ -1314,7 +1361,7 @@ func TestEndUserAuthentication(t *testing.T) {
 				conn.EXPECT().Close().Times(1)
 			},
 			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(errors.New("some bind error")).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Return(nil, errors.New("some bind error")).Times(1)
 			},
 			skipDryRunAuthenticateUser: true,
 			wantError:                  testutil.WantSprintfErrorString(`error binding for user "%s" using provided password against DN "%s": some bind error`, testUpstreamUsername, testUserSearchResultDNValue),
@@ -1338,8 +1385,213 @@ func TestEndUserAuthentication(t *testing.T) {
 					Err:        errors.New("some bind error"),
 					ResultCode: ldap.LDAPResultInvalidCredentials,
 				}
-				conn.EXPECT().Bind(testUserSearchResultDNValue, testUpstreamPassword).Return(err).Times(1)
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Return(nil, err).Times(1)
+			},
+		},
+		{
+			name:           "when binding as the found user returns an Active Directory error because the password expired",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				err := &ldap.Error{
+					Err:        errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 532, v4563"),
+					ResultCode: ldap.LDAPResultInvalidCredentials,
+				}
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Return(nil, err).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantError: testutil.WantSprintfErrorString(
+				`error binding for user "%s" against DN "%s": password expired: LDAP Result Code 49 "Invalid Credentials": 80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 532, v4563`,
+				testUpstreamUsername, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when binding as the found user returns an Active Directory error because the password must be changed",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				err := &ldap.Error{
+					Err:        errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 773, v4563"),
+					ResultCode: ldap.LDAPResultInvalidCredentials,
+				}
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Return(nil, err).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantError: testutil.WantSprintfErrorString(
+				`error binding for user "%s" against DN "%s": password must be changed: LDAP Result Code 49 "Invalid Credentials": 80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 773, v4563`,
+				testUpstreamUsername, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when binding as the found user returns an Active Directory error because the account is locked, which does not prove that the password was correct",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				err := &ldap.Error{
+					Err:        errors.New("80090308: LdapErr: DSID-0C09044E, comment: AcceptSecurityContext error, data 775, v4563"),
+					ResultCode: ldap.LDAPResultInvalidCredentials,
+				}
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Return(nil, err).Times(1)
 			},
+			skipDryRunAuthenticateUser: true,
+			wantUnauthenticated:        true,
+		},
+		{
+			name:           "when binding as the found user fails and returns a password policy control because the account is locked, which does not prove that the password was correct",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Error = ldap.BeheraAccountLocked
+				err := ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error"))
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, err).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantUnauthenticated:        true,
+		},
+		{
+			name:           "when binding as the found user succeeds but returns a password policy control because the account is locked",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Error = ldap.BeheraAccountLocked
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, nil).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantError: testutil.WantSprintfErrorString(
+				`error binding for user "%s" against DN "%s": account locked`,
+				testUpstreamUsername, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when binding as the found user succeeds but returns a password policy control because the password must be changed after a reset",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Error = ldap.BeheraChangeAfterReset
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, nil).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantError: testutil.WantSprintfErrorString(
+				`error binding for user "%s" against DN "%s": password must be changed`,
+				testUpstreamUsername, testUserSearchResultDNValue),
+		},
+		{
+			name:           "when binding as the found user succeeds and returns a password policy control because the password will expire soon",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Expire = 3 * 24 * 60 * 60
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, nil).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.Warnings = []string{"Your password will expire in 3 days. Please change your password."}
+			}),
+		},
+		{
+			name:           "when binding as the found user succeeds using a grace login of an expired password",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Grace = 2
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, nil).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantAuthResponse: expectedAuthResponse(func(r *authenticators.Response) {
+				r.Warnings = []string{"Your password has expired, and you may only log in 2 more time(s) before you must change it."}
+			}),
+		},
+		{
+			name:           "when binding as the found user succeeds and returns a password policy control for a password which will not expire soon",
+			username:       testUpstreamUsername,
+			password:       testUpstreamPassword,
+			providerConfig: providerConfig(nil),
+			searchMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+				conn.EXPECT().Search(expectedUserSearch(nil)).Return(exampleUserSearchResult, nil).Times(1)
+				conn.EXPECT().SearchWithPaging(expectedGroupSearch(nil), expectedGroupSearchPageSize).
+					Return(exampleGroupSearchResult, nil).Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+			bindEndUserMocks: func(conn *mockldapconn.MockConn) {
+				passwordPolicy := ldap.NewControlBeheraPasswordPolicy()
+				passwordPolicy.Expire = 30 * 24 * 60 * 60
+				conn.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).
+					Return(&ldap.SimpleBindResult{Controls: []ldap.Control{passwordPolicy}}, nil).Times(1)
+			},
+			skipDryRunAuthenticateUser: true,
+			wantAuthResponse:           expectedAuthResponse(nil),
 		},
 		{
 			name:                "when no username is specified",
@@ -2269,6 +2521,18 @@ func TestTestConnection(t *testing.T) {
 			},
 			wantError: testutil.WantSprintfErrorString(`error binding as "%s": some bind error`, testBindUsername),
 		},
+		{
+			name: "when a bind client certificate is configured, binds using SASL EXTERNAL instead of the password",
+			providerConfig: providerConfig(func(p *ProviderConfig) {
+				p.BindPassword = ""
+				p.BindClientCertificate = []byte("some-client-cert")
+				p.BindClientKey = []byte("some-client-key")
+			}),
+			setupMocks: func(conn *mockldapconn.MockConn) {
+				conn.EXPECT().ExternalBind().Times(1)
+				conn.EXPECT().Close().Times(1)
+			},
+		},
 		{
 			name: "when the config is invalid",
 			providerConfig: providerConfig(func(p *ProviderConfig) {
@@ -2317,6 +2581,441 @@ func TestTestConnection(t *testing.T) {
 	}
 }
 
+func TestDialingMultipleHosts(t *testing.T) {
+	const (
+		testHost2 = "ldap2.example.com:8443"
+		testHost3 = "ldap3.example.com:8443"
+	)
+
+	tests := []struct {
+		name               string
+		additionalHosts    []string
+		withHostHealth     bool
+		dialErrors         map[string]error
+		numberOfDials      int
+		wantDialedHosts    []string
+		wantError          testutil.RequireErrorStringFunc
+		wantReachableHosts []string
+	}{
+		{
+			name:            "when the first host can be dialed, it does not dial the other hosts",
+			additionalHosts: []string{testHost2, testHost3},
+			numberOfDials:   1,
+			wantDialedHosts: []string{testHost},
+		},
+		{
+			name:            "when the first host cannot be dialed, it dials the next host",
+			additionalHosts: []string{testHost2, testHost3},
+			dialErrors:      map[string]error{testHost: errors.New("some dial error")},
+			numberOfDials:   1,
+			wantDialedHosts: []string{testHost, testHost2},
+		},
+		{
+			name:            "duplicate hosts are only dialed once",
+			additionalHosts: []string{testHost, testHost2, testHost2},
+			dialErrors:      map[string]error{testHost: errors.New("some dial error"), testHost2: errors.New("some other dial error")},
+			numberOfDials:   1,
+			wantDialedHosts: []string{testHost, testHost2},
+			wantError: testutil.WantSprintfErrorString("error dialing hosts [%q %q]: %s: some dial error\n%s: some other dial error",
+				testHost, testHost2, testHost, testHost2),
+		},
+		{
+			name:            "without host health, every dial tries the hosts in their configured order",
+			additionalHosts: []string{testHost2, testHost3},
+			dialErrors:      map[string]error{testHost: errors.New("some dial error")},
+			numberOfDials:   2,
+			wantDialedHosts: []string{testHost, testHost2, testHost, testHost2},
+		},
+		{
+			name:               "with host health, the hosts which recently failed are dialed last",
+			additionalHosts:    []string{testHost2, testHost3},
+			withHostHealth:     true,
+			dialErrors:         map[string]error{testHost: errors.New("some dial error")},
+			numberOfDials:      2,
+			wantDialedHosts:    []string{testHost, testHost2, testHost2},
+			wantReachableHosts: []string{testHost2},
+		},
+	}
+
+	for _, test := range tests {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			conn := mockldapconn.NewMockConn(ctrl)
+			if tt.wantError == nil {
+				conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(tt.numberOfDials)
+				conn.EXPECT().Close().Times(tt.numberOfDials)
+			}
+
+			var dialedHosts []string
+			config := ProviderConfig{
+				Name:               "some-provider-name",
+				Host:               testHost,
+				AdditionalHosts:    tt.additionalHosts,
+				ConnectionProtocol: TLS,
+				BindUsername:       testBindUsername,
+				BindPassword:       testBindPassword,
+				Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+					dialedHosts = append(dialedHosts, addr.Endpoint())
+					if err := tt.dialErrors[addr.Endpoint()]; err != nil {
+						return nil, err
+					}
+					return conn, nil
+				}),
+			}
+			if tt.withHostHealth {
+				config.HostHealth = NewHostHealth()
+			}
+			provider := New(config)
+
+			for i := 0; i < tt.numberOfDials; i++ {
+				err := provider.TestConnection(context.Background())
+				if tt.wantError != nil {
+					testutil.RequireErrorStringFromErr(t, err, tt.wantError)
+				} else {
+					require.NoError(t, err)
+				}
+			}
+
+			require.Equal(t, tt.wantDialedHosts, dialedHosts)
+			if tt.withHostHealth {
+				for _, host := range provider.allHosts() {
+					require.Equal(t, slices.Contains(tt.wantReachableHosts, host), config.HostHealth.IsReachable(host), host)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeHosts(t *testing.T) {
+	const (
+		testHost2 = "ldap2.example.com:8443"
+		testHost3 = "ldap3.example.com:8443"
+	)
+
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	conn := mockldapconn.NewMockConn(ctrl)
+	badBindConn := mockldapconn.NewMockConn(ctrl)
+
+	dialErrors := map[string]error{testHost2: errors.New("some dial error")}
+	var dialedHosts []string
+	hostHealth := NewHostHealth()
+	provider := New(ProviderConfig{
+		Name:               "some-provider-name",
+		Host:               testHost,
+		AdditionalHosts:    []string{testHost2, testHost3},
+		HostHealth:         hostHealth,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			dialedHosts = append(dialedHosts, addr.Endpoint())
+			if err := dialErrors[addr.Endpoint()]; err != nil {
+				return nil, err
+			}
+			if addr.Endpoint() == testHost3 {
+				return badBindConn, nil
+			}
+			return conn, nil
+		}),
+	})
+
+	// The first probe tries every host, and a host which cannot be bound to counts as unreachable.
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	conn.EXPECT().Close().Times(1)
+	badBindConn.EXPECT().Bind(testBindUsername, testBindPassword).Return(errors.New("some bind error")).Times(1)
+	badBindConn.EXPECT().Close().Times(1)
+	reachable, unreachable := provider.ProbeHosts(context.Background())
+	require.Equal(t, []string{testHost}, reachable)
+	require.Equal(t, []string{testHost2, testHost3}, unreachable)
+	require.Equal(t, []string{testHost, testHost2, testHost3}, dialedHosts)
+	require.True(t, hostHealth.IsReachable(testHost))
+	require.False(t, hostHealth.IsReachable(testHost2))
+	require.False(t, hostHealth.IsReachable(testHost3))
+
+	// The next probe tries every host again, including the hosts which were reachable.
+	dialedHosts = nil
+	delete(dialErrors, testHost2)
+	dialErrors[testHost] = errors.New("some dial error")
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	conn.EXPECT().Close().Times(1)
+	badBindConn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	badBindConn.EXPECT().Close().Times(1)
+	reachable, unreachable = provider.ProbeHosts(context.Background())
+	require.Equal(t, []string{testHost2, testHost3}, reachable)
+	require.Equal(t, []string{testHost}, unreachable)
+	require.Equal(t, []string{testHost, testHost2, testHost3}, dialedHosts)
+	require.False(t, hostHealth.IsReachable(testHost))
+	require.True(t, hostHealth.IsReachable(testHost2))
+	require.True(t, hostHealth.IsReachable(testHost3))
+}
+
+func TestAuthenticationWithConnectionPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+
+	// The connection which is bound as the bind user is only dialed and bound once, and then reused from the pool.
+	bindUserConn := mockldapconn.NewMockConn(ctrl)
+	bindUserConn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	bindUserConn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(3)
+	bindUserConn.EXPECT().IsClosing().Return(false).Times(5)
+	bindUserConn.EXPECT().Close().Times(1)
+
+	// Each end user bind happens on a new connection, which is never pooled.
+	endUserConn1 := mockldapconn.NewMockConn(ctrl)
+	endUserConn1.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, testUpstreamPassword)).Times(1)
+	endUserConn1.EXPECT().Close().Times(1)
+	endUserConn2 := mockldapconn.NewMockConn(ctrl)
+	endUserConn2.EXPECT().SimpleBind(expectedEndUserBindRequest(testUserSearchResultDNValue, "wrong-password")).
+		Return(nil, ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("some bind error"))).Times(1)
+	endUserConn2.EXPECT().Close().Times(1)
+
+	dialedConns := []Conn{bindUserConn, endUserConn1, endUserConn2}
+	pool := NewConnectionPool()
+	provider := New(ProviderConfig{
+		Name:               "some-provider-name",
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		UserSearch: UserSearchConfig{
+			Base:              testUserSearchBase,
+			Filter:            testUserSearchFilter,
+			UsernameAttribute: testUserSearchUsernameAttribute,
+			UIDAttribute:      testUserSearchUIDAttribute,
+		},
+		ConnectionPool: pool,
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			require.NotEmpty(t, dialedConns, "dialed too many times")
+			conn := dialedConns[0]
+			dialedConns = dialedConns[1:]
+			return conn, nil
+		}),
+	})
+
+	response, authenticated, err := provider.AuthenticateUser(context.Background(), testUpstreamUsername, testUpstreamPassword)
+	require.NoError(t, err)
+	require.True(t, authenticated)
+	require.Equal(t, testUserSearchResultUsernameAttributeValue, response.User.GetName())
+
+	response, authenticated, err = provider.AuthenticateUser(context.Background(), testUpstreamUsername, "wrong-password")
+	require.NoError(t, err)
+	require.False(t, authenticated)
+	require.Nil(t, response)
+
+	// A dry run does not bind as the end user, so it does not dial.
+	response, authenticated, err = provider.DryRunAuthenticateUser(context.Background(), testUpstreamUsername)
+	require.NoError(t, err)
+	require.True(t, authenticated)
+	require.Equal(t, testUserSearchResultUsernameAttributeValue, response.User.GetName())
+
+	require.Empty(t, dialedConns)
+	pool.Close()
+}
+
+func TestConnectionPoolErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					ldap.NewEntryAttribute(testUserSearchUIDAttribute, []string{testUserSearchResultUIDAttributeValue}),
+				},
+			},
+		},
+	}
+
+	// A pooled connection which went stale is closed, and the search is retried once on a new connection.
+	staleConn := mockldapconn.NewMockConn(ctrl)
+	staleConn.EXPECT().IsClosing().Return(false).Times(2)
+	staleConn.EXPECT().Search(gomock.Any()).Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("some network error"))).Times(1)
+	staleConn.EXPECT().Close().Times(1)
+
+	// The new connection is returned to the pool after it succeeds, but it is closed after it fails.
+	newConn := mockldapconn.NewMockConn(ctrl)
+	newConn.EXPECT().Bind(testBindUsername, testBindPassword).Times(1)
+	gomock.InOrder(
+		newConn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil),
+		newConn.EXPECT().Search(gomock.Any()).Return(nil, ldap.NewError(ldap.LDAPResultOperationsError, errors.New("some search error"))),
+	)
+	newConn.EXPECT().IsClosing().Return(false).Times(2)
+	newConn.EXPECT().Close().Times(1)
+
+	dialedConns := []Conn{newConn}
+	pool := NewConnectionPool()
+	provider := New(ProviderConfig{
+		Name:               "some-provider-name",
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		UserSearch: UserSearchConfig{
+			Base:              testUserSearchBase,
+			Filter:            testUserSearchFilter,
+			UsernameAttribute: testUserSearchUsernameAttribute,
+			UIDAttribute:      testUserSearchUIDAttribute,
+		},
+		ConnectionPool: pool,
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			require.NotEmpty(t, dialedConns, "dialed too many times")
+			conn := dialedConns[0]
+			dialedConns = dialedConns[1:]
+			return conn, nil
+		}),
+	})
+	pool.put(provider.connectionPoolKey(), staleConn)
+
+	response, authenticated, err := provider.DryRunAuthenticateUser(context.Background(), testUpstreamUsername)
+	require.NoError(t, err)
+	require.True(t, authenticated)
+	require.Equal(t, testUserSearchResultUsernameAttributeValue, response.User.GetName())
+	require.Empty(t, dialedConns)
+
+	// Other errors are not retried.
+	_, authenticated, err = provider.DryRunAuthenticateUser(context.Background(), testUpstreamUsername)
+	require.EqualError(t, err, `error searching for user: LDAP Result Code 1 "Operations Error": some search error`)
+	require.False(t, authenticated)
+
+	// The connection which failed was not returned to the pool.
+	require.Nil(t, pool.get(provider.connectionPoolKey()))
+}
+
+type fakeGroupCache struct {
+	entries map[string][]string
+	ttls    map[string]time.Duration
+}
+
+func (c *fakeGroupCache) Get(_ context.Context, key string) ([]string, bool) {
+	groups, found := c.entries[key]
+	return groups, found
+}
+
+func (c *fakeGroupCache) Put(_ context.Context, key string, groups []string, ttl time.Duration) {
+	c.entries[key] = groups
+	c.ttls[key] = ttl
+}
+
+func TestRefreshWithGroupCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	userSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testUserSearchResultDNValue,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testUserSearchUsernameAttribute, []string{testUserSearchResultUsernameAttributeValue}),
+					{Name: testUserSearchUIDAttribute, ByteValues: [][]byte{[]byte(testUserSearchResultUIDAttributeValue)}},
+				},
+			},
+		},
+	}
+	groupSearchResult := &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: testGroupSearchResultDNValue1,
+				Attributes: []*ldap.EntryAttribute{
+					ldap.NewEntryAttribute(testGroupSearchGroupNameAttribute, []string{testGroupSearchResultGroupNameAttributeValue1}),
+				},
+			},
+		},
+	}
+
+	// The user is searched during every refresh, but the groups are only searched until they are cached.
+	conn := mockldapconn.NewMockConn(ctrl)
+	conn.EXPECT().Bind(testBindUsername, testBindPassword).Times(5)
+	conn.EXPECT().Search(gomock.Any()).Return(userSearchResult, nil).Times(5)
+	conn.EXPECT().SearchWithPaging(gomock.Any(), expectedGroupSearchPageSize).Return(groupSearchResult, nil).Times(4)
+	conn.EXPECT().Close().Times(5)
+
+	groupCache := &fakeGroupCache{entries: map[string][]string{}, ttls: map[string]time.Duration{}}
+	config := ProviderConfig{
+		Name:               "some-provider-name",
+		ResourceUID:        "some-resource-uid",
+		Host:               testHost,
+		ConnectionProtocol: TLS,
+		BindUsername:       testBindUsername,
+		BindPassword:       testBindPassword,
+		UserSearch: UserSearchConfig{
+			Base:              testUserSearchBase,
+			UIDAttribute:      testUserSearchUIDAttribute,
+			UsernameAttribute: testUserSearchUsernameAttribute,
+		},
+		GroupSearch: GroupSearchConfig{
+			Base:               testGroupSearchBase,
+			Filter:             testGroupSearchFilter,
+			GroupNameAttribute: testGroupSearchGroupNameAttribute,
+			RefreshCacheTTL:    5 * time.Minute,
+		},
+		GroupCache: groupCache,
+		Dialer: LDAPDialerFunc(func(ctx context.Context, addr endpointaddr.HostPort) (Conn, error) {
+			return conn, nil
+		}),
+	}
+
+	refresh := func(p *Provider) []string {
+		t.Helper()
+		groups, err := p.PerformRefresh(context.Background(), upstreamprovider.RefreshAttributes{
+			Username: testUserSearchResultUsernameAttributeValue,
+			Subject: fmt.Sprintf(
+				"ldaps://ldap.example.com:8443?base=some-upstream-user-base-dn&idpName=%s&sub=c29tZS11cHN0cmVhbS11aWQtdmFsdWU",
+				testUpstreamName,
+			),
+			DN: testUserSearchResultDNValue,
+		}, testUpstreamName)
+		require.NoError(t, err)
+		return groups
+	}
+
+	wantGroups := []string{testGroupSearchResultGroupNameAttributeValue1}
+	require.Equal(t, wantGroups, refresh(New(config)))
+	require.Len(t, groupCache.entries, 1)
+	for _, ttl := range groupCache.ttls {
+		require.Equal(t, 5*time.Minute, ttl)
+	}
+
+	// The cached groups are used by later refreshes, including by new providers for the same upstream.
+	require.Equal(t, wantGroups, refresh(New(config)))
+
+	// The cached groups are not used after the group search settings change.
+	config.GroupSearch.Filter = "(some-other-filter={})"
+	require.Equal(t, wantGroups, refresh(New(config)))
+	require.Len(t, groupCache.entries, 2)
+
+	// The cached groups are not used after the group name parsing overrides change.
+	config.GroupAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){
+		testGroupSearchGroupNameAttribute: func(entry *ldap.Entry) (string, error) { return "overridden", nil },
+	}
+	require.Equal(t, []string{"overridden"}, refresh(New(config)))
+	require.Len(t, groupCache.entries, 3)
+
+	config.GroupAttributeParsingOverrides = map[string]func(*ldap.Entry) (string, error){
+		testGroupSearchGroupNameAttribute: func(entry *ldap.Entry) (string, error) { return "overridden differently", nil },
+	}
+	require.Equal(t, []string{"overridden differently"}, refresh(New(config)))
+	require.Len(t, groupCache.entries, 4)
+}
+
 func TestGetConfig(t *testing.T) {
 	c := ProviderConfig{
 		Name:         "original-provider-name",
@@ -2395,13 +3094,18 @@ func TestRealTLSDialing(t *testing.T) {
 	alreadyCancelledContext, cancelFunc := context.WithCancel(context.Background())
 	cancelFunc() // cancel it immediately
 
+	bindClientCertPEM, bindClientKeyPEM, err := caForTestServerWithBadCertName.IssueClientCertPEM("bind-client", nil, time.Hour)
+	require.NoError(t, err)
+
 	tests := []struct {
-		name      string
-		host      string
-		connProto LDAPConnectionProtocol
-		caBundle  []byte
-		context   context.Context
-		wantError testutil.RequireErrorStringFunc
+		name                  string
+		host                  string
+		connProto             LDAPConnectionProtocol
+		caBundle              []byte
+		bindClientCertificate []byte
+		bindClientKey         []byte
+		context               context.Context
+		wantError             testutil.RequireErrorStringFunc
 	}{
 		{
 			name:      "happy path",
@@ -2410,6 +3114,25 @@ func TestRealTLSDialing(t *testing.T) {
 			connProto: TLS,
 			context:   context.Background(),
 		},
+		{
+			name:                  "happy path with a bind client certificate",
+			host:                  testServerHostAndPort,
+			caBundle:              testServerCABundle,
+			bindClientCertificate: bindClientCertPEM,
+			bindClientKey:         bindClientKeyPEM,
+			connProto:             TLS,
+			context:               context.Background(),
+		},
+		{
+			name:                  "invalid bind client certificate",
+			host:                  testServerHostAndPort,
+			caBundle:              testServerCABundle,
+			bindClientCertificate: bindClientCertPEM,
+			bindClientKey:         []byte("not a key"),
+			connProto:             TLS,
+			context:               context.Background(),
+			wantError:             testutil.WantExactErrorString(`LDAP Result Code 200 "Network Error": could not parse bind client certificate: tls: failed to find any PEM data in key input`),
+		},
 		{
 			name:      "server cert name does not match the address to which the client connected",
 			host:      testServerWithBadCertNameAddr,
@@ -2488,10 +3211,12 @@ func TestRealTLSDialing(t *testing.T) {
 		tt := test
 		t.Run(tt.name, func(t *testing.T) {
 			provider := New(ProviderConfig{
-				Host:               tt.host,
-				CABundle:           tt.caBundle,
-				ConnectionProtocol: tt.connProto,
-				Dialer:             nil, // this test is for the default (production) TLS dialer
+				Host:                  tt.host,
+				CABundle:              tt.caBundle,
+				BindClientCertificate: tt.bindClientCertificate,
+				BindClientKey:         tt.bindClientKey,
+				ConnectionProtocol:    tt.connProto,
+				Dialer:                nil, // this test is for the default (production) TLS dialer
 			})
 			conn, err := provider.dial(tt.context)
 			if conn != nil {
@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package upstreamoidc implements an abstraction of upstream OIDC provider interactions.
@@ -36,17 +36,19 @@ func New(config *oauth2.Config, provider *coreosoidc.Provider, client *http.Clie
 
 // ProviderConfig holds the active configuration of an upstream OIDC provider.
 type ProviderConfig struct {
-	Name                     string
-	ResourceUID              types.UID
-	UsernameClaim            string
-	GroupsClaim              string
-	Config                   *oauth2.Config
-	Client                   *http.Client
-	AllowPasswordGrant       bool
-	AdditionalAuthcodeParams map[string]string
-	AdditionalClaimMappings  map[string]string
-	RevocationURL            *url.URL // will commonly be nil: many providers do not offer this
-	Provider                 interface {
+	Name                               string
+	ResourceUID                        types.UID
+	UsernameClaim                      string
+	GroupsClaim                        string
+	Config                             *oauth2.Config
+	Client                             *http.Client
+	AllowPasswordGrant                 bool
+	AdditionalAuthcodeParams           map[string]string
+	PassthroughAuthcodeParams          []string
+	AdditionalClaimMappings            map[string]string
+	AdditionalClaimsForTransformations []string
+	RevocationURL                      *url.URL // will commonly be nil: many providers do not offer this
+	Provider                           interface {
 		Verifier(*coreosoidc.Config) *coreosoidc.IDTokenVerifier
 		Claims(v interface{}) error
 		UserInfo(ctx context.Context, tokenSource oauth2.TokenSource) (*coreosoidc.UserInfo, error)
@@ -80,10 +82,18 @@ func (p *ProviderConfig) GetAdditionalAuthcodeParams() map[string]string {
 	return p.AdditionalAuthcodeParams
 }
 
+func (p *ProviderConfig) GetPassthroughAuthcodeParams() []string {
+	return p.PassthroughAuthcodeParams
+}
+
 func (p *ProviderConfig) GetAdditionalClaimMappings() map[string]string {
 	return p.AdditionalClaimMappings
 }
 
+func (p *ProviderConfig) GetAdditionalClaimsForTransformations() []string {
+	return p.AdditionalClaimsForTransformations
+}
+
 func (p *ProviderConfig) GetName() string {
 	return p.Name
 }
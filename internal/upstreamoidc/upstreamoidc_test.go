@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package upstreamoidc
@@ -79,6 +79,11 @@ func TestProviderConfig(t *testing.T) {
 		require.Empty(t, p.AdditionalClaimMappings)
 		p.AdditionalClaimMappings = map[string]string{"additional": "claimMappings"}
 		require.Equal(t, p.GetAdditionalClaimMappings(), map[string]string{"additional": "claimMappings"})
+
+		// AdditionalClaimsForTransformations defaults to empty
+		require.Empty(t, p.AdditionalClaimsForTransformations)
+		p.AdditionalClaimsForTransformations = []string{"email_verified"}
+		require.Equal(t, []string{"email_verified"}, p.GetAdditionalClaimsForTransformations())
 	})
 
 	const (
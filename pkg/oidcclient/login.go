@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package oidcclient implements a CLI OIDC login flow.
@@ -62,13 +62,15 @@ const (
 	// we set this to be relatively long.
 	overallTimeout = 90 * time.Minute
 
-	usernamePrompt = "Username: "
-	passwordPrompt = "Password: "
+	usernamePrompt        = "Username: "
+	passwordPrompt        = "Password: "
+	oneTimePasswordPrompt = "One-time password: "
 
 	// For CLI-based auth, such as with LDAP upstream identity providers, the user may use these environment variables
-	// to avoid getting interactively prompted for username and password.
-	defaultUsernameEnvVarName = "PINNIPED_USERNAME"
-	defaultPasswordEnvVarName = "PINNIPED_PASSWORD" //nolint:gosec // this is not a credential
+	// to avoid getting interactively prompted for username, password, and one-time password.
+	defaultUsernameEnvVarName        = "PINNIPED_USERNAME"
+	defaultPasswordEnvVarName        = "PINNIPED_PASSWORD" //nolint:gosec // this is not a credential
+	defaultOneTimePasswordEnvVarName = "PINNIPED_OTP"
 
 	httpLocationHeaderName = "Location"
 )
@@ -488,6 +490,55 @@ func (h *handlerState) cliBasedAuth(authorizeOptions *[]oauth2.AuthCodeOption) (
 	// Now that we have a redirect URL, we can build the authorize URL.
 	authorizeURL := h.oauth2Config.AuthCodeURL(h.state.String(), *authorizeOptions...)
 
+	// Send an authorize request.
+	authHeaders := http.Header{}
+	authHeaders.Set(oidcapi.AuthorizeUsernameHeaderName, username)
+	authHeaders.Set(oidcapi.AuthorizePasswordHeaderName, password)
+	location, err := h.sendCLIAuthorizeRequest(authorizeURL, authHeaders, localhostAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// When the identity provider requires a second factor, ask for the one-time password and try again.
+	if location.Query().Get("code") == "" && location.Query().Get("error") == oidcapi.AuthorizeErrorOneTimePasswordRequired {
+		oneTimePassword, err := h.getOneTimePassword()
+		if err != nil {
+			return nil, err
+		}
+		authHeaders.Set(oidcapi.AuthorizeOneTimePasswordHeaderName, oneTimePassword)
+		location, err = h.sendCLIAuthorizeRequest(authorizeURL, authHeaders, localhostAddr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Get the auth code or return the error from the server.
+	authCode := location.Query().Get("code")
+	if authCode == "" {
+		// Check for error response parameters. See https://openid.net/specs/openid-connect-core-1_0.html#AuthError.
+		requiredErrorCode := location.Query().Get("error")
+		optionalErrorDescription := location.Query().Get("error_description")
+		if optionalErrorDescription == "" {
+			return nil, fmt.Errorf("login failed with code %q", requiredErrorCode)
+		}
+		return nil, fmt.Errorf("login failed with code %q: %s", requiredErrorCode, optionalErrorDescription)
+	}
+
+	// Exchange the authorization code for access, ID, and refresh tokens and perform required
+	// validations on the returned ID token.
+	tokenCtx, tokenCtxCancelFunc := context.WithTimeout(h.ctx, httpRequestTimeout)
+	defer tokenCtxCancelFunc()
+	token, err := h.redeemAuthCode(tokenCtx, authCode)
+	if err != nil {
+		return nil, fmt.Errorf("could not complete authorization code exchange: %w", err)
+	}
+
+	return token, nil
+}
+
+// Make a direct call to the authorize endpoint with the given headers. Return the location of the redirect to the
+// callback URL, after checking the state param of the redirect.
+func (h *handlerState) sendCLIAuthorizeRequest(authorizeURL string, headers http.Header, localhostAddr string) (*url.URL, error) {
 	// Don't follow redirects automatically because we want to handle redirects here.
 	var sawRedirect bool
 	h.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
@@ -495,15 +546,15 @@ func (h *handlerState) cliBasedAuth(authorizeOptions *[]oauth2.AuthCodeOption) (
 		return http.ErrUseLastResponse
 	}
 
-	// Send an authorize request.
 	authCtx, authorizeCtxCancelFunc := context.WithTimeout(h.ctx, httpRequestTimeout)
 	defer authorizeCtxCancelFunc()
 	authReq, err := http.NewRequestWithContext(authCtx, http.MethodGet, authorizeURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("could not build authorize request: %w", err)
 	}
-	authReq.Header.Set(oidcapi.AuthorizeUsernameHeaderName, username)
-	authReq.Header.Set(oidcapi.AuthorizePasswordHeaderName, password)
+	for name := range headers {
+		authReq.Header.Set(name, headers.Get(name))
+	}
 	authRes, err := h.httpClient.Do(authReq)
 	if err != nil {
 		return nil, fmt.Errorf("authorization response error: %w", err)
@@ -532,28 +583,22 @@ func (h *handlerState) cliBasedAuth(authorizeOptions *[]oauth2.AuthCodeOption) (
 		return nil, fmt.Errorf("missing or invalid state parameter in authorization response: %s", rawLocation)
 	}
 
-	// Get the auth code or return the error from the server.
-	authCode := location.Query().Get("code")
-	if authCode == "" {
-		// Check for error response parameters. See https://openid.net/specs/openid-connect-core-1_0.html#AuthError.
-		requiredErrorCode := location.Query().Get("error")
-		optionalErrorDescription := location.Query().Get("error_description")
-		if optionalErrorDescription == "" {
-			return nil, fmt.Errorf("login failed with code %q", requiredErrorCode)
-		}
-		return nil, fmt.Errorf("login failed with code %q: %s", requiredErrorCode, optionalErrorDescription)
+	return location, nil
+}
+
+// Prompt for the user's one-time password, or read it from an env var if it is available.
+func (h *handlerState) getOneTimePassword() (string, error) {
+	oneTimePassword := h.getEnv(defaultOneTimePasswordEnvVarName)
+	if oneTimePassword != "" {
+		h.logger.V(plog.KlogLevelDebug).Info("Pinniped: Read one-time password from environment variable", "name", defaultOneTimePasswordEnvVarName)
+		return oneTimePassword, nil
 	}
 
-	// Exchange the authorization code for access, ID, and refresh tokens and perform required
-	// validations on the returned ID token.
-	tokenCtx, tokenCtxCancelFunc := context.WithTimeout(h.ctx, httpRequestTimeout)
-	defer tokenCtxCancelFunc()
-	token, err := h.redeemAuthCode(tokenCtx, authCode)
+	oneTimePassword, err := h.promptForValue(h.ctx, oneTimePasswordPrompt, h.out)
 	if err != nil {
-		return nil, fmt.Errorf("could not complete authorization code exchange: %w", err)
+		return "", fmt.Errorf("error prompting for one-time password: %w", err)
 	}
-
-	return token, nil
+	return oneTimePassword, nil
 }
 
 // Prompt for the user's username and password, or read them from env vars if they are available.
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcclient
@@ -1342,6 +1342,66 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 			wantStdErr: "^\nLog in to some-upstream-name\n\n$",
 			wantErr:    "error prompting for password: some prompt error",
 		},
+		{
+			name:     "ldap login when prompting for one-time password returns an error",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					_ = defaultLDAPTestOpts(t, h, &http.Response{
+						StatusCode: http.StatusFound,
+						Header: http.Header{"Location": []string{
+							"http://127.0.0.1:0/callback?error=pinniped_one_time_password_required&state=test-state",
+						}},
+					}, nil)
+					h.promptForValue = func(_ context.Context, promptLabel string, _ io.Writer) (string, error) {
+						if promptLabel == "Username: " {
+							return "some-upstream-username", nil
+						}
+						require.Equal(t, "One-time password: ", promptLabel)
+						return "", errors.New("some prompt error")
+					}
+					return nil
+				}
+			},
+			issuer:     successServer.URL,
+			wantLogs:   []string{`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`},
+			wantStdErr: "^\nLog in to some-upstream-name\n\n$",
+			wantErr:    "error prompting for one-time password: some prompt error",
+		},
+		{
+			name:     "ldap login when the one-time password is not accepted",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					_ = defaultLDAPTestOpts(t, h, &http.Response{
+						StatusCode: http.StatusFound,
+						Header: http.Header{"Location": []string{
+							"http://127.0.0.1:0/callback?error=pinniped_one_time_password_required&state=test-state",
+						}},
+					}, nil)
+					promptCount := 0
+					h.promptForValue = func(_ context.Context, promptLabel string, _ io.Writer) (string, error) {
+						promptCount++
+						switch promptCount {
+						case 1:
+							require.Equal(t, "Username: ", promptLabel)
+							return "some-upstream-username", nil
+						default:
+							require.Equal(t, "One-time password: ", promptLabel)
+							return "123456", nil
+						}
+					}
+					t.Cleanup(func() {
+						require.Equal(t, 2, promptCount, "should have prompted only once for the one-time password")
+					})
+					return nil
+				}
+			},
+			issuer:     successServer.URL,
+			wantLogs:   []string{`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`},
+			wantStdErr: "^\nLog in to some-upstream-name\n\n$",
+			wantErr:    `login failed with code "pinniped_one_time_password_required"`,
+		},
 		{
 			name:     "ldap login when there is a problem with parsing the authorize URL",
 			clientID: "test-client-id",
@@ -1716,6 +1776,114 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 			},
 			wantToken: &testToken,
 		},
+		{
+			name:     "successful ldap login with env vars for username, password, and one-time password",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					fakeAuthCode := "test-authcode-value"
+
+					h.getProvider = func(_ *oauth2.Config, _ *oidc.Provider, _ *http.Client) upstreamprovider.UpstreamOIDCIdentityProviderI {
+						mock := mockUpstream(t)
+						mock.EXPECT().
+							ExchangeAuthcodeAndValidateTokens(
+								gomock.Any(), fakeAuthCode, pkce.Code("test-pkce"), nonce.Nonce("test-nonce"), "http://127.0.0.1:0/callback").
+							Return(&testToken, nil)
+						return mock
+					}
+
+					h.generateState = func() (state.State, error) { return "test-state", nil }
+					h.generatePKCE = func() (pkce.Code, error) { return "test-pkce", nil }
+					h.generateNonce = func() (nonce.Nonce, error) { return "test-nonce", nil }
+					h.getEnv = func(key string) string {
+						switch key {
+						case "PINNIPED_USERNAME":
+							return "some-upstream-username"
+						case "PINNIPED_PASSWORD":
+							return "some-upstream-password"
+						case "PINNIPED_OTP":
+							return "123456"
+						default:
+							return "" // all other env vars are treated as if they are unset
+						}
+					}
+					h.promptForValue = func(_ context.Context, promptLabel string, _ io.Writer) (string, error) {
+						require.FailNow(t, fmt.Sprintf("saw unexpected prompt from the CLI: %q", promptLabel))
+						return "", nil
+					}
+					h.promptForSecret = func(promptLabel string, _ io.Writer) (string, error) {
+						require.FailNow(t, fmt.Sprintf("saw unexpected prompt from the CLI: %q", promptLabel))
+						return "", nil
+					}
+
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					cacheKey := SessionCacheKey{
+						Issuer:      successServer.URL,
+						ClientID:    "test-client-id",
+						Scopes:      []string{"test-scope"},
+						RedirectURI: "http://localhost:0/callback",
+					}
+					t.Cleanup(func() {
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawGetKeys)
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawPutKeys)
+						require.Equal(t, []*oidctypes.Token{&testToken}, cache.sawPutTokens)
+					})
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithCLISendingCredentials()(h))
+
+					discoveryRequestWasMade := false
+					authorizeRequestCount := 0
+					t.Cleanup(func() {
+						require.True(t, discoveryRequestWasMade, "should have made an discovery request")
+						require.Equal(t, 2, authorizeRequestCount, "should have made two authorize requests")
+					})
+
+					client := newClientForServer(successServer)
+					client.Transport = roundtripper.Func(func(req *http.Request) (*http.Response, error) {
+						switch req.URL.Scheme + "://" + req.URL.Host + req.URL.Path {
+						case "https://" + successServer.Listener.Addr().String() + "/.well-known/openid-configuration":
+							discoveryRequestWasMade = true
+							return defaultDiscoveryResponse(req)
+						case "https://" + successServer.Listener.Addr().String() + "/authorize":
+							authorizeRequestCount++
+							require.Equal(t, "some-upstream-username", req.Header.Get("Pinniped-Username"))
+							require.Equal(t, "some-upstream-password", req.Header.Get("Pinniped-Password"))
+							require.Equal(t, "test-state", req.URL.Query().Get("state"))
+							if authorizeRequestCount == 1 {
+								require.Empty(t, req.Header.Values("Pinniped-One-Time-Password"))
+								return &http.Response{
+									StatusCode: http.StatusFound,
+									Header: http.Header{"Location": []string{
+										"http://127.0.0.1:0/callback?error=pinniped_one_time_password_required&state=test-state",
+									}},
+								}, nil
+							}
+							require.Equal(t, "123456", req.Header.Get("Pinniped-One-Time-Password"))
+							return &http.Response{
+								StatusCode: http.StatusFound,
+								Header: http.Header{"Location": []string{
+									fmt.Sprintf("http://127.0.0.1:0/callback?code=%s&state=test-state", fakeAuthCode),
+								}},
+							}, nil
+						default:
+							// Note that "/token" requests should not be made. They are mocked by mocking calls to ExchangeAuthcodeAndValidateTokens().
+							require.FailNow(t, fmt.Sprintf("saw unexpected http call from the CLI: %s", req.URL.String()))
+							return nil, nil
+						}
+					})
+					require.NoError(t, WithClient(client)(h))
+					return nil
+				}
+			},
+			issuer: successServer.URL,
+			wantLogs: []string{
+				`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`,
+				`"level"=4 "msg"="Pinniped: Read username from environment variable"  "name"="PINNIPED_USERNAME"`,
+				`"level"=4 "msg"="Pinniped: Read password from environment variable"  "name"="PINNIPED_PASSWORD"`,
+				`"level"=4 "msg"="Pinniped: Read one-time password from environment variable"  "name"="PINNIPED_OTP"`,
+			},
+			wantToken: &testToken,
+		},
 		{
 			name:     "successful ldap login with env vars for username and password, http.StatusSeeOther redirect",
 			clientID: "test-client-id",
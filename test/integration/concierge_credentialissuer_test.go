@@ -1,4 +1,4 @@
-// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -53,7 +53,16 @@ func TestCredentialIssuer(t *testing.T) {
 		actualStatusStrategies := actualConfigList.Items[0].Status.Strategies
 
 		// There should be two. One of type KubeClusterSigningCertificate and one of type ImpersonationProxy.
+		// The ExternalSigner and ConciergeManagedCA strategies are opt-in, so they are not reported by a default install.
 		require.Len(t, actualStatusStrategies, 2)
+		actualStrategyTypes := make([]configv1alpha1.StrategyType, 0, len(actualStatusStrategies))
+		for _, s := range actualStatusStrategies {
+			actualStrategyTypes = append(actualStrategyTypes, s.Type)
+		}
+		require.ElementsMatch(t, []configv1alpha1.StrategyType{
+			configv1alpha1.KubeClusterSigningCertificateStrategyType,
+			configv1alpha1.ImpersonationProxyStrategyType,
+		}, actualStrategyTypes)
 
 		// The details of the ImpersonationProxy type is tested by a different integration test for the impersonator.
 		// Grab the KubeClusterSigningCertificate result so we can check it in detail below.
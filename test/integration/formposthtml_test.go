@@ -1,4 +1,4 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -172,11 +172,11 @@ func formpostCallbackServer(t *testing.T) (string, func(*testing.T, url.Values))
 func formpostTemplateServer(t *testing.T, redirectURI string, responseParams url.Values) string {
 	t.Helper()
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		fosite.WriteAuthorizeFormPostResponse(redirectURI, responseParams, formposthtml.Template(), w)
+		fosite.WriteAuthorizeFormPostResponse(redirectURI, responseParams, formposthtml.Template(nil), w)
 	})
 	server := httptest.NewServer(securityheader.WrapWithCustomCSP(
 		handler,
-		formposthtml.ContentSecurityPolicy(),
+		formposthtml.ContentSecurityPolicy(nil),
 	))
 	t.Cleanup(server.Close)
 	return server.URL
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -57,6 +57,7 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 	idpSupervisorGV := makeGV("idp", "supervisor")
 	configSupervisorGV := makeGV("config", "supervisor")
 	clientSecretSupervisorGV := makeGV("clientsecret", "supervisor")
+	transformationSupervisorGV := makeGV("transformation", "supervisor")
 
 	tests := []struct {
 		group             metav1.APIGroup
@@ -143,6 +144,33 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 				},
 			},
 		},
+		{
+			group: metav1.APIGroup{
+				Name: transformationSupervisorGV.Group,
+				Versions: []metav1.GroupVersionForDiscovery{
+					{
+						GroupVersion: transformationSupervisorGV.String(),
+						Version:      transformationSupervisorGV.Version,
+					},
+				},
+				PreferredVersion: metav1.GroupVersionForDiscovery{
+					GroupVersion: transformationSupervisorGV.String(),
+					Version:      transformationSupervisorGV.Version,
+				},
+			},
+			resourceByVersion: map[string][]metav1.APIResource{
+				transformationSupervisorGV.String(): {
+					{
+						Name:         "identitytransformationtestrequests",
+						SingularName: "identitytransformationtestrequest",
+						Kind:         "IdentityTransformationTestRequest",
+						Verbs:        []string{"create", "list"},
+						Namespaced:   true,
+						Categories:   []string{"pinniped"},
+					},
+				},
+			},
+		},
 		{
 			group: metav1.APIGroup{
 				Name: configSupervisorGV.Group,
@@ -173,6 +201,20 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 						Kind:       "FederationDomain",
 						Verbs:      []string{"get", "patch", "update"},
 					},
+					{
+						Name:         "identitytransformationlibraries",
+						SingularName: "identitytransformationlibrary",
+						Namespaced:   false,
+						Kind:         "IdentityTransformationLibrary",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped"},
+					},
+					{
+						Name:       "identitytransformationlibraries/status",
+						Namespaced: false,
+						Kind:       "IdentityTransformationLibrary",
+						Verbs:      []string{"get", "patch", "update"},
+					},
 					{
 						Name:         "oidcclients",
 						SingularName: "oidcclient",
@@ -386,7 +428,7 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 	t.Run("every API has a status subresource", func(t *testing.T) {
 		t.Parallel()
 
-		aggregatedAPIs := sets.NewString("tokencredentialrequests", "whoamirequests", "oidcclientsecretrequests")
+		aggregatedAPIs := sets.NewString("tokencredentialrequests", "whoamirequests", "oidcclientsecretrequests", "identitytransformationtestrequests")
 
 		var regular, status []string
 
@@ -565,6 +607,12 @@ func TestCRDAdditionalPrinterColumns_Parallel(t *testing.T) {
 				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
 			},
 		},
+		addSuffix("identitytransformationlibraries.config.supervisor"): {
+			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
+				{Name: "Status", Type: "string", JSONPath: ".status.phase"},
+				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+			},
+		},
 		addSuffix("ldapidentityproviders.idp.supervisor"): {
 			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
 				{Name: "Host", Type: "string", JSONPath: ".spec.host"},
@@ -590,7 +638,7 @@ func TestCRDAdditionalPrinterColumns_Parallel(t *testing.T) {
 	}
 
 	actualPinnipedCRDCount := 0
-	expectedPinnipedCRDCount := 8 // the current number of CRDs that we ship as part of Pinniped
+	expectedPinnipedCRDCount := 9 // the current number of CRDs that we ship as part of Pinniped
 
 	for _, crd := range crdList.Items {
 		if !strings.Contains(crd.Spec.Group, env.APIGroupSuffix) {
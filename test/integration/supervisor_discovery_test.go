@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -531,18 +531,24 @@ func requireWellKnownEndpointIsWorking(t *testing.T, supervisorScheme, superviso
       "issuer": "%s",
       "authorization_endpoint": "%s/oauth2/authorize",
       "token_endpoint": "%s/oauth2/token",
-      "token_endpoint_auth_methods_supported": ["client_secret_basic"],
+      "token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt", "tls_client_auth"],
+      "token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
       "jwks_uri": "%s/jwks.json",
       "scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
       "response_types_supported": ["code"],
       "response_modes_supported": ["query", "form_post"],
       "code_challenge_methods_supported": ["S256"],
+      "pushed_authorization_request_endpoint": "%s/oauth2/par",
+      "require_pushed_authorization_requests": false,
+      "request_parameter_supported": true,
+      "request_uri_parameter_supported": false,
+      "request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
       "claims_supported": ["username", "groups", "additionalClaims"],
       "discovery.supervisor.pinniped.dev/v1alpha1": {"pinniped_identity_providers_endpoint": "%s/v1alpha1/pinniped_identity_providers"},
       "subject_types_supported": ["public"],
       "id_token_signing_alg_values_supported": ["ES256"]
     }`)
-	expectedJSON := fmt.Sprintf(expectedResultTemplate, issuerName, issuerName, issuerName, issuerName, issuerName)
+	expectedJSON := fmt.Sprintf(expectedResultTemplate, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName)
 
 	require.Equal(t, "application/json", response.Header.Get("content-type"))
 	require.JSONEq(t, expectedJSON, responseBody)
@@ -660,16 +666,20 @@ func requireDelete(t *testing.T, client supervisorclientset.Interface, ns, name
 
 func withAllSuccessfulConditions() map[string]metav1.ConditionStatus {
 	return map[string]metav1.ConditionStatus{
-		"Ready":                                         metav1.ConditionTrue,
-		"IssuerIsUnique":                                metav1.ConditionTrue,
-		"IdentityProvidersFound":                        metav1.ConditionTrue,
-		"OneTLSSecretPerIssuerHostname":                 metav1.ConditionTrue,
-		"IssuerURLValid":                                metav1.ConditionTrue,
-		"IdentityProvidersObjectRefKindValid":           metav1.ConditionTrue,
+		"Ready":                               metav1.ConditionTrue,
+		"BrandingValid":                       metav1.ConditionTrue,
+		"IssuerIsUnique":                      metav1.ConditionTrue,
+		"IdentityProvidersFound":              metav1.ConditionTrue,
+		"OneTLSSecretPerIssuerHostname":       metav1.ConditionTrue,
+		"IssuerURLValid":                      metav1.ConditionTrue,
+		"IdentityProvidersObjectRefKindValid": metav1.ConditionTrue,
 		"IdentityProvidersObjectRefAPIGroupSuffixValid": metav1.ConditionTrue,
 		"IdentityProvidersDisplayNamesUnique":           metav1.ConditionTrue,
+		"IdentityProvidersRoutingValid":                 metav1.ConditionTrue,
+		"IdentityProvidersSecondFactorValid":            metav1.ConditionTrue,
 		"TransformsExpressionsValid":                    metav1.ConditionTrue,
 		"TransformsExamplesPassed":                      metav1.ConditionTrue,
+		"TokenExchangeAudiencesValid":                   metav1.ConditionTrue,
 	}
 }
 
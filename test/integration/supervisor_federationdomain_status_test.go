@@ -1,4 +1,4 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -981,6 +981,10 @@ func allSuccessfulLegacyFederationDomainConditions(idpName string, federationDom
 
 func allSuccessfulFederationDomainConditions(federationDomainSpec v1alpha1.FederationDomainSpec) []metav1.Condition {
 	return []metav1.Condition{
+		{
+			Type: "BrandingValid", Status: "True", Reason: "Success",
+			Message: "no branding was specified by .spec.branding: using the default pages",
+		},
 		{
 			Type: "IdentityProvidersDisplayNamesUnique", Status: "True", Reason: "Success",
 			Message: "the names specified by .spec.identityProviders[].displayName are unique",
@@ -997,6 +1001,14 @@ func allSuccessfulFederationDomainConditions(federationDomainSpec v1alpha1.Feder
 			Type: "IdentityProvidersObjectRefKindValid", Status: "True", Reason: "Success",
 			Message: "the kinds specified by .spec.identityProviders[].objectRef.kind are recognized",
 		},
+		{
+			Type: "IdentityProvidersRoutingValid", Status: "True", Reason: "Success",
+			Message: "the routing rules specified by .spec.identityProviders[].routing are valid",
+		},
+		{
+			Type: "IdentityProvidersSecondFactorValid", Status: "True", Reason: "Success",
+			Message: "the second factors specified by .spec.identityProviders[].secondFactor are supported",
+		},
 		{
 			Type: "IssuerIsUnique", Status: "True", Reason: "Success",
 			Message: "spec.issuer is unique among all FederationDomains",
@@ -1014,6 +1026,10 @@ func allSuccessfulFederationDomainConditions(federationDomainSpec v1alpha1.Feder
 			Message: fmt.Sprintf("the FederationDomain is ready and its endpoints are available: "+
 				"the discovery endpoint is %s/.well-known/openid-configuration", federationDomainSpec.Issuer),
 		},
+		{
+			Type: "TokenExchangeAudiencesValid", Status: "True", Reason: "Success",
+			Message: "the audiences specified by .spec.tokenExchange.allowedAudiences[] are valid",
+		},
 		{
 			Type: "TransformsExamplesPassed", Status: "True", Reason: "Success",
 			Message: "the examples specified by .spec.identityProviders[].transforms.examples[] had no errors",
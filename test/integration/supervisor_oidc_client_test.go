@@ -1,4 +1,4 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -555,6 +555,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "MissingRequiredValue",
 					Message: `"openid" must always be included in "allowedScopes"; "offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "False",
@@ -590,6 +596,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "Success",
 					Message: `"allowedScopes" is valid`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "False",
@@ -625,6 +637,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "Success",
 					Message: `"allowedScopes" is valid`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "True",